@@ -42,8 +42,36 @@ const (
 	// CleanerFinalizer allows Reconciler to clean up resources associated with
 	// Cleaner instance before removing it from the apiserver.
 	CleanerFinalizer = "projectsveltos.io/cleaner-finalizer"
+
+	// TestNotificationAnnotation, when set on a Cleaner instance, triggers a test
+	// notification with a synthetic report for the notification whose name is the
+	// annotation value. The annotation is removed once the test notification is sent.
+	TestNotificationAnnotation = "projectsveltos.io/test-notification"
+
+	// BackupCleanerLabel is set on the ConfigMaps resources are backed up to.
+	// Its value is the name of the Cleaner instance.
+	BackupCleanerLabel = "projectsveltos.io/cleaner-backup"
 )
 
+// StalenessWatchdog configures the detection of Cleaner instances which stopped
+// running
+type StalenessWatchdog struct {
+	// Factor is how many schedule intervals may elapse since the last run before
+	// the Cleaner is considered stale. The schedule interval is the time between
+	// the two scheduled runs following the last run.
+	// +kubebuilder:default:=2
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Factor int32 `json:"factor,omitempty"`
+}
+
+// ResourceThrottling configures how often the same resource is notified
+type ResourceThrottling struct {
+	// Cooldown is the minimum time between two notifications listing the same
+	// resource, identified by apiVersion, kind, namespace and name
+	Cooldown metav1.Duration `json:"cooldown"`
+}
+
 // DeleteOptions contains options for delete requests. It's generally a subset
 // of metav1.DeleteOptions.
 type DeleteOptions struct {
@@ -66,6 +94,45 @@ type DeleteOptions struct {
 	PropagationPolicy *metav1.DeletionPropagation `json:"propagationPolicy,omitempty"`
 }
 
+// BackupSinkType is the type of storage resources are backed up to
+// +kubebuilder:validation:Enum:=PersistentVolume;S3;ConfigMap
+type BackupSinkType string
+
+const (
+	// BackupSinkPersistentVolume writes each resource to a file of a volume
+	// mounted in the controller
+	BackupSinkPersistentVolume = BackupSinkType("PersistentVolume")
+
+	// BackupSinkS3 uploads each resource to an S3 bucket
+	BackupSinkS3 = BackupSinkType("S3")
+
+	// BackupSinkConfigMap stores each resource in a ConfigMap
+	BackupSinkConfigMap = BackupSinkType("ConfigMap")
+)
+
+// ResourceBackup configures where resources are backed up before being deleted
+type ResourceBackup struct {
+	// Type is the type of storage resources are backed up to
+	Type BackupSinkType `json:"type"`
+
+	// Path is the directory, in a volume mounted in the controller, resources
+	// are written to. Required when Type is PersistentVolume.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// SecretRef references the Secret containing the S3 bucket, region and,
+	// optionally, prefix, endpoint and AWS credentials, with the same keys as
+	// S3 notifications. Required when Type is S3.
+	// +optional
+	SecretRef *corev1.ObjectReference `json:"secretRef,omitempty"`
+
+	// Namespace is the namespace ConfigMaps are created in. Required when Type
+	// is ConfigMap. Resources larger than the ConfigMap size limit cannot be
+	// backed up, and so are not deleted.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
 type ResourceSelector struct {
 	// Namespace of the resource deployed in the  Cluster.
 	// Empty for resources scoped at cluster level.
@@ -121,7 +188,7 @@ type ResourcePolicySet struct {
 }
 
 // NotificationType specifies different type of notifications
-// +kubebuilder:validation:Enum:=CleanerReport;Slack;Webex;Discord;Teams;SMTP
+// +kubebuilder:validation:Enum:=CleanerReport;Slack;Webex;Discord;Teams;SMTP;SplunkHEC;Event;File;CloudEvents;VictorOps;S3;SMS;Redis;GitLab;Loki;Log;Elasticsearch
 type NotificationType string
 
 const (
@@ -142,8 +209,723 @@ const (
 
 	// NotificationTypeSMTP refers to sending an email
 	NotificationTypeSMTP = NotificationType("SMTP")
+
+	// NotificationTypeSplunkHEC refers to sending an event to a Splunk
+	// HTTP Event Collector
+	NotificationTypeSplunkHEC = NotificationType("SplunkHEC")
+
+	// NotificationTypeEvent refers to recording a Kubernetes Event on the
+	// Cleaner instance
+	NotificationTypeEvent = NotificationType("Event")
+
+	// NotificationTypeFile refers to writing the report to a file
+	NotificationTypeFile = NotificationType("File")
+
+	// NotificationTypeCloudEvents refers to sending the report as a CloudEvent
+	// to an HTTP sink
+	NotificationTypeCloudEvents = NotificationType("CloudEvents")
+
+	// NotificationTypeVictorOps refers to raising an alert in VictorOps
+	// (Splunk On-Call) using the REST endpoint integration
+	NotificationTypeVictorOps = NotificationType("VictorOps")
+
+	// NotificationTypeS3 refers to uploading the report to an S3 bucket
+	NotificationTypeS3 = NotificationType("S3")
+
+	// NotificationTypeSMS refers to sending a short summary of the report as
+	// SMS using Twilio
+	NotificationTypeSMS = NotificationType("SMS")
+
+	// NotificationTypeRedis refers to adding the report to a Redis stream or
+	// publishing it to a Redis channel
+	NotificationTypeRedis = NotificationType("Redis")
+
+	// NotificationTypeGitLab refers to creating or updating a GitLab issue, or
+	// a note on a GitLab merge request
+	NotificationTypeGitLab = NotificationType("GitLab")
+
+	// NotificationTypeLoki refers to pushing the report as log lines to a
+	// Grafana Loki push endpoint
+	NotificationTypeLoki = NotificationType("Loki")
+
+	// NotificationTypeLog refers to writing the report to the k8s-cleaner
+	// controller log
+	NotificationTypeLog = NotificationType("Log")
+
+	// NotificationTypeElasticsearch refers to indexing each resource of the
+	// report as a document of an Elasticsearch index
+	NotificationTypeElasticsearch = NotificationType("Elasticsearch")
+)
+
+const (
+	// SplunkHECURL is the key of the Secret data containing the Splunk HTTP
+	// Event Collector URL (for instance https://splunk:8088/services/collector/event)
+	SplunkHECURL = "SPLUNK_HEC_URL"
+
+	// SplunkHECToken is the key of the Secret data containing the Splunk HTTP
+	// Event Collector token
+	SplunkHECToken = "SPLUNK_HEC_TOKEN"
+
+	// CloudEventsSinkURL is the key of the Secret data containing the URL
+	// CloudEvents are sent to (for instance a Knative broker)
+	CloudEventsSinkURL = "CLOUDEVENTS_SINK_URL"
+
+	// CloudEventsAuthType is the key of the Secret data containing the
+	// authentication used with the CloudEvents sink: basic, bearer or none.
+	// Defaults to none.
+	CloudEventsAuthType = "CLOUDEVENTS_AUTH_TYPE"
+
+	// CloudEventsUsername is the key of the Secret data containing the
+	// username sent to the CloudEvents sink with basic authentication
+	CloudEventsUsername = "CLOUDEVENTS_USERNAME"
+
+	// CloudEventsPassword is the key of the Secret data containing the
+	// password sent to the CloudEvents sink with basic authentication
+	CloudEventsPassword = "CLOUDEVENTS_PASSWORD"
+
+	// CloudEventsToken is the key of the Secret data containing the token
+	// sent to the CloudEvents sink with bearer authentication
+	CloudEventsToken = "CLOUDEVENTS_TOKEN"
+
+	// VictorOpsAPIKey is the key of the Secret data containing the API key of
+	// the VictorOps REST endpoint integration
+	VictorOpsAPIKey = "VICTOROPS_API_KEY"
+
+	// VictorOpsRoutingKey is the key of the Secret data containing the VictorOps
+	// routing key alerts are sent with
+	VictorOpsRoutingKey = "VICTOROPS_ROUTING_KEY"
+
+	// SlackWebhookURL is the key of the Secret data containing the URL of a
+	// Slack incoming webhook. When set, Slack messages are posted to it instead
+	// of using the Slack token and channel ID.
+	SlackWebhookURL = "SLACK_WEBHOOK_URL"
+
+	// S3Bucket is the key of the Secret data containing the name of the S3
+	// bucket reports are uploaded to
+	S3Bucket = "S3_BUCKET"
+
+	// S3Region is the key of the Secret data containing the AWS region of the
+	// S3 bucket
+	S3Region = "S3_REGION"
+
+	// S3Prefix is the key of the Secret data containing the optional prefix
+	// of the keys reports are uploaded with (for instance audit/k8s-cleaner)
+	S3Prefix = "S3_PREFIX"
+
+	// S3Endpoint is the key of the Secret data containing the optional URL of
+	// an S3 compatible endpoint (for instance https://minio:9000). Buckets are
+	// then addressed path-style. Defaults to the AWS S3 endpoint of S3Region.
+	S3Endpoint = "S3_ENDPOINT"
+
+	// AWSAccessKeyID is the key of the Secret data containing the AWS access
+	// key ID. If not set, credentials of the IAM role of the k8s-cleaner service
+	// account (IRSA) are used.
+	AWSAccessKeyID = "AWS_ACCESS_KEY_ID"
+
+	// AWSSecretAccessKey is the key of the Secret data containing the AWS
+	// secret access key
+	AWSSecretAccessKey = "AWS_SECRET_ACCESS_KEY"
+
+	// AWSSessionToken is the key of the Secret data containing the optional
+	// session token of temporary AWS credentials
+	AWSSessionToken = "AWS_SESSION_TOKEN"
+
+	// TwilioAccountSID is the key of the Secret data containing the Twilio
+	// account SID
+	TwilioAccountSID = "TWILIO_ACCOUNT_SID"
+
+	// TwilioAuthToken is the key of the Secret data containing the Twilio
+	// auth token
+	TwilioAuthToken = "TWILIO_AUTH_TOKEN"
+
+	// TwilioFromNumber is the key of the Secret data containing the Twilio
+	// phone number SMS are sent from, in E.164 format (for instance +15005550006)
+	TwilioFromNumber = "TWILIO_FROM_NUMBER"
+
+	// TwilioToNumbers is the key of the Secret data containing the comma
+	// separated phone numbers, in E.164 format, SMS are sent to
+	TwilioToNumbers = "TWILIO_TO_NUMBERS"
+
+	// RedisAddress is the key of the Secret data containing the host:port
+	// address of the Redis server
+	RedisAddress = "REDIS_ADDRESS"
+
+	// RedisUsername is the key of the Secret data containing the optional
+	// username of the Redis ACL user
+	RedisUsername = "REDIS_USERNAME"
+
+	// RedisPassword is the key of the Secret data containing the optional
+	// Redis password
+	RedisPassword = "REDIS_PASSWORD"
+
+	// RedisKey is the key of the Secret data containing the name of the
+	// stream reports are added to or, in PubSub mode, of the channel they are
+	// published to
+	RedisKey = "REDIS_KEY"
+
+	// RedisTLS is the key of the Secret data which, when "true", makes the
+	// connection to Redis use TLS. TLS is also used when the Secret contains
+	// TLSCACert or a client certificate.
+	RedisTLS = "REDIS_TLS"
+
+	// TLSClientCert is the key of the Secret data containing the PEM encoded
+	// client certificate presented to endpoints requiring mutual TLS
+	TLSClientCert = "TLS_CLIENT_CERT"
+
+	// TLSClientKey is the key of the Secret data containing the PEM encoded
+	// private key of the client certificate
+	TLSClientKey = "TLS_CLIENT_KEY"
+
+	// TLSCACert is the key of the Secret data containing the PEM encoded CA
+	// bundle used to verify the server certificate of the notification endpoint
+	// (SplunkHEC, CloudEvents, Teams, Slack incoming webhook, Loki, GitLab,
+	// Elasticsearch and Redis). If not set, system roots are used.
+	TLSCACert = "TLS_CA_CERT"
+
+	// GitLabToken is the key of the Secret data containing the GitLab access
+	// token. It needs the api scope.
+	GitLabToken = "GITLAB_TOKEN"
+
+	// GitLabProject is the key of the Secret data containing the ID or the
+	// path (for instance platform/cleanups) of the GitLab project
+	GitLabProject = "GITLAB_PROJECT"
+
+	// GitLabURL is the key of the Secret data containing the optional URL of
+	// a self-managed GitLab instance. Defaults to https://gitlab.com.
+	GitLabURL = "GITLAB_URL"
+
+	// LokiURL is the key of the Secret data containing the Loki push URL
+	// (for instance http://loki:3100/loki/api/v1/push)
+	LokiURL = "LOKI_URL"
+
+	// LokiTenantID is the key of the Secret data containing the optional
+	// tenant, sent as X-Scope-OrgID header, of multi-tenant Loki instances
+	LokiTenantID = "LOKI_TENANT_ID"
+
+	// LokiUsername is the key of the Secret data containing the optional
+	// username used for basic authentication
+	LokiUsername = "LOKI_USERNAME"
+
+	// LokiPassword is the key of the Secret data containing the password
+	// used for basic authentication. Required when LokiUsername is set.
+	LokiPassword = "LOKI_PASSWORD"
+
+	// ElasticsearchURL is the key of the Secret data containing the URL of the
+	// Elasticsearch cluster (for instance https://elasticsearch:9200)
+	ElasticsearchURL = "ELASTICSEARCH_URL"
+
+	// ElasticsearchIndex is the key of the Secret data containing the index, or
+	// data stream, documents are indexed into
+	ElasticsearchIndex = "ELASTICSEARCH_INDEX"
+
+	// ElasticsearchAPIKey is the key of the Secret data containing the optional
+	// API key, base64 encoded as returned by Elasticsearch, used for authentication
+	ElasticsearchAPIKey = "ELASTICSEARCH_API_KEY"
+
+	// ElasticsearchUsername is the key of the Secret data containing the optional
+	// username used for basic authentication
+	ElasticsearchUsername = "ELASTICSEARCH_USERNAME"
+
+	// ElasticsearchPassword is the key of the Secret data containing the password
+	// used for basic authentication. Required when ElasticsearchUsername is set.
+	ElasticsearchPassword = "ELASTICSEARCH_PASSWORD"
+)
+
+// ResourceScope selects resources by scope
+// +kubebuilder:validation:Enum:=All;Namespaced;Cluster
+type ResourceScope string
+
+const (
+	// ResourceScopeAll selects all resources
+	ResourceScopeAll = ResourceScope("All")
+
+	// ResourceScopeNamespaced only selects namespaced resources
+	ResourceScopeNamespaced = ResourceScope("Namespaced")
+
+	// ResourceScopeCluster only selects cluster-scoped resources
+	ResourceScopeCluster = ResourceScope("Cluster")
+)
+
+// ReportField is a field of the resources listed in a report
+// +kubebuilder:validation:Enum:=apiVersion;kind;namespace;name;message;labels;annotations;diff;outcome;error;backup
+type ReportField string
+
+// Report fields are named after the JSON field of the resource in the report
+const (
+	ReportFieldAPIVersion  = ReportField("apiVersion")
+	ReportFieldKind        = ReportField("kind")
+	ReportFieldNamespace   = ReportField("namespace")
+	ReportFieldName        = ReportField("name")
+	ReportFieldMessage     = ReportField("message")
+	ReportFieldLabels      = ReportField("labels")
+	ReportFieldAnnotations = ReportField("annotations")
+	ReportFieldDiff        = ReportField("diff")
+	ReportFieldOutcome     = ReportField("outcome")
+	ReportFieldError       = ReportField("error")
+	ReportFieldBackup      = ReportField("backup")
+)
+
+// NamespaceRoute sends the resources of the namespaces it matches using its
+// own notification Secret
+type NamespaceRoute struct {
+	// Namespace is the regular expression (RE2 syntax, for instance "team-a|team-a-.*")
+	// the resource namespace must match. The expression must match the whole value.
+	// Cluster-scoped resources have an empty namespace.
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+
+	// NotificationRef is a reference to the Secret holding the details used to
+	// notify the resources of matching namespaces, for instance the Slack token
+	// and channel of a tenant
+	NotificationRef corev1.ObjectReference `json:"notificationRef"`
+}
+
+// ResourceFilter selects resources by kind and namespace
+type ResourceFilter struct {
+	// Include, when set, only keeps the resources matching it
+	// +optional
+	Include *ResourceFilterMatch `json:"include,omitempty"`
+
+	// Exclude, when set, removes the resources matching it, including the ones
+	// matching Include
+	// +optional
+	Exclude *ResourceFilterMatch `json:"exclude,omitempty"`
+}
+
+// ResourceFilterMatch matches resources whose kind and namespace match regular
+// expressions (RE2 syntax, for instance "Deployment|StatefulSet"). Expressions
+// must match the whole value. An empty expression matches any value.
+type ResourceFilterMatch struct {
+	// Kind is the regular expression the resource kind must match
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Namespace is the regular expression the resource namespace must match.
+	// Cluster-scoped resources have an empty namespace.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// SMTPReportDelivery specifies how the report is delivered in an email
+// +kubebuilder:validation:Enum:=Body;Attachment;BodyAndAttachment
+type SMTPReportDelivery string
+
+const (
+	// SMTPReportDeliveryBody sends the report in the email body
+	SMTPReportDeliveryBody = SMTPReportDelivery("Body")
+
+	// SMTPReportDeliveryAttachment attaches the report as an HTML file.
+	// Email body only contains a short summary.
+	SMTPReportDeliveryAttachment = SMTPReportDelivery("Attachment")
+
+	// SMTPReportDeliveryBodyAndAttachment sends the report in the email body
+	// and also attaches it as an HTML file
+	SMTPReportDeliveryBodyAndAttachment = SMTPReportDelivery("BodyAndAttachment")
+)
+
+// SMTPOptions contains options for SMTP notifications
+type SMTPOptions struct {
+	// ReportDelivery specifies how the report is delivered. When the report is
+	// attached, a self-contained HTML file named after the Cleaner instance and
+	// the time the report was generated is added to the email.
+	// +kubebuilder:default:=Body
+	// +optional
+	ReportDelivery SMTPReportDelivery `json:"reportDelivery,omitempty"`
+}
+
+// SlackOptions contains options for Slack notifications
+type SlackOptions struct {
+	// ThreadPeriod, when set, keeps a running log in a Slack thread: the first
+	// message is posted to the channel and messages of subsequent runs are
+	// posted as replies in its thread. Once ThreadPeriod has elapsed since the
+	// thread was started, the next message starts a new thread.
+	// +optional
+	ThreadPeriod *metav1.Duration `json:"threadPeriod,omitempty"`
+
+	// UploadReport, when set, posts a short summary message and uploads the
+	// full report as a JSON file in the thread of that message, instead of
+	// sending the report as message attachment (which is truncated for large
+	// reports). The Slack app needs the files:write scope.
+	// +kubebuilder:default:=false
+	// +optional
+	UploadReport bool `json:"uploadReport,omitempty"`
+
+	// Mentions, when set, mentions Slack users and user groups in messages of
+	// reports meeting its conditions, so that they are notified
+	// +optional
+	Mentions *SlackMentions `json:"mentions,omitempty"`
+
+	// RecordMessage, when set, stores the last message posted by the notification
+	// in the Cleaner status: its channel, timestamp and permalink. Operators can
+	// then link directly to the alert. Getting the permalink needs no additional
+	// scope.
+	// +kubebuilder:default:=false
+	// +optional
+	RecordMessage bool `json:"recordMessage,omitempty"`
+}
+
+// SlackMentions lists the Slack users and user groups mentioned in a message,
+// and the conditions a report must meet for them to be mentioned
+type SlackMentions struct {
+	// UserIDs lists IDs of Slack users (for instance "U024BE7LH"), mentioned
+	// as <@ID>
+	// +kubebuilder:validation:items:Pattern=`^[A-Z0-9]+$`
+	// +optional
+	UserIDs []string `json:"userIDs,omitempty"`
+
+	// GroupIDs lists IDs of Slack user groups (for instance "SAZ94GDB8"),
+	// mentioned as <!subteam^ID>
+	// +kubebuilder:validation:items:Pattern=`^[A-Z0-9]+$`
+	// +optional
+	GroupIDs []string `json:"groupIDs,omitempty"`
+
+	// MinResources is the minimum number of resources a report must contain
+	// for mentions to be added. Defaults to 1.
+	// +kubebuilder:default:=1
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinResources int32 `json:"minResources,omitempty"`
+
+	// Actions, when set, only adds mentions to reports of runs performing one
+	// of those actions (for instance Delete)
+	// +optional
+	Actions []Action `json:"actions,omitempty"`
+}
+
+// WebexFormat specifies how the Webex message is rendered
+// +kubebuilder:validation:Enum:=Markdown;Text;Card
+type WebexFormat string
+
+const (
+	// WebexFormatMarkdown sends the message as markdown
+	WebexFormatMarkdown = WebexFormat("Markdown")
+
+	// WebexFormatText sends the message as plain text, for spaces or bots
+	// rendering markdown inconsistently
+	WebexFormatText = WebexFormat("Text")
+
+	// WebexFormatCard sends the message as an adaptive card listing the action
+	// and the resources, with the markdown message as fallback for clients not
+	// rendering cards. The report file is not attached, as Webex does not
+	// accept files along with cards.
+	WebexFormatCard = WebexFormat("Card")
+)
+
+// WebexOptions contains options for Webex notifications
+type WebexOptions struct {
+	// Format controls whether the message is sent as markdown, as plain text
+	// or as an adaptive card. Default is Markdown.
+	// +kubebuilder:default:=Markdown
+	// +optional
+	Format WebexFormat `json:"format,omitempty"`
+}
+
+// SplunkOptions contains options for Splunk HEC notifications
+type SplunkOptions struct {
+	// SourceType is the Splunk sourcetype set on each event
+	// +kubebuilder:default:=k8s-cleaner
+	// +optional
+	SourceType string `json:"sourceType,omitempty"`
+
+	// Index is the Splunk index events are sent to. If not set, the default
+	// index configured for the HEC token is used.
+	// +optional
+	Index string `json:"index,omitempty"`
+
+	// InsecureSkipVerify disables verification of the HEC server certificate.
+	// Use only for testing or with self-signed certificates.
+	// +kubebuilder:default:=false
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+// EventOptions contains options for Event notifications
+type EventOptions struct {
+	// RecordOnResources, when set, records an Event also on each resource
+	// matched by the Cleaner instance (up to a maximum number per run)
+	// +kubebuilder:default:=false
+	// +optional
+	RecordOnResources bool `json:"recordOnResources,omitempty"`
+}
+
+// CloudEventsContentMode specifies how a CloudEvent is encoded in the HTTP request
+// +kubebuilder:validation:Enum:=Structured;Binary
+type CloudEventsContentMode string
+
+const (
+	// CloudEventsContentModeStructured sends the whole event, attributes and
+	// data, as a JSON document in the request body
+	CloudEventsContentModeStructured = CloudEventsContentMode("Structured")
+
+	// CloudEventsContentModeBinary sends event attributes as ce- prefixed HTTP
+	// headers and only the data in the request body
+	CloudEventsContentModeBinary = CloudEventsContentMode("Binary")
+)
+
+// CloudEventsOptions contains options for CloudEvents notifications
+type CloudEventsOptions struct {
+	// ContentMode specifies how the CloudEvent is encoded in the HTTP request
+	// +kubebuilder:default:=Structured
+	// +optional
+	ContentMode CloudEventsContentMode `json:"contentMode,omitempty"`
+}
+
+// RedisMode specifies how reports are delivered to Redis
+// +kubebuilder:validation:Enum:=Stream;PubSub
+type RedisMode string
+
+const (
+	// RedisModeStream adds reports to a stream (XADD), where they are kept
+	// until consumers read them
+	RedisModeStream = RedisMode("Stream")
+
+	// RedisModePubSub publishes reports to a channel (PUBLISH). Only clients
+	// subscribed when the report is published receive it.
+	RedisModePubSub = RedisMode("PubSub")
+)
+
+// RedisOptions contains options for Redis notifications
+type RedisOptions struct {
+	// Mode specifies whether reports are added to a stream or published to a
+	// channel
+	// +kubebuilder:default:=Stream
+	// +optional
+	Mode RedisMode `json:"mode,omitempty"`
+
+	// MaxLen, when set, trims the stream to approximately this number of
+	// entries when adding a report. Only used in Stream mode.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxLen int64 `json:"maxLen,omitempty"`
+}
+
+// GitLabOptions contains options for GitLab notifications
+type GitLabOptions struct {
+	// MergeRequestIID, when set, is the IID of the merge request a note is
+	// posted to, instead of opening an issue
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MergeRequestIID int64 `json:"mergeRequestIID,omitempty"`
+
+	// Labels are added to the issues opened by the notification
+	// +optional
+	Labels []string `json:"labels,omitempty"`
+}
+
+// LogLevel specifies the verbosity a Log notification writes reports at
+// +kubebuilder:validation:Enum:=Info;Debug;Verbose
+type LogLevel string
+
+const (
+	// LogLevelInfo writes reports at the default verbosity
+	LogLevelInfo = LogLevel("Info")
+
+	// LogLevelDebug writes reports only when the controller runs with
+	// debug verbosity or higher
+	LogLevelDebug = LogLevel("Debug")
+
+	// LogLevelVerbose writes reports only when the controller runs with
+	// verbose verbosity
+	LogLevelVerbose = LogLevel("Verbose")
+)
+
+// LogOptions contains options for Log notifications
+type LogOptions struct {
+	// Level is the verbosity the report is written at
+	// +kubebuilder:default:=Info
+	// +optional
+	Level LogLevel `json:"level,omitempty"`
+
+	// Format of the report
+	// +kubebuilder:default:=JSON
+	// +optional
+	Format ReportFormat `json:"format,omitempty"`
+}
+
+// S3ServerSideEncryption specifies how S3 encrypts uploaded reports
+// +kubebuilder:validation:Enum:=AES256;"aws:kms"
+type S3ServerSideEncryption string
+
+const (
+	// S3ServerSideEncryptionAES256 encrypts reports with S3 managed keys (SSE-S3)
+	S3ServerSideEncryptionAES256 = S3ServerSideEncryption("AES256")
+
+	// S3ServerSideEncryptionKMS encrypts reports with AWS KMS keys (SSE-KMS)
+	S3ServerSideEncryptionKMS = S3ServerSideEncryption("aws:kms")
+)
+
+// ReportFormat specifies the format of a report written to a file
+// +kubebuilder:validation:Enum:=JSON;CSV;YAML
+type ReportFormat string
+
+const (
+	// ReportFormatJSON writes the report as JSON
+	ReportFormatJSON = ReportFormat("JSON")
+
+	// ReportFormatCSV writes the report as CSV, one line per resource
+	ReportFormatCSV = ReportFormat("CSV")
+
+	// ReportFormatYAML writes the report as YAML, using the same field names
+	// as JSON
+	ReportFormatYAML = ReportFormat("YAML")
+)
+
+// FileOptions contains options for File notifications
+type FileOptions struct {
+	// Path is the directory, inside the k8s-cleaner pod, where reports are
+	// written. It is created if it does not exist. Usually it is backed by a
+	// mounted PersistentVolume.
+	// Each report is written to a file named after the Cleaner instance and the
+	// time the report was generated.
+	// +kubebuilder:validation:MinLength=1
+	Path string `json:"path"`
+
+	// Format of the report
+	// +kubebuilder:default:=JSON
+	// +optional
+	Format ReportFormat `json:"format,omitempty"`
+
+	// MaxFiles is the maximum number of reports kept for this Cleaner instance.
+	// When exceeded, oldest reports are removed. If not set, all reports are kept.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxFiles *int32 `json:"maxFiles,omitempty"`
+
+	// MaxAge is the maximum age of reports kept for this Cleaner instance.
+	// Older reports are removed. If not set, reports are kept regardless of age.
+	// +optional
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+}
+
+// S3Options contains options for S3 notifications
+type S3Options struct {
+	// Format of the report
+	// +kubebuilder:default:=JSON
+	// +optional
+	Format ReportFormat `json:"format,omitempty"`
+
+	// Gzip, when set, compresses the report with gzip. ".gz" is appended to
+	// the object key.
+	// +optional
+	Gzip bool `json:"gzip,omitempty"`
+
+	// ServerSideEncryption, when set, requests S3 to encrypt the report at rest.
+	// If not set, the default encryption of the bucket applies.
+	// +optional
+	ServerSideEncryption S3ServerSideEncryption `json:"serverSideEncryption,omitempty"`
+
+	// KMSKeyID is the ID of the AWS KMS key used when ServerSideEncryption is
+	// aws:kms. If not set, the AWS managed key is used.
+	// +optional
+	KMSKeyID string `json:"kmsKeyID,omitempty"`
+}
+
+// ReportResourceMetadata selects labels and annotations of matching resources
+// to include in reports. Each entry is either a key or, when ending with "*",
+// a prefix matching all keys starting with it (e.g. "app.kubernetes.io/*").
+type ReportResourceMetadata struct {
+	// Labels lists label keys or prefixes to include
+	// +kubebuilder:validation:MaxItems=20
+	// +optional
+	Labels []string `json:"labels,omitempty"`
+
+	// Annotations lists annotation keys or prefixes to include
+	// +kubebuilder:validation:MaxItems=20
+	// +optional
+	Annotations []string `json:"annotations,omitempty"`
+}
+
+// ReportRedaction masks sensitive substrings of reports sent outside the cluster
+type ReportRedaction struct {
+	// Patterns lists regular expressions (RE2 syntax, for instance
+	// "db-password-.*"). Substrings matching any of them are replaced in
+	// resource names, messages, errors, diffs, label and annotation values and
+	// in the Cleaner spec included in reports.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=20
+	Patterns []string `json:"patterns"`
+
+	// Replacement is the text matching substrings are replaced with
+	// +kubebuilder:default:="[REDACTED]"
+	// +optional
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// Weekday is a day of the week
+// +kubebuilder:validation:Enum:=Monday;Tuesday;Wednesday;Thursday;Friday;Saturday;Sunday
+type Weekday string
+
+// NotificationWindowPolicy specifies what happens to reports of runs outside
+// the active window of a notification
+// +kubebuilder:validation:Enum:=Suppress;Queue
+type NotificationWindowPolicy string
+
+const (
+	// NotificationWindowPolicySuppress drops reports of runs outside the window
+	NotificationWindowPolicySuppress = NotificationWindowPolicy("Suppress")
+
+	// NotificationWindowPolicyQueue accumulates reports of runs outside the
+	// window. They are sent, combined, with the first run inside the window.
+	NotificationWindowPolicyQueue = NotificationWindowPolicy("Queue")
 )
 
+// NotificationFailurePolicy specifies what happens when a notification cannot
+// be delivered
+// +kubebuilder:validation:Enum:=Fail;Retry;Ignore
+type NotificationFailurePolicy string
+
+const (
+	// NotificationFailurePolicyFail returns the delivery error, so the run is
+	// reported as failed
+	NotificationFailurePolicyFail = NotificationFailurePolicy("Fail")
+
+	// NotificationFailurePolicyRetry sends the notification again, with
+	// exponential backoff, before returning the delivery error
+	NotificationFailurePolicyRetry = NotificationFailurePolicy("Retry")
+
+	// NotificationFailurePolicyIgnore only logs the delivery error and records
+	// it in the Cleaner status. The run is not affected.
+	NotificationFailurePolicyIgnore = NotificationFailurePolicy("Ignore")
+)
+
+// NotificationWindow defines when a notification is allowed to be sent.
+// Days and hours are evaluated in the notification Timezone.
+type NotificationWindow struct {
+	// Days lists the days of the week the window is open. When not set, the
+	// window is open every day.
+	// +optional
+	Days []Weekday `json:"days,omitempty"`
+
+	// StartHour is the hour (0-23) the window opens
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=23
+	// +optional
+	StartHour int32 `json:"startHour,omitempty"`
+
+	// EndHour is the hour (1-24) the window closes. When not greater than
+	// StartHour, the window spans midnight (for instance from 22 to 6) and
+	// Days refer to the day the window opens. Defaults to 24.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=24
+	// +kubebuilder:default:=24
+	// +optional
+	EndHour int32 `json:"endHour,omitempty"`
+
+	// Policy specifies what happens to reports of runs outside the window.
+	// Defaults to Suppress.
+	// +kubebuilder:default:=Suppress
+	// +optional
+	Policy NotificationWindowPolicy `json:"policy,omitempty"`
+}
+
+// DigestOptions contains options to send a notification as a digest
+type DigestOptions struct {
+	// Interval is the time between two digests. Reports of all runs since the
+	// last digest are combined and sent once Interval has elapsed, whether or
+	// not the Cleaner runs again.
+	Interval metav1.Duration `json:"interval"`
+}
+
 type Notification struct {
 	// Name of the notification check.
 	// Must be a DNS_LABEL and unique within the Cleaner.
@@ -154,8 +936,263 @@ type Notification struct {
 
 	// NotificationRef is a reference to a notification-specific resource that holds
 	// the details for the notification.
+	// A Secret shared by several notification types can hold the details of each
+	// type under keys prefixed by the lowercased type (e.g. slack.token).
+	// When not set, the details are read from the controller environment variables
+	// prefixed by the notification name, uppercased and with any character other than
+	// letters and digits replaced by an underscore (e.g. PROD_SLACK_SLACK_TOKEN).
 	// +optional
 	NotificationRef *corev1.ObjectReference `json:"notificationRef,omitempty"`
+
+	// FailoverNotificationRefs is an ordered list of references to additional
+	// notification-specific resources. When delivery using NotificationRef fails
+	// because of an authentication or permission error, those are tried in order
+	// till one succeeds.
+	// Currently only Slack notifications support failover.
+	// +optional
+	FailoverNotificationRefs []corev1.ObjectReference `json:"failoverNotificationRefs,omitempty"`
+
+	// Metadata is a set of key/value pairs (for instance environment, team or
+	// cost-center) added to the notification payload where the channel supports
+	// it: Slack attachment fields, Teams facts, Discord embed fields and Splunk
+	// HEC indexed fields.
+	// +optional
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Username, when set, overrides the name messages are posted with, so that
+	// different Cleaner instances appear as different senders in the same channel.
+	// Slack posts messages with this name (the Slack app needs the
+	// chat:write.customize scope). Discord bots cannot change their name per
+	// message, so the name is shown as embed author instead.
+	// +kubebuilder:validation:MaxLength=80
+	// +optional
+	Username string `json:"username,omitempty"`
+
+	// IconURL, when set, overrides the icon messages are posted with. Used by
+	// Slack and, as embed author icon when Username is set, by Discord.
+	// +optional
+	IconURL string `json:"iconURL,omitempty"`
+
+	// IconEmoji, when set, overrides the icon of Slack messages with an emoji
+	// (for instance ":broom:"). It takes precedence over IconURL.
+	// +optional
+	IconEmoji string `json:"iconEmoji,omitempty"`
+
+	// SMTP contains options used only when Type is SMTP
+	// +optional
+	SMTP *SMTPOptions `json:"smtp,omitempty"`
+
+	// Slack contains options used only when Type is Slack
+	// +optional
+	Slack *SlackOptions `json:"slack,omitempty"`
+
+	// Webex contains options used only when Type is Webex
+	// +optional
+	Webex *WebexOptions `json:"webex,omitempty"`
+
+	// Splunk contains options used only when Type is SplunkHEC
+	// +optional
+	Splunk *SplunkOptions `json:"splunk,omitempty"`
+
+	// Event contains options used only when Type is Event
+	// +optional
+	Event *EventOptions `json:"event,omitempty"`
+
+	// File contains options used only when Type is File
+	// +optional
+	File *FileOptions `json:"file,omitempty"`
+
+	// CloudEvents contains options used only when Type is CloudEvents
+	// +optional
+	CloudEvents *CloudEventsOptions `json:"cloudEvents,omitempty"`
+
+	// S3 contains options used only when Type is S3
+	// +optional
+	S3 *S3Options `json:"s3,omitempty"`
+
+	// Redis contains options used only when Type is Redis
+	// +optional
+	Redis *RedisOptions `json:"redis,omitempty"`
+
+	// GitLab contains options used only when Type is GitLab
+	// +optional
+	GitLab *GitLabOptions `json:"gitLab,omitempty"`
+
+	// Log contains options used only when Type is Log
+	// +optional
+	Log *LogOptions `json:"log,omitempty"`
+
+	// Timezone is the IANA name of the time zone (e.g. "Europe/Rome") used
+	// to format timestamps in this notification. Defaults to UTC.
+	// +optional
+	Timezone string `json:"timezone,omitempty"`
+
+	// Digest, when set, accumulates reports across runs and sends a single
+	// notification combining all of them every Digest.Interval, instead of
+	// one notification per run. CleanerReport notifications ignore it.
+	// +optional
+	Digest *DigestOptions `json:"digest,omitempty"`
+
+	// ActiveWindow, when set, restricts the days and hours the notification is
+	// sent. Reports of runs outside the window are dropped or queued according
+	// to its Policy. Failure notifications are always sent right away, resolved
+	// and threshold exceeded notifications are dropped outside the window.
+	// +optional
+	ActiveWindow *NotificationWindow `json:"activeWindow,omitempty"`
+
+	// NotifyOnFailure, when set, sends a notification when a Cleaner run fails
+	// (for instance because listing or deleting resources is forbidden). The
+	// notification contains the error along with the resources processed before
+	// the failure. Failure notifications are never accumulated in a digest.
+	// When not set, only the resources processed are reported.
+	// +optional
+	NotifyOnFailure bool `json:"notifyOnFailure,omitempty"`
+
+	// NotifyOnResolved, when set, sends a resolved notification the first time
+	// a run matches no resource after a run which matched some. This confirms a
+	// cleanup is complete, or reveals a selector which no longer matches.
+	// Resolved notifications are never accumulated in a digest.
+	// +optional
+	NotifyOnResolved bool `json:"notifyOnResolved,omitempty"`
+
+	// WarningThreshold, when set, sends a threshold exceeded notification the
+	// first time a run matches at least this number of resources after a run
+	// which matched fewer. This gives early warning of resources building up,
+	// for instance with a Scan Cleaner ahead of a Delete one. The threshold
+	// exceeded notification takes the place of the report of the run and is
+	// never accumulated in a digest.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	WarningThreshold *int32 `json:"warningThreshold,omitempty"`
+
+	// OnFailure specifies what happens when the notification cannot be
+	// delivered. With Fail, the delivery error fails the run: resources are not
+	// stored and, for instance, a resolved notification is sent again by next
+	// run. Retry sends the notification again a few times before failing. Use
+	// Ignore when notifications are best effort: the cleanup already happened.
+	// Defaults to Fail.
+	// +kubebuilder:default:=Fail
+	// +optional
+	OnFailure NotificationFailurePolicy `json:"onFailure,omitempty"`
+
+	// ChannelTemplate, when set, routes each resource to the channel resulting
+	// from this Go template, evaluated against the resource. Available fields are
+	// .Kind, .Namespace, .Name, .Labels and .Annotations (for instance
+	// "team-{{ .Labels.team }}"). Resources are grouped by channel and one message
+	// is sent per channel. Resources for which the template yields an empty string
+	// are sent to the channel of the notification credentials.
+	// Supported by Slack (channel ID), Discord (channel ID) and Webex (room ID).
+	// +optional
+	ChannelTemplate string `json:"channelTemplate,omitempty"`
+
+	// NamespaceRoutes, when set, notifies the resources of each namespace using
+	// the Secret of the first route matching it, so each tenant is notified with
+	// its own credentials. Resources are grouped by Secret and one report, only
+	// listing its resources, is sent per Secret. Resources matching no route are
+	// notified using NotificationRef. Secrets with no resource are not notified:
+	// when the run matched none, only NotificationRef is.
+	// Not supported along with Digest or an ActiveWindow with Queue policy, nor
+	// by CleanerReport and Event notifications.
+	// +optional
+	NamespaceRoutes []NamespaceRoute `json:"namespaceRoutes,omitempty"`
+
+	// LinkTemplate, when set, adds links to the message, for instance to the
+	// dashboard of the Cleaner or of the namespace. It is a Go template evaluated
+	// against each resource, or once when there is none. Available fields are
+	// .Cleaner, .Action, .RunID, .Kind, .Namespace, .Name, .Labels and
+	// .Annotations (for instance
+	// "https://grafana.example.com/d/cleaner?var-namespace={{ .Namespace }}").
+	// Distinct http(s) URLs, up to five, are rendered as Slack buttons and Teams
+	// OpenUrl actions. Discord links the embed title to the first URL and lists
+	// the others in the embed.
+	// +optional
+	LinkTemplate string `json:"linkTemplate,omitempty"`
+
+	// MessageTemplate, when set, is the Go template of the text sent along with
+	// the report of a run. Available fields are .Cleaner, .Action, .Count (the
+	// number of resources in the report) and .RunID (for instance
+	// "[{{ .Action }}] {{ .Cleaner }}: {{ .Count }} resource(s)"). It overrides
+	// the controller default message template. Failure and resolved messages
+	// are not affected.
+	// +optional
+	MessageTemplate string `json:"messageTemplate,omitempty"`
+
+	// AttachmentNameTemplate, when set, is the Go template of the name of the
+	// report file attached by Discord, Webex and SMTP notifications and written
+	// by File notifications. Available fields are .Cleaner, .Action, .Count and
+	// .Timestamp (UTC, formatted as 20060102-150405), for instance
+	// "{{ .Cleaner }}-{{ .Action }}-{{ .Timestamp }}". Characters not allowed in
+	// file names are replaced with '-', and the extension of the report format
+	// is appended.
+	// +kubebuilder:validation:MaxLength=253
+	// +optional
+	AttachmentNameTemplate string `json:"attachmentNameTemplate,omitempty"`
+
+	// IncludeRawReport, when set to false, omits the JSON report from Slack,
+	// Discord, Webex and SMTP notifications, which then only carry the rendered
+	// summary. Defaults to true.
+	// +kubebuilder:default:=true
+	// +optional
+	IncludeRawReport *bool `json:"includeRawReport,omitempty"`
+
+	// IncludeKubectlCommands, when set, adds to the message a kubectl command to
+	// inspect each resource of the report, up to ten. The command restoring the
+	// backup of each resource, when Backup is set, or, when the Cleaner deletes
+	// resources and StoreResourcePath is set, the path of its stored copy is
+	// listed as well, so it can be restored.
+	// Failure, resolved and threshold exceeded messages are not affected.
+	// +optional
+	IncludeKubectlCommands bool `json:"includeKubectlCommands,omitempty"`
+
+	// IncludeDelta, when set, adds to the message the resources matched by the
+	// run but not by the previous one, and the ones matched by the previous run
+	// only, up to ten each. Previous run is the report stored in the Report
+	// instance, so the Cleaner needs a CleanerReport notification. The full list
+	// of resources is still reported.
+	// Failure, resolved and threshold exceeded messages are not affected.
+	// +optional
+	IncludeDelta bool `json:"includeDelta,omitempty"`
+
+	// Fields, when set, only includes those fields of each resource in the
+	// report sent by this notification (for instance namespace, kind and name),
+	// the others are omitted from the payload. Counts per kind and per namespace
+	// are only included when those fields are. Defaults to all fields.
+	// The Report instance of CleanerReport notifications always contains every
+	// field.
+	// +listType=set
+	// +optional
+	Fields []ReportField `json:"fields,omitempty"`
+
+	// SummaryAsCodeBlock, when set, renders the resource summary in the body of
+	// Slack and Discord messages as a markdown code block, and in Teams messages
+	// as a code block element, so long resource lists read as monospace text.
+	// The code block is truncated to fit the channel message limits.
+	// +optional
+	SummaryAsCodeBlock bool `json:"summaryAsCodeBlock,omitempty"`
+
+	// DisableAttachments, when set, makes Slack, Discord and Webex notifications
+	// never upload a file, for channels whose data-loss-prevention policies forbid
+	// it. The report is sent inline instead, truncated to fit the message, and
+	// Slack UploadReport is ignored.
+	// +optional
+	DisableAttachments bool `json:"disableAttachments,omitempty"`
+
+	// ResourceScope limits the resources included in the report of this
+	// notification to namespaced or cluster-scoped ones. Defaults to All.
+	// +kubebuilder:default:=All
+	// +optional
+	ResourceScope ResourceScope `json:"resourceScope,omitempty"`
+
+	// ResourceFilter, when set, limits the resources included in the report of
+	// this notification by kind and namespace. It is applied along with ResourceScope.
+	// +optional
+	ResourceFilter *ResourceFilter `json:"resourceFilter,omitempty"`
+
+	// Enabled, when set to false, mutes the notification while preserving its
+	// configuration. Defaults to true.
+	// +kubebuilder:default:=true
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
 }
 
 // CleanerSpec defines the desired state of Cleaner
@@ -169,11 +1206,26 @@ type CleanerSpec struct {
 	// +kubebuilder:default:=Delete
 	Action Action `json:"action,omitempty"`
 
+	// Reason is a short, human readable, description of the policy the Cleaner
+	// enforces, for instance "expired PR preview environments". It is included
+	// in reports and prefixes the message of every notification, so it is shown
+	// in Slack messages, Teams and Webex titles and email subjects.
+	// +kubebuilder:validation:MaxLength=256
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
 	// DeleteOption is some configuration that modifies options for a delete request.
 	// This will be used only when action is delete
 	// +optional
 	DeleteOptions *DeleteOptions `json:"deleteOptions,omitempty"`
 
+	// Backup, when set, makes the Delete action back up each matched resource
+	// before deleting it. Resources which cannot be backed up are not deleted,
+	// and are reported as failed. The location of each backup, and the command
+	// restoring it, are included in reports.
+	// +optional
+	Backup *ResourceBackup `json:"backup,omitempty"`
+
 	// Transform contains a function "transform" in lua language.
 	// When Action is set to *Transform*, this function will be invoked
 	// and be passed one of the object selected based on
@@ -190,6 +1242,13 @@ type CleanerSpec struct {
 	// +optional
 	StartingDeadlineSeconds *int64 `json:"startingDeadlineSeconds,omitempty"`
 
+	// StalenessWatchdog, when set, sends a notification through all configured
+	// notifications when the Cleaner has not run for longer than its schedule
+	// interval multiplied by a factor (for instance because the controller was
+	// down).
+	// +optional
+	StalenessWatchdog *StalenessWatchdog `json:"stalenessWatchdog,omitempty"`
+
 	// Notification is a list of source of events to evaluate.
 	// +patchMergeKey=name
 	// +patchStrategy=merge,retainKeys
@@ -198,10 +1257,51 @@ type CleanerSpec struct {
 	// +optional
 	Notifications []Notification `json:"notifications,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
 
+	// ResourceThrottling, when set, mentions each matching resource in
+	// notifications once per cooldown. Resources matched again within the
+	// cooldown are collapsed into a "still present" note, so notifications
+	// highlight new matches. CleanerReport notifications always list every resource.
+	// +optional
+	ResourceThrottling *ResourceThrottling `json:"resourceThrottling,omitempty"`
+
+	// ReportResourceMetadata selects labels and annotations of matching resources
+	// included in reports, and so in every notification. By default none is included.
+	// +optional
+	ReportResourceMetadata *ReportResourceMetadata `json:"reportResourceMetadata,omitempty"`
+
+	// ReportRedaction, when set, masks sensitive substrings of reports before
+	// they are sent by notifications. The Report instance (CleanerReport) and
+	// Kubernetes Events, which stay in the cluster, keep the full detail.
+	// +optional
+	ReportRedaction *ReportRedaction `json:"reportRedaction,omitempty"`
+
+	// DisableReport, when set, prevents the Report instance of this Cleaner from
+	// being created: CleanerReport notifications are skipped and reports too large
+	// for a channel are not stored. Other notifications are sent as usual.
+	// +optional
+	DisableReport bool `json:"disableReport,omitempty"`
+
+	// ReportHistoryLimit, when set, keeps in the Report status the summaries
+	// (time, run ID and resource counts) of the last ReportHistoryLimit runs
+	// whose report was stored, while the Report spec holds the latest report.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	ReportHistoryLimit int32 `json:"reportHistoryLimit,omitempty"`
+
 	// StoreResources will store full resources in this directory.
 	// Must be a volume where Cleaner can dump all matching resources.
 	// +optional
 	StoreResourcePath string `json:"storeResourcePath,omitempty"`
+
+	// IncludeSpecInReport, when set, includes in reports the schedule, action,
+	// resource selectors, Lua functions and delete options of the Cleaner, so
+	// reviewers can tell which rules produced a cleanup. Notifications are
+	// never included. ReportRedaction patterns are applied to the included
+	// selectors and Lua functions.
+	// +kubebuilder:default:=false
+	// +optional
+	IncludeSpecInReport bool `json:"includeSpecInReport,omitempty"`
 }
 
 // CleanerStatus defines the observed state of Cleaner
@@ -214,9 +1314,229 @@ type CleanerStatus struct {
 	// +optional
 	LastRunTime *metav1.Time `json:"lastRunTime,omitempty"`
 
+	// LastRunID is the ID of the last processed run. It matches the run ID
+	// included in notifications and logs for that run.
+	// +optional
+	LastRunID string `json:"lastRunID,omitempty"`
+
+	// LastMatchCount is the number of resources processed by the last
+	// successful run. It is used to detect when a Cleaner goes from matching
+	// resources to matching none.
+	// +optional
+	LastMatchCount *int32 `json:"lastMatchCount,omitempty"`
+
+	// LastStaleNotificationTime is when the last stale notification was sent.
+	// A single stale notification is sent till the Cleaner runs again.
+	// +optional
+	LastStaleNotificationTime *metav1.Time `json:"lastStaleNotificationTime,omitempty"`
+
 	// FailureMessage provides more information about the error, if
 	// any occurred
 	FailureMessage *string `json:"failureMessage,omitempty"`
+
+	// NotifiedValidationError is the last configuration error notified for
+	// this Cleaner. Each distinct error is notified once. It is cleared once
+	// the Cleaner is valid.
+	// +optional
+	NotifiedValidationError string `json:"notifiedValidationError,omitempty"`
+
+	// NotificationDigests contains the reports accumulated, since last digest
+	// was sent, for each notification with Digest set
+	// +listType=map
+	// +listMapKey=notificationName
+	// +optional
+	NotificationDigests []NotificationDigest `json:"notificationDigests,omitempty"`
+
+	// NotificationStatuses contains the delivery state of notifications which
+	// failed
+	// +listType=map
+	// +listMapKey=notificationName
+	// +optional
+	NotificationStatuses []NotificationStatus `json:"notificationStatuses,omitempty"`
+
+	// NotificationOutcomes contains, for each notification, the outcome of the
+	// last run which notified
+	// +listType=map
+	// +listMapKey=notificationName
+	// +optional
+	NotificationOutcomes []NotificationOutcome `json:"notificationOutcomes,omitempty"`
+
+	// NotifiedResources contains, when ResourceThrottling is set, the resources
+	// matched by the last run along with when they were first matched and last
+	// notified
+	// +optional
+	NotifiedResources []NotifiedResource `json:"notifiedResources,omitempty"`
+
+	// SlackThreads contains the Slack threads messages of notifications with
+	// ThreadPeriod set are posted to
+	// +listType=map
+	// +listMapKey=notificationName
+	// +optional
+	SlackThreads []SlackThread `json:"slackThreads,omitempty"`
+
+	// SlackMessages contains the last message posted by notifications with
+	// RecordMessage set
+	// +listType=map
+	// +listMapKey=notificationName
+	// +optional
+	SlackMessages []SlackMessage `json:"slackMessages,omitempty"`
+
+	// Conditions contains the current conditions of the Cleaner instance
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	// ConditionTypeNotificationDegraded is True when at least one notification
+	// is skipped because it failed too many consecutive times
+	ConditionTypeNotificationDegraded = "NotificationDegraded"
+)
+
+// SlackThread identifies the Slack thread a notification posts to
+type SlackThread struct {
+	// NotificationName is the name of the notification
+	NotificationName string `json:"notificationName"`
+
+	// ChannelID is the Slack channel the thread belongs to
+	ChannelID string `json:"channelID"`
+
+	// Timestamp is the timestamp of the message which started the thread
+	Timestamp string `json:"timestamp"`
+
+	// StartTime is the time the thread was started
+	StartTime metav1.Time `json:"startTime"`
+}
+
+// SlackMessage identifies a message posted by a Slack notification
+type SlackMessage struct {
+	// NotificationName is the name of the notification
+	NotificationName string `json:"notificationName"`
+
+	// ChannelID is the Slack channel the message was posted to
+	ChannelID string `json:"channelID"`
+
+	// Timestamp is the timestamp of the message. Along with ChannelID, it
+	// identifies the message in the Slack API, for instance to fetch its
+	// reactions.
+	Timestamp string `json:"timestamp"`
+
+	// Permalink is the URL of the message. It is empty when Slack could not
+	// return one.
+	// +optional
+	Permalink string `json:"permalink,omitempty"`
+
+	// PostTime is the time the message was posted
+	PostTime metav1.Time `json:"postTime"`
+}
+
+// NotifiedResource tracks when a resource matched by consecutive runs was
+// last notified
+type NotifiedResource struct {
+	// APIVersion is the API version of the resource
+	APIVersion string `json:"apiVersion"`
+
+	// Kind is the kind of the resource
+	Kind string `json:"kind"`
+
+	// Namespace is the namespace of the resource. Empty for cluster wide resources.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name is the name of the resource
+	Name string `json:"name"`
+
+	// FirstMatched is when the resource was first matched by a run
+	FirstMatched metav1.Time `json:"firstMatched"`
+
+	// LastNotified is when the resource was last listed in notifications
+	LastNotified metav1.Time `json:"lastNotified"`
+}
+
+// NotificationOutcomeType is the outcome of a notification for a run
+// +kubebuilder:validation:Enum:=Delivered;Failed;Skipped;Batched;Queued
+type NotificationOutcomeType string
+
+const (
+	// NotificationOutcomeDelivered indicates the notification was delivered or,
+	// for digests, the report was accumulated
+	NotificationOutcomeDelivered = NotificationOutcomeType("Delivered")
+
+	// NotificationOutcomeFailed indicates the notification could not be delivered
+	NotificationOutcomeFailed = NotificationOutcomeType("Failed")
+
+	// NotificationOutcomeSkipped indicates the notification was not sent, for
+	// instance because it is disabled or suspended
+	NotificationOutcomeSkipped = NotificationOutcomeType("Skipped")
+
+	// NotificationOutcomeBatched indicates the report was added to the batch of
+	// the notification target, delivered later
+	NotificationOutcomeBatched = NotificationOutcomeType("Batched")
+
+	// NotificationOutcomeQueued indicates the run was outside the notification
+	// active window and the report was queued till the window opens
+	NotificationOutcomeQueued = NotificationOutcomeType("Queued")
+)
+
+// NotificationOutcome contains the outcome of a notification for a run
+type NotificationOutcome struct {
+	// NotificationName is the name of the notification
+	NotificationName string `json:"notificationName"`
+
+	// Outcome is the outcome of the notification
+	Outcome NotificationOutcomeType `json:"outcome"`
+
+	// Message provides more information about the outcome, for instance the
+	// error of a failed delivery or why the notification was skipped
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// NotificationStatus contains the delivery state of a notification
+type NotificationStatus struct {
+	// NotificationName is the name of the notification
+	NotificationName string `json:"notificationName"`
+
+	// ConsecutiveFailures is the number of consecutive runs the notification
+	// failed to be delivered
+	ConsecutiveFailures int32 `json:"consecutiveFailures"`
+
+	// LastFailureTime is the time of the last failure
+	// +optional
+	LastFailureTime *metav1.Time `json:"lastFailureTime,omitempty"`
+
+	// LastFailureMessage is the error of the last failure
+	// +optional
+	LastFailureMessage string `json:"lastFailureMessage,omitempty"`
+
+	// SuspendedUntil, when set, is the time until the notification is skipped
+	// because it failed too many consecutive times. After that time, delivery
+	// is attempted again.
+	// +optional
+	SuspendedUntil *metav1.Time `json:"suspendedUntil,omitempty"`
+}
+
+// NotificationDigest contains reports accumulated for a notification since
+// last digest was sent
+type NotificationDigest struct {
+	// NotificationName is the name of the notification
+	NotificationName string `json:"notificationName"`
+
+	// Since is the time the accumulation started
+	Since metav1.Time `json:"since"`
+
+	// Runs is the number of runs accumulated
+	Runs int32 `json:"runs"`
+
+	// ResourceInfo contains resources of all accumulated runs
+	// +optional
+	ResourceInfo []ResourceInfo `json:"resourceInfo,omitempty"`
+
+	// OmittedResources is the number of resources not accumulated because
+	// the limit was reached
+	// +optional
+	OmittedResources int32 `json:"omittedResources,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -21,6 +21,22 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// ResourceOutcome is the outcome of the action taken on a resource
+// +kubebuilder:validation:Enum:=Succeeded;Failed;Skipped
+type ResourceOutcome string
+
+const (
+	// ResourceOutcomeSucceeded means the action was taken on the resource
+	ResourceOutcomeSucceeded = ResourceOutcome("Succeeded")
+
+	// ResourceOutcomeFailed means the action failed on the resource
+	ResourceOutcomeFailed = ResourceOutcome("Failed")
+
+	// ResourceOutcomeSkipped means no action was needed, for instance because
+	// the resource was already gone
+	ResourceOutcomeSkipped = ResourceOutcome("Skipped")
+)
+
 type ResourceInfo struct {
 	// Resource identify a Kubernetes resource
 	Resource corev1.ObjectReference `json:"resource,omitempty"`
@@ -33,8 +49,60 @@ type ResourceInfo struct {
 	// Message is an optional field.
 	// +optional
 	Message string `json:"message,omitempty"`
+
+	// Labels contains the resource labels selected by the Cleaner
+	// ReportResourceMetadata
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations contains the resource annotations selected by the Cleaner
+	// ReportResourceMetadata
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Diff is the unified diff of the resource before and after the
+	// Transform action. Only set for Transform actions.
+	// +optional
+	Diff string `json:"diff,omitempty"`
+
+	// Outcome is the outcome of the action taken on the resource.
+	// Not set for Scan actions.
+	// +optional
+	Outcome ResourceOutcome `json:"outcome,omitempty"`
+
+	// Error is the error returned when the action failed on the resource
+	// +optional
+	Error string `json:"error,omitempty"`
+
+	// Backup is the backup of the resource taken before deleting it. Only set
+	// when the Cleaner has Backup set.
+	// +optional
+	Backup *BackupReference `json:"backup,omitempty"`
 }
 
+// BackupReference identifies the backup of a resource
+type BackupReference struct {
+	// Type is the type of storage the resource is backed up to
+	Type BackupSinkType `json:"type"`
+
+	// Location is the file path, S3 URL (s3://bucket/key) or ConfigMap
+	// (namespace/name) of the backup
+	Location string `json:"location"`
+
+	// RestoreCommand is the command restoring the resource from its backup
+	// +optional
+	RestoreCommand string `json:"restoreCommand,omitempty"`
+}
+
+// ReportSchemaVersion is the version of the ReportSpec shape serialized in
+// notification payloads. It must be bumped whenever a field is removed, renamed
+// or changes meaning, so consumers can switch on it. Adding optional fields does
+// not require a bump.
+//
+// Versions:
+//   - 1: resourceInfo, action, runID, summary and error
+const ReportSchemaVersion = 1
+
 // ReportSpec defines the desired state of Report
 type ReportSpec struct {
 	// Resources identify a set of Kubernetes resource
@@ -42,17 +110,126 @@ type ReportSpec struct {
 
 	// Action indicates the action to take on selected object.
 	Action Action `json:"action"`
+
+	// RunID uniquely identifies the Cleaner run which generated this report.
+	// The same ID is included in every notification and log for that run.
+	// +optional
+	RunID string `json:"runID,omitempty"`
+
+	// Reason is the Reason of the Cleaner which generated this report
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Summary contains counts of the resources in the report
+	// +optional
+	Summary *ReportSummary `json:"summary,omitempty"`
+
+	// Error is set when the Cleaner run failed. ResourceInfo then only
+	// contains the resources processed before the failure.
+	// +optional
+	Error string `json:"error,omitempty"`
+
+	// SchemaVersion is the version of the report shape, see ReportSchemaVersion
+	// +optional
+	SchemaVersion int32 `json:"schemaVersion,omitempty"`
+
+	// CleanerSpec is the part of the Cleaner spec which selected the resources
+	// and the action taken on them. Only set when the Cleaner has
+	// IncludeSpecInReport set.
+	// +optional
+	CleanerSpec *ReportCleanerSpec `json:"cleanerSpec,omitempty"`
+}
+
+// ReportCleanerSpec is the copy, included in reports, of the rules of the
+// Cleaner which generated the report. Notifications, which may reference
+// credentials, are not included.
+type ReportCleanerSpec struct {
+	// Schedule of the Cleaner
+	Schedule string `json:"schedule"`
+
+	// Action of the Cleaner
+	Action Action `json:"action"`
+
+	// ResourceSelectors of the Cleaner
+	// +optional
+	ResourceSelectors []ResourceSelector `json:"resourceSelectors,omitempty"`
+
+	// AggregatedSelection of the Cleaner
+	// +optional
+	AggregatedSelection string `json:"aggregatedSelection,omitempty"`
+
+	// Transform of the Cleaner
+	// +optional
+	Transform string `json:"transform,omitempty"`
+
+	// DeleteOptions of the Cleaner
+	// +optional
+	DeleteOptions *DeleteOptions `json:"deleteOptions,omitempty"`
+}
+
+// ReportSummary contains counts of the resources in a report. When a
+// notification truncates the report, counts still include all resources.
+type ReportSummary struct {
+	// Total is the number of resources
+	Total int32 `json:"total"`
+
+	// ByKind is the number of resources per Kind
+	// +optional
+	ByKind map[string]int32 `json:"byKind,omitempty"`
+
+	// ByNamespace is the number of namespaced resources per namespace.
+	// Cluster-scoped resources are not included.
+	// +optional
+	ByNamespace map[string]int32 `json:"byNamespace,omitempty"`
+
+	// Failed is the number of resources the action failed on
+	// +optional
+	Failed int32 `json:"failed,omitempty"`
+}
+
+// ReportRunSummary summarizes the report of a past Cleaner run
+type ReportRunSummary struct {
+	// Time is when the report of the run was stored
+	Time metav1.Time `json:"time"`
+
+	// RunID identifies the Cleaner run
+	// +optional
+	RunID string `json:"runID,omitempty"`
+
+	// Action is the action taken on the resources
+	Action Action `json:"action"`
+
+	// Total is the number of resources in the report
+	Total int32 `json:"total"`
+
+	// Failed is the number of resources the action failed on
+	// +optional
+	Failed int32 `json:"failed,omitempty"`
+
+	// RunFailed is set when the Cleaner run failed
+	// +optional
+	RunFailed bool `json:"runFailed,omitempty"`
+}
+
+// ReportStatus defines the observed state of Report
+type ReportStatus struct {
+	// History contains the summaries of the last runs, oldest first. Only kept
+	// when the Cleaner has ReportHistoryLimit set, which caps its length.
+	// +optional
+	History []ReportRunSummary `json:"history,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:resource:path=reports,scope=Cluster
+//+kubebuilder:subresource:status
 
 // Report is the Schema for the reports API
 type Report struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec ReportSpec `json:"spec,omitempty"`
+	Spec   ReportSpec   `json:"spec,omitempty"`
+	Status ReportStatus `json:"status,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -28,6 +28,21 @@ import (
 	"github.com/projectsveltos/libsveltos/api/v1beta1"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupReference) DeepCopyInto(out *BackupReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupReference.
+func (in *BackupReference) DeepCopy() *BackupReference {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Cleaner) DeepCopyInto(out *Cleaner) {
 	*out = *in
@@ -96,11 +111,21 @@ func (in *CleanerSpec) DeepCopyInto(out *CleanerSpec) {
 		*out = new(DeleteOptions)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Backup != nil {
+		in, out := &in.Backup, &out.Backup
+		*out = new(ResourceBackup)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.StartingDeadlineSeconds != nil {
 		in, out := &in.StartingDeadlineSeconds, &out.StartingDeadlineSeconds
 		*out = new(int64)
 		**out = **in
 	}
+	if in.StalenessWatchdog != nil {
+		in, out := &in.StalenessWatchdog, &out.StalenessWatchdog
+		*out = new(StalenessWatchdog)
+		**out = **in
+	}
 	if in.Notifications != nil {
 		in, out := &in.Notifications, &out.Notifications
 		*out = make([]Notification, len(*in))
@@ -108,6 +133,21 @@ func (in *CleanerSpec) DeepCopyInto(out *CleanerSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ResourceThrottling != nil {
+		in, out := &in.ResourceThrottling, &out.ResourceThrottling
+		*out = new(ResourceThrottling)
+		**out = **in
+	}
+	if in.ReportResourceMetadata != nil {
+		in, out := &in.ReportResourceMetadata, &out.ReportResourceMetadata
+		*out = new(ReportResourceMetadata)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReportRedaction != nil {
+		in, out := &in.ReportRedaction, &out.ReportRedaction
+		*out = new(ReportRedaction)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CleanerSpec.
@@ -131,11 +171,67 @@ func (in *CleanerStatus) DeepCopyInto(out *CleanerStatus) {
 		in, out := &in.LastRunTime, &out.LastRunTime
 		*out = (*in).DeepCopy()
 	}
+	if in.LastMatchCount != nil {
+		in, out := &in.LastMatchCount, &out.LastMatchCount
+		*out = new(int32)
+		**out = **in
+	}
+	if in.LastStaleNotificationTime != nil {
+		in, out := &in.LastStaleNotificationTime, &out.LastStaleNotificationTime
+		*out = (*in).DeepCopy()
+	}
 	if in.FailureMessage != nil {
 		in, out := &in.FailureMessage, &out.FailureMessage
 		*out = new(string)
 		**out = **in
 	}
+	if in.NotificationDigests != nil {
+		in, out := &in.NotificationDigests, &out.NotificationDigests
+		*out = make([]NotificationDigest, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NotificationStatuses != nil {
+		in, out := &in.NotificationStatuses, &out.NotificationStatuses
+		*out = make([]NotificationStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NotificationOutcomes != nil {
+		in, out := &in.NotificationOutcomes, &out.NotificationOutcomes
+		*out = make([]NotificationOutcome, len(*in))
+		copy(*out, *in)
+	}
+	if in.NotifiedResources != nil {
+		in, out := &in.NotifiedResources, &out.NotifiedResources
+		*out = make([]NotifiedResource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SlackThreads != nil {
+		in, out := &in.SlackThreads, &out.SlackThreads
+		*out = make([]SlackThread, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SlackMessages != nil {
+		in, out := &in.SlackMessages, &out.SlackMessages
+		*out = make([]SlackMessage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CleanerStatus.
@@ -148,6 +244,21 @@ func (in *CleanerStatus) DeepCopy() *CleanerStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CloudEventsOptions) DeepCopyInto(out *CloudEventsOptions) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloudEventsOptions.
+func (in *CloudEventsOptions) DeepCopy() *CloudEventsOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(CloudEventsOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DeleteOptions) DeepCopyInto(out *DeleteOptions) {
 	*out = *in
@@ -174,86 +285,243 @@ func (in *DeleteOptions) DeepCopy() *DeleteOptions {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Notification) DeepCopyInto(out *Notification) {
+func (in *DigestOptions) DeepCopyInto(out *DigestOptions) {
 	*out = *in
-	if in.NotificationRef != nil {
-		in, out := &in.NotificationRef, &out.NotificationRef
-		*out = new(corev1.ObjectReference)
-		**out = **in
+	out.Interval = in.Interval
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DigestOptions.
+func (in *DigestOptions) DeepCopy() *DigestOptions {
+	if in == nil {
+		return nil
 	}
+	out := new(DigestOptions)
+	in.DeepCopyInto(out)
+	return out
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Notification.
-func (in *Notification) DeepCopy() *Notification {
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EventOptions) DeepCopyInto(out *EventOptions) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EventOptions.
+func (in *EventOptions) DeepCopy() *EventOptions {
 	if in == nil {
 		return nil
 	}
-	out := new(Notification)
+	out := new(EventOptions)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Report) DeepCopyInto(out *Report) {
+func (in *FileOptions) DeepCopyInto(out *FileOptions) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
+	if in.MaxFiles != nil {
+		in, out := &in.MaxFiles, &out.MaxFiles
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxAge != nil {
+		in, out := &in.MaxAge, &out.MaxAge
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Report.
-func (in *Report) DeepCopy() *Report {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FileOptions.
+func (in *FileOptions) DeepCopy() *FileOptions {
 	if in == nil {
 		return nil
 	}
-	out := new(Report)
+	out := new(FileOptions)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Report) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitLabOptions) DeepCopyInto(out *GitLabOptions) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
-	return nil
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitLabOptions.
+func (in *GitLabOptions) DeepCopy() *GitLabOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(GitLabOptions)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ReportList) DeepCopyInto(out *ReportList) {
+func (in *LogOptions) DeepCopyInto(out *LogOptions) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]Report, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogOptions.
+func (in *LogOptions) DeepCopy() *LogOptions {
+	if in == nil {
+		return nil
 	}
+	out := new(LogOptions)
+	in.DeepCopyInto(out)
+	return out
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportList.
-func (in *ReportList) DeepCopy() *ReportList {
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceRoute) DeepCopyInto(out *NamespaceRoute) {
+	*out = *in
+	out.NotificationRef = in.NotificationRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceRoute.
+func (in *NamespaceRoute) DeepCopy() *NamespaceRoute {
 	if in == nil {
 		return nil
 	}
-	out := new(ReportList)
+	out := new(NamespaceRoute)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ReportList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Notification) DeepCopyInto(out *Notification) {
+	*out = *in
+	if in.NotificationRef != nil {
+		in, out := &in.NotificationRef, &out.NotificationRef
+		*out = new(corev1.ObjectReference)
+		**out = **in
 	}
-	return nil
+	if in.FailoverNotificationRefs != nil {
+		in, out := &in.FailoverNotificationRefs, &out.FailoverNotificationRefs
+		*out = make([]corev1.ObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SMTP != nil {
+		in, out := &in.SMTP, &out.SMTP
+		*out = new(SMTPOptions)
+		**out = **in
+	}
+	if in.Slack != nil {
+		in, out := &in.Slack, &out.Slack
+		*out = new(SlackOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Webex != nil {
+		in, out := &in.Webex, &out.Webex
+		*out = new(WebexOptions)
+		**out = **in
+	}
+	if in.Splunk != nil {
+		in, out := &in.Splunk, &out.Splunk
+		*out = new(SplunkOptions)
+		**out = **in
+	}
+	if in.Event != nil {
+		in, out := &in.Event, &out.Event
+		*out = new(EventOptions)
+		**out = **in
+	}
+	if in.File != nil {
+		in, out := &in.File, &out.File
+		*out = new(FileOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CloudEvents != nil {
+		in, out := &in.CloudEvents, &out.CloudEvents
+		*out = new(CloudEventsOptions)
+		**out = **in
+	}
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(S3Options)
+		**out = **in
+	}
+	if in.Redis != nil {
+		in, out := &in.Redis, &out.Redis
+		*out = new(RedisOptions)
+		**out = **in
+	}
+	if in.GitLab != nil {
+		in, out := &in.GitLab, &out.GitLab
+		*out = new(GitLabOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Log != nil {
+		in, out := &in.Log, &out.Log
+		*out = new(LogOptions)
+		**out = **in
+	}
+	if in.Digest != nil {
+		in, out := &in.Digest, &out.Digest
+		*out = new(DigestOptions)
+		**out = **in
+	}
+	if in.ActiveWindow != nil {
+		in, out := &in.ActiveWindow, &out.ActiveWindow
+		*out = new(NotificationWindow)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WarningThreshold != nil {
+		in, out := &in.WarningThreshold, &out.WarningThreshold
+		*out = new(int32)
+		**out = **in
+	}
+	if in.NamespaceRoutes != nil {
+		in, out := &in.NamespaceRoutes, &out.NamespaceRoutes
+		*out = make([]NamespaceRoute, len(*in))
+		copy(*out, *in)
+	}
+	if in.IncludeRawReport != nil {
+		in, out := &in.IncludeRawReport, &out.IncludeRawReport
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Fields != nil {
+		in, out := &in.Fields, &out.Fields
+		*out = make([]ReportField, len(*in))
+		copy(*out, *in)
+	}
+	if in.ResourceFilter != nil {
+		in, out := &in.ResourceFilter, &out.ResourceFilter
+		*out = new(ResourceFilter)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Notification.
+func (in *Notification) DeepCopy() *Notification {
+	if in == nil {
+		return nil
+	}
+	out := new(Notification)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ReportSpec) DeepCopyInto(out *ReportSpec) {
+func (in *NotificationDigest) DeepCopyInto(out *NotificationDigest) {
 	*out = *in
+	in.Since.DeepCopyInto(&out.Since)
 	if in.ResourceInfo != nil {
 		in, out := &in.ResourceInfo, &out.ResourceInfo
 		*out = make([]ResourceInfo, len(*in))
@@ -263,75 +531,652 @@ func (in *ReportSpec) DeepCopyInto(out *ReportSpec) {
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportSpec.
-func (in *ReportSpec) DeepCopy() *ReportSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationDigest.
+func (in *NotificationDigest) DeepCopy() *NotificationDigest {
 	if in == nil {
 		return nil
 	}
-	out := new(ReportSpec)
+	out := new(NotificationDigest)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ResourceInfo) DeepCopyInto(out *ResourceInfo) {
+func (in *NotificationOutcome) DeepCopyInto(out *NotificationOutcome) {
 	*out = *in
-	out.Resource = in.Resource
-	if in.FullResource != nil {
-		in, out := &in.FullResource, &out.FullResource
-		*out = make([]byte, len(*in))
-		copy(*out, *in)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceInfo.
-func (in *ResourceInfo) DeepCopy() *ResourceInfo {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationOutcome.
+func (in *NotificationOutcome) DeepCopy() *NotificationOutcome {
 	if in == nil {
 		return nil
 	}
-	out := new(ResourceInfo)
+	out := new(NotificationOutcome)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ResourcePolicySet) DeepCopyInto(out *ResourcePolicySet) {
+func (in *NotificationStatus) DeepCopyInto(out *NotificationStatus) {
 	*out = *in
-	if in.ResourceSelectors != nil {
-		in, out := &in.ResourceSelectors, &out.ResourceSelectors
-		*out = make([]ResourceSelector, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.LastFailureTime != nil {
+		in, out := &in.LastFailureTime, &out.LastFailureTime
+		*out = (*in).DeepCopy()
+	}
+	if in.SuspendedUntil != nil {
+		in, out := &in.SuspendedUntil, &out.SuspendedUntil
+		*out = (*in).DeepCopy()
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourcePolicySet.
-func (in *ResourcePolicySet) DeepCopy() *ResourcePolicySet {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationStatus.
+func (in *NotificationStatus) DeepCopy() *NotificationStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(ResourcePolicySet)
+	out := new(NotificationStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ResourceSelector) DeepCopyInto(out *ResourceSelector) {
+func (in *NotificationWindow) DeepCopyInto(out *NotificationWindow) {
 	*out = *in
-	if in.LabelFilters != nil {
-		in, out := &in.LabelFilters, &out.LabelFilters
-		*out = make([]v1beta1.LabelFilter, len(*in))
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]Weekday, len(*in))
 		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceSelector.
-func (in *ResourceSelector) DeepCopy() *ResourceSelector {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationWindow.
+func (in *NotificationWindow) DeepCopy() *NotificationWindow {
 	if in == nil {
 		return nil
 	}
-	out := new(ResourceSelector)
+	out := new(NotificationWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotifiedResource) DeepCopyInto(out *NotifiedResource) {
+	*out = *in
+	in.FirstMatched.DeepCopyInto(&out.FirstMatched)
+	in.LastNotified.DeepCopyInto(&out.LastNotified)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotifiedResource.
+func (in *NotifiedResource) DeepCopy() *NotifiedResource {
+	if in == nil {
+		return nil
+	}
+	out := new(NotifiedResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisOptions) DeepCopyInto(out *RedisOptions) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisOptions.
+func (in *RedisOptions) DeepCopy() *RedisOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Report) DeepCopyInto(out *Report) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Report.
+func (in *Report) DeepCopy() *Report {
+	if in == nil {
+		return nil
+	}
+	out := new(Report)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Report) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportCleanerSpec) DeepCopyInto(out *ReportCleanerSpec) {
+	*out = *in
+	if in.ResourceSelectors != nil {
+		in, out := &in.ResourceSelectors, &out.ResourceSelectors
+		*out = make([]ResourceSelector, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DeleteOptions != nil {
+		in, out := &in.DeleteOptions, &out.DeleteOptions
+		*out = new(DeleteOptions)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportCleanerSpec.
+func (in *ReportCleanerSpec) DeepCopy() *ReportCleanerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportCleanerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportList) DeepCopyInto(out *ReportList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Report, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportList.
+func (in *ReportList) DeepCopy() *ReportList {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReportList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportRedaction) DeepCopyInto(out *ReportRedaction) {
+	*out = *in
+	if in.Patterns != nil {
+		in, out := &in.Patterns, &out.Patterns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportRedaction.
+func (in *ReportRedaction) DeepCopy() *ReportRedaction {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportRedaction)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportResourceMetadata) DeepCopyInto(out *ReportResourceMetadata) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportResourceMetadata.
+func (in *ReportResourceMetadata) DeepCopy() *ReportResourceMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportResourceMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportRunSummary) DeepCopyInto(out *ReportRunSummary) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportRunSummary.
+func (in *ReportRunSummary) DeepCopy() *ReportRunSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportRunSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportSpec) DeepCopyInto(out *ReportSpec) {
+	*out = *in
+	if in.ResourceInfo != nil {
+		in, out := &in.ResourceInfo, &out.ResourceInfo
+		*out = make([]ResourceInfo, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Summary != nil {
+		in, out := &in.Summary, &out.Summary
+		*out = new(ReportSummary)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CleanerSpec != nil {
+		in, out := &in.CleanerSpec, &out.CleanerSpec
+		*out = new(ReportCleanerSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportSpec.
+func (in *ReportSpec) DeepCopy() *ReportSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportStatus) DeepCopyInto(out *ReportStatus) {
+	*out = *in
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = make([]ReportRunSummary, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportStatus.
+func (in *ReportStatus) DeepCopy() *ReportStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportSummary) DeepCopyInto(out *ReportSummary) {
+	*out = *in
+	if in.ByKind != nil {
+		in, out := &in.ByKind, &out.ByKind
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ByNamespace != nil {
+		in, out := &in.ByNamespace, &out.ByNamespace
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportSummary.
+func (in *ReportSummary) DeepCopy() *ReportSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceBackup) DeepCopyInto(out *ResourceBackup) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(corev1.ObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceBackup.
+func (in *ResourceBackup) DeepCopy() *ResourceBackup {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceBackup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceFilter) DeepCopyInto(out *ResourceFilter) {
+	*out = *in
+	if in.Include != nil {
+		in, out := &in.Include, &out.Include
+		*out = new(ResourceFilterMatch)
+		**out = **in
+	}
+	if in.Exclude != nil {
+		in, out := &in.Exclude, &out.Exclude
+		*out = new(ResourceFilterMatch)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceFilter.
+func (in *ResourceFilter) DeepCopy() *ResourceFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceFilterMatch) DeepCopyInto(out *ResourceFilterMatch) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceFilterMatch.
+func (in *ResourceFilterMatch) DeepCopy() *ResourceFilterMatch {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceFilterMatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceInfo) DeepCopyInto(out *ResourceInfo) {
+	*out = *in
+	out.Resource = in.Resource
+	if in.FullResource != nil {
+		in, out := &in.FullResource, &out.FullResource
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Backup != nil {
+		in, out := &in.Backup, &out.Backup
+		*out = new(BackupReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceInfo.
+func (in *ResourceInfo) DeepCopy() *ResourceInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourcePolicySet) DeepCopyInto(out *ResourcePolicySet) {
+	*out = *in
+	if in.ResourceSelectors != nil {
+		in, out := &in.ResourceSelectors, &out.ResourceSelectors
+		*out = make([]ResourceSelector, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourcePolicySet.
+func (in *ResourcePolicySet) DeepCopy() *ResourcePolicySet {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourcePolicySet)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceSelector) DeepCopyInto(out *ResourceSelector) {
+	*out = *in
+	if in.LabelFilters != nil {
+		in, out := &in.LabelFilters, &out.LabelFilters
+		*out = make([]v1beta1.LabelFilter, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceSelector.
+func (in *ResourceSelector) DeepCopy() *ResourceSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceThrottling) DeepCopyInto(out *ResourceThrottling) {
+	*out = *in
+	out.Cooldown = in.Cooldown
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceThrottling.
+func (in *ResourceThrottling) DeepCopy() *ResourceThrottling {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceThrottling)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *S3Options) DeepCopyInto(out *S3Options) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3Options.
+func (in *S3Options) DeepCopy() *S3Options {
+	if in == nil {
+		return nil
+	}
+	out := new(S3Options)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SMTPOptions) DeepCopyInto(out *SMTPOptions) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SMTPOptions.
+func (in *SMTPOptions) DeepCopy() *SMTPOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SMTPOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SlackMentions) DeepCopyInto(out *SlackMentions) {
+	*out = *in
+	if in.UserIDs != nil {
+		in, out := &in.UserIDs, &out.UserIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.GroupIDs != nil {
+		in, out := &in.GroupIDs, &out.GroupIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Actions != nil {
+		in, out := &in.Actions, &out.Actions
+		*out = make([]Action, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SlackMentions.
+func (in *SlackMentions) DeepCopy() *SlackMentions {
+	if in == nil {
+		return nil
+	}
+	out := new(SlackMentions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SlackMessage) DeepCopyInto(out *SlackMessage) {
+	*out = *in
+	in.PostTime.DeepCopyInto(&out.PostTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SlackMessage.
+func (in *SlackMessage) DeepCopy() *SlackMessage {
+	if in == nil {
+		return nil
+	}
+	out := new(SlackMessage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SlackOptions) DeepCopyInto(out *SlackOptions) {
+	*out = *in
+	if in.ThreadPeriod != nil {
+		in, out := &in.ThreadPeriod, &out.ThreadPeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Mentions != nil {
+		in, out := &in.Mentions, &out.Mentions
+		*out = new(SlackMentions)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SlackOptions.
+func (in *SlackOptions) DeepCopy() *SlackOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SlackOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SlackThread) DeepCopyInto(out *SlackThread) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SlackThread.
+func (in *SlackThread) DeepCopy() *SlackThread {
+	if in == nil {
+		return nil
+	}
+	out := new(SlackThread)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SplunkOptions) DeepCopyInto(out *SplunkOptions) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SplunkOptions.
+func (in *SplunkOptions) DeepCopy() *SplunkOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SplunkOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StalenessWatchdog) DeepCopyInto(out *StalenessWatchdog) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StalenessWatchdog.
+func (in *StalenessWatchdog) DeepCopy() *StalenessWatchdog {
+	if in == nil {
+		return nil
+	}
+	out := new(StalenessWatchdog)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebexOptions) DeepCopyInto(out *WebexOptions) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebexOptions.
+func (in *WebexOptions) DeepCopy() *WebexOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(WebexOptions)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -43,6 +43,7 @@ import (
 
 	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
 	"gianlucam76/k8s-cleaner/internal/controller"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -58,6 +59,16 @@ var (
 	syncPeriod            time.Duration
 	jitterWindowInSeconds int
 	healthAddr            string
+	notificationProxy     string
+	notificationBatch     time.Duration
+	messageTemplate       string
+	notificationReadiness bool
+	adminNotificationType string
+	adminNotificationRef  string
+
+	notificationTLSMinVersion     string
+	notificationCipherSuites      []string
+	notificationDisableKeepAlives bool
 )
 
 // Add RBAC for the authorized diagnostics endpoint.
@@ -85,6 +96,10 @@ func main() {
 
 	ctrl.SetLogger(zapr.NewLogger(zapLogger))
 
+	if err := setNotificationOptions(); err != nil {
+		os.Exit(1)
+	}
+
 	ctx := ctrl.SetupSignalHandler()
 
 	ctrlOptions := ctrl.Options{
@@ -128,6 +143,12 @@ func main() {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	if notificationReadiness {
+		if err := mgr.AddReadyzCheck("notifications", executor.NotificationsReadyCheck); err != nil {
+			setupLog.Error(err, "unable to set up notifications ready check")
+			os.Exit(1)
+		}
+	}
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctx); err != nil {
@@ -178,10 +199,74 @@ func initFlags(fs *pflag.FlagSet) {
 
 	fs.StringVar(&healthAddr, "health-addr", ":9440",
 		"The address the health endpoint binds to.")
+
+	fs.StringVar(&notificationProxy, "notification-proxy", "",
+		"URL of the HTTP(S) proxy notifications are sent through (e.g. http://proxy:3128). Hosts in NO_PROXY "+
+			"are reached directly. If not set, HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables are used.")
+
+	fs.StringVar(&notificationTLSMinVersion, "notification-tls-min-version", "1.2",
+		"Minimum TLS version (1.2 or 1.3) of connections opened to send notifications.")
+
+	fs.StringSliceVar(&notificationCipherSuites, "notification-tls-cipher-suites", nil,
+		"Comma-separated list of cipher suites (e.g. TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384) allowed for TLS 1.2 "+
+			"connections opened to send notifications. TLS 1.3 cipher suites are not configurable. "+
+			"If not set, Go default cipher suites are used.")
+
+	fs.BoolVar(&notificationDisableKeepAlives, "notification-disable-keepalives", false,
+		"Open a new connection for every notification request instead of reusing idle connections.")
+
+	fs.DurationVar(&notificationBatch, "notification-batch-window", 0,
+		"How long reports of different Cleaner instances sent to the same Slack, Teams, Discord, Webex or SMTP "+
+			"target are collected before being sent as a single message (e.g. 1m). Batching is disabled if not set.")
+
+	fs.StringVar(&messageTemplate, "default-message-template", "",
+		"Go template of the text sent along with reports by notifications without their own messageTemplate. "+
+			"Available fields are .Cleaner, .Action, .Count and .RunID. If not set, the built-in message is used.")
+
+	fs.BoolVar(&notificationReadiness, "notification-readiness", false,
+		"Report k8s-cleaner as not ready when the last delivery of every notification type in use failed.")
+
+	fs.StringVar(&adminNotificationType, "admin-notification-type", "",
+		"Notification type (e.g. Slack) configuration errors of every Cleaner are sent to. "+
+			"If not set, configuration errors are only recorded as Events.")
+
+	fs.StringVar(&adminNotificationRef, "admin-notification-secret", "",
+		"namespace/name of the Secret containing the credentials of the admin notification.")
 }
 
 //+kubebuilder:rbac:groups=*,resources=*,verbs=get;list;watch;delete
 
+// setNotificationOptions configures notifications from the command line flags.
+// The error of an invalid flag is logged and returned.
+func setNotificationOptions() error {
+	if err := executor.SetNotificationProxy(notificationProxy); err != nil {
+		setupLog.Error(err, "invalid notification proxy")
+		return err
+	}
+	if err := executor.SetNotificationTLSMinVersion(notificationTLSMinVersion); err != nil {
+		setupLog.Error(err, "invalid notification TLS minimum version")
+		return err
+	}
+	if err := executor.SetNotificationCipherSuites(notificationCipherSuites); err != nil {
+		setupLog.Error(err, "invalid notification cipher suites")
+		return err
+	}
+	executor.SetNotificationDisableKeepAlives(notificationDisableKeepAlives)
+	if err := executor.SetNotificationBatchWindow(notificationBatch); err != nil {
+		setupLog.Error(err, "invalid notification batch window")
+		return err
+	}
+	if err := executor.SetDefaultMessageTemplate(messageTemplate); err != nil {
+		setupLog.Error(err, "invalid default message template")
+		return err
+	}
+	if err := executor.SetAdminNotification(adminNotificationType, adminNotificationRef); err != nil {
+		setupLog.Error(err, "invalid admin notification")
+		return err
+	}
+	return nil
+}
+
 func initScheme() (*runtime.Scheme, error) {
 	s := runtime.NewScheme()
 	if err := clientgoscheme.AddToScheme(s); err != nil {
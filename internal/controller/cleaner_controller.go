@@ -31,6 +31,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
@@ -41,6 +42,9 @@ import (
 	"github.com/go-logr/logr"
 )
 
+// defaultStalenessFactor is the staleness watchdog factor used when none is set
+const defaultStalenessFactor = 2
+
 // CleanerReconciler reconciles a Cleaner object
 type CleanerReconciler struct {
 	client.Client
@@ -140,9 +144,18 @@ func (r *CleanerReconciler) reconcileNormal(ctx context.Context, cleanerScope *s
 		return reconcile.Result{}, err
 	}
 
+	if notificationName, ok := cleanerScope.Cleaner.Annotations[appsv1alpha1.TestNotificationAnnotation]; ok {
+		sendTestNotification(ctx, cleanerScope, notificationName, logger)
+	}
+
+	checkValidation(ctx, cleanerScope, logger)
+
 	executorClient := executor.GetClient()
 	result := executorClient.GetResult(cleanerScope.Cleaner.Name)
 	if result.ResultStatus != executor.Unavailable {
+		if result.RunID != "" {
+			cleanerScope.SetLastRunID(result.RunID)
+		}
 		if result.Err != nil {
 			msg := result.Err.Error()
 			cleanerScope.SetFailureMessage(&msg)
@@ -152,6 +165,9 @@ func (r *CleanerReconciler) reconcileNormal(ctx context.Context, cleanerScope *s
 	}
 
 	now := time.Now()
+	// Staleness is verified before scheduling, as a missed run is scheduled right away
+	checkStaleness(ctx, cleanerScope, now, logger)
+
 	nextRun, err := schedule(ctx, cleanerScope, r.JitterWindowInSeconds, logger)
 	if err != nil {
 		logger.Info("failed to get next run. Err: %v", err)
@@ -165,15 +181,68 @@ func (r *CleanerReconciler) reconcileNormal(ctx context.Context, cleanerScope *s
 	return scheduledResult, nil
 }
 
+// sendTestNotification sends a test notification for the Cleaner notification named
+// notificationName, then removes the annotation requesting it. Outcome is recorded as
+// an Event on the Cleaner instance; a failure does not fail the reconciliation.
+func sendTestNotification(ctx context.Context, cleanerScope *scope.CleanerScope, notificationName string,
+	logger logr.Logger) {
+
+	l := logger.WithValues("notification", notificationName)
+	l.Info("sending test notification")
+	if err := executor.SendTestNotification(ctx, cleanerScope.Cleaner, notificationName, l); err != nil {
+		l.Info(fmt.Sprintf("test notification failed: %v", err))
+	}
+
+	// Annotation is removed when the scope is closed and Cleaner patched
+	delete(cleanerScope.Cleaner.Annotations, appsv1alpha1.TestNotificationAnnotation)
+}
+
+// checkValidation notifies the configuration errors of the Cleaner, if any. Each
+// distinct error is notified once, and notified again only if the Cleaner is
+// later fixed and broken again. A failure does not fail the reconciliation:
+// errors preventing the Cleaner from running are reported when scheduling it.
+func checkValidation(ctx context.Context, cleanerScope *scope.CleanerScope, logger logr.Logger) {
+	cleaner := cleanerScope.Cleaner
+	validationErr := executor.ValidateCleaner(cleaner)
+	if validationErr == nil {
+		cleanerScope.SetNotifiedValidationError("")
+		return
+	}
+	if cleaner.Status.NotifiedValidationError == validationErr.Error() {
+		return
+	}
+
+	logger.Info(fmt.Sprintf("cleaner is misconfigured: %v", validationErr))
+	if err := executor.SendValidationErrorNotification(ctx, cleaner, validationErr, logger); err != nil {
+		logger.Info(fmt.Sprintf("failed to send validation error notification: %v", err))
+	}
+	// Failed deliveries are not retried, to avoid notifying on every reconciliation
+	cleanerScope.SetNotifiedValidationError(validationErr.Error())
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *CleanerReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager,
 	numOfWorker int, logger logr.Logger) error {
 
-	executor.InitializeClient(ctx, logger, mgr.GetConfig(), mgr.GetClient(), mgr.GetScheme(), numOfWorker)
+	executor.InitializeClient(ctx, logger, mgr.GetConfig(), mgr.GetClient(), mgr.GetScheme(),
+		mgr.GetEventRecorderFor("k8s-cleaner"), numOfWorker)
+
+	// Digests are sent once their interval has elapsed, whether or not the
+	// Cleaner instances run
+	if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		return executor.RunDigestScheduler(ctx, logger.WithName("digest"))
+	})); err != nil {
+		return err
+	}
+	// Pending batches are delivered on shutdown
+	if err := mgr.Add(manager.RunnableFunc(executor.FlushNotificationBatchesOnStop)); err != nil {
+		return err
+	}
 
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&appsv1alpha1.Cleaner{}).
-		WithEventFilter(predicate.GenerationChangedPredicate{}).
+		// Annotation changes are watched to serve test notifications
+		WithEventFilter(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{})).
 		WithOptions(controller.Options{
 			MaxConcurrentReconciles: r.ConcurrentReconciles,
 		}).
@@ -312,6 +381,66 @@ func shouldSchedule(cleaner *appsv1alpha1.Cleaner, jitterWindowInSeconds int, lo
 	return true
 }
 
+// checkStaleness sends a stale notification when the staleness watchdog is
+// enabled and the Cleaner has not run for longer than its schedule interval
+// multiplied by the watchdog factor. A single notification is sent till the
+// Cleaner runs again.
+func checkStaleness(ctx context.Context, cleanerScope *scope.CleanerScope, now time.Time, logger logr.Logger) {
+	cleaner := cleanerScope.Cleaner
+	stale, lastRun, interval, err := isStale(cleaner, now)
+	if err != nil {
+		logger.Info(fmt.Sprintf("failed to verify staleness: %v", err))
+		return
+	}
+	if !stale {
+		return
+	}
+
+	lastNotified := cleaner.Status.LastStaleNotificationTime
+	if lastNotified != nil && !lastNotified.Time.Before(lastRun) {
+		// Already notified since last run
+		return
+	}
+
+	if err := executor.SendStaleNotification(ctx, cleaner, lastRun, interval, logger); err != nil {
+		logger.Info(fmt.Sprintf("failed to send stale notification: %v", err))
+	}
+	// Failed channels are not retried, to avoid notifying the others repeatedly
+	cleanerScope.SetLastStaleNotificationTime(&metav1.Time{Time: now})
+}
+
+// isStale returns true if the staleness watchdog of cleaner is enabled and the
+// time elapsed since the last run (or since creation, if it never ran) exceeds the
+// schedule interval multiplied by the watchdog factor. Last run and schedule
+// interval are returned as well.
+func isStale(cleaner *appsv1alpha1.Cleaner, now time.Time) (stale bool, lastRun time.Time,
+	interval time.Duration, err error) {
+
+	if cleaner.Spec.StalenessWatchdog == nil {
+		return false, lastRun, 0, nil
+	}
+
+	sched, err := cron.ParseStandard(cleaner.Spec.Schedule)
+	if err != nil {
+		return false, lastRun, 0, fmt.Errorf("unparseable schedule %q: %w", cleaner.Spec.Schedule, err)
+	}
+
+	lastRun = cleaner.CreationTimestamp.Time
+	if cleaner.Status.LastRunTime != nil {
+		lastRun = cleaner.Status.LastRunTime.Time
+	}
+
+	// Interval between the two scheduled runs following last run
+	expected := sched.Next(lastRun)
+	interval = sched.Next(expected).Sub(expected)
+
+	factor := cleaner.Spec.StalenessWatchdog.Factor
+	if factor < 1 {
+		factor = defaultStalenessFactor
+	}
+	return now.Sub(lastRun) > time.Duration(factor)*interval, lastRun, interval, nil
+}
+
 func removeQueuedJobs(cleanerScope *scope.CleanerScope) {
 	executorClient := executor.GetClient()
 	executorClient.RemoveEntries(cleanerScope.Cleaner.Name)
@@ -96,6 +96,46 @@ var _ = Describe("CleanerClient", func() {
 		Expect(nextSchedule.Minute()).To(Equal(minute))
 	})
 
+	It("isStale returns true when last run is older than schedule interval times factor", func() {
+		now := time.Now()
+		cleaner := &appsv1alpha1.Cleaner{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              randomString(),
+				CreationTimestamp: metav1.Time{Time: now.Add(-24 * time.Hour)},
+			},
+			Spec: appsv1alpha1.CleanerSpec{
+				Schedule: "0 * * * *",
+			},
+			Status: appsv1alpha1.CleanerStatus{
+				LastRunTime: &metav1.Time{Time: now.Add(-150 * time.Minute)},
+			},
+		}
+
+		// Watchdog not enabled
+		stale, _, _, err := controller.IsStale(cleaner, now)
+		Expect(err).To(BeNil())
+		Expect(stale).To(BeFalse())
+
+		cleaner.Spec.StalenessWatchdog = &appsv1alpha1.StalenessWatchdog{Factor: 2}
+		stale, lastRun, interval, err := controller.IsStale(cleaner, now)
+		Expect(err).To(BeNil())
+		Expect(stale).To(BeTrue())
+		Expect(lastRun).To(Equal(cleaner.Status.LastRunTime.Time))
+		Expect(interval).To(Equal(time.Hour))
+
+		cleaner.Spec.StalenessWatchdog.Factor = 3
+		stale, _, _, err = controller.IsStale(cleaner, now)
+		Expect(err).To(BeNil())
+		Expect(stale).To(BeFalse())
+
+		// Cleaner which never ran is verified against its creation time
+		cleaner.Status.LastRunTime = nil
+		stale, lastRun, _, err = controller.IsStale(cleaner, now)
+		Expect(err).To(BeNil())
+		Expect(stale).To(BeTrue())
+		Expect(lastRun).To(Equal(cleaner.CreationTimestamp.Time))
+	})
+
 	It("removeReport removes corresponding Report instance", func() {
 		cleaner := &appsv1alpha1.Cleaner{
 			ObjectMeta: metav1.ObjectMeta{
@@ -0,0 +1,115 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+const (
+	// adminNotificationName is the name of the admin notification
+	adminNotificationName = "admin"
+
+	// eventReasonInvalid is the reason of Events recorded on Cleaner instances
+	// whose configuration is invalid
+	eventReasonInvalid = "CleanerInvalid"
+)
+
+// adminNotification, when set, is the notification platform owners receive
+// the validation errors of every Cleaner on
+var adminNotification *appsv1alpha1.Notification
+
+// SetAdminNotification sets the notification Cleaner validation errors are sent
+// to. secret is the namespace/name of the Secret containing the credentials of
+// notificationType, with the same keys used by Cleaner notifications. If
+// notificationType is empty, validation errors are not notified.
+func SetAdminNotification(notificationType, secret string) error {
+	if notificationType == "" {
+		adminNotification = nil
+		return nil
+	}
+
+	t := appsv1alpha1.NotificationType(notificationType)
+	if t == appsv1alpha1.NotificationTypeCleanerReport || t == appsv1alpha1.NotificationTypeEvent {
+		return fmt.Errorf("invalid admin notification type %s: a notification channel is required", t)
+	}
+	if _, err := getNotifier(t); err != nil {
+		return fmt.Errorf("invalid admin notification type: %w", err)
+	}
+
+	namespace, name, ok := strings.Cut(secret, "/")
+	if !ok || namespace == "" || name == "" {
+		return fmt.Errorf("invalid admin notification secret %q: must be namespace/name", secret)
+	}
+
+	adminNotification = &appsv1alpha1.Notification{
+		Name: adminNotificationName,
+		Type: t,
+		NotificationRef: &corev1.ObjectReference{
+			Kind:       "Secret",
+			APIVersion: "v1",
+			Namespace:  namespace,
+			Name:       name,
+		},
+	}
+	return nil
+}
+
+// SendValidationErrorNotification notifies that the configuration of cleaner
+// is invalid. A Warning Event is recorded on the Cleaner instance and, when an
+// admin notification is set, the error is sent to it.
+func SendValidationErrorNotification(ctx context.Context, cleaner *appsv1alpha1.Cleaner, validationErr error,
+	logger logr.Logger) error {
+
+	if eventRecorder != nil {
+		eventRecorder.Event(cleaner, corev1.EventTypeWarning, eventReasonInvalid,
+			truncateEventMessage(validationErr.Error()))
+	}
+	if adminNotification == nil {
+		return nil
+	}
+
+	l := getNotificationLogger(logger, adminNotification)
+	l.V(logs.LogInfo).Info("send validation error notification", "reason", validationErr.Error())
+
+	reportSpec := generateReportSpec(nil, cleaner, "", time.Now().UTC())
+	reportSpec.Error = validationErr.Error()
+	message := getValidationErrorMessage(cleaner.Name, validationErr)
+
+	if err := deliverNotification(ctx, cleaner, reportSpec, nil, message, adminNotification, l); err != nil {
+		l.Error(err, logMsgSendFailed)
+		return err
+	}
+	return nil
+}
+
+// getValidationErrorMessage returns the text sent along with a validation
+// error notification
+func getValidationErrorMessage(cleanerName string, validationErr error) string {
+	return fmt.Sprintf("k8s-cleaner instance %s is misconfigured and may not run as expected: %v",
+		cleanerName, validationErr)
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+const (
+	// maxAttachmentNameLength is the maximum length of a templated attachment
+	// name, extension excluded
+	maxAttachmentNameLength = 200
+
+	// Report is sent inline, as a message of its own, when attachments are
+	// disabled. Leave room for the code block around it in the 2000 characters.
+	discordMaxInlineReportSize = discordMaxContent - 20
+
+	// Webex rejects messages longer than 7439 bytes. The inline report is
+	// added to the message, so it gets what the rest of the message leaves.
+	webexMaxMessageSize = 7439
+
+	// inlineReportFence starts the code block of inline reports
+	inlineReportFence = "```json\n"
+)
+
+// inlineReportSizeLimits maps each notification type sending the report inline,
+// instead of as a file, when attachments are disabled to the maximum size of
+// that report
+var inlineReportSizeLimits = map[appsv1alpha1.NotificationType]int{
+	appsv1alpha1.NotificationTypeSlack:   slackMaxReportSize,
+	appsv1alpha1.NotificationTypeDiscord: discordMaxInlineReportSize,
+	appsv1alpha1.NotificationTypeWebex:   webexMaxMessageSize,
+}
+
+// invalidFileNameChars matches the characters replaced in templated attachment names
+var invalidFileNameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// attachmentNameData is the data attachment name templates are evaluated against
+type attachmentNameData struct {
+	Cleaner   string
+	Action    appsv1alpha1.Action
+	Count     int
+	Timestamp string
+}
+
+// getAttachmentName returns the name of the report file, with the given extension,
+// attached by notification. Empty string is returned when notification has no
+// AttachmentNameTemplate, or the template yields an empty name, and the default
+// name of the notification type must be used.
+func getAttachmentName(cleanerName string, reportSpec *appsv1alpha1.ReportSpec, extension string,
+	notification *appsv1alpha1.Notification, now time.Time) (string, error) {
+
+	if notification.AttachmentNameTemplate == "" {
+		return "", nil
+	}
+
+	tmpl, err := template.New("attachmentName").Option("missingkey=zero").Parse(notification.AttachmentNameTemplate)
+	if err != nil {
+		return "", fmt.Errorf("invalid attachment name template: %w", err)
+	}
+
+	count := len(reportSpec.ResourceInfo)
+	if reportSpec.Summary != nil {
+		count = int(reportSpec.Summary.Total)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, attachmentNameData{
+		Cleaner:   cleanerName,
+		Action:    reportSpec.Action,
+		Count:     count,
+		Timestamp: now.UTC().Format(reportTimestampFormat),
+	}); err != nil {
+		return "", fmt.Errorf("failed to evaluate attachment name template: %w", err)
+	}
+
+	name := sanitizeFileName(buf.String())
+	if name == "" {
+		return "", nil
+	}
+	return name + "." + extension, nil
+}
+
+// sanitizeFileName replaces characters not allowed in file names with '-'.
+// Leading dots and dashes are removed, so the name is neither hidden nor
+// parsed as an option, and the name is truncated to maxAttachmentNameLength.
+func sanitizeFileName(name string) string {
+	name = invalidFileNameChars.ReplaceAllString(strings.TrimSpace(name), "-")
+	name = strings.TrimLeft(name, ".-")
+	if len(name) > maxAttachmentNameLength {
+		name = name[:maxAttachmentNameLength]
+	}
+	return strings.TrimRight(name, ".-")
+}
+
+// isAttachmentDisabled returns true if notification must never upload the
+// report as a file
+func isAttachmentDisabled(notification *appsv1alpha1.Notification) bool {
+	_, ok := inlineReportSizeLimits[notification.Type]
+	return ok && notification.DisableAttachments
+}
+
+// getInlineReport returns reportSpec as a markdown code block of at most limit
+// bytes. Trailing resources which do not fit are dropped, and the truncation
+// marker, with the number of resources omitted, ends the block. An empty string
+// is returned when limit leaves no room for the report.
+func getInlineReport(reportSpec *appsv1alpha1.ReportSpec, limit int, encoding reportEncoding) (string, error) {
+	const closingFence = "\n```"
+	limit -= len(inlineReportFence) + len(closingFence)
+	if limit <= 0 {
+		return "", nil
+	}
+
+	data, err := truncateReport(reportSpec, limit, encoding)
+	if err != nil {
+		return "", err
+	}
+	return inlineReportFence + data + closingFence, nil
+}
+
+// openReportFile writes data to a temporary file, named after pattern as in
+// os.CreateTemp, and returns it opened for reading, so it can be attached to a
+// message. The returned function closes and removes the file.
+func openReportFile(pattern, data string, logger logr.Logger) (*os.File, func(), error) {
+	tmpFile, err := os.CreateTemp(os.TempDir(), pattern)
+	if err != nil {
+		logger.Error(err, "failed to create temporary file")
+		return nil, nil, err
+	}
+	removeFile := func() {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+	}
+
+	if _, err = tmpFile.WriteString(data); err != nil {
+		logger.Error(err, logMsgWriteTemporaryFileFailed)
+		removeFile()
+		return nil, nil, err
+	}
+
+	fileReader, err := os.Open(tmpFile.Name())
+	if err != nil {
+		removeFile()
+		return nil, nil, fmt.Errorf("error opening file: %w", err)
+	}
+
+	return fileReader, func() {
+		fileReader.Close()
+		removeFile()
+	}, nil
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+var _ = Describe("Attachment name", func() {
+	reportSpec := &appsv1alpha1.ReportSpec{
+		Action:  appsv1alpha1.ActionDelete,
+		Summary: &appsv1alpha1.ReportSummary{Total: 3},
+	}
+	now := time.Date(2024, time.May, 15, 10, 30, 0, 0, time.UTC)
+
+	It("getAttachmentName evaluates the template and appends the extension", func() {
+		notification := &appsv1alpha1.Notification{
+			AttachmentNameTemplate: "{{ .Cleaner }}-{{ .Action }}-{{ .Count }}-{{ .Timestamp }}",
+		}
+
+		name, err := executor.GetAttachmentName("stale-pods", reportSpec, "json", notification, now)
+		Expect(err).To(BeNil())
+		Expect(name).To(Equal("stale-pods-Delete-3-20240515-103000.json"))
+	})
+
+	It("getAttachmentName sanitizes the name", func() {
+		notification := &appsv1alpha1.Notification{
+			AttachmentNameTemplate: "../reports/{{ .Cleaner }} report: {{ .Action }}..",
+		}
+
+		name, err := executor.GetAttachmentName("stale-pods", reportSpec, "csv", notification, now)
+		Expect(err).To(BeNil())
+		Expect(name).To(Equal("reports-stale-pods-report-Delete.csv"))
+	})
+
+	It("getAttachmentName returns empty name when the default name must be used", func() {
+		for _, text := range []string{"", "{{ if false }}x{{ end }}", " / "} {
+			notification := &appsv1alpha1.Notification{AttachmentNameTemplate: text}
+			name, err := executor.GetAttachmentName("stale-pods", reportSpec, "json", notification, now)
+			Expect(err).To(BeNil())
+			Expect(name).To(BeEmpty())
+		}
+
+		notification := &appsv1alpha1.Notification{AttachmentNameTemplate: "{{ .Cleaner"}
+		_, err := executor.GetAttachmentName("stale-pods", reportSpec, "json", notification, now)
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("sendNotifications writes File reports with the templated name", func() {
+		dir := GinkgoT().TempDir()
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeFile, nil)
+		cleaner.Spec.Notifications[0].File = &appsv1alpha1.FileOptions{Path: dir}
+		cleaner.Spec.Notifications[0].AttachmentNameTemplate = "{{ .Action }}-{{ .Cleaner }}"
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(listFiles(dir)).To(Equal([]string{"Delete-" + cleaner.Name + ".json"}))
+	})
+
+	It("sendNotifications attaches Webex reports with the templated name", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.WebexRoomID: []byte(randomWebexRoomID()),
+			libsveltosv1alpha1.WebexToken:  []byte(randomString()),
+		})
+
+		fake := &fakeWebexClient{}
+		useFakeWebexClient(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeWebex, ref)
+		cleaner.Spec.Notifications[0].AttachmentNameTemplate = "{{ .Cleaner }}-{{ .Count }}"
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.requests).To(HaveLen(1))
+		Expect(fake.requests[0].Files).To(HaveLen(1))
+		Expect(fake.requests[0].Files[0].Name).To(Equal(cleaner.Name + "-1.json"))
+	})
+})
+
+var _ = Describe("Disabled attachments", func() {
+	It("sendNotifications sends the report inline to Slack instead of uploading it", func() {
+		ref, fake := createSlackSecret()
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications[0].DisableAttachments = true
+		cleaner.Spec.Notifications[0].Slack = &appsv1alpha1.SlackOptions{UploadReport: true}
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.uploads).To(BeEmpty())
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).ToNot(ContainSubstring("Full report attached"))
+		Expect(fake.values[0].Get("attachments")).To(ContainSubstring(resource.Message))
+	})
+
+	It("sendNotifications sends the report inline, truncated, to Discord instead of attaching it", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.DiscordChannelID: []byte(randomDiscordID()),
+			libsveltosv1alpha1.DiscordToken:     []byte(randomString()),
+		})
+		fake := &fakeDiscordClient{}
+		useFakeDiscordClient(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeDiscord, ref)
+		cleaner.Spec.Notifications[0].DisableAttachments = true
+		cleaner.Spec.DisableReport = true
+		// Resources are sorted in reports, so the first one is the first listed
+		namespace := randomString()
+		resources := make([]executor.ResourceResult, 40)
+		for i := range resources {
+			resources[i] = getResourceResult("ConfigMap", namespace, fmt.Sprintf("%02d-%s", i, randomString()))
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.files).To(BeEmpty())
+		Expect(fake.messages).To(HaveLen(2))
+		Expect(fake.messages[0].Embeds).To(HaveLen(1))
+		report := fake.messages[1].Content
+		Expect(len([]rune(report))).To(BeNumerically("<=", 2000))
+		Expect(report).To(HavePrefix("```json\n"))
+		Expect(report).To(HaveSuffix("\n```"))
+		Expect(report).To(ContainSubstring(resources[0].Resource.GetName()))
+		Expect(report).To(MatchRegexp(`…\(truncated, \d+ resources omitted\)`))
+	})
+
+	It("sendNotifications adds the report to the Webex message instead of attaching it", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.WebexRoomID: []byte(randomWebexRoomID()),
+			libsveltosv1alpha1.WebexToken:  []byte(randomString()),
+		})
+		fake := &fakeWebexClient{}
+		useFakeWebexClient(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeWebex, ref)
+		cleaner.Spec.Notifications[0].DisableAttachments = true
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.files).To(BeEmpty())
+		Expect(fake.requests).To(HaveLen(1))
+		markdown := fake.requests[0].Markdown
+		Expect(len(markdown)).To(BeNumerically("<=", 7439))
+		Expect(markdown).To(ContainSubstring("```json\n"))
+		Expect(markdown[strings.Index(markdown, "```json"):]).To(ContainSubstring(resource.Message))
+	})
+})
@@ -0,0 +1,92 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+const (
+	httpAuthTypeNone   = "none"
+	httpAuthTypeBasic  = "basic"
+	httpAuthTypeBearer = "bearer"
+)
+
+// httpAuth is the authentication set on requests sent to an HTTP endpoint
+type httpAuth struct {
+	authType string
+	username string
+	password string
+	token    string
+}
+
+// getCloudEventsAuth returns the authentication defined in the CloudEvents
+// notification Secret. Nil is returned when no authentication is used.
+func getCloudEventsAuth(secret *corev1.Secret) (*httpAuth, error) {
+	authType := httpAuthTypeNone
+	if value, ok := secret.Data[appsv1alpha1.CloudEventsAuthType]; ok {
+		authType = strings.ToLower(strings.TrimSpace(string(value)))
+	}
+
+	switch authType {
+	case httpAuthTypeNone, "":
+		return nil, nil
+	case httpAuthTypeBasic:
+		username := secret.Data[appsv1alpha1.CloudEventsUsername]
+		if len(username) == 0 {
+			return nil, fmt.Errorf("secret must contain %s when %s is %s",
+				appsv1alpha1.CloudEventsUsername, appsv1alpha1.CloudEventsAuthType, httpAuthTypeBasic)
+		}
+		password, ok := secret.Data[appsv1alpha1.CloudEventsPassword]
+		if !ok {
+			return nil, fmt.Errorf("secret must contain %s when %s is %s",
+				appsv1alpha1.CloudEventsPassword, appsv1alpha1.CloudEventsAuthType, httpAuthTypeBasic)
+		}
+		return &httpAuth{authType: authType, username: string(username), password: string(password)}, nil
+	case httpAuthTypeBearer:
+		token := secret.Data[appsv1alpha1.CloudEventsToken]
+		if len(token) == 0 {
+			return nil, fmt.Errorf("secret must contain %s when %s is %s",
+				appsv1alpha1.CloudEventsToken, appsv1alpha1.CloudEventsAuthType, httpAuthTypeBearer)
+		}
+		return &httpAuth{authType: authType, token: strings.TrimSpace(string(token))}, nil
+	default:
+		return nil, fmt.Errorf("unsupported %s %q (must be %s, %s or %s)", appsv1alpha1.CloudEventsAuthType,
+			authType, httpAuthTypeBasic, httpAuthTypeBearer, httpAuthTypeNone)
+	}
+}
+
+// setAuthorization sets the Authorization header of req. Nothing is done when
+// a is nil.
+func (a *httpAuth) setAuthorization(req *http.Request) {
+	if a == nil {
+		return
+	}
+
+	switch a.authType {
+	case httpAuthTypeBasic:
+		req.SetBasicAuth(a.username, a.password)
+	case httpAuthTypeBearer:
+		req.Header.Set("Authorization", "Bearer "+a.token)
+	}
+}
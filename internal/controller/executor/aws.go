@@ -0,0 +1,252 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	awsSigningAlgorithm = "AWS4-HMAC-SHA256"
+	awsDateFormat       = "20060102T150405Z"
+
+	awsRequestTimeout = 30 * time.Second
+
+	// maximum number of bytes of an AWS error response included in errors
+	maxAWSResponseBody = 4096
+
+	// Environment variables set by EKS on pods whose service account is
+	// annotated with an IAM role (IRSA)
+	awsRoleARNEnv              = "AWS_ROLE_ARN"
+	awsWebIdentityTokenFileEnv = "AWS_WEB_IDENTITY_TOKEN_FILE"
+
+	awsRoleSessionName = "k8s-cleaner"
+)
+
+// stsURL is the AWS STS endpoint used to assume the IRSA role. If empty, the
+// regional endpoint of the bucket region is used.
+var stsURL = ""
+
+// awsCredentials are the credentials AWS requests are signed with
+type awsCredentials struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+// assumeRoleWithWebIdentityResponse is the subset of the STS
+// AssumeRoleWithWebIdentity response used to sign requests
+type assumeRoleWithWebIdentityResponse struct {
+	Credentials struct {
+		AccessKeyID     string `xml:"AccessKeyId"`
+		SecretAccessKey string `xml:"SecretAccessKey"`
+		SessionToken    string `xml:"SessionToken"`
+	} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+}
+
+// getIRSACredentials returns temporary credentials of the IAM role associated
+// to the k8s-cleaner service account, exchanging the projected service account
+// token with STS
+func getIRSACredentials(ctx context.Context, region string) (*awsCredentials, error) {
+	roleARN := os.Getenv(awsRoleARNEnv)
+	tokenFile := os.Getenv(awsWebIdentityTokenFileEnv)
+	if roleARN == "" || tokenFile == "" {
+		return nil, fmt.Errorf("no AWS credentials in secret and %s/%s not set: "+
+			"add credentials to the secret or associate an IAM role to the k8s-cleaner service account",
+			awsRoleARNEnv, awsWebIdentityTokenFileEnv)
+	}
+
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read web identity token: %w", err)
+	}
+
+	endpoint := stsURL
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://sts.%s.amazonaws.com/", region)
+	}
+	form := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {roleARN},
+		"RoleSessionName":  {awsRoleSessionName},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := newNotificationHTTPClient(awsRequestTimeout).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assume role %s: %w", roleARN, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxAWSResponseBody))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to assume role %s: sts returned %s: %s", roleARN, resp.Status, getAWSError(body))
+	}
+
+	result := &assumeRoleWithWebIdentityResponse{}
+	if err := xml.Unmarshal(body, result); err != nil {
+		return nil, fmt.Errorf("failed to parse sts response: %w", err)
+	}
+	if result.Credentials.AccessKeyID == "" {
+		return nil, fmt.Errorf("sts response does not contain credentials")
+	}
+
+	return &awsCredentials{
+		accessKeyID:     result.Credentials.AccessKeyID,
+		secretAccessKey: result.Credentials.SecretAccessKey,
+		sessionToken:    result.Credentials.SessionToken,
+	}, nil
+}
+
+// awsError is the error returned by AWS APIs
+type awsError struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// getAWSError returns the code and message of the AWS error contained in body.
+// body is returned as is if it cannot be parsed.
+func getAWSError(body []byte) string {
+	result := &struct {
+		awsError
+		// STS wraps the error
+		Error awsError `xml:"Error"`
+	}{}
+	if err := xml.Unmarshal(body, result); err != nil {
+		return string(body)
+	}
+	for _, e := range []awsError{result.awsError, result.Error} {
+		if e.Code != "" {
+			return fmt.Sprintf("%s: %s", e.Code, e.Message)
+		}
+	}
+	return string(body)
+}
+
+// signAWSRequest signs req with AWS Signature Version 4. payloadHash is the hex
+// encoded SHA256 of the request body. Host and all headers set on req are signed.
+// https://docs.aws.amazon.com/IAM/latest/UserGuide/create-signed-request.html
+func signAWSRequest(req *http.Request, payloadHash string, credentials *awsCredentials,
+	region, service string, now time.Time) {
+
+	amzDate := now.UTC().Format(awsDateFormat)
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", amzDate[:8], region, service)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if credentials.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		awsURIEncode(req.URL.EscapedPath()),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		awsSigningAlgorithm,
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+credentials.secretAccessKey), amzDate[:8])
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		awsSigningAlgorithm, credentials.accessKeyID, scope, signedHeaders, signature))
+}
+
+// awsURIEncode returns the canonical URI of an already escaped path: every
+// byte other than unreserved characters and "/" is percent-encoded
+func awsURIEncode(escapedPath string) string {
+	path, err := url.PathUnescape(escapedPath)
+	if err != nil {
+		path = escapedPath
+	}
+	if path == "" {
+		return "/"
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+
+			sb.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&sb, "%%%02X", c)
+	}
+	return sb.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
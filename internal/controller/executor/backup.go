@@ -0,0 +1,197 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+const (
+	// backupDataKey is the key of the ConfigMap data containing the backup
+	backupDataKey = "resource.yaml"
+
+	// backupTimestampFormat is the format of the time added to backup names,
+	// so backups of a resource recreated and deleted again are all kept
+	backupTimestampFormat = "20060102-150405"
+
+	// maxBackupConfigMapPrefix is the maximum length of the Cleaner name used
+	// as prefix of backup ConfigMap names
+	maxBackupConfigMapPrefix = 200
+)
+
+// backupMetadataFields are the metadata fields removed from backups, as they
+// are set by the API server and prevent the resource from being re-created
+var backupMetadataFields = []string{
+	"resourceVersion", "uid", "creationTimestamp", "deletionTimestamp", "deletionGracePeriodSeconds",
+	"generation", "managedFields", "selfLink",
+}
+
+// backupResource backs up resource to the storage configured by backup and
+// returns the reference to the backup
+func backupResource(ctx context.Context, cleanerName string, backup *appsv1alpha1.ResourceBackup,
+	resource *unstructured.Unstructured, now time.Time) (*appsv1alpha1.BackupReference, error) {
+
+	data, err := getBackupData(resource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize resource: %w", err)
+	}
+
+	switch backup.Type {
+	case appsv1alpha1.BackupSinkPersistentVolume:
+		return backupToFile(cleanerName, backup, resource, data, now)
+	case appsv1alpha1.BackupSinkS3:
+		return backupToS3(ctx, cleanerName, backup, resource, data, now)
+	case appsv1alpha1.BackupSinkConfigMap:
+		return backupToConfigMap(ctx, cleanerName, backup, resource, data, now)
+	default:
+		return nil, fmt.Errorf("unknown backup type %q", backup.Type)
+	}
+}
+
+// getBackupData returns the YAML of resource, without the fields set by the
+// API server and without status
+func getBackupData(resource *unstructured.Unstructured) ([]byte, error) {
+	backup := resource.DeepCopy()
+	for _, field := range backupMetadataFields {
+		unstructured.RemoveNestedField(backup.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(backup.Object, "status")
+	return yaml.Marshal(backup.Object)
+}
+
+// getBackupPath returns the path of the backup of resource, relative to the
+// backup directory or prefix: <cleaner>/<namespace>/<kind>/<name>-<timestamp>.yaml
+func getBackupPath(cleanerName string, resource *unstructured.Unstructured, now time.Time) string {
+	return path.Join(cleanerName, resource.GetNamespace(), resource.GetKind(),
+		fmt.Sprintf("%s-%s.yaml", resource.GetName(), now.UTC().Format(backupTimestampFormat)))
+}
+
+func backupToFile(cleanerName string, backup *appsv1alpha1.ResourceBackup, resource *unstructured.Unstructured,
+	data []byte, now time.Time) (*appsv1alpha1.BackupReference, error) {
+
+	if backup.Path == "" {
+		return nil, fmt.Errorf("backup path is not set")
+	}
+	if _, err := os.Stat(backup.Path); err != nil {
+		return nil, fmt.Errorf("backup directory %s not found: %w", backup.Path, err)
+	}
+
+	file := filepath.Join(backup.Path, getBackupPath(cleanerName, resource, now))
+	if err := os.MkdirAll(filepath.Dir(file), permission0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(file, data, permission0600); err != nil {
+		return nil, err
+	}
+
+	return &appsv1alpha1.BackupReference{
+		Type:           appsv1alpha1.BackupSinkPersistentVolume,
+		Location:       file,
+		RestoreCommand: "kubectl apply -f " + file,
+	}, nil
+}
+
+func backupToS3(ctx context.Context, cleanerName string, backup *appsv1alpha1.ResourceBackup,
+	resource *unstructured.Unstructured, data []byte, now time.Time) (*appsv1alpha1.BackupReference, error) {
+
+	if backup.SecretRef == nil {
+		return nil, fmt.Errorf("backup secretRef is not set")
+	}
+	secret, err := getSecretFromRef(ctx, backup.SecretRef)
+	if err != nil {
+		return nil, err
+	}
+	info, err := getS3InfoFromSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	key := path.Join(info.prefix, getBackupPath(cleanerName, resource, now))
+	if err := putS3Object(ctx, info, key, data,
+		map[string]string{"Content-Type": s3ContentTypes[appsv1alpha1.ReportFormatYAML]}); err != nil {
+		return nil, err
+	}
+
+	location := fmt.Sprintf("s3://%s/%s", info.bucket, key)
+	copyCommand := "aws s3 cp"
+	if info.endpoint != "" {
+		copyCommand += " --endpoint-url " + info.endpoint
+	}
+	return &appsv1alpha1.BackupReference{
+		Type:           appsv1alpha1.BackupSinkS3,
+		Location:       location,
+		RestoreCommand: fmt.Sprintf("%s %s - | kubectl apply -f -", copyCommand, location),
+	}, nil
+}
+
+func backupToConfigMap(ctx context.Context, cleanerName string, backup *appsv1alpha1.ResourceBackup,
+	resource *unstructured.Unstructured, data []byte, now time.Time) (*appsv1alpha1.BackupReference, error) {
+
+	if backup.Namespace == "" {
+		return nil, fmt.Errorf("backup namespace is not set")
+	}
+
+	c, err := getK8sClient()
+	if err != nil {
+		return nil, err
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: backup.Namespace,
+			Name:      getBackupConfigMapName(cleanerName, resource, now),
+			Labels:    map[string]string{appsv1alpha1.BackupCleanerLabel: cleanerName},
+		},
+		Data: map[string]string{backupDataKey: string(data)},
+	}
+	if err := c.Create(ctx, configMap); err != nil {
+		return nil, err
+	}
+
+	return &appsv1alpha1.BackupReference{
+		Type:     appsv1alpha1.BackupSinkConfigMap,
+		Location: configMap.Namespace + "/" + configMap.Name,
+		RestoreCommand: fmt.Sprintf("kubectl get configmap %s -n %s -o jsonpath='{.data.resource\\.yaml}' | kubectl apply -f -",
+			configMap.Name, configMap.Namespace),
+	}, nil
+}
+
+// getBackupConfigMapName returns the name of the ConfigMap backing up resource:
+// the Cleaner name, a hash identifying the resource and the backup time
+func getBackupConfigMapName(cleanerName string, resource *unstructured.Unstructured, now time.Time) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%s/%s", resource.GetAPIVersion(), resource.GetKind(),
+		resource.GetNamespace(), resource.GetName())))
+	prefix := cleanerName
+	if len(prefix) > maxBackupConfigMapPrefix {
+		prefix = prefix[:maxBackupConfigMapPrefix]
+	}
+	return fmt.Sprintf("%s-%s-%s", prefix, hex.EncodeToString(hash[:])[:10], now.UTC().Format(backupTimestampFormat))
+}
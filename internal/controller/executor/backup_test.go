@@ -0,0 +1,222 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+// createBackupConfigMap creates a ConfigMap in a new namespace and returns it
+// as matched resource
+func createBackupConfigMap() (*corev1.ConfigMap, executor.ResourceResult) {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: randomString()}}
+	Expect(k8sClient.Create(context.TODO(), ns)).To(Succeed())
+	Expect(waitForObject(context.TODO(), k8sClient, ns)).To(Succeed())
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, Name: randomString()},
+		Data:       map[string]string{"key": randomString()},
+	}
+	Expect(k8sClient.Create(context.TODO(), configMap)).To(Succeed())
+	Expect(waitForObject(context.TODO(), k8sClient, configMap)).To(Succeed())
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(configMap)
+	Expect(err).To(BeNil())
+	resource := &unstructured.Unstructured{Object: content}
+	resource.SetAPIVersion("v1")
+	resource.SetKind("ConfigMap")
+	return configMap, executor.ResourceResult{Resource: resource}
+}
+
+// getBackupCleaner returns a Cleaner deleting resources after backing them up
+// to backup
+func getBackupCleaner(backup *appsv1alpha1.ResourceBackup) *appsv1alpha1.Cleaner {
+	return &appsv1alpha1.Cleaner{
+		ObjectMeta: metav1.ObjectMeta{Name: randomString()},
+		Spec: appsv1alpha1.CleanerSpec{
+			Action: appsv1alpha1.ActionDelete,
+			Backup: backup,
+		},
+	}
+}
+
+// expectBackup verifies data is the backup of configMap, which can be created again
+func expectBackup(data []byte, configMap *corev1.ConfigMap) {
+	restored := &corev1.ConfigMap{}
+	Expect(yaml.Unmarshal(data, restored)).To(Succeed())
+	Expect(restored.Name).To(Equal(configMap.Name))
+	Expect(restored.Namespace).To(Equal(configMap.Namespace))
+	Expect(restored.Data).To(Equal(configMap.Data))
+	Expect(restored.ResourceVersion).To(BeEmpty())
+	Expect(restored.UID).To(BeEmpty())
+}
+
+// expectDeleted verifies configMap no longer exists
+func expectDeleted(configMap *corev1.ConfigMap) {
+	err := k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(configMap), &corev1.ConfigMap{})
+	Expect(apierrors.IsNotFound(err)).To(BeTrue())
+}
+
+var _ = Describe("Resource backup", func() {
+	It("deleteMatchingResources backs up resources to a volume before deleting them", func() {
+		dir := GinkgoT().TempDir()
+		configMap, resource := createBackupConfigMap()
+		cleaner := getBackupCleaner(&appsv1alpha1.ResourceBackup{
+			Type: appsv1alpha1.BackupSinkPersistentVolume,
+			Path: dir,
+		})
+
+		processed, err := executor.DeleteMatchingResources(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, logr.Discard())
+		Expect(err).To(BeNil())
+		Expect(processed).To(HaveLen(1))
+		Expect(processed[0].Outcome).To(Equal(appsv1alpha1.ResourceOutcomeSucceeded))
+
+		backup := processed[0].Backup
+		Expect(backup).ToNot(BeNil())
+		Expect(backup.Type).To(Equal(appsv1alpha1.BackupSinkPersistentVolume))
+		Expect(backup.Location).To(MatchRegexp(`^%s/%s/%s/ConfigMap/%s-\d{8}-\d{6}\.yaml$`,
+			dir, cleaner.Name, configMap.Namespace, configMap.Name))
+		Expect(backup.RestoreCommand).To(Equal("kubectl apply -f " + backup.Location))
+
+		data, err := os.ReadFile(backup.Location)
+		Expect(err).To(BeNil())
+		expectBackup(data, configMap)
+		expectDeleted(configMap)
+	})
+
+	It("deleteMatchingResources backs up resources to ConfigMaps before deleting them", func() {
+		configMap, resource := createBackupConfigMap()
+		cleaner := getBackupCleaner(&appsv1alpha1.ResourceBackup{
+			Type:      appsv1alpha1.BackupSinkConfigMap,
+			Namespace: configMap.Namespace,
+		})
+
+		processed, err := executor.DeleteMatchingResources(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, logr.Discard())
+		Expect(err).To(BeNil())
+		Expect(processed).To(HaveLen(1))
+
+		backup := processed[0].Backup
+		Expect(backup).ToNot(BeNil())
+		Expect(backup.Type).To(Equal(appsv1alpha1.BackupSinkConfigMap))
+		Expect(backup.Location).To(HavePrefix(configMap.Namespace + "/" + cleaner.Name + "-"))
+		name := strings.TrimPrefix(backup.Location, configMap.Namespace+"/")
+		Expect(backup.RestoreCommand).To(Equal("kubectl get configmap " + name + " -n " + configMap.Namespace +
+			` -o jsonpath='{.data.resource\.yaml}' | kubectl apply -f -`))
+
+		stored := &corev1.ConfigMap{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Namespace: configMap.Namespace, Name: name},
+			stored)).To(Succeed())
+		Expect(stored.Labels).To(HaveKeyWithValue(appsv1alpha1.BackupCleanerLabel, cleaner.Name))
+		expectBackup([]byte(stored.Data["resource.yaml"]), configMap)
+		expectDeleted(configMap)
+	})
+
+	It("deleteMatchingResources backs up resources to S3 before deleting them", func() {
+		var uploads []s3Upload
+		server := newS3Server(&uploads)
+		bucket := randomString()
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.S3Bucket:           []byte(bucket),
+			appsv1alpha1.S3Region:           []byte("eu-west-1"),
+			appsv1alpha1.S3Prefix:           []byte("backups"),
+			appsv1alpha1.S3Endpoint:         []byte(server.URL),
+			appsv1alpha1.AWSAccessKeyID:     []byte(randomString()),
+			appsv1alpha1.AWSSecretAccessKey: []byte(randomString()),
+		})
+
+		configMap, resource := createBackupConfigMap()
+		cleaner := getBackupCleaner(&appsv1alpha1.ResourceBackup{
+			Type:      appsv1alpha1.BackupSinkS3,
+			SecretRef: ref,
+		})
+
+		processed, err := executor.DeleteMatchingResources(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, logr.Discard())
+		Expect(err).To(BeNil())
+		Expect(processed).To(HaveLen(1))
+
+		Expect(uploads).To(HaveLen(1))
+		Expect(uploads[0].path).To(MatchRegexp(`^/%s/backups/%s/%s/ConfigMap/%s-\d{8}-\d{6}\.yaml$`,
+			bucket, cleaner.Name, configMap.Namespace, configMap.Name))
+		Expect(uploads[0].header.Get("Content-Type")).To(Equal("application/yaml"))
+		expectBackup(uploads[0].body, configMap)
+
+		backup := processed[0].Backup
+		Expect(backup).ToNot(BeNil())
+		Expect(backup.Location).To(Equal("s3://" + bucket + strings.TrimPrefix(uploads[0].path, "/"+bucket)))
+		Expect(backup.RestoreCommand).To(Equal("aws s3 cp --endpoint-url " + server.URL + " " + backup.Location +
+			" - | kubectl apply -f -"))
+		expectDeleted(configMap)
+	})
+
+	It("deleteMatchingResources does not delete resources which cannot be backed up", func() {
+		configMap, resource := createBackupConfigMap()
+		cleaner := getBackupCleaner(&appsv1alpha1.ResourceBackup{
+			Type: appsv1alpha1.BackupSinkPersistentVolume,
+			Path: "/" + randomString(),
+		})
+
+		processed, err := executor.DeleteMatchingResources(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(HavePrefix("failed to delete 1 of 1 resources"))
+		Expect(processed).To(HaveLen(1))
+		Expect(processed[0].Outcome).To(Equal(appsv1alpha1.ResourceOutcomeFailed))
+		Expect(processed[0].Error).To(HavePrefix("backup failed, resource not deleted: backup directory"))
+		Expect(processed[0].Backup).To(BeNil())
+
+		Expect(k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(configMap), &corev1.ConfigMap{})).To(Succeed())
+	})
+
+	It("sendNotifications includes the backup of resources in reports", func() {
+		ref, fake := createSlackSecret()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications[0].IncludeKubectlCommands = true
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		resource.Backup = &appsv1alpha1.BackupReference{
+			Type:           appsv1alpha1.BackupSinkPersistentVolume,
+			Location:       "/backups/" + resource.Resource.GetName() + ".yaml",
+			RestoreCommand: "kubectl apply -f /backups/" + resource.Resource.GetName() + ".yaml",
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(ContainSubstring(" (restore with: " + resource.Backup.RestoreCommand + ")"))
+		Expect(fake.values[0].Get("attachments")).To(ContainSubstring(resource.Backup.Location))
+	})
+})
@@ -0,0 +1,266 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// batchableNotificationTypes are the notification types whose reports can be
+// combined, across Cleaner instances, into a single message
+var batchableNotificationTypes = map[appsv1alpha1.NotificationType]bool{
+	appsv1alpha1.NotificationTypeSlack:   true,
+	appsv1alpha1.NotificationTypeTeams:   true,
+	appsv1alpha1.NotificationTypeDiscord: true,
+	appsv1alpha1.NotificationTypeWebex:   true,
+	appsv1alpha1.NotificationTypeSMTP:    true,
+}
+
+var (
+	// notificationBatchWindow is how long reports sent to the same target are
+	// collected before being delivered as a single message. Zero disables batching.
+	notificationBatchWindow time.Duration
+
+	batchMux sync.Mutex
+	// notificationBatches contains the pending batches, keyed by notification target
+	notificationBatches = map[string]*notificationBatch{}
+)
+
+// noBatchKey marks contexts whose notifications must be sent right away
+type noBatchKey struct{}
+
+// withoutBatching returns a copy of ctx whose notifications are never batched
+func withoutBatching(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noBatchKey{}, true)
+}
+
+// batchEntry is the report of a Cleaner run waiting to be delivered in a batch
+type batchEntry struct {
+	cleaner          *appsv1alpha1.Cleaner
+	notificationName string
+	reportSpec       *appsv1alpha1.ReportSpec
+	message          string
+}
+
+// notificationBatch collects the reports sent to the same target within
+// notificationBatchWindow
+type notificationBatch struct {
+	// notification is the first notification added to the batch. Its settings
+	// are used to deliver the batch.
+	notification *appsv1alpha1.Notification
+	entries      []batchEntry
+	timer        *time.Timer
+	logger       logr.Logger
+}
+
+// SetNotificationBatchWindow sets how long reports of different Cleaner instances
+// sent to the same notification target are collected before being delivered as a
+// single message. Zero disables batching.
+func SetNotificationBatchWindow(window time.Duration) error {
+	if window < 0 {
+		return fmt.Errorf("invalid notification batch window %s: must not be negative", window)
+	}
+	notificationBatchWindow = window
+	return nil
+}
+
+// isBatchNotification returns true if the report of a run must be added to the
+// batch of the notification target instead of being sent right away.
+// Digests, routed and threaded notifications depend on the Cleaner instance, so
+// they are never batched, nor are notifications sent with a context returned by
+// withoutBatching.
+func isBatchNotification(ctx context.Context, notification *appsv1alpha1.Notification) bool {
+	if noBatch, _ := ctx.Value(noBatchKey{}).(bool); noBatch {
+		return false
+	}
+	return notificationBatchWindow > 0 && batchableNotificationTypes[notification.Type] &&
+		!isDigestNotification(notification) && notification.ChannelTemplate == "" &&
+		!isSlackThreadNotification(notification)
+}
+
+// getNotificationTarget returns the key identifying where notification is sent.
+// Notifications of the same type using the same credentials share a target.
+func getNotificationTarget(notification *appsv1alpha1.Notification) string {
+	if notification.NotificationRef == nil {
+		return fmt.Sprintf("%s/env/%s", notification.Type, getEnvPrefix(notification))
+	}
+	return fmt.Sprintf("%s/%s/%s", notification.Type, notification.NotificationRef.Namespace,
+		notification.NotificationRef.Name)
+}
+
+// addToNotificationBatch adds reportSpec to the batch of the notification target.
+// The first report added to a batch starts the window after which the batch is
+// delivered. Report is redacted according to the Cleaner it belongs to, and
+// only contains the fields selected by notification.
+func addToNotificationBatch(cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	message string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+	redactor, err := getReportRedactor(cleaner.Spec.ReportRedaction, notification.Type)
+	if err != nil {
+		return err
+	}
+	entry := batchEntry{
+		cleaner:          cleaner.DeepCopy(),
+		notificationName: notification.Name,
+		reportSpec:       redactor.redactReport(selectReportFields(reportSpec, notification)),
+		message:          redactor.redactString(message),
+	}
+
+	target := getNotificationTarget(notification)
+
+	batchMux.Lock()
+	defer batchMux.Unlock()
+
+	batch, ok := notificationBatches[target]
+	if !ok {
+		batch = &notificationBatch{
+			notification: notification.DeepCopy(),
+			logger:       logger.WithValues("batchTarget", target),
+		}
+		batch.timer = time.AfterFunc(notificationBatchWindow, func() {
+			flushNotificationBatch(target, batch)
+		})
+		notificationBatches[target] = batch
+	}
+	batch.entries = append(batch.entries, entry)
+	logger.V(logs.LogDebug).Info("report added to batch", "batchTarget", target, "batchSize", len(batch.entries))
+	return nil
+}
+
+// FlushNotificationBatchesOnStop waits for ctx to be cancelled, then delivers all
+// pending batches, so that reports already batched are not lost on shutdown
+func FlushNotificationBatchesOnStop(ctx context.Context) error {
+	<-ctx.Done()
+	flushNotificationBatches()
+	return nil
+}
+
+// flushNotificationBatches delivers all pending batches right away
+func flushNotificationBatches() {
+	batchMux.Lock()
+	batches := make(map[string]*notificationBatch, len(notificationBatches))
+	for target, batch := range notificationBatches {
+		batch.timer.Stop()
+		batches[target] = batch
+	}
+	batchMux.Unlock()
+
+	for target, batch := range batches {
+		flushNotificationBatch(target, batch)
+	}
+}
+
+// flushNotificationBatch delivers batch, if it is still the pending batch of
+// target, and records the delivery result for every notification in it
+func flushNotificationBatch(target string, batch *notificationBatch) {
+	batchMux.Lock()
+	if notificationBatches[target] != batch {
+		batchMux.Unlock()
+		return
+	}
+	delete(notificationBatches, target)
+	batchMux.Unlock()
+
+	ctx := context.Background()
+	logger := batch.logger
+	logger.V(logs.LogDebug).Info("send batch", "batchSize", len(batch.entries))
+
+	err := sendNotificationBatch(ctx, batch.notification, batch.entries, getBatchMessage, logger)
+	if err != nil {
+		logger.Error(err, logMsgSendFailed)
+	} else {
+		logger.V(logs.LogDebug).Info(logMsgNotificationDelivered)
+	}
+
+	now := time.Now()
+	recorded := make(map[string]bool)
+	for i := range batch.entries {
+		entry := &batch.entries[i]
+		key := entry.cleaner.Name + "/" + entry.notificationName
+		if recorded[key] {
+			continue
+		}
+		recorded[key] = true
+		if recordErr := recordNotificationResult(ctx, entry.cleaner, entry.notificationName, err, now); recordErr != nil {
+			logger.Error(recordErr, logMsgRecordStatusFailed)
+		}
+	}
+}
+
+// sendNotificationBatch sends, using the settings of notification, a single
+// notification combining the reports of batchEntries, grouped by Cleaner instance.
+// Its text is returned by getMessage.
+func sendNotificationBatch(ctx context.Context, notification *appsv1alpha1.Notification, batchEntries []batchEntry,
+	getMessage func(entries []batchEntry) string, logger logr.Logger) error {
+
+	n, err := getNotifier(notification.Type)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]batchEntry, len(batchEntries))
+	copy(entries, batchEntries)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].cleaner.Name < entries[j].cleaner.Name
+	})
+
+	// Resources of previous runs are not available, so no Event is recorded on
+	// resources for batches
+	return n.Send(ctx, entries[0].cleaner, getBatchReportSpec(entries), nil, getMessage(entries),
+		notification, logger)
+}
+
+// getBatchReportSpec returns the report combining the reports of entries.
+// Action is only set when all runs performed the same action.
+func getBatchReportSpec(entries []batchEntry) *appsv1alpha1.ReportSpec {
+	reportSpec := &appsv1alpha1.ReportSpec{
+		SchemaVersion: appsv1alpha1.ReportSchemaVersion,
+		Action:        entries[0].reportSpec.Action,
+	}
+	for i := range entries {
+		if entries[i].reportSpec.Action != reportSpec.Action {
+			reportSpec.Action = ""
+		}
+		reportSpec.ResourceInfo = append(reportSpec.ResourceInfo, entries[i].reportSpec.ResourceInfo...)
+	}
+	reportSpec.Summary = getReportSummary(reportSpec.ResourceInfo)
+	return reportSpec
+}
+
+// getBatchMessage returns the text sent along with a batch: one line per run,
+// in Cleaner order
+func getBatchMessage(entries []batchEntry) string {
+	cleaners := make(map[string]bool)
+	lines := make([]string, len(entries))
+	for i := range entries {
+		cleaners[entries[i].cleaner.Name] = true
+		lines[i] = "- " + entries[i].message
+	}
+	return fmt.Sprintf("k8s-cleaner batched %d report(s) from %d Cleaner instance(s):\n%s",
+		len(entries), len(cleaners), strings.Join(lines, "\n"))
+}
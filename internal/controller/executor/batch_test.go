@@ -0,0 +1,203 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+// enableNotificationBatching enables batching with a window long enough for
+// batches to be sent only when flushed
+func enableNotificationBatching() {
+	Expect(executor.SetNotificationBatchWindow(time.Hour)).To(Succeed())
+	DeferCleanup(func() {
+		executor.FlushNotificationBatches()
+		Expect(executor.SetNotificationBatchWindow(0)).To(Succeed())
+	})
+}
+
+// createSlackSecret creates a Slack notification secret and returns the fake
+// client messages are posted to
+func createSlackSecret() (*corev1.ObjectReference, *fakeSlackClient) {
+	ref := createNotificationSecret(map[string][]byte{
+		libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+		libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+	})
+	fake := &fakeSlackClient{}
+	DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+		return fake
+	}))
+	return ref, fake
+}
+
+var _ = Describe("Notification batching", func() {
+	It("SetNotificationBatchWindow rejects negative windows", func() {
+		Expect(executor.SetNotificationBatchWindow(-time.Second)).ToNot(Succeed())
+	})
+
+	It("sendNotifications combines reports of Cleaners sharing a target into one message", func() {
+		enableNotificationBatching()
+		ref, fake := createSlackSecret()
+
+		first := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		first.Name = "b-" + first.Name
+		second := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		second.Name = "a-" + second.Name
+		firstResource := getResourceResult("ConfigMap", randomString(), randomString())
+		secondResource := getResourceResult("Secret", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{firstResource},
+			first, "", logr.Discard())).To(Succeed())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{secondResource},
+			second, "", logr.Discard())).To(Succeed())
+		Expect(fake.values).To(BeEmpty())
+
+		executor.FlushNotificationBatches()
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(HavePrefix("k8s-cleaner batched 2 report(s) from 2 Cleaner instance(s):\n" +
+			"- k8s-cleaner '" + second.Name + "' performed Delete on 1 resource\n" +
+			"- k8s-cleaner '" + first.Name + "' performed Delete on 1 resource"))
+		attachments := fake.values[0].Get("attachments")
+		Expect(attachments).To(ContainSubstring(firstResource.Resource.GetName()))
+		Expect(attachments).To(ContainSubstring(secondResource.Resource.GetName()))
+	})
+
+	It("sendNotifications sends one message per target", func() {
+		enableNotificationBatching()
+		ref, fake := createSlackSecret()
+		otherRef := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+
+		for _, r := range []*corev1.ObjectReference{ref, otherRef} {
+			cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, r)
+			resource := getResourceResult("ConfigMap", randomString(), randomString())
+			Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+				cleaner, "", logr.Discard())).To(Succeed())
+		}
+		Expect(fake.values).To(BeEmpty())
+
+		executor.FlushNotificationBatches()
+
+		Expect(fake.values).To(HaveLen(2))
+		for i := range fake.values {
+			Expect(fake.values[i].Get("text")).To(HavePrefix("k8s-cleaner batched 1 report(s) from 1 Cleaner instance(s)"))
+		}
+	})
+
+	It("sendRunNotifications sends failure notifications right away", func() {
+		enableNotificationBatching()
+		ref, fake := createSlackSecret()
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications[0].NotifyOnFailure = true
+
+		Expect(executor.SendRunNotifications(context.TODO(), nil, cleaner, "", errors.New("list failed"),
+			logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(HavePrefix("Execution failed for k8s-cleaner instance"))
+	})
+
+	It("SendTestNotification sends test notifications right away", func() {
+		enableNotificationBatching()
+		ref, fake := createSlackSecret()
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		Expect(executor.SendTestNotification(context.TODO(), cleaner, cleaner.Spec.Notifications[0].Name,
+			logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+	})
+
+	It("sendNotifications does not batch notification types which are not messages", func() {
+		enableNotificationBatching()
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCloudEvents, nil)
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).ToNot(Succeed())
+	})
+
+	It("FlushNotificationBatchesOnStop delivers pending batches once stopped", func() {
+		enableNotificationBatching()
+		ref, fake := createSlackSecret()
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		done := make(chan error)
+		go func() {
+			done <- executor.FlushNotificationBatchesOnStop(ctx)
+		}()
+		Consistently(done, 100*time.Millisecond).ShouldNot(Receive())
+		Expect(fake.values).To(BeEmpty())
+
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("attachments")).To(ContainSubstring(resource.Resource.GetName()))
+	})
+
+	It("flushNotificationBatches records failed delivery on every Cleaner of the batch", func() {
+		enableNotificationBatching()
+		ref, fake := createSlackSecret()
+		fake.err = errors.New("channel_not_found")
+
+		names := []string{}
+		for i := 0; i < 2; i++ {
+			cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+			cleaner.Spec.Schedule = "0 * * * *"
+			cleaner.Spec.ResourcePolicySet.ResourceSelectors = []appsv1alpha1.ResourceSelector{
+				{Kind: "ConfigMap", Version: "v1"},
+			}
+			Expect(k8sClient.Create(context.TODO(), cleaner)).To(Succeed())
+			Expect(waitForObject(context.TODO(), k8sClient, cleaner)).To(Succeed())
+			names = append(names, cleaner.Name)
+
+			resource := getResourceResult("ConfigMap", randomString(), randomString())
+			Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+				cleaner, "", logr.Discard())).To(Succeed())
+		}
+
+		executor.FlushNotificationBatches()
+
+		Expect(fake.values).To(HaveLen(1))
+		for _, name := range names {
+			current := &appsv1alpha1.Cleaner{}
+			Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: name}, current)).To(Succeed())
+			Expect(current.Status.NotificationStatuses).To(HaveLen(1))
+			Expect(current.Status.NotificationStatuses[0].ConsecutiveFailures).To(Equal(int32(1)))
+		}
+	})
+})
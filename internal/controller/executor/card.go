@@ -0,0 +1,50 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"github.com/atc0005/go-teams-notify/v2/adaptivecard"
+)
+
+// addCardMetadata adds metadata, if any, to card as a set of facts sorted by key
+func addCardMetadata(card *adaptivecard.Card, metadata map[string]string) error {
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	factSet := adaptivecard.NewFactSet()
+	for _, key := range getSortedMetadataKeys(metadata) {
+		if err := factSet.AddFact(adaptivecard.Fact{Title: key, Value: metadata[key]}); err != nil {
+			return err
+		}
+	}
+	return card.AddFactSet(false, factSet)
+}
+
+// addCardLinks adds links, if any, to card as OpenUrl actions
+func addCardLinks(card *adaptivecard.Card, links []notificationLink) error {
+	for i := range links {
+		action, err := adaptivecard.NewActionOpenURL(links[i].url, links[i].label)
+		if err != nil {
+			return err
+		}
+		if err := card.AddAction(false, action); err != nil {
+			return err
+		}
+	}
+	return nil
+}
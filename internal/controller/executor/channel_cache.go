@@ -0,0 +1,78 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+const (
+	// channelIDCacheTTL is how long a channel (or room) name resolved to an ID
+	// is cached
+	channelIDCacheTTL = time.Hour
+)
+
+type channelIDCacheEntry struct {
+	id      string
+	expires time.Time
+}
+
+var (
+	channelIDMux sync.Mutex
+	// channelIDCache contains the channel IDs resolved from channel names, keyed
+	// by notification type, token hash and name
+	channelIDCache = map[string]channelIDCacheEntry{}
+)
+
+// getCachedChannelID returns the ID name was resolved to with token, if it was
+// resolved less than channelIDCacheTTL ago
+func getCachedChannelID(notificationType appsv1alpha1.NotificationType, token, name string,
+	now time.Time) (string, bool) {
+
+	channelIDMux.Lock()
+	defer channelIDMux.Unlock()
+
+	entry, ok := channelIDCache[getChannelIDCacheKey(notificationType, token, name)]
+	if !ok || !now.Before(entry.expires) {
+		return "", false
+	}
+	return entry.id, true
+}
+
+// cacheChannelID stores the ID name was resolved to with token
+func cacheChannelID(notificationType appsv1alpha1.NotificationType, token, name, id string, now time.Time) {
+	channelIDMux.Lock()
+	defer channelIDMux.Unlock()
+
+	channelIDCache[getChannelIDCacheKey(notificationType, token, name)] = channelIDCacheEntry{
+		id:      id,
+		expires: now.Add(channelIDCacheTTL),
+	}
+}
+
+// getChannelIDCacheKey returns the cache key of a channel name. Tokens are
+// hashed so they are not kept in memory.
+func getChannelIDCacheKey(notificationType appsv1alpha1.NotificationType, token, name string) string {
+	hash := sha256.Sum256([]byte(token))
+	return fmt.Sprintf("%s/%s/%s", notificationType, hex.EncodeToString(hash[:]), name)
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"fmt"
+	"sync"
+	"unicode/utf8"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+// channelLocks contains, for each chat channel, the mutex held while messages
+// of a chunked delivery are sent
+var channelLocks sync.Map
+
+// splitMessage splits content into at most maxParts messages of at most
+// maxLength characters. When content does not fit a single message, each
+// message starts with a part marker, for instance "[Part 1/3] ", so readers
+// can tell parts apart from other messages of the channel.
+func splitMessage(content string, maxLength, maxParts int) []string {
+	if utf8.RuneCountInString(content) <= maxLength {
+		return []string{content}
+	}
+	return addPartMarkers(splitContent(content, maxLength-getPartMarkerLength(maxParts), maxParts))
+}
+
+// splitContent splits content into at most maxParts parts of at most maxLength
+// characters. Parts end at line breaks when possible. The last part is truncated
+// if content does not fit.
+func splitContent(content string, maxLength, maxParts int) []string {
+	parts := []string{}
+	runes := []rune(content)
+	for len(runes) > maxLength && len(parts) < maxParts-1 {
+		cut, next := maxLength, maxLength
+		for i := maxLength; i > 0; i-- {
+			if runes[i] == '\n' {
+				// Line break is dropped: the next message starts a new line
+				cut, next = i, i+1
+				break
+			}
+		}
+		parts = append(parts, string(runes[:cut]))
+		runes = runes[next:]
+	}
+	return append(parts, truncateString(string(runes), maxLength))
+}
+
+// addPartMarkers prefixes each of parts with its part marker. A single part is
+// returned unchanged.
+func addPartMarkers(parts []string) []string {
+	if len(parts) < 2 {
+		return parts
+	}
+	marked := make([]string, len(parts))
+	for i := range parts {
+		marked[i] = getPartMarker(i+1, len(parts)) + parts[i]
+	}
+	return marked
+}
+
+func getPartMarker(part, total int) string {
+	return fmt.Sprintf("[Part %d/%d] ", part, total)
+}
+
+// getPartMarkerLength returns the maximum length of the marker of a message
+// split into at most maxParts parts
+func getPartMarkerLength(maxParts int) int {
+	return utf8.RuneCountInString(getPartMarker(maxParts, maxParts))
+}
+
+// lockChannel serializes chunked deliveries to channel of notificationType, so
+// that parts sent by concurrent runs do not interleave. Returned function
+// releases the channel.
+func lockChannel(notificationType appsv1alpha1.NotificationType, channel string) func() {
+	value, _ := channelLocks.LoadOrStore(string(notificationType)+"/"+channel, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+const (
+	// notificationFailureThreshold is the number of consecutive failures after
+	// which a notification is suspended
+	notificationFailureThreshold = 5
+
+	// notificationSuspension is how long a notification is suspended. Once
+	// elapsed, delivery is attempted again. A new failure suspends it again.
+	notificationSuspension = time.Hour
+
+	// maxNotificationFailureMessageSize is the maximum size of the failure
+	// message stored in the status
+	maxNotificationFailureMessageSize = 1024
+
+	conditionReasonNotificationSuspended = "NotificationSuspended"
+	conditionReasonNotificationsHealthy  = "NotificationsHealthy"
+)
+
+// getNotificationStatus returns the status of the notification named notificationName,
+// nil if none is present
+func getNotificationStatus(cleaner *appsv1alpha1.Cleaner, notificationName string) *appsv1alpha1.NotificationStatus {
+	for i := range cleaner.Status.NotificationStatuses {
+		if cleaner.Status.NotificationStatuses[i].NotificationName == notificationName {
+			return &cleaner.Status.NotificationStatuses[i]
+		}
+	}
+	return nil
+}
+
+// ignoreSuspensionKey marks contexts whose notifications are sent even when suspended
+type ignoreSuspensionKey struct{}
+
+// withoutSuspension returns a copy of ctx whose notifications are sent even when
+// suspended because of repeated failures. Delivery results are still recorded.
+func withoutSuspension(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ignoreSuspensionKey{}, true)
+}
+
+// isSuspensionIgnored returns true if ctx was returned by withoutSuspension
+func isSuspensionIgnored(ctx context.Context) bool {
+	ignore, _ := ctx.Value(ignoreSuspensionKey{}).(bool)
+	return ignore
+}
+
+// isNotificationSuspended returns true if the notification is currently skipped
+// because it failed too many consecutive times
+func isNotificationSuspended(cleaner *appsv1alpha1.Cleaner, notificationName string, now time.Time) bool {
+	status := getNotificationStatus(cleaner, notificationName)
+	return status != nil && status.SuspendedUntil != nil && now.Before(status.SuspendedUntil.Time)
+}
+
+// recordNotificationResult updates the notification status in the Cleaner instance
+// with the outcome of a delivery attempt. deliveryErr is nil on success.
+// On success, notification status is reset. On failure, the number of consecutive
+// failures is increased and, once notificationFailureThreshold is reached, the
+// notification is suspended for notificationSuspension.
+// The NotificationDegraded condition is updated accordingly.
+func recordNotificationResult(ctx context.Context, cleaner *appsv1alpha1.Cleaner, notificationName string,
+	deliveryErr error, now time.Time) error {
+
+	if deliveryErr == nil && getNotificationStatus(cleaner, notificationName) == nil {
+		// Nothing to reset. Avoid updating status for every successful delivery
+		return nil
+	}
+
+	return updateCleanerStatus(ctx, cleaner.Name, func(current *appsv1alpha1.Cleaner) {
+		statuses := make([]appsv1alpha1.NotificationStatus, 0, len(current.Status.NotificationStatuses)+1)
+		for i := range current.Status.NotificationStatuses {
+			name := current.Status.NotificationStatuses[i].NotificationName
+			// Status of notifications removed from the spec is dropped
+			if name != notificationName && hasNotification(current, name) {
+				statuses = append(statuses, current.Status.NotificationStatuses[i])
+			}
+		}
+
+		if deliveryErr != nil {
+			status := appsv1alpha1.NotificationStatus{NotificationName: notificationName}
+			if previous := getNotificationStatus(current, notificationName); previous != nil {
+				status = *previous
+			}
+			status.ConsecutiveFailures++
+			status.LastFailureTime = &metav1.Time{Time: now}
+			status.LastFailureMessage = truncateString(deliveryErr.Error(), maxNotificationFailureMessageSize)
+			if status.ConsecutiveFailures >= notificationFailureThreshold {
+				status.SuspendedUntil = &metav1.Time{Time: now.Add(notificationSuspension)}
+			}
+			statuses = append(statuses, status)
+		}
+
+		sort.Slice(statuses, func(i, j int) bool {
+			return statuses[i].NotificationName < statuses[j].NotificationName
+		})
+		current.Status.NotificationStatuses = statuses
+		setNotificationDegradedCondition(current, now)
+	})
+}
+
+func hasNotification(cleaner *appsv1alpha1.Cleaner, notificationName string) bool {
+	for i := range cleaner.Spec.Notifications {
+		if cleaner.Spec.Notifications[i].Name == notificationName {
+			return true
+		}
+	}
+	return false
+}
+
+// setNotificationDegradedCondition sets the NotificationDegraded condition to True,
+// listing the suspended notifications, if any notification is suspended. To False
+// otherwise.
+func setNotificationDegradedCondition(cleaner *appsv1alpha1.Cleaner, now time.Time) {
+	suspended := make([]string, 0)
+	for i := range cleaner.Status.NotificationStatuses {
+		status := &cleaner.Status.NotificationStatuses[i]
+		if status.SuspendedUntil != nil && now.Before(status.SuspendedUntil.Time) {
+			suspended = append(suspended, status.NotificationName)
+		}
+	}
+
+	condition := metav1.Condition{
+		Type:    appsv1alpha1.ConditionTypeNotificationDegraded,
+		Status:  metav1.ConditionFalse,
+		Reason:  conditionReasonNotificationsHealthy,
+		Message: "all notifications are delivered",
+	}
+	if len(suspended) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = conditionReasonNotificationSuspended
+		condition.Message = fmt.Sprintf("notifications suspended after %d consecutive failures: %s",
+			notificationFailureThreshold, strings.Join(suspended, ", "))
+	}
+	meta.SetStatusCondition(&cleaner.Status.Conditions, condition)
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+var _ = Describe("Notification circuit breaker", func() {
+	It("sendNotifications suspends a notification after consecutive failures and resets it on success", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+
+		fake := &fakeSlackClient{err: fmt.Errorf("connection refused")}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Schedule = "0 * * * *"
+		cleaner.Spec.ResourcePolicySet.ResourceSelectors = []appsv1alpha1.ResourceSelector{
+			{Kind: "ConfigMap", Version: "v1"},
+		}
+		Expect(k8sClient.Create(context.TODO(), cleaner)).To(Succeed())
+		Expect(waitForObject(context.TODO(), k8sClient, cleaner)).To(Succeed())
+
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		currentCleaner := &appsv1alpha1.Cleaner{}
+		for i := 0; i < executor.NotificationFailureThreshold; i++ {
+			Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, currentCleaner)).To(Succeed())
+			Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+				currentCleaner, "", logr.Discard())).ToNot(Succeed())
+		}
+		Expect(fake.values).To(HaveLen(executor.NotificationFailureThreshold))
+
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, currentCleaner)).To(Succeed())
+		Expect(currentCleaner.Status.NotificationStatuses).To(HaveLen(1))
+		status := &currentCleaner.Status.NotificationStatuses[0]
+		Expect(status.NotificationName).To(Equal(cleaner.Spec.Notifications[0].Name))
+		Expect(status.ConsecutiveFailures).To(Equal(int32(executor.NotificationFailureThreshold)))
+		Expect(status.LastFailureMessage).To(ContainSubstring("connection refused"))
+		Expect(status.SuspendedUntil).ToNot(BeNil())
+		Expect(meta.IsStatusConditionTrue(currentCleaner.Status.Conditions,
+			appsv1alpha1.ConditionTypeNotificationDegraded)).To(BeTrue())
+
+		// Suspended notification is skipped
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			currentCleaner, "", logr.Discard())).To(Succeed())
+		Expect(fake.values).To(HaveLen(executor.NotificationFailureThreshold))
+
+		// Once suspension elapses, delivery is retried. Success resets status.
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, currentCleaner)).To(Succeed())
+		status = &currentCleaner.Status.NotificationStatuses[0]
+		status.SuspendedUntil = &metav1.Time{Time: time.Now().Add(-time.Minute)}
+		Expect(k8sClient.Status().Update(context.TODO(), currentCleaner)).To(Succeed())
+		fake.err = nil
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			currentCleaner, "", logr.Discard())).To(Succeed())
+		Expect(fake.values).To(HaveLen(executor.NotificationFailureThreshold + 1))
+
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, currentCleaner)).To(Succeed())
+		Expect(currentCleaner.Status.NotificationStatuses).To(BeEmpty())
+		Expect(meta.IsStatusConditionFalse(currentCleaner.Status.Conditions,
+			appsv1alpha1.ConditionTypeNotificationDegraded)).To(BeTrue())
+	})
+
+	It("sendNotifications suspends again a notification failing after suspension elapsed", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return &fakeSlackClient{err: fmt.Errorf("connection refused")}
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Schedule = "0 * * * *"
+		cleaner.Spec.ResourcePolicySet.ResourceSelectors = []appsv1alpha1.ResourceSelector{
+			{Kind: "ConfigMap", Version: "v1"},
+		}
+		Expect(k8sClient.Create(context.TODO(), cleaner)).To(Succeed())
+		Expect(waitForObject(context.TODO(), k8sClient, cleaner)).To(Succeed())
+
+		currentCleaner := &appsv1alpha1.Cleaner{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, currentCleaner)).To(Succeed())
+		currentCleaner.Status.NotificationStatuses = []appsv1alpha1.NotificationStatus{
+			{
+				NotificationName:    cleaner.Spec.Notifications[0].Name,
+				ConsecutiveFailures: executor.NotificationFailureThreshold,
+				SuspendedUntil:      &metav1.Time{Time: time.Now().Add(-time.Minute)},
+			},
+		}
+		Expect(k8sClient.Status().Update(context.TODO(), currentCleaner)).To(Succeed())
+
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			currentCleaner, "", logr.Discard())).ToNot(Succeed())
+
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, currentCleaner)).To(Succeed())
+		Expect(currentCleaner.Status.NotificationStatuses).To(HaveLen(1))
+		status := &currentCleaner.Status.NotificationStatuses[0]
+		Expect(status.ConsecutiveFailures).To(Equal(int32(executor.NotificationFailureThreshold + 1)))
+		Expect(status.SuspendedUntil.Time.After(time.Now())).To(BeTrue())
+	})
+})
@@ -18,6 +18,7 @@ package executor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 
@@ -26,6 +27,7 @@ import (
 	"go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
@@ -34,8 +36,39 @@ import (
 var (
 	getClientLock   = &sync.Mutex{}
 	managerInstance *Manager
+
+	// k8sClient is the client used to act on resources and to read and update
+	// Cleaner, Report and Secret instances. Use getK8sClient to access it.
+	k8sClient    client.Client
+	k8sClientMux sync.RWMutex
 )
 
+// errClientNotInitialized is returned when the client is used before being set
+// by InitializeClient or InjectClient
+var errClientNotInitialized = errors.New("executor client is not initialized: " +
+	"InitializeClient or InjectClient must be called first")
+
+// InjectClient sets the client used to act on resources and to read and update
+// Cleaner, Report and Secret instances. InitializeClient calls it, so it only
+// needs to be called directly when the executor workers are not started.
+// It is safe to call concurrently with running workers.
+func InjectClient(c client.Client) {
+	k8sClientMux.Lock()
+	defer k8sClientMux.Unlock()
+	k8sClient = c
+}
+
+// getK8sClient returns the client set by InjectClient, or errClientNotInitialized
+// if none was set
+func getK8sClient() (client.Client, error) {
+	k8sClientMux.RLock()
+	defer k8sClientMux.RUnlock()
+	if k8sClient == nil {
+		return nil, errClientNotInitialized
+	}
+	return k8sClient, nil
+}
+
 const (
 	unavailable = "unavailable"
 )
@@ -67,13 +100,16 @@ type Result struct {
 	ResultStatus
 	Message string
 	Err     error
+	// RunID identifies the run which produced this result
+	RunID string
 }
 
 type Manager struct {
 	log logr.Logger
 	client.Client
-	config *rest.Config
-	scheme *runtime.Scheme
+	config   *rest.Config
+	scheme   *runtime.Scheme
+	recorder record.EventRecorder
 
 	mu *sync.Mutex
 
@@ -89,12 +125,13 @@ type Manager struct {
 	jobQueue []string
 
 	// results contains results for processed requests (cleaner names)
-	results map[string]error
+	results map[string]responseParams
 }
 
-// InitializeClient initializes a client
+// InitializeClient initializes a client.
+// recorder is used to record Kubernetes Events for Event notifications.
 func InitializeClient(ctx context.Context, l logr.Logger, config *rest.Config,
-	c client.Client, scheme *runtime.Scheme, numOfWorker int) {
+	c client.Client, scheme *runtime.Scheme, recorder record.EventRecorder, numOfWorker int) {
 
 	if managerInstance == nil {
 		getClientLock.Lock()
@@ -109,6 +146,7 @@ func InitializeClient(ctx context.Context, l logr.Logger, config *rest.Config,
 			l.V(logs.LogInfo).Info(fmt.Sprintf("Creating instance now. Number of workers: %d", numOfWorker))
 			managerInstance = &Manager{log: l, Client: c, config: config}
 			managerInstance.scheme = scheme
+			managerInstance.recorder = recorder
 			managerInstance.log = zapr.NewLogger(logger)
 			managerInstance.startWorkloadWorkers(ctx, numOfWorker, l)
 		}
@@ -124,10 +162,11 @@ func (m *Manager) startWorkloadWorkers(ctx context.Context, numOfWorker int, log
 	m.dirty = make([]string, 0)
 	m.inProgress = make([]string, 0)
 	m.jobQueue = make([]string, 0)
-	m.results = make(map[string]error)
-	k8sClient = m.Client
+	m.results = make(map[string]responseParams)
+	InjectClient(m.Client)
 	config = m.config
 	scheme = m.scheme
+	eventRecorder = m.recorder
 
 	for i := 0; i < numOfWorker; i++ {
 		go processRequests(ctx, i, logger.WithValues("worker", fmt.Sprintf("%d", i)))
@@ -195,11 +234,13 @@ func (m *Manager) GetResult(cleanerName string) Result {
 		return Result{
 			ResultStatus: Failed,
 			Err:          responseParam.err,
+			RunID:        responseParam.runID,
 		}
 	}
 
 	return Result{
 		ResultStatus: Processed,
+		RunID:        responseParam.runID,
 	}
 }
 
@@ -20,9 +20,12 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/go-logr/logr"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
 	"gianlucam76/k8s-cleaner/internal/controller/executor"
 )
 
@@ -57,6 +60,20 @@ var _ = Describe("CleanerClient", func() {
 		Expect(result.ResultStatus).To(Equal(executor.Failed))
 	})
 
+	It("GetResult returns run ID", func() {
+		cleanerName := randomString()
+		runID := randomString()
+
+		d := executor.GetClient()
+		defer d.ClearInternalStruct()
+
+		d.SetResultWithRunID(cleanerName, runID, fmt.Errorf("failed to deploy"))
+
+		result := d.GetResult(cleanerName)
+		Expect(result.ResultStatus).To(Equal(executor.Failed))
+		Expect(result.RunID).To(Equal(runID))
+	})
+
 	It("GetResult returns InProgress when request is still queued (currently in progress)", func() {
 		cleanerName := randomString()
 
@@ -154,4 +171,47 @@ var _ = Describe("CleanerClient", func() {
 		Expect(len(d.GetJobQueue())).To(Equal(1))
 		Expect(len(d.GetResults())).To(Equal(0))
 	})
+
+	It("createReportInstance returns an error when client is not initialized", func() {
+		DeferCleanup(executor.SetK8sClient(nil))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCleanerReport, nil)
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("client is not initialized"))
+	})
+
+	It("getSecret returns an error when client is not initialized", func() {
+		DeferCleanup(executor.SetK8sClient(nil))
+
+		notification := &appsv1alpha1.Notification{
+			Name: randomString(),
+			Type: appsv1alpha1.NotificationTypeSlack,
+			NotificationRef: &corev1.ObjectReference{
+				Kind:       "Secret",
+				APIVersion: "v1",
+				Namespace:  randomString(),
+				Name:       randomString(),
+			},
+		}
+		_, err := executor.GetSecret(context.TODO(), notification)
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("client is not initialized"))
+	})
+
+	It("InjectClient sets the client used to get Secrets", func() {
+		DeferCleanup(executor.SetK8sClient(nil))
+
+		ref := createNotificationSecret(map[string][]byte{"key": []byte(randomString())})
+		notification := &appsv1alpha1.Notification{
+			Name:            randomString(),
+			Type:            appsv1alpha1.NotificationTypeSlack,
+			NotificationRef: ref,
+		}
+
+		executor.InjectClient(k8sClient)
+		secret, err := executor.GetSecret(context.TODO(), notification)
+		Expect(err).To(BeNil())
+		Expect(secret.Name).To(Equal(ref.Name))
+	})
 })
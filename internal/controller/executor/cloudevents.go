@@ -0,0 +1,207 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/util/uuid"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+const (
+	cloudEventsSpecVersion = "1.0"
+	cloudEventsReportType  = "io.k8scleaner.report"
+	cloudEventsFailureType = "io.k8scleaner.failure"
+
+	cloudEventsStructuredContentType = "application/cloudevents+json"
+	cloudEventsDataContentType       = "application/json"
+
+	cloudEventsRequestTimeout = 30 * time.Second
+
+	// maximum number of bytes of the sink response included in errors
+	maxCloudEventsResponseBody = 4096
+)
+
+type cloudEventsInfo struct {
+	sinkURL   string
+	tlsConfig *tls.Config
+	auth      *httpAuth
+}
+
+// cloudEvent is a CloudEvents 1.0 event in the JSON format.
+// https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/formats/json-format.md
+type cloudEvent struct {
+	SpecVersion     string                   `json:"specversion"`
+	ID              string                   `json:"id"`
+	Source          string                   `json:"source"`
+	Type            string                   `json:"type"`
+	Time            string                   `json:"time"`
+	DataContentType string                   `json:"datacontenttype"`
+	RunID           string                   `json:"runid,omitempty"`
+	Data            *appsv1alpha1.ReportSpec `json:"data"`
+}
+
+func init() {
+	registerNotifier(appsv1alpha1.NotificationTypeCloudEvents, notifierFunc(
+		func(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+			_ []ResourceResult, _ string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+			return sendCloudEventsNotification(ctx, cleaner, reportSpec, notification, logger)
+		}))
+}
+
+func sendCloudEventsNotification(ctx context.Context, cleaner *appsv1alpha1.Cleaner,
+	reportSpec *appsv1alpha1.ReportSpec, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+	info, err := getCloudEventsInfo(ctx, notification)
+	if err != nil {
+		return err
+	}
+
+	l := logger.WithValues(logKeyURL, info.sinkURL)
+	l.V(logs.LogInfo).Info("send cloudevent")
+
+	req, err := getCloudEventsRequest(ctx, info.sinkURL, getCloudEvent(cleaner, reportSpec),
+		getCloudEventsContentMode(notification))
+	if err != nil {
+		l.Error(err, "failed to prepare cloudevent")
+		return err
+	}
+	info.auth.setAuthorization(req)
+
+	resp, err := newNotificationTLSHTTPClient(cloudEventsRequestTimeout, info.tlsConfig).Do(req)
+	if err != nil {
+		err = getTLSError(err)
+		l.Error(err, logMsgSendFailed)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxCloudEventsResponseBody))
+		err = fmt.Errorf("cloudevents sink returned %s: %s", resp.Status, string(body))
+		l.Error(err, logMsgSendFailed)
+		return err
+	}
+
+	l.V(logs.LogDebug).Info("cloudevent sent")
+	return nil
+}
+
+func getCloudEvent(cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec) *cloudEvent {
+	eventType := cloudEventsReportType
+	if reportSpec.Error != "" {
+		eventType = cloudEventsFailureType
+	}
+
+	return &cloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              string(uuid.NewUUID()),
+		Source:          cleaner.Name,
+		Type:            eventType,
+		Time:            time.Now().UTC().Format(time.RFC3339),
+		DataContentType: cloudEventsDataContentType,
+		RunID:           reportSpec.RunID,
+		Data:            reportSpec,
+	}
+}
+
+func getCloudEventsContentMode(notification *appsv1alpha1.Notification) appsv1alpha1.CloudEventsContentMode {
+	if notification.CloudEvents != nil && notification.CloudEvents.ContentMode != "" {
+		return notification.CloudEvents.ContentMode
+	}
+	return appsv1alpha1.CloudEventsContentModeStructured
+}
+
+// getCloudEventsRequest returns the HTTP request delivering event to sinkURL.
+// In structured mode the whole event is the request body. In binary mode event
+// attributes are set as ce- prefixed headers and data is the request body.
+// https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/bindings/http-protocol-binding.md
+func getCloudEventsRequest(ctx context.Context, sinkURL string, event *cloudEvent,
+	contentMode appsv1alpha1.CloudEventsContentMode) (*http.Request, error) {
+
+	switch contentMode {
+	case appsv1alpha1.CloudEventsContentModeStructured:
+		data, err := json.Marshal(event)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, sinkURL, bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", cloudEventsStructuredContentType)
+		return req, nil
+	case appsv1alpha1.CloudEventsContentModeBinary:
+		data, err := json.Marshal(event.Data)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, sinkURL, bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", event.DataContentType)
+		req.Header.Set("ce-specversion", event.SpecVersion)
+		req.Header.Set("ce-id", event.ID)
+		req.Header.Set("ce-source", event.Source)
+		req.Header.Set("ce-type", event.Type)
+		req.Header.Set("ce-time", event.Time)
+		if event.RunID != "" {
+			req.Header.Set("ce-runid", event.RunID)
+		}
+		return req, nil
+	default:
+		return nil, fmt.Errorf("unsupported cloudevents content mode %q", contentMode)
+	}
+}
+
+func getCloudEventsInfo(ctx context.Context, notification *appsv1alpha1.Notification) (*cloudEventsInfo, error) {
+	secret, err := getSecret(ctx, notification)
+	if err != nil {
+		return nil, err
+	}
+
+	sinkURL, ok := secret.Data[appsv1alpha1.CloudEventsSinkURL]
+	if !ok || len(sinkURL) == 0 {
+		return nil, fmt.Errorf("secret does not contain cloudevents sink URL")
+	}
+
+	tlsConfig, err := getNotificationTLSConfig(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := getCloudEventsAuth(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cloudEventsInfo{sinkURL: string(sinkURL), tlsConfig: tlsConfig, auth: auth}, nil
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+var _ = Describe("CloudEvents", func() {
+	It("sendNotifications sends report as structured CloudEvent by default", func() {
+		var header http.Header
+		var payload map[string]interface{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header = r.Header
+			body, err := io.ReadAll(r.Body)
+			Expect(err).To(BeNil())
+			Expect(json.Unmarshal(body, &payload)).To(Succeed())
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		DeferCleanup(server.Close)
+
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.CloudEventsSinkURL: []byte(server.URL),
+		})
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCloudEvents, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		runID := randomString()
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, runID, logr.Discard())).To(Succeed())
+
+		Expect(header.Get("Content-Type")).To(Equal("application/cloudevents+json"))
+		Expect(payload["specversion"]).To(Equal("1.0"))
+		Expect(payload["type"]).To(Equal("io.k8scleaner.report"))
+		Expect(payload["source"]).To(Equal(cleaner.Name))
+		Expect(payload["datacontenttype"]).To(Equal("application/json"))
+		Expect(payload["runid"]).To(Equal(runID))
+		Expect(payload["id"]).ToNot(BeEmpty())
+		Expect(payload["time"]).ToNot(BeEmpty())
+
+		data, err := json.Marshal(payload["data"])
+		Expect(err).To(BeNil())
+		reportSpec := &appsv1alpha1.ReportSpec{}
+		Expect(json.Unmarshal(data, reportSpec)).To(Succeed())
+		Expect(reportSpec.Action).To(Equal(appsv1alpha1.ActionDelete))
+		Expect(reportSpec.SchemaVersion).To(Equal(int32(appsv1alpha1.ReportSchemaVersion)))
+		Expect(reportSpec.ResourceInfo).To(HaveLen(1))
+		Expect(reportSpec.ResourceInfo[0].Resource.Name).To(Equal(resource.Resource.GetName()))
+	})
+
+	It("sendNotifications sends report as binary CloudEvent", func() {
+		var header http.Header
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header = r.Header
+			var err error
+			body, err = io.ReadAll(r.Body)
+			Expect(err).To(BeNil())
+			w.WriteHeader(http.StatusOK)
+		}))
+		DeferCleanup(server.Close)
+
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.CloudEventsSinkURL: []byte(server.URL),
+		})
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCloudEvents, ref)
+		cleaner.Spec.Notifications[0].CloudEvents = &appsv1alpha1.CloudEventsOptions{
+			ContentMode: appsv1alpha1.CloudEventsContentModeBinary,
+		}
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		runID := randomString()
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, runID, logr.Discard())).To(Succeed())
+
+		Expect(header.Get("Content-Type")).To(Equal("application/json"))
+		Expect(header.Get("ce-specversion")).To(Equal("1.0"))
+		Expect(header.Get("ce-type")).To(Equal("io.k8scleaner.report"))
+		Expect(header.Get("ce-source")).To(Equal(cleaner.Name))
+		Expect(header.Get("ce-runid")).To(Equal(runID))
+		Expect(header.Get("ce-id")).ToNot(BeEmpty())
+		Expect(header.Get("ce-time")).ToNot(BeEmpty())
+
+		reportSpec := &appsv1alpha1.ReportSpec{}
+		Expect(json.Unmarshal(body, reportSpec)).To(Succeed())
+		Expect(reportSpec.ResourceInfo).To(HaveLen(1))
+		Expect(reportSpec.RunID).To(Equal(runID))
+	})
+
+	It("sendNotifications returns sink response body on non-2xx status", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`unknown event type`))
+		}))
+		DeferCleanup(server.Close)
+
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.CloudEventsSinkURL: []byte(server.URL),
+		})
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCloudEvents, ref)
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("400"))
+		Expect(err.Error()).To(ContainSubstring("unknown event type"))
+	})
+
+	It("sendNotifications fails when secret does not contain sink URL", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.SplunkHECURL: []byte(randomString()),
+		})
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCloudEvents, ref)
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("sink URL"))
+	})
+
+	It("sendNotifications sets basic authorization", func() {
+		var username, password string
+		var ok bool
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			username, password, ok = r.BasicAuth()
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		DeferCleanup(server.Close)
+
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.CloudEventsSinkURL:  []byte(server.URL),
+			appsv1alpha1.CloudEventsAuthType: []byte("Basic"),
+			appsv1alpha1.CloudEventsUsername: []byte("cleaner"),
+			appsv1alpha1.CloudEventsPassword: []byte("secret"),
+		})
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCloudEvents, ref)
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+		Expect(ok).To(BeTrue())
+		Expect(username).To(Equal("cleaner"))
+		Expect(password).To(Equal("secret"))
+	})
+
+	It("sendNotifications sets bearer authorization", func() {
+		var authorization string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization = r.Header.Get("Authorization")
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		DeferCleanup(server.Close)
+
+		token := randomString()
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.CloudEventsSinkURL:  []byte(server.URL),
+			appsv1alpha1.CloudEventsAuthType: []byte("bearer"),
+			appsv1alpha1.CloudEventsToken:    []byte(token),
+		})
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCloudEvents, ref)
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+		Expect(authorization).To(Equal("Bearer " + token))
+	})
+
+	It("sendNotifications does not set authorization by default", func() {
+		authorization := "unset"
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization = r.Header.Get("Authorization")
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		DeferCleanup(server.Close)
+
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.CloudEventsSinkURL: []byte(server.URL),
+			appsv1alpha1.CloudEventsToken:   []byte(randomString()),
+		})
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCloudEvents, ref)
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+		Expect(authorization).To(BeEmpty())
+	})
+
+	It("sendNotifications fails when fields required by auth type are missing", func() {
+		testCases := []struct {
+			data     map[string][]byte
+			expected string
+		}{
+			{
+				data:     map[string][]byte{appsv1alpha1.CloudEventsAuthType: []byte("basic")},
+				expected: appsv1alpha1.CloudEventsUsername,
+			},
+			{
+				data: map[string][]byte{
+					appsv1alpha1.CloudEventsAuthType: []byte("basic"),
+					appsv1alpha1.CloudEventsUsername: []byte(randomString()),
+				},
+				expected: appsv1alpha1.CloudEventsPassword,
+			},
+			{
+				data:     map[string][]byte{appsv1alpha1.CloudEventsAuthType: []byte("bearer")},
+				expected: appsv1alpha1.CloudEventsToken,
+			},
+			{
+				data:     map[string][]byte{appsv1alpha1.CloudEventsAuthType: []byte("digest")},
+				expected: "unsupported",
+			},
+		}
+
+		for i := range testCases {
+			testCases[i].data[appsv1alpha1.CloudEventsSinkURL] = []byte("http://127.0.0.1:1")
+			ref := createNotificationSecret(testCases[i].data)
+			cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCloudEvents, ref)
+
+			err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring(testCases[i].expected))
+		}
+	})
+})
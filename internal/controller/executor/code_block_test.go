@@ -0,0 +1,152 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+var _ = Describe("Summary code block", func() {
+	var (
+		resource     executor.ResourceResult
+		codeBlock    string
+		resourceLine string
+	)
+
+	BeforeEach(func() {
+		resource = getResourceResult("ConfigMap", randomString(), randomString())
+		resourceLine = "- ConfigMap " + resource.Resource.GetNamespace() + "/" + resource.Resource.GetName()
+		codeBlock = "```\nResources: 1 (ConfigMap: 1)\n" + resourceLine + "\n```"
+	})
+
+	It("sendNotifications renders the Slack summary as code block", func() {
+		ref, fake := createSlackSecret()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications[0].SummaryAsCodeBlock = true
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(HavePrefix("k8s-cleaner '" + cleaner.Name +
+			"' performed Delete on 1 resource\n" + codeBlock))
+	})
+
+	It("sendNotifications renders the Slack summary as markdown by default", func() {
+		ref, fake := createSlackSecret()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(ContainSubstring("*Resources:* 1 (ConfigMap: 1)\n" + resourceLine))
+		Expect(fake.values[0].Get("text")).ToNot(ContainSubstring("```"))
+	})
+
+	It("sendNotifications sends the Discord summary code block as last message", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.DiscordChannelID: []byte(randomDiscordID()),
+			libsveltosv1alpha1.DiscordToken:     []byte(randomString()),
+		})
+		fake := &fakeDiscordClient{}
+		useFakeDiscordClient(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeDiscord, ref)
+		cleaner.Spec.Notifications[0].SummaryAsCodeBlock = true
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.messages).To(HaveLen(1))
+		Expect(fake.messages[0].Content).To(Equal("k8s-cleaner '" + cleaner.Name +
+			"' performed Delete on 1 resource\n" + codeBlock))
+	})
+
+	It("sendNotifications sends the Discord summary code block as a message of its own when it does not fit", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.DiscordChannelID: []byte(randomDiscordID()),
+			libsveltosv1alpha1.DiscordToken:     []byte(randomString()),
+		})
+		fake := &fakeDiscordClient{}
+		useFakeDiscordClient(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeDiscord, ref)
+		cleaner.Spec.Notifications[0].SummaryAsCodeBlock = true
+		cleaner.Spec.Notifications[0].MessageTemplate = strings.Repeat("a", 1980)
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.messages).To(HaveLen(2))
+		Expect(fake.messages[0].Content).To(Equal("[Part 1/2] " + strings.Repeat("a", 1980)))
+		Expect(fake.messages[1].Content).To(Equal("[Part 2/2] " + codeBlock))
+		Expect(fake.messages[1].Embeds).To(HaveLen(1))
+	})
+
+	It("sendNotifications adds the Teams summary as code block element", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.TeamsWebhookURL: []byte("https://example.webhook.office.com/" + randomString()),
+		})
+		fake := &fakeTeamsClient{}
+		DeferCleanup(executor.SetTeamsClientFactory(func() executor.TeamsClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeTeams, ref)
+		cleaner.Spec.Notifications[0].SummaryAsCodeBlock = true
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.messages).To(HaveLen(1))
+		payload, err := json.Marshal(fake.messages[0])
+		Expect(err).To(BeNil())
+		summary, err := json.Marshal("Resources: 1 (ConfigMap: 1)\n" + resourceLine)
+		Expect(err).To(BeNil())
+		Expect(string(payload)).To(ContainSubstring(`"type":"CodeBlock"`))
+		Expect(string(payload)).To(ContainSubstring(`"codeSnippet":` + string(summary)))
+	})
+
+	It("getCodeBlockSummary truncates the summary so the code block fits and is closed", func() {
+		reportSpec := &appsv1alpha1.ReportSpec{}
+		for i := 0; i < 5; i++ {
+			reportSpec.ResourceInfo = append(reportSpec.ResourceInfo, appsv1alpha1.ResourceInfo{
+				Resource: corev1.ObjectReference{Kind: "ConfigMap", Namespace: randomString(),
+					Name: strings.Repeat("a", 200)},
+			})
+		}
+
+		block := executor.GetCodeBlockSummary(reportSpec, 300)
+		Expect(utf8.RuneCountInString(block)).To(Equal(300))
+		Expect(block).To(HavePrefix("```\nResources: 5"))
+		Expect(block).To(HaveSuffix("…\n```"))
+	})
+})
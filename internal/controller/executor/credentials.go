@@ -0,0 +1,226 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+// getNotificationSecret returns the credentials of notification. When ref is set,
+// they are read from the referenced Secret. Otherwise they are read from the
+// controller environment variables prefixed by the notification name.
+func getNotificationSecret(ctx context.Context, notification *appsv1alpha1.Notification,
+	ref *corev1.ObjectReference) (*corev1.Secret, error) {
+
+	if ref != nil {
+		secret, err := getSecretFromRef(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		return getSharedSecret(secret, notification.Type), nil
+	}
+	return getEnvSecret(notification)
+}
+
+// sharedSecretKeys maps, for each notification type, the keys of its section
+// in a shared Secret to the keys read by the notification. A section key is
+// prefixed with the lowercased notification type, for instance slack.token
+// or teams.webhookURL, so a single Secret holds the credentials of several
+// notification types.
+var sharedSecretKeys = map[appsv1alpha1.NotificationType]map[string]string{
+	appsv1alpha1.NotificationTypeSlack: {
+		"token":      libsveltosv1alpha1.SlackToken,
+		"channelID":  libsveltosv1alpha1.SlackChannelID,
+		"webhookURL": appsv1alpha1.SlackWebhookURL,
+	},
+	appsv1alpha1.NotificationTypeWebex: {
+		"token":  libsveltosv1alpha1.WebexToken,
+		"roomID": libsveltosv1alpha1.WebexRoomID,
+	},
+	appsv1alpha1.NotificationTypeDiscord: {
+		"token":     libsveltosv1alpha1.DiscordToken,
+		"channelID": libsveltosv1alpha1.DiscordChannelID,
+	},
+	appsv1alpha1.NotificationTypeTeams: {
+		"webhookURL": libsveltosv1alpha1.TeamsWebhookURL,
+	},
+	appsv1alpha1.NotificationTypeSMTP: {
+		"recipients": libsveltosv1beta1.SmtpRecipients,
+		"bcc":        libsveltosv1beta1.SmtpBcc,
+		"identity":   libsveltosv1beta1.SmtpIdentity,
+		"sender":     libsveltosv1beta1.SmtpSender,
+		"password":   libsveltosv1beta1.SmtpPassword,
+		"host":       libsveltosv1beta1.SmtpHost,
+		"port":       libsveltosv1beta1.SmtpPort,
+	},
+	appsv1alpha1.NotificationTypeSplunkHEC: {
+		"url":   appsv1alpha1.SplunkHECURL,
+		"token": appsv1alpha1.SplunkHECToken,
+	},
+	appsv1alpha1.NotificationTypeCloudEvents: {
+		"sinkURL":  appsv1alpha1.CloudEventsSinkURL,
+		"authType": appsv1alpha1.CloudEventsAuthType,
+		"username": appsv1alpha1.CloudEventsUsername,
+		"password": appsv1alpha1.CloudEventsPassword,
+		"token":    appsv1alpha1.CloudEventsToken,
+	},
+	appsv1alpha1.NotificationTypeVictorOps: {
+		"apiKey":     appsv1alpha1.VictorOpsAPIKey,
+		"routingKey": appsv1alpha1.VictorOpsRoutingKey,
+	},
+	appsv1alpha1.NotificationTypeS3: {
+		"bucket":             appsv1alpha1.S3Bucket,
+		"region":             appsv1alpha1.S3Region,
+		"prefix":             appsv1alpha1.S3Prefix,
+		"endpoint":           appsv1alpha1.S3Endpoint,
+		"awsAccessKeyID":     appsv1alpha1.AWSAccessKeyID,
+		"awsSecretAccessKey": appsv1alpha1.AWSSecretAccessKey,
+		"awsSessionToken":    appsv1alpha1.AWSSessionToken,
+	},
+	appsv1alpha1.NotificationTypeSMS: {
+		"accountSID": appsv1alpha1.TwilioAccountSID,
+		"authToken":  appsv1alpha1.TwilioAuthToken,
+		"fromNumber": appsv1alpha1.TwilioFromNumber,
+		"toNumbers":  appsv1alpha1.TwilioToNumbers,
+	},
+	appsv1alpha1.NotificationTypeRedis: {
+		"address":  appsv1alpha1.RedisAddress,
+		"username": appsv1alpha1.RedisUsername,
+		"password": appsv1alpha1.RedisPassword,
+		"key":      appsv1alpha1.RedisKey,
+		"tls":      appsv1alpha1.RedisTLS,
+	},
+	appsv1alpha1.NotificationTypeGitLab: {
+		"token":   appsv1alpha1.GitLabToken,
+		"project": appsv1alpha1.GitLabProject,
+		"url":     appsv1alpha1.GitLabURL,
+	},
+	appsv1alpha1.NotificationTypeLoki: {
+		"url":      appsv1alpha1.LokiURL,
+		"tenantID": appsv1alpha1.LokiTenantID,
+		"username": appsv1alpha1.LokiUsername,
+		"password": appsv1alpha1.LokiPassword,
+	},
+	appsv1alpha1.NotificationTypeElasticsearch: {
+		"url":      appsv1alpha1.ElasticsearchURL,
+		"index":    appsv1alpha1.ElasticsearchIndex,
+		"apiKey":   appsv1alpha1.ElasticsearchAPIKey,
+		"username": appsv1alpha1.ElasticsearchUsername,
+		"password": appsv1alpha1.ElasticsearchPassword,
+	},
+}
+
+// sharedTLSSecretKeys are the keys available in the section of every
+// notification type
+var sharedTLSSecretKeys = map[string]string{
+	"tlsClientCert": appsv1alpha1.TLSClientCert,
+	"tlsClientKey":  appsv1alpha1.TLSClientKey,
+	"tlsCACert":     appsv1alpha1.TLSCACert,
+}
+
+// getSharedSecret returns secret with the keys of the notificationType section
+// of a shared Secret added under the keys read by the notification. Section
+// keys take precedence over the same keys set without section. Keys of other
+// sections are ignored. secret is returned as is when it has no key of the
+// notificationType section.
+func getSharedSecret(secret *corev1.Secret, notificationType appsv1alpha1.NotificationType) *corev1.Secret {
+	section := strings.ToLower(string(notificationType)) + "."
+
+	var data map[string][]byte
+	for key, value := range secret.Data {
+		name, ok := strings.CutPrefix(key, section)
+		if !ok {
+			continue
+		}
+		flatKey, ok := sharedSecretKeys[notificationType][name]
+		if !ok {
+			if flatKey, ok = sharedTLSSecretKeys[name]; !ok {
+				continue
+			}
+		}
+		if data == nil {
+			data = make(map[string][]byte, len(secret.Data))
+			for k, v := range secret.Data {
+				data[k] = v
+			}
+		}
+		data[flatKey] = value
+	}
+	if data == nil {
+		return secret
+	}
+
+	shared := secret.DeepCopy()
+	shared.Data = data
+	return shared
+}
+
+// getEnvPrefix returns the prefix of the environment variables holding the
+// credentials of notification: the notification name, uppercased, with any
+// character other than letters and digits replaced by an underscore.
+// For instance notification "prod-slack" reads PROD_SLACK_SLACK_TOKEN.
+func getEnvPrefix(notification *appsv1alpha1.Notification) string {
+	prefix := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, notification.Name)
+	return strings.ToUpper(prefix) + "_"
+}
+
+// getEnvSecret returns a Secret whose data is made of the environment variables
+// prefixed by the notification env prefix, keyed by the variable name without
+// the prefix. So the same keys used in Secrets apply.
+func getEnvSecret(notification *appsv1alpha1.Notification) (*corev1.Secret, error) {
+	prefix := getEnvPrefix(notification)
+
+	data := make(map[string][]byte)
+	for _, env := range os.Environ() {
+		key, value, _ := strings.Cut(env, "=")
+		if strings.HasPrefix(key, prefix) && len(key) > len(prefix) {
+			data[strings.TrimPrefix(key, prefix)] = []byte(value)
+		}
+	}
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("notification %s has no notificationRef and no environment variable "+
+			"with prefix %s is set: reference a Secret or set the credentials as %s<KEY> environment variables",
+			notification.Name, prefix, prefix)
+	}
+
+	return &corev1.Secret{Data: data}, nil
+}
+
+// getCredentialSource returns a description of where the credentials for ref
+// are read from, for logging
+func getCredentialSource(notification *appsv1alpha1.Notification, ref *corev1.ObjectReference) string {
+	if ref == nil {
+		return fmt.Sprintf("env:%s*", getEnvPrefix(notification))
+	}
+	return fmt.Sprintf("%s/%s", ref.Namespace, ref.Name)
+}
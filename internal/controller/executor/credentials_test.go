@@ -0,0 +1,138 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+var _ = Describe("Credentials", func() {
+	var fake *fakeSlackClient
+
+	BeforeEach(func() {
+		fake = &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+	})
+
+	// setEnv sets the environment variable name for the duration of the spec
+	setEnv := func(name, value string) {
+		Expect(os.Setenv(name, value)).To(Succeed())
+		DeferCleanup(os.Unsetenv, name)
+	}
+
+	It("sendNotifications reads credentials from environment variables when notificationRef is not set", func() {
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, nil)
+		cleaner.Spec.Notifications[0].Name = "env-" + randomString()
+		prefix := strings.ToUpper(strings.ReplaceAll(cleaner.Spec.Notifications[0].Name, "-", "_")) + "_"
+
+		channelID := randomSlackChannelID()
+		setEnv(prefix+libsveltosv1alpha1.SlackToken, randomString())
+		setEnv(prefix+libsveltosv1alpha1.SlackChannelID, channelID)
+
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.channelIDs).To(Equal([]string{channelID}))
+	})
+
+	It("sendNotifications uses the referenced Secret when environment variables are set as well", func() {
+		channelID := randomSlackChannelID()
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(channelID),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		prefix := strings.ToUpper(cleaner.Spec.Notifications[0].Name) + "_"
+		setEnv(prefix+libsveltosv1alpha1.SlackToken, randomString())
+		setEnv(prefix+libsveltosv1alpha1.SlackChannelID, randomString())
+
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.channelIDs).To(Equal([]string{channelID}))
+	})
+
+	It("sendNotifications reads credentials from the section of its type in a shared Secret", func() {
+		channelID := randomSlackChannelID()
+		ref := createNotificationSecret(map[string][]byte{
+			"slack.token":      []byte(randomString()),
+			"slack.channelID":  []byte(channelID),
+			"teams.webhookURL": []byte("https://example.webhook.office.com/" + randomString()),
+		})
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.channelIDs).To(Equal([]string{channelID}))
+	})
+
+	It("getSecret maps section keys of a shared Secret and keeps flat keys", func() {
+		webhookURL := "https://example.webhook.office.com/" + randomString()
+		caCert := randomString()
+		ref := createNotificationSecret(map[string][]byte{
+			"teams.webhookURL":                []byte(webhookURL),
+			"teams.tlsCACert":                 []byte(caCert),
+			"slack.token":                     []byte(randomString()),
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			// Section keys take precedence over flat keys
+			libsveltosv1alpha1.TeamsWebhookURL: []byte(randomString()),
+		})
+		notification := &appsv1alpha1.Notification{
+			Name:            randomString(),
+			Type:            appsv1alpha1.NotificationTypeTeams,
+			NotificationRef: ref,
+		}
+
+		secret, err := executor.GetSecret(context.TODO(), notification)
+		Expect(err).To(BeNil())
+		Expect(string(secret.Data[libsveltosv1alpha1.TeamsWebhookURL])).To(Equal(webhookURL))
+		Expect(string(secret.Data[appsv1alpha1.TLSCACert])).To(Equal(caCert))
+		Expect(secret.Data).To(HaveKey(libsveltosv1alpha1.SlackChannelID))
+		Expect(secret.Data).ToNot(HaveKey(libsveltosv1alpha1.SlackToken))
+	})
+
+	It("getSecret fails when neither notificationRef nor environment variables are set", func() {
+		notification := &appsv1alpha1.Notification{
+			Name: "missing-" + randomString(),
+			Type: appsv1alpha1.NotificationTypeTeams,
+		}
+
+		_, err := executor.GetSecret(context.TODO(), notification)
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("no notificationRef"))
+		Expect(err.Error()).To(ContainSubstring(
+			strings.ToUpper(strings.ReplaceAll(notification.Name, "-", "_")) + "_"))
+	})
+})
@@ -0,0 +1,52 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"errors"
+	"fmt"
+
+	goteamsnotify "github.com/atc0005/go-teams-notify/v2"
+)
+
+// Some providers answer with a success status even when the message is dropped.
+// Functions in this file look at what the provider returned, besides the
+// transport status, to tell whether the message was actually delivered.
+
+// verifySlackDelivery returns an error if Slack accepted a message without
+// posting it. err is the error returned by PostMessage, timestamp the timestamp
+// of the posted message. Slack assigns a timestamp to every posted message.
+func verifySlackDelivery(timestamp string, err error) error {
+	if err != nil {
+		return err
+	}
+	if timestamp == "" {
+		return fmt.Errorf("slack accepted the message but did not post it: no message timestamp returned")
+	}
+	return nil
+}
+
+// verifyTeamsDelivery returns an error if Teams did not deliver the message.
+// err is the error returned by Send. Connectors reply 200 along with an error
+// text, for instance when the webhook has been disabled or the channel is
+// throttled. Those replies are reported with the text Teams returned.
+func verifyTeamsDelivery(err error) error {
+	if errors.Is(err, goteamsnotify.ErrInvalidWebhookURLResponseText) {
+		return fmt.Errorf("teams webhook returned success status but did not deliver the message: %w", err)
+	}
+	return err
+}
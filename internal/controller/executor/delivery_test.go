@@ -0,0 +1,120 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	goteamsnotify "github.com/atc0005/go-teams-notify/v2"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+// newProviderServer returns a server answering every request with status code and body
+func newProviderServer(statusCode int, body string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		_, _ = w.Write([]byte(body))
+	}))
+	DeferCleanup(server.Close)
+	return server
+}
+
+// useSlackServer makes Slack notifications use a Slack client talking to server
+func useSlackServer(server *httptest.Server) {
+	DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+		return slack.New(token, slack.OptionAPIURL(server.URL+"/"))
+	}))
+}
+
+// useTeamsServer returns a Teams notification posting to server
+func useTeamsServer(server *httptest.Server) *appsv1alpha1.Cleaner {
+	DeferCleanup(executor.SetTeamsClientFactory(func() executor.TeamsClient {
+		return goteamsnotify.NewTeamsClient().SkipWebhookURLValidationOnSend(true)
+	}))
+	ref := createNotificationSecret(map[string][]byte{
+		libsveltosv1alpha1.TeamsWebhookURL: []byte(server.URL),
+	})
+	return getCleanerWithNotification(appsv1alpha1.NotificationTypeTeams, ref)
+}
+
+var _ = Describe("Delivery verification", func() {
+	It("sendNotifications succeeds when Slack posts the message", func() {
+		useSlackServer(newProviderServer(http.StatusOK,
+			`{"ok":true,"channel":"C0000000001","ts":"1700000000.000100"}`))
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte("C0000000001"),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+	})
+
+	It("sendNotifications fails when Slack returns 200 with an error payload", func() {
+		useSlackServer(newProviderServer(http.StatusOK, `{"ok":false,"error":"channel_not_found"}`))
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte("C0000000001"),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("channel_not_found"))
+	})
+
+	It("sendNotifications fails when Slack accepts the message without posting it", func() {
+		useSlackServer(newProviderServer(http.StatusOK, `{"ok":true,"channel":"C0000000001"}`))
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte("C0000000001"),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("did not post it"))
+	})
+
+	It("sendNotifications succeeds when Teams confirms delivery", func() {
+		cleaner := useTeamsServer(newProviderServer(http.StatusOK, goteamsnotify.ExpectedWebhookURLResponseText))
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+	})
+
+	It("sendNotifications fails when Teams returns 200 with an error payload", func() {
+		// Reply of a connector which has been removed from the channel
+		cleaner := useTeamsServer(newProviderServer(http.StatusOK,
+			"Webhook message delivery failed with error: Microsoft Teams endpoint returned HTTP error 404"))
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("did not deliver the message"))
+		Expect(err.Error()).To(ContainSubstring("HTTP error 404"))
+	})
+})
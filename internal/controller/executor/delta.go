@@ -0,0 +1,164 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+// maxDeltaResources is the maximum number of added, and of removed, resources
+// listed in the delta note
+const maxDeltaResources = 10
+
+// hasDeltaNotification returns true if any notification of cleaner has
+// IncludeDelta set
+func hasDeltaNotification(cleaner *appsv1alpha1.Cleaner) bool {
+	for i := range cleaner.Spec.Notifications {
+		if cleaner.Spec.Notifications[i].IncludeDelta {
+			return true
+		}
+	}
+	return false
+}
+
+// hasReportNotification returns true if the report of each run of cleaner is
+// stored in its Report instance, that is cleaner has an enabled CleanerReport
+// notification and reports are not disabled
+func hasReportNotification(cleaner *appsv1alpha1.Cleaner) bool {
+	if cleaner.Spec.DisableReport {
+		return false
+	}
+	for i := range cleaner.Spec.Notifications {
+		notification := &cleaner.Spec.Notifications[i]
+		if notification.Type == appsv1alpha1.NotificationTypeCleanerReport && isNotificationEnabled(notification) {
+			return true
+		}
+	}
+	return false
+}
+
+// getPreviousReportSpec returns the report of the previous run, as stored in the
+// Report instance of cleaner. It must be called before the run stores its own
+// report. Nil is returned when no notification has IncludeDelta set or when no
+// report is stored yet.
+func getPreviousReportSpec(ctx context.Context, cleaner *appsv1alpha1.Cleaner) (*appsv1alpha1.ReportSpec, error) {
+	if !hasDeltaNotification(cleaner) {
+		return nil, nil
+	}
+
+	c, err := getK8sClient()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &appsv1alpha1.Report{}
+	if err := c.Get(ctx, types.NamespacedName{Name: cleaner.Name}, report); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &report.Spec, nil
+}
+
+// getDeltaNote returns the note appended to the message when notification has
+// IncludeDelta set: the resources matched by the run and not by the previous one,
+// and the other way around. Previous report is restricted to the ResourceScope
+// and ResourceFilter of notification, as resources already are.
+// An empty string is returned when the note is disabled or there is no previous
+// report.
+func getDeltaNote(previous *appsv1alpha1.ReportSpec, resources []ResourceResult,
+	notification *appsv1alpha1.Notification, filter *resourceFilter) string {
+
+	if !notification.IncludeDelta || previous == nil {
+		return ""
+	}
+
+	// Filtering replaces ResourceInfo, so a shallow copy is enough
+	filtered := *previous
+	filterReportByScope(&filtered, notification.ResourceScope)
+	filterReportByFilter(&filtered, filter)
+
+	previousKeys := make(map[string]bool, len(filtered.ResourceInfo))
+	for i := range filtered.ResourceInfo {
+		previousKeys[getDeltaKey(&filtered.ResourceInfo[i].Resource)] = true
+	}
+
+	currentKeys := make(map[string]bool, len(resources))
+	var added []corev1.ObjectReference
+	for i := range resources {
+		ref := corev1.ObjectReference{
+			APIVersion: resources[i].Resource.GetAPIVersion(),
+			Kind:       resources[i].Resource.GetKind(),
+			Namespace:  resources[i].Resource.GetNamespace(),
+			Name:       resources[i].Resource.GetName(),
+		}
+		key := getDeltaKey(&ref)
+		currentKeys[key] = true
+		if !previousKeys[key] {
+			added = append(added, ref)
+		}
+	}
+
+	var removed []corev1.ObjectReference
+	for i := range filtered.ResourceInfo {
+		if !currentKeys[getDeltaKey(&filtered.ResourceInfo[i].Resource)] {
+			removed = append(removed, filtered.ResourceInfo[i].Resource)
+		}
+	}
+
+	if len(added) == 0 && len(removed) == 0 {
+		return "\nNo change since previous run"
+	}
+	note := fmt.Sprintf("\nChanges since previous run: %d added, %d removed", len(added), len(removed))
+	note += getDeltaList("Added", added)
+	note += getDeltaList("Removed", removed)
+	return note
+}
+
+// getDeltaList returns the line listing the first maxDeltaResources resources.
+// An empty string is returned when there is no resource.
+func getDeltaList(title string, resources []corev1.ObjectReference) string {
+	if len(resources) == 0 {
+		return ""
+	}
+
+	listed := make([]string, 0, maxDeltaResources)
+	for i := range resources {
+		if i == maxDeltaResources {
+			break
+		}
+		listed = append(listed, getResourceDescription(&resources[i]))
+	}
+	list := fmt.Sprintf("\n%s: %s", title, strings.Join(listed, ", "))
+	if len(resources) > maxDeltaResources {
+		list += fmt.Sprintf(" and %d more", len(resources)-maxDeltaResources)
+	}
+	return list
+}
+
+func getDeltaKey(ref *corev1.ObjectReference) string {
+	return fmt.Sprintf("%s/%s/%s/%s", ref.APIVersion, ref.Kind, ref.Namespace, ref.Name)
+}
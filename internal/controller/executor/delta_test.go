@@ -0,0 +1,126 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+// getDeltaCleaner returns a Cleaner with a Slack notification with IncludeDelta
+// set and a CleanerReport notification storing the report of each run
+func getDeltaCleaner() (*appsv1alpha1.Cleaner, *fakeSlackClient) {
+	ref, fake := createSlackSecret()
+	cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+	cleaner.Spec.Notifications[0].IncludeDelta = true
+	cleaner.Spec.Notifications = append(cleaner.Spec.Notifications, appsv1alpha1.Notification{
+		Name: randomString(),
+		Type: appsv1alpha1.NotificationTypeCleanerReport,
+	})
+	return cleaner, fake
+}
+
+var _ = Describe("Delta", func() {
+	It("sendNotifications lists resources added and removed since the previous run", func() {
+		cleaner, fake := getDeltaCleaner()
+		namespace := randomString()
+		removed := getResourceResult("ConfigMap", namespace, randomString())
+		kept := getResourceResult("ConfigMap", namespace, randomString())
+		added := getResourceResult("Secret", namespace, randomString())
+
+		// No report is stored before the first run
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{removed, kept},
+			cleaner, "", logr.Discard())).To(Succeed())
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).ToNot(ContainSubstring("previous run"))
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{kept, added},
+			cleaner, "", logr.Discard())).To(Succeed())
+		Expect(fake.values).To(HaveLen(2))
+		Expect(fake.values[1].Get("text")).To(ContainSubstring("\nChanges since previous run: 1 added, 1 removed" +
+			"\nAdded: Secret " + namespace + "/" + added.Resource.GetName() +
+			"\nRemoved: ConfigMap " + namespace + "/" + removed.Resource.GetName()))
+		// Full list is still reported
+		attachments := fake.values[1].Get("attachments")
+		Expect(attachments).To(ContainSubstring(kept.Resource.GetName()))
+		Expect(attachments).To(ContainSubstring(added.Resource.GetName()))
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{kept, added},
+			cleaner, "", logr.Discard())).To(Succeed())
+		Expect(fake.values).To(HaveLen(3))
+		Expect(fake.values[2].Get("text")).To(ContainSubstring("\nNo change since previous run"))
+	})
+
+	It("sendNotifications only compares resources in the notification ResourceFilter", func() {
+		cleaner, fake := getDeltaCleaner()
+		cleaner.Spec.Notifications[0].ResourceFilter = &appsv1alpha1.ResourceFilter{
+			Include: &appsv1alpha1.ResourceFilterMatch{Kind: "ConfigMap"},
+		}
+		namespace := randomString()
+		configMap := getResourceResult("ConfigMap", namespace, randomString())
+		secret := getResourceResult("Secret", namespace, randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{configMap, secret},
+			cleaner, "", logr.Discard())).To(Succeed())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{configMap},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(2))
+		Expect(fake.values[1].Get("text")).To(ContainSubstring("\nNo change since previous run"))
+	})
+
+	It("sendNotifications lists the first ten added resources", func() {
+		cleaner, fake := getDeltaCleaner()
+		namespace := randomString()
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+
+		resources := make([]executor.ResourceResult, 12)
+		for i := range resources {
+			resources[i] = getResourceResult("ConfigMap", namespace, fmt.Sprintf("cm-%02d", i))
+		}
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(2))
+		text := fake.values[1].Get("text")
+		Expect(text).To(ContainSubstring("\nChanges since previous run: 12 added, 0 removed"))
+		Expect(text).To(ContainSubstring("ConfigMap " + namespace + "/cm-09 and 2 more"))
+		Expect(text).ToNot(ContainSubstring("cm-10"))
+		Expect(text).ToNot(ContainSubstring("\nRemoved:"))
+	})
+
+	It("ValidateCleaner reports includeDelta without a CleanerReport notification", func() {
+		cleaner, _ := getDeltaCleaner()
+		cleaner.Spec.Schedule = "0 * * * *"
+		Expect(executor.ValidateCleaner(cleaner)).To(Succeed())
+
+		cleaner.Spec.DisableReport = true
+		Expect(executor.ValidateCleaner(cleaner)).To(MatchError(ContainSubstring(
+			"notification " + cleaner.Spec.Notifications[0].Name + ": includeDelta requires an enabled CleanerReport notification")))
+
+		cleaner.Spec.DisableReport = false
+		cleaner.Spec.Notifications = cleaner.Spec.Notifications[:1]
+		Expect(executor.ValidateCleaner(cleaner)).To(MatchError(ContainSubstring("includeDelta requires")))
+	})
+})
@@ -0,0 +1,157 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+const (
+	// maxResourceDiffSize is the maximum size of the diff of a single resource.
+	// Longer diffs are truncated.
+	maxResourceDiffSize = 4096
+
+	// Number of unchanged lines shown around each change
+	diffContextLines = 3
+
+	diffTruncatedMarker = "…(diff truncated)"
+)
+
+// Maximum size, in bytes, of the diffs rendered in chat messages. The full
+// diffs are always part of the report.
+const (
+	slackMaxDiffSize   = 3000
+	teamsMaxDiffSize   = 4000
+	discordMaxDiffSize = 3000
+	webexMaxDiffSize   = 5500
+)
+
+// getResourceDiff returns the unified diff between the YAML representation of a
+// resource before and after a transformation. Managed fields are ignored.
+// Diffs longer than maxResourceDiffSize are truncated.
+func getResourceDiff(before, after *unstructured.Unstructured) (string, error) {
+	beforeYAML, err := getDiffableYAML(before)
+	if err != nil {
+		return "", err
+	}
+	afterYAML, err := getDiffableYAML(after)
+	if err != nil {
+		return "", err
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(beforeYAML),
+		B:        difflib.SplitLines(afterYAML),
+		FromFile: "before",
+		ToFile:   "after",
+		Context:  diffContextLines,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(diff) > maxResourceDiffSize {
+		diff = strings.ToValidUTF8(diff[:maxResourceDiffSize-len(diffTruncatedMarker)], "") + diffTruncatedMarker
+	}
+	return diff, nil
+}
+
+func getDiffableYAML(u *unstructured.Unstructured) (string, error) {
+	u = u.DeepCopy()
+	unstructured.RemoveNestedField(u.Object, "metadata", "managedFields")
+
+	data, err := yaml.Marshal(u.Object)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// getDiffMarkdown returns the diffs contained in reportSpec as markdown, one
+// code block per resource. Diffs which do not fit in limit bytes are omitted.
+// An empty string is returned when there is no diff.
+func getDiffMarkdown(reportSpec *appsv1alpha1.ReportSpec, limit int) string {
+	var sb strings.Builder
+	total := 0
+	for i := range reportSpec.ResourceInfo {
+		if reportSpec.ResourceInfo[i].Diff != "" {
+			total++
+		}
+	}
+
+	included := 0
+	for i := range reportSpec.ResourceInfo {
+		info := &reportSpec.ResourceInfo[i]
+		if info.Diff == "" {
+			continue
+		}
+		block := fmt.Sprintf("%s\n```diff\n%s\n```\n", getResourceDescription(&info.Resource),
+			strings.TrimSuffix(info.Diff, "\n"))
+		remaining := total - included - 1
+		size := sb.Len() + len(block)
+		if remaining > 0 {
+			size += len(getDiffOmittedMarker(remaining))
+		}
+		if size > limit {
+			break
+		}
+		sb.WriteString(block)
+		included++
+	}
+
+	if included < total {
+		sb.WriteString(getDiffOmittedMarker(total - included))
+	}
+	return sb.String()
+}
+
+func getDiffOmittedMarker(omitted int) string {
+	return fmt.Sprintf("…(%d diffs omitted, see full report)", omitted)
+}
+
+// diffLine is a line of a unified diff along with the color used to
+// render it
+type diffLine struct {
+	Text  string
+	Color string
+}
+
+// getDiffLines splits a unified diff in lines, coloring added and removed lines
+func getDiffLines(diff string) []diffLine {
+	lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+	result := make([]diffLine, len(lines))
+	for i := range lines {
+		result[i] = diffLine{Text: lines[i]}
+		switch {
+		case strings.HasPrefix(lines[i], "+++"), strings.HasPrefix(lines[i], "---"):
+		case strings.HasPrefix(lines[i], "+"):
+			result[i].Color = "#e6ffec"
+		case strings.HasPrefix(lines[i], "-"):
+			result[i].Color = "#ffebe9"
+		case strings.HasPrefix(lines[i], "@@"):
+			result[i].Color = "#ddf4ff"
+		}
+	}
+	return result
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+var _ = Describe("Diff", func() {
+	It("getResourceDiff returns unified diff of transformed resource", func() {
+		before := getResourceResult("ConfigMap", randomString(), randomString()).Resource
+		before.SetLabels(map[string]string{"env": "staging"})
+		before.SetManagedFields(nil)
+		after := before.DeepCopy()
+		after.SetLabels(map[string]string{"env": "production"})
+
+		diff, err := executor.GetResourceDiff(before, after)
+		Expect(err).To(BeNil())
+		Expect(diff).To(ContainSubstring("--- before"))
+		Expect(diff).To(ContainSubstring("+++ after"))
+		Expect(diff).To(ContainSubstring("-    env: staging"))
+		Expect(diff).To(ContainSubstring("+    env: production"))
+		Expect(diff).ToNot(ContainSubstring("-  name:"))
+	})
+
+	It("getResourceDiff returns empty diff when resource is not changed", func() {
+		before := getResourceResult("ConfigMap", randomString(), randomString()).Resource
+
+		diff, err := executor.GetResourceDiff(before, before.DeepCopy())
+		Expect(err).To(BeNil())
+		Expect(diff).To(BeEmpty())
+	})
+
+	It("getResourceDiff truncates long diffs", func() {
+		before := getResourceResult("ConfigMap", randomString(), randomString()).Resource
+		after := before.DeepCopy()
+		after.SetAnnotations(map[string]string{"value": strings.Repeat("a", 2*executor.MaxResourceDiffSize)})
+
+		diff, err := executor.GetResourceDiff(before, after)
+		Expect(err).To(BeNil())
+		Expect(len(diff)).To(BeNumerically("<=", executor.MaxResourceDiffSize))
+		Expect(diff).To(HaveSuffix("…(diff truncated)"))
+	})
+
+	It("getDiffMarkdown omits diffs which do not fit", func() {
+		reportSpec := &appsv1alpha1.ReportSpec{Action: appsv1alpha1.ActionTransform}
+		for i := 0; i < 3; i++ {
+			reportSpec.ResourceInfo = append(reportSpec.ResourceInfo, appsv1alpha1.ResourceInfo{
+				Resource: corev1.ObjectReference{Kind: "ConfigMap", Namespace: "default", Name: randomString()},
+				Diff:     "-a: b\n+a: " + strings.Repeat("c", 100) + "\n",
+			})
+		}
+		// Resources without diff are ignored
+		reportSpec.ResourceInfo = append(reportSpec.ResourceInfo, appsv1alpha1.ResourceInfo{
+			Resource: corev1.ObjectReference{Kind: "Secret", Namespace: "default", Name: randomString()},
+		})
+
+		markdown := executor.GetDiffMarkdown(reportSpec, 300)
+		Expect(len(markdown)).To(BeNumerically("<=", 300))
+		Expect(strings.Count(markdown, "```diff")).To(Equal(1))
+		Expect(markdown).To(ContainSubstring(reportSpec.ResourceInfo[0].Resource.Name))
+		Expect(markdown).To(HaveSuffix("…(2 diffs omitted, see full report)"))
+
+		Expect(executor.GetDiffMarkdown(&appsv1alpha1.ReportSpec{}, 300)).To(BeEmpty())
+	})
+
+	It("sendNotifications posts Transform diffs in Slack message", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Action = appsv1alpha1.ActionTransform
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		resource.Diff = "--- before\n+++ after\n@@ -1 +1 @@\n-env: staging\n+env: production\n"
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		text := fake.values[0].Get("text")
+		Expect(text).To(ContainSubstring("```diff\n--- before"))
+		Expect(text).To(ContainSubstring("+env: production"))
+	})
+
+	It("renderHTMLReport renders Transform diffs as a table", func() {
+		reportSpec := &appsv1alpha1.ReportSpec{
+			Action: appsv1alpha1.ActionTransform,
+			ResourceInfo: []appsv1alpha1.ResourceInfo{
+				{
+					Resource: corev1.ObjectReference{Kind: "ConfigMap", Namespace: "default", Name: "cm"},
+					Diff:     "--- before\n+++ after\n@@ -1 +1 @@\n-env: staging\n+env: production\n",
+				},
+			},
+		}
+
+		data, err := executor.RenderHTMLReport(randomString(), reportSpec, time.Now())
+		Expect(err).To(BeNil())
+		html := string(data)
+		Expect(html).To(ContainSubstring("<h3>Changes</h3>"))
+		Expect(html).To(ContainSubstring("background-color: #ffebe9;\">-env: staging</td>"))
+		Expect(html).To(ContainSubstring("background-color: #e6ffec;\">&#43;env: production</td>"))
+	})
+})
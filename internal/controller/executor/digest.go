@@ -0,0 +1,394 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/uuid"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+const (
+	// maxDigestResources is the maximum number of resources accumulated in a
+	// digest. Accumulated resources are stored in the Cleaner status, so this
+	// keeps the Cleaner instance well below the object size limit.
+	maxDigestResources = 500
+
+	// digestCheckInterval is how often the digest scheduler looks for digests
+	// whose interval has elapsed
+	digestCheckInterval = time.Minute
+)
+
+// isDigestNotification returns true if notification must be sent as a digest
+func isDigestNotification(notification *appsv1alpha1.Notification) bool {
+	return notification.Digest != nil && notification.Type != appsv1alpha1.NotificationTypeCleanerReport
+}
+
+// processDigest adds reportSpec to the digest of notification. Digests are
+// sent by the digest scheduler once their interval has elapsed, independently
+// of Cleaner runs.
+// The digest is persisted in the Cleaner status.
+func processDigest(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+	// Digest is updated as currently stored, so runs are never lost to a digest
+	// sent meanwhile by the scheduler
+	var runs int32
+	err := mutateNotificationDigest(ctx, cleaner.Name, notification.Name, func(digest *appsv1alpha1.NotificationDigest) {
+		addToDigest(digest, reportSpec)
+		runs = digest.Runs
+	})
+	if err != nil {
+		return err
+	}
+	logger.V(logs.LogDebug).Info("digest accumulated", logKeyRuns, runs)
+	return nil
+}
+
+// RunDigestScheduler sends, every digestCheckInterval, the digests whose interval
+// has elapsed until ctx is cancelled
+func RunDigestScheduler(ctx context.Context, logger logr.Logger) error {
+	ticker := time.NewTicker(digestCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			if err := sendDueDigests(ctx, now, logger); err != nil {
+				logger.Error(err, "failed to send digests")
+			}
+		}
+	}
+}
+
+// dueDigest is a digest whose interval has elapsed
+type dueDigest struct {
+	cleaner      *appsv1alpha1.Cleaner
+	notification *appsv1alpha1.Notification
+	digest       *appsv1alpha1.NotificationDigest
+}
+
+// sendDueDigests sends all digests, of all Cleaner instances, whose interval has
+// elapsed at now. Due digests sent to the same target are combined into a single
+// message, grouped by Cleaner instance.
+func sendDueDigests(ctx context.Context, now time.Time, logger logr.Logger) error {
+	c, err := getK8sClient()
+	if err != nil {
+		return err
+	}
+
+	cleaners := &appsv1alpha1.CleanerList{}
+	if err := c.List(ctx, cleaners); err != nil {
+		return err
+	}
+
+	var targets []string
+	groups := make(map[string][]dueDigest)
+	for i := range cleaners.Items {
+		cleaner := &cleaners.Items[i]
+		if !cleaner.DeletionTimestamp.IsZero() {
+			continue
+		}
+		for j := range cleaner.Spec.Notifications {
+			notification := &cleaner.Spec.Notifications[j]
+			digest, due := getDueDigest(cleaner, notification, now,
+				getNotificationLogger(logger.WithValues("cleaner", cleaner.Name), notification))
+			if !due {
+				continue
+			}
+			target := cleaner.Name + "/" + notification.Name
+			if isCombinedDigestNotification(notification) {
+				target = getNotificationTarget(notification)
+			}
+			if _, ok := groups[target]; !ok {
+				targets = append(targets, target)
+			}
+			groups[target] = append(groups[target], dueDigest{cleaner: cleaner, notification: notification, digest: digest})
+		}
+	}
+
+	var errs []error
+	for _, target := range targets {
+		if err := sendDueDigestGroup(ctx, groups[target], now, logger.WithValues("digestTarget", target)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// getDueDigest returns the digest of notification and whether it must be sent
+// at now: its interval has elapsed, it accumulated at least one run and
+// notification is enabled, not suspended and within its active window
+func getDueDigest(cleaner *appsv1alpha1.Cleaner, notification *appsv1alpha1.Notification, now time.Time,
+	logger logr.Logger) (*appsv1alpha1.NotificationDigest, bool) {
+
+	if !isDigestNotification(notification) || !isNotificationEnabled(notification) ||
+		isNotificationSuspended(cleaner, notification.Name, now) {
+
+		return nil, false
+	}
+	digest := getNotificationDigest(cleaner, notification.Name, now)
+	if digest.Runs == 0 || now.Sub(digest.Since.Time) < notification.Digest.Interval.Duration {
+		return nil, false
+	}
+	inWindow, err := isInNotificationWindow(notification, now)
+	if err != nil {
+		logger.Error(err, logMsgSendFailed)
+		return nil, false
+	}
+	return digest, inWindow
+}
+
+// isCombinedDigestNotification returns true if the digest of notification can be
+// combined with the digests of other Cleaner instances sent to the same target.
+// Routed and threaded notifications depend on the Cleaner instance.
+func isCombinedDigestNotification(notification *appsv1alpha1.Notification) bool {
+	return batchableNotificationTypes[notification.Type] && notification.ChannelTemplate == "" &&
+		!isSlackThreadNotification(notification)
+}
+
+// sendDueDigestGroup sends the due digests of a target, as a single message when
+// there is more than one, then resets the digests sent and records the delivery
+// result for each notification. On failure, digests are kept so they are sent
+// again once the scheduler runs next.
+func sendDueDigestGroup(ctx context.Context, group []dueDigest, now time.Time, logger logr.Logger) error {
+	runID := string(uuid.NewUUID())
+	entries := make([]batchEntry, len(group))
+	for i := range group {
+		location, err := getNotificationLocation(group[i].notification)
+		if err != nil {
+			return err
+		}
+		reportSpec := generateReportSpec(nil, group[i].cleaner, runID, now.In(location))
+		reportSpec.ResourceInfo = group[i].digest.ResourceInfo
+		reportSpec.Summary = getReportSummary(reportSpec.ResourceInfo)
+		entries[i] = batchEntry{
+			cleaner:          group[i].cleaner,
+			notificationName: group[i].notification.Name,
+			reportSpec:       reportSpec,
+			message: getReasonMessage(group[i].cleaner.Spec.Reason,
+				getDigestMessage(group[i].cleaner.Name, group[i].digest, location)),
+		}
+	}
+
+	var err error
+	if len(group) == 1 {
+		logger.V(logs.LogDebug).Info("send digest", logKeyRuns, group[0].digest.Runs)
+		// Resources of previous runs are not available, so no Event is recorded on
+		// resources for digests
+		err = deliverNotification(ctx, entries[0].cleaner, entries[0].reportSpec, nil, entries[0].message,
+			group[0].notification, logger)
+	} else {
+		logger.V(logs.LogDebug).Info("send combined digests", "digests", len(group))
+		for i := range entries {
+			redactor, redactErr := getReportRedactor(entries[i].cleaner.Spec.ReportRedaction, group[i].notification.Type)
+			if redactErr != nil {
+				return redactErr
+			}
+			entries[i].reportSpec = redactor.redactReport(selectReportFields(entries[i].reportSpec, group[i].notification))
+			entries[i].message = redactor.redactString(entries[i].message)
+		}
+		err = sendNotificationBatch(ctx, group[0].notification, entries, getCombinedDigestMessage, logger)
+	}
+	if err != nil {
+		logger.Error(err, logMsgSendFailed)
+	}
+
+	for i := range group {
+		if err == nil {
+			sent := group[i].digest
+			if resetErr := mutateNotificationDigest(ctx, group[i].cleaner.Name, sent.NotificationName,
+				func(digest *appsv1alpha1.NotificationDigest) {
+					removeFromDigest(digest, sent, now)
+				}); resetErr != nil {
+
+				logger.Error(resetErr, "failed to reset digest")
+			}
+		}
+		if recordErr := recordNotificationResult(ctx, group[i].cleaner, group[i].notification.Name, err, now); recordErr != nil {
+			logger.Error(recordErr, logMsgRecordStatusFailed)
+		}
+	}
+	return err
+}
+
+// getCombinedDigestMessage returns the text sent along with the digests of
+// entries: one line per digest, in Cleaner order
+func getCombinedDigestMessage(entries []batchEntry) string {
+	lines := make([]string, len(entries))
+	for i := range entries {
+		lines[i] = "- " + entries[i].message
+	}
+	return fmt.Sprintf("k8s-cleaner combined %d digest(s):\n%s", len(entries), strings.Join(lines, "\n"))
+}
+
+// removeFromDigest removes from digest the runs of sent, a copy of digest taken
+// before it was sent. Runs accumulated since are kept, in a digest starting at
+// now. Digest is left untouched if it has been reset meanwhile.
+func removeFromDigest(digest, sent *appsv1alpha1.NotificationDigest, now time.Time) {
+	if !digest.Since.Equal(&sent.Since) || digest.Runs < sent.Runs ||
+		len(digest.ResourceInfo) < len(sent.ResourceInfo) {
+
+		return
+	}
+	digest.Since = metav1.NewTime(now)
+	digest.Runs -= sent.Runs
+	digest.ResourceInfo = digest.ResourceInfo[len(sent.ResourceInfo):]
+	digest.OmittedResources -= sent.OmittedResources
+}
+
+// sendDigest delivers a single notification combining all runs accumulated in
+// digest, then resets the digest. On failure, the digest is kept so it is sent
+// again when the next report is sent.
+func sendDigest(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	digest *appsv1alpha1.NotificationDigest, notification *appsv1alpha1.Notification, now time.Time,
+	logger logr.Logger) error {
+
+	logger.V(logs.LogDebug).Info("send digest", logKeyRuns, digest.Runs)
+	digestSpec := &appsv1alpha1.ReportSpec{
+		SchemaVersion: appsv1alpha1.ReportSchemaVersion,
+		Action:        reportSpec.Action,
+		ResourceInfo:  digest.ResourceInfo,
+		RunID:         reportSpec.RunID,
+		Reason:        reportSpec.Reason,
+		Summary:       getReportSummary(digest.ResourceInfo),
+		CleanerSpec:   reportSpec.CleanerSpec,
+	}
+	location, err := getNotificationLocation(notification)
+	if err != nil {
+		return err
+	}
+	message := getReasonMessage(cleaner.Spec.Reason, getDigestMessage(cleaner.Name, digest, location))
+
+	// Resources of previous runs are not available, so no Event is recorded on
+	// resources for digests
+	err = sendWithFailurePolicy(ctx, notification, func(ctx context.Context) error {
+		return deliverNotification(ctx, cleaner, digestSpec, nil, message, notification, logger)
+	}, logger)
+	if err != nil {
+		// Keep accumulating. Digest is sent again with the next report
+		if updateErr := updateNotificationDigest(ctx, cleaner.Name, digest); updateErr != nil {
+			logger.Error(updateErr, "failed to update digest")
+		}
+		return err
+	}
+
+	return updateNotificationDigest(ctx, cleaner.Name, &appsv1alpha1.NotificationDigest{
+		NotificationName: notification.Name,
+		Since:            metav1.NewTime(now),
+	})
+}
+
+// getNotificationDigest returns a copy of the digest stored in the Cleaner status for
+// the notification. A new digest starting at now is returned if none is found.
+func getNotificationDigest(cleaner *appsv1alpha1.Cleaner, notificationName string,
+	now time.Time) *appsv1alpha1.NotificationDigest {
+
+	for i := range cleaner.Status.NotificationDigests {
+		if cleaner.Status.NotificationDigests[i].NotificationName == notificationName {
+			return cleaner.Status.NotificationDigests[i].DeepCopy()
+		}
+	}
+
+	return &appsv1alpha1.NotificationDigest{
+		NotificationName: notificationName,
+		Since:            metav1.NewTime(now),
+	}
+}
+
+// addToDigest adds the resources in reportSpec to digest. Resources beyond
+// maxDigestResources are only counted.
+func addToDigest(digest *appsv1alpha1.NotificationDigest, reportSpec *appsv1alpha1.ReportSpec) {
+	digest.Runs++
+	for i := range reportSpec.ResourceInfo {
+		if len(digest.ResourceInfo) == maxDigestResources {
+			digest.OmittedResources += int32(len(reportSpec.ResourceInfo) - i)
+			return
+		}
+		digest.ResourceInfo = append(digest.ResourceInfo, reportSpec.ResourceInfo[i])
+	}
+}
+
+// getDigestMessage returns the text sent along with a digest. Timestamps are
+// formatted in location.
+func getDigestMessage(cleanerName string, digest *appsv1alpha1.NotificationDigest,
+	location *time.Location) string {
+
+	message := fmt.Sprintf("This digest has been generated by k8s-cleaner for instance: %s. %d run(s) since %s",
+		cleanerName, digest.Runs, digest.Since.In(location).Format(time.RFC3339))
+	if digest.OmittedResources > 0 {
+		message += fmt.Sprintf(" (%d resources omitted)", digest.OmittedResources)
+	}
+	return message
+}
+
+// updateNotificationDigest stores digest in the Cleaner status
+func updateNotificationDigest(ctx context.Context, cleanerName string,
+	digest *appsv1alpha1.NotificationDigest) error {
+
+	return mutateNotificationDigest(ctx, cleanerName, digest.NotificationName,
+		func(current *appsv1alpha1.NotificationDigest) {
+			*current = *digest
+		})
+}
+
+// mutateNotificationDigest applies mutate to the digest of notificationName as
+// currently stored in the Cleaner status, a new digest if none is stored, and
+// stores the result
+func mutateNotificationDigest(ctx context.Context, cleanerName, notificationName string,
+	mutate func(digest *appsv1alpha1.NotificationDigest)) error {
+
+	return updateCleanerStatus(ctx, cleanerName, func(cleaner *appsv1alpha1.Cleaner) {
+		digest := getNotificationDigest(cleaner, notificationName, time.Now())
+		mutate(digest)
+
+		// Digests of notifications removed, or not accumulating reports anymore,
+		// are dropped
+		digestNames := make(map[string]bool)
+		for i := range cleaner.Spec.Notifications {
+			if isDigestNotification(&cleaner.Spec.Notifications[i]) ||
+				isQueueWindowNotification(&cleaner.Spec.Notifications[i]) {
+				digestNames[cleaner.Spec.Notifications[i].Name] = true
+			}
+		}
+
+		digests := []appsv1alpha1.NotificationDigest{*digest}
+		for i := range cleaner.Status.NotificationDigests {
+			name := cleaner.Status.NotificationDigests[i].NotificationName
+			if name != digest.NotificationName && digestNames[name] {
+				digests = append(digests, cleaner.Status.NotificationDigests[i])
+			}
+		}
+		sort.Slice(digests, func(i, j int) bool {
+			return digests[i].NotificationName < digests[j].NotificationName
+		})
+		cleaner.Status.NotificationDigests = digests
+	})
+}
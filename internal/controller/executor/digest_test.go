@@ -0,0 +1,200 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+// createDigestCleaner creates a Cleaner with a Slack digest notification sent
+// every interval, using the Secret ref. Each token is mapped to its own fake
+// client, so that digests of other Cleaner instances are not seen.
+func createDigestCleaner(ref *corev1.ObjectReference, interval time.Duration) *appsv1alpha1.Cleaner {
+	cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+	cleaner.Spec.Schedule = "0 * * * *"
+	cleaner.Spec.ResourcePolicySet.ResourceSelectors = []appsv1alpha1.ResourceSelector{
+		{Kind: "ConfigMap", Version: "v1"},
+	}
+	cleaner.Spec.Notifications[0].Digest = &appsv1alpha1.DigestOptions{
+		Interval: metav1.Duration{Duration: interval},
+	}
+	Expect(k8sClient.Create(context.TODO(), cleaner)).To(Succeed())
+	Expect(waitForObject(context.TODO(), k8sClient, cleaner)).To(Succeed())
+	return cleaner
+}
+
+// setDigestFactory makes fake the Slack client of token. Other tokens get a
+// client discarding messages.
+func setDigestFactory(token string, fake *fakeSlackClient) {
+	DeferCleanup(executor.SetSlackClientFactory(func(t string) executor.SlackClient {
+		if t == token {
+			return fake
+		}
+		return &fakeSlackClient{}
+	}))
+}
+
+// moveDigestBack moves the start of the digest of cleaner back by d
+func moveDigestBack(cleaner *appsv1alpha1.Cleaner, d time.Duration) {
+	currentCleaner := &appsv1alpha1.Cleaner{}
+	Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, currentCleaner)).To(Succeed())
+	Expect(currentCleaner.Status.NotificationDigests).To(HaveLen(1))
+	digest := &currentCleaner.Status.NotificationDigests[0]
+	digest.Since = metav1.NewTime(digest.Since.Add(-d))
+	Expect(k8sClient.Status().Update(context.TODO(), currentCleaner)).To(Succeed())
+}
+
+var _ = Describe("Digest", func() {
+	It("sendNotifications accumulates runs and sendDueDigests sends a single digest once interval elapsed", func() {
+		token := randomString()
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(token),
+		})
+		fake := &fakeSlackClient{}
+		setDigestFactory(token, fake)
+
+		cleaner := createDigestCleaner(ref, time.Hour)
+
+		first := getResourceResult("ConfigMap", randomString(), randomString())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{first},
+			cleaner, "", logr.Discard())).To(Succeed())
+		currentCleaner := &appsv1alpha1.Cleaner{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, currentCleaner)).To(Succeed())
+		second := getResourceResult("ConfigMap", randomString(), randomString())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{second},
+			currentCleaner, "", logr.Discard())).To(Succeed())
+		Expect(fake.values).To(BeEmpty())
+
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, currentCleaner)).To(Succeed())
+		Expect(currentCleaner.Status.NotificationDigests).To(HaveLen(1))
+		digest := &currentCleaner.Status.NotificationDigests[0]
+		Expect(digest.NotificationName).To(Equal(cleaner.Spec.Notifications[0].Name))
+		Expect(digest.Runs).To(Equal(int32(2)))
+		Expect(digest.ResourceInfo).To(HaveLen(2))
+
+		// Interval has not elapsed yet
+		Expect(executor.SendDueDigests(context.TODO(), time.Now(), logr.Discard())).To(Succeed())
+		Expect(fake.values).To(BeEmpty())
+
+		Expect(executor.SendDueDigests(context.TODO(), time.Now().Add(2*time.Hour), logr.Discard())).To(Succeed())
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(ContainSubstring("2 run(s) since"))
+		Expect(fake.values[0].Get("attachments")).To(ContainSubstring(first.Resource.GetName()))
+		Expect(fake.values[0].Get("attachments")).To(ContainSubstring(second.Resource.GetName()))
+
+		// Digest is reset after being sent
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, currentCleaner)).To(Succeed())
+		Expect(currentCleaner.Status.NotificationDigests).To(HaveLen(1))
+		Expect(currentCleaner.Status.NotificationDigests[0].Runs).To(BeZero())
+		Expect(currentCleaner.Status.NotificationDigests[0].ResourceInfo).To(BeEmpty())
+	})
+
+	It("sendDueDigests sends a pending digest with no new run", func() {
+		token := randomString()
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(token),
+		})
+		fake := &fakeSlackClient{}
+		setDigestFactory(token, fake)
+
+		cleaner := createDigestCleaner(ref, time.Hour)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+		moveDigestBack(cleaner, 2*time.Hour)
+
+		// Cleaner does not run again
+		Expect(executor.SendDueDigests(context.TODO(), time.Now(), logr.Discard())).To(Succeed())
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(ContainSubstring("1 run(s) since"))
+		Expect(fake.values[0].Get("attachments")).To(ContainSubstring(resource.Resource.GetName()))
+
+		// Nothing is sent until a run is accumulated again
+		Expect(executor.SendDueDigests(context.TODO(), time.Now().Add(2*time.Hour), logr.Discard())).To(Succeed())
+		Expect(fake.values).To(HaveLen(1))
+	})
+
+	It("sendDueDigests combines the digests sent to the same target, grouped by Cleaner instance", func() {
+		token := randomString()
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(token),
+		})
+		fake := &fakeSlackClient{}
+		setDigestFactory(token, fake)
+
+		resources := map[string]executor.ResourceResult{}
+		for i := 0; i < 2; i++ {
+			cleaner := createDigestCleaner(ref, time.Hour)
+			resources[cleaner.Name] = getResourceResult("ConfigMap", randomString(), randomString())
+			Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resources[cleaner.Name]},
+				cleaner, "", logr.Discard())).To(Succeed())
+		}
+
+		Expect(executor.SendDueDigests(context.TODO(), time.Now().Add(2*time.Hour), logr.Discard())).To(Succeed())
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(HavePrefix("k8s-cleaner combined 2 digest(s):"))
+		for cleanerName, resource := range resources {
+			Expect(fake.values[0].Get("text")).To(ContainSubstring("for instance: " + cleanerName))
+			Expect(fake.values[0].Get("attachments")).To(ContainSubstring(resource.Resource.GetName()))
+
+			currentCleaner := &appsv1alpha1.Cleaner{}
+			Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleanerName}, currentCleaner)).To(Succeed())
+			Expect(currentCleaner.Status.NotificationDigests[0].Runs).To(BeZero())
+		}
+	})
+
+	It("sendDueDigests keeps accumulating when digest delivery fails", func() {
+		token := randomString()
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(token),
+		})
+		setDigestFactory(token, &fakeSlackClient{err: fmt.Errorf("connection refused")})
+
+		cleaner := createDigestCleaner(ref, time.Hour)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(executor.SendDueDigests(context.TODO(), time.Now().Add(2*time.Hour), logr.Discard())).ToNot(Succeed())
+
+		currentCleaner := &appsv1alpha1.Cleaner{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, currentCleaner)).To(Succeed())
+		Expect(currentCleaner.Status.NotificationDigests).To(HaveLen(1))
+		Expect(currentCleaner.Status.NotificationDigests[0].Runs).To(Equal(int32(1)))
+		Expect(currentCleaner.Status.NotificationDigests[0].ResourceInfo).To(HaveLen(1))
+		Expect(currentCleaner.Status.NotificationStatuses).To(HaveLen(1))
+		Expect(currentCleaner.Status.NotificationStatuses[0].ConsecutiveFailures).To(Equal(int32(1)))
+	})
+})
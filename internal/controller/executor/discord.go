@@ -0,0 +1,256 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+// Discord embed limits
+// https://discord.com/developers/docs/resources/message#embed-object-embed-limits
+const (
+	discordMaxEmbedFields     = 25
+	discordMaxEmbedTitle      = 256
+	discordMaxEmbedFieldName  = 256
+	discordMaxEmbedFieldValue = 1024
+	discordMaxEmbedAuthorName = 256
+
+	// discordMaxContent is the maximum length of a message content
+	discordMaxContent = 2000
+
+	// discordMaxContentMessages is the maximum number of messages a long content
+	// is split into. Content not fitting is truncated.
+	discordMaxContentMessages = 5
+)
+
+// Embed colors
+const (
+	discordColorNoResources = 0x2EB67D // green
+	discordColorScan        = 0x3B88C3 // blue
+	discordColorTransform   = 0xF4A100 // orange
+	discordColorDelete      = 0xE01E5A // red
+	discordColorFailed      = 0xB00020 // dark red
+)
+
+// getDiscordEmbed returns an embed summarizing the report: title with Cleaner
+// name and action, color keyed to the action severity (or to failures) and one
+// field per resource kind with the number of resources. Notification metadata,
+// if any, is added as fields as well. Failed resources and transform diffs, if
+// any, are set as description.
+// The run ID is set as footer. Notification username and icon URL, if set, are
+// shown as author. The title links to the first link, if any, and the other
+// links are listed in the description.
+// Discord limits are respected: when there are more kinds than available fields,
+// remaining kinds are summarized in a single field.
+func getDiscordEmbed(cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	notification *appsv1alpha1.Notification, links []notificationLink) *discordgo.MessageEmbed {
+
+	embed := &discordgo.MessageEmbed{
+		Title: truncateString(fmt.Sprintf("%s: %s", cleaner.Name, reportSpec.Action), discordMaxEmbedTitle),
+		Color: getDiscordEmbedColor(reportSpec),
+	}
+	if notification.Username != "" {
+		// Bots cannot change their name per message
+		embed.Author = &discordgo.MessageEmbedAuthor{
+			Name:    truncateString(notification.Username, discordMaxEmbedAuthorName),
+			IconURL: notification.IconURL,
+		}
+	}
+	metadata := notification.Metadata
+	description := []string{}
+	if failures := getFailedResourcesMarkdown(reportSpec, discordMaxFailuresSize); failures != "" {
+		description = append(description, failures)
+	}
+	if diff := getDiffMarkdown(reportSpec, discordMaxDiffSize); diff != "" {
+		description = append(description, diff)
+	}
+	if len(links) > 0 {
+		embed.URL = links[0].url
+		for i := 1; i < len(links); i++ {
+			description = append(description, fmt.Sprintf("[%s](%s)", links[i].label, links[i].url))
+		}
+	}
+	embed.Description = strings.Join(description, "\n")
+	if reportSpec.RunID != "" {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("Run ID: %s", reportSpec.RunID)}
+	}
+
+	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+		Name:   "Resources",
+		Value:  strconv.Itoa(len(reportSpec.ResourceInfo)),
+		Inline: true,
+	})
+
+	metadataKeys := getSortedMetadataKeys(metadata)
+	if len(metadataKeys) > discordMaxEmbedFields-2 {
+		// Keep room for total and at least one kind summary field
+		metadataKeys = metadataKeys[:discordMaxEmbedFields-2]
+	}
+
+	kinds, counts := getResourceCountByKind(reportSpec)
+	available := discordMaxEmbedFields - len(embed.Fields) - len(metadataKeys)
+	for i := range kinds {
+		if i == available-1 && len(kinds) > available {
+			// Summarize all remaining kinds in last available field
+			remaining := 0
+			for j := i; j < len(kinds); j++ {
+				remaining += counts[kinds[j]]
+			}
+			embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+				Name: "Other kinds",
+				Value: truncateString(fmt.Sprintf("%d resources in %d kinds: %s", remaining, len(kinds)-i,
+					strings.Join(kinds[i:], ", ")), discordMaxEmbedFieldValue),
+				Inline: true,
+			})
+			break
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   truncateString(kinds[i], discordMaxEmbedFieldName),
+			Value:  strconv.Itoa(counts[kinds[i]]),
+			Inline: true,
+		})
+	}
+
+	for _, key := range metadataKeys {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   truncateString(key, discordMaxEmbedFieldName),
+			Value:  truncateString(metadata[key], discordMaxEmbedFieldValue),
+			Inline: true,
+		})
+	}
+
+	return embed
+}
+
+func getDiscordEmbedColor(reportSpec *appsv1alpha1.ReportSpec) int {
+	if len(reportSpec.ResourceInfo) == 0 {
+		return discordColorNoResources
+	}
+	if getFailedResourceCount(reportSpec) > 0 {
+		return discordColorFailed
+	}
+
+	switch reportSpec.Action {
+	case appsv1alpha1.ActionDelete:
+		return discordColorDelete
+	case appsv1alpha1.ActionTransform:
+		return discordColorTransform
+	default:
+		return discordColorScan
+	}
+}
+
+// getResourceCountByKind returns the kinds present in reportSpec, sorted by number
+// of resources (descending) and name, along with the number of resources per kind
+func getResourceCountByKind(reportSpec *appsv1alpha1.ReportSpec) ([]string, map[string]int) {
+	counts := make(map[string]int)
+	for i := range reportSpec.ResourceInfo {
+		counts[reportSpec.ResourceInfo[i].Resource.Kind]++
+	}
+
+	kinds := make([]string, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool {
+		if counts[kinds[i]] != counts[kinds[j]] {
+			return counts[kinds[i]] > counts[kinds[j]]
+		}
+		return kinds[i] < kinds[j]
+	})
+
+	return kinds, counts
+}
+
+// truncateString returns s cut to at most maxLength characters. An ellipsis
+// marks truncated values.
+func truncateString(s string, maxLength int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLength {
+		return s
+	}
+	return string(runes[:maxLength-1]) + "…"
+}
+
+// validateDiscordInfo verifies token and channel ID are set and that channel ID
+// is a Discord snowflake (a numeric ID)
+func validateDiscordInfo(info *discordInfo) error {
+	if strings.TrimSpace(info.token) == "" {
+		return fmt.Errorf("discord token is empty: set %s in the secret to the bot token",
+			libsveltosv1alpha1.DiscordToken)
+	}
+	if strings.HasPrefix(info.token, "Bot ") {
+		return fmt.Errorf("discord token must not contain the \"Bot \" prefix")
+	}
+
+	if info.serverID == "" {
+		return fmt.Errorf("discord channel ID is empty: set %s in the secret",
+			libsveltosv1alpha1.DiscordChannelID)
+	}
+	if _, err := strconv.ParseUint(info.serverID, 10, 64); err != nil {
+		return fmt.Errorf("discord channel ID %q is not valid: it must be the numeric channel ID "+
+			"(enable Developer Mode in Discord and use \"Copy Channel ID\")", info.serverID)
+	}
+
+	return nil
+}
+
+// translateDiscordError converts common Discord API errors into actionable
+// messages. Original error is wrapped.
+func translateDiscordError(err error, channelID string) error {
+	if errors.Is(err, discordgo.ErrUnauthorized) {
+		return fmt.Errorf("discord token is invalid or revoked: %w", err)
+	}
+
+	var restErr *discordgo.RESTError
+	if !errors.As(err, &restErr) {
+		return err
+	}
+
+	code := 0
+	if restErr.Message != nil {
+		code = restErr.Message.Code
+	}
+
+	switch {
+	case code == discordgo.ErrCodeInvalidAuthenticationToken ||
+		(restErr.Response != nil && restErr.Response.StatusCode == http.StatusUnauthorized):
+		return fmt.Errorf("discord token is invalid or revoked: %w", err)
+	case code == discordgo.ErrCodeUnknownChannel:
+		return fmt.Errorf("discord channel %s does not exist or is not visible to the bot: "+
+			"verify the channel ID: %w", channelID, err)
+	case code == discordgo.ErrCodeMissingAccess:
+		return fmt.Errorf("bot lacks VIEW_CHANNEL in channel %s (or was not added to the server): %w",
+			channelID, err)
+	case code == discordgo.ErrCodeMissingPermissions:
+		return fmt.Errorf("bot lacks SEND_MESSAGES, EMBED_LINKS or ATTACH_FILES in channel %s: %w",
+			channelID, err)
+	}
+
+	return err
+}
@@ -0,0 +1,277 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+const (
+	elasticsearchRequestTimeout = 30 * time.Second
+
+	// maximum number of bytes of the Elasticsearch response included in errors
+	maxElasticsearchResponseBody = 4096
+
+	// elasticsearchMaxDocumentsPerBulk is the maximum number of documents sent
+	// in a single bulk request. Larger reports are sent with multiple requests.
+	elasticsearchMaxDocumentsPerBulk = 1000
+)
+
+type elasticsearchInfo struct {
+	url      string
+	index    string
+	apiKey   string
+	username string
+	password string
+	// tlsConfig is nil when the secret defines no client certificate nor CA
+	tlsConfig *tls.Config
+}
+
+// elasticsearchDocument is the document indexed for each resource of a report
+type elasticsearchDocument struct {
+	Timestamp   string                       `json:"@timestamp"`
+	Cleaner     string                       `json:"cleaner"`
+	Action      appsv1alpha1.Action          `json:"action"`
+	RunID       string                       `json:"runID,omitempty"`
+	APIVersion  string                       `json:"apiVersion,omitempty"`
+	Kind        string                       `json:"kind"`
+	Namespace   string                       `json:"namespace,omitempty"`
+	Name        string                       `json:"name"`
+	Outcome     appsv1alpha1.ResourceOutcome `json:"outcome,omitempty"`
+	Message     string                       `json:"message,omitempty"`
+	Error       string                       `json:"error,omitempty"`
+	Labels      map[string]string            `json:"labels,omitempty"`
+	Annotations map[string]string            `json:"annotations,omitempty"`
+	Metadata    map[string]string            `json:"metadata,omitempty"`
+}
+
+// elasticsearchBulkResponse contains the fields of a bulk API response used
+// to report documents which were not indexed
+// https://www.elastic.co/guide/en/elasticsearch/reference/current/docs-bulk.html#bulk-api-response-body
+type elasticsearchBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error,omitempty"`
+	} `json:"items"`
+}
+
+func init() {
+	registerNotifier(appsv1alpha1.NotificationTypeElasticsearch, notifierFunc(
+		func(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+			_ []ResourceResult, _ string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+			return sendElasticsearchNotification(ctx, cleaner, reportSpec, notification, logger)
+		}))
+}
+
+// sendElasticsearchNotification indexes a document per resource of the report
+// using the bulk API, in batches of at most elasticsearchMaxDocumentsPerBulk
+// documents. Reports with no resource index nothing.
+func sendElasticsearchNotification(ctx context.Context, cleaner *appsv1alpha1.Cleaner,
+	reportSpec *appsv1alpha1.ReportSpec, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+	info, err := getElasticsearchInfo(ctx, notification)
+	if err != nil {
+		return err
+	}
+
+	l := logger.WithValues(logKeyURL, info.url, logKeyIndex, info.index)
+	if len(reportSpec.ResourceInfo) == 0 {
+		l.V(logs.LogDebug).Info("no resource to index")
+		return nil
+	}
+	l.V(logs.LogInfo).Info("index elasticsearch documents")
+
+	bulks, err := getElasticsearchBulks(cleaner.Name, reportSpec, info.index, notification.Metadata, time.Now())
+	if err != nil {
+		l.Error(err, "failed to prepare elasticsearch bulk request")
+		return err
+	}
+
+	for i := range bulks {
+		if err := postElasticsearchBulk(ctx, info, bulks[i]); err != nil {
+			err = fmt.Errorf("bulk request %d of %d failed: %w", i+1, len(bulks), err)
+			l.Error(err, logMsgSendFailed)
+			return err
+		}
+	}
+
+	l.V(logs.LogDebug).Info("elasticsearch documents indexed", "requests", len(bulks))
+	return nil
+}
+
+// getElasticsearchBulks returns the bodies of the bulk requests indexing a
+// document per resource of reportSpec into index. Documents are created, so
+// index can be a data stream, and timestamped with now.
+func getElasticsearchBulks(cleanerName string, reportSpec *appsv1alpha1.ReportSpec, index string,
+	metadata map[string]string, now time.Time) ([][]byte, error) {
+
+	action, err := json.Marshal(map[string]map[string]string{"create": {"_index": index}})
+	if err != nil {
+		return nil, err
+	}
+	timestamp := now.UTC().Format(time.RFC3339Nano)
+
+	var bulks [][]byte
+	var buf bytes.Buffer
+	for i := range reportSpec.ResourceInfo {
+		info := &reportSpec.ResourceInfo[i]
+		document, err := json.Marshal(&elasticsearchDocument{
+			Timestamp:   timestamp,
+			Cleaner:     cleanerName,
+			Action:      reportSpec.Action,
+			RunID:       reportSpec.RunID,
+			APIVersion:  info.Resource.APIVersion,
+			Kind:        info.Resource.Kind,
+			Namespace:   info.Resource.Namespace,
+			Name:        info.Resource.Name,
+			Outcome:     info.Outcome,
+			Message:     info.Message,
+			Error:       info.Error,
+			Labels:      info.Labels,
+			Annotations: info.Annotations,
+			Metadata:    metadata,
+		})
+		if err != nil {
+			return nil, err
+		}
+		// Bulk body is newline delimited JSON, terminated by a newline
+		buf.Write(action)
+		buf.WriteByte('\n')
+		buf.Write(document)
+		buf.WriteByte('\n')
+
+		if (i+1)%elasticsearchMaxDocumentsPerBulk == 0 || i == len(reportSpec.ResourceInfo)-1 {
+			bulks = append(bulks, bytes.Clone(buf.Bytes()))
+			buf.Reset()
+		}
+	}
+	return bulks, nil
+}
+
+func postElasticsearchBulk(ctx context.Context, info *elasticsearchInfo, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, info.url+"/_bulk", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	switch {
+	case info.apiKey != "":
+		req.Header.Set("Authorization", "ApiKey "+info.apiKey)
+	case info.username != "":
+		req.SetBasicAuth(info.username, info.password)
+	}
+
+	resp, err := newNotificationTLSHTTPClient(elasticsearchRequestTimeout, info.tlsConfig).Do(req)
+	if err != nil {
+		return getTLSError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxElasticsearchResponseBody))
+		return fmt.Errorf("elasticsearch returned %s: %s", resp.Status, string(data))
+	}
+
+	// Bulk requests succeed even when documents are rejected
+	response := &elasticsearchBulkResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("failed to decode elasticsearch response: %w", err)
+	}
+	return getElasticsearchBulkError(response)
+}
+
+// getElasticsearchBulkError returns an error reporting how many documents the
+// bulk request failed to index, and the reason of the first failure. Nil is
+// returned when every document was indexed.
+func getElasticsearchBulkError(response *elasticsearchBulkResponse) error {
+	if !response.Errors {
+		return nil
+	}
+
+	failed := 0
+	reason := ""
+	for i := range response.Items {
+		for _, item := range response.Items[i] {
+			if item.Error == nil {
+				continue
+			}
+			if failed == 0 {
+				reason = fmt.Sprintf("%s: %s", item.Error.Type, item.Error.Reason)
+			}
+			failed++
+		}
+	}
+	return fmt.Errorf("elasticsearch failed to index %d of %d documents: %s", failed, len(response.Items), reason)
+}
+
+func getElasticsearchInfo(ctx context.Context, notification *appsv1alpha1.Notification) (*elasticsearchInfo, error) {
+	secret, err := getSecret(ctx, notification)
+	if err != nil {
+		return nil, err
+	}
+
+	url, ok := secret.Data[appsv1alpha1.ElasticsearchURL]
+	if !ok || len(url) == 0 {
+		return nil, fmt.Errorf("secret does not contain elasticsearch URL")
+	}
+
+	index, ok := secret.Data[appsv1alpha1.ElasticsearchIndex]
+	if !ok || len(index) == 0 {
+		return nil, fmt.Errorf("secret does not contain elasticsearch index")
+	}
+
+	info := &elasticsearchInfo{
+		url:      strings.TrimSuffix(string(bytes.TrimSpace(url)), "/"),
+		index:    string(bytes.TrimSpace(index)),
+		apiKey:   string(bytes.TrimSpace(secret.Data[appsv1alpha1.ElasticsearchAPIKey])),
+		username: string(secret.Data[appsv1alpha1.ElasticsearchUsername]),
+	}
+	if info.username != "" {
+		password, ok := secret.Data[appsv1alpha1.ElasticsearchPassword]
+		if !ok {
+			return nil, fmt.Errorf("secret must contain %s when %s is set",
+				appsv1alpha1.ElasticsearchPassword, appsv1alpha1.ElasticsearchUsername)
+		}
+		info.password = string(password)
+	}
+
+	info.tlsConfig, err = getNotificationTLSConfig(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+// fakeElasticsearch is an Elasticsearch bulk endpoint recording the lines of
+// every request. When response is set, it is returned instead of a success.
+type fakeElasticsearch struct {
+	mux      sync.Mutex
+	requests [][]map[string]interface{}
+	headers  []http.Header
+	paths    []string
+	response string
+}
+
+func (f *fakeElasticsearch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		line := map[string]interface{}{}
+		Expect(json.Unmarshal(scanner.Bytes(), &line)).To(Succeed())
+		lines = append(lines, line)
+	}
+	Expect(scanner.Err()).To(BeNil())
+	f.requests = append(f.requests, lines)
+	f.headers = append(f.headers, r.Header.Clone())
+	f.paths = append(f.paths, r.URL.Path)
+
+	w.Header().Set("Content-Type", "application/json")
+	if f.response != "" {
+		_, _ = w.Write([]byte(f.response))
+		return
+	}
+	_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+}
+
+// createElasticsearchSecret starts a fake Elasticsearch and creates the
+// notification secret pointing to it. extra is added to the secret data.
+func createElasticsearchSecret(index string, extra map[string][]byte) (*corev1.ObjectReference, *fakeElasticsearch) {
+	fake := &fakeElasticsearch{}
+	server := httptest.NewServer(fake)
+	DeferCleanup(server.Close)
+
+	data := map[string][]byte{
+		appsv1alpha1.ElasticsearchURL:   []byte(server.URL + "/"),
+		appsv1alpha1.ElasticsearchIndex: []byte(index),
+	}
+	for key, value := range extra {
+		data[key] = value
+	}
+	return createNotificationSecret(data), fake
+}
+
+var _ = Describe("Elasticsearch", func() {
+	It("sendNotifications indexes a document per resource with the bulk API", func() {
+		index := randomString()
+		apiKey := randomString()
+		ref, fake := createElasticsearchSecret(index, map[string][]byte{
+			appsv1alpha1.ElasticsearchAPIKey: []byte(apiKey),
+		})
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeElasticsearch, ref)
+		cleaner.Spec.Notifications[0].Metadata = map[string]string{"team": "platform"}
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		resource.Outcome = appsv1alpha1.ResourceOutcomeSucceeded
+		runID := randomString()
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, runID, logr.Discard())).To(Succeed())
+
+		Expect(fake.requests).To(HaveLen(1))
+		Expect(fake.paths[0]).To(Equal("/_bulk"))
+		Expect(fake.headers[0].Get("Content-Type")).To(Equal("application/x-ndjson"))
+		Expect(fake.headers[0].Get("Authorization")).To(Equal("ApiKey " + apiKey))
+
+		lines := fake.requests[0]
+		Expect(lines).To(HaveLen(2))
+		Expect(lines[0]).To(Equal(map[string]interface{}{"create": map[string]interface{}{"_index": index}}))
+		document := lines[1]
+		Expect(document["@timestamp"]).ToNot(BeEmpty())
+		Expect(document["cleaner"]).To(Equal(cleaner.Name))
+		Expect(document["action"]).To(Equal(string(appsv1alpha1.ActionDelete)))
+		Expect(document["runID"]).To(Equal(runID))
+		Expect(document["kind"]).To(Equal("ConfigMap"))
+		Expect(document["namespace"]).To(Equal(resource.Resource.GetNamespace()))
+		Expect(document["name"]).To(Equal(resource.Resource.GetName()))
+		Expect(document["outcome"]).To(Equal(string(appsv1alpha1.ResourceOutcomeSucceeded)))
+		Expect(document["metadata"]).To(Equal(map[string]interface{}{"team": "platform"}))
+	})
+
+	It("sendNotifications splits large reports into multiple bulk requests", func() {
+		ref, fake := createElasticsearchSecret(randomString(), map[string][]byte{
+			appsv1alpha1.ElasticsearchUsername: []byte("cleaner"),
+			appsv1alpha1.ElasticsearchPassword: []byte("secret"),
+		})
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeElasticsearch, ref)
+		namespace := randomString()
+		resources := make([]executor.ResourceResult, 1200)
+		for i := range resources {
+			resources[i] = getResourceResult("ConfigMap", namespace, randomString())
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.requests).To(HaveLen(2))
+		Expect(fake.requests[0]).To(HaveLen(2 * 1000))
+		Expect(fake.requests[1]).To(HaveLen(2 * 200))
+		Expect(fake.headers[0].Get("Authorization")).To(HavePrefix("Basic "))
+	})
+
+	It("sendNotifications does not send a request when there is no resource", func() {
+		ref, fake := createElasticsearchSecret(randomString(), nil)
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeElasticsearch, ref)
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+		Expect(fake.requests).To(BeEmpty())
+	})
+
+	It("sendNotifications fails when documents are rejected", func() {
+		ref, fake := createElasticsearchSecret(randomString(), nil)
+		fake.response = `{"errors":true,"items":[{"create":{"status":201}},` +
+			`{"create":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse field"}}}]}`
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeElasticsearch, ref)
+		resources := []executor.ResourceResult{
+			getResourceResult("ConfigMap", randomString(), randomString()),
+			getResourceResult("ConfigMap", randomString(), randomString()),
+		}
+
+		err := executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("elasticsearch failed to index 1 of 2 documents: " +
+			"mapper_parsing_exception: failed to parse field"))
+	})
+
+	It("sendNotifications verifies the server certificate with the CA of the secret", func() {
+		fake := &fakeElasticsearch{}
+		server := httptest.NewTLSServer(fake)
+		DeferCleanup(server.Close)
+
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.ElasticsearchURL:   []byte(server.URL),
+			appsv1alpha1.ElasticsearchIndex: []byte(randomString()),
+			appsv1alpha1.TLSCACert:          getServerCAPEM(server),
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeElasticsearch, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+		Expect(fake.requests).To(HaveLen(1))
+	})
+})
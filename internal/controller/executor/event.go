@@ -0,0 +1,177 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+const (
+	// eventReasonReport is the reason of Events recorded on Cleaner instances
+	eventReasonReport = "CleanerReport"
+
+	// eventReasonFailure is the reason of Events recorded on Cleaner instances
+	// when a run fails
+	eventReasonFailure = "CleanerFailed"
+
+	// eventReasonResourceFailure is the reason of Events recorded on resources
+	// the action failed on
+	eventReasonResourceFailure = "CleanerActionFailed"
+
+	// maxEventMessageSize is the maximum size of an Event message. Bigger
+	// messages are truncated by the API server.
+	maxEventMessageSize = 1024
+
+	// maxResourceEvents is the maximum number of Events recorded on matching
+	// resources per run. Along with the spam filter of the event recorder, this
+	// prevents a single run from flooding the API server with Events.
+	maxResourceEvents = 100
+)
+
+func init() {
+	registerNotifier(appsv1alpha1.NotificationTypeEvent, notifierFunc(
+		func(_ context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+			resources []ResourceResult, _ string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+			return sendEventNotification(cleaner, reportSpec, resources, notification, logger)
+		}))
+}
+
+// sendEventNotification records a Kubernetes Event on the Cleaner instance
+// summarizing the resources processed. If requested, an Event is also recorded
+// on each resource.
+// Events are recorded using the controller event recorder, which aggregates
+// similar Events and throttles Events per object.
+func sendEventNotification(cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	resources []ResourceResult, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+	if eventRecorder == nil {
+		return fmt.Errorf("event recorder is not initialized")
+	}
+
+	logger.V(logs.LogInfo).Info("record event")
+	if reportSpec.Error != "" || getFailedResourceCount(reportSpec) > 0 {
+		eventRecorder.Event(cleaner, corev1.EventTypeWarning, eventReasonFailure, getEventMessage(reportSpec))
+	} else {
+		eventRecorder.Event(cleaner, corev1.EventTypeNormal, eventReasonReport, getEventMessage(reportSpec))
+	}
+
+	if notification.Event == nil || !notification.Event.RecordOnResources {
+		return nil
+	}
+
+	reason := getResourceEventReason(reportSpec.Action)
+	for i := range resources {
+		if i == maxResourceEvents {
+			logger.V(logs.LogInfo).Info("too many resources. Skip recording events on remaining ones",
+				"recorded", maxResourceEvents, "skipped", len(resources)-maxResourceEvents)
+			break
+		}
+
+		eventType, eventReason := corev1.EventTypeNormal, reason
+		message := fmt.Sprintf("%s by Cleaner %s", strings.TrimSuffix(reason, "ByCleaner"), cleaner.Name)
+		if resources[i].Outcome == appsv1alpha1.ResourceOutcomeFailed {
+			eventType, eventReason = corev1.EventTypeWarning, eventReasonResourceFailure
+			message = fmt.Sprintf("Cleaner %s failed to %s resource", cleaner.Name,
+				strings.ToLower(string(reportSpec.Action)))
+		}
+		if reportSpec.RunID != "" {
+			message += fmt.Sprintf(" (run ID: %s)", reportSpec.RunID)
+		}
+		if resources[i].Outcome == appsv1alpha1.ResourceOutcomeFailed {
+			message += ": " + resources[i].Error
+		} else if resources[i].Message != "" {
+			message += ": " + resources[i].Message
+		}
+		eventRecorder.Event(resources[i].Resource, eventType, eventReason, truncateEventMessage(message))
+	}
+
+	return nil
+}
+
+// getEventMessage returns a summary of the resources in reportSpec. Resources which
+// do not fit in an Event message are omitted. For failed runs, the error follows
+// the list of resources.
+func getEventMessage(reportSpec *appsv1alpha1.ReportSpec) string {
+	message := fmt.Sprintf("Action %s on %d resource(s)", reportSpec.Action, len(reportSpec.ResourceInfo))
+	suffix := ""
+	if failed := getFailedResourceCount(reportSpec); failed > 0 {
+		message += fmt.Sprintf(", %d failed", failed)
+	}
+	if reportSpec.Error != "" {
+		message = fmt.Sprintf("Action %s failed after %d resource(s)", reportSpec.Action,
+			len(reportSpec.ResourceInfo))
+		suffix = ". Error: " + truncateString(reportSpec.Error, maxEventMessageSize/2)
+	}
+	if reportSpec.RunID != "" {
+		message += fmt.Sprintf(" (run ID: %s)", reportSpec.RunID)
+	}
+
+	for i := range reportSpec.ResourceInfo {
+		separator := ": "
+		if i > 0 {
+			separator = ", "
+		}
+		candidate := message + separator + getResourceDescription(&reportSpec.ResourceInfo[i].Resource)
+
+		omitted := len(reportSpec.ResourceInfo) - i - 1
+		size := len(candidate) + len(suffix)
+		if omitted > 0 {
+			size += len(getTruncationMarker(omitted))
+		}
+		if size > maxEventMessageSize {
+			return message + getTruncationMarker(len(reportSpec.ResourceInfo)-i) + suffix
+		}
+		message = candidate
+	}
+
+	return message + suffix
+}
+
+func getResourceDescription(ref *corev1.ObjectReference) string {
+	if ref.Namespace == "" {
+		return fmt.Sprintf("%s %s", ref.Kind, ref.Name)
+	}
+	return fmt.Sprintf("%s %s/%s", ref.Kind, ref.Namespace, ref.Name)
+}
+
+func getResourceEventReason(action appsv1alpha1.Action) string {
+	switch action {
+	case appsv1alpha1.ActionDelete:
+		return "DeletedByCleaner"
+	case appsv1alpha1.ActionTransform:
+		return "TransformedByCleaner"
+	default:
+		return "MatchedByCleaner"
+	}
+}
+
+func truncateEventMessage(message string) string {
+	if len(message) <= maxEventMessageSize {
+		return message
+	}
+	return strings.ToValidUTF8(message[:maxEventMessageSize], "")
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/tools/record"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+var _ = Describe("Event", func() {
+	It("sendNotifications records an Event on the Cleaner instance", func() {
+		recorder := record.NewFakeRecorder(10)
+		DeferCleanup(executor.SetEventRecorder(recorder))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeEvent, nil)
+		resources := []executor.ResourceResult{
+			getResourceResult("ConfigMap", "foo", "bar"),
+			getResourceResult("Secret", "foo", "baz"),
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(recorder.Events).To(HaveLen(1))
+		Expect(<-recorder.Events).To(Equal(
+			"Normal CleanerReport Action Delete on 2 resource(s): ConfigMap foo/bar, Secret foo/baz"))
+	})
+
+	It("sendNotifications records an Event on each resource when requested", func() {
+		recorder := record.NewFakeRecorder(10)
+		DeferCleanup(executor.SetEventRecorder(recorder))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeEvent, nil)
+		cleaner.Spec.Notifications[0].Event = &appsv1alpha1.EventOptions{RecordOnResources: true}
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(recorder.Events).To(HaveLen(2))
+		Expect(<-recorder.Events).To(HavePrefix("Normal CleanerReport"))
+		Expect(<-recorder.Events).To(Equal(fmt.Sprintf("Normal DeletedByCleaner Deleted by Cleaner %s: %s",
+			cleaner.Name, resource.Message)))
+	})
+
+	It("sendNotifications limits the number of Events recorded on resources", func() {
+		recorder := record.NewFakeRecorder(2 * executor.MaxResourceEvents)
+		DeferCleanup(executor.SetEventRecorder(recorder))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeEvent, nil)
+		cleaner.Spec.Notifications[0].Event = &appsv1alpha1.EventOptions{RecordOnResources: true}
+
+		resources := make([]executor.ResourceResult, executor.MaxResourceEvents+10)
+		for i := range resources {
+			resources[i] = getResourceResult("ConfigMap", randomString(), randomString())
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		// One Event on the Cleaner and one per resource up to the limit
+		Expect(recorder.Events).To(HaveLen(executor.MaxResourceEvents + 1))
+
+		cleanerEvent := <-recorder.Events
+		Expect(len(cleanerEvent)).To(BeNumerically("<=", executor.MaxEventMessageSize+len("Normal CleanerReport ")))
+		Expect(cleanerEvent).To(MatchRegexp(`…\(truncated, \d+ resources omitted\)$`))
+	})
+
+	It("sendNotifications returns an error when event recorder is not set", func() {
+		DeferCleanup(executor.SetEventRecorder(nil))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeEvent, nil)
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).ToNot(Succeed())
+	})
+})
@@ -37,6 +37,8 @@ import (
 	"sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
 )
 
 var (
@@ -83,7 +85,7 @@ var _ = BeforeSuite(func() {
 	logger, err := zap.NewDevelopment()
 	Expect(err).To(BeNil())
 
-	executor.InitializeClient(context.TODO(), zapr.NewLogger(logger), config, k8sClient, scheme, 10)
+	executor.InitializeClient(context.TODO(), zapr.NewLogger(logger), config, k8sClient, scheme, nil, 10)
 
 	By("bootstrapping completed")
 })
@@ -99,6 +101,9 @@ func setupScheme() (*runtime.Scheme, error) {
 	if err := clientgoscheme.AddToScheme(s); err != nil {
 		return nil, err
 	}
+	if err := appsv1alpha1.AddToScheme(s); err != nil {
+		return nil, err
+	}
 
 	return s, nil
 }
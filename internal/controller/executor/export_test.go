@@ -16,6 +16,23 @@ limitations under the License.
 
 package executor
 
+import (
+	"context"
+	"crypto/tls"
+	"html/template"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
 var (
 	FetchResources          = fetchResources
 	GetMatchingResources    = getMatchingResources
@@ -24,18 +41,29 @@ var (
 	Transform               = transform
 	AggregatedSelection     = aggregatedSelection
 	GetNamespaces           = getNamespaces
+	ProcessCleanerInstance  = processCleanerInstance
 )
 
 var (
-	GetWebexInfo = getWebexInfo
-	GetSlackInfo = getSlackInfo
+	GetWebexInfo     = getWebexInfo
+	GetWebexNextPage = getWebexNextPage
+	GetSlackInfo     = getSlackInfo
+
+	GetNotificationRefs    = getNotificationRefs
+	IsSlackAuthError       = isSlackAuthError
+	IsInNotificationWindow = isInNotificationWindow
+	GetAttachmentName      = getAttachmentName
+	GetSecret              = getSecret
+	SendDueDigests         = sendDueDigests
+
+	GetNotificationTLSConfig = getNotificationTLSConfig
 )
 
 func (m *Manager) ClearInternalStruct() {
 	m.dirty = make([]string, 0)
 	m.inProgress = make([]string, 0)
 	m.jobQueue = make([]string, 0)
-	m.results = make(map[string]error)
+	m.results = make(map[string]responseParams)
 }
 
 func (m *Manager) SetInProgress(inProgress []string) {
@@ -63,10 +91,19 @@ func (m *Manager) GetJobQueue() []string {
 }
 
 func (m *Manager) SetResults(results map[string]error) {
-	m.results = results
+	m.results = make(map[string]responseParams)
+	for cleanerName, err := range results {
+		m.results[cleanerName] = responseParams{cleanerName: cleanerName, err: err}
+	}
 }
 
-func (m *Manager) GetResults() map[string]error {
+func (m *Manager) SetResultWithRunID(cleanerName, runID string, err error) {
+	m.results = map[string]responseParams{
+		cleanerName: {cleanerName: cleanerName, runID: runID, err: err},
+	}
+}
+
+func (m *Manager) GetResults() map[string]responseParams {
 	return m.results
 }
 
@@ -83,3 +120,324 @@ func GetSlackChannelID(info *slackInfo) string {
 func GetSlackToken(info *slackInfo) string {
 	return info.token
 }
+
+type (
+	SlackClient    = slackClient
+	TeamsClient    = teamsClient
+	DiscordClient  = discordClient
+	WebexClient    = webexClient
+	Mailer         = mailer
+	MailAttachment = mailAttachment
+)
+
+var (
+	SendNotifications    = sendNotifications
+	SendRunNotifications = sendRunNotifications
+	RenderHTMLReport     = renderHTMLReport
+
+	TruncateReport      = truncateReport
+	GetReportEncoding   = getReportEncoding
+	GetTruncationMarker = getTruncationMarker
+	GetSplunkEventData  = getSplunkEventData
+
+	TranslateDiscordError = translateDiscordError
+	SplitContent          = splitContent
+	SplitMessage          = splitMessage
+	GetCodeBlockSummary   = getCodeBlockSummary
+
+	GetResourceDiff = getResourceDiff
+	GetDiffMarkdown = getDiffMarkdown
+
+	GetFailedResourcesMarkdown = getFailedResourcesMarkdown
+
+	GetReportSizeLimit = getReportSizeLimit
+	GetReportHistory   = getReportHistory
+
+	GetVictorOpsMessageType = getVictorOpsMessageType
+
+	AWSURIEncode     = awsURIEncode
+	RecordMatchCount = recordMatchCount
+	GetSMSBody       = getSMSBody
+
+	FlushNotificationBatches = flushNotificationBatches
+)
+
+const (
+	SplunkMaxEventSize  = splunkMaxEventSize
+	MaxResourceDiffSize = maxResourceDiffSize
+
+	NotificationFailureThreshold = notificationFailureThreshold
+
+	CompactJSON  = compactJSON
+	IndentedJSON = indentedJSON
+)
+
+// GetReportSizeLimits returns, per notification type, the maximum size of the report
+func GetReportSizeLimits() map[appsv1alpha1.NotificationType]int {
+	limits := make(map[appsv1alpha1.NotificationType]int, len(reportSizeLimits))
+	for k, v := range reportSizeLimits {
+		limits[k] = v
+	}
+	return limits
+}
+
+func GetAttachmentFileName(a MailAttachment) string {
+	return a.fileName
+}
+
+func GetAttachmentContentType(a MailAttachment) string {
+	return a.contentType
+}
+
+func GetAttachmentData(a MailAttachment) []byte {
+	return a.data
+}
+
+// BuildMailMessage returns the email that would be sent to recipients from sender
+func BuildMailMessage(sender, recipients, subject, message string, sendAsHtml bool,
+	attachments []MailAttachment) ([]byte, error) {
+
+	info := &smtpInfo{fromEmail: sender, recipients: recipients}
+	return buildMailMessage(info, subject, message, sendAsHtml, attachments)
+}
+
+func NewMailAttachment(fileName, contentType string, data []byte) MailAttachment {
+	return mailAttachment{fileName: fileName, contentType: contentType, data: data}
+}
+
+// Client factories used outside of tests. Captured before tests replace them.
+var (
+	NewDefaultSlackClient   = newSlackClient
+	NewDefaultTeamsClient   = newTeamsClient
+	NewDefaultDiscordClient = newDiscordClient
+	NewDefaultWebexClient   = newWebexClient
+)
+
+// NewNotificationTransport returns the transport used by notification HTTP clients
+func NewNotificationTransport() *http.Transport {
+	return newNotificationTransport()
+}
+
+// ResetNotificationTransportSettings restores the default TLS and keep-alive
+// settings of notification clients
+func ResetNotificationTransportSettings() {
+	notificationTLSMinVersion = tls.VersionTLS12
+	notificationCipherSuites = nil
+	notificationDisableKeepAlives = false
+	resetNotificationTransports()
+}
+
+// GetNotificationTransport returns the transport of notification HTTP clients
+// using tlsConfig
+func GetNotificationTransport(tlsConfig *tls.Config) *http.Transport {
+	return getNotificationTransport(tlsConfig, false)
+}
+
+// GetNotificationProxy returns the proxy used by notification HTTP clients for req
+func GetNotificationProxy(req *http.Request) (*url.URL, error) {
+	return notificationProxy(req)
+}
+
+// SetSlackClientFactory replaces the Slack client factory. Returned function restores
+// the previous one.
+func SetSlackClientFactory(f func(token string) slackClient) func() {
+	old := newSlackClient
+	newSlackClient = f
+	return func() { newSlackClient = old }
+}
+
+// SetTeamsClientFactory replaces the Teams client factory. Returned function restores
+// the previous one.
+func SetTeamsClientFactory(f func() teamsClient) func() {
+	old := newTeamsClient
+	newTeamsClient = func(*tls.Config) teamsClient { return f() }
+	return func() { newTeamsClient = old }
+}
+
+// SetDiscordClientFactory replaces the Discord client factory. Returned function restores
+// the previous one.
+func SetDiscordClientFactory(f func(token string) (discordClient, error)) func() {
+	old := newDiscordClient
+	newDiscordClient = f
+	return func() { newDiscordClient = old }
+}
+
+// SetWebexClientFactory replaces the Webex client factory. Returned function restores
+// the previous one.
+func SetWebexClientFactory(f func(token string) webexClient) func() {
+	old := newWebexClient
+	newWebexClient = f
+	return func() { newWebexClient = old }
+}
+
+// SetVictorOpsURL replaces the VictorOps REST endpoint. Returned function restores
+// the previous one.
+func SetVictorOpsURL(u string) func() {
+	old := victorOpsURL
+	victorOpsURL = u
+	return func() { victorOpsURL = old }
+}
+
+// SetTwilioURL replaces the Twilio REST API base URL. Returned function restores
+// the previous one.
+func SetTwilioURL(u string) func() {
+	old := twilioURL
+	twilioURL = u
+	return func() { twilioURL = old }
+}
+
+// SetSTSURL replaces the AWS STS endpoint. Returned function restores the
+// previous one.
+func SetSTSURL(u string) func() {
+	old := stsURL
+	stsURL = u
+	return func() { stsURL = old }
+}
+
+// SignAWSRequest signs req with AWS Signature Version 4 using the given credentials
+func SignAWSRequest(req *http.Request, payloadHash, accessKeyID, secretAccessKey, region, service string,
+	now time.Time) {
+
+	signAWSRequest(req, payloadHash, &awsCredentials{accessKeyID: accessKeyID, secretAccessKey: secretAccessKey},
+		region, service, now)
+}
+
+// SetHTMLReportTemplate replaces the template HTML reports are rendered with.
+// Returned function restores the previous one.
+func SetHTMLReportTemplate(text string) func() {
+	old := htmlReportTemplate
+	htmlReportTemplate = template.Must(template.New("report").Parse(text))
+	return func() { htmlReportTemplate = old }
+}
+
+// SetMailerFactory replaces the SMTP mailer factory. Returned function restores
+// the previous one.
+func SetMailerFactory(f func(ctx context.Context, notification *appsv1alpha1.Notification) (mailer, error)) func() {
+	old := newMailer
+	newMailer = f
+	return func() { newMailer = old }
+}
+
+// SetTracer replaces the tracer used for notification spans. Returned function
+// restores the previous one.
+func SetTracer(t trace.Tracer) func() {
+	old := tracer
+	tracer = t
+	return func() { tracer = old }
+}
+
+// SetEventRecorder replaces the recorder used for Event notifications. Returned
+// function restores the previous one.
+func SetEventRecorder(r record.EventRecorder) func() {
+	old := eventRecorder
+	eventRecorder = r
+	return func() { eventRecorder = old }
+}
+
+type (
+	Notifier     = notifier
+	NotifierFunc = notifierFunc
+)
+
+var GetNotifier = getNotifier
+
+// SetNotifier replaces the notifier registered for notificationType. Returned
+// function restores the previous one.
+func SetNotifier(notificationType appsv1alpha1.NotificationType, n notifier) func() {
+	old, ok := notifiers[notificationType]
+	notifiers[notificationType] = n
+	return func() {
+		if ok {
+			notifiers[notificationType] = old
+		} else {
+			delete(notifiers, notificationType)
+		}
+	}
+}
+
+// SetNotificationRetryBackoff replaces the backoff used to send again notifications
+// whose OnFailure policy is Retry. Returned function restores the previous one.
+func SetNotificationRetryBackoff(b wait.Backoff) func() {
+	old := notificationRetryBackoff
+	notificationRetryBackoff = b
+	return func() { notificationRetryBackoff = old }
+}
+
+// SetReportKindBackoff replaces the backoff used while the Report CRD is not
+// established. Returned function restores the previous one.
+func SetReportKindBackoff(b wait.Backoff) func() {
+	old := reportKindBackoff
+	reportKindBackoff = b
+	return func() { reportKindBackoff = old }
+}
+
+// SetK8sClient replaces the client used to act on resources. Returned function
+// restores the previous one.
+func SetK8sClient(c client.Client) func() {
+	k8sClientMux.RLock()
+	old := k8sClient
+	k8sClientMux.RUnlock()
+	InjectClient(c)
+	return func() { InjectClient(old) }
+}
+
+const (
+	MaxEventMessageSize = maxEventMessageSize
+	MaxResourceEvents   = maxResourceEvents
+)
+
+// ResetNotificationHealth forgets the health of all notification types
+func ResetNotificationHealth() {
+	healthMux.Lock()
+	defer healthMux.Unlock()
+	notificationHealth = map[appsv1alpha1.NotificationType]*notificationTypeHealth{}
+}
+
+// GetNotificationMetric returns the value of the gauge name for notificationType.
+// False is returned if the gauge is not set.
+func GetNotificationMetric(name string, notificationType appsv1alpha1.NotificationType) (float64, bool) {
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		return 0, false
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == labelNotificationType && label.GetValue() == string(notificationType) {
+					return metric.GetGauge().GetValue(), true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// GetReportMetric returns the number of observations of the histogram name, or
+// the value of the counter name, for the Cleaner cleanerName. False is returned
+// if the metric is not set.
+func GetReportMetric(name, cleanerName string) (float64, bool) {
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		return 0, false
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() != labelCleanerName || label.GetValue() != cleanerName {
+					continue
+				}
+				if histogram := metric.GetHistogram(); histogram != nil {
+					return float64(histogram.GetSampleCount()), true
+				}
+				return metric.GetCounter().GetValue(), true
+			}
+		}
+	}
+	return 0, false
+}
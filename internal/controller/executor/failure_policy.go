@@ -0,0 +1,87 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// notificationRetryBackoff is used to send again notifications whose OnFailure
+// policy is Retry
+var notificationRetryBackoff = wait.Backoff{
+	Duration: 2 * time.Second,
+	Factor:   2,
+	Steps:    3,
+}
+
+// isIgnoreFailureNotification returns true if delivery errors of notification
+// must not be returned
+func isIgnoreFailureNotification(notification *appsv1alpha1.Notification) bool {
+	return notification.OnFailure == appsv1alpha1.NotificationFailurePolicyIgnore
+}
+
+// sendWithFailurePolicy calls send once or, when notification OnFailure policy
+// is Retry, till it succeeds or notificationRetryBackoff is exhausted. Attempts
+// stop when ctx is done. Every attempt is passed the same context, tracking the
+// delivery progress across attempts.
+// send must only deliver the notification: the delivery result is recorded
+// once, by the caller, whatever the number of attempts.
+func sendWithFailurePolicy(ctx context.Context, notification *appsv1alpha1.Notification,
+	send func(ctx context.Context) error, logger logr.Logger) error {
+
+	if notification.OnFailure != appsv1alpha1.NotificationFailurePolicyRetry {
+		return send(ctx)
+	}
+
+	ctx = context.WithValue(ctx, deliveryProgressKey{}, &deliveryProgress{})
+	attempt := 0
+	return retry.OnError(notificationRetryBackoff, func(err error) bool {
+		logger.V(logs.LogInfo).Info(logMsgRetryNotification, "attempt", attempt, "error", err.Error())
+		return ctx.Err() == nil
+	}, func() error {
+		attempt++
+		return send(ctx)
+	})
+}
+
+// deliveryProgressKey is the context key of the deliveryProgress of a delivery
+type deliveryProgressKey struct{}
+
+// deliveryProgress counts the messages of a delivery already sent, so that a
+// delivery sent as multiple messages resumes, when attempted again, from the
+// first message not sent yet
+type deliveryProgress struct {
+	sent int
+}
+
+// getDeliveryProgress returns the progress of the delivery ctx was passed to.
+// Deliveries which are never attempted again start from scratch.
+func getDeliveryProgress(ctx context.Context) *deliveryProgress {
+	if progress, ok := ctx.Value(deliveryProgressKey{}).(*deliveryProgress); ok {
+		return progress
+	}
+	return &deliveryProgress{}
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+// getFailingCleaner returns a Cleaner with a notification, using policy, whose
+// first failures deliveries fail. Returned function returns the number of
+// delivery attempts.
+func getFailingCleaner(policy appsv1alpha1.NotificationFailurePolicy, failures int) (*appsv1alpha1.Cleaner, func() int) {
+	DeferCleanup(executor.SetNotificationRetryBackoff(wait.Backoff{Duration: time.Millisecond, Steps: 3}))
+
+	notificationType := appsv1alpha1.NotificationType(randomString())
+	attempts := 0
+	DeferCleanup(executor.SetNotifier(notificationType, executor.NotifierFunc(
+		func(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+			resources []executor.ResourceResult, message string, notification *appsv1alpha1.Notification,
+			logger logr.Logger) error {
+
+			attempts++
+			if attempts <= failures {
+				return errors.New("service unavailable")
+			}
+			return nil
+		})))
+
+	cleaner := getCleanerWithNotification(notificationType, nil)
+	cleaner.Spec.Notifications[0].OnFailure = policy
+	return cleaner, func() int { return attempts }
+}
+
+var _ = Describe("Notification failure policy", func() {
+	It("sendNotifications returns delivery errors with Fail policy", func() {
+		for _, policy := range []appsv1alpha1.NotificationFailurePolicy{"", appsv1alpha1.NotificationFailurePolicyFail} {
+			cleaner, attempts := getFailingCleaner(policy, 1)
+
+			err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("service unavailable"))
+			Expect(attempts()).To(Equal(1))
+		}
+	})
+
+	It("sendNotifications sends notifications again with Retry policy", func() {
+		cleaner, attempts := getFailingCleaner(appsv1alpha1.NotificationFailurePolicyRetry, 2)
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+		Expect(attempts()).To(Equal(3))
+	})
+
+	It("sendNotifications returns delivery errors once retries are exhausted with Retry policy", func() {
+		cleaner, attempts := getFailingCleaner(appsv1alpha1.NotificationFailurePolicyRetry, 10)
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("service unavailable"))
+		Expect(attempts()).To(Equal(3))
+	})
+
+	It("sendNotifications counts a single failure per run with Retry policy", func() {
+		cleaner, attempts := getFailingCleaner(appsv1alpha1.NotificationFailurePolicyRetry, 100)
+		cleaner.Spec.Schedule = "0 * * * *"
+		cleaner.Spec.ResourcePolicySet.ResourceSelectors = []appsv1alpha1.ResourceSelector{
+			{Kind: "ConfigMap", Version: "v1"},
+		}
+		Expect(k8sClient.Create(context.TODO(), cleaner)).To(Succeed())
+		Expect(waitForObject(context.TODO(), k8sClient, cleaner)).To(Succeed())
+
+		current := &appsv1alpha1.Cleaner{}
+		for i := 1; i <= 2; i++ {
+			Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, current)).To(Succeed())
+			Expect(executor.SendNotifications(context.TODO(), nil, current, "", logr.Discard())).ToNot(Succeed())
+			Expect(attempts()).To(Equal(3 * i))
+
+			Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, current)).To(Succeed())
+			Expect(current.Status.NotificationStatuses).To(HaveLen(1))
+			Expect(current.Status.NotificationStatuses[0].ConsecutiveFailures).To(Equal(int32(i)))
+		}
+	})
+
+	It("sendNotifications records but does not return delivery errors with Ignore policy", func() {
+		cleaner, attempts := getFailingCleaner(appsv1alpha1.NotificationFailurePolicyIgnore, 1)
+		cleaner.Spec.Schedule = "0 * * * *"
+		cleaner.Spec.ResourcePolicySet.ResourceSelectors = []appsv1alpha1.ResourceSelector{
+			{Kind: "ConfigMap", Version: "v1"},
+		}
+		Expect(k8sClient.Create(context.TODO(), cleaner)).To(Succeed())
+		Expect(waitForObject(context.TODO(), k8sClient, cleaner)).To(Succeed())
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+		Expect(attempts()).To(Equal(1))
+
+		current := &appsv1alpha1.Cleaner{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, current)).To(Succeed())
+		Expect(current.Status.NotificationStatuses).To(HaveLen(1))
+		Expect(current.Status.NotificationStatuses[0].ConsecutiveFailures).To(Equal(int32(1)))
+	})
+
+	It("sendTestNotification returns delivery errors with Ignore policy", func() {
+		cleaner, _ := getFailingCleaner(appsv1alpha1.NotificationFailurePolicyIgnore, 1)
+
+		Expect(executor.SendTestNotification(context.TODO(), cleaner, cleaner.Spec.Notifications[0].Name,
+			logr.Discard())).ToNot(Succeed())
+	})
+})
@@ -0,0 +1,182 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+var _ = Describe("Failure notifications", func() {
+	var fake *fakeSlackClient
+
+	BeforeEach(func() {
+		fake = &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+	})
+
+	getSlackCleaner := func() *appsv1alpha1.Cleaner {
+		return getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		}))
+	}
+
+	It("sendRunNotifications sends error and partial resources when NotifyOnFailure is set", func() {
+		cleaner := getSlackCleaner()
+		cleaner.Spec.Notifications[0].NotifyOnFailure = true
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		runID := randomString()
+		runErr := fmt.Errorf("configmaps %q is forbidden", randomString())
+
+		Expect(executor.SendRunNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, runID, runErr, logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		text := fake.values[0].Get("text")
+		Expect(text).To(HavePrefix("Execution failed for k8s-cleaner instance: " + cleaner.Name))
+		Expect(text).To(ContainSubstring(runID))
+		Expect(text).To(ContainSubstring("1 resource(s) processed before the failure"))
+		Expect(text).To(ContainSubstring(runErr.Error()))
+		Expect(fake.values[0].Get("attachments")).To(ContainSubstring(resource.Resource.GetName()))
+	})
+
+	It("sendRunNotifications sets the error only in the report of notifications with NotifyOnFailure", func() {
+		cleaner := getSlackCleaner()
+		cleaner.Spec.Notifications[0].NotifyOnFailure = true
+		other := cleaner.Spec.Notifications[0]
+		other.Name = randomString()
+		other.NotifyOnFailure = false
+		cleaner.Spec.Notifications = append(cleaner.Spec.Notifications, other)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		runErr := fmt.Errorf("configmaps %q is forbidden", randomString())
+
+		Expect(executor.SendRunNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", runErr, logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(2))
+		Expect(fake.values[0].Get("attachments")).To(ContainSubstring("forbidden"))
+		Expect(fake.values[1].Get("attachments")).To(ContainSubstring(resource.Resource.GetName()))
+		Expect(fake.values[1].Get("attachments")).ToNot(ContainSubstring("forbidden"))
+	})
+
+	It("sendRunNotifications sends a plain report of processed resources when NotifyOnFailure is not set", func() {
+		cleaner := getSlackCleaner()
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendRunNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", fmt.Errorf("timeout"), logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(HavePrefix("k8s-cleaner '" + cleaner.Name + "' performed Delete on 1 resource"))
+		Expect(fake.values[0].Get("text")).ToNot(ContainSubstring("timeout"))
+	})
+
+	It("sendRunNotifications skips notifications without NotifyOnFailure when nothing was processed", func() {
+		cleaner := getSlackCleaner()
+
+		Expect(executor.SendRunNotifications(context.TODO(), nil,
+			cleaner, "", fmt.Errorf("timeout"), logr.Discard())).To(Succeed())
+		Expect(fake.values).To(BeEmpty())
+
+		cleaner.Spec.Notifications[0].NotifyOnFailure = true
+		Expect(executor.SendRunNotifications(context.TODO(), nil,
+			cleaner, "", fmt.Errorf("timeout"), logr.Discard())).To(Succeed())
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(ContainSubstring("0 resource(s) processed before the failure"))
+	})
+
+	It("sendRunNotifications sends failures immediately for digest notifications", func() {
+		cleaner := getSlackCleaner()
+		cleaner.Spec.Schedule = "0 * * * *"
+		cleaner.Spec.ResourcePolicySet.ResourceSelectors = []appsv1alpha1.ResourceSelector{
+			{Kind: "ConfigMap", Version: "v1"},
+		}
+		cleaner.Spec.Notifications[0].NotifyOnFailure = true
+		cleaner.Spec.Notifications[0].Digest = &appsv1alpha1.DigestOptions{
+			Interval: metav1.Duration{Duration: time.Hour},
+		}
+		Expect(k8sClient.Create(context.TODO(), cleaner)).To(Succeed())
+		Expect(waitForObject(context.TODO(), k8sClient, cleaner)).To(Succeed())
+
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		Expect(executor.SendRunNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", fmt.Errorf("timeout"), logr.Discard())).To(Succeed())
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(HavePrefix("Execution failed"))
+
+		currentCleaner := &appsv1alpha1.Cleaner{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, currentCleaner)).To(Succeed())
+		Expect(currentCleaner.Status.NotificationDigests).To(BeEmpty())
+	})
+
+	It("sendRunNotifications records a Warning Event on the Cleaner", func() {
+		recorder := record.NewFakeRecorder(10)
+		DeferCleanup(executor.SetEventRecorder(recorder))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeEvent, nil)
+		cleaner.Spec.Notifications[0].NotifyOnFailure = true
+
+		Expect(executor.SendRunNotifications(context.TODO(), nil,
+			cleaner, "", fmt.Errorf("deployments.apps is forbidden"), logr.Discard())).To(Succeed())
+
+		Expect(recorder.Events).To(HaveLen(1))
+		Expect(<-recorder.Events).To(Equal(
+			"Warning CleanerFailed Action Delete failed after 0 resource(s). Error: deployments.apps is forbidden"))
+	})
+
+	It("sendRunNotifications sends failure CloudEvent with the error", func() {
+		var payload map[string]interface{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			Expect(err).To(BeNil())
+			Expect(json.Unmarshal(body, &payload)).To(Succeed())
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		DeferCleanup(server.Close)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCloudEvents, createNotificationSecret(map[string][]byte{
+			appsv1alpha1.CloudEventsSinkURL: []byte(server.URL),
+		}))
+		cleaner.Spec.Notifications[0].NotifyOnFailure = true
+
+		Expect(executor.SendRunNotifications(context.TODO(), nil,
+			cleaner, "", fmt.Errorf("timeout"), logr.Discard())).To(Succeed())
+
+		Expect(payload["type"]).To(Equal("io.k8scleaner.failure"))
+		Expect(payload["data"]).To(HaveKeyWithValue("error", "timeout"))
+	})
+})
@@ -0,0 +1,99 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+// selectReportFields returns a copy of reportSpec where resources only contain
+// the Fields of notification. The summary only counts resources per kind and
+// per namespace when those fields are selected. reportSpec is returned as is
+// when Fields is not set, or for CleanerReport notifications.
+func selectReportFields(reportSpec *appsv1alpha1.ReportSpec,
+	notification *appsv1alpha1.Notification) *appsv1alpha1.ReportSpec {
+
+	if len(notification.Fields) == 0 || notification.Type == appsv1alpha1.NotificationTypeCleanerReport {
+		return reportSpec
+	}
+
+	selected := make(map[appsv1alpha1.ReportField]bool, len(notification.Fields))
+	for _, field := range notification.Fields {
+		selected[field] = true
+	}
+
+	// Only resources are changed, so a shallow copy of the other fields is enough
+	selectedSpec := *reportSpec
+	selectedSpec.ResourceInfo = make([]appsv1alpha1.ResourceInfo, len(reportSpec.ResourceInfo))
+	for i := range reportSpec.ResourceInfo {
+		selectedSpec.ResourceInfo[i] = selectResourceInfoFields(&reportSpec.ResourceInfo[i], selected)
+	}
+	// Counts per kind and per namespace would reveal the omitted fields
+	if reportSpec.Summary != nil {
+		summary := *reportSpec.Summary
+		if !selected[appsv1alpha1.ReportFieldKind] {
+			summary.ByKind = nil
+		}
+		if !selected[appsv1alpha1.ReportFieldNamespace] {
+			summary.ByNamespace = nil
+		}
+		selectedSpec.Summary = &summary
+	}
+	return &selectedSpec
+}
+
+// selectResourceInfoFields returns the selected fields of info. The full
+// resource is never included.
+func selectResourceInfoFields(info *appsv1alpha1.ResourceInfo,
+	selected map[appsv1alpha1.ReportField]bool) appsv1alpha1.ResourceInfo {
+
+	var result appsv1alpha1.ResourceInfo
+	if selected[appsv1alpha1.ReportFieldAPIVersion] {
+		result.Resource.APIVersion = info.Resource.APIVersion
+	}
+	if selected[appsv1alpha1.ReportFieldKind] {
+		result.Resource.Kind = info.Resource.Kind
+	}
+	if selected[appsv1alpha1.ReportFieldNamespace] {
+		result.Resource.Namespace = info.Resource.Namespace
+	}
+	if selected[appsv1alpha1.ReportFieldName] {
+		result.Resource.Name = info.Resource.Name
+	}
+	if selected[appsv1alpha1.ReportFieldMessage] {
+		result.Message = info.Message
+	}
+	if selected[appsv1alpha1.ReportFieldLabels] {
+		result.Labels = info.Labels
+	}
+	if selected[appsv1alpha1.ReportFieldAnnotations] {
+		result.Annotations = info.Annotations
+	}
+	if selected[appsv1alpha1.ReportFieldDiff] {
+		result.Diff = info.Diff
+	}
+	if selected[appsv1alpha1.ReportFieldOutcome] {
+		result.Outcome = info.Outcome
+	}
+	if selected[appsv1alpha1.ReportFieldError] {
+		result.Error = info.Error
+	}
+	if selected[appsv1alpha1.ReportFieldBackup] {
+		result.Backup = info.Backup
+	}
+	return result
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+var _ = Describe("Report fields", func() {
+	// sendWithFields sends resource to a File notification with fields, and
+	// returns the written report
+	sendWithFields := func(resource executor.ResourceResult, fields []appsv1alpha1.ReportField) []byte {
+		dir := GinkgoT().TempDir()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeFile, nil)
+		cleaner.Spec.Notifications = []appsv1alpha1.Notification{
+			{
+				Name:   randomString(),
+				Type:   appsv1alpha1.NotificationTypeFile,
+				File:   &appsv1alpha1.FileOptions{Path: dir},
+				Fields: fields,
+			},
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		files := listFiles(dir)
+		Expect(files).To(HaveLen(1))
+		data, err := os.ReadFile(filepath.Join(dir, files[0]))
+		Expect(err).To(BeNil())
+		return data
+	}
+
+	It("sendNotifications only includes the selected fields of each resource", func() {
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		resource.Resource.SetLabels(map[string]string{"team": "payments"})
+
+		data := sendWithFields(resource, []appsv1alpha1.ReportField{
+			appsv1alpha1.ReportFieldNamespace, appsv1alpha1.ReportFieldKind, appsv1alpha1.ReportFieldName,
+		})
+		Expect(string(data)).ToNot(ContainSubstring("apiVersion"))
+		Expect(string(data)).ToNot(ContainSubstring(resource.Message))
+
+		reportSpec := &appsv1alpha1.ReportSpec{}
+		Expect(json.Unmarshal(data, reportSpec)).To(Succeed())
+		Expect(reportSpec.ResourceInfo).To(HaveLen(1))
+		info := reportSpec.ResourceInfo[0]
+		Expect(info.Resource.Kind).To(Equal("ConfigMap"))
+		Expect(info.Resource.Namespace).To(Equal(resource.Resource.GetNamespace()))
+		Expect(info.Resource.Name).To(Equal(resource.Resource.GetName()))
+		Expect(info.Resource.APIVersion).To(BeEmpty())
+		Expect(info.Message).To(BeEmpty())
+		Expect(info.Outcome).To(BeEmpty())
+		// Report level fields are kept
+		Expect(reportSpec.Action).To(Equal(appsv1alpha1.ActionDelete))
+		Expect(reportSpec.Summary.Total).To(Equal(int32(1)))
+		Expect(reportSpec.Summary.ByKind).To(HaveKeyWithValue("ConfigMap", int32(1)))
+	})
+
+	It("sendNotifications includes every field by default", func() {
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		reportSpec := &appsv1alpha1.ReportSpec{}
+		Expect(json.Unmarshal(sendWithFields(resource, nil), reportSpec)).To(Succeed())
+		Expect(reportSpec.ResourceInfo).To(HaveLen(1))
+		Expect(reportSpec.ResourceInfo[0].Resource.APIVersion).To(Equal("v1"))
+		Expect(reportSpec.ResourceInfo[0].Message).To(ContainSubstring(resource.Message))
+	})
+
+	It("sendNotifications omits unselected fields from Slack attachments", func() {
+		ref, fake := createSlackSecret()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications[0].Fields = []appsv1alpha1.ReportField{appsv1alpha1.ReportFieldKind}
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		attachments := fake.values[0].Get("attachments")
+		Expect(attachments).To(ContainSubstring("ConfigMap"))
+		Expect(attachments).ToNot(ContainSubstring(resource.Resource.GetName()))
+		Expect(attachments).ToNot(ContainSubstring(resource.Resource.GetNamespace()))
+	})
+})
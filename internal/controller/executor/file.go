@@ -0,0 +1,215 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+func init() {
+	registerNotifier(appsv1alpha1.NotificationTypeFile, notifierFunc(
+		func(_ context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+			_ []ResourceResult, _ string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+			return sendFileNotification(cleaner, reportSpec, notification, logger)
+		}))
+}
+
+// sendFileNotification writes the report to a timestamped file in the configured
+// directory, then applies retention removing the oldest reports of this Cleaner
+// instance.
+func sendFileNotification(cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+	if notification.File == nil || notification.File.Path == "" {
+		return fmt.Errorf("file notification requires a path")
+	}
+
+	l := logger.WithValues(logKeyPath, notification.File.Path)
+	l.V(logs.LogInfo).Info("write report to file")
+
+	format := notification.File.Format
+	if format == "" {
+		format = appsv1alpha1.ReportFormatJSON
+	}
+
+	data, err := renderFileReport(reportSpec, format)
+	if err != nil {
+		l.Error(err, "failed to render report")
+		return err
+	}
+
+	if err := os.MkdirAll(notification.File.Path, permission0755); err != nil {
+		l.Error(err, "failed to create directory")
+		return err
+	}
+
+	now := time.Now()
+	extension := getReportFileExtension(format)
+	name, err := getAttachmentName(cleaner.Name, reportSpec, extension, notification, now)
+	if err != nil {
+		l.Error(err, logMsgSendFailed)
+		return err
+	}
+	if name == "" {
+		name = getReportFileName(cleaner.Name, reportSpec.RunID, now, extension)
+	}
+	fileName := filepath.Join(notification.File.Path, name)
+	if err := writeFileAtomically(fileName, data); err != nil {
+		l.Error(err, logMsgSendFailed)
+		return err
+	}
+	l.V(logs.LogDebug).Info("report written", "file", fileName)
+
+	return removeExpiredReports(notification.File, cleaner.Name, extension, time.Now(), l)
+}
+
+func renderFileReport(reportSpec *appsv1alpha1.ReportSpec, format appsv1alpha1.ReportFormat) ([]byte, error) {
+	switch format {
+	case appsv1alpha1.ReportFormatCSV:
+		return renderCSVReport(reportSpec)
+	case appsv1alpha1.ReportFormatJSON:
+		return marshalReport(reportSpec, getReportEncoding(appsv1alpha1.NotificationTypeFile))
+	case appsv1alpha1.ReportFormatYAML:
+		return renderYAMLReport(reportSpec)
+	default:
+		return nil, fmt.Errorf("unsupported report format %s", format)
+	}
+}
+
+func getReportFileExtension(format appsv1alpha1.ReportFormat) string {
+	return strings.ToLower(string(format))
+}
+
+// writeFileAtomically writes data to a temporary file in the same directory and
+// then renames it, so a partially written report is never visible
+func writeFileAtomically(fileName string, data []byte) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(fileName), ".k8s-cleaner-report-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpFile.Name(), permission0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), fileName)
+}
+
+// removeExpiredReports removes reports of the Cleaner instance in excess of
+// MaxFiles or older than MaxAge
+func removeExpiredReports(options *appsv1alpha1.FileOptions, cleanerName, extension string,
+	now time.Time, logger logr.Logger) error {
+
+	if options.MaxFiles == nil && options.MaxAge == nil {
+		return nil
+	}
+
+	reports, err := listReports(options.Path, cleanerName, extension)
+	if err != nil {
+		logger.Error(err, "failed to list reports")
+		return err
+	}
+
+	// reports are sorted from oldest to newest
+	toRemove := 0
+	if options.MaxFiles != nil && len(reports) > int(*options.MaxFiles) {
+		toRemove = len(reports) - int(*options.MaxFiles)
+	}
+	if options.MaxAge != nil {
+		for toRemove < len(reports) && now.Sub(reports[toRemove].generatedAt) > options.MaxAge.Duration {
+			toRemove++
+		}
+	}
+
+	for i := 0; i < toRemove; i++ {
+		logger.V(logs.LogDebug).Info("removing report", "file", reports[i].path)
+		if err := os.Remove(reports[i].path); err != nil && !os.IsNotExist(err) {
+			logger.Error(err, "failed to remove report", "file", reports[i].path)
+			return err
+		}
+	}
+
+	return nil
+}
+
+type reportFile struct {
+	path        string
+	generatedAt time.Time
+}
+
+// listReports returns the reports of the Cleaner instance, with the given extension,
+// present in dir sorted from oldest to newest
+func listReports(dir, cleanerName, extension string) ([]reportFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reports of a run with an ID have the run ID after the timestamp
+	re := regexp.MustCompile(fmt.Sprintf(`^%s-(\d{8}-\d{6})(-[^.]+)?\.%s$`,
+		regexp.QuoteMeta(cleanerName), regexp.QuoteMeta(extension)))
+
+	reports := make([]reportFile, 0)
+	for i := range entries {
+		if entries[i].IsDir() {
+			continue
+		}
+		match := re.FindStringSubmatch(entries[i].Name())
+		if match == nil {
+			continue
+		}
+		generatedAt, err := time.Parse(reportTimestampFormat, match[1])
+		if err != nil {
+			continue
+		}
+		reports = append(reports, reportFile{
+			path:        filepath.Join(dir, entries[i].Name()),
+			generatedAt: generatedAt,
+		})
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].generatedAt.Before(reports[j].generatedAt)
+	})
+	return reports, nil
+}
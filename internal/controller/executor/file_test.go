@@ -0,0 +1,218 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+var _ = Describe("File", func() {
+	It("sendNotifications writes JSON report creating the directory", func() {
+		dir := filepath.Join(GinkgoT().TempDir(), "reports", "cleaner")
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeFile, nil)
+		cleaner.Spec.Notifications[0].File = &appsv1alpha1.FileOptions{Path: dir}
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		runID := randomString()
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, runID, logr.Discard())).To(Succeed())
+
+		files := listFiles(dir)
+		Expect(files).To(HaveLen(1))
+		Expect(files[0]).To(MatchRegexp(fmt.Sprintf(`^%s-\d{8}-\d{6}-%s\.json$`, cleaner.Name, runID)))
+
+		data, err := os.ReadFile(filepath.Join(dir, files[0]))
+		Expect(err).To(BeNil())
+		reportSpec := &appsv1alpha1.ReportSpec{}
+		Expect(json.Unmarshal(data, reportSpec)).To(Succeed())
+		Expect(reportSpec.Action).To(Equal(appsv1alpha1.ActionDelete))
+		Expect(reportSpec.RunID).To(Equal(runID))
+		Expect(reportSpec.ResourceInfo).To(HaveLen(1))
+		Expect(reportSpec.ResourceInfo[0].Resource.Name).To(Equal(resource.Resource.GetName()))
+	})
+
+	It("sendNotifications writes CSV report", func() {
+		dir := GinkgoT().TempDir()
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeFile, nil)
+		cleaner.Spec.Notifications[0].File = &appsv1alpha1.FileOptions{
+			Path:   dir,
+			Format: appsv1alpha1.ReportFormatCSV,
+		}
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		runID := randomString()
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, runID, logr.Discard())).To(Succeed())
+
+		files := listFiles(dir)
+		Expect(files).To(HaveLen(1))
+		Expect(files[0]).To(HaveSuffix(".csv"))
+
+		f, err := os.Open(filepath.Join(dir, files[0]))
+		Expect(err).To(BeNil())
+		defer f.Close()
+		records, err := csv.NewReader(f).ReadAll()
+		Expect(err).To(BeNil())
+		Expect(records).To(HaveLen(2))
+		Expect(records[0]).To(Equal([]string{"runID", "action", "kind", "namespace", "name", "apiVersion", "message",
+			"labels", "annotations", "diff", "outcome", "error", "backup"}))
+		Expect(records[1][:6]).To(Equal([]string{runID, "Delete", "ConfigMap", resource.Resource.GetNamespace(),
+			resource.Resource.GetName(), "v1"}))
+	})
+
+	It("sendNotifications writes YAML report which decodes back into a ReportSpec", func() {
+		dir := GinkgoT().TempDir()
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeFile, nil)
+		cleaner.Spec.Notifications[0].File = &appsv1alpha1.FileOptions{
+			Path:   dir,
+			Format: appsv1alpha1.ReportFormatYAML,
+		}
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		runID := randomString()
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, runID, logr.Discard())).To(Succeed())
+
+		files := listFiles(dir)
+		Expect(files).To(HaveLen(1))
+		Expect(files[0]).To(MatchRegexp(fmt.Sprintf(`^%s-\d{8}-\d{6}-%s\.yaml$`, cleaner.Name, runID)))
+
+		data, err := os.ReadFile(filepath.Join(dir, files[0]))
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(ContainSubstring("action: Delete"))
+		reportSpec := &appsv1alpha1.ReportSpec{}
+		Expect(yaml.UnmarshalStrict(data, reportSpec)).To(Succeed())
+		Expect(reportSpec.Action).To(Equal(appsv1alpha1.ActionDelete))
+		Expect(reportSpec.RunID).To(Equal(runID))
+		Expect(reportSpec.ResourceInfo).To(HaveLen(1))
+		Expect(reportSpec.ResourceInfo[0].Resource.Name).To(Equal(resource.Resource.GetName()))
+		Expect(reportSpec.ResourceInfo[0].Message).To(HavePrefix(resource.Message))
+
+		roundTrip, err := yaml.Marshal(reportSpec)
+		Expect(err).To(BeNil())
+		Expect(string(roundTrip)).To(Equal(string(data)))
+	})
+
+	It("sendNotifications writes a report per run for runs within the same second", func() {
+		dir := GinkgoT().TempDir()
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeFile, nil)
+		cleaner.Spec.Notifications[0].File = &appsv1alpha1.FileOptions{Path: dir}
+
+		for i := 0; i < 2; i++ {
+			Expect(executor.SendNotifications(context.TODO(), nil, cleaner, randomString(), logr.Discard())).To(Succeed())
+		}
+
+		Expect(listFiles(dir)).To(HaveLen(2))
+	})
+
+	It("sendNotifications removes oldest reports beyond MaxFiles", func() {
+		dir := GinkgoT().TempDir()
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeFile, nil)
+		cleaner.Spec.Notifications[0].File = &appsv1alpha1.FileOptions{
+			Path:     dir,
+			MaxFiles: ptr.To(int32(2)),
+		}
+
+		now := time.Now()
+		oldest := createReportFile(dir, cleaner.Name, "", now.Add(-3*time.Hour))
+		// Reports named after their run are considered too
+		older := createReportFile(dir, cleaner.Name, randomString(), now.Add(-2*time.Hour))
+		newer := createReportFile(dir, cleaner.Name, randomString(), now.Add(-time.Hour))
+		// Reports of a different Cleaner instance sharing the same prefix are not touched
+		other := createReportFile(dir, cleaner.Name+"-other", "", now.Add(-3*time.Hour))
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+
+		files := listFiles(dir)
+		Expect(files).To(HaveLen(3))
+		Expect(files).To(ContainElements(newer, other))
+		Expect(files).ToNot(ContainElements(oldest, older))
+	})
+
+	It("sendNotifications removes reports older than MaxAge", func() {
+		dir := GinkgoT().TempDir()
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeFile, nil)
+		cleaner.Spec.Notifications[0].File = &appsv1alpha1.FileOptions{
+			Path:   dir,
+			MaxAge: &metav1.Duration{Duration: 24 * time.Hour},
+		}
+
+		now := time.Now()
+		expired := createReportFile(dir, cleaner.Name, "", now.Add(-48*time.Hour))
+		recent := createReportFile(dir, cleaner.Name, "", now.Add(-time.Hour))
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+
+		files := listFiles(dir)
+		Expect(files).To(HaveLen(2))
+		Expect(files).To(ContainElement(recent))
+		Expect(files).ToNot(ContainElement(expired))
+	})
+
+	It("sendNotifications returns an error when report cannot be written", func() {
+		// Path is a regular file, so directory cannot be created
+		path := filepath.Join(GinkgoT().TempDir(), "file")
+		Expect(os.WriteFile(path, []byte("data"), 0600)).To(Succeed())
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeFile, nil)
+		cleaner.Spec.Notifications[0].File = &appsv1alpha1.FileOptions{Path: path}
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).ToNot(Succeed())
+	})
+})
+
+func listFiles(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	Expect(err).To(BeNil())
+
+	files := make([]string, len(entries))
+	for i := range entries {
+		files[i] = entries[i].Name()
+	}
+	return files
+}
+
+func createReportFile(dir, cleanerName, runID string, generatedAt time.Time) string {
+	fileName := fmt.Sprintf("%s-%s.json", cleanerName, generatedAt.UTC().Format("20060102-150405"))
+	if runID != "" {
+		fileName = fmt.Sprintf("%s-%s-%s.json", cleanerName, generatedAt.UTC().Format("20060102-150405"), runID)
+	}
+	Expect(os.WriteFile(filepath.Join(dir, fileName), []byte("{}"), 0600)).To(Succeed())
+	return fileName
+}
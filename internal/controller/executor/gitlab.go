@@ -0,0 +1,317 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+const (
+	gitLabRequestTimeout = 30 * time.Second
+
+	// maximum number of bytes of the GitLab response included in errors
+	maxGitLabResponseBody = 4096
+
+	// gitLabMaxResources is the maximum number of resources listed in the
+	// resource table
+	gitLabMaxResources = 100
+
+	// gitLabMaxFailures is the maximum size of the failed resources section
+	gitLabMaxFailures = 10000
+
+	defaultGitLabURL = "https://gitlab.com"
+)
+
+type gitLabInfo struct {
+	token string
+	// projectURL is the API URL of the project
+	projectURL string
+	tlsConfig  *tls.Config
+}
+
+// gitLabIssue contains the fields of a GitLab issue used by the notification
+type gitLabIssue struct {
+	IID         int64  `json:"iid"`
+	Description string `json:"description"`
+}
+
+// gitLabNote contains the fields of a GitLab note used by the notification
+type gitLabNote struct {
+	ID     int64  `json:"id"`
+	Body   string `json:"body"`
+	System bool   `json:"system"`
+}
+
+func init() {
+	registerNotifier(appsv1alpha1.NotificationTypeGitLab, notifierFunc(
+		func(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+			_ []ResourceResult, message string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+			return sendGitLabNotification(ctx, cleaner, reportSpec, message, notification, logger)
+		}))
+}
+
+// sendGitLabNotification posts the report to the issue, or merge request note,
+// of the Cleaner instance. The issue or note carries a marker identifying the
+// Cleaner, so following runs update it instead of opening a new one.
+func sendGitLabNotification(ctx context.Context, cleaner *appsv1alpha1.Cleaner,
+	reportSpec *appsv1alpha1.ReportSpec, message string, notification *appsv1alpha1.Notification,
+	logger logr.Logger) error {
+
+	info, err := getGitLabInfo(ctx, notification)
+	if err != nil {
+		return err
+	}
+
+	l := logger.WithValues(logKeyURL, info.projectURL)
+	body := getGitLabBody(cleaner.Name, reportSpec, message)
+
+	if notification.GitLab != nil && notification.GitLab.MergeRequestIID != 0 {
+		l = l.WithValues("mergeRequest", notification.GitLab.MergeRequestIID)
+		l.V(logs.LogInfo).Info("send gitlab merge request note")
+		err = postGitLabNote(ctx, info, notification.GitLab.MergeRequestIID, cleaner.Name, body)
+	} else {
+		l.V(logs.LogInfo).Info("send gitlab issue")
+		err = postGitLabIssue(ctx, info, cleaner.Name, reportSpec, body, notification.GitLab)
+	}
+	if err != nil {
+		l.Error(err, logMsgSendFailed)
+		return err
+	}
+
+	l.V(logs.LogDebug).Info("gitlab notification sent")
+	return nil
+}
+
+// postGitLabIssue updates the open issue of the Cleaner instance, or opens one.
+// Runs which succeed with no resource close the issue, and open none.
+func postGitLabIssue(ctx context.Context, info *gitLabInfo, cleanerName string,
+	reportSpec *appsv1alpha1.ReportSpec, body string, options *appsv1alpha1.GitLabOptions) error {
+
+	issue, err := findGitLabIssue(ctx, info, cleanerName)
+	if err != nil {
+		return err
+	}
+	resolved := reportSpec.Error == "" && len(reportSpec.ResourceInfo) == 0
+
+	if issue == nil {
+		if resolved {
+			return nil
+		}
+		fields := map[string]interface{}{
+			"title":       getGitLabIssueTitle(cleanerName, reportSpec),
+			"description": body,
+		}
+		if options != nil && len(options.Labels) > 0 {
+			fields["labels"] = strings.Join(options.Labels, ",")
+		}
+		return doGitLabRequest(ctx, info, http.MethodPost, "/issues", fields, nil)
+	}
+
+	fields := map[string]interface{}{
+		"title":       getGitLabIssueTitle(cleanerName, reportSpec),
+		"description": body,
+	}
+	if resolved {
+		fields["state_event"] = "close"
+	}
+	return doGitLabRequest(ctx, info, http.MethodPut, fmt.Sprintf("/issues/%d", issue.IID), fields, nil)
+}
+
+// findGitLabIssue returns the open issue whose description contains the marker
+// of the Cleaner instance. Nil is returned when there is none.
+func findGitLabIssue(ctx context.Context, info *gitLabInfo, cleanerName string) (*gitLabIssue, error) {
+	query := url.Values{}
+	query.Set("state", "opened")
+	query.Set("in", "description")
+	query.Set("search", cleanerName)
+	query.Set("per_page", "100")
+
+	var issues []gitLabIssue
+	if err := doGitLabRequest(ctx, info, http.MethodGet, "/issues?"+query.Encode(), nil, &issues); err != nil {
+		return nil, err
+	}
+
+	marker := getGitLabMarker(cleanerName)
+	for i := range issues {
+		if strings.Contains(issues[i].Description, marker) {
+			return &issues[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// postGitLabNote updates the note of the Cleaner instance on the merge request,
+// or posts one. Only the most recently updated notes are searched for the marker.
+func postGitLabNote(ctx context.Context, info *gitLabInfo, mergeRequestIID int64, cleanerName, body string) error {
+	notesPath := fmt.Sprintf("/merge_requests/%d/notes", mergeRequestIID)
+
+	var notes []gitLabNote
+	if err := doGitLabRequest(ctx, info, http.MethodGet, notesPath+"?sort=desc&order_by=updated_at&per_page=100",
+		nil, &notes); err != nil {
+		return err
+	}
+
+	fields := map[string]interface{}{"body": body}
+	marker := getGitLabMarker(cleanerName)
+	for i := range notes {
+		if !notes[i].System && strings.Contains(notes[i].Body, marker) {
+			return doGitLabRequest(ctx, info, http.MethodPut, fmt.Sprintf("%s/%d", notesPath, notes[i].ID), fields, nil)
+		}
+	}
+	return doGitLabRequest(ctx, info, http.MethodPost, notesPath, fields, nil)
+}
+
+// doGitLabRequest sends a request to path, relative to the project API URL.
+// fields, if not nil, are sent as JSON body. When result is not nil, the
+// response is decoded into it.
+func doGitLabRequest(ctx context.Context, info *gitLabInfo, method, path string,
+	fields map[string]interface{}, result interface{}) error {
+
+	var reqBody io.Reader
+	if fields != nil {
+		data, err := json.Marshal(fields)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, info.projectURL+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("PRIVATE-TOKEN", info.token)
+	if fields != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := newNotificationTLSHTTPClient(gitLabRequestTimeout, info.tlsConfig).Do(req)
+	if err != nil {
+		return getTLSError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxGitLabResponseBody))
+		return fmt.Errorf("gitlab returned %s: %s", resp.Status, string(body))
+	}
+
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode gitlab response: %w", err)
+	}
+	return nil
+}
+
+// getGitLabMarker returns the hidden marker identifying the issue, or note,
+// of a Cleaner instance
+func getGitLabMarker(cleanerName string) string {
+	return fmt.Sprintf("<!-- k8s-cleaner: %s -->", cleanerName)
+}
+
+func getGitLabIssueTitle(cleanerName string, reportSpec *appsv1alpha1.ReportSpec) string {
+	return fmt.Sprintf("k8s-cleaner '%s': %s", cleanerName, reportSpec.Action)
+}
+
+// getGitLabBody returns the markdown description of an issue, or body of a note:
+// the marker, message, resource counts, a table of resources and the failed
+// resources, if any
+func getGitLabBody(cleanerName string, reportSpec *appsv1alpha1.ReportSpec, message string) string {
+	sections := []string{getGitLabMarker(cleanerName) + "\n" + message, getResourceSummary(reportSpec, false)}
+	if table := getGitLabResourceTable(reportSpec); table != "" {
+		sections = append(sections, table)
+	}
+	if failures := getFailedResourcesMarkdown(reportSpec, gitLabMaxFailures); failures != "" {
+		sections = append(sections, failures)
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+// getGitLabResourceTable returns the first gitLabMaxResources resources of
+// reportSpec as a markdown table. An empty string is returned when there is
+// no resource.
+func getGitLabResourceTable(reportSpec *appsv1alpha1.ReportSpec) string {
+	if len(reportSpec.ResourceInfo) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("| Kind | Namespace | Name | Outcome |\n|---|---|---|---|\n")
+	for i := range reportSpec.ResourceInfo {
+		if i == gitLabMaxResources {
+			sb.WriteString(fmt.Sprintf("\n…and %d more", len(reportSpec.ResourceInfo)-i))
+			break
+		}
+		info := &reportSpec.ResourceInfo[i]
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n", info.Resource.Kind, info.Resource.Namespace,
+			info.Resource.Name, info.Outcome))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func getGitLabInfo(ctx context.Context, notification *appsv1alpha1.Notification) (*gitLabInfo, error) {
+	secret, err := getSecret(ctx, notification)
+	if err != nil {
+		return nil, err
+	}
+
+	token, ok := secret.Data[appsv1alpha1.GitLabToken]
+	if !ok || len(token) == 0 {
+		return nil, fmt.Errorf("secret does not contain gitlab token")
+	}
+
+	project, ok := secret.Data[appsv1alpha1.GitLabProject]
+	if !ok || len(project) == 0 {
+		return nil, fmt.Errorf("secret does not contain gitlab project")
+	}
+
+	baseURL := defaultGitLabURL
+	if value := secret.Data[appsv1alpha1.GitLabURL]; len(value) > 0 {
+		baseURL = strings.TrimSuffix(strings.TrimSpace(string(value)), "/")
+	}
+
+	tlsConfig, err := getNotificationTLSConfig(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gitLabInfo{
+		token: strings.TrimSpace(string(token)),
+		// Project path is a single, escaped, path segment
+		projectURL: fmt.Sprintf("%s/api/v4/projects/%s", baseURL,
+			url.PathEscape(strings.TrimSpace(string(project)))),
+		tlsConfig: tlsConfig,
+	}, nil
+}
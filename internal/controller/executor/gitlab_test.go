@@ -0,0 +1,202 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+const gitLabProjectPath = "/api/v4/projects/platform%2Fcleanups"
+
+// fakeGitLab is a GitLab API server storing issues and merge request notes
+type fakeGitLab struct {
+	mux     sync.Mutex
+	issues  []map[string]interface{}
+	notes   []map[string]interface{}
+	methods []string
+	tokens  []string
+}
+
+func (f *fakeGitLab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	path := strings.TrimPrefix(r.URL.EscapedPath(), gitLabProjectPath)
+	f.methods = append(f.methods, r.Method+" "+path)
+	f.tokens = append(f.tokens, r.Header.Get("PRIVATE-TOKEN"))
+
+	fields := map[string]interface{}{}
+	if r.Method != http.MethodGet {
+		Expect(json.NewDecoder(r.Body).Decode(&fields)).To(Succeed())
+	}
+
+	switch {
+	case r.Method == http.MethodGet && path == "/issues":
+		opened := make([]map[string]interface{}, 0)
+		for i := range f.issues {
+			if f.issues[i]["state"] == "opened" {
+				opened = append(opened, f.issues[i])
+			}
+		}
+		Expect(json.NewEncoder(w).Encode(opened)).To(Succeed())
+	case r.Method == http.MethodPost && path == "/issues":
+		fields["iid"] = len(f.issues) + 1
+		fields["state"] = "opened"
+		f.issues = append(f.issues, fields)
+		Expect(json.NewEncoder(w).Encode(fields)).To(Succeed())
+	case r.Method == http.MethodPut && strings.HasPrefix(path, "/issues/"):
+		var iid int
+		_, err := fmt.Sscanf(path, "/issues/%d", &iid)
+		Expect(err).To(BeNil())
+		issue := f.issues[iid-1]
+		for k, v := range fields {
+			issue[k] = v
+		}
+		if fields["state_event"] == "close" {
+			issue["state"] = "closed"
+		}
+		Expect(json.NewEncoder(w).Encode(issue)).To(Succeed())
+	case r.Method == http.MethodGet && path == "/merge_requests/7/notes":
+		Expect(json.NewEncoder(w).Encode(f.notes)).To(Succeed())
+	case r.Method == http.MethodPost && path == "/merge_requests/7/notes":
+		fields["id"] = len(f.notes) + 1
+		f.notes = append(f.notes, fields)
+		Expect(json.NewEncoder(w).Encode(fields)).To(Succeed())
+	case r.Method == http.MethodPut && strings.HasPrefix(path, "/merge_requests/7/notes/"):
+		var id int
+		_, err := fmt.Sscanf(path, "/merge_requests/7/notes/%d", &id)
+		Expect(err).To(BeNil())
+		f.notes[id-1]["body"] = fields["body"]
+		Expect(json.NewEncoder(w).Encode(f.notes[id-1])).To(Succeed())
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"404 Not Found"}`))
+	}
+}
+
+// createGitLabSecret starts a fake GitLab server and creates the secret of a
+// GitLab notification using it
+func createGitLabSecret(token string) (*corev1.ObjectReference, *fakeGitLab) {
+	fake := &fakeGitLab{}
+	server := httptest.NewServer(fake)
+	DeferCleanup(server.Close)
+
+	ref := createNotificationSecret(map[string][]byte{
+		appsv1alpha1.GitLabToken:   []byte(token),
+		appsv1alpha1.GitLabProject: []byte("platform/cleanups"),
+		appsv1alpha1.GitLabURL:     []byte(server.URL + "/"),
+	})
+	return ref, fake
+}
+
+var _ = Describe("GitLab", func() {
+	It("sendNotifications opens an issue and updates it on following runs", func() {
+		token := randomString()
+		ref, fake := createGitLabSecret(token)
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeGitLab, ref)
+		cleaner.Spec.Notifications[0].GitLab = &appsv1alpha1.GitLabOptions{Labels: []string{"cleanup", "k8s"}}
+
+		first := getResourceResult("ConfigMap", randomString(), randomString())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{first},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.issues).To(HaveLen(1))
+		Expect(fake.issues[0]["title"]).To(Equal("k8s-cleaner '" + cleaner.Name + "': Delete"))
+		Expect(fake.issues[0]["labels"]).To(Equal("cleanup,k8s"))
+		description := fake.issues[0]["description"].(string)
+		Expect(description).To(HavePrefix("<!-- k8s-cleaner: " + cleaner.Name + " -->\nk8s-cleaner '" + cleaner.Name +
+			"' performed Delete on 1 resource"))
+		Expect(description).To(ContainSubstring("| Kind | Namespace | Name | Outcome |"))
+		Expect(description).To(ContainSubstring(fmt.Sprintf("| ConfigMap | %s | %s |",
+			first.Resource.GetNamespace(), first.Resource.GetName())))
+		Expect(fake.tokens).To(HaveEach(token))
+
+		second := getResourceResult("Secret", randomString(), randomString())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{second},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.issues).To(HaveLen(1))
+		Expect(fake.methods).To(ContainElement("PUT /issues/1"))
+		Expect(fake.issues[0]["description"]).To(ContainSubstring(second.Resource.GetName()))
+		Expect(fake.issues[0]["description"]).ToNot(ContainSubstring(first.Resource.GetName()))
+	})
+
+	It("sendNotifications closes the issue when no resource is matched", func() {
+		ref, fake := createGitLabSecret(randomString())
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeGitLab, ref)
+
+		// No issue is opened for runs matching nothing
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+		Expect(fake.issues).To(BeEmpty())
+
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.issues).To(HaveLen(1))
+		Expect(fake.issues[0]["state"]).To(Equal("closed"))
+
+		// Next matching run opens a new issue
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+		Expect(fake.issues).To(HaveLen(2))
+	})
+
+	It("sendNotifications posts a merge request note and updates it on following runs", func() {
+		ref, fake := createGitLabSecret(randomString())
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeGitLab, ref)
+		cleaner.Spec.Notifications[0].GitLab = &appsv1alpha1.GitLabOptions{MergeRequestIID: 7}
+		// Note of another Cleaner instance
+		fake.notes = []map[string]interface{}{{"id": 1, "body": "<!-- k8s-cleaner: other -->"}}
+
+		for i := 0; i < 2; i++ {
+			resource := getResourceResult("ConfigMap", randomString(), randomString())
+			Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+				cleaner, "", logr.Discard())).To(Succeed())
+		}
+
+		Expect(fake.issues).To(BeEmpty())
+		Expect(fake.notes).To(HaveLen(2))
+		Expect(fake.notes[1]["body"]).To(HavePrefix("<!-- k8s-cleaner: " + cleaner.Name + " -->"))
+		Expect(fake.methods).To(ContainElement("PUT /merge_requests/7/notes/2"))
+	})
+
+	It("sendNotifications returns GitLab error", func() {
+		ref, _ := createGitLabSecret(randomString())
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeGitLab, ref)
+		cleaner.Spec.Notifications[0].GitLab = &appsv1alpha1.GitLabOptions{MergeRequestIID: 8}
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("404 Not Found"))
+	})
+})
@@ -0,0 +1,140 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+const (
+	labelNotificationType = "type"
+)
+
+var (
+	notificationLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8s_cleaner_notification_last_success_timestamp_seconds",
+		Help: "Unix time of the last successful delivery, per notification type",
+	}, []string{labelNotificationType})
+
+	notificationLastFailure = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8s_cleaner_notification_last_failure_timestamp_seconds",
+		Help: "Unix time of the last failed delivery, per notification type",
+	}, []string{labelNotificationType})
+
+	notificationUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "k8s_cleaner_notification_up",
+		Help: "Whether the last delivery succeeded (1) or failed (0), per notification type",
+	}, []string{labelNotificationType})
+)
+
+func init() {
+	metrics.Registry.MustRegister(notificationLastSuccess, notificationLastFailure, notificationUp)
+}
+
+// notificationTypeHealth is the outcome of the deliveries of a notification type,
+// across all Cleaner instances
+type notificationTypeHealth struct {
+	lastSuccess time.Time
+	lastFailure time.Time
+	lastError   string
+}
+
+// isUp returns true if the last delivery succeeded
+func (h *notificationTypeHealth) isUp() bool {
+	return !h.lastSuccess.Before(h.lastFailure)
+}
+
+var (
+	healthMux sync.RWMutex
+	// notificationHealth contains the health of each notification type used since
+	// k8s-cleaner started
+	notificationHealth = map[appsv1alpha1.NotificationType]*notificationTypeHealth{}
+)
+
+// healthNotifier records the outcome of every delivery of the notifier it wraps
+type healthNotifier struct {
+	notifier
+	notificationType appsv1alpha1.NotificationType
+}
+
+func (h *healthNotifier) Send(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	resources []ResourceResult, message string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+	err := h.notifier.Send(ctx, cleaner, reportSpec, resources, message, notification, logger)
+	recordNotificationHealth(h.notificationType, err, time.Now())
+	return err
+}
+
+// recordNotificationHealth records the outcome of a delivery of notificationType
+// and updates the corresponding metrics
+func recordNotificationHealth(notificationType appsv1alpha1.NotificationType, deliveryErr error, now time.Time) {
+	healthMux.Lock()
+	defer healthMux.Unlock()
+
+	health, ok := notificationHealth[notificationType]
+	if !ok {
+		health = &notificationTypeHealth{}
+		notificationHealth[notificationType] = health
+	}
+
+	label := string(notificationType)
+	if deliveryErr != nil {
+		health.lastFailure = now
+		health.lastError = truncateString(deliveryErr.Error(), maxNotificationFailureMessageSize)
+		notificationLastFailure.WithLabelValues(label).Set(float64(now.Unix()))
+		notificationUp.WithLabelValues(label).Set(0)
+		return
+	}
+	health.lastSuccess = now
+	notificationLastSuccess.WithLabelValues(label).Set(float64(now.Unix()))
+	notificationUp.WithLabelValues(label).Set(1)
+}
+
+// NotificationsReadyCheck is a readiness check failing when the last delivery
+// of every notification type used since k8s-cleaner started failed. It passes
+// when no notification has been sent yet. The error lists the last error of
+// each notification type.
+func NotificationsReadyCheck(_ *http.Request) error {
+	healthMux.RLock()
+	defer healthMux.RUnlock()
+
+	failures := make([]string, 0, len(notificationHealth))
+	for notificationType, health := range notificationHealth {
+		if health.isUp() {
+			return nil
+		}
+		failures = append(failures, fmt.Sprintf("%s: %s", notificationType, health.lastError))
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+
+	sort.Strings(failures)
+	return fmt.Errorf("no notification backend is reachable: %s", strings.Join(failures, "; "))
+}
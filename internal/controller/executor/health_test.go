@@ -0,0 +1,88 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+var _ = Describe("Notification health", func() {
+	BeforeEach(func() {
+		executor.ResetNotificationHealth()
+		DeferCleanup(executor.ResetNotificationHealth)
+	})
+
+	It("NotificationsReadyCheck passes when no notification has been sent", func() {
+		Expect(executor.NotificationsReadyCheck(nil)).To(Succeed())
+	})
+
+	It("sendNotifications records delivery outcome per notification type", func() {
+		ref, fake := createSlackSecret()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+		up, ok := executor.GetNotificationMetric("k8s_cleaner_notification_up", appsv1alpha1.NotificationTypeSlack)
+		Expect(ok).To(BeTrue())
+		Expect(up).To(Equal(float64(1)))
+		lastSuccess, ok := executor.GetNotificationMetric("k8s_cleaner_notification_last_success_timestamp_seconds",
+			appsv1alpha1.NotificationTypeSlack)
+		Expect(ok).To(BeTrue())
+		Expect(lastSuccess).ToNot(BeZero())
+		Expect(executor.NotificationsReadyCheck(nil)).To(Succeed())
+
+		fake.err = errors.New("dial tcp: lookup slack.com: no such host")
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).ToNot(Succeed())
+		up, ok = executor.GetNotificationMetric("k8s_cleaner_notification_up", appsv1alpha1.NotificationTypeSlack)
+		Expect(ok).To(BeTrue())
+		Expect(up).To(BeZero())
+
+		err := executor.NotificationsReadyCheck(nil)
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("Slack: dial tcp: lookup slack.com: no such host"))
+	})
+
+	It("NotificationsReadyCheck passes while any notification type is reachable", func() {
+		ref, fake := createSlackSecret()
+		fake.err = errors.New("channel_not_found")
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).ToNot(Succeed())
+		Expect(executor.NotificationsReadyCheck(nil)).ToNot(Succeed())
+
+		workingType := appsv1alpha1.NotificationType(randomString())
+		DeferCleanup(executor.SetNotifier(workingType, executor.NotifierFunc(
+			func(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+				resources []executor.ResourceResult, message string, notification *appsv1alpha1.Notification,
+				logger logr.Logger) error {
+
+				return nil
+			})))
+		cleaner.Spec.Notifications[0].Type = workingType
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+		Expect(executor.NotificationsReadyCheck(nil)).To(Succeed())
+	})
+})
@@ -0,0 +1,90 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+// maxKubectlCommands is the maximum number of resources a kubectl command is
+// listed for
+const maxKubectlCommands = 10
+
+// getKubectlNote returns the note appended to the message when notification
+// has IncludeKubectlCommands set: a kubectl command to inspect each of the first
+// maxKubectlCommands resources of reportSpec. The command restoring the backup
+// of a resource follows its command. Otherwise, when the Cleaner deletes resources
+// and stores them, the path of the stored copy does.
+// An empty string is returned when the note is disabled or there is no resource.
+func getKubectlNote(cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	notification *appsv1alpha1.Notification) string {
+
+	if !notification.IncludeKubectlCommands || len(reportSpec.ResourceInfo) == 0 {
+		return ""
+	}
+	stored := cleaner.Spec.Action == appsv1alpha1.ActionDelete && cleaner.Spec.StoreResourcePath != "" &&
+		cleaner.Spec.Backup == nil
+
+	var sb strings.Builder
+	sb.WriteString("\nInspect with:")
+	for i := range reportSpec.ResourceInfo {
+		if i == maxKubectlCommands {
+			sb.WriteString(fmt.Sprintf("\n…and %d more", len(reportSpec.ResourceInfo)-i))
+			break
+		}
+		resource := &reportSpec.ResourceInfo[i].Resource
+		sb.WriteString("\n" + getKubectlGetCommand(resource))
+		if backup := reportSpec.ResourceInfo[i].Backup; backup != nil {
+			sb.WriteString(" (restore with: " + backup.RestoreCommand + ")")
+		} else if stored {
+			sb.WriteString(" (stored copy: " + getStoredResourcePath(cleaner, resource) + ")")
+		}
+	}
+	if stored {
+		sb.WriteString("\nRestore a stored copy with: kubectl apply -f <file>")
+	}
+	return sb.String()
+}
+
+// getKubectlGetCommand returns the kubectl command getting resource. Kinds of
+// API groups other than core are qualified with the group, so the command is
+// not ambiguous.
+func getKubectlGetCommand(resource *corev1.ObjectReference) string {
+	kind := strings.ToLower(resource.Kind)
+	if gv, err := schema.ParseGroupVersion(resource.APIVersion); err == nil && gv.Group != "" {
+		kind += "." + gv.Group
+	}
+	command := fmt.Sprintf("kubectl get %s %s", kind, resource.Name)
+	if resource.Namespace != "" {
+		command += " -n " + resource.Namespace
+	}
+	return command
+}
+
+// getStoredResourcePath returns the path resource is stored at when the Cleaner
+// has StoreResourcePath set
+func getStoredResourcePath(cleaner *appsv1alpha1.Cleaner, resource *corev1.ObjectReference) string {
+	return path.Join(cleaner.Spec.StoreResourcePath, cleaner.Name, resource.Namespace, resource.Kind,
+		resource.Name+".yaml")
+}
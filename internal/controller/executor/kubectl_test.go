@@ -0,0 +1,93 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+var _ = Describe("Kubectl commands", func() {
+	It("sendNotifications adds a kubectl command per resource when IncludeKubectlCommands is set", func() {
+		ref, fake := createSlackSecret()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Action = appsv1alpha1.ActionScan
+		cleaner.Spec.Notifications[0].IncludeKubectlCommands = true
+
+		namespace := randomString()
+		configMap := getResourceResult("ConfigMap", namespace, randomString())
+		deployment := getResourceResult("Deployment", namespace, randomString())
+		deployment.Resource.SetAPIVersion("apps/v1")
+		clusterRole := getResourceResult("ClusterRole", "", randomString())
+		clusterRole.Resource.SetAPIVersion("rbac.authorization.k8s.io/v1")
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{configMap, deployment, clusterRole},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		text := fake.values[0].Get("text")
+		Expect(text).To(ContainSubstring("\nInspect with:"))
+		Expect(text).To(ContainSubstring("\nkubectl get configmap " + configMap.Resource.GetName() + " -n " + namespace))
+		Expect(text).To(ContainSubstring("\nkubectl get deployment.apps " + deployment.Resource.GetName() + " -n " + namespace))
+		Expect(text).To(ContainSubstring("\nkubectl get clusterrole.rbac.authorization.k8s.io " +
+			clusterRole.Resource.GetName()))
+		Expect(text).ToNot(ContainSubstring(clusterRole.Resource.GetName() + " -n"))
+		Expect(text).ToNot(ContainSubstring("stored copy"))
+	})
+
+	It("sendNotifications lists the stored copy of deleted resources", func() {
+		ref, fake := createSlackSecret()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.StoreResourcePath = "/collection"
+		cleaner.Spec.Notifications[0].IncludeKubectlCommands = true
+
+		resources := make([]executor.ResourceResult, 12)
+		namespace := randomString()
+		for i := range resources {
+			resources[i] = getResourceResult("ConfigMap", namespace, fmt.Sprintf("%02d-%s", i, randomString()))
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		text := fake.values[0].Get("text")
+		Expect(text).To(ContainSubstring("\nkubectl get configmap " + resources[0].Resource.GetName() + " -n " + namespace +
+			" (stored copy: /collection/" + cleaner.Name + "/" + namespace + "/ConfigMap/" +
+			resources[0].Resource.GetName() + ".yaml)"))
+		Expect(text).To(ContainSubstring("\n…and 2 more\nRestore a stored copy with: kubectl apply -f <file>"))
+		Expect(text).ToNot(ContainSubstring(resources[11].Resource.GetName()))
+	})
+
+	It("sendNotifications does not add kubectl commands by default", func() {
+		ref, fake := createSlackSecret()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).ToNot(ContainSubstring("kubectl get"))
+	})
+})
@@ -0,0 +1,131 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+const (
+	// maxNotificationLinks is the maximum number of links added to a message
+	maxNotificationLinks = 5
+
+	// maxLinkLabelLength is the maximum length of a link label (Slack limits
+	// button text to 75 characters)
+	maxLinkLabelLength = 75
+
+	// defaultLinkLabel is the label of the link when there is only one
+	defaultLinkLabel = "Open dashboard"
+)
+
+// notificationLink is a link added to a message
+type notificationLink struct {
+	label string
+	url   string
+}
+
+// linkTemplateData is the data the link template is evaluated against
+type linkTemplateData struct {
+	Cleaner     string
+	Action      appsv1alpha1.Action
+	RunID       string
+	Kind        string
+	Namespace   string
+	Name        string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// getNotificationLinks evaluates the LinkTemplate of notification against each
+// resource of reportSpec, or once when there is none, and returns the distinct
+// resulting URLs in order, up to maxNotificationLinks. Results which are not
+// absolute http(s) URLs, including empty ones, are ignored.
+// A single link is labeled defaultLinkLabel, otherwise links are labeled with
+// their URL so they can be told apart.
+func getNotificationLinks(cleanerName string, reportSpec *appsv1alpha1.ReportSpec,
+	notification *appsv1alpha1.Notification) ([]notificationLink, error) {
+
+	if notification.LinkTemplate == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.New("link").Option("missingkey=zero").Parse(notification.LinkTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid link template: %w", err)
+	}
+
+	data := []linkTemplateData{}
+	for i := range reportSpec.ResourceInfo {
+		info := &reportSpec.ResourceInfo[i]
+		data = append(data, linkTemplateData{
+			Cleaner:     cleanerName,
+			Action:      reportSpec.Action,
+			RunID:       reportSpec.RunID,
+			Kind:        info.Resource.Kind,
+			Namespace:   info.Resource.Namespace,
+			Name:        info.Resource.Name,
+			Labels:      info.Labels,
+			Annotations: info.Annotations,
+		})
+	}
+	if len(data) == 0 {
+		data = append(data, linkTemplateData{Cleaner: cleanerName, Action: reportSpec.Action, RunID: reportSpec.RunID})
+	}
+
+	var links []notificationLink
+	seen := make(map[string]bool)
+	for i := range data {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data[i]); err != nil {
+			return nil, fmt.Errorf("failed to evaluate link template: %w", err)
+		}
+		link := strings.TrimSpace(buf.String())
+		if seen[link] || !isHTTPURL(link) {
+			continue
+		}
+		seen[link] = true
+		links = append(links, notificationLink{url: link})
+		if len(links) == maxNotificationLinks {
+			break
+		}
+	}
+
+	for i := range links {
+		if len(links) == 1 {
+			links[i].label = defaultLinkLabel
+			continue
+		}
+		label := strings.TrimPrefix(strings.TrimPrefix(links[i].url, "https://"), "http://")
+		links[i].label = truncateString(label, maxLinkLabelLength)
+	}
+	return links, nil
+}
+
+// isHTTPURL returns true if s is an absolute http or https URL
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
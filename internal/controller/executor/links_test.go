@@ -0,0 +1,212 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/atc0005/go-teams-notify/v2/adaptivecard"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+// getSlackButtons returns the buttons of the encoded Slack attachments
+func getSlackButtons(attachments string) []*slack.ButtonBlockElement {
+	var decoded []slack.Attachment
+	Expect(json.Unmarshal([]byte(attachments), &decoded)).To(Succeed())
+
+	var buttons []*slack.ButtonBlockElement
+	for i := range decoded {
+		for _, block := range decoded[i].Blocks.BlockSet {
+			actionBlock, ok := block.(*slack.ActionBlock)
+			if !ok {
+				continue
+			}
+			for _, element := range actionBlock.Elements.ElementSet {
+				button, ok := element.(*slack.ButtonBlockElement)
+				Expect(ok).To(BeTrue())
+				buttons = append(buttons, button)
+			}
+		}
+	}
+	return buttons
+}
+
+var _ = Describe("Links", func() {
+	It("sendNotifications adds a Slack button per distinct link", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications[0].LinkTemplate = "https://grafana.example.com/d/ns?var-namespace={{ .Namespace }}"
+		resources := []executor.ResourceResult{
+			getResourceResult("ConfigMap", "prod", randomString()),
+			getResourceResult("Secret", "prod", randomString()),
+			getResourceResult("ConfigMap", "dev", randomString()),
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		buttons := getSlackButtons(fake.values[0].Get("attachments"))
+		Expect(buttons).To(HaveLen(2))
+		urls := []string{buttons[0].URL, buttons[1].URL}
+		Expect(urls).To(ConsistOf(
+			"https://grafana.example.com/d/ns?var-namespace=prod",
+			"https://grafana.example.com/d/ns?var-namespace=dev"))
+		for i := range buttons {
+			Expect(buttons[i].Text.Text).To(Equal(buttons[i].URL[len("https://"):]))
+		}
+	})
+
+	It("sendNotifications evaluates link template against the Cleaner when there are no resources", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications[0].LinkTemplate = "https://backstage.example.com/cleaner/{{ .Cleaner }}/{{ .RunID }}"
+		runID := randomString()
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, runID, logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		buttons := getSlackButtons(fake.values[0].Get("attachments"))
+		Expect(buttons).To(HaveLen(1))
+		Expect(buttons[0].URL).To(Equal(fmt.Sprintf("https://backstage.example.com/cleaner/%s/%s", cleaner.Name, runID)))
+		Expect(buttons[0].Text.Text).To(Equal("Open dashboard"))
+	})
+
+	It("sendNotifications ignores links which are not http URLs and limits their number", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications[0].LinkTemplate =
+			`{{ if eq .Namespace "internal" }}javascript:alert(1){{ else }}https://example.com/{{ .Name }}{{ end }}`
+		resources := []executor.ResourceResult{getResourceResult("ConfigMap", "internal", randomString())}
+		for i := 0; i < 10; i++ {
+			resources = append(resources, getResourceResult("ConfigMap", randomString(), randomString()))
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		buttons := getSlackButtons(fake.values[0].Get("attachments"))
+		Expect(buttons).To(HaveLen(5))
+		for i := range buttons {
+			Expect(buttons[i].URL).To(HavePrefix("https://example.com/"))
+		}
+	})
+
+	It("sendNotifications adds OpenUrl actions to Teams message", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.TeamsWebhookURL: []byte("https://example.webhook.office.com/" + randomString()),
+		})
+		fake := &fakeTeamsClient{}
+		DeferCleanup(executor.SetTeamsClientFactory(func() executor.TeamsClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeTeams, ref)
+		cleaner.Spec.Notifications[0].LinkTemplate = "https://grafana.example.com/d/cleaner?var-cleaner={{ .Cleaner }}"
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.messages).To(HaveLen(1))
+		message, ok := fake.messages[0].(*adaptivecard.Message)
+		Expect(ok).To(BeTrue())
+		Expect(message.Attachments).To(HaveLen(1))
+		actions := message.Attachments[0].Content.Actions
+		Expect(actions).To(HaveLen(1))
+		Expect(actions[0].Type).To(Equal(adaptivecard.TypeActionOpenURL))
+		Expect(actions[0].URL).To(Equal("https://grafana.example.com/d/cleaner?var-cleaner=" + cleaner.Name))
+		Expect(actions[0].Title).To(Equal("Open dashboard"))
+	})
+
+	It("sendNotifications links Discord embed to the first link", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.DiscordChannelID: []byte(randomDiscordID()),
+			libsveltosv1alpha1.DiscordToken:     []byte(randomString()),
+		})
+		fake := &fakeDiscordClient{}
+		useFakeDiscordClient(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeDiscord, ref)
+		cleaner.Spec.Notifications[0].LinkTemplate = "https://example.com/{{ .Namespace }}"
+		resources := []executor.ResourceResult{
+			getResourceResult("ConfigMap", "prod", randomString()),
+			getResourceResult("ConfigMap", "dev", randomString()),
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.messages).To(HaveLen(1))
+		embed := fake.messages[0].Embeds[0]
+		Expect(embed.URL).To(Or(Equal("https://example.com/prod"), Equal("https://example.com/dev")))
+		other := "https://example.com/dev"
+		if embed.URL == other {
+			other = "https://example.com/prod"
+		}
+		Expect(embed.Description).To(ContainSubstring(fmt.Sprintf("[%s](%s)", other[len("https://"):], other)))
+	})
+
+	It("sendNotifications fails when the link template is invalid", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return &fakeSlackClient{}
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications[0].LinkTemplate = "https://example.com/{{ .Namespace"
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("invalid link template"))
+	})
+})
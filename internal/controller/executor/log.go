@@ -0,0 +1,88 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+func init() {
+	registerNotifier(appsv1alpha1.NotificationTypeLog, notifierFunc(
+		func(_ context.Context, _ *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+			_ []ResourceResult, message string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+			return sendLogNotification(reportSpec, message, notification, logger)
+		}))
+}
+
+// sendLogNotification writes the message and the rendered report to the
+// controller log. It requires no Secret, so it can be used to validate a
+// Cleaner before any real channel is set up.
+func sendLogNotification(reportSpec *appsv1alpha1.ReportSpec, message string,
+	notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+	level, err := getLogNotificationLevel(notification.Log)
+	if err != nil {
+		return err
+	}
+
+	format := appsv1alpha1.ReportFormatJSON
+	if notification.Log != nil && notification.Log.Format != "" {
+		format = notification.Log.Format
+	}
+
+	var data []byte
+	// File reports are indented, while a log line must stay compact
+	if format == appsv1alpha1.ReportFormatJSON {
+		data, err = marshalReport(reportSpec, getReportEncoding(notification.Type))
+	} else {
+		data, err = renderFileReport(reportSpec, format)
+	}
+	if err != nil {
+		logger.Error(err, logMsgMarshalReportFailed)
+		return err
+	}
+
+	logger.V(level).Info(logMsgReport, logKeyMessage, message, logKeyReport, string(data))
+	return nil
+}
+
+// getLogNotificationLevel returns the verbosity a Log notification writes
+// reports at. Reports are written at info level by default.
+func getLogNotificationLevel(options *appsv1alpha1.LogOptions) (int, error) {
+	if options == nil {
+		return logs.LogInfo, nil
+	}
+
+	switch options.Level {
+	case appsv1alpha1.LogLevelInfo, "":
+		return logs.LogInfo, nil
+	case appsv1alpha1.LogLevelDebug:
+		return logs.LogDebug, nil
+	case appsv1alpha1.LogLevelVerbose:
+		return logs.LogVerbose, nil
+	default:
+		return 0, fmt.Errorf("unsupported log level %s", options.Level)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+// getReportLogEntries returns a logger with the given verbosity, and the
+// entries it logged with the report message
+func getReportLogEntries(verbosity int) (logr.Logger, *[]map[string]interface{}) {
+	entries := make([]map[string]interface{}, 0)
+	logger := funcr.NewJSON(func(obj string) {
+		entry := make(map[string]interface{})
+		Expect(json.Unmarshal([]byte(obj), &entry)).To(Succeed())
+		if entry["msg"] == "k8s-cleaner report" {
+			entries = append(entries, entry)
+		}
+	}, funcr.Options{Verbosity: verbosity})
+	return logger, &entries
+}
+
+var _ = Describe("Log notification", func() {
+	It("sendNotifications writes the message and the JSON report to the log", func() {
+		logger, entries := getReportLogEntries(0)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeLog, nil)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logger)).To(Succeed())
+
+		Expect(*entries).To(HaveLen(1))
+		entry := (*entries)[0]
+		Expect(entry).To(HaveKeyWithValue("notification", cleaner.Spec.Notifications[0].Name))
+		Expect(entry).To(HaveKeyWithValue("message", "k8s-cleaner '"+cleaner.Name+"' performed Delete on 1 resource"))
+
+		report, ok := entry["report"].(string)
+		Expect(ok).To(BeTrue())
+		Expect(report).ToNot(ContainSubstring("\n"))
+		reportSpec := &appsv1alpha1.ReportSpec{}
+		Expect(json.Unmarshal([]byte(report), reportSpec)).To(Succeed())
+		Expect(reportSpec.ResourceInfo).To(HaveLen(1))
+		Expect(reportSpec.ResourceInfo[0].Resource.Name).To(Equal(resource.Resource.GetName()))
+	})
+
+	It("sendNotifications writes the report at the configured level and format", func() {
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeLog, nil)
+		cleaner.Spec.Notifications[0].Log = &appsv1alpha1.LogOptions{
+			Level:  appsv1alpha1.LogLevelDebug,
+			Format: appsv1alpha1.ReportFormatYAML,
+		}
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		logger, entries := getReportLogEntries(0)
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logger)).To(Succeed())
+		Expect(*entries).To(BeEmpty())
+
+		logger, entries = getReportLogEntries(5)
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logger)).To(Succeed())
+		Expect(*entries).To(HaveLen(1))
+		Expect((*entries)[0]).To(HaveKeyWithValue("level", BeNumerically("==", 5)))
+
+		report, ok := (*entries)[0]["report"].(string)
+		Expect(ok).To(BeTrue())
+		reportSpec := &appsv1alpha1.ReportSpec{}
+		Expect(yaml.UnmarshalStrict([]byte(report), reportSpec)).To(Succeed())
+		Expect(reportSpec.ResourceInfo).To(HaveLen(1))
+		Expect(reportSpec.ResourceInfo[0].Resource.Name).To(Equal(resource.Resource.GetName()))
+	})
+})
@@ -0,0 +1,67 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"github.com/go-logr/logr"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+// Keys of the structured fields logged while delivering notifications.
+// Dashboards filter on them, so they must not change.
+const (
+	logKeyNotification = "notification"
+	logKeyType         = "type"
+	logKeyChannel      = "channel"
+	logKeyRunID        = "runID"
+	logKeyResources    = "resources"
+	logKeyRuns         = "runs"
+	logKeyPath         = "path"
+	logKeyURL          = "url"
+	logKeySize         = "size"
+	logKeyLimit        = "limit"
+	logKeyBucket       = "bucket"
+	logKeyKey          = "key"
+	logKeyIndex        = "index"
+	logKeyMessage      = "message"
+	logKeyReport       = "report"
+)
+
+// Messages logged while delivering notifications. Dashboards match on them,
+// so they must not change.
+const (
+	logMsgDeliverNotification      = "deliver notification"
+	logMsgNotificationDelivered    = "notification delivered"
+	logMsgSendFailed               = "failed to send notification"
+	logMsgNotificationSuspended    = "notification suspended after repeated failures"
+	logMsgNotificationDisabled     = "notification skipped as disabled"
+	logMsgReportDisabled           = "notification skipped as report is disabled"
+	logMsgOutsideActiveWindow      = "notification skipped outside its active window"
+	logMsgFailureIgnored           = "notification failure ignored as per onFailure policy"
+	logMsgRetryNotification        = "retry notification"
+	logMsgRecordStatusFailed       = "failed to record notification status"
+	logMsgMarshalReportFailed      = "failed to marshal report"
+	logMsgWriteTemporaryFileFailed = "failed to write report to temporary file"
+	logMsgReport                   = "k8s-cleaner report"
+)
+
+// getNotificationLogger returns logger with the name and type of notification
+// as structured fields
+func getNotificationLogger(logger logr.Logger, notification *appsv1alpha1.Notification) logr.Logger {
+	return logger.WithValues(logKeyNotification, notification.Name, logKeyType, string(notification.Type))
+}
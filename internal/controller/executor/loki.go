@@ -0,0 +1,283 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+const (
+	lokiRequestTimeout = 30 * time.Second
+
+	// maximum number of bytes of the Loki response included in errors
+	maxLokiResponseBody = 4096
+
+	// lokiMaxEntriesPerPush is the maximum number of log lines sent in a single
+	// push request. Larger reports are sent with multiple requests.
+	lokiMaxEntriesPerPush = 1000
+
+	lokiLabelCleaner   = "cleaner"
+	lokiLabelAction    = "action"
+	lokiLabelNamespace = "namespace"
+)
+
+type lokiInfo struct {
+	url       string
+	tenantID  string
+	username  string
+	password  string
+	tlsConfig *tls.Config
+}
+
+// lokiStream is a stream of the Loki push API: a set of labels and the
+// log lines, as [timestamp in nanoseconds, line] pairs
+// https://grafana.com/docs/loki/latest/reference/loki-http-api/#ingest-logs
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type lokiPush struct {
+	Streams []*lokiStream `json:"streams"`
+}
+
+// lokiReportLine is the log line summarizing a run
+type lokiReportLine struct {
+	Message  string                      `json:"message"`
+	RunID    string                      `json:"runID,omitempty"`
+	Summary  *appsv1alpha1.ReportSummary `json:"summary,omitempty"`
+	Error    string                      `json:"error,omitempty"`
+	Metadata map[string]string           `json:"metadata,omitempty"`
+}
+
+// lokiResourceLine is the log line of a resource of the report
+type lokiResourceLine struct {
+	RunID      string                       `json:"runID,omitempty"`
+	APIVersion string                       `json:"apiVersion,omitempty"`
+	Kind       string                       `json:"kind"`
+	Namespace  string                       `json:"namespace,omitempty"`
+	Name       string                       `json:"name"`
+	Outcome    appsv1alpha1.ResourceOutcome `json:"outcome,omitempty"`
+	Message    string                       `json:"message,omitempty"`
+	Error      string                       `json:"error,omitempty"`
+}
+
+func init() {
+	registerNotifier(appsv1alpha1.NotificationTypeLoki, notifierFunc(
+		func(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+			_ []ResourceResult, message string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+			return sendLokiNotification(ctx, cleaner, reportSpec, message, notification, logger)
+		}))
+}
+
+// sendLokiNotification pushes the report to Loki: a line summarizing the run,
+// followed by a line per resource. Resources are pushed in batches of at most
+// lokiMaxEntriesPerPush lines.
+func sendLokiNotification(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	message string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+	info, err := getLokiInfo(ctx, notification)
+	if err != nil {
+		return err
+	}
+
+	l := logger.WithValues(logKeyURL, info.url)
+	l.V(logs.LogInfo).Info("send loki log lines")
+
+	pushes, err := getLokiPushes(cleaner.Name, reportSpec, message, notification.Metadata, time.Now())
+	if err != nil {
+		l.Error(err, "failed to prepare loki push request")
+		return err
+	}
+
+	for i := range pushes {
+		if err := postLokiPush(ctx, info, pushes[i]); err != nil {
+			err = fmt.Errorf("push request %d of %d failed: %w", i+1, len(pushes), err)
+			l.Error(err, logMsgSendFailed)
+			return err
+		}
+	}
+
+	l.V(logs.LogDebug).Info("loki log lines sent", "requests", len(pushes))
+	return nil
+}
+
+func postLokiPush(ctx context.Context, info *lokiInfo, push *lokiPush) error {
+	data, err := json.Marshal(push)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, info.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if info.tenantID != "" {
+		req.Header.Set("X-Scope-OrgID", info.tenantID)
+	}
+	if info.username != "" {
+		req.SetBasicAuth(info.username, info.password)
+	}
+
+	resp, err := newNotificationTLSHTTPClient(lokiRequestTimeout, info.tlsConfig).Do(req)
+	if err != nil {
+		return getTLSError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxLokiResponseBody))
+		return fmt.Errorf("loki returned %s: %s", resp.Status, string(body))
+	}
+	return nil
+}
+
+// getLokiPushes returns the push requests sending reportSpec to Loki.
+// Labels are limited to cleaner, action and namespace, so the number of streams
+// stays low: resources are not labels, but fields of the log lines. The line
+// summarizing the run, and lines of cluster wide resources, have no namespace
+// label. Lines are timestamped one nanosecond apart, starting at now, so they
+// are kept in order by Loki.
+func getLokiPushes(cleanerName string, reportSpec *appsv1alpha1.ReportSpec, message string,
+	metadata map[string]string, now time.Time) ([]*lokiPush, error) {
+
+	labels := map[string]string{lokiLabelCleaner: cleanerName}
+	if reportSpec.Action != "" {
+		labels[lokiLabelAction] = string(reportSpec.Action)
+	}
+
+	var pushes []*lokiPush
+	var push *lokiPush
+	var streams map[string]*lokiStream
+	entries := 0
+	timestamp := now.UnixNano()
+
+	add := func(namespace string, line interface{}) error {
+		data, err := json.Marshal(line)
+		if err != nil {
+			return err
+		}
+
+		if push == nil || entries == lokiMaxEntriesPerPush {
+			push = &lokiPush{}
+			pushes = append(pushes, push)
+			streams = map[string]*lokiStream{}
+			entries = 0
+		}
+
+		stream, ok := streams[namespace]
+		if !ok {
+			stream = &lokiStream{Stream: getLokiStreamLabels(labels, namespace)}
+			streams[namespace] = stream
+			push.Streams = append(push.Streams, stream)
+		}
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(timestamp, 10), string(data)})
+		timestamp++
+		entries++
+		return nil
+	}
+
+	if err := add("", &lokiReportLine{
+		Message:  message,
+		RunID:    reportSpec.RunID,
+		Summary:  reportSpec.Summary,
+		Error:    reportSpec.Error,
+		Metadata: metadata,
+	}); err != nil {
+		return nil, err
+	}
+
+	for i := range reportSpec.ResourceInfo {
+		info := &reportSpec.ResourceInfo[i]
+		if err := add(info.Resource.Namespace, &lokiResourceLine{
+			RunID:      reportSpec.RunID,
+			APIVersion: info.Resource.APIVersion,
+			Kind:       info.Resource.Kind,
+			Namespace:  info.Resource.Namespace,
+			Name:       info.Resource.Name,
+			Outcome:    info.Outcome,
+			Message:    info.Message,
+			Error:      info.Error,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return pushes, nil
+}
+
+// getLokiStreamLabels returns labels with the namespace label added. Loki
+// ignores labels with an empty value, so none is added for an empty namespace.
+func getLokiStreamLabels(labels map[string]string, namespace string) map[string]string {
+	stream := make(map[string]string, len(labels)+1)
+	for key, value := range labels {
+		stream[key] = value
+	}
+	if namespace != "" {
+		stream[lokiLabelNamespace] = namespace
+	}
+	return stream
+}
+
+func getLokiInfo(ctx context.Context, notification *appsv1alpha1.Notification) (*lokiInfo, error) {
+	secret, err := getSecret(ctx, notification)
+	if err != nil {
+		return nil, err
+	}
+
+	url, ok := secret.Data[appsv1alpha1.LokiURL]
+	if !ok || len(url) == 0 {
+		return nil, fmt.Errorf("secret does not contain loki URL")
+	}
+
+	info := &lokiInfo{
+		url:      string(bytes.TrimSpace(url)),
+		tenantID: string(bytes.TrimSpace(secret.Data[appsv1alpha1.LokiTenantID])),
+		username: string(secret.Data[appsv1alpha1.LokiUsername]),
+	}
+	if info.username != "" {
+		password, ok := secret.Data[appsv1alpha1.LokiPassword]
+		if !ok {
+			return nil, fmt.Errorf("secret must contain %s when %s is set",
+				appsv1alpha1.LokiPassword, appsv1alpha1.LokiUsername)
+		}
+		info.password = string(password)
+	}
+
+	info.tlsConfig, err = getNotificationTLSConfig(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// fakeLoki is a Loki push endpoint recording the streams of every request
+type fakeLoki struct {
+	mux      sync.Mutex
+	requests [][]lokiStream
+	headers  []http.Header
+}
+
+func (f *fakeLoki) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	push := struct {
+		Streams []lokiStream `json:"streams"`
+	}{}
+	Expect(json.NewDecoder(r.Body).Decode(&push)).To(Succeed())
+	f.requests = append(f.requests, push.Streams)
+	f.headers = append(f.headers, r.Header.Clone())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// createLokiSecret starts a fake Loki and creates the notification secret
+// pointing to it. extra is added to the secret data.
+func createLokiSecret(extra map[string][]byte) (*corev1.ObjectReference, *fakeLoki) {
+	fake := &fakeLoki{}
+	server := httptest.NewServer(fake)
+	DeferCleanup(server.Close)
+
+	data := map[string][]byte{appsv1alpha1.LokiURL: []byte(server.URL + "/loki/api/v1/push")}
+	for key, value := range extra {
+		data[key] = value
+	}
+	return createNotificationSecret(data), fake
+}
+
+// getLokiLine returns the decoded log line of value
+func getLokiLine(value [2]string) map[string]interface{} {
+	line := map[string]interface{}{}
+	Expect(json.Unmarshal([]byte(value[1]), &line)).To(Succeed())
+	return line
+}
+
+var _ = Describe("Loki", func() {
+	It("sendNotifications pushes a summary line and a line per resource", func() {
+		tenant := randomString()
+		ref, fake := createLokiSecret(map[string][]byte{
+			appsv1alpha1.LokiTenantID: []byte(tenant),
+			appsv1alpha1.LokiUsername: []byte("cleaner"),
+			appsv1alpha1.LokiPassword: []byte("secret"),
+		})
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeLoki, ref)
+		cleaner.Spec.Notifications[0].Metadata = map[string]string{"team": "platform"}
+		namespaced := getResourceResult("ConfigMap", randomString(), randomString())
+		clusterWide := getResourceResult("ClusterRole", "", randomString())
+		runID := randomString()
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{namespaced, clusterWide},
+			cleaner, runID, logr.Discard())).To(Succeed())
+
+		Expect(fake.requests).To(HaveLen(1))
+		Expect(fake.headers[0].Get("X-Scope-OrgID")).To(Equal(tenant))
+		Expect(fake.headers[0].Get("Authorization")).To(HavePrefix("Basic "))
+
+		streams := fake.requests[0]
+		Expect(streams).To(HaveLen(2))
+		Expect(streams[0].Stream).To(Equal(map[string]string{
+			"cleaner": cleaner.Name,
+			"action":  string(appsv1alpha1.ActionDelete),
+		}))
+		Expect(streams[0].Values).To(HaveLen(2))
+		summary := getLokiLine(streams[0].Values[0])
+		Expect(summary["message"]).To(HavePrefix("k8s-cleaner '" + cleaner.Name + "' performed Delete on 2 resources"))
+		Expect(summary["runID"]).To(Equal(runID))
+		Expect(summary["metadata"]).To(Equal(map[string]interface{}{"team": "platform"}))
+		Expect(getLokiLine(streams[0].Values[1])["name"]).To(Equal(clusterWide.Resource.GetName()))
+
+		Expect(streams[1].Stream).To(Equal(map[string]string{
+			"cleaner":   cleaner.Name,
+			"action":    string(appsv1alpha1.ActionDelete),
+			"namespace": namespaced.Resource.GetNamespace(),
+		}))
+		Expect(streams[1].Values).To(HaveLen(1))
+		line := getLokiLine(streams[1].Values[0])
+		Expect(line["kind"]).To(Equal("ConfigMap"))
+		Expect(line["name"]).To(Equal(namespaced.Resource.GetName()))
+		Expect(line["runID"]).To(Equal(runID))
+
+		// Lines are timestamped in the order they are pushed
+		first, err := strconv.ParseInt(streams[0].Values[1][0], 10, 64)
+		Expect(err).To(BeNil())
+		second, err := strconv.ParseInt(streams[1].Values[0][0], 10, 64)
+		Expect(err).To(BeNil())
+		Expect(first).To(BeNumerically("<", second))
+	})
+
+	It("sendNotifications splits large reports into multiple push requests", func() {
+		ref, fake := createLokiSecret(nil)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeLoki, ref)
+		namespace := randomString()
+		resources := make([]executor.ResourceResult, 1200)
+		for i := range resources {
+			resources[i] = getResourceResult("ConfigMap", namespace, randomString())
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.requests).To(HaveLen(2))
+		lines := 0
+		for _, streams := range fake.requests {
+			for i := range streams {
+				lines += len(streams[i].Values)
+			}
+		}
+		Expect(lines).To(Equal(len(resources) + 1))
+	})
+
+	It("sendNotifications returns Loki response body on non-2xx status", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("entry too far behind"))
+		}))
+		DeferCleanup(server.Close)
+
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.LokiURL: []byte(server.URL),
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeLoki, ref)
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("400"))
+		Expect(err.Error()).To(ContainSubstring("entry too far behind"))
+	})
+
+	It("sendNotifications requires a password when a username is set", func() {
+		ref, fake := createLokiSecret(map[string][]byte{
+			appsv1alpha1.LokiUsername: []byte("cleaner"),
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeLoki, ref)
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring(appsv1alpha1.LokiPassword))
+		Expect(fake.requests).To(BeEmpty())
+	})
+})
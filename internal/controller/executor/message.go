@@ -0,0 +1,109 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+// defaultMessageTemplate is the controller wide template of the text sent along
+// with reports, used by notifications without a MessageTemplate. When nil, the
+// built-in message is used.
+var defaultMessageTemplate *template.Template
+
+// messageTemplateData is the data message templates are evaluated against
+type messageTemplateData struct {
+	Cleaner string
+	Action  appsv1alpha1.Action
+	Count   int
+	RunID   string
+}
+
+// SetDefaultMessageTemplate sets the Go template of the text sent along with
+// reports by notifications which do not define their own MessageTemplate.
+// An empty text restores the built-in message.
+func SetDefaultMessageTemplate(text string) error {
+	if text == "" {
+		defaultMessageTemplate = nil
+		return nil
+	}
+
+	tmpl, err := parseMessageTemplate(text)
+	if err != nil {
+		return err
+	}
+	defaultMessageTemplate = tmpl
+	return nil
+}
+
+func parseMessageTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("message").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// getNotificationMessage returns the text sent along with the report of a run
+// by notification. The MessageTemplate of notification is used if set, then the
+// controller default template. If neither is set, or the template yields an
+// empty string, the built-in message is returned.
+func getNotificationMessage(cleanerName string, action appsv1alpha1.Action, resourceCount int, runID string,
+	notification *appsv1alpha1.Notification) (string, error) {
+
+	tmpl := defaultMessageTemplate
+	if notification.MessageTemplate != "" {
+		var err error
+		if tmpl, err = parseMessageTemplate(notification.MessageTemplate); err != nil {
+			return "", err
+		}
+	}
+	if tmpl == nil {
+		return getReportMessage(cleanerName, action, resourceCount, runID), nil
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, messageTemplateData{
+		Cleaner: cleanerName,
+		Action:  action,
+		Count:   resourceCount,
+		RunID:   runID,
+	}); err != nil {
+		return "", fmt.Errorf("failed to evaluate message template: %w", err)
+	}
+
+	message := strings.TrimSpace(buf.String())
+	if message == "" {
+		return getReportMessage(cleanerName, action, resourceCount, runID), nil
+	}
+	return message, nil
+}
+
+// getReasonMessage returns message prefixed with the reason of the Cleaner, so
+// the reason is the first thing read. message is returned unchanged when the
+// Cleaner has no reason.
+func getReasonMessage(reason, message string) string {
+	if reason == "" {
+		return message
+	}
+	return fmt.Sprintf("[%s] %s", reason, message)
+}
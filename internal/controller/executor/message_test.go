@@ -0,0 +1,91 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+// setDefaultMessageTemplate sets the controller default message template for
+// the duration of the spec
+func setDefaultMessageTemplate(text string) {
+	Expect(executor.SetDefaultMessageTemplate(text)).To(Succeed())
+	DeferCleanup(func() {
+		Expect(executor.SetDefaultMessageTemplate("")).To(Succeed())
+	})
+}
+
+var _ = Describe("Message template", func() {
+	It("SetDefaultMessageTemplate rejects invalid templates", func() {
+		Expect(executor.SetDefaultMessageTemplate("{{ .Cleaner")).ToNot(Succeed())
+	})
+
+	It("sendNotifications uses the controller default message template", func() {
+		setDefaultMessageTemplate("[{{ .Action }}] {{ .Cleaner }}: {{ .Count }} resource(s), run {{ .RunID }}")
+		ref, fake := createSlackSecret()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		runID := randomString()
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, runID, logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(HavePrefix("[Delete] " + cleaner.Name + ": 1 resource(s), run " + runID + "\n"))
+	})
+
+	It("sendNotifications uses the notification message template over the default one", func() {
+		setDefaultMessageTemplate("default {{ .Cleaner }}")
+		ref, fake := createSlackSecret()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications[0].MessageTemplate = "custom {{ .Cleaner }}"
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(HavePrefix("custom " + cleaner.Name + "\n"))
+	})
+
+	It("sendNotifications uses the built-in message when template yields an empty string", func() {
+		ref, fake := createSlackSecret()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications[0].MessageTemplate = "{{ if gt .Count 10 }}many{{ end }}"
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(HavePrefix("k8s-cleaner '" + cleaner.Name + "' performed Delete on 0 resources"))
+	})
+
+	It("sendNotifications returns an error when the notification message template is invalid", func() {
+		ref, fake := createSlackSecret()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications[0].MessageTemplate = "{{ .Unknown }}"
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("message template"))
+		Expect(fake.values).To(BeEmpty())
+	})
+})
@@ -0,0 +1,164 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"fmt"
+	"regexp"
+
+	corev1 "k8s.io/api/core/v1"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+// namespaceRoute is a compiled NamespaceRoute
+type namespaceRoute struct {
+	namespace *regexp.Regexp
+	ref       *corev1.ObjectReference
+	// secret identifies the Secret of the route, an empty string for the
+	// NotificationRef of the notification
+	secret string
+}
+
+// namespaceRouteMatch keeps the resources whose namespace is routed to secret,
+// an empty string for the resources matching no route
+type namespaceRouteMatch struct {
+	routes []namespaceRoute
+	secret string
+}
+
+func (m *namespaceRouteMatch) matches(namespace string) bool {
+	return getRoutedSecret(m.routes, namespace) == m.secret
+}
+
+// namespaceRouteTarget is a notification a report is sent to, along with the
+// filter of the resources it lists
+type namespaceRouteTarget struct {
+	notification *appsv1alpha1.Notification
+	filter       *resourceFilter
+	secret       string
+}
+
+// getRoutedSecret returns the Secret of the first route matching namespace,
+// an empty string if none does
+func getRoutedSecret(routes []namespaceRoute, namespace string) string {
+	for i := range routes {
+		if routes[i].namespace.MatchString(namespace) {
+			return routes[i].secret
+		}
+	}
+	return ""
+}
+
+// getNamespaceRoutes compiles the NamespaceRoutes of notification. Nil is
+// returned when notification has none.
+func getNamespaceRoutes(notification *appsv1alpha1.Notification) ([]namespaceRoute, error) {
+	if len(notification.NamespaceRoutes) == 0 {
+		return nil, nil
+	}
+	// Digests and queued reports are stored per notification, so they cannot
+	// be split by Secret
+	switch {
+	case notification.Type == appsv1alpha1.NotificationTypeCleanerReport,
+		notification.Type == appsv1alpha1.NotificationTypeEvent:
+		return nil, fmt.Errorf("namespaceRoutes is not supported by %s notifications", notification.Type)
+	case isDigestNotification(notification):
+		return nil, fmt.Errorf("namespaceRoutes is not supported along with digest")
+	case isQueueWindowNotification(notification):
+		return nil, fmt.Errorf("namespaceRoutes is not supported along with an active window with %s policy",
+			appsv1alpha1.NotificationWindowPolicyQueue)
+	}
+
+	defaultSecret := ""
+	if notification.NotificationRef != nil {
+		defaultSecret = getNamespaceRouteSecret(notification.NotificationRef)
+	}
+	routes := make([]namespaceRoute, len(notification.NamespaceRoutes))
+	for i := range notification.NamespaceRoutes {
+		route := &notification.NamespaceRoutes[i]
+		namespace, err := compileWholeValueRegexp(route.Namespace)
+		if err != nil || namespace == nil {
+			return nil, fmt.Errorf("invalid namespaceRoutes[%d] namespace %q: %v", i, route.Namespace, err)
+		}
+		secret := getNamespaceRouteSecret(&route.NotificationRef)
+		if secret == defaultSecret {
+			// Same Secret as the notification
+			secret = ""
+		}
+		routes[i] = namespaceRoute{namespace: namespace, ref: &route.NotificationRef, secret: secret}
+	}
+	return routes, nil
+}
+
+func getNamespaceRouteSecret(ref *corev1.ObjectReference) string {
+	return ref.Namespace + "/" + ref.Name
+}
+
+// getNamespaceRouteTargets returns the notifications the report of a run is sent
+// to. When notification has no NamespaceRoutes, that is notification itself,
+// with filter. Otherwise it is one notification per Secret resources are routed
+// to, whose filter only keeps the resources of that Secret: notification for the
+// resources matching no route, then the notification of each route Secret, in
+// route order. Secrets with no resource are omitted. When no resource is
+// matched, the notification of NotificationRef is the only target.
+func getNamespaceRouteTargets(notification *appsv1alpha1.Notification, filter *resourceFilter,
+	resources []ResourceResult) ([]namespaceRouteTarget, error) {
+
+	routes, err := getNamespaceRoutes(notification)
+	if err != nil {
+		return nil, err
+	}
+	if routes == nil {
+		return []namespaceRouteTarget{{notification: notification, filter: filter}}, nil
+	}
+
+	refs := map[string]*corev1.ObjectReference{"": notification.NotificationRef}
+	secrets := []string{""}
+	for i := range routes {
+		if _, ok := refs[routes[i].secret]; !ok {
+			refs[routes[i].secret] = routes[i].ref
+			secrets = append(secrets, routes[i].secret)
+		}
+	}
+
+	var targets []namespaceRouteTarget
+	var fallback namespaceRouteTarget
+	for _, secret := range secrets {
+		routedFilter := &resourceFilter{}
+		if filter != nil {
+			*routedFilter = *filter
+		}
+		routedFilter.route = &namespaceRouteMatch{routes: routes, secret: secret}
+
+		routed := *notification
+		routed.NotificationRef = refs[secret]
+		routed.NamespaceRoutes = nil
+		target := namespaceRouteTarget{notification: &routed, filter: routedFilter, secret: secret}
+		if secret == "" {
+			fallback = target
+		}
+		if len(filterResourcesByFilter(resources, routedFilter)) > 0 {
+			targets = append(targets, target)
+		}
+	}
+	// Target keeps filtering on the route, so resources of routed namespaces
+	// (for instance of the previous run) are never sent to NotificationRef
+	if len(targets) == 0 {
+		return []namespaceRouteTarget{fallback}, nil
+	}
+	return targets, nil
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+// getRoutedCleaner returns a Cleaner with a Slack notification routing the
+// resources of namespaces matching "team-a.*" and "team-b" to their own Secret.
+// Fake clients are returned by Secret: default, team-a and team-b.
+func getRoutedCleaner() (*appsv1alpha1.Cleaner, map[string]*fakeSlackClient) {
+	fakes := map[string]*fakeSlackClient{}
+	refs := map[string]*corev1.ObjectReference{}
+	tokens := map[string]*fakeSlackClient{}
+	for _, secret := range []string{"default", "team-a", "team-b"} {
+		token := randomString()
+		refs[secret] = createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(token),
+		})
+		fakes[secret] = &fakeSlackClient{}
+		tokens[token] = fakes[secret]
+	}
+	DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+		return tokens[token]
+	}))
+
+	cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, refs["default"])
+	cleaner.Spec.Notifications[0].NamespaceRoutes = []appsv1alpha1.NamespaceRoute{
+		{Namespace: "team-a.*", NotificationRef: *refs["team-a"]},
+		{Namespace: "team-b", NotificationRef: *refs["team-b"]},
+	}
+	return cleaner, fakes
+}
+
+var _ = Describe("Namespace routes", func() {
+	It("sendNotifications sends the resources of each namespace using the Secret of its route", func() {
+		cleaner, fakes := getRoutedCleaner()
+		teamA := getResourceResult("ConfigMap", "team-a-"+randomString(), randomString())
+		teamB := getResourceResult("ConfigMap", "team-b", randomString())
+		other := getResourceResult("ConfigMap", randomString(), randomString())
+		clusterScoped := getResourceResult("ClusterRole", "", randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{teamA, teamB, other, clusterScoped},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		expected := map[string][]executor.ResourceResult{
+			"default": {other, clusterScoped},
+			"team-a":  {teamA},
+			"team-b":  {teamB},
+		}
+		all := []executor.ResourceResult{teamA, teamB, other, clusterScoped}
+		for secret, resources := range expected {
+			fake := fakes[secret]
+			Expect(fake.values).To(HaveLen(1), "secret %s", secret)
+			attachments := fake.values[0].Get("attachments")
+			for i := range all {
+				name := all[i].Resource.GetName()
+				if containsResource(resources, &all[i]) {
+					Expect(attachments).To(ContainSubstring(name), "secret %s", secret)
+				} else {
+					Expect(attachments).ToNot(ContainSubstring(name), "secret %s", secret)
+				}
+			}
+		}
+		Expect(fakes["team-a"].values[0].Get("text")).To(HavePrefix("k8s-cleaner '" + cleaner.Name +
+			"' performed Delete on 1 resource"))
+	})
+
+	It("sendNotifications does not notify Secrets with no resource", func() {
+		cleaner, fakes := getRoutedCleaner()
+		teamB := getResourceResult("ConfigMap", "team-b", randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{teamB},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fakes["default"].values).To(BeEmpty())
+		Expect(fakes["team-a"].values).To(BeEmpty())
+		Expect(fakes["team-b"].values).To(HaveLen(1))
+	})
+
+	It("sendNotifications only notifies the notification Secret when no resource is matched", func() {
+		cleaner, fakes := getRoutedCleaner()
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fakes["default"].values).To(HaveLen(1))
+		Expect(fakes["team-a"].values).To(BeEmpty())
+		Expect(fakes["team-b"].values).To(BeEmpty())
+	})
+
+	It("sendNotifications records a failure when any route fails", func() {
+		cleaner, fakes := getRoutedCleaner()
+		cleaner.Spec.Schedule = "0 * * * *"
+		cleaner.Spec.ResourcePolicySet.ResourceSelectors = []appsv1alpha1.ResourceSelector{
+			{Kind: "ConfigMap", Version: "v1"},
+		}
+		Expect(k8sClient.Create(context.TODO(), cleaner)).To(Succeed())
+		Expect(waitForObject(context.TODO(), k8sClient, cleaner)).To(Succeed())
+		fakes["team-a"].err = errors.New("channel_not_found")
+
+		teamA := getResourceResult("ConfigMap", "team-a-"+randomString(), randomString())
+		teamB := getResourceResult("ConfigMap", "team-b", randomString())
+		err := executor.SendNotifications(context.TODO(), []executor.ResourceResult{teamA, teamB},
+			cleaner, "", logr.Discard())
+		Expect(err).To(MatchError(ContainSubstring("channel_not_found")))
+
+		// Following route is still sent
+		Expect(fakes["team-b"].values).To(HaveLen(1))
+
+		current := &appsv1alpha1.Cleaner{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, current)).To(Succeed())
+		Expect(current.Status.NotificationStatuses).To(HaveLen(1))
+		Expect(current.Status.NotificationStatuses[0].ConsecutiveFailures).To(Equal(int32(1)))
+		Expect(current.Status.NotificationOutcomes).To(HaveLen(1))
+		Expect(current.Status.NotificationOutcomes[0].Outcome).To(Equal(appsv1alpha1.NotificationOutcomeFailed))
+	})
+
+	It("sendNotifications records the batched outcome once every route is batched", func() {
+		enableNotificationBatching()
+		cleaner, fakes := getRoutedCleaner()
+		cleaner.Spec.Schedule = "0 * * * *"
+		cleaner.Spec.ResourcePolicySet.ResourceSelectors = []appsv1alpha1.ResourceSelector{
+			{Kind: "ConfigMap", Version: "v1"},
+		}
+		Expect(k8sClient.Create(context.TODO(), cleaner)).To(Succeed())
+		Expect(waitForObject(context.TODO(), k8sClient, cleaner)).To(Succeed())
+
+		teamA := getResourceResult("ConfigMap", "team-a-"+randomString(), randomString())
+		teamB := getResourceResult("ConfigMap", "team-b", randomString())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{teamA, teamB},
+			cleaner, "", logr.Discard())).To(Succeed())
+		Expect(fakes["team-a"].values).To(BeEmpty())
+		Expect(fakes["team-b"].values).To(BeEmpty())
+
+		current := &appsv1alpha1.Cleaner{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, current)).To(Succeed())
+		Expect(current.Status.NotificationOutcomes).To(HaveLen(1))
+		Expect(current.Status.NotificationOutcomes[0].Outcome).To(Equal(appsv1alpha1.NotificationOutcomeBatched))
+	})
+
+	It("ValidateCleaner reports namespace routes which are invalid or not supported", func() {
+		cleaner, _ := getRoutedCleaner()
+		cleaner.Spec.Notifications[0].NamespaceRoutes[1].Namespace = "team-("
+		Expect(executor.ValidateCleaner(cleaner)).To(MatchError(ContainSubstring(
+			"notification " + cleaner.Spec.Notifications[0].Name + ": invalid namespaceRoutes[1] namespace \"team-(\"")))
+
+		cleaner, _ = getRoutedCleaner()
+		cleaner.Spec.Notifications[0].Digest = &appsv1alpha1.DigestOptions{
+			Interval: metav1.Duration{Duration: time.Hour},
+		}
+		Expect(executor.ValidateCleaner(cleaner)).To(MatchError(ContainSubstring(
+			"namespaceRoutes is not supported along with digest")))
+	})
+})
+
+func containsResource(resources []executor.ResourceResult, resource *executor.ResourceResult) bool {
+	for i := range resources {
+		if resources[i].Resource.GetName() == resource.Resource.GetName() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"crypto/tls"
+
+	goteamsnotify "github.com/atc0005/go-teams-notify/v2"
+	"github.com/bwmarrin/discordgo"
+	"github.com/go-resty/resty/v2"
+	webexteams "github.com/jbogarin/go-cisco-webex-teams/sdk"
+	"github.com/slack-go/slack"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+// slackClient is the subset of the Slack API used to deliver notifications
+type slackClient interface {
+	PostMessage(channelID string, options ...slack.MsgOption) (string, string, error)
+	UploadFileV2Context(ctx context.Context, params slack.UploadFileV2Parameters) (*slack.FileSummary, error)
+	GetConversationsContext(ctx context.Context, params *slack.GetConversationsParameters) ([]slack.Channel, string, error)
+	GetPermalinkContext(ctx context.Context, params *slack.PermalinkParameters) (string, error)
+}
+
+// teamsClient is the subset of the Teams API used to deliver notifications
+type teamsClient interface {
+	ValidateWebhook(webhookURL string) error
+	Send(webhookURL string, message goteamsnotify.TeamsMessage) error
+}
+
+// discordClient is the subset of the Discord API used to deliver notifications
+type discordClient interface {
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend,
+		options ...discordgo.RequestOption) (*discordgo.Message, error)
+}
+
+// webexClient is the subset of the Webex API used to deliver notifications
+type webexClient interface {
+	CreateMessage(messageCreateRequest *webexteams.MessageCreateRequest) (*webexteams.Message, *resty.Response, error)
+	ListRooms() ([]webexteams.Room, error)
+}
+
+// mailer sends emails
+type mailer interface {
+	SendMail(subject, message string, sendAsHtml bool, attachments ...mailAttachment) error
+}
+
+// Factories used to create the clients for each notification type.
+// Those are variables so that tests can replace them with fakes.
+var (
+	newSlackClient = func(token string) slackClient {
+		return slack.New(token, slack.OptionHTTPClient(newNotificationHTTPClient(0)))
+	}
+
+	newTeamsClient = func(tlsConfig *tls.Config) teamsClient {
+		// Send timeout is enforced by the Teams client using a context
+		return goteamsnotify.NewTeamsClient().SetHTTPClient(newNotificationTLSHTTPClient(0, tlsConfig))
+	}
+
+	newDiscordClient = func(token string) (discordClient, error) {
+		session, err := discordgo.New("Bot " + token)
+		if err != nil {
+			return nil, err
+		}
+		session.Client = newNotificationHTTPClient(session.Client.Timeout)
+		return session, nil
+	}
+
+	newWebexClient = func(token string) webexClient {
+		return newWebexMessagesClient(token)
+	}
+
+	newMailer = func(ctx context.Context, notification *appsv1alpha1.Notification) (mailer, error) {
+		return newSmtpMailer(ctx, notification)
+	}
+)
@@ -0,0 +1,284 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/url"
+	"strings"
+	"time"
+
+	goteamsnotify "github.com/atc0005/go-teams-notify/v2"
+	"github.com/bwmarrin/discordgo"
+	"github.com/go-resty/resty/v2"
+	webexteams "github.com/jbogarin/go-cisco-webex-teams/sdk"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+// fakeSlackClient records every message posted and every file uploaded.
+// Channels are returned when listing conversations, and conversationCalls
+// counts how many times they were listed. permalinkErr, when set, is returned
+// when getting the permalink of a message.
+type fakeSlackClient struct {
+	channelIDs        []string
+	values            []url.Values
+	uploads           []slack.UploadFileV2Parameters
+	channels          []slack.Channel
+	conversationCalls int
+	err               error
+	uploadErr         error
+	permalinkErr      error
+}
+
+func (f *fakeSlackClient) PostMessage(channelID string, options ...slack.MsgOption) (string, string, error) {
+	_, values, err := slack.UnsafeApplyMsgOptions("", channelID, "", options...)
+	if err != nil {
+		return "", "", err
+	}
+	f.channelIDs = append(f.channelIDs, channelID)
+	f.values = append(f.values, values)
+	if f.err != nil {
+		return "", "", f.err
+	}
+	return channelID, "1700000000.000100", nil
+}
+
+func (f *fakeSlackClient) GetConversationsContext(ctx context.Context, params *slack.GetConversationsParameters,
+) ([]slack.Channel, string, error) {
+
+	f.conversationCalls++
+	return f.channels, "", nil
+}
+
+func (f *fakeSlackClient) GetPermalinkContext(ctx context.Context, params *slack.PermalinkParameters,
+) (string, error) {
+
+	if f.permalinkErr != nil {
+		return "", f.permalinkErr
+	}
+	return fmt.Sprintf("https://example.slack.com/archives/%s/p%s", params.Channel,
+		strings.ReplaceAll(params.Ts, ".", "")), nil
+}
+
+func (f *fakeSlackClient) UploadFileV2Context(ctx context.Context, params slack.UploadFileV2Parameters,
+) (*slack.FileSummary, error) {
+
+	f.uploads = append(f.uploads, params)
+	if f.uploadErr != nil {
+		return nil, f.uploadErr
+	}
+	return &slack.FileSummary{ID: "F0000000001", Title: params.Title}, nil
+}
+
+// fakeTeamsClient records every message sent
+type fakeTeamsClient struct {
+	webhookURLs []string
+	messages    []goteamsnotify.TeamsMessage
+	err         error
+}
+
+func (f *fakeTeamsClient) ValidateWebhook(webhookURL string) error {
+	return nil
+}
+
+func (f *fakeTeamsClient) Send(webhookURL string, message goteamsnotify.TeamsMessage) error {
+	f.webhookURLs = append(f.webhookURLs, webhookURL)
+	f.messages = append(f.messages, message)
+	return f.err
+}
+
+// fakeDiscordClient records every message sent. Content of attached files is
+// read and stored in files, readers of attached files in readers
+type fakeDiscordClient struct {
+	channelIDs []string
+	messages   []*discordgo.MessageSend
+	files      [][]byte
+	readers    []io.Reader
+	err        error
+	// delay, if set, is how long each message takes to be sent
+	delay time.Duration
+	// failOnce, if set, maps the number of messages sent so far to the error
+	// returned, only once, instead of sending the next message
+	failOnce map[int]error
+}
+
+func (f *fakeDiscordClient) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend,
+	options ...discordgo.RequestOption) (*discordgo.Message, error) {
+
+	time.Sleep(f.delay)
+	if err, ok := f.failOnce[len(f.messages)]; ok {
+		delete(f.failOnce, len(f.messages))
+		return nil, err
+	}
+	f.channelIDs = append(f.channelIDs, channelID)
+	f.messages = append(f.messages, data)
+	for i := range data.Files {
+		content, err := io.ReadAll(data.Files[i].Reader)
+		Expect(err).To(BeNil())
+		f.files = append(f.files, content)
+		f.readers = append(f.readers, data.Files[i].Reader)
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &discordgo.Message{ChannelID: channelID}, nil
+}
+
+// fakeWebexClient records every message sent. Content of attached files is
+// read and stored in files, readers of attached files in readers. Rooms are
+// returned when listing rooms, and listCalls counts how many times they were listed.
+type fakeWebexClient struct {
+	requests  []*webexteams.MessageCreateRequest
+	files     [][]byte
+	readers   []io.Reader
+	rooms     []webexteams.Room
+	listCalls int
+	err       error
+}
+
+func (f *fakeWebexClient) CreateMessage(messageCreateRequest *webexteams.MessageCreateRequest,
+) (*webexteams.Message, *resty.Response, error) {
+
+	f.requests = append(f.requests, messageCreateRequest)
+	for i := range messageCreateRequest.Files {
+		content, err := io.ReadAll(messageCreateRequest.Files[i].Reader)
+		Expect(err).To(BeNil())
+		f.files = append(f.files, content)
+		f.readers = append(f.readers, messageCreateRequest.Files[i].Reader)
+	}
+	if f.err != nil {
+		return nil, nil, f.err
+	}
+	return &webexteams.Message{RoomID: messageCreateRequest.RoomID}, nil, nil
+}
+
+func (f *fakeWebexClient) ListRooms() ([]webexteams.Room, error) {
+	f.listCalls++
+	return f.rooms, nil
+}
+
+// fakeMailer records every email sent
+type fakeMailer struct {
+	subjects    []string
+	bodies      []string
+	attachments [][]executor.MailAttachment
+	err         error
+}
+
+func (f *fakeMailer) SendMail(subject, message string, sendAsHtml bool,
+	attachments ...executor.MailAttachment) error {
+
+	f.subjects = append(f.subjects, subject)
+	f.bodies = append(f.bodies, message)
+	f.attachments = append(f.attachments, attachments)
+	return f.err
+}
+
+// useFakeDiscordClient makes Discord notifications, till the end of the spec,
+// send their messages to fake
+func useFakeDiscordClient(fake *fakeDiscordClient) {
+	DeferCleanup(executor.SetDiscordClientFactory(func(token string) (executor.DiscordClient, error) {
+		return fake, nil
+	}))
+}
+
+// useFakeWebexClient makes Webex notifications, till the end of the spec,
+// send their messages to fake
+func useFakeWebexClient(fake *fakeWebexClient) {
+	DeferCleanup(executor.SetWebexClientFactory(func(token string) executor.WebexClient {
+		return fake
+	}))
+}
+
+// useFakeMailer makes SMTP notifications, till the end of the spec, send
+// their emails to fake
+func useFakeMailer(fake *fakeMailer) {
+	DeferCleanup(executor.SetMailerFactory(func(ctx context.Context,
+		notification *appsv1alpha1.Notification) (executor.Mailer, error) {
+
+		return fake, nil
+	}))
+}
+
+// createNotificationSecret creates a namespace and a Secret in it containing data.
+// It returns a reference to the Secret.
+func createNotificationSecret(data map[string][]byte) *corev1.ObjectReference {
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: randomString(),
+		},
+	}
+	Expect(k8sClient.Create(context.TODO(), ns)).To(Succeed())
+	Expect(waitForObject(context.TODO(), k8sClient, ns)).To(Succeed())
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      randomString(),
+			Namespace: ns.Name,
+		},
+		Data: data,
+	}
+	Expect(k8sClient.Create(context.TODO(), secret)).To(Succeed())
+	Expect(waitForObject(context.TODO(), k8sClient, secret)).To(Succeed())
+
+	return &corev1.ObjectReference{
+		Kind:       "Secret",
+		APIVersion: "v1",
+		Namespace:  secret.Namespace,
+		Name:       secret.Name,
+	}
+}
+
+// randomDiscordID returns a random Discord snowflake
+func randomDiscordID() string {
+	const idLength = 18
+	digits := make([]byte, idLength)
+	for i := range digits {
+		digits[i] = byte('0' + rand.Intn(10))
+	}
+	digits[0] = '1'
+	return string(digits)
+}
+
+// randomWebexRoomID returns a random Webex room ID
+func randomWebexRoomID() string {
+	return base64.RawStdEncoding.EncodeToString([]byte("ciscospark://us/ROOM/" + randomString()))
+}
+
+// randomSlackChannelID returns a random Slack public channel ID
+func randomSlackChannelID() string {
+	return "C" + strings.ToUpper(randomString())
+}
+
+// getSlackChannel returns a Slack channel the bot is a member of
+func getSlackChannel(id, name string) slack.Channel {
+	channel := slack.Channel{IsMember: true}
+	channel.ID = id
+	channel.Name = name
+	return channel
+}
@@ -0,0 +1,396 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+
+	goteamsnotify "github.com/atc0005/go-teams-notify/v2"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+// recordingServer is an HTTP server recording the body of every request.
+// Each request is answered with reply.
+type recordingServer struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	bodies []string
+}
+
+func newRecordingServer(reply string) *recordingServer {
+	s := &recordingServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		Expect(err).To(BeNil())
+		s.mu.Lock()
+		s.bodies = append(s.bodies, string(body))
+		s.mu.Unlock()
+		_, _ = w.Write([]byte(reply))
+	}))
+	DeferCleanup(s.Close)
+	return s
+}
+
+func (s *recordingServer) getBodies() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bodies
+}
+
+// notificationFlow contains the fakes every notification type of a Cleaner
+// created by newNotificationFlow delivers to
+type notificationFlow struct {
+	slackMessages []slack.WebhookMessage
+	teams         *recordingServer
+	discord       *fakeDiscordClient
+	webex         *fakeWebexClient
+	mailer        *fakeMailer
+	splunk        *recordingServer
+	cloudEvents   *recordingServer
+	victorOps     *recordingServer
+	s3Uploads     []s3Upload
+	smsMessages   []twilioMessage
+	redis         *fakeRedis
+	recorder      *record.FakeRecorder
+	fileDir       string
+}
+
+// newNotificationFlow returns a Cleaner instance with a notification of each type.
+// Every notification is pointed at a fake server or client recording what is
+// delivered.
+func newNotificationFlow() (*appsv1alpha1.Cleaner, *notificationFlow) {
+	flow := &notificationFlow{recorder: record.NewFakeRecorder(10)}
+	DeferCleanup(executor.SetEventRecorder(flow.recorder))
+
+	notifications := []appsv1alpha1.Notification{
+		newFlowNotification(appsv1alpha1.NotificationTypeCleanerReport, nil),
+		flow.newSlackNotification(),
+		flow.newTeamsNotification(),
+		flow.newDiscordNotification(),
+		flow.newWebexNotification(),
+		flow.newSMTPNotification(),
+		flow.newSplunkNotification(),
+		newFlowNotification(appsv1alpha1.NotificationTypeEvent, nil),
+		flow.newFileNotification(),
+		flow.newCloudEventsNotification(),
+		flow.newVictorOpsNotification(),
+		flow.newS3Notification(),
+		flow.newSMSNotification(),
+		flow.newRedisNotification(),
+	}
+
+	namespace := createNamespace()
+	cleaner := &appsv1alpha1.Cleaner{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: randomString(),
+		},
+		Spec: appsv1alpha1.CleanerSpec{
+			Schedule: "0 * * * *",
+			Action:   appsv1alpha1.ActionScan,
+			ResourcePolicySet: appsv1alpha1.ResourcePolicySet{
+				ResourceSelectors: []appsv1alpha1.ResourceSelector{
+					{
+						Namespace: namespace,
+						Kind:      "ConfigMap",
+						Group:     "",
+						Version:   "v1",
+					},
+				},
+			},
+			Notifications: notifications,
+		},
+	}
+	Expect(k8sClient.Create(context.TODO(), cleaner)).To(Succeed())
+	Expect(waitForObject(context.TODO(), k8sClient, cleaner)).To(Succeed())
+
+	return cleaner, flow
+}
+
+// newFlowNotification returns a notification of the given type. When data is set,
+// the notification references a Secret containing it.
+func newFlowNotification(notificationType appsv1alpha1.NotificationType,
+	data map[string][]byte) appsv1alpha1.Notification {
+
+	n := appsv1alpha1.Notification{Name: randomString(), Type: notificationType}
+	if data != nil {
+		n.NotificationRef = createNotificationSecret(data)
+	}
+	return n
+}
+
+// newSlackNotification returns a Slack notification posting to a fake webhook
+func (f *notificationFlow) newSlackNotification() appsv1alpha1.Notification {
+	server := newSlackWebhookServer(&f.slackMessages)
+	return newFlowNotification(appsv1alpha1.NotificationTypeSlack, map[string][]byte{
+		appsv1alpha1.SlackWebhookURL: []byte(server.URL),
+	})
+}
+
+// newTeamsNotification returns a Teams notification posting to a recording server
+func (f *notificationFlow) newTeamsNotification() appsv1alpha1.Notification {
+	f.teams = newRecordingServer(goteamsnotify.ExpectedWebhookURLResponseText)
+	DeferCleanup(executor.SetTeamsClientFactory(func() executor.TeamsClient {
+		return goteamsnotify.NewTeamsClient().SkipWebhookURLValidationOnSend(true)
+	}))
+	return newFlowNotification(appsv1alpha1.NotificationTypeTeams, map[string][]byte{
+		libsveltosv1alpha1.TeamsWebhookURL: []byte(f.teams.URL),
+	})
+}
+
+// newDiscordNotification returns a Discord notification sent with f.discord
+func (f *notificationFlow) newDiscordNotification() appsv1alpha1.Notification {
+	f.discord = &fakeDiscordClient{}
+	useFakeDiscordClient(f.discord)
+	return newFlowNotification(appsv1alpha1.NotificationTypeDiscord, map[string][]byte{
+		libsveltosv1alpha1.DiscordChannelID: []byte(randomDiscordID()),
+		libsveltosv1alpha1.DiscordToken:     []byte(randomString()),
+	})
+}
+
+// newWebexNotification returns a Webex notification sent with f.webex
+func (f *notificationFlow) newWebexNotification() appsv1alpha1.Notification {
+	f.webex = &fakeWebexClient{}
+	useFakeWebexClient(f.webex)
+	return newFlowNotification(appsv1alpha1.NotificationTypeWebex, map[string][]byte{
+		libsveltosv1alpha1.WebexRoomID: []byte(randomWebexRoomID()),
+		libsveltosv1alpha1.WebexToken:  []byte(randomString()),
+	})
+}
+
+// newSMTPNotification returns a SMTP notification sent with f.mailer
+func (f *notificationFlow) newSMTPNotification() appsv1alpha1.Notification {
+	f.mailer = &fakeMailer{}
+	useFakeMailer(f.mailer)
+	return newFlowNotification(appsv1alpha1.NotificationTypeSMTP, nil)
+}
+
+// newSplunkNotification returns a Splunk HEC notification posting to a recording server
+func (f *notificationFlow) newSplunkNotification() appsv1alpha1.Notification {
+	f.splunk = newRecordingServer(`{"text":"Success","code":0}`)
+	return newFlowNotification(appsv1alpha1.NotificationTypeSplunkHEC, map[string][]byte{
+		appsv1alpha1.SplunkHECURL:   []byte(f.splunk.URL),
+		appsv1alpha1.SplunkHECToken: []byte(randomString()),
+	})
+}
+
+// newFileNotification returns a File notification writing reports to f.fileDir
+func (f *notificationFlow) newFileNotification() appsv1alpha1.Notification {
+	f.fileDir = GinkgoT().TempDir()
+	n := newFlowNotification(appsv1alpha1.NotificationTypeFile, nil)
+	n.File = &appsv1alpha1.FileOptions{Path: f.fileDir}
+	return n
+}
+
+// newCloudEventsNotification returns a CloudEvents notification posting to a recording server
+func (f *notificationFlow) newCloudEventsNotification() appsv1alpha1.Notification {
+	f.cloudEvents = newRecordingServer("")
+	return newFlowNotification(appsv1alpha1.NotificationTypeCloudEvents, map[string][]byte{
+		appsv1alpha1.CloudEventsSinkURL: []byte(f.cloudEvents.URL),
+	})
+}
+
+// newVictorOpsNotification returns a VictorOps notification posting to a recording server
+func (f *notificationFlow) newVictorOpsNotification() appsv1alpha1.Notification {
+	f.victorOps = newRecordingServer(`{"result":"success"}`)
+	DeferCleanup(executor.SetVictorOpsURL(f.victorOps.URL))
+	return newFlowNotification(appsv1alpha1.NotificationTypeVictorOps, map[string][]byte{
+		appsv1alpha1.VictorOpsAPIKey:     []byte(randomString()),
+		appsv1alpha1.VictorOpsRoutingKey: []byte(randomString()),
+	})
+}
+
+// newS3Notification returns a S3 notification uploading to a fake S3 server
+func (f *notificationFlow) newS3Notification() appsv1alpha1.Notification {
+	server := newS3Server(&f.s3Uploads)
+	return newFlowNotification(appsv1alpha1.NotificationTypeS3, map[string][]byte{
+		appsv1alpha1.S3Bucket:           []byte(randomString()),
+		appsv1alpha1.S3Region:           []byte("eu-west-1"),
+		appsv1alpha1.S3Endpoint:         []byte(server.URL),
+		appsv1alpha1.AWSAccessKeyID:     []byte(randomString()),
+		appsv1alpha1.AWSSecretAccessKey: []byte(randomString()),
+	})
+}
+
+// newSMSNotification returns a SMS notification sent through a fake Twilio server
+func (f *notificationFlow) newSMSNotification() appsv1alpha1.Notification {
+	newTwilioServer(&f.smsMessages)
+	return newFlowNotification(appsv1alpha1.NotificationTypeSMS, map[string][]byte{
+		appsv1alpha1.TwilioAccountSID: []byte("AC" + randomString()),
+		appsv1alpha1.TwilioAuthToken:  []byte(randomString()),
+		appsv1alpha1.TwilioFromNumber: []byte("+15005550006"),
+		appsv1alpha1.TwilioToNumbers:  []byte("+15005550001"),
+	})
+}
+
+// newRedisNotification returns a Redis notification publishing to a fake Redis server
+func (f *notificationFlow) newRedisNotification() appsv1alpha1.Notification {
+	var address string
+	f.redis, address = newFakeRedis(redisOK)
+	return newFlowNotification(appsv1alpha1.NotificationTypeRedis, map[string][]byte{
+		appsv1alpha1.RedisAddress: []byte(address),
+		appsv1alpha1.RedisKey:     []byte(randomString()),
+	})
+}
+
+func createNamespace() string {
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: randomString(),
+		},
+	}
+	Expect(k8sClient.Create(context.TODO(), ns)).To(Succeed())
+	Expect(waitForObject(context.TODO(), k8sClient, ns)).To(Succeed())
+	return ns.Name
+}
+
+func createConfigMap(namespace string) *corev1.ConfigMap {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      randomString(),
+		},
+	}
+	Expect(k8sClient.Create(context.TODO(), configMap)).To(Succeed())
+	Expect(waitForObject(context.TODO(), k8sClient, configMap)).To(Succeed())
+	return configMap
+}
+
+// expectReport verifies the Report instance of cleaner lists configMaps and
+// refers to runID
+func expectReport(cleaner *appsv1alpha1.Cleaner, runID string, configMaps ...*corev1.ConfigMap) {
+	report := &appsv1alpha1.Report{}
+	Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, report)).To(Succeed())
+	Expect(report.Spec.Action).To(Equal(appsv1alpha1.ActionScan))
+	Expect(report.Spec.RunID).To(Equal(runID))
+	Expect(report.Spec.ResourceInfo).To(HaveLen(len(configMaps)))
+	names := make([]string, len(report.Spec.ResourceInfo))
+	for i := range report.Spec.ResourceInfo {
+		names[i] = report.Spec.ResourceInfo[i].Resource.Name
+	}
+	for i := range configMaps {
+		Expect(names).To(ContainElement(configMaps[i].Name))
+	}
+}
+
+var _ = Describe("Notification flow", func() {
+	It("processCleanerInstance delivers the report to every notification type", func() {
+		cleaner, flow := newNotificationFlow()
+		namespace := cleaner.Spec.ResourcePolicySet.ResourceSelectors[0].Namespace
+		configMaps := []*corev1.ConfigMap{createConfigMap(namespace), createConfigMap(namespace)}
+		runID := randomString()
+
+		Expect(executor.ProcessCleanerInstance(context.TODO(), cleaner.Name, runID, logr.Discard())).To(Succeed())
+
+		expectReport(cleaner, runID, configMaps...)
+
+		Expect(flow.slackMessages).To(HaveLen(1))
+		Expect(flow.slackMessages[0].Text).To(ContainSubstring("*Resources:* 2 (ConfigMap: 2)"))
+
+		Expect(flow.discord.messages).To(HaveLen(1))
+		Expect(flow.discord.messages[0].Content).To(ContainSubstring(cleaner.Name))
+
+		Expect(flow.webex.requests).To(HaveLen(1))
+		Expect(flow.webex.requests[0].Markdown).To(ContainSubstring(cleaner.Name))
+
+		Expect(flow.mailer.subjects).To(HaveLen(1))
+		Expect(flow.mailer.subjects[0]).To(ContainSubstring(cleaner.Name))
+
+		Expect(flow.smsMessages).To(HaveLen(1))
+		Expect(flow.smsMessages[0].body).To(HavePrefix("k8s-cleaner " + cleaner.Name + ": Scan\n" +
+			"Resources: 2 (ConfigMap: 2)"))
+
+		Expect(flow.recorder.Events).To(HaveLen(1))
+		Expect(<-flow.recorder.Events).To(HavePrefix("Normal CleanerReport Action Scan on 2 resource(s)"))
+
+		Expect(flow.s3Uploads).To(HaveLen(1))
+		reportSpec := &appsv1alpha1.ReportSpec{}
+		Expect(json.Unmarshal(flow.s3Uploads[0].body, reportSpec)).To(Succeed())
+		Expect(reportSpec.RunID).To(Equal(runID))
+		Expect(reportSpec.ResourceInfo).To(HaveLen(2))
+
+		files := listFiles(flow.fileDir)
+		Expect(files).To(HaveLen(1))
+		data, err := os.ReadFile(filepath.Join(flow.fileDir, files[0]))
+		Expect(err).To(BeNil())
+		reportSpec = &appsv1alpha1.ReportSpec{}
+		Expect(json.Unmarshal(data, reportSpec)).To(Succeed())
+		Expect(reportSpec.RunID).To(Equal(runID))
+		Expect(reportSpec.ResourceInfo).To(HaveLen(2))
+
+		commands := flow.redis.getCommands()
+		Expect(commands).To(HaveLen(1))
+		Expect(commands[0][0]).To(Equal("XADD"))
+
+		// Payloads of these types contain each resource
+		for _, server := range []*recordingServer{flow.teams, flow.splunk, flow.cloudEvents, flow.victorOps} {
+			bodies := server.getBodies()
+			Expect(bodies).To(HaveLen(1))
+			for i := range configMaps {
+				Expect(bodies[0]).To(ContainSubstring(configMaps[i].Name))
+			}
+		}
+		Expect(commands[0]).To(ContainElement(ContainSubstring(configMaps[0].Name)))
+
+		// All notifications were delivered
+		currentCleaner := &appsv1alpha1.Cleaner{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, currentCleaner)).To(Succeed())
+		Expect(currentCleaner.Status.NotificationStatuses).To(BeEmpty())
+	})
+
+	It("processCleanerInstance updates the Report on following runs", func() {
+		cleaner, flow := newNotificationFlow()
+		namespace := cleaner.Spec.ResourcePolicySet.ResourceSelectors[0].Namespace
+		configMap := createConfigMap(namespace)
+		runID := randomString()
+
+		Expect(executor.ProcessCleanerInstance(context.TODO(), cleaner.Name, runID, logr.Discard())).To(Succeed())
+		expectReport(cleaner, runID, configMap)
+
+		newConfigMap := createConfigMap(namespace)
+		newRunID := randomString()
+
+		Expect(executor.ProcessCleanerInstance(context.TODO(), cleaner.Name, newRunID, logr.Discard())).To(Succeed())
+		expectReport(cleaner, newRunID, configMap, newConfigMap)
+
+		Expect(flow.slackMessages).To(HaveLen(2))
+		Expect(flow.slackMessages[1].Text).To(ContainSubstring("*Resources:* 2 (ConfigMap: 2)"))
+		Expect(flow.smsMessages).To(HaveLen(2))
+		Expect(flow.smsMessages[1].body).To(ContainSubstring("Run ID: " + newRunID))
+		Expect(flow.teams.getBodies()).To(HaveLen(2))
+		Expect(flow.teams.getBodies()[1]).To(ContainSubstring(newConfigMap.Name))
+	})
+})
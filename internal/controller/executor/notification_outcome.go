@@ -0,0 +1,95 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"sort"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+func getSkippedOutcome(notificationName, reason string) appsv1alpha1.NotificationOutcome {
+	return appsv1alpha1.NotificationOutcome{
+		NotificationName: notificationName,
+		Outcome:          appsv1alpha1.NotificationOutcomeSkipped,
+		Message:          reason,
+	}
+}
+
+// getDeliveryOutcome returns the outcome of a notification which was sent.
+// deliveryErr is nil on success, in which case outcomeType is used.
+func getDeliveryOutcome(notificationName string, outcomeType appsv1alpha1.NotificationOutcomeType,
+	deliveryErr error) appsv1alpha1.NotificationOutcome {
+
+	outcome := appsv1alpha1.NotificationOutcome{
+		NotificationName: notificationName,
+		Outcome:          outcomeType,
+	}
+	if deliveryErr != nil {
+		outcome.Outcome = appsv1alpha1.NotificationOutcomeFailed
+		outcome.Message = truncateString(deliveryErr.Error(), maxNotificationFailureMessageSize)
+	}
+	return outcome
+}
+
+// recordNotificationOutcomes stores outcomes in the Cleaner status, replacing
+// the previous outcome of the same notifications. Outcomes of notifications
+// removed from the spec are dropped. Status is only updated when it changes.
+func recordNotificationOutcomes(ctx context.Context, cleaner *appsv1alpha1.Cleaner,
+	outcomes []appsv1alpha1.NotificationOutcome) error {
+
+	if !isNotificationOutcomeChanged(cleaner, outcomes) {
+		return nil
+	}
+
+	return updateCleanerStatus(ctx, cleaner.Name, func(current *appsv1alpha1.Cleaner) {
+		merged := make(map[string]appsv1alpha1.NotificationOutcome)
+		for i := range current.Status.NotificationOutcomes {
+			merged[current.Status.NotificationOutcomes[i].NotificationName] = current.Status.NotificationOutcomes[i]
+		}
+		for i := range outcomes {
+			merged[outcomes[i].NotificationName] = outcomes[i]
+		}
+
+		result := make([]appsv1alpha1.NotificationOutcome, 0, len(merged))
+		for name := range merged {
+			if hasNotification(current, name) {
+				result = append(result, merged[name])
+			}
+		}
+		sort.Slice(result, func(i, j int) bool {
+			return result[i].NotificationName < result[j].NotificationName
+		})
+		current.Status.NotificationOutcomes = result
+	})
+}
+
+// isNotificationOutcomeChanged returns true if any of outcomes differs from the
+// one in the status of cleaner
+func isNotificationOutcomeChanged(cleaner *appsv1alpha1.Cleaner, outcomes []appsv1alpha1.NotificationOutcome) bool {
+	previous := make(map[string]appsv1alpha1.NotificationOutcome)
+	for i := range cleaner.Status.NotificationOutcomes {
+		previous[cleaner.Status.NotificationOutcomes[i].NotificationName] = cleaner.Status.NotificationOutcomes[i]
+	}
+	for i := range outcomes {
+		if outcome, ok := previous[outcomes[i].NotificationName]; !ok || outcome != outcomes[i] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+var _ = Describe("Notification outcomes", func() {
+	It("sendNotifications sends all notifications and records the outcome of each", func() {
+		recorder := record.NewFakeRecorder(10)
+		DeferCleanup(executor.SetEventRecorder(recorder))
+		ref, fake := createSlackSecret()
+		fake.err = errors.New("channel_not_found")
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Name = "outcome-" + cleaner.Name
+		cleaner.Spec.Schedule = "0 * * * *"
+		cleaner.Spec.ResourcePolicySet.ResourceSelectors = []appsv1alpha1.ResourceSelector{
+			{Kind: "ConfigMap", Version: "v1"},
+		}
+		failing := cleaner.Spec.Notifications[0]
+		failing.Name = "a-slack"
+		other := failing
+		other.Name = "c-slack"
+		cleaner.Spec.Notifications = []appsv1alpha1.Notification{
+			failing,
+			{Name: "b-event", Type: appsv1alpha1.NotificationTypeEvent},
+			other,
+			{Name: "d-muted", Type: appsv1alpha1.NotificationTypeEvent, Enabled: ptr.To(false)},
+		}
+		Expect(k8sClient.Create(context.TODO(), cleaner)).To(Succeed())
+		Expect(waitForObject(context.TODO(), k8sClient, cleaner)).To(Succeed())
+
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		err := executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("notification a-slack: channel_not_found"))
+		Expect(err.Error()).To(ContainSubstring("notification c-slack: channel_not_found"))
+
+		// Notifications after the failing one are sent
+		Expect(fake.values).To(HaveLen(2))
+		Expect(recorder.Events).To(HaveLen(1))
+
+		current := &appsv1alpha1.Cleaner{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, current)).To(Succeed())
+		Expect(current.Status.NotificationOutcomes).To(HaveLen(4))
+		outcomes := current.Status.NotificationOutcomes
+		Expect(outcomes[0].NotificationName).To(Equal("a-slack"))
+		Expect(outcomes[0].Outcome).To(Equal(appsv1alpha1.NotificationOutcomeFailed))
+		Expect(outcomes[0].Message).To(ContainSubstring("channel_not_found"))
+		Expect(outcomes[1].NotificationName).To(Equal("b-event"))
+		Expect(outcomes[1].Outcome).To(Equal(appsv1alpha1.NotificationOutcomeDelivered))
+		Expect(outcomes[2].NotificationName).To(Equal("c-slack"))
+		Expect(outcomes[2].Outcome).To(Equal(appsv1alpha1.NotificationOutcomeFailed))
+		Expect(outcomes[3].NotificationName).To(Equal("d-muted"))
+		Expect(outcomes[3].Outcome).To(Equal(appsv1alpha1.NotificationOutcomeSkipped))
+	})
+
+	It("sendNotifications returns the error of the only failed notification as is", func() {
+		ref, fake := createSlackSecret()
+		fake.err = errors.New("channel_not_found")
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications = append(cleaner.Spec.Notifications, appsv1alpha1.Notification{
+			Name: randomString(),
+			Type: appsv1alpha1.NotificationTypeEvent,
+		})
+		DeferCleanup(executor.SetEventRecorder(record.NewFakeRecorder(10)))
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(Equal("channel_not_found"))
+	})
+
+	It("sendNotifications keeps outcomes of notifications not sent by the run", func() {
+		ref, _ := createSlackSecret()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Schedule = "0 * * * *"
+		cleaner.Spec.ResourcePolicySet.ResourceSelectors = []appsv1alpha1.ResourceSelector{
+			{Kind: "ConfigMap", Version: "v1"},
+		}
+		cleaner.Spec.Notifications[0].NotifyOnFailure = true
+		cleaner.Spec.Notifications = append(cleaner.Spec.Notifications, appsv1alpha1.Notification{
+			Name:            randomString(),
+			Type:            appsv1alpha1.NotificationTypeSlack,
+			NotificationRef: ref,
+		})
+		Expect(k8sClient.Create(context.TODO(), cleaner)).To(Succeed())
+		Expect(waitForObject(context.TODO(), k8sClient, cleaner)).To(Succeed())
+
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		current := &appsv1alpha1.Cleaner{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, current)).To(Succeed())
+		Expect(current.Status.NotificationOutcomes).To(HaveLen(2))
+
+		// Run failing before processing any resource only notifies the first notification
+		Expect(executor.SendRunNotifications(context.TODO(), nil, current, "", errors.New("list failed"),
+			logr.Discard())).To(Succeed())
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, current)).To(Succeed())
+		Expect(current.Status.NotificationOutcomes).To(HaveLen(2))
+		for i := range current.Status.NotificationOutcomes {
+			Expect(current.Status.NotificationOutcomes[i].Outcome).To(Equal(appsv1alpha1.NotificationOutcomeDelivered))
+		}
+	})
+})
@@ -18,11 +18,26 @@ package executor_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/bwmarrin/discordgo"
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
 
 	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
 	"gianlucam76/k8s-cleaner/internal/controller/executor"
@@ -32,7 +47,7 @@ import (
 
 var _ = Describe("Notification", func() {
 	It("getWebexInfo get webex information from Secret", func() {
-		webexRoomID := randomString()
+		webexRoomID := randomWebexRoomID()
 		webexToken := randomString()
 
 		secretNamespace := randomString()
@@ -77,7 +92,7 @@ var _ = Describe("Notification", func() {
 	})
 
 	It("getSlackInfo get slack information from Secret", func() {
-		slackChannelID := randomString()
+		slackChannelID := randomSlackChannelID()
 		slackToken := randomString()
 		secret := &corev1.Secret{
 			ObjectMeta: metav1.ObjectMeta{
@@ -118,4 +133,1270 @@ var _ = Describe("Notification", func() {
 		Expect(executor.GetSlackChannelID(slackInfo)).To(Equal(slackChannelID))
 		Expect(executor.GetSlackToken(slackInfo)).To(Equal(slackToken))
 	})
+
+	It("getNotificationRefs returns NotificationRef followed by failover references", func() {
+		notification := &appsv1alpha1.Notification{
+			Name: randomString(),
+			Type: appsv1alpha1.NotificationTypeSlack,
+			NotificationRef: &corev1.ObjectReference{
+				Kind: "Secret", APIVersion: "v1", Namespace: randomString(), Name: randomString(),
+			},
+			FailoverNotificationRefs: []corev1.ObjectReference{
+				{Kind: "Secret", APIVersion: "v1", Namespace: randomString(), Name: randomString()},
+				{Kind: "Secret", APIVersion: "v1", Namespace: randomString(), Name: randomString()},
+			},
+		}
+
+		refs := executor.GetNotificationRefs(notification)
+		Expect(len(refs)).To(Equal(3))
+		Expect(refs[0].Name).To(Equal(notification.NotificationRef.Name))
+		Expect(refs[1].Name).To(Equal(notification.FailoverNotificationRefs[0].Name))
+		Expect(refs[2].Name).To(Equal(notification.FailoverNotificationRefs[1].Name))
+	})
+
+	It("isSlackAuthError detects revoked or invalid credentials", func() {
+		Expect(executor.IsSlackAuthError(slack.SlackErrorResponse{Err: "invalid_auth"})).To(BeTrue())
+		Expect(executor.IsSlackAuthError(slack.SlackErrorResponse{Err: "token_revoked"})).To(BeTrue())
+		Expect(executor.IsSlackAuthError(slack.SlackErrorResponse{Err: "channel_not_found"})).To(BeFalse())
+		Expect(executor.IsSlackAuthError(fmt.Errorf("connection refused"))).To(BeFalse())
+	})
+
+	It("sendNotifications delivers Slack message using the Slack client", func() {
+		slackChannelID := randomSlackChannelID()
+		slackToken := randomString()
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(slackChannelID),
+			libsveltosv1alpha1.SlackToken:     []byte(slackToken),
+		})
+
+		fake := &fakeSlackClient{}
+		var usedToken string
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			usedToken = token
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		runID := randomString()
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, runID, logr.Discard())).To(Succeed())
+
+		Expect(usedToken).To(Equal(slackToken))
+		Expect(fake.channelIDs).To(Equal([]string{slackChannelID}))
+		Expect(fake.values[0].Get("text")).To(ContainSubstring(cleaner.Name))
+		Expect(fake.values[0].Get("text")).To(ContainSubstring(runID))
+		Expect(fake.values[0].Get("attachments")).To(ContainSubstring(runID))
+		Expect(fake.values[0].Get("attachments")).To(ContainSubstring(resource.Resource.GetName()))
+	})
+
+	It("sendNotifications logs delivery failures as structured fields", func() {
+		slackChannelID := randomSlackChannelID()
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(slackChannelID),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+
+		fake := &fakeSlackClient{err: errors.New("channel_not_found")}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		entries := make([]map[string]interface{}, 0)
+		logger := funcr.NewJSON(func(obj string) {
+			entry := make(map[string]interface{})
+			Expect(json.Unmarshal([]byte(obj), &entry)).To(Succeed())
+			entries = append(entries, entry)
+		}, funcr.Options{Verbosity: 10})
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logger)).ToNot(Succeed())
+
+		failures := make([]map[string]interface{}, 0)
+		for i := range entries {
+			if entries[i]["msg"] == "failed to send notification" {
+				failures = append(failures, entries[i])
+			}
+		}
+		Expect(failures).ToNot(BeEmpty())
+		for i := range failures {
+			Expect(failures[i]).To(HaveKeyWithValue("notification", cleaner.Spec.Notifications[0].Name))
+			Expect(failures[i]).To(HaveKeyWithValue("type", string(appsv1alpha1.NotificationTypeSlack)))
+			Expect(failures[i]).To(HaveKeyWithValue("error", "channel_not_found"))
+		}
+		Expect(failures[0]).To(HaveKeyWithValue("channel", slackChannelID))
+	})
+
+	It("sendNotifications fails over to next Slack secret on authentication error", func() {
+		primaryToken := randomString()
+		primary := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(primaryToken),
+		})
+		backupChannelID := randomSlackChannelID()
+		backupToken := randomString()
+		backup := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(backupChannelID),
+			libsveltosv1alpha1.SlackToken:     []byte(backupToken),
+		})
+
+		clients := map[string]*fakeSlackClient{
+			primaryToken: {err: slack.SlackErrorResponse{Err: "token_revoked"}},
+			backupToken:  {},
+		}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return clients[token]
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, primary)
+		cleaner.Spec.Notifications[0].FailoverNotificationRefs = []corev1.ObjectReference{*backup}
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+		Expect(len(clients[primaryToken].channelIDs)).To(Equal(1))
+		Expect(clients[backupToken].channelIDs).To(Equal([]string{backupChannelID}))
+	})
+
+	It("sendNotifications does not fail over when Slack secret cannot be read", func() {
+		backupToken := randomString()
+		backup := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(backupToken),
+		})
+
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		missing := &corev1.ObjectReference{Kind: "Secret", APIVersion: "v1", Namespace: backup.Namespace, Name: randomString()}
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, missing)
+		cleaner.Spec.Notifications[0].FailoverNotificationRefs = []corev1.ObjectReference{*backup}
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("not found"))
+		Expect(fake.values).To(BeEmpty())
+	})
+
+	It("sendNotifications returns an error when Slack token is empty", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(""),
+		})
+
+		factoryCalled := false
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			factoryCalled = true
+			return &fakeSlackClient{}
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("slack token is empty"))
+		Expect(factoryCalled).To(BeFalse())
+	})
+
+	It("sendNotifications returns an error when Slack client cannot be created", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return nil
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("failed to get slack client"))
+	})
+
+	It("sendNotifications adds notification metadata to Slack attachment fields", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications[0].Metadata = map[string]string{
+			"team":        "platform",
+			"environment": "production",
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+
+		attachments := []slack.Attachment{}
+		Expect(json.Unmarshal([]byte(fake.values[0].Get("attachments")), &attachments)).To(Succeed())
+		Expect(len(attachments)).To(Equal(1))
+		Expect(attachments[0].Fields).To(Equal([]slack.AttachmentField{
+			{Title: "environment", Value: "production", Short: true},
+			{Title: "team", Value: "platform", Short: true},
+		}))
+	})
+
+	It("sendNotifications includes resource summary in Slack message text", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		namespace := randomString()
+		deployment := getResourceResult("Deployment", namespace, randomString())
+		service := getResourceResult("Service", namespace, randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{deployment, service},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		text := fake.values[0].Get("text")
+		Expect(text).To(ContainSubstring("*Resources:* 2 (Deployment: 1, Service: 1)"))
+		Expect(text).ToNot(ContainSubstring("**"))
+		Expect(text).To(ContainSubstring(fmt.Sprintf("- Deployment %s/%s", namespace, deployment.Resource.GetName())))
+		Expect(text).To(ContainSubstring(fmt.Sprintf("- Service %s/%s", namespace, service.Resource.GetName())))
+	})
+
+	It("sendNotifications uploads Slack report as file in the thread of summary message", func() {
+		channelID := randomSlackChannelID()
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(channelID),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications[0].Slack = &appsv1alpha1.SlackOptions{UploadReport: true}
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		// Summary message does not contain the report
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(ContainSubstring("Full report attached in thread."))
+		Expect(fake.values[0].Has("attachments")).To(BeFalse())
+
+		Expect(fake.uploads).To(HaveLen(1))
+		upload := fake.uploads[0]
+		Expect(upload.Channel).To(Equal(channelID))
+		// Timestamp returned by fakeSlackClient for the summary message
+		Expect(upload.ThreadTimestamp).To(Equal("1700000000.000100"))
+		Expect(upload.Filename).To(HavePrefix(cleaner.Name))
+		Expect(upload.Filename).To(HaveSuffix(".json"))
+		Expect(upload.FileSize).To(Equal(len(upload.Content)))
+		reportSpec := &appsv1alpha1.ReportSpec{}
+		Expect(json.Unmarshal([]byte(upload.Content), reportSpec)).To(Succeed())
+		Expect(reportSpec.ResourceInfo).To(HaveLen(1))
+		Expect(reportSpec.ResourceInfo[0].Resource.Name).To(Equal(resource.Resource.GetName()))
+	})
+
+	It("sendNotifications does not fail over when Slack report upload fails", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+		failoverRef := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+
+		fake := &fakeSlackClient{uploadErr: slack.SlackErrorResponse{Err: "missing_scope"}}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications[0].FailoverNotificationRefs = []corev1.ObjectReference{*failoverRef}
+		cleaner.Spec.Notifications[0].Slack = &appsv1alpha1.SlackOptions{UploadReport: true}
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("failed to upload slack report file"))
+		Expect(err.Error()).To(ContainSubstring("missing_scope"))
+		// Summary message is not posted again using failover credentials
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.uploads).To(HaveLen(1))
+	})
+
+	It("sendNotifications overrides Slack sender name and icon", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications[0].Username = "stale-pods-cleaner"
+		cleaner.Spec.Notifications[0].IconURL = "https://example.com/icon.png"
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+		Expect(fake.values[0].Get("username")).To(Equal("stale-pods-cleaner"))
+		Expect(fake.values[0].Get("icon_url")).To(Equal("https://example.com/icon.png"))
+		Expect(fake.values[0].Get("icon_emoji")).To(BeEmpty())
+
+		// Emoji takes precedence over icon URL
+		cleaner.Spec.Notifications[0].IconEmoji = ":broom:"
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+		Expect(fake.values[1].Get("icon_emoji")).To(Equal(":broom:"))
+		Expect(fake.values[1].Get("icon_url")).To(BeEmpty())
+	})
+
+	It("sendNotifications uses default Slack sender identity", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+		Expect(fake.values[0].Has("username")).To(BeFalse())
+		Expect(fake.values[0].Has("icon_url")).To(BeFalse())
+		Expect(fake.values[0].Has("icon_emoji")).To(BeFalse())
+	})
+
+	It("sendNotifications delivers Teams message using the Teams client", func() {
+		webhookURL := "https://example.webhook.office.com/" + randomString()
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.TeamsWebhookURL: []byte(webhookURL),
+		})
+
+		fake := &fakeTeamsClient{}
+		DeferCleanup(executor.SetTeamsClientFactory(func() executor.TeamsClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeTeams, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.webhookURLs).To(Equal([]string{webhookURL}))
+		payload, err := json.Marshal(fake.messages[0])
+		Expect(err).To(BeNil())
+		Expect(string(payload)).To(ContainSubstring(cleaner.Name))
+		Expect(string(payload)).To(ContainSubstring(resource.Resource.GetName()))
+	})
+
+	It("sendNotifications adds notification metadata to Teams facts", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.TeamsWebhookURL: []byte("https://example.webhook.office.com/" + randomString()),
+		})
+
+		fake := &fakeTeamsClient{}
+		DeferCleanup(executor.SetTeamsClientFactory(func() executor.TeamsClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeTeams, ref)
+		cleaner.Spec.Notifications[0].Metadata = map[string]string{
+			"cost-center": "cc-1234",
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+
+		payload, err := json.Marshal(fake.messages[0])
+		Expect(err).To(BeNil())
+		Expect(string(payload)).To(ContainSubstring(`"type":"FactSet"`))
+		Expect(string(payload)).To(ContainSubstring(`{"title":"cost-center","value":"cc-1234"}`))
+	})
+
+	It("sendNotifications delivers Discord message using the Discord client", func() {
+		discordChannelID := randomDiscordID()
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.DiscordChannelID: []byte(discordChannelID),
+			libsveltosv1alpha1.DiscordToken:     []byte(randomString()),
+		})
+
+		fake := &fakeDiscordClient{}
+		useFakeDiscordClient(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeDiscord, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.channelIDs).To(Equal([]string{discordChannelID}))
+		Expect(fake.messages[0].Content).To(ContainSubstring(cleaner.Name))
+		Expect(len(fake.files)).To(Equal(1))
+		Expect(string(fake.files[0])).To(ContainSubstring(resource.Resource.GetName()))
+	})
+
+	It("sendNotifications includes resource summary in Discord message content", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.DiscordChannelID: []byte(randomDiscordID()),
+			libsveltosv1alpha1.DiscordToken:     []byte(randomString()),
+		})
+
+		fake := &fakeDiscordClient{}
+		useFakeDiscordClient(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeDiscord, ref)
+		failed := getResourceResult("Pod", randomString(), randomString())
+		failed.Outcome = appsv1alpha1.ResourceOutcomeFailed
+		failed.Error = "pods is forbidden"
+		resources := []executor.ResourceResult{failed}
+		for i := 0; i < 7; i++ {
+			resources = append(resources, getResourceResult("ConfigMap", randomString(), randomString()))
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.messages).To(HaveLen(1))
+		content := fake.messages[0].Content
+		Expect(content).To(ContainSubstring(cleaner.Name))
+		Expect(content).To(ContainSubstring("**Resources:** 8 (ConfigMap: 7, Pod: 1)"))
+		// Failed resources are listed, along with the error, in the embed
+		Expect(content).ToNot(ContainSubstring(failed.Resource.GetName()))
+		Expect(strings.Count(content, "\n- ConfigMap ")).To(Equal(5))
+		Expect(content).To(HaveSuffix("…and 2 more"))
+	})
+
+	It("sendNotifications splits long Discord message content into sequential messages", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.DiscordChannelID: []byte(randomDiscordID()),
+			libsveltosv1alpha1.DiscordToken:     []byte(randomString()),
+		})
+
+		fake := &fakeDiscordClient{}
+		useFakeDiscordClient(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeDiscord, ref)
+		lines := make([]string, 60)
+		for i := range lines {
+			lines[i] = fmt.Sprintf("line %02d %s", i, strings.Repeat("x", 50))
+		}
+		cleaner.Spec.Notifications[0].MessageTemplate = strings.Join(lines, "\n")
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.messages).To(HaveLen(2))
+		contents := []string{}
+		for i := range fake.messages {
+			Expect(len([]rune(fake.messages[i].Content))).To(BeNumerically("<=", 2000))
+			contents = append(contents, fake.messages[i].Content)
+		}
+		// Messages are split at line breaks, so no content is lost
+		Expect(contents[0]).To(HavePrefix("[Part 1/2] "))
+		Expect(contents[1]).To(HavePrefix("[Part 2/2] "))
+		contents[0] = strings.TrimPrefix(contents[0], "[Part 1/2] ")
+		contents[1] = strings.TrimPrefix(contents[1], "[Part 2/2] ")
+		Expect(strings.Join(contents, "\n")).To(HavePrefix(strings.Join(lines, "\n")))
+		Expect(contents[1]).To(ContainSubstring(resource.Resource.GetName()))
+
+		// Embed and report file are only sent with the last message
+		Expect(fake.messages[0].Embeds).To(BeEmpty())
+		Expect(fake.messages[0].Files).To(BeEmpty())
+		Expect(fake.messages[1].Embeds).To(HaveLen(1))
+		Expect(fake.files).To(HaveLen(1))
+		Expect(string(fake.files[0])).To(ContainSubstring(resource.Resource.GetName()))
+	})
+
+	It("sendNotifications does not send Discord messages again when retrying the following ones", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.DiscordChannelID: []byte(randomDiscordID()),
+			libsveltosv1alpha1.DiscordToken:     []byte(randomString()),
+		})
+
+		// Second part fails the first time it is sent
+		fake := &fakeDiscordClient{failOnce: map[int]error{1: errors.New("service unavailable")}}
+		useFakeDiscordClient(fake)
+		DeferCleanup(executor.SetNotificationRetryBackoff(wait.Backoff{Duration: time.Millisecond, Steps: 3}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeDiscord, ref)
+		cleaner.Spec.Notifications[0].OnFailure = appsv1alpha1.NotificationFailurePolicyRetry
+		lines := make([]string, 60)
+		for i := range lines {
+			lines[i] = fmt.Sprintf("line %02d %s", i, strings.Repeat("x", 50))
+		}
+		cleaner.Spec.Notifications[0].MessageTemplate = strings.Join(lines, "\n")
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.messages).To(HaveLen(2))
+		Expect(fake.messages[0].Content).To(HavePrefix("[Part 1/2] "))
+		Expect(fake.messages[1].Content).To(HavePrefix("[Part 2/2] "))
+		Expect(fake.messages[1].Embeds).To(HaveLen(1))
+	})
+
+	It("sendNotifications does not interleave Discord messages of concurrent runs to the same channel", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.DiscordChannelID: []byte(randomDiscordID()),
+			libsveltosv1alpha1.DiscordToken:     []byte(randomString()),
+		})
+
+		fake := &fakeDiscordClient{delay: 10 * time.Millisecond}
+		useFakeDiscordClient(fake)
+
+		const runs = 4
+		var wg sync.WaitGroup
+		for i := 0; i < runs; i++ {
+			cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeDiscord, ref)
+			lines := make([]string, 60)
+			for j := range lines {
+				lines[j] = cleaner.Name + " " + strings.Repeat("x", 50)
+			}
+			cleaner.Spec.Notifications[0].MessageTemplate = strings.Join(lines, "\n")
+
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+			}()
+		}
+		wg.Wait()
+
+		// Each run sends two parts, one after the other
+		Expect(fake.messages).To(HaveLen(2 * runs))
+		for i := 0; i < len(fake.messages); i += 2 {
+			Expect(fake.messages[i].Content).To(HavePrefix("[Part 1/2] "))
+			Expect(fake.messages[i+1].Content).To(HavePrefix("[Part 2/2] "))
+			cleanerName := strings.Fields(strings.TrimPrefix(fake.messages[i].Content, "[Part 1/2] "))[0]
+			Expect(strings.TrimPrefix(fake.messages[i+1].Content, "[Part 2/2] ")).To(HavePrefix(cleanerName))
+		}
+	})
+
+	It("splitContent splits content at line breaks and truncates what does not fit", func() {
+		Expect(executor.SplitContent("short", 10, 3)).To(Equal([]string{"short"}))
+		Expect(executor.SplitContent("abc\ndefg\nhi", 9, 3)).To(Equal([]string{"abc\ndefg", "hi"}))
+		// Lines longer than a message are cut
+		Expect(executor.SplitContent("abcdefghij", 4, 3)).To(Equal([]string{"abcd", "efgh", "ij"}))
+		Expect(executor.SplitContent("abcdefghijkl", 4, 2)).To(Equal([]string{"abcd", "efg…"}))
+	})
+
+	It("splitMessage marks parts of content not fitting a single message", func() {
+		Expect(executor.SplitMessage("short", 20, 3)).To(Equal([]string{"short"}))
+		// Marker of a 3 parts message is 11 characters long
+		Expect(executor.SplitMessage("abc\ndefg\nhi", 20, 3)).To(Equal([]string{"abc\ndefg\nhi"}))
+		Expect(executor.SplitMessage("abcdefg\nhijklmn\nopq", 18, 3)).To(Equal([]string{
+			"[Part 1/3] abcdefg", "[Part 2/3] hijklmn", "[Part 3/3] opq"}))
+		for _, part := range executor.SplitMessage(strings.Repeat("a", 100), 20, 3) {
+			Expect(len([]rune(part))).To(BeNumerically("<=", 20))
+		}
+	})
+
+	It("sendNotifications closes Discord report file, also when send fails", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.DiscordChannelID: []byte(randomDiscordID()),
+			libsveltosv1alpha1.DiscordToken:     []byte(randomString()),
+		})
+
+		fake := &fakeDiscordClient{}
+		useFakeDiscordClient(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeDiscord, ref)
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+
+		fake.err = fmt.Errorf("connection refused")
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).ToNot(Succeed())
+
+		Expect(fake.readers).To(HaveLen(2))
+		for i := range fake.readers {
+			expectClosedFile(fake.readers[i])
+		}
+	})
+
+	It("sendNotifications adds notification metadata to Discord embed fields", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.DiscordChannelID: []byte(randomDiscordID()),
+			libsveltosv1alpha1.DiscordToken:     []byte(randomString()),
+		})
+
+		fake := &fakeDiscordClient{}
+		useFakeDiscordClient(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeDiscord, ref)
+		cleaner.Spec.Notifications[0].Metadata = map[string]string{
+			"team": "platform",
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(len(fake.messages[0].Embeds)).To(Equal(1))
+		Expect(fake.messages[0].Embeds[0].Fields).To(ContainElement(&discordgo.MessageEmbedField{
+			Name: "team", Value: "platform", Inline: true,
+		}))
+	})
+
+	It("sendNotifications shows sender name and icon as Discord embed author", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.DiscordChannelID: []byte(randomDiscordID()),
+			libsveltosv1alpha1.DiscordToken:     []byte(randomString()),
+		})
+
+		fake := &fakeDiscordClient{}
+		useFakeDiscordClient(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeDiscord, ref)
+		cleaner.Spec.Notifications[0].Username = "stale-pods-cleaner"
+		cleaner.Spec.Notifications[0].IconURL = "https://example.com/icon.png"
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(len(fake.messages[0].Embeds)).To(Equal(1))
+		Expect(fake.messages[0].Embeds[0].Author).To(Equal(&discordgo.MessageEmbedAuthor{
+			Name:    "stale-pods-cleaner",
+			IconURL: "https://example.com/icon.png",
+		}))
+	})
+
+	It("sendNotifications sends Discord embed summarizing resources", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.DiscordChannelID: []byte(randomDiscordID()),
+			libsveltosv1alpha1.DiscordToken:     []byte(randomString()),
+		})
+
+		fake := &fakeDiscordClient{}
+		useFakeDiscordClient(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeDiscord, ref)
+		resources := []executor.ResourceResult{
+			getResourceResult("Secret", randomString(), randomString()),
+			getResourceResult("ConfigMap", randomString(), randomString()),
+			getResourceResult("ConfigMap", randomString(), randomString()),
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(len(fake.messages[0].Embeds)).To(Equal(1))
+		embed := fake.messages[0].Embeds[0]
+		Expect(embed.Title).To(Equal(fmt.Sprintf("%s: %s", cleaner.Name, appsv1alpha1.ActionDelete)))
+		Expect(embed.Color).To(Equal(0xE01E5A))
+		Expect(embed.Fields).To(Equal([]*discordgo.MessageEmbedField{
+			{Name: "Resources", Value: "3", Inline: true},
+			{Name: "ConfigMap", Value: "2", Inline: true},
+			{Name: "Secret", Value: "1", Inline: true},
+		}))
+		// Full report is still attached
+		Expect(string(fake.files[0])).To(ContainSubstring(resources[0].Resource.GetName()))
+	})
+
+	It("sendNotifications respects Discord embed field limits", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.DiscordChannelID: []byte(randomDiscordID()),
+			libsveltosv1alpha1.DiscordToken:     []byte(randomString()),
+		})
+
+		fake := &fakeDiscordClient{}
+		useFakeDiscordClient(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeDiscord, ref)
+		cleaner.Spec.Notifications[0].Metadata = map[string]string{
+			"team": strings.Repeat("a", 2000),
+		}
+		const kinds = 40
+		resources := make([]executor.ResourceResult, kinds)
+		for i := range resources {
+			resources[i] = getResourceResult(fmt.Sprintf("Kind%02d", i), randomString(), randomString())
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		embed := fake.messages[0].Embeds[0]
+		Expect(embed.Fields).To(HaveLen(25))
+		for i := range embed.Fields {
+			Expect(len([]rune(embed.Fields[i].Value))).To(BeNumerically("<=", 1024))
+		}
+		// total, 22 kinds, summary of remaining kinds, metadata
+		Expect(embed.Fields[23].Name).To(Equal("Other kinds"))
+		Expect(embed.Fields[23].Value).To(HavePrefix(fmt.Sprintf("%d resources in %d kinds", kinds-22, kinds-22)))
+		Expect(embed.Fields[24].Name).To(Equal("team"))
+	})
+
+	It("sendNotifications validates Discord channel ID and token", func() {
+		DeferCleanup(executor.SetDiscordClientFactory(func(token string) (executor.DiscordClient, error) {
+			return &fakeDiscordClient{}, nil
+		}))
+
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.DiscordChannelID: []byte("general"),
+			libsveltosv1alpha1.DiscordToken:     []byte(randomString()),
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeDiscord, ref)
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("numeric channel ID"))
+
+		ref = createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.DiscordChannelID: []byte(randomDiscordID()),
+			libsveltosv1alpha1.DiscordToken:     []byte(""),
+		})
+		cleaner = getCleanerWithNotification(appsv1alpha1.NotificationTypeDiscord, ref)
+		err = executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("discord token is empty"))
+	})
+
+	It("sendNotifications translates Discord permission errors", func() {
+		channelID := randomDiscordID()
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.DiscordChannelID: []byte(channelID),
+			libsveltosv1alpha1.DiscordToken:     []byte(randomString()),
+		})
+
+		restErr := getDiscordRESTError(http.StatusForbidden, discordgo.ErrCodeMissingPermissions)
+		DeferCleanup(executor.SetDiscordClientFactory(func(token string) (executor.DiscordClient, error) {
+			return &fakeDiscordClient{err: restErr}, nil
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeDiscord, ref)
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(HavePrefix(fmt.Sprintf("bot lacks SEND_MESSAGES, EMBED_LINKS or ATTACH_FILES in channel %s",
+			channelID)))
+		Expect(errors.Is(err, restErr)).To(BeTrue())
+	})
+
+	It("translateDiscordError returns actionable messages", func() {
+		channelID := randomDiscordID()
+
+		err := executor.TranslateDiscordError(getDiscordRESTError(http.StatusUnauthorized, 0), channelID)
+		Expect(err.Error()).To(HavePrefix("discord token is invalid or revoked"))
+
+		err = executor.TranslateDiscordError(getDiscordRESTError(http.StatusNotFound,
+			discordgo.ErrCodeUnknownChannel), channelID)
+		Expect(err.Error()).To(HavePrefix(fmt.Sprintf("discord channel %s does not exist", channelID)))
+
+		err = executor.TranslateDiscordError(getDiscordRESTError(http.StatusForbidden,
+			discordgo.ErrCodeMissingAccess), channelID)
+		Expect(err.Error()).To(HavePrefix(fmt.Sprintf("bot lacks VIEW_CHANNEL in channel %s", channelID)))
+
+		otherErr := fmt.Errorf("connection refused")
+		Expect(executor.TranslateDiscordError(otherErr, channelID)).To(Equal(otherErr))
+	})
+
+	It("sendNotifications delivers Webex message using the Webex client", func() {
+		webexRoomID := randomWebexRoomID()
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.WebexRoomID: []byte(webexRoomID),
+			libsveltosv1alpha1.WebexToken:  []byte(randomString()),
+		})
+
+		fake := &fakeWebexClient{}
+		useFakeWebexClient(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeWebex, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(len(fake.requests)).To(Equal(1))
+		Expect(fake.requests[0].RoomID).To(Equal(webexRoomID))
+		Expect(fake.requests[0].Markdown).To(ContainSubstring(cleaner.Name))
+		Expect(len(fake.files)).To(Equal(1))
+		Expect(string(fake.files[0])).To(ContainSubstring(resource.Resource.GetName()))
+	})
+
+	It("sendNotifications includes resource summary in Webex markdown message", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.WebexRoomID: []byte(randomWebexRoomID()),
+			libsveltosv1alpha1.WebexToken:  []byte(randomString()),
+		})
+
+		fake := &fakeWebexClient{}
+		useFakeWebexClient(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeWebex, ref)
+		resources := []executor.ResourceResult{
+			getResourceResult("ConfigMap", randomString(), randomString()),
+			getResourceResult("ConfigMap", randomString(), randomString()),
+			getResourceResult("Secret", randomString(), randomString()),
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.requests).To(HaveLen(1))
+		Expect(fake.requests[0].Text).To(BeEmpty())
+		Expect(fake.requests[0].Markdown).To(ContainSubstring("**Resources:** 3 (ConfigMap: 2, Secret: 1)"))
+	})
+
+	It("sendNotifications sends Webex message as plain text when requested", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.WebexRoomID: []byte(randomWebexRoomID()),
+			libsveltosv1alpha1.WebexToken:  []byte(randomString()),
+		})
+
+		fake := &fakeWebexClient{}
+		useFakeWebexClient(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeWebex, ref)
+		cleaner.Spec.Notifications[0].Webex = &appsv1alpha1.WebexOptions{Format: appsv1alpha1.WebexFormatText}
+		failed := getResourceResult("ConfigMap", randomString(), randomString())
+		failed.Outcome = appsv1alpha1.ResourceOutcomeFailed
+		failed.Error = "configmaps is forbidden"
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{failed},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.requests).To(HaveLen(1))
+		Expect(fake.requests[0].Markdown).To(BeEmpty())
+		text := fake.requests[0].Text
+		Expect(text).To(ContainSubstring(cleaner.Name))
+		Expect(text).To(ContainSubstring("Resources: 1 (ConfigMap: 1)"))
+		Expect(text).To(ContainSubstring("1 resource(s) failed:"))
+		Expect(text).To(ContainSubstring("configmaps is forbidden"))
+		Expect(text).ToNot(ContainSubstring("**"))
+		Expect(fake.files).To(HaveLen(1))
+	})
+
+	It("sendNotifications closes Webex report file, also when send fails", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.WebexRoomID: []byte(randomWebexRoomID()),
+			libsveltosv1alpha1.WebexToken:  []byte(randomString()),
+		})
+
+		fake := &fakeWebexClient{}
+		useFakeWebexClient(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeWebex, ref)
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+
+		fake.err = fmt.Errorf("connection refused")
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).ToNot(Succeed())
+
+		Expect(fake.readers).To(HaveLen(2))
+		for i := range fake.readers {
+			expectClosedFile(fake.readers[i])
+		}
+	})
+
+	It("sendNotifications includes selected labels and annotations of matching resources", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.WebexRoomID: []byte(randomWebexRoomID()),
+			libsveltosv1alpha1.WebexToken:  []byte(randomString()),
+		})
+
+		fake := &fakeWebexClient{}
+		useFakeWebexClient(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeWebex, ref)
+		cleaner.Spec.ReportResourceMetadata = &appsv1alpha1.ReportResourceMetadata{
+			Labels:      []string{"team", "app.kubernetes.io/*"},
+			Annotations: []string{"owner"},
+		}
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		resource.Resource.SetLabels(map[string]string{
+			"team":                      "platform",
+			"app.kubernetes.io/name":    "cleaner",
+			"app.kubernetes.io/version": "v1",
+			"environment":               "production",
+		})
+		resource.Resource.SetAnnotations(map[string]string{
+			"owner": "alice",
+			"kubectl.kubernetes.io/last-applied-configuration": randomString(),
+		})
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(len(fake.files)).To(Equal(1))
+		reportSpec := &appsv1alpha1.ReportSpec{}
+		Expect(json.Unmarshal(fake.files[0], reportSpec)).To(Succeed())
+		Expect(reportSpec.ResourceInfo).To(HaveLen(1))
+		Expect(reportSpec.ResourceInfo[0].Labels).To(Equal(map[string]string{
+			"team":                      "platform",
+			"app.kubernetes.io/name":    "cleaner",
+			"app.kubernetes.io/version": "v1",
+		}))
+		Expect(reportSpec.ResourceInfo[0].Annotations).To(Equal(map[string]string{"owner": "alice"}))
+	})
+
+	It("sendNotifications does not include labels and annotations by default", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.WebexRoomID: []byte(randomWebexRoomID()),
+			libsveltosv1alpha1.WebexToken:  []byte(randomString()),
+		})
+
+		fake := &fakeWebexClient{}
+		useFakeWebexClient(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeWebex, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		resource.Resource.SetLabels(map[string]string{"team": "platform"})
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(len(fake.files)).To(Equal(1))
+		Expect(string(fake.files[0])).ToNot(ContainSubstring("platform"))
+	})
+
+	It("sendNotifications includes resource counts in the report summary", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.WebexRoomID: []byte(randomWebexRoomID()),
+			libsveltosv1alpha1.WebexToken:  []byte(randomString()),
+		})
+
+		fake := &fakeWebexClient{}
+		useFakeWebexClient(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeWebex, ref)
+		namespace := randomString()
+		resources := []executor.ResourceResult{
+			getResourceResult("ConfigMap", namespace, randomString()),
+			getResourceResult("ConfigMap", namespace, randomString()),
+			getResourceResult("Secret", namespace, randomString()),
+			getResourceResult("ConfigMap", "default", randomString()),
+			// Cluster-scoped resource
+			getResourceResult("ClusterRole", "", randomString()),
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(len(fake.files)).To(Equal(1))
+		reportSpec := &appsv1alpha1.ReportSpec{}
+		Expect(json.Unmarshal(fake.files[0], reportSpec)).To(Succeed())
+		Expect(reportSpec.Summary).ToNot(BeNil())
+		Expect(reportSpec.Summary.Total).To(Equal(int32(5)))
+		Expect(reportSpec.Summary.ByKind).To(Equal(map[string]int32{
+			"ConfigMap":   3,
+			"Secret":      1,
+			"ClusterRole": 1,
+		}))
+		Expect(reportSpec.Summary.ByNamespace).To(Equal(map[string]int32{
+			namespace: 3,
+			"default": 1,
+		}))
+	})
+
+	It("sendNotifications message includes the action and the number of resources", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Action = appsv1alpha1.ActionScan
+		resources := []executor.ResourceResult{
+			getResourceResult("ConfigMap", randomString(), randomString()),
+			getResourceResult("Secret", randomString(), randomString()),
+		}
+		runID := randomString()
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, runID, logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(HavePrefix(
+			"k8s-cleaner '" + cleaner.Name + "' performed Scan on 2 resources (run ID: " + runID + ")"))
+	})
+
+	It("sendNotifications lists each resource once merging messages of duplicates", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.WebexRoomID: []byte(randomWebexRoomID()),
+			libsveltosv1alpha1.WebexToken:  []byte(randomString()),
+		})
+
+		fake := &fakeWebexClient{}
+		useFakeWebexClient(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeWebex, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		// Same resource matched by a second rule
+		duplicate := getResourceResult("ConfigMap", resource.Resource.GetNamespace(), resource.Resource.GetName())
+		duplicate.Outcome = appsv1alpha1.ResourceOutcomeFailed
+		duplicate.Error = "configmaps is forbidden"
+		// Same name, different kind
+		secret := getResourceResult("Secret", resource.Resource.GetNamespace(), resource.Resource.GetName())
+		resources := []executor.ResourceResult{resource, secret, duplicate, resource}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(len(fake.files)).To(Equal(1))
+		reportSpec := &appsv1alpha1.ReportSpec{}
+		Expect(json.Unmarshal(fake.files[0], reportSpec)).To(Succeed())
+		Expect(reportSpec.ResourceInfo).To(HaveLen(2))
+		Expect(reportSpec.Summary.Total).To(Equal(int32(2)))
+
+		// Failed resource is listed first
+		merged := reportSpec.ResourceInfo[0]
+		Expect(merged.Resource.Kind).To(Equal("ConfigMap"))
+		Expect(merged.Outcome).To(Equal(appsv1alpha1.ResourceOutcomeFailed))
+		Expect(merged.Error).To(Equal(duplicate.Error))
+		Expect(merged.Message).To(HavePrefix(resource.Message + "; " + duplicate.Message + ". time: "))
+		Expect(reportSpec.ResourceInfo[1].Resource.Kind).To(Equal("Secret"))
+	})
+
+	It("sendNotifications sorts resources by namespace, kind and name", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.WebexRoomID: []byte(randomWebexRoomID()),
+			libsveltosv1alpha1.WebexToken:  []byte(randomString()),
+		})
+
+		fake := &fakeWebexClient{}
+		useFakeWebexClient(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeWebex, ref)
+		resources := []executor.ResourceResult{
+			getResourceResult("ConfigMap", "b", "x"),
+			getResourceResult("Secret", "a", "y"),
+			getResourceResult("ConfigMap", "a", "z"),
+			getResourceResult("ConfigMap", "a", "w"),
+		}
+		reversed := make([]executor.ResourceResult, len(resources))
+		for i := range resources {
+			reversed[len(resources)-1-i] = resources[i]
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+		Expect(executor.SendNotifications(context.TODO(), reversed, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(len(fake.files)).To(Equal(2))
+		for i := range fake.files {
+			reportSpec := &appsv1alpha1.ReportSpec{}
+			Expect(json.Unmarshal(fake.files[i], reportSpec)).To(Succeed())
+			Expect(reportSpec.ResourceInfo).To(HaveLen(4))
+			order := make([]string, len(reportSpec.ResourceInfo))
+			for j := range reportSpec.ResourceInfo {
+				r := reportSpec.ResourceInfo[j].Resource
+				order[j] = r.Namespace + "/" + r.Kind + "/" + r.Name
+			}
+			Expect(order).To(Equal([]string{"a/ConfigMap/w", "a/ConfigMap/z", "a/Secret/y", "b/ConfigMap/x"}))
+		}
+	})
+
+	It("sendNotifications formats timestamps in the notification timezone", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications[0].Timezone = "Asia/Tokyo"
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values[0].Get("attachments")).To(MatchRegexp(`time: \d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\+09:00`))
+	})
+
+	It("sendNotifications formats timestamps in UTC by default", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values[0].Get("attachments")).To(MatchRegexp(`time: \d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z`))
+	})
+
+	It("sendNotifications fails when notification timezone is invalid", func() {
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, nil)
+		cleaner.Spec.Notifications[0].Timezone = "Mars/Olympus_Mons"
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring(`invalid timezone "Mars/Olympus_Mons"`))
+		Expect(fake.values).To(BeEmpty())
+	})
+
+	It("sendNotifications sends SMTP report in the email body by default", func() {
+		fake := &fakeMailer{}
+		useFakeMailer(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSMTP, nil)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(len(fake.subjects)).To(Equal(1))
+		Expect(fake.subjects[0]).To(ContainSubstring(cleaner.Name))
+		Expect(fake.bodies[0]).To(ContainSubstring(resource.Resource.GetName()))
+		Expect(fake.attachments[0]).To(BeEmpty())
+	})
+
+	It("sendNotifications attaches HTML report to SMTP notification", func() {
+		fake := &fakeMailer{}
+		useFakeMailer(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSMTP, nil)
+		cleaner.Spec.Notifications[0].SMTP = &appsv1alpha1.SMTPOptions{
+			ReportDelivery: appsv1alpha1.SMTPReportDeliveryAttachment,
+		}
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(len(fake.bodies)).To(Equal(1))
+		Expect(fake.bodies[0]).ToNot(ContainSubstring(resource.Resource.GetName()))
+		Expect(len(fake.attachments[0])).To(Equal(1))
+
+		attachment := fake.attachments[0][0]
+		Expect(executor.GetAttachmentFileName(attachment)).To(MatchRegexp(
+			fmt.Sprintf(`^%s-\d{8}-\d{6}\.html$`, cleaner.Name)))
+		Expect(executor.GetAttachmentContentType(attachment)).To(HavePrefix("text/html"))
+		html := string(executor.GetAttachmentData(attachment))
+		Expect(html).To(HavePrefix("<!DOCTYPE html>"))
+		Expect(html).To(ContainSubstring(cleaner.Name))
+		Expect(html).To(ContainSubstring(resource.Resource.GetName()))
+		Expect(html).To(ContainSubstring(resource.Resource.GetNamespace()))
+	})
+
+	It("sendNotifications sends SMTP report in body and as attachment", func() {
+		fake := &fakeMailer{}
+		useFakeMailer(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSMTP, nil)
+		cleaner.Spec.Notifications[0].SMTP = &appsv1alpha1.SMTPOptions{
+			ReportDelivery: appsv1alpha1.SMTPReportDeliveryBodyAndAttachment,
+		}
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.bodies[0]).To(ContainSubstring(resource.Resource.GetName()))
+		Expect(len(fake.attachments[0])).To(Equal(1))
+	})
+
+	It("sendNotifications sends SMTP report in the email body when the HTML report cannot be rendered", func() {
+		fake := &fakeMailer{}
+		useFakeMailer(fake)
+		DeferCleanup(executor.SetHTMLReportTemplate("{{ .Missing }}"))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSMTP, nil)
+		cleaner.Spec.Notifications[0].SMTP = &appsv1alpha1.SMTPOptions{
+			ReportDelivery: appsv1alpha1.SMTPReportDeliveryAttachment,
+		}
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(len(fake.bodies)).To(Equal(1))
+		Expect(fake.subjects[0]).To(ContainSubstring(cleaner.Name))
+		Expect(fake.bodies[0]).To(ContainSubstring(resource.Resource.GetName()))
+		Expect(fake.attachments[0]).To(BeEmpty())
+	})
+
+	It("sendNotifications sends SMTP report in the email body when the attachment name template fails", func() {
+		fake := &fakeMailer{}
+		useFakeMailer(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSMTP, nil)
+		cleaner.Spec.Notifications[0].AttachmentNameTemplate = "{{ .Missing }}"
+		cleaner.Spec.Notifications[0].SMTP = &appsv1alpha1.SMTPOptions{
+			ReportDelivery: appsv1alpha1.SMTPReportDeliveryBodyAndAttachment,
+		}
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(len(fake.bodies)).To(Equal(1))
+		Expect(fake.bodies[0]).To(ContainSubstring(resource.Resource.GetName()))
+		Expect(fake.attachments[0]).To(BeEmpty())
+	})
 })
+
+func getCleanerWithNotification(notificationType appsv1alpha1.NotificationType,
+	ref *corev1.ObjectReference) *appsv1alpha1.Cleaner {
+
+	return &appsv1alpha1.Cleaner{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: randomString(),
+		},
+		Spec: appsv1alpha1.CleanerSpec{
+			Action: appsv1alpha1.ActionDelete,
+			Notifications: []appsv1alpha1.Notification{
+				{
+					Name:            randomString(),
+					Type:            notificationType,
+					NotificationRef: ref,
+				},
+			},
+		},
+	}
+}
+
+func getResourceResult(kind, namespace, name string) executor.ResourceResult {
+	resource := &unstructured.Unstructured{}
+	resource.SetAPIVersion("v1")
+	resource.SetKind(kind)
+	resource.SetNamespace(namespace)
+	resource.SetName(name)
+
+	return executor.ResourceResult{
+		Resource: resource,
+		Message:  randomString(),
+	}
+}
+
+// expectClosedFile verifies reader is a file which has been closed
+func expectClosedFile(reader io.Reader) {
+	file, ok := reader.(*os.File)
+	Expect(ok).To(BeTrue())
+	_, err := file.Read(make([]byte, 1))
+	Expect(errors.Is(err, os.ErrClosed)).To(BeTrue())
+}
+
+func getDiscordRESTError(statusCode, code int) *discordgo.RESTError {
+	body := fmt.Sprintf(`{"message":"error","code":%d}`, code)
+	return &discordgo.RESTError{
+		Response:     &http.Response{StatusCode: statusCode, Status: http.StatusText(statusCode)},
+		ResponseBody: []byte(body),
+		Message:      &discordgo.APIErrorMessage{Code: code, Message: "error"},
+	}
+}
@@ -18,33 +18,71 @@ package executor
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/tls"
+	"errors"
 	"fmt"
-	"io"
-	"os"
+	"net/http"
+	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 
-	goteamsnotify "github.com/atc0005/go-teams-notify/v2"
 	"github.com/atc0005/go-teams-notify/v2/adaptivecard"
 	"github.com/bwmarrin/discordgo"
 	"github.com/go-logr/logr"
 	webexteams "github.com/jbogarin/go-cisco-webex-teams/sdk"
 	"github.com/slack-go/slack"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
 
 	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
 
 	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
-	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
 	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
-	sveltosnotifications "github.com/projectsveltos/libsveltos/lib/notifications"
 )
 
+const (
+	// maxReportMetadataEntries is the maximum number of labels (and annotations)
+	// of a resource included in a report
+	maxReportMetadataEntries = 20
+
+	// resourceMessageSeparator separates the messages of a resource matched
+	// more than once in the same run
+	resourceMessageSeparator = "; "
+
+	// maxReportMetadataValueSize is the maximum size of a label (or annotation)
+	// value included in a report. Longer values are truncated.
+	maxReportMetadataValueSize = 256
+
+	// maxRunErrorSize is the maximum size of the error of a failed run included
+	// in a report
+	maxRunErrorSize = 1024
+)
+
+// reportKindBackoff is used to retry storing the Report instance while the Report
+// CRD is not established
+var reportKindBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2,
+	Steps:    3,
+}
+
 type slackInfo struct {
 	token     string
 	channelID string
+	// webhookURL, when set, is the incoming webhook messages are posted to
+	// instead of using token and channelID
+	webhookURL string
+	// tlsConfig is used to verify the incoming webhook server certificate
+	tlsConfig *tls.Config
 }
 
 type webexInfo struct {
@@ -59,57 +97,567 @@ type discordInfo struct {
 
 type teamsInfo struct {
 	webhookUrl string
+	tlsConfig  *tls.Config
 }
 
-// sendNotification delivers notification
+func init() {
+	registerNotifier(appsv1alpha1.NotificationTypeCleanerReport, notifierFunc(
+		func(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+			_ []ResourceResult, _ string, _ *appsv1alpha1.Notification, logger logr.Logger) error {
+
+			err := createReportInstance(ctx, cleaner, reportSpec, logger)
+			if meta.IsNoMatchError(err) {
+				// Report CRD is not established, for instance right after a fresh
+				// install. Do not fail the other notifications.
+				logger.Info("Report CRD is not available. Skip CleanerReport notification", "error", err.Error())
+				return nil
+			}
+			return err
+		}))
+	registerNotifier(appsv1alpha1.NotificationTypeSlack, notifierFunc(
+		func(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+			_ []ResourceResult, message string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+			return sendSlackNotification(ctx, cleaner, reportSpec, message, notification, logger)
+		}))
+	registerNotifier(appsv1alpha1.NotificationTypeWebex, notifierFunc(
+		func(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+			_ []ResourceResult, message string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+			return sendWebexNotification(ctx, cleaner, reportSpec, message, notification, logger)
+		}))
+	registerNotifier(appsv1alpha1.NotificationTypeDiscord, notifierFunc(
+		func(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+			_ []ResourceResult, message string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+			return sendDiscordNotification(ctx, cleaner, reportSpec, message, notification, logger)
+		}))
+	registerNotifier(appsv1alpha1.NotificationTypeTeams, notifierFunc(
+		func(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+			_ []ResourceResult, message string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+			return sendTeamsNotification(ctx, cleaner, reportSpec, message, notification, logger)
+		}))
+	registerNotifier(appsv1alpha1.NotificationTypeSMTP, notifierFunc(
+		func(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+			_ []ResourceResult, message string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+			return sendSmtpNotification(ctx, cleaner, reportSpec, message, notification, logger)
+		}))
+}
+
+// sendNotification delivers notification. runID identifies the run and is
+// included in every notification.
 func sendNotifications(ctx context.Context, resources []ResourceResult,
-	cleaner *appsv1alpha1.Cleaner, logger logr.Logger) error {
+	cleaner *appsv1alpha1.Cleaner, runID string, logger logr.Logger) error {
 
-	reportSpec := &appsv1alpha1.ReportSpec{}
-	if len(cleaner.Spec.Notifications) > 0 {
-		reportSpec = generateReportSpec(resources, cleaner)
-	}
+	return sendRunNotifications(ctx, resources, cleaner, runID, nil, logger)
+}
+
+// isNotificationEnabled returns false when notification has been muted
+func isNotificationEnabled(notification *appsv1alpha1.Notification) bool {
+	return notification.Enabled == nil || *notification.Enabled
+}
 
-	message := fmt.Sprintf("This report has been generated by k8s-cleaner for instance: %s", cleaner.Name)
+// sendRunNotifications delivers notifications for a run. runErr, if not nil, is
+// the error which made the run fail and resources are the ones processed before
+// the failure. Notifications with NotifyOnFailure set then receive a failure
+// report, sent immediately even for digest notifications. Other notifications
+// only receive a report when resources were processed.
+// When the run matched no resource while the previous one matched some,
+// notifications with NotifyOnResolved set receive a resolved report, sent
+// immediately as well. So do notifications whose WarningThreshold is reached
+// by the run for the first time, receiving a threshold exceeded report.
+// When notification batching is enabled, reports of other runs are added to the
+// batch of their notification target instead of being sent right away.
+// Each notification only receives the resources in its ResourceScope.
+// A failing notification does not prevent the others from being sent. The
+// outcome of each notification is recorded in the Cleaner status and the
+// returned error aggregates the failures, if any.
+func sendRunNotifications(ctx context.Context, resources []ResourceResult,
+	cleaner *appsv1alpha1.Cleaner, runID string, runErr error, logger logr.Logger) (err error) {
+
+	// Same resource can be matched more than once (for instance by overlapping
+	// rules). Report and notifications must list it once.
+	resources = dedupResourceResults(resources)
+	recordReportResourceCount(cleaner.Name, len(resources))
+
+	ctx, span := tracer.Start(ctx, notifySpanName, trace.WithAttributes(
+		attribute.String(attributeCleanerName, cleaner.Name),
+		attribute.String(attributeRunID, runID),
+		attribute.Int(attributeResourceCount, len(resources)),
+	))
+	defer func() { endSpan(span, err) }()
+
+	run := &notificationRun{
+		resources:   resources,
+		cleaner:     cleaner,
+		runID:       runID,
+		runErr:      runErr,
+		resolved:    isResolvedRun(cleaner, resources, runErr),
+		reportSpecs: make(map[string]*appsv1alpha1.ReportSpec),
+		now:         time.Now(),
+	}
+	if runErr == nil {
+		run.throttle = getResourceThrottle(cleaner, resources, run.now)
+	}
+	// Previous report is read before any notification of this run stores its own
+	var previousErr error
+	run.previous, previousErr = getPreviousReportSpec(ctx, cleaner)
+	if previousErr != nil {
+		logger.Error(previousErr, "failed to get previous report")
+	}
+	throttled := false
+	outcomes := make([]appsv1alpha1.NotificationOutcome, 0, len(cleaner.Spec.Notifications))
+	var errs []error
+	var failed []string
 
 	for i := range cleaner.Spec.Notifications {
 		notification := &cleaner.Spec.Notifications[i]
-		logger = logger.WithValues("notification", fmt.Sprintf("%s:%s", notification.Type, notification.Name))
-		logger.V(logs.LogDebug).Info("deliver notification")
+		logger := getNotificationLogger(logger, notification)
+		outcome, notificationThrottled, sendErr := sendRunNotificationIfDue(ctx, run, notification, logger)
+		if outcome == nil {
+			continue
+		}
+		outcomes = append(outcomes, *outcome)
+		if sendErr != nil {
+			// Keep sending the other notifications
+			logger.Error(sendErr, logMsgSendFailed)
+			if isIgnoreFailureNotification(notification) {
+				logger.V(logs.LogInfo).Info(logMsgFailureIgnored)
+				continue
+			}
+			errs = append(errs, sendErr)
+			failed = append(failed, notification.Name)
+			continue
+		}
+		throttled = throttled || notificationThrottled
+	}
 
-		var err error
+	if recordErr := recordNotificationOutcomes(ctx, cleaner, outcomes); recordErr != nil {
+		logger.Error(recordErr, logMsgRecordStatusFailed)
+	}
+	// Resources are considered notified only when every throttled notification
+	// succeeded, so they are notified again by next run otherwise
+	if throttled && len(errs) == 0 {
+		if recordErr := recordNotifiedResources(ctx, cleaner, run.throttle); recordErr != nil {
+			logger.Error(recordErr, logMsgRecordStatusFailed)
+		}
+	}
+	return aggregateNotificationErrors(failed, errs)
+}
 
-		switch notification.Type {
-		case appsv1alpha1.NotificationTypeCleanerReport:
-			err = createReportInstance(ctx, cleaner, reportSpec, logger)
-		case appsv1alpha1.NotificationTypeSlack:
-			err = sendSlackNotification(ctx, reportSpec, message, notification, logger)
-		case appsv1alpha1.NotificationTypeWebex:
-			err = sendWebexNotification(ctx, reportSpec, message, notification, logger)
-		case appsv1alpha1.NotificationTypeDiscord:
-			err = sendDiscordNotification(ctx, reportSpec, message, notification, logger)
-		case appsv1alpha1.NotificationTypeTeams:
-			err = sendTeamsNotification(ctx, reportSpec, message, notification, logger)
-		case appsv1alpha1.NotificationTypeSMTP:
-			err = sendSmtpNotification(ctx, reportSpec, message, notification, logger)
+// notificationRun contains what the notifications of a run are sent with
+type notificationRun struct {
+	resources []ResourceResult
+	cleaner   *appsv1alpha1.Cleaner
+	runID     string
+	runErr    error
+	// resolved is true if the run resolves the issues reported by previous runs
+	resolved bool
+	// throttle lists the resources already notified. Nil when the run failed.
+	throttle *resourceThrottle
+	// previous is the report of the previous run, nil if there is none
+	previous *appsv1alpha1.ReportSpec
+	// reportSpecs caches the report of each resource scope
+	reportSpecs map[string]*appsv1alpha1.ReportSpec
+	now         time.Time
+}
+
+// sendRunNotificationIfDue sends notification for run, unless it is disabled,
+// suspended or outside its active window. The returned outcome is nil when the
+// notification is not concerned by the run. throttled is true when only the
+// resources not notified yet were sent.
+func sendRunNotificationIfDue(ctx context.Context, run *notificationRun, notification *appsv1alpha1.Notification,
+	logger logr.Logger) (outcome *appsv1alpha1.NotificationOutcome, throttled bool, err error) {
+
+	isFailure := run.runErr != nil && notification.NotifyOnFailure
+	isResolved := run.resolved && notification.NotifyOnResolved
+	isThreshold := isThresholdExceededRun(run.cleaner, run.resources, run.runErr, notification.WarningThreshold)
+	if run.runErr != nil && !isFailure && len(run.resources) == 0 {
+		return nil, false, nil
+	}
+	if reason := getNotificationSkipReason(ctx, run.cleaner, notification, run.now, logger); reason != "" {
+		skipped := getSkippedOutcome(notification.Name, reason)
+		return &skipped, false, nil
+	}
+	// Failures are always sent right away
+	inWindow, err := isInNotificationWindow(notification, run.now)
+	if err == nil && !inWindow && !isFailure &&
+		(isResolved || isThreshold || !isQueueWindowNotification(notification)) {
+		logger.V(logs.LogInfo).Info(logMsgOutsideActiveWindow)
+		skipped := getSkippedOutcome(notification.Name, logMsgOutsideActiveWindow)
+		return &skipped, false, nil
+	}
+
+	// Failure, resolved and threshold exceeded reports list every resource
+	var throttle *resourceThrottle
+	if !isFailure && !isResolved && !isThreshold && isThrottledNotification(run.throttle, notification) {
+		throttle = run.throttle
+	}
+
+	result := appsv1alpha1.NotificationOutcomeFailed
+	if err == nil {
+		result, err = sendRunNotification(ctx, run.resources, run.cleaner, run.runID, run.runErr, notification,
+			run.reportSpecs, run.previous, throttle, isFailure, isResolved, isThreshold,
+			!inWindow && !isFailure, run.now, logger)
+	}
+	if err == nil && result == appsv1alpha1.NotificationOutcomeDelivered {
+		logger.V(logs.LogDebug).Info(logMsgNotificationDelivered)
+	}
+	delivery := getDeliveryOutcome(notification.Name, result, err)
+	return &delivery, throttle != nil, err
+}
+
+// getNotificationSkipReason logs and returns why notification is skipped
+// whatever the run: it is disabled, suspended because of repeated failures, or
+// it creates a CleanerReport while reports are disabled. An empty string is
+// returned if notification can be sent.
+func getNotificationSkipReason(ctx context.Context, cleaner *appsv1alpha1.Cleaner,
+	notification *appsv1alpha1.Notification, now time.Time, logger logr.Logger) string {
+
+	switch {
+	case !isNotificationEnabled(notification):
+		logger.V(logs.LogInfo).Info(logMsgNotificationDisabled)
+		return logMsgNotificationDisabled
+	case isNotificationSuspended(cleaner, notification.Name, now) && !isSuspensionIgnored(ctx):
+		logger.V(logs.LogInfo).Info(logMsgNotificationSuspended,
+			"suspendedUntil", getNotificationStatus(cleaner, notification.Name).SuspendedUntil.Format(time.RFC3339))
+		return logMsgNotificationSuspended
+	case notification.Type == appsv1alpha1.NotificationTypeCleanerReport && cleaner.Spec.DisableReport:
+		logger.V(logs.LogInfo).Info(logMsgReportDisabled)
+		return logMsgReportDisabled
+	}
+	return ""
+}
+
+// aggregateNotificationErrors returns the error of the only failed notification
+// as is. When more notifications failed, each error is prefixed with the name
+// of its notification and an aggregate of them is returned.
+func aggregateNotificationErrors(notificationNames []string, errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	wrapped := make([]error, len(errs))
+	for i := range errs {
+		wrapped[i] = fmt.Errorf("notification %s: %w", notificationNames[i], errs[i])
+	}
+	return utilerrors.NewAggregate(wrapped)
+}
+
+// sendRunNotification delivers the report of a run to notification, or adds it
+// to the notification digest or batch, or, when queue is set, to the reports
+// queued till its active window opens. It returns the outcome of the notification.
+// When the report is batched, the delivery result is recorded when the batch is sent.
+// reportSpecs caches the reports generated so far, by time zone. When throttle
+// is set, only its resources are reported and the throttled ones are listed in
+// the message. previous, if not nil, is the report of the previous run the
+// resources are compared to.
+// When notification has NamespaceRoutes, one report is sent per Secret the
+// resources are routed to, only listing its resources.
+func sendRunNotification(ctx context.Context, resources []ResourceResult, cleaner *appsv1alpha1.Cleaner,
+	runID string, runErr error, notification *appsv1alpha1.Notification, reportSpecs map[string]*appsv1alpha1.ReportSpec,
+	previous *appsv1alpha1.ReportSpec, throttle *resourceThrottle, isFailure, isResolved, isThreshold, queue bool, now time.Time,
+	logger logr.Logger) (appsv1alpha1.NotificationOutcomeType, error) {
+
+	filter, err := getResourceFilter(notification.ResourceFilter)
+	if err != nil {
+		return appsv1alpha1.NotificationOutcomeFailed, err
+	}
+	targets, err := getNamespaceRouteTargets(notification, filter, filterResourcesByScope(resources, notification.ResourceScope))
+	if err != nil {
+		return appsv1alpha1.NotificationOutcomeFailed, err
+	}
+
+	// Outcome is Failed if any target failed, and Batched only if every target
+	// was batched
+	outcome := appsv1alpha1.NotificationOutcomeDelivered
+	batched := 0
+	var errs []error
+	for i := range targets {
+		l := logger
+		if targets[i].secret != "" {
+			l = logger.WithValues("routedSecret", targets[i].secret)
+		}
+		targetOutcome, sendErr := sendRunNotificationTarget(ctx, resources, cleaner, runID, runErr, targets[i].notification,
+			targets[i].filter, reportSpecs, previous, throttle, isFailure, isResolved, isThreshold, queue, now, l)
+		switch {
+		case sendErr != nil:
+			// Keep sending to the other Secrets
+			errs = append(errs, sendErr)
+		case targetOutcome == appsv1alpha1.NotificationOutcomeBatched:
+			batched++
 		default:
-			logger.V(logs.LogInfo).Info("no handler registered for notification")
-			panic(1)
+			outcome = targetOutcome
 		}
+	}
+	switch {
+	case len(errs) == 1:
+		outcome = appsv1alpha1.NotificationOutcomeFailed
+		err = errs[0]
+	case len(errs) > 1:
+		outcome = appsv1alpha1.NotificationOutcomeFailed
+		err = utilerrors.NewAggregate(errs)
+	case len(targets) > 0 && batched == len(targets):
+		outcome = appsv1alpha1.NotificationOutcomeBatched
+	}
+	if outcome != appsv1alpha1.NotificationOutcomeBatched {
+		if recordErr := recordNotificationResult(ctx, cleaner, notification.Name, err, now); recordErr != nil {
+			logger.Error(recordErr, logMsgRecordStatusFailed)
+		}
+	}
+	return outcome, err
+}
 
-		if err != nil {
-			logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to send notification: %v", err))
-			return err
+// sendRunNotificationTarget sends the report of a run to notification, only
+// listing the resources kept by filter. See sendRunNotification.
+func sendRunNotificationTarget(ctx context.Context, resources []ResourceResult, cleaner *appsv1alpha1.Cleaner,
+	runID string, runErr error, notification *appsv1alpha1.Notification, filter *resourceFilter,
+	reportSpecs map[string]*appsv1alpha1.ReportSpec, previous *appsv1alpha1.ReportSpec, throttle *resourceThrottle,
+	isFailure, isResolved, isThreshold, queue bool, now time.Time,
+	logger logr.Logger) (appsv1alpha1.NotificationOutcomeType, error) {
+
+	// Delta compares every resource matched by the run, throttled or not
+	runResources := resources
+	if throttle != nil {
+		resources = throttle.resources
+		reportSpecs = throttle.reportSpecs
+	}
+	notificationResources := filterResourcesByFilter(filterResourcesByScope(resources, notification.ResourceScope), filter)
+	logger.V(logs.LogDebug).Info(logMsgDeliverNotification, logKeyResources, len(notificationResources))
+
+	// Timestamps are formatted in the notification time zone, so the report
+	// is generated once per time zone, and only for notifications sent
+	location, err := getNotificationLocation(notification)
+	if err != nil {
+		return appsv1alpha1.NotificationOutcomeFailed, err
+	}
+	reportSpec, ok := reportSpecs[location.String()]
+	if !ok {
+		reportSpec = generateReportSpec(resources, cleaner, runID, now.In(location))
+		reportSpecs[location.String()] = reportSpec
+	}
+	// Report is shared by notifications in the same time zone. Notifiers only
+	// set top level fields, so a shallow copy is enough
+	shared := *reportSpec
+	reportSpec = &shared
+	filterReportByScope(reportSpec, notification.ResourceScope)
+	filterReportByFilter(reportSpec, filter)
+	notificationMessage, err := getNotificationMessage(cleaner.Name, cleaner.Spec.Action, len(notificationResources),
+		runID, notification)
+	if err != nil {
+		return appsv1alpha1.NotificationOutcomeFailed, err
+	}
+	notificationMessage += getDeltaNote(previous,
+		filterResourcesByFilter(filterResourcesByScope(runResources, notification.ResourceScope), filter),
+		notification, filter)
+	if throttle != nil {
+		notificationMessage += getStillPresentNote(filterStillPresent(throttle.stillPresent,
+			notification.ResourceScope, filter))
+	}
+	notificationMessage += getKubectlNote(cleaner, reportSpec, notification)
+	if isFailure {
+		reportSpec.Error = truncateString(runErr.Error(), maxRunErrorSize)
+		notificationMessage = getFailureMessage(cleaner.Name, runID, reportSpec)
+	} else if isResolved {
+		notificationMessage = getResolvedMessage(cleaner.Name, getLastMatchCount(cleaner), runID)
+	} else if isThreshold {
+		notificationMessage = getThresholdExceededMessage(cleaner.Name, len(resources),
+			*notification.WarningThreshold, getLastMatchCount(cleaner), runID)
+	}
+	notificationMessage = getReasonMessage(cleaner.Spec.Reason, notificationMessage)
+	// Failure, resolved and threshold exceeded reports are sent right away
+	immediate := isFailure || isResolved || isThreshold
+
+	notificationCtx, notificationSpan := tracer.Start(ctx, getNotificationSpanName(notification.Type),
+		trace.WithAttributes(
+			attribute.String(attributeCleanerName, cleaner.Name),
+			attribute.String(attributeNotificationName, notification.Name),
+			attribute.String(attributeNotificationType, string(notification.Type)),
+			attribute.String(attributeRunID, runID),
+			attribute.Int(attributeResourceCount, len(notificationResources)),
+		))
+
+	outcome := appsv1alpha1.NotificationOutcomeDelivered
+	switch {
+	case queue:
+		outcome = appsv1alpha1.NotificationOutcomeQueued
+		err = queueReport(notificationCtx, cleaner, reportSpec, notification, now, logger)
+	case isDigestNotification(notification) && !immediate:
+		err = processDigest(notificationCtx, cleaner, reportSpec, notification, logger)
+	case hasQueuedReports(cleaner, notification.Name) && !immediate:
+		err = sendQueuedReports(notificationCtx, cleaner, reportSpec, notification, now, logger)
+	case isBatchNotification(ctx, notification) && !immediate:
+		outcome = appsv1alpha1.NotificationOutcomeBatched
+		err = addToNotificationBatch(cleaner, reportSpec, notificationMessage, notification, logger)
+	default:
+		// Only the delivery is attempted again, so other targets are not sent
+		// twice and the delivery result is recorded once per run
+		err = sendWithFailurePolicy(notificationCtx, notification, func(ctx context.Context) error {
+			return deliverNotification(ctx, cleaner, reportSpec, notificationResources, notificationMessage,
+				notification, logger)
+		}, logger)
+	}
+	endSpan(notificationSpan, err)
+	return outcome, err
+}
+
+// deliverNotification sends a single notification using the notifier registered
+// for its type. Reports too large for the channel are stored in the Report instance
+// first. When the notification has a channel template, resources are grouped by
+// channel and one message is sent per channel.
+func deliverNotification(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	resources []ResourceResult, message string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+	n, err := getNotifier(notification.Type)
+	if err != nil {
+		logger.Error(err, "no notifier registered")
+		return err
+	}
+	// Report instance stores the full report, so redaction and field selection
+	// are applied after handling overflow
+	redactor, err := getReportRedactor(cleaner.Spec.ReportRedaction, notification.Type)
+	if err != nil {
+		logger.Error(err, logMsgSendFailed)
+		return err
+	}
+	message = redactor.redactString(handleReportOverflow(ctx, cleaner, reportSpec, message, notification, logger))
+
+	// Digests and failures without resources are sent to the default channel
+	if notification.ChannelTemplate == "" || len(resources) == 0 {
+		return n.Send(ctx, cleaner, redactor.redactReport(selectReportFields(reportSpec, notification)), resources, message,
+			notification, logger)
+	}
+	if !isRoutingSupported(notification.Type) {
+		logger.V(logs.LogInfo).Info("channel template is not supported by this notification type. Ignore it")
+		return n.Send(ctx, cleaner, redactor.redactReport(selectReportFields(reportSpec, notification)), resources, message,
+			notification, logger)
+	}
+
+	groups, err := groupByChannel(notification.ChannelTemplate, reportSpec, resources)
+	if err != nil {
+		logger.Error(err, "failed to route resources to channels")
+		return err
+	}
+	for _, group := range groups {
+		l := logger.WithValues("routedChannel", group.channel)
+		l.V(logs.LogDebug).Info("send resources to routed channel", logKeyResources, len(group.resources))
+		// Keep sending to the other channels
+		// Resources are routed on their actual names, so each group is redacted,
+		// and its fields selected, once routed
+		groupSpec := redactor.redactReport(selectReportFields(group.reportSpec, notification))
+		if sendErr := n.Send(withRoutedChannel(ctx, group.channel), cleaner, groupSpec,
+			group.resources, message, notification, l); sendErr != nil {
+
+			err = sendErr
 		}
-		logger.V(logs.LogDebug).Info("notification delivered")
 	}
-	return nil
+	return err
 }
 
-func generateReportSpec(resources []ResourceResult, cleaner *appsv1alpha1.Cleaner) *appsv1alpha1.ReportSpec {
-	reportSpec := appsv1alpha1.ReportSpec{}
+// getReportMessage returns the text sent along with a report: the Cleaner
+// instance, the action it performed and the number of resources affected
+func getReportMessage(cleanerName string, action appsv1alpha1.Action, resourceCount int, runID string) string {
+	noun := "resources"
+	if resourceCount == 1 {
+		noun = "resource"
+	}
+	message := fmt.Sprintf("k8s-cleaner '%s' performed %s on %d %s", cleanerName, action, resourceCount, noun)
+	if runID != "" {
+		message += fmt.Sprintf(" (run ID: %s)", runID)
+	}
+	return message
+}
+
+// getFailureMessage returns the text sent along with the report of a failed run
+func getFailureMessage(cleanerName, runID string, reportSpec *appsv1alpha1.ReportSpec) string {
+	message := fmt.Sprintf("Execution failed for k8s-cleaner instance: %s", cleanerName)
+	if runID != "" {
+		message += fmt.Sprintf(" (run ID: %s)", runID)
+	}
+	return message + fmt.Sprintf(". %d resource(s) processed before the failure. Error: %s",
+		len(reportSpec.ResourceInfo), reportSpec.Error)
+}
+
+// getNotificationLocation returns the time zone used to format timestamps
+// in notification. UTC is returned if none is set.
+func getNotificationLocation(notification *appsv1alpha1.Notification) (*time.Location, error) {
+	if notification.Timezone == "" {
+		return time.UTC, nil
+	}
+
+	location, err := time.LoadLocation(notification.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("notification %s: invalid timezone %q, must be an IANA time zone name (e.g. Europe/Rome): %w",
+			notification.Name, notification.Timezone, err)
+	}
+	return location, nil
+}
+
+// dedupResourceResults returns resources with duplicates, same kind, apiVersion,
+// namespace and name, merged into the first occurrence. Distinct messages are
+// joined. A failure on any duplicate makes the merged result failed.
+func dedupResourceResults(resources []ResourceResult) []ResourceResult {
+	result := make([]ResourceResult, 0, len(resources))
+	indexes := make(map[string]int, len(resources))
+	for i := range resources {
+		key := getResourceResultKey(&resources[i])
+		index, ok := indexes[key]
+		if !ok {
+			indexes[key] = len(result)
+			result = append(result, resources[i])
+			continue
+		}
+
+		merged := &result[index]
+		if resources[i].Message != "" && !containsMessage(merged.Message, resources[i].Message) {
+			if merged.Message == "" {
+				merged.Message = resources[i].Message
+			} else {
+				merged.Message += resourceMessageSeparator + resources[i].Message
+			}
+		}
+		if merged.Diff == "" {
+			merged.Diff = resources[i].Diff
+		}
+		if merged.Outcome == "" ||
+			(resources[i].Outcome == appsv1alpha1.ResourceOutcomeFailed && merged.Outcome != appsv1alpha1.ResourceOutcomeFailed) {
+
+			merged.Outcome = resources[i].Outcome
+			merged.Error = resources[i].Error
+		}
+	}
+	return result
+}
+
+func getResourceResultKey(resource *ResourceResult) string {
+	return fmt.Sprintf("%s/%s/%s/%s", resource.Resource.GetAPIVersion(), resource.Resource.GetKind(),
+		resource.Resource.GetNamespace(), resource.Resource.GetName())
+}
+
+// containsMessage returns true if message is one of the messages merged in messages
+func containsMessage(messages, message string) bool {
+	for _, current := range strings.Split(messages, resourceMessageSeparator) {
+		if current == message {
+			return true
+		}
+	}
+	return false
+}
+
+// generateReportSpec returns the report for resources. now is the time,
+// in the notification time zone, the report is generated.
+func generateReportSpec(resources []ResourceResult, cleaner *appsv1alpha1.Cleaner,
+	runID string, now time.Time) *appsv1alpha1.ReportSpec {
+
+	reportSpec := appsv1alpha1.ReportSpec{SchemaVersion: appsv1alpha1.ReportSchemaVersion}
 	reportSpec.Action = cleaner.Spec.Action
-	message := fmt.Sprintf(". time: %v", time.Now())
+	reportSpec.RunID = runID
+	reportSpec.Reason = cleaner.Spec.Reason
+	message := fmt.Sprintf(". time: %s", now.Format(time.RFC3339))
 
 	reportSpec.ResourceInfo = make([]appsv1alpha1.ResourceInfo, len(resources))
 	for i := range resources {
@@ -121,69 +669,512 @@ func generateReportSpec(resources []ResourceResult, cleaner *appsv1alpha1.Cleane
 				APIVersion: resources[i].Resource.GetAPIVersion(),
 			},
 			Message: resources[i].Message + message,
+			Diff:    resources[i].Diff,
+			Outcome: resources[i].Outcome,
+			Error:   resources[i].Error,
+			Backup:  resources[i].Backup,
+		}
+		if selection := cleaner.Spec.ReportResourceMetadata; selection != nil {
+			reportSpec.ResourceInfo[i].Labels = selectResourceMetadata(resources[i].Resource.GetLabels(),
+				selection.Labels)
+			reportSpec.ResourceInfo[i].Annotations = selectResourceMetadata(resources[i].Resource.GetAnnotations(),
+				selection.Annotations)
 		}
 	}
+	// Failed resources are listed first so they are the first thing read and
+	// are never dropped when a report is truncated.
+	// Resources are otherwise sorted, so reports do not depend on the order
+	// resources were processed in
+	sort.Slice(reportSpec.ResourceInfo, func(i, j int) bool {
+		iFailed := reportSpec.ResourceInfo[i].Outcome == appsv1alpha1.ResourceOutcomeFailed
+		jFailed := reportSpec.ResourceInfo[j].Outcome == appsv1alpha1.ResourceOutcomeFailed
+		if iFailed != jFailed {
+			return iFailed
+		}
+		return lessResource(&reportSpec.ResourceInfo[i].Resource, &reportSpec.ResourceInfo[j].Resource)
+	})
+	reportSpec.Summary = getReportSummary(reportSpec.ResourceInfo)
+	if cleaner.Spec.IncludeSpecInReport {
+		reportSpec.CleanerSpec = getReportCleanerSpec(cleaner)
+	}
 
 	return &reportSpec
 }
 
+// getReportCleanerSpec returns the rules of cleaner included in its reports
+func getReportCleanerSpec(cleaner *appsv1alpha1.Cleaner) *appsv1alpha1.ReportCleanerSpec {
+	spec := cleaner.Spec.DeepCopy()
+	return &appsv1alpha1.ReportCleanerSpec{
+		Schedule:            spec.Schedule,
+		Action:              spec.Action,
+		ResourceSelectors:   spec.ResourcePolicySet.ResourceSelectors,
+		AggregatedSelection: spec.ResourcePolicySet.AggregatedSelection,
+		Transform:           spec.Transform,
+		DeleteOptions:       spec.DeleteOptions,
+	}
+}
+
+// lessResource orders resources by namespace, kind, name and then apiVersion
+func lessResource(a, b *corev1.ObjectReference) bool {
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+	if a.Kind != b.Kind {
+		return a.Kind < b.Kind
+	}
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	return a.APIVersion < b.APIVersion
+}
+
+// getReportSummary returns the counts of resources, total, per kind and per
+// namespace
+func getReportSummary(resourceInfo []appsv1alpha1.ResourceInfo) *appsv1alpha1.ReportSummary {
+	summary := &appsv1alpha1.ReportSummary{
+		Total: int32(len(resourceInfo)),
+	}
+	for i := range resourceInfo {
+		resource := &resourceInfo[i].Resource
+		if summary.ByKind == nil {
+			summary.ByKind = make(map[string]int32)
+		}
+		summary.ByKind[resource.Kind]++
+		if resourceInfo[i].Outcome == appsv1alpha1.ResourceOutcomeFailed {
+			summary.Failed++
+		}
+		if resource.Namespace != "" {
+			if summary.ByNamespace == nil {
+				summary.ByNamespace = make(map[string]int32)
+			}
+			summary.ByNamespace[resource.Namespace]++
+		}
+	}
+	return summary
+}
+
+// selectResourceMetadata returns the entries of values whose key is in keys. A key
+// ending with "*" selects all entries with that prefix.
+// To keep reports small, at most maxReportMetadataEntries entries are returned
+// and values are truncated to maxReportMetadataValueSize bytes.
+func selectResourceMetadata(values map[string]string, keys []string) map[string]string {
+	if len(values) == 0 || len(keys) == 0 {
+		return nil
+	}
+
+	matches := func(key string) bool {
+		for i := range keys {
+			if prefix, ok := strings.CutSuffix(keys[i], "*"); ok {
+				if strings.HasPrefix(key, prefix) {
+					return true
+				}
+			} else if key == keys[i] {
+				return true
+			}
+		}
+		return false
+	}
+
+	var selected map[string]string
+	for _, key := range getSortedMetadataKeys(values) {
+		if !matches(key) {
+			continue
+		}
+		if len(selected) == maxReportMetadataEntries {
+			break
+		}
+		if selected == nil {
+			selected = make(map[string]string)
+		}
+		value := values[key]
+		if len(value) > maxReportMetadataValueSize {
+			value = strings.ToValidUTF8(value[:maxReportMetadataValueSize], "")
+		}
+		selected[key] = value
+	}
+	return selected
+}
+
+// createReportInstance stores reportSpec in the Report instance named after the
+// Cleaner, creating it if needed. On conflict, because the Report was modified
+// (or created) concurrently, the Report is fetched again and reportSpec applied
+// to the latest version. When the Report kind is not known yet, because the CRD
+// is not established, storing is retried following reportKindBackoff.
 func createReportInstance(ctx context.Context, cleaner *appsv1alpha1.Cleaner,
 	reportSpec *appsv1alpha1.ReportSpec, logger logr.Logger) error {
 
-	report := &appsv1alpha1.Report{}
-	err := k8sClient.Get(ctx, types.NamespacedName{Name: cleaner.Name}, report)
+	return retry.OnError(reportKindBackoff, meta.IsNoMatchError, func() error {
+		return storeReportInstance(ctx, cleaner, reportSpec, logger)
+	})
+}
+
+func storeReportInstance(ctx context.Context, cleaner *appsv1alpha1.Cleaner,
+	reportSpec *appsv1alpha1.ReportSpec, logger logr.Logger) error {
+
+	isRetriable := func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}
+
+	c, err := getK8sClient()
 	if err != nil {
-		if apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return retry.OnError(retry.DefaultRetry, isRetriable, func() error {
+		report := &appsv1alpha1.Report{}
+		err := c.Get(ctx, types.NamespacedName{Name: cleaner.Name}, report)
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				return err
+			}
 			logger.V(logs.LogInfo).Info("create report instance")
 			report.Name = cleaner.Name
 			report.Spec = *reportSpec
-			return k8sClient.Create(ctx, report)
+			err = c.Create(ctx, report)
+		} else {
+			report.Spec = *reportSpec
+			logger.V(logs.LogInfo).Info("update report instance")
+			err = c.Update(ctx, report)
+		}
+		if err != nil {
+			return err
 		}
 
-		return err
-	}
-
-	report.Spec = *reportSpec
-	logger.V(logs.LogInfo).Info("update report instance")
-	return k8sClient.Update(ctx, report)
+		// Status is a subresource: the spec is stored first. A conflict storing
+		// the history stores the report again.
+		history := getReportHistory(report.Status.History, reportSpec, cleaner.Spec.ReportHistoryLimit, metav1.Now())
+		if len(history) == 0 && len(report.Status.History) == 0 {
+			return nil
+		}
+		report.Status.History = history
+		return c.Status().Update(ctx, report)
+	})
 }
 
-func sendSlackNotification(ctx context.Context, reportSpec *appsv1alpha1.ReportSpec,
+func sendSlackNotification(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
 	message string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
 
-	info, err := getSlackInfo(ctx, notification)
+	links, err := getNotificationLinks(cleaner.Name, reportSpec, notification)
 	if err != nil {
+		logger.Error(err, logMsgSendFailed)
 		return err
 	}
 
-	l := logger.WithValues("channel", info.channelID)
-	l.V(logs.LogInfo).Info("send slack message")
-
-	resourceSpecString, err := json.Marshal(*reportSpec)
+	uploadReport := isRawReportIncluded(notification) && !isAttachmentDisabled(notification) &&
+		notification.Slack != nil && notification.Slack.UploadReport
+	msg, err := getSlackMessage(reportSpec, message, notification, links, uploadReport)
 	if err != nil {
-		l.V(logs.LogInfo).Info(fmt.Sprintf("failed to marshal resourceSpec: %v", err))
+		logger.Error(err, logMsgMarshalReportFailed)
 		return err
 	}
 
-	attachment := slack.Attachment{
-		Text: string(resourceSpecString),
+	refs := getNotificationRefs(notification)
+	for i := range refs {
+		var info *slackInfo
+		info, err = getSlackInfoFromRef(ctx, notification, refs[i])
+		if err != nil {
+			// A misconfigured reference is reported, not hidden by failing over
+			logger.Error(err, "failed to get slack info", "secret", getCredentialSource(notification, refs[i]))
+			return err
+		}
+
+		if info.webhookURL != "" {
+			l := logger.WithValues("secret", getCredentialSource(notification, refs[i]))
+			err = sendSlackWebhookNotification(ctx, info, reportSpec, message, notification, links, msg,
+				uploadReport, l)
+			if err == nil || !isSlackAuthError(err) {
+				return err
+			}
+			continue
+		}
+
+		l := logger.WithValues("secret", getCredentialSource(notification, refs[i]),
+			logKeyChannel, info.channelID)
+		l.V(logs.LogInfo).Info("send slack message")
+
+		var api slackClient
+		api, err = getSlackChannelClient(ctx, info, l)
+		if err != nil {
+			if !isSlackAuthError(err) {
+				return err
+			}
+			continue
+		}
+
+		now := time.Now()
+		thread := getActiveSlackThread(cleaner, notification, info.channelID, now)
+		if thread != nil {
+			l.V(logs.LogDebug).Info("reply in thread", "thread", thread.Timestamp)
+		}
+
+		var timestamp string
+		_, timestamp, err = api.PostMessage(info.channelID, getSlackMsgOptions(msg, notification, thread)...)
+		err = verifySlackDelivery(timestamp, err)
+		if err == nil {
+			l.V(logs.LogInfo).Info("slack message sent")
+			recordSlackDelivery(ctx, api, cleaner, notification, info.channelID, timestamp, thread, now, l)
+			if !uploadReport {
+				return nil
+			}
+			threadTimestamp := timestamp
+			if thread != nil {
+				threadTimestamp = thread.Timestamp
+			}
+			// Summary message was already posted, so do not fail over
+			return uploadSlackReport(ctx, api, cleaner, reportSpec.RunID, info.channelID, threadTimestamp,
+				msg.reportData, l)
+		}
+
+		l.Error(err, logMsgSendFailed)
+		if !isSlackAuthError(err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// sendSlackWebhookNotification posts msg to the incoming webhook of info.
+// Incoming webhooks cannot upload files, so when uploadReport is set the report
+// is sent as attachment instead.
+func sendSlackWebhookNotification(ctx context.Context, info *slackInfo, reportSpec *appsv1alpha1.ReportSpec,
+	message string, notification *appsv1alpha1.Notification, links []notificationLink, msg *slackMessage,
+	uploadReport bool, logger logr.Logger) error {
+
+	if uploadReport {
+		var err error
+		if msg, err = getSlackMessage(reportSpec, message, notification, links, false); err != nil {
+			logger.Error(err, logMsgMarshalReportFailed)
+			return err
+		}
+	}
+
+	err := postSlackWebhook(ctx, info, msg, notification, logger)
+	if err != nil {
+		logger.Error(err, logMsgSendFailed)
+	}
+	return err
+}
+
+// getSlackChannelClient returns the Slack client for the token of info. The
+// channel of info can be a name, and it is resolved to the ID threads are
+// tracked by.
+func getSlackChannelClient(ctx context.Context, info *slackInfo, logger logr.Logger) (slackClient, error) {
+	if info.token == "" {
+		err := fmt.Errorf("slack token is empty")
+		logger.Error(err, logMsgSendFailed)
+		return nil, err
 	}
 
-	api := slack.New(info.token)
+	api := newSlackClient(info.token)
 	if api == nil {
-		l.V(logs.LogInfo).Info("failed to get slack client")
+		err := fmt.Errorf("failed to get slack client")
+		logger.Error(err, logMsgSendFailed)
+		return nil, err
 	}
 
-	_, _, err = api.PostMessage(info.channelID, slack.MsgOptionText(message, false), slack.MsgOptionAttachments(attachment))
+	channelID, err := getSlackChannelID(ctx, api, info.token, info.channelID, time.Now())
 	if err != nil {
-		l.V(logs.LogInfo).Info(fmt.Sprintf("Failed to send message. Error: %v", err))
-		return err
+		logger.Error(err, logMsgSendFailed)
+		return nil, err
+	}
+	info.channelID = channelID
+	return api, nil
+}
+
+// getSlackMsgOptions returns the options msg is posted with: its text, its
+// attachments and link buttons, the sender overrides and, when replying in
+// thread, the thread timestamp
+func getSlackMsgOptions(msg *slackMessage, notification *appsv1alpha1.Notification,
+	thread *appsv1alpha1.SlackThread) []slack.MsgOption {
+
+	options := []slack.MsgOption{slack.MsgOptionText(msg.text, false)}
+	if attachments := msg.getAttachments(); len(attachments) > 0 {
+		options = append(options, slack.MsgOptionAttachments(attachments...))
+	}
+	options = append(options, getSlackSenderOptions(notification)...)
+	if thread != nil {
+		options = append(options, slack.MsgOptionTS(thread.Timestamp))
+	}
+	return options
+}
+
+// recordSlackDelivery stores, in the Cleaner status, the thread started by the
+// message posted at timestamp and the message itself, as the notification
+// requires. The message was delivered, so failures are only logged.
+func recordSlackDelivery(ctx context.Context, api slackClient, cleaner *appsv1alpha1.Cleaner,
+	notification *appsv1alpha1.Notification, channelID, timestamp string, thread *appsv1alpha1.SlackThread,
+	now time.Time, logger logr.Logger) {
+
+	if thread == nil && isSlackThreadNotification(notification) {
+		// Failing to store the thread only means next message starts a new thread
+		if err := updateSlackThread(ctx, cleaner.Name,
+			newSlackThread(notification.Name, channelID, timestamp, now)); err != nil {
+			logger.Error(err, "failed to store slack thread")
+		}
+	}
+	if isSlackMessageRecorded(notification) {
+		if err := updateSlackMessage(ctx, cleaner.Name, newSlackMessage(ctx, api, notification.Name,
+			channelID, timestamp, now, logger)); err != nil {
+			logger.Error(err, "failed to store slack message")
+		}
+	}
+}
+
+// slackMessage is the content of a Slack notification
+type slackMessage struct {
+	text       string
+	attachment slack.Attachment
+	// reportData is the report uploaded as file when uploadReport is set
+	reportData string
+	// links are rendered as buttons
+	links []notificationLink
+}
+
+// getAttachments returns the attachments of the message: the report attachment,
+// unless empty, followed by the buttons of the links, if any
+func (m *slackMessage) getAttachments() []slack.Attachment {
+	var attachments []slack.Attachment
+	if m.attachment.Text != "" || len(m.attachment.Fields) > 0 {
+		attachments = append(attachments, m.attachment)
+	}
+	if len(m.links) > 0 {
+		buttons := make([]slack.BlockElement, len(m.links))
+		for i := range m.links {
+			button := slack.NewButtonBlockElement(fmt.Sprintf("link-%d", i), "",
+				slack.NewTextBlockObject(slack.PlainTextType, m.links[i].label, false, false))
+			button.URL = m.links[i].url
+			buttons[i] = button
+		}
+		attachments = append(attachments, slack.Attachment{
+			Blocks: slack.Blocks{BlockSet: []slack.Block{slack.NewActionBlock("links", buttons...)}},
+		})
+	}
+	return attachments
+}
+
+// getSlackMessage returns the text and the attachment posted for reportSpec.
+// When uploadReport is set, the report is uploaded as file rather than included
+// in the attachment.
+func getSlackMessage(reportSpec *appsv1alpha1.ReportSpec, message string, notification *appsv1alpha1.Notification,
+	links []notificationLink, uploadReport bool) (*slackMessage, error) {
+
+	var reportData string
+	var err error
+	if uploadReport {
+		reportData, err = truncateReport(reportSpec, slackMaxFileSize, getReportEncoding(notification.Type))
+	} else {
+		reportData, err = truncateReport(reportSpec, slackMaxReportSize, getReportEncoding(notification.Type))
+	}
+	if err != nil {
+		return nil, err
 	}
 
+	failures := getFailedResourcesMarkdown(reportSpec, slackMaxFailuresSize)
+	attachment := slack.Attachment{}
+	if isRawReportIncluded(notification) && !uploadReport {
+		attachment.Text = reportData
+	}
+	if failures != "" {
+		attachment.Color = "danger"
+	}
+	for _, key := range getSortedMetadataKeys(notification.Metadata) {
+		attachment.Fields = append(attachment.Fields, slack.AttachmentField{
+			Title: key,
+			Value: notification.Metadata[key],
+			Short: true,
+		})
+	}
+
+	// Slack uses single asterisks for bold
+	text := message + "\n" + strings.ReplaceAll(getMessageSummary(reportSpec, notification, slackMaxCodeBlockSize),
+		"**", "*")
+	if mentions := getSlackMentions(reportSpec, notification); mentions != "" {
+		text = mentions + " " + text
+	}
+	if failures != "" {
+		text += "\n" + strings.Replace(failures, "**", "*", 2)
+	}
+	if uploadReport {
+		// Diffs are part of the uploaded report
+		text += "\nFull report attached in thread."
+	} else if diff := getDiffMarkdown(reportSpec, slackMaxDiffSize); diff != "" {
+		text += "\n" + diff
+	}
+
+	return &slackMessage{text: text, attachment: attachment, reportData: reportData, links: links}, nil
+}
+
+// uploadSlackReport uploads the report as a JSON file, shared in channelID in the
+// thread of threadTimestamp. files.uploadV2 flow is used: an upload URL is requested,
+// the file is sent to it and the upload is then completed sharing the file.
+func uploadSlackReport(ctx context.Context, api slackClient, cleaner *appsv1alpha1.Cleaner,
+	runID, channelID, threadTimestamp, reportData string, logger logr.Logger) error {
+
+	fileName := getReportFileName(cleaner.Name, runID, time.Now(), "json")
+	_, err := api.UploadFileV2Context(ctx, slack.UploadFileV2Parameters{
+		Channel:         channelID,
+		ThreadTimestamp: threadTimestamp,
+		Filename:        fileName,
+		Title:           fileName,
+		Content:         reportData,
+		FileSize:        len(reportData),
+	})
+	if err != nil {
+		logger.Error(err, "failed to upload slack report file")
+		return fmt.Errorf("failed to upload slack report file: %w", err)
+	}
+
+	logger.V(logs.LogInfo).Info("slack report file uploaded")
 	return nil
 }
 
-func sendTeamsNotification(ctx context.Context, reportSpec *appsv1alpha1.ReportSpec,
+// getSlackSenderOptions returns the options overriding the name and icon messages
+// are posted with, if set in the notification
+func getSlackSenderOptions(notification *appsv1alpha1.Notification) []slack.MsgOption {
+	options := make([]slack.MsgOption, 0)
+	if notification.Username != "" {
+		options = append(options, slack.MsgOptionUsername(notification.Username))
+	}
+	// Slack uses icon_emoji when both are set
+	if notification.IconEmoji != "" {
+		options = append(options, slack.MsgOptionIconEmoji(notification.IconEmoji))
+	} else if notification.IconURL != "" {
+		options = append(options, slack.MsgOptionIconURL(notification.IconURL))
+	}
+	return options
+}
+
+// isSlackAuthError returns true if err indicates the Slack credentials were
+// rejected (invalid, revoked or lacking permissions)
+func isSlackAuthError(err error) bool {
+	// Incoming webhooks reply 403 when the webhook token is invalid, 404 when
+	// the webhook has been removed and 410 when the channel has been archived
+	var statusErr slack.StatusCodeError
+	if errors.As(err, &statusErr) {
+		switch statusErr.Code {
+		case http.StatusForbidden, http.StatusNotFound, http.StatusGone:
+			return true
+		}
+		return false
+	}
+
+	var slackErr slack.SlackErrorResponse
+	if !errors.As(err, &slackErr) {
+		return false
+	}
+
+	switch slackErr.Err {
+	case "not_authed", "invalid_auth", "account_inactive", "token_revoked",
+		"token_expired", "no_permission", "missing_scope", "not_allowed_token_type",
+		"ekm_access_denied", "team_access_not_granted":
+		return true
+	}
+
+	return false
+}
+
+func sendTeamsNotification(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
 	message string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
 
 	info, err := getTeamsInfo(ctx, notification)
@@ -194,229 +1185,424 @@ func sendTeamsNotification(ctx context.Context, reportSpec *appsv1alpha1.ReportS
 	l := logger.WithValues("webhookUrl", info.webhookUrl)
 	l.V(logs.LogInfo).Info("send teams message")
 
-	teamsClient := goteamsnotify.NewTeamsClient()
+	teamsClient := newTeamsClient(info.tlsConfig)
 
 	// Validate Teams Webhook expected format
-	if teamsClient.ValidateWebhook(info.webhookUrl) != nil {
-		l.V(logs.LogInfo).Info("failed to validate Teams webhook URL: %v", err)
+	if err = teamsClient.ValidateWebhook(info.webhookUrl); err != nil {
+		l.Error(err, "failed to validate Teams webhook URL")
 		return err
 	}
 
-	resourceSpecData, err := json.Marshal(*reportSpec)
+	resourceSpecData, err := truncateReport(reportSpec, teamsMaxReportSize, getReportEncoding(notification.Type))
 	if err != nil {
-		l.V(logs.LogInfo).Info(fmt.Sprintf("failed to marshal resourceSpec: %v", err))
+		l.Error(err, logMsgMarshalReportFailed)
 		return err
 	}
 
-	teamsMessage, err := adaptivecard.NewSimpleMessage(string(resourceSpecData), message, true)
+	links, err := getNotificationLinks(cleaner.Name, reportSpec, notification)
 	if err != nil {
-		l.V(logs.LogInfo).Info("failed to create Teams message: %v", err)
+		l.Error(err, logMsgSendFailed)
+		return err
+	}
+
+	summary := ""
+	if notification.SummaryAsCodeBlock {
+		summary = truncateString(getPlainTextSummary(reportSpec), teamsMaxCodeBlockSize)
+	}
+	teamsMessage, err := getTeamsMessage(resourceSpecData, message, notification.Metadata,
+		getFailedResourcesMarkdown(reportSpec, teamsMaxFailuresSize), getDiffMarkdown(reportSpec, teamsMaxDiffSize),
+		summary, links)
+	if err != nil {
+		l.Error(err, "failed to create Teams message")
 		return err
 	}
 
 	// Send the meesage with the user provided webhook URL
-	if teamsClient.Send(info.webhookUrl, teamsMessage) != nil {
-		l.V(logs.LogInfo).Info("failed to send Teams message: %v", err)
+	if err = verifyTeamsDelivery(getTLSError(teamsClient.Send(info.webhookUrl, teamsMessage))); err != nil {
+		l.Error(err, logMsgSendFailed)
 		return err
 	}
 
 	return nil
 }
 
-func sendDiscordNotification(ctx context.Context, reportSpec *appsv1alpha1.ReportSpec,
-	message string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+// getTeamsMessage returns a Teams message with text and title. Failed resources,
+// if any, are shown in red right after the title. Metadata, if any, is added as a
+// set of facts. Summary and diff, if any, are added as code blocks. Links, if any,
+// are added as OpenUrl actions.
+func getTeamsMessage(text, title string, metadata map[string]string, failures, diff, summary string,
+	links []notificationLink) (*adaptivecard.Message, error) {
 
-	info, err := getDiscordInfo(ctx, notification)
+	card, err := adaptivecard.NewTextBlockCard(text, "", true)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	l := logger.WithValues("room", info.serverID)
-	l.V(logs.LogInfo).Info("send discord message")
+	if failures != "" {
+		failuresBlock := adaptivecard.NewTextBlock(failures, true)
+		failuresBlock.Color = adaptivecard.ColorAttention
+		if err := card.AddElement(true, failuresBlock); err != nil {
+			return nil, err
+		}
+	}
+	if title != "" {
+		if err := card.AddElement(true, adaptivecard.NewTitleTextBlock(title, true)); err != nil {
+			return nil, err
+		}
+	}
 
-	// Create a new Discord session using the provided token
-	dg, err := discordgo.New("Bot " + info.token)
-	if err != nil {
-		l.V(logs.LogInfo).Info("failed to get discord session")
-		return err
+	if summary != "" {
+		if err := card.AddElement(false, adaptivecard.NewCodeBlock(summary, "PlainText", 1)); err != nil {
+			return nil, err
+		}
 	}
 
-	resourceSpecData, err := json.Marshal(*reportSpec)
-	if err != nil {
-		l.V(logs.LogInfo).Info(fmt.Sprintf("failed to marshal resourceSpec: %v", err))
-		return err
+	if diff != "" {
+		if err := card.AddElement(false, adaptivecard.NewCodeBlock(diff, "PlainText", 1)); err != nil {
+			return nil, err
+		}
 	}
 
-	// Create a temporary file
-	tmpFile, err := os.CreateTemp(os.TempDir(), "k8s-cleaner-webex")
-	if err != nil {
-		l.V(logs.LogInfo).Info(fmt.Sprintf("error creating temporary file: %v", err))
-		return err
+	if err := addCardMetadata(&card, metadata); err != nil {
+		return nil, err
+	}
+	if err := addCardLinks(&card, links); err != nil {
+		return nil, err
 	}
 
-	defer func() {
-		// Close the file
-		tmpFile.Close()
+	teamsMessage := adaptivecard.NewMessage()
+	if err := teamsMessage.Attach(card); err != nil {
+		return nil, err
+	}
+	return teamsMessage, nil
+}
 
-		// Remove the temporary file
-		os.Remove(tmpFile.Name())
-	}()
+func sendDiscordNotification(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	message string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
 
-	_, err = tmpFile.Write(resourceSpecData)
+	info, err := getDiscordInfo(ctx, notification)
 	if err != nil {
-		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to write to file: %s", err))
 		return err
 	}
 
-	// Open the temporary file for reading
-	withFileReader := func() (io.Reader, error) {
-		var fileContentReader *os.File
-		fileContentReader, err = os.Open(tmpFile.Name())
-		if err != nil {
-			return nil, fmt.Errorf("error opening file: %w", err)
-		}
+	l := logger.WithValues(logKeyChannel, info.serverID)
+	l.V(logs.LogInfo).Info("send discord message")
 
-		return fileContentReader, nil
+	links, err := getNotificationLinks(cleaner.Name, reportSpec, notification)
+	if err != nil {
+		l.Error(err, logMsgSendFailed)
+		return err
 	}
 
-	// Create the attachment object
-	fileReader, err := withFileReader()
+	// Create a new Discord session using the provided token
+	dg, err := newDiscordClient(info.token)
 	if err != nil {
+		l.Error(err, "failed to get discord session")
 		return err
 	}
 
-	// Create a new message with both a text content and the file attachment
-	_, err = dg.ChannelMessageSendComplex(info.serverID, &discordgo.MessageSend{
-		Content: message,
-		Files: []*discordgo.File{
-			{
-				Name:   "k8s-cleaner-report", // Replace with desired filename
-				Reader: fileReader,
-			},
-		},
-	})
+	content := getDiscordContent(reportSpec, message, notification)
+	discordMessage := &discordgo.MessageSend{
+		Content: content[len(content)-1],
+		Embeds:  []*discordgo.MessageEmbed{getDiscordEmbed(cleaner, reportSpec, notification, links)},
+	}
 
-	return err
+	inlineReport := ""
+	if isRawReportIncluded(notification) && isAttachmentDisabled(notification) {
+		inlineReport, err = getInlineReport(reportSpec, discordMaxInlineReportSize, getReportEncoding(notification.Type))
+		if err != nil {
+			l.Error(err, logMsgMarshalReportFailed)
+			return err
+		}
+	} else if isRawReportIncluded(notification) {
+		discordFile, closeFile, err := getDiscordReportFile(cleaner, reportSpec, notification, l)
+		if err != nil {
+			return err
+		}
+		defer closeFile()
+		discordMessage.Files = []*discordgo.File{discordFile}
+	}
+
+	messages := make([]*discordgo.MessageSend, 0, len(content)+1)
+	for i := 0; i < len(content)-1; i++ {
+		messages = append(messages, &discordgo.MessageSend{Content: content[i]})
+	}
+	messages = append(messages, discordMessage)
+	if inlineReport != "" {
+		messages = append(messages, &discordgo.MessageSend{Content: inlineReport})
+	}
+
+	// Messages of concurrent runs to the same channel must not interleave
+	unlock := lockChannel(notification.Type, info.serverID)
+	defer unlock()
+	return sendDiscordMessages(ctx, dg, info.serverID, messages, l)
 }
 
-func sendSmtpNotification(ctx context.Context, reportSpec *appsv1alpha1.ReportSpec,
-	message string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+// getDiscordContent returns the content of the Discord messages of a report.
+// Content longer than a message is split into sequential messages, marked
+// with their part number. The last one carries the embed and, unless opted
+// out, the report as file attachment
+func getDiscordContent(reportSpec *appsv1alpha1.ReportSpec, message string,
+	notification *appsv1alpha1.Notification) []string {
 
-	sveltosNotification := &libsveltosv1beta1.Notification{
-		Name:            notification.Name,
-		Type:            libsveltosv1beta1.NotificationTypeSMTP,
-		NotificationRef: notification.NotificationRef,
+	if !notification.SummaryAsCodeBlock {
+		return splitMessage(message+"\n"+getChatSummary(reportSpec), discordMaxContent,
+			discordMaxContentMessages)
 	}
 
-	mailer, err := sveltosnotifications.NewMailer(ctx, k8sClient, sveltosNotification)
+	// Code block is never split: it is added to the last message when it
+	// fits, and sent as a message of its own otherwise. Room is left for
+	// the part markers, as the code block may add a part.
+	maxLength := discordMaxContent - getPartMarkerLength(discordMaxContentMessages)
+	content := splitContent(message, maxLength, discordMaxContentMessages-1)
+	codeBlock := getMessageSummary(reportSpec, notification, maxLength)
+	last := content[len(content)-1] + "\n" + codeBlock
+	if utf8.RuneCountInString(last) <= maxLength {
+		content[len(content)-1] = last
+	} else {
+		content = append(content, codeBlock)
+	}
+	return addPartMarkers(content)
+}
+
+// getDiscordReportFile returns the report attached to Discord messages. The
+// returned function closes and removes the file the attachment is read from.
+func getDiscordReportFile(cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	notification *appsv1alpha1.Notification, logger logr.Logger) (*discordgo.File, func(), error) {
+
+	resourceSpecData, err := truncateReport(reportSpec, discordMaxReportSize, getReportEncoding(notification.Type))
 	if err != nil {
-		return err
+		logger.Error(err, logMsgMarshalReportFailed)
+		return nil, nil, err
 	}
 
-	l := logger.WithValues("notification", fmt.Sprintf("%s:%s", notification.Type, notification.Name))
-	l.V(logs.LogInfo).Info("send smtp message")
+	fileName, err := getAttachmentName(cleaner.Name, reportSpec, "json", notification, time.Now())
+	if err != nil {
+		logger.Error(err, logMsgSendFailed)
+		return nil, nil, err
+	}
+	if fileName == "" {
+		fileName = "k8s-cleaner-report"
+	}
 
-	resourceSpecData, err := json.Marshal(*reportSpec)
+	fileReader, closeFile, err := openReportFile("k8s-cleaner-discord", resourceSpecData, logger)
 	if err != nil {
-		l.V(logs.LogInfo).Info(fmt.Sprintf("failed to marshal resourceSpec: %v", err))
+		return nil, nil, err
+	}
+	return &discordgo.File{Name: fileName, Reader: fileReader}, closeFile, nil
+}
+
+// sendDiscordMessages sends messages, in order, to channelID. Messages sent by
+// a previous attempt of the same delivery are not sent again.
+func sendDiscordMessages(ctx context.Context, dg discordClient, channelID string,
+	messages []*discordgo.MessageSend, logger logr.Logger) error {
+
+	progress := getDeliveryProgress(ctx)
+	for ; progress.sent < len(messages); progress.sent++ {
+		if _, err := dg.ChannelMessageSendComplex(channelID, messages[progress.sent]); err != nil {
+			err = translateDiscordError(err, channelID)
+			logger.Error(err, logMsgSendFailed)
+			return err
+		}
 	}
-	return mailer.SendMail(message, string(resourceSpecData), false)
+	return nil
 }
 
-func sendWebexNotification(ctx context.Context, reportSpec *appsv1alpha1.ReportSpec,
+// sendSmtpNotification emails the report of a run. Depending on ReportDelivery,
+// the report is sent in the body, as HTML attachment, or both. When the HTML
+// report cannot be rendered, the report is sent in the body instead, so the
+// email still goes out.
+func sendSmtpNotification(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
 	message string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
 
-	info, err := getWebexInfo(ctx, notification)
+	mailer, err := newMailer(ctx, notification)
 	if err != nil {
 		return err
 	}
 
-	l := logger.WithValues("room", info.room)
-	l.V(logs.LogInfo).Info("send webex message")
+	logger.V(logs.LogInfo).Info("send smtp message")
+
+	delivery := appsv1alpha1.SMTPReportDeliveryBody
+	if notification.SMTP != nil && notification.SMTP.ReportDelivery != "" {
+		delivery = notification.SMTP.ReportDelivery
+	}
 
-	webexClient := webexteams.NewClient()
-	if webexClient == nil {
-		l.V(logs.LogInfo).Info("failed to get webexClient client")
-		return fmt.Errorf("failed to get webexClient client")
+	var attachments []mailAttachment
+	if delivery != appsv1alpha1.SMTPReportDeliveryBody {
+		attachment, err := getSmtpHTMLAttachment(cleaner.Name, reportSpec, notification)
+		if err != nil {
+			// An email still goes out, with the report in its body
+			logger.Error(err, "failed to render html report, report is sent in the email body instead")
+			delivery = appsv1alpha1.SMTPReportDeliveryBody
+		} else {
+			attachments = append(attachments, *attachment)
+		}
 	}
-	webexClient.SetAuthToken(info.token)
 
-	webexMessage := &webexteams.MessageCreateRequest{
-		Markdown: message,
-		RoomID:   info.room,
+	// First line of the message is the subject. Following lines, if any, are
+	// details such as where an overflowing report has been stored.
+	subject, details, _ := strings.Cut(message, "\n")
+	body := message
+	if delivery != appsv1alpha1.SMTPReportDeliveryAttachment {
+		if isRawReportIncluded(notification) {
+			resourceSpecData, err := truncateReport(reportSpec, smtpMaxReportSize, getReportEncoding(notification.Type))
+			if err != nil {
+				logger.Error(err, logMsgMarshalReportFailed)
+				resourceSpecData = getPlainTextSummary(reportSpec)
+			}
+			body = resourceSpecData
+		} else {
+			body = getPlainTextSummary(reportSpec)
+		}
+		if details != "" {
+			body = details + "\n\n" + body
+		}
 	}
 
-	resourceSpecData, err := json.Marshal(*reportSpec)
+	return mailer.SendMail(subject, body, false, attachments...)
+}
+
+// getSmtpHTMLAttachment returns the HTML report attached to SMTP notifications.
+// An error is returned when the report, or its file name, cannot be rendered.
+func getSmtpHTMLAttachment(cleanerName string, reportSpec *appsv1alpha1.ReportSpec,
+	notification *appsv1alpha1.Notification) (*mailAttachment, error) {
+
+	location, err := getNotificationLocation(notification)
 	if err != nil {
-		l.V(logs.LogInfo).Info(fmt.Sprintf("failed to marshal resourceSpec: %v", err))
-		return err
+		return nil, err
+	}
+	now := time.Now().In(location)
+	htmlReport, err := renderHTMLReport(cleanerName, reportSpec, now)
+	if err != nil {
+		return nil, err
 	}
+	fileName, err := getAttachmentName(cleanerName, reportSpec, "html", notification, now)
+	if err != nil {
+		return nil, err
+	}
+	if fileName == "" {
+		fileName = getReportFileName(cleanerName, reportSpec.RunID, now, "html")
+	}
+	return &mailAttachment{
+		fileName:    fileName,
+		contentType: "text/html; charset=\"UTF-8\"",
+		data:        htmlReport,
+	}, nil
+}
+
+func sendWebexNotification(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	message string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
 
-	// Create a temporary file
-	tmpFile, err := os.CreateTemp(os.TempDir(), "k8s-cleaner-webex")
+	info, err := getWebexInfo(ctx, notification)
 	if err != nil {
-		l.V(logs.LogInfo).Info(fmt.Sprintf("error creating temporary file: %v", err))
 		return err
 	}
 
-	defer func() {
-		// Close the file
-		tmpFile.Close()
-
-		// Remove the temporary file
-		os.Remove(tmpFile.Name())
-	}()
+	l := logger.WithValues(logKeyChannel, info.room)
+	l.V(logs.LogInfo).Info("send webex message")
 
-	_, err = tmpFile.Write(resourceSpecData)
+	webexClient, roomID, err := resolveWebexRoomID(info)
 	if err != nil {
-		logger.V(logs.LogInfo).Info(fmt.Sprintf("failed to write to file: %s", err))
+		l.Error(err, logMsgSendFailed)
 		return err
 	}
 
-	// Open the temporary file for reading
-	withFileReader := func() (io.Reader, error) {
-		var fileContentReader *os.File
-		fileContentReader, err = os.Open(tmpFile.Name())
+	webexMessage := getWebexMessage(reportSpec, message, roomID, notification)
+
+	withCard := false
+	if isWebexCardNotification(notification) {
+		var card *webexteams.Attachment
+		card, err = getWebexCard(cleaner.Name, reportSpec, message, notification)
 		if err != nil {
-			return nil, fmt.Errorf("Error opening file: %w", err)
+			// Markdown message is sent instead
+			l.Error(err, "failed to render webex card")
+		} else {
+			webexMessage.Attachments = []webexteams.Attachment{*card}
+			withCard = true
 		}
+	}
 
-		return fileContentReader, nil
+	// Unless opted out, report is attached as file, or added to the message when
+	// attachments are disabled. Webex does not accept files along with cards.
+	if isRawReportIncluded(notification) && !withCard && isAttachmentDisabled(notification) {
+		if err := addWebexInlineReport(webexMessage, reportSpec, notification); err != nil {
+			l.Error(err, logMsgMarshalReportFailed)
+			return err
+		}
+	} else if isRawReportIncluded(notification) && !withCard {
+		webexFile, closeFile, err := getWebexReportFile(cleaner, reportSpec, notification, l)
+		if err != nil {
+			return err
+		}
+		defer closeFile()
+		webexMessage.Files = []webexteams.File{*webexFile}
 	}
 
-	// Create the attachment object
-	fileReader, err := withFileReader()
+	_, resp, err := webexClient.CreateMessage(webexMessage)
 	if err != nil {
+		l.Error(err, logMsgSendFailed)
 		return err
 	}
 
-	webexFile := webexteams.File{
-		Name:        tmpFile.Name(),
-		Reader:      fileReader,
-		ContentType: "multipart/form-data",
+	if resp != nil {
+		l.V(logs.LogDebug).Info("webex response", "response", string(resp.Body()))
 	}
 
-	webexMessage.Files = []webexteams.File{webexFile}
+	return nil
+}
+
+// getWebexReportFile returns the report attached to Webex messages. The returned
+// function closes and removes the file the attachment is read from.
+func getWebexReportFile(cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	notification *appsv1alpha1.Notification, logger logr.Logger) (*webexteams.File, func(), error) {
 
-	_, resp, err := webexClient.Messages.CreateMessage(webexMessage)
+	resourceSpecData, err := truncateReport(reportSpec, webexMaxReportSize, getReportEncoding(notification.Type))
 	if err != nil {
-		l.V(logs.LogInfo).Info(fmt.Sprintf("Failed to send message. Error: %v", err))
-		return err
+		logger.Error(err, logMsgMarshalReportFailed)
+		return nil, nil, err
 	}
 
-	if resp != nil {
-		l.V(logs.LogDebug).Info(fmt.Sprintf("response: %s", string(resp.Body())))
+	fileName, err := getAttachmentName(cleaner.Name, reportSpec, "json", notification, time.Now())
+	if err != nil {
+		logger.Error(err, logMsgSendFailed)
+		return nil, nil, err
 	}
 
-	return nil
+	fileReader, closeFile, err := openReportFile("k8s-cleaner-webex", resourceSpecData, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	if fileName == "" {
+		fileName = fileReader.Name()
+	}
+
+	return &webexteams.File{
+		Name:        fileName,
+		Reader:      fileReader,
+		ContentType: "multipart/form-data",
+	}, closeFile, nil
 }
 
 func getSlackInfo(ctx context.Context, notification *appsv1alpha1.Notification) (*slackInfo, error) {
-	secret, err := getSecret(ctx, notification)
+	return getSlackInfoFromRef(ctx, notification, notification.NotificationRef)
+}
+
+func getSlackInfoFromRef(ctx context.Context, notification *appsv1alpha1.Notification,
+	ref *corev1.ObjectReference) (*slackInfo, error) {
+
+	secret, err := getNotificationSecret(ctx, notification, ref)
 	if err != nil {
 		return nil, err
 	}
 
+	if webhookURL := secret.Data[appsv1alpha1.SlackWebhookURL]; len(webhookURL) > 0 {
+		tlsConfig, tlsErr := getNotificationTLSConfig(secret)
+		if tlsErr != nil {
+			return nil, tlsErr
+		}
+		return &slackInfo{webhookURL: string(webhookURL), tlsConfig: tlsConfig}, nil
+	}
+
 	authToken, ok := secret.Data[libsveltosv1alpha1.SlackToken]
 	if !ok {
 		return nil, fmt.Errorf("secret does not contain slack token")
@@ -427,7 +1613,11 @@ func getSlackInfo(ctx context.Context, notification *appsv1alpha1.Notification)
 		return nil, fmt.Errorf("secret does not contain slack channelID")
 	}
 
-	return &slackInfo{token: string(authToken), channelID: string(channelID)}, nil
+	info := &slackInfo{token: string(authToken), channelID: string(channelID)}
+	if channel := getRoutedChannel(ctx); channel != "" {
+		info.channelID = channel
+	}
+	return info, nil
 }
 
 func getTeamsInfo(ctx context.Context, notification *appsv1alpha1.Notification) (*teamsInfo, error) {
@@ -441,7 +1631,12 @@ func getTeamsInfo(ctx context.Context, notification *appsv1alpha1.Notification)
 		return nil, fmt.Errorf("secret does not contain webhook URL")
 	}
 
-	return &teamsInfo{webhookUrl: string(webhookUrl)}, nil
+	tlsConfig, err := getNotificationTLSConfig(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &teamsInfo{webhookUrl: string(webhookUrl), tlsConfig: tlsConfig}, nil
 }
 
 func getDiscordInfo(ctx context.Context, notification *appsv1alpha1.Notification) (*discordInfo, error) {
@@ -460,7 +1655,14 @@ func getDiscordInfo(ctx context.Context, notification *appsv1alpha1.Notification
 		return nil, fmt.Errorf("secret does not contain discord channel id")
 	}
 
-	return &discordInfo{token: string(authToken), serverID: string(serverID)}, nil
+	info := &discordInfo{token: string(authToken), serverID: string(serverID)}
+	if channel := getRoutedChannel(ctx); channel != "" {
+		info.serverID = channel
+	}
+	if err := validateDiscordInfo(info); err != nil {
+		return nil, err
+	}
+	return info, nil
 }
 
 func getWebexInfo(ctx context.Context, notification *appsv1alpha1.Notification) (*webexInfo, error) {
@@ -479,26 +1681,60 @@ func getWebexInfo(ctx context.Context, notification *appsv1alpha1.Notification)
 		return nil, fmt.Errorf("secret does not contain webex room")
 	}
 
-	return &webexInfo{token: string(authToken), room: string(room)}, nil
+	info := &webexInfo{token: string(authToken), room: string(room)}
+	if channel := getRoutedChannel(ctx); channel != "" {
+		info.room = channel
+	}
+	return info, nil
+}
+
+// getNotificationRefs returns NotificationRef followed by all FailoverNotificationRefs
+func getNotificationRefs(notification *appsv1alpha1.Notification) []*corev1.ObjectReference {
+	refs := []*corev1.ObjectReference{notification.NotificationRef}
+	for i := range notification.FailoverNotificationRefs {
+		refs = append(refs, &notification.FailoverNotificationRefs[i])
+	}
+
+	return refs
+}
+
+// getSortedMetadataKeys returns metadata keys in alphabetical order so payloads
+// are deterministic
+func getSortedMetadataKeys(metadata map[string]string) []string {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 func getSecret(ctx context.Context, notification *appsv1alpha1.Notification) (*corev1.Secret, error) {
-	if notification.NotificationRef == nil {
+	return getNotificationSecret(ctx, notification, notification.NotificationRef)
+}
+
+func getSecretFromRef(ctx context.Context, ref *corev1.ObjectReference) (*corev1.Secret, error) {
+	if ref == nil {
 		return nil, fmt.Errorf("notification must reference secret containing slack token/channel id")
 	}
 
-	if notification.NotificationRef.Kind != "Secret" {
+	if ref.Kind != "Secret" {
 		return nil, fmt.Errorf("notification must reference secret containing slack token/channel id")
 	}
 
-	if notification.NotificationRef.APIVersion != "v1" {
+	if ref.APIVersion != "v1" {
 		return nil, fmt.Errorf("notification must reference secret containing slack token/channel id")
 	}
 
+	c, err := getK8sClient()
+	if err != nil {
+		return nil, err
+	}
+
 	secret := &corev1.Secret{}
-	err := k8sClient.Get(ctx, types.NamespacedName{
-		Namespace: notification.NotificationRef.Namespace,
-		Name:      notification.NotificationRef.Name,
+	err = c.Get(ctx, types.NamespacedName{
+		Namespace: ref.Namespace,
+		Name:      ref.Name,
 	}, secret)
 	if err != nil {
 		return nil, err
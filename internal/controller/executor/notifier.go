@@ -0,0 +1,69 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+// notifier delivers reports for a notification type.
+// Each notification type registers its notifier, with registerNotifier, in an
+// init function of the file implementing it.
+type notifier interface {
+	// Send delivers reportSpec along with message. resources are the resources
+	// the report was generated from. They are nil for digests.
+	Send(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+		resources []ResourceResult, message string, notification *appsv1alpha1.Notification,
+		logger logr.Logger) error
+}
+
+// notifierFunc is a function implementing notifier
+type notifierFunc func(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	resources []ResourceResult, message string, notification *appsv1alpha1.Notification, logger logr.Logger) error
+
+func (f notifierFunc) Send(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	resources []ResourceResult, message string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+	return f(ctx, cleaner, reportSpec, resources, message, notification, logger)
+}
+
+// notifiers contains the notifier of each notification type
+var notifiers = map[appsv1alpha1.NotificationType]notifier{}
+
+// registerNotifier registers n as the notifier of notificationType.
+// It panics if a notifier is already registered for notificationType.
+func registerNotifier(notificationType appsv1alpha1.NotificationType, n notifier) {
+	if _, ok := notifiers[notificationType]; ok {
+		panic(fmt.Sprintf("notifier already registered for notification type %s", notificationType))
+	}
+	notifiers[notificationType] = n
+}
+
+// getNotifier returns the notifier registered for notificationType. Outcome of
+// deliveries is recorded in the notification subsystem health.
+func getNotifier(notificationType appsv1alpha1.NotificationType) (notifier, error) {
+	n, ok := notifiers[notificationType]
+	if !ok {
+		return nil, fmt.Errorf("no notifier registered for notification type %s", notificationType)
+	}
+	return &healthNotifier{notifier: n, notificationType: notificationType}, nil
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+var _ = Describe("Notifier registry", func() {
+	It("a notifier is registered for every notification type", func() {
+		for _, notificationType := range []appsv1alpha1.NotificationType{
+			appsv1alpha1.NotificationTypeCleanerReport,
+			appsv1alpha1.NotificationTypeSlack,
+			appsv1alpha1.NotificationTypeWebex,
+			appsv1alpha1.NotificationTypeDiscord,
+			appsv1alpha1.NotificationTypeTeams,
+			appsv1alpha1.NotificationTypeSMTP,
+			appsv1alpha1.NotificationTypeSplunkHEC,
+			appsv1alpha1.NotificationTypeEvent,
+			appsv1alpha1.NotificationTypeFile,
+			appsv1alpha1.NotificationTypeCloudEvents,
+			appsv1alpha1.NotificationTypeVictorOps,
+			appsv1alpha1.NotificationTypeS3,
+			appsv1alpha1.NotificationTypeSMS,
+			appsv1alpha1.NotificationTypeRedis,
+			appsv1alpha1.NotificationTypeGitLab,
+			appsv1alpha1.NotificationTypeLoki,
+			appsv1alpha1.NotificationTypeLog,
+			appsv1alpha1.NotificationTypeElasticsearch,
+		} {
+			n, err := executor.GetNotifier(notificationType)
+			Expect(err).To(BeNil(), string(notificationType))
+			Expect(n).ToNot(BeNil())
+		}
+	})
+
+	It("sendNotifications delivers through the notifier registered for the notification type", func() {
+		notificationType := appsv1alpha1.NotificationType(randomString())
+		var messages []string
+		var delivered [][]executor.ResourceResult
+		DeferCleanup(executor.SetNotifier(notificationType, executor.NotifierFunc(
+			func(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+				resources []executor.ResourceResult, message string, notification *appsv1alpha1.Notification,
+				logger logr.Logger) error {
+
+				messages = append(messages, message)
+				delivered = append(delivered, resources)
+				return nil
+			})))
+
+		cleaner := getCleanerWithNotification(notificationType, nil)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		runID := randomString()
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, runID, logr.Discard())).To(Succeed())
+
+		Expect(messages).To(HaveLen(1))
+		Expect(messages[0]).To(ContainSubstring(runID))
+		Expect(delivered[0]).To(Equal([]executor.ResourceResult{resource}))
+	})
+
+	It("sendNotifications dispatches SMTP like any other notification type", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+		slackFake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return slackFake
+		}))
+		mailerFake := &fakeMailer{}
+		useFakeMailer(mailerFake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSMTP, nil)
+		cleaner.Spec.Notifications = append(cleaner.Spec.Notifications, appsv1alpha1.Notification{
+			Name:            randomString(),
+			Type:            appsv1alpha1.NotificationTypeSlack,
+			NotificationRef: ref,
+		})
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(mailerFake.subjects).To(HaveLen(1))
+		Expect(mailerFake.bodies[0]).To(ContainSubstring(resource.Resource.GetName()))
+		Expect(slackFake.values).To(HaveLen(1))
+	})
+
+	It("sendNotifications returns an error when no notifier is registered", func() {
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationType(randomString()), nil)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		err := executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("no notifier registered for notification type"))
+	})
+
+	It("sendNotifications skips disabled notifications", func() {
+		notificationType := appsv1alpha1.NotificationType(randomString())
+		var delivered []string
+		DeferCleanup(executor.SetNotifier(notificationType, executor.NotifierFunc(
+			func(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+				resources []executor.ResourceResult, message string, notification *appsv1alpha1.Notification,
+				logger logr.Logger) error {
+
+				delivered = append(delivered, notification.Name)
+				return nil
+			})))
+
+		cleaner := getCleanerWithNotification(notificationType, nil)
+		muted := cleaner.Spec.Notifications[0]
+		muted.Name = randomString()
+		muted.Enabled = ptr.To(false)
+		cleaner.Spec.Notifications = append(cleaner.Spec.Notifications, muted)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+		Expect(delivered).To(Equal([]string{cleaner.Spec.Notifications[0].Name}))
+	})
+})
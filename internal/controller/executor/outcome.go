@@ -0,0 +1,94 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+const (
+	// maxResourceErrorLength is the maximum length of the error of a failed
+	// resource rendered in chat messages. Full errors are always part of the
+	// report.
+	maxResourceErrorLength = 200
+)
+
+// Maximum size, in bytes, of the list of failed resources rendered in chat
+// messages. All failed resources are always part of the report.
+const (
+	slackMaxFailuresSize   = 2000
+	teamsMaxFailuresSize   = 2000
+	discordMaxFailuresSize = 1000
+	webexMaxFailuresSize   = 1000
+)
+
+// getFailedResourceCount returns the number of resources in reportSpec the
+// action failed on
+func getFailedResourceCount(reportSpec *appsv1alpha1.ReportSpec) int {
+	failed := 0
+	for i := range reportSpec.ResourceInfo {
+		if reportSpec.ResourceInfo[i].Outcome == appsv1alpha1.ResourceOutcomeFailed {
+			failed++
+		}
+	}
+	return failed
+}
+
+// getFailedResourcesMarkdown returns the resources in reportSpec the action failed
+// on, along with their error, as a markdown list. Resources which do not fit in
+// limit bytes are omitted. An empty string is returned when no resource failed.
+func getFailedResourcesMarkdown(reportSpec *appsv1alpha1.ReportSpec, limit int) string {
+	total := getFailedResourceCount(reportSpec)
+	if total == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("**%d resource(s) failed:**\n", total))
+
+	included := 0
+	for i := range reportSpec.ResourceInfo {
+		info := &reportSpec.ResourceInfo[i]
+		if info.Outcome != appsv1alpha1.ResourceOutcomeFailed {
+			continue
+		}
+		line := fmt.Sprintf("- %s: %s\n", getResourceDescription(&info.Resource),
+			truncateString(info.Error, maxResourceErrorLength))
+		remaining := total - included - 1
+		size := sb.Len() + len(line)
+		if remaining > 0 {
+			size += len(getFailureOmittedMarker(remaining))
+		}
+		if size > limit {
+			break
+		}
+		sb.WriteString(line)
+		included++
+	}
+
+	if included < total {
+		sb.WriteString(getFailureOmittedMarker(total - included))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func getFailureOmittedMarker(omitted int) string {
+	return fmt.Sprintf("…(%d failures omitted, see full report)", omitted)
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+// forbiddenDeleteClient fails deleting the resource named name
+type forbiddenDeleteClient struct {
+	client.Client
+	name string
+}
+
+func (c *forbiddenDeleteClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if obj.GetName() == c.name {
+		return apierrors.NewForbidden(corev1.Resource("configmaps"), obj.GetName(), fmt.Errorf("denied"))
+	}
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+var _ = Describe("Resource outcome", func() {
+	It("deleteMatchingResources records the outcome of each resource and continues on failure", func() {
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: randomString()}}
+		Expect(k8sClient.Create(context.TODO(), ns)).To(Succeed())
+		Expect(waitForObject(context.TODO(), k8sClient, ns)).To(Succeed())
+
+		existing := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, Name: randomString()}}
+		Expect(k8sClient.Create(context.TODO(), existing)).To(Succeed())
+		Expect(waitForObject(context.TODO(), k8sClient, existing)).To(Succeed())
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(existing)
+		Expect(err).To(BeNil())
+		existingResource := &unstructured.Unstructured{Object: content}
+		existingResource.SetAPIVersion("v1")
+		existingResource.SetKind("ConfigMap")
+
+		forbidden := getResourceResult("ConfigMap", ns.Name, randomString())
+		DeferCleanup(executor.SetK8sClient(&forbiddenDeleteClient{Client: k8sClient,
+			name: forbidden.Resource.GetName()}))
+
+		missing := getResourceResult("ConfigMap", ns.Name, randomString())
+		resources := []executor.ResourceResult{
+			forbidden, missing, {Resource: existingResource},
+		}
+
+		processed, err := executor.DeleteMatchingResources(context.TODO(), resources, &appsv1alpha1.Cleaner{}, logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(HavePrefix("failed to delete 1 of 3 resources"))
+		Expect(processed).To(HaveLen(3))
+		Expect(processed[0].Outcome).To(Equal(appsv1alpha1.ResourceOutcomeFailed))
+		Expect(processed[0].Error).To(ContainSubstring("forbidden"))
+		Expect(processed[1].Outcome).To(Equal(appsv1alpha1.ResourceOutcomeSkipped))
+		Expect(processed[2].Outcome).To(Equal(appsv1alpha1.ResourceOutcomeSucceeded))
+	})
+
+	It("sendNotifications lists failed resources first and highlights them in Slack", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		succeeded := getResourceResult("ConfigMap", randomString(), randomString())
+		succeeded.Outcome = appsv1alpha1.ResourceOutcomeSucceeded
+		failed := getResourceResult("Secret", randomString(), randomString())
+		failed.Outcome = appsv1alpha1.ResourceOutcomeFailed
+		failed.Error = "secrets is forbidden"
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{succeeded, failed},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		text := fake.values[0].Get("text")
+		Expect(text).To(ContainSubstring("*1 resource(s) failed:*"))
+		Expect(text).To(ContainSubstring(fmt.Sprintf("- Secret %s/%s: secrets is forbidden",
+			failed.Resource.GetNamespace(), failed.Resource.GetName())))
+
+		attachments := fake.values[0].Get("attachments")
+		Expect(attachments).To(ContainSubstring(`"color":"danger"`))
+		Expect(strings.Index(attachments, failed.Resource.GetName())).To(
+			BeNumerically("<", strings.Index(attachments, succeeded.Resource.GetName())))
+		Expect(attachments).To(ContainSubstring(`\"failed\": 1`))
+	})
+
+	It("getFailedResourcesMarkdown omits failed resources beyond limit", func() {
+		reportSpec := &appsv1alpha1.ReportSpec{}
+		for i := 0; i < 3; i++ {
+			reportSpec.ResourceInfo = append(reportSpec.ResourceInfo, appsv1alpha1.ResourceInfo{
+				Resource: corev1.ObjectReference{Kind: "ConfigMap", Namespace: randomString(), Name: randomString()},
+				Outcome:  appsv1alpha1.ResourceOutcomeFailed,
+				Error:    strings.Repeat("x", 60),
+			})
+		}
+		reportSpec.ResourceInfo = append(reportSpec.ResourceInfo, appsv1alpha1.ResourceInfo{
+			Resource: corev1.ObjectReference{Kind: "ConfigMap", Name: randomString()},
+			Outcome:  appsv1alpha1.ResourceOutcomeSucceeded,
+		})
+
+		markdown := executor.GetFailedResourcesMarkdown(reportSpec, 200)
+		Expect(len(markdown)).To(BeNumerically("<=", 200))
+		Expect(markdown).To(HavePrefix("**3 resource(s) failed:**"))
+		Expect(markdown).To(ContainSubstring(reportSpec.ResourceInfo[0].Resource.Name))
+		Expect(markdown).To(HaveSuffix("…(2 failures omitted, see full report)"))
+
+		Expect(executor.GetFailedResourcesMarkdown(&appsv1alpha1.ReportSpec{ResourceInfo: reportSpec.ResourceInfo[3:]},
+			200)).To(BeEmpty())
+	})
+
+	It("renderHTMLReport highlights failed resources", func() {
+		reportSpec := &appsv1alpha1.ReportSpec{
+			Action: appsv1alpha1.ActionDelete,
+			ResourceInfo: []appsv1alpha1.ResourceInfo{
+				{
+					Resource: corev1.ObjectReference{Kind: "ConfigMap", Namespace: randomString(), Name: randomString()},
+					Outcome:  appsv1alpha1.ResourceOutcomeFailed,
+					Error:    "configmaps is forbidden",
+				},
+			},
+		}
+
+		html, err := executor.RenderHTMLReport(randomString(), reportSpec, time.Now())
+		Expect(err).To(BeNil())
+		Expect(string(html)).To(ContainSubstring(`<tr style="background-color: #ffebe9; color: #cf222e;">`))
+		Expect(string(html)).To(ContainSubstring("Failed: configmaps is forbidden"))
+		Expect(string(html)).To(ContainSubstring("Failed: 1</span>"))
+	})
+})
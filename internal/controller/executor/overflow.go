@@ -0,0 +1,92 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+// getReportSizeLimit returns the maximum size of the report sent by notification,
+// 0 if the report sent by its type is not size limited or if no report is sent
+func getReportSizeLimit(notification *appsv1alpha1.Notification) int {
+	if !isRawReportIncluded(notification) {
+		return 0
+	}
+	if isAttachmentDisabled(notification) {
+		return inlineReportSizeLimits[notification.Type]
+	}
+	if notification.Type == appsv1alpha1.NotificationTypeSlack &&
+		notification.Slack != nil && notification.Slack.UploadReport {
+
+		return slackMaxFileSize
+	}
+	return reportSizeLimits[notification.Type]
+}
+
+// handleReportOverflow verifies reportSpec fits in the report size limit of
+// notification. If it does not, the full report is stored in the Report instance
+// of the Cleaner, so that no data is lost when the channel truncates it, and the
+// returned message points to it. Report size is recorded in the report metrics. message is returned unchanged otherwise, if
+// the Report instance cannot be stored or if the Cleaner disables it.
+func handleReportOverflow(ctx context.Context, cleaner *appsv1alpha1.Cleaner,
+	reportSpec *appsv1alpha1.ReportSpec, message string, notification *appsv1alpha1.Notification,
+	logger logr.Logger) string {
+
+	limit := getReportSizeLimit(notification)
+	if limit == 0 {
+		return message
+	}
+
+	data, err := marshalReport(reportSpec, getReportEncoding(notification.Type))
+	if err != nil {
+		// Marshaling error is surfaced by the notifier
+		return message
+	}
+	recordReportSize(cleaner.Name, notification.Type, len(data), limit)
+	if len(data) <= limit {
+		return message
+	}
+
+	if cleaner.Spec.DisableReport {
+		logger.V(logs.LogInfo).Info("report exceeds the channel limit. Report is disabled, send it truncated",
+			logKeySize, len(data), logKeyLimit, limit)
+		return message
+	}
+
+	logger.V(logs.LogInfo).Info("report exceeds the channel limit. Store it in Report",
+		logKeySize, len(data), logKeyLimit, limit)
+	if err := createReportInstance(ctx, cleaner, reportSpec, logger); err != nil {
+		logger.Error(err, "failed to store overflowing report")
+		return message
+	}
+
+	return message + getReportOverflowMessage(cleaner.Name)
+}
+
+// getReportOverflowMessage returns the text appended to the message of a
+// notification whose report has been stored in the Report instance reportName
+func getReportOverflowMessage(reportName string) string {
+	return fmt.Sprintf("\nReport too large for this channel. Full report stored in Report %s: "+
+		"kubectl get report %s -o yaml", reportName, reportName)
+}
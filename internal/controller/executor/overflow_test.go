@@ -0,0 +1,131 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+var _ = Describe("Report overflow", func() {
+	It("sendNotifications stores report too large for the channel in Report instance", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.TeamsWebhookURL: []byte("https://example.webhook.office.com/" + randomString()),
+		})
+
+		fake := &fakeTeamsClient{}
+		DeferCleanup(executor.SetTeamsClientFactory(func() executor.TeamsClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeTeams, ref)
+		const resourceCount = 300
+		resources := make([]executor.ResourceResult, resourceCount)
+		for i := range resources {
+			resources[i] = getResourceResult("ConfigMap", randomString(), randomString())
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		report := &appsv1alpha1.Report{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, report)).To(Succeed())
+		Expect(report.Spec.ResourceInfo).To(HaveLen(resourceCount))
+
+		Expect(fake.messages).To(HaveLen(1))
+		payload, err := json.Marshal(fake.messages[0])
+		Expect(err).To(BeNil())
+		Expect(string(payload)).To(ContainSubstring(fmt.Sprintf("kubectl get report %s -o yaml", cleaner.Name)))
+	})
+
+	It("sendNotifications does not store report too large for the channel when DisableReport is set", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.TeamsWebhookURL: []byte("https://example.webhook.office.com/" + randomString()),
+		})
+
+		fake := &fakeTeamsClient{}
+		DeferCleanup(executor.SetTeamsClientFactory(func() executor.TeamsClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeTeams, ref)
+		cleaner.Spec.DisableReport = true
+		resources := make([]executor.ResourceResult, 300)
+		for i := range resources {
+			resources[i] = getResourceResult("ConfigMap", randomString(), randomString())
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		report := &appsv1alpha1.Report{}
+		err := k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, report)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		Expect(fake.messages).To(HaveLen(1))
+		payload, err := json.Marshal(fake.messages[0])
+		Expect(err).To(BeNil())
+		Expect(string(payload)).ToNot(ContainSubstring("kubectl get report"))
+	})
+
+	It("sendNotifications does not store report fitting in the channel", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.TeamsWebhookURL: []byte("https://example.webhook.office.com/" + randomString()),
+		})
+
+		fake := &fakeTeamsClient{}
+		DeferCleanup(executor.SetTeamsClientFactory(func() executor.TeamsClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeTeams, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		report := &appsv1alpha1.Report{}
+		err := k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, report)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		payload, err := json.Marshal(fake.messages[0])
+		Expect(err).To(BeNil())
+		Expect(string(payload)).ToNot(ContainSubstring("kubectl get report"))
+	})
+
+	It("getReportSizeLimit uses file size limit for Slack reports uploaded as file", func() {
+		notification := &appsv1alpha1.Notification{Type: appsv1alpha1.NotificationTypeSlack}
+		Expect(executor.GetReportSizeLimit(notification)).To(Equal(executor.GetReportSizeLimits()[notification.Type]))
+
+		notification.Slack = &appsv1alpha1.SlackOptions{UploadReport: true}
+		Expect(executor.GetReportSizeLimit(notification)).To(BeNumerically(">",
+			executor.GetReportSizeLimits()[notification.Type]))
+
+		notification = &appsv1alpha1.Notification{Type: appsv1alpha1.NotificationTypeEvent}
+		Expect(executor.GetReportSizeLimit(notification)).To(BeZero())
+	})
+})
@@ -0,0 +1,161 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// maxNotificationTLSTransports is the maximum number of transports cached for
+// notifications with their own TLS configuration. Cache is emptied once reached,
+// for instance after many Secret rotations.
+const maxNotificationTLSTransports = 100
+
+// notificationProxy returns the proxy used by notification HTTP clients for a
+// request. By default HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables
+// are honored.
+var notificationProxy = http.ProxyFromEnvironment
+
+var (
+	transportMux sync.Mutex
+	// notificationTransport is shared by notification HTTP clients, so that
+	// connections are reused across notifications. It is built again when the
+	// proxy, TLS or keep-alive settings change.
+	notificationTransport = newNotificationTransport()
+	// notificationTLSTransports are the clones of notificationTransport used by
+	// notifications with their own TLS configuration
+	notificationTLSTransports = map[notificationTransportKey]*http.Transport{}
+)
+
+// notificationTransportKey identifies the TLS configuration of a transport.
+// TLS configurations of notification Secrets are cached by getNotificationTLSConfig,
+// so they can be compared by pointer.
+type notificationTransportKey struct {
+	tlsConfig          *tls.Config
+	insecureSkipVerify bool
+}
+
+// SetNotificationProxy sets the HTTP(S) proxy all notification HTTP clients send
+// requests through. Hosts listed in NO_PROXY environment variable are reached
+// directly. If proxyURL is empty, HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment
+// variables are used.
+func SetNotificationProxy(proxyURL string) error {
+	if proxyURL == "" {
+		notificationProxy = http.ProxyFromEnvironment
+		resetNotificationTransports()
+		return nil
+	}
+
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return fmt.Errorf("invalid notification proxy URL %q: %w", proxyURL, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return fmt.Errorf("invalid notification proxy URL %q: scheme must be http, https or socks5", proxyURL)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid notification proxy URL %q: host is missing", proxyURL)
+	}
+
+	config := httpproxy.FromEnvironment()
+	config.HTTPProxy = proxyURL
+	config.HTTPSProxy = proxyURL
+	proxyFunc := config.ProxyFunc()
+	notificationProxy = func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+	resetNotificationTransports()
+	return nil
+}
+
+// newNotificationTransport returns a transport using the notification proxy and
+// TLS and keep-alive settings
+func newNotificationTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = notificationProxy
+	transport.TLSClientConfig = newNotificationTLSConfig()
+	transport.DisableKeepAlives = notificationDisableKeepAlives
+	return transport
+}
+
+// resetNotificationTransports builds again the shared transport using the
+// current settings, and drops the cached transports and TLS configurations
+func resetNotificationTransports() {
+	transportMux.Lock()
+	defer transportMux.Unlock()
+
+	notificationTransport.CloseIdleConnections()
+	notificationTransport = newNotificationTransport()
+	clearNotificationTLSTransports()
+	notificationTLSConfigs = map[string]*tls.Config{}
+}
+
+// clearNotificationTLSTransports drops the cached TLS transports. Must be called
+// with transportMux held.
+func clearNotificationTLSTransports() {
+	for _, transport := range notificationTLSTransports {
+		transport.CloseIdleConnections()
+	}
+	notificationTLSTransports = map[notificationTransportKey]*http.Transport{}
+}
+
+// getNotificationTransport returns the transport of notification HTTP clients
+// using tlsConfig: the shared transport when tlsConfig is nil, otherwise a clone
+// of it using tlsConfig, created once per configuration. insecureSkipVerify
+// disables the verification of the server certificate.
+func getNotificationTransport(tlsConfig *tls.Config, insecureSkipVerify bool) *http.Transport {
+	transportMux.Lock()
+	defer transportMux.Unlock()
+
+	if tlsConfig == nil && !insecureSkipVerify {
+		return notificationTransport
+	}
+
+	key := notificationTransportKey{tlsConfig: tlsConfig, insecureSkipVerify: insecureSkipVerify}
+	if transport, ok := notificationTLSTransports[key]; ok {
+		return transport
+	}
+	if len(notificationTLSTransports) >= maxNotificationTLSTransports {
+		clearNotificationTLSTransports()
+	}
+
+	transport := notificationTransport.Clone()
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig.Clone()
+	}
+	transport.TLSClientConfig.InsecureSkipVerify = insecureSkipVerify
+	notificationTLSTransports[key] = transport
+	return transport
+}
+
+// newNotificationHTTPClient returns an HTTP client using the shared notification
+// transport. A zero timeout means no timeout.
+func newNotificationHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: getNotificationTransport(nil, false),
+	}
+}
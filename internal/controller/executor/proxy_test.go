@@ -0,0 +1,156 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+
+	"github.com/atc0005/go-teams-notify/v2/adaptivecard"
+	"github.com/bwmarrin/discordgo"
+	"github.com/go-logr/logr"
+	webexteams "github.com/jbogarin/go-cisco-webex-teams/sdk"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+// testProxy is a forward proxy recording the hosts requests are sent to.
+// Plain HTTP requests are answered directly. CONNECT requests (used for HTTPS)
+// are rejected, so no connection leaves the test.
+type testProxy struct {
+	mu    sync.Mutex
+	hosts []string
+}
+
+func (p *testProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	p.hosts = append(p.hosts, r.Host)
+	p.mu.Unlock()
+
+	if r.Method == http.MethodConnect {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (p *testProxy) getHosts() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string{}, p.hosts...)
+}
+
+var _ = Describe("Notification proxy", func() {
+	var proxy *testProxy
+
+	BeforeEach(func() {
+		proxy = &testProxy{}
+		server := httptest.NewServer(proxy)
+		DeferCleanup(server.Close)
+
+		Expect(executor.SetNotificationProxy(server.URL)).To(Succeed())
+		DeferCleanup(func() {
+			Expect(executor.SetNotificationProxy("")).To(Succeed())
+		})
+	})
+
+	It("sendNotifications sends CloudEvents through the proxy", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.CloudEventsSinkURL: []byte("http://cloudevents.example.com/events"),
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCloudEvents, ref)
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+		Expect(proxy.getHosts()).To(Equal([]string{"cloudevents.example.com"}))
+	})
+
+	It("sendNotifications sends Splunk HEC events through the proxy", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.SplunkHECURL:   []byte("http://splunk.example.com:8088/services/collector/event"),
+			appsv1alpha1.SplunkHECToken: []byte(randomString()),
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSplunkHEC, ref)
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+		Expect(proxy.getHosts()).To(Equal([]string{"splunk.example.com:8088"}))
+	})
+
+	It("Slack client sends requests through the proxy", func() {
+		client := executor.NewDefaultSlackClient(randomString())
+		_, _, err := client.PostMessage(randomString(), slack.MsgOptionText(randomString(), false))
+		Expect(err).ToNot(BeNil())
+		Expect(proxy.getHosts()).To(ContainElement("slack.com:443"))
+	})
+
+	It("Teams client sends requests through the proxy", func() {
+		message, err := adaptivecard.NewSimpleMessage(randomString(), randomString(), true)
+		Expect(err).To(BeNil())
+
+		client := executor.NewDefaultTeamsClient(nil)
+		err = client.Send("https://example.webhook.office.com/webhookb2/"+randomString(), message)
+		Expect(err).ToNot(BeNil())
+		Expect(proxy.getHosts()).To(ContainElement("example.webhook.office.com:443"))
+	})
+
+	It("Discord client sends requests through the proxy", func() {
+		client, err := executor.NewDefaultDiscordClient(randomString())
+		Expect(err).To(BeNil())
+		_, err = client.ChannelMessageSendComplex(randomDiscordID(), &discordgo.MessageSend{Content: randomString()})
+		Expect(err).ToNot(BeNil())
+		Expect(proxy.getHosts()).To(ContainElement("discord.com:443"))
+	})
+
+	It("Webex client sends requests through the proxy", func() {
+		client := executor.NewDefaultWebexClient(randomString())
+		_, _, err := client.CreateMessage(&webexteams.MessageCreateRequest{
+			RoomID:   randomString(),
+			Markdown: randomString(),
+		})
+		Expect(err).ToNot(BeNil())
+		Expect(proxy.getHosts()).To(ContainElement("webexapis.com:443"))
+	})
+
+	It("SetNotificationProxy honors NO_PROXY", func() {
+		Expect(os.Setenv("NO_PROXY", "direct.example.com")).To(Succeed())
+		DeferCleanup(os.Unsetenv, "NO_PROXY")
+		Expect(executor.SetNotificationProxy("http://proxy.example.com:3128")).To(Succeed())
+
+		req, err := http.NewRequest(http.MethodPost, "https://direct.example.com/hook", http.NoBody)
+		Expect(err).To(BeNil())
+		proxyURL, err := executor.GetNotificationProxy(req)
+		Expect(err).To(BeNil())
+		Expect(proxyURL).To(BeNil())
+
+		req, err = http.NewRequest(http.MethodPost, "https://hooks.slack.com/services", http.NoBody)
+		Expect(err).To(BeNil())
+		proxyURL, err = executor.GetNotificationProxy(req)
+		Expect(err).To(BeNil())
+		Expect(proxyURL.String()).To(Equal("http://proxy.example.com:3128"))
+	})
+
+	It("SetNotificationProxy rejects invalid proxy URLs", func() {
+		Expect(executor.SetNotificationProxy("ftp://proxy.example.com")).ToNot(Succeed())
+		Expect(executor.SetNotificationProxy("http://")).ToNot(Succeed())
+	})
+})
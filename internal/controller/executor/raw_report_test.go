@@ -0,0 +1,123 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+// Resource message is only part of the JSON report, never of the summary
+var _ = Describe("Raw report", func() {
+	It("sendNotifications sends only the summary to Slack when raw report is excluded", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications[0].IncludeRawReport = ptr.To(false)
+		cleaner.Spec.Notifications[0].Slack = &appsv1alpha1.SlackOptions{UploadReport: true}
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		text := fake.values[0].Get("text")
+		Expect(text).To(ContainSubstring(resource.Resource.GetName()))
+		Expect(text).ToNot(ContainSubstring("Full report attached"))
+		Expect(fake.values[0].Get("attachments")).ToNot(ContainSubstring(resource.Message))
+		Expect(fake.uploads).To(BeEmpty())
+	})
+
+	It("sendNotifications does not attach report to Discord and Webex messages when raw report is excluded", func() {
+		discordRef := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.DiscordChannelID: []byte(randomDiscordID()),
+			libsveltosv1alpha1.DiscordToken:     []byte(randomString()),
+		})
+		discord := &fakeDiscordClient{}
+		useFakeDiscordClient(discord)
+
+		webexRef := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.WebexRoomID: []byte(randomWebexRoomID()),
+			libsveltosv1alpha1.WebexToken:  []byte(randomString()),
+		})
+		webex := &fakeWebexClient{}
+		useFakeWebexClient(webex)
+
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		for _, cleaner := range []*appsv1alpha1.Cleaner{
+			getCleanerWithNotification(appsv1alpha1.NotificationTypeDiscord, discordRef),
+			getCleanerWithNotification(appsv1alpha1.NotificationTypeWebex, webexRef),
+		} {
+			cleaner.Spec.Notifications[0].IncludeRawReport = ptr.To(false)
+			Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+				cleaner, "", logr.Discard())).To(Succeed())
+		}
+
+		Expect(discord.messages).To(HaveLen(1))
+		Expect(discord.messages[0].Content).To(ContainSubstring(resource.Resource.GetName()))
+		Expect(discord.files).To(BeEmpty())
+
+		Expect(webex.requests).To(HaveLen(1))
+		Expect(webex.requests[0].Markdown).To(ContainSubstring("**Resources:** 1 (ConfigMap: 1)"))
+		Expect(webex.files).To(BeEmpty())
+	})
+
+	It("sendNotifications sends summary in SMTP body when raw report is excluded", func() {
+		fake := &fakeMailer{}
+		useFakeMailer(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSMTP, nil)
+		cleaner.Spec.Notifications[0].IncludeRawReport = ptr.To(false)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.bodies).To(HaveLen(1))
+		Expect(fake.bodies[0]).To(HavePrefix("Resources: 1 (ConfigMap: 1)"))
+		Expect(fake.bodies[0]).To(ContainSubstring(resource.Resource.GetName()))
+		Expect(fake.bodies[0]).ToNot(ContainSubstring(resource.Message))
+	})
+
+	It("getReportSizeLimit ignores the report size limit when raw report is excluded", func() {
+		notification := &appsv1alpha1.Notification{
+			Type:             appsv1alpha1.NotificationTypeDiscord,
+			IncludeRawReport: ptr.To(false),
+		}
+		Expect(executor.GetReportSizeLimit(notification)).To(BeZero())
+
+		// Teams always sends the report
+		notification.Type = appsv1alpha1.NotificationTypeTeams
+		Expect(executor.GetReportSizeLimit(notification)).ToNot(BeZero())
+	})
+})
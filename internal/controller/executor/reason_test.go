@@ -0,0 +1,95 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+const cleanerReason = "expired PR preview environments"
+
+var _ = Describe("Reason", func() {
+	It("sendNotifications prefixes the Slack message with the Cleaner reason", func() {
+		ref, fake := createSlackSecret()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Reason = cleanerReason
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(HavePrefix("[" + cleanerReason + "] k8s-cleaner '" +
+			cleaner.Name + "' performed Delete on 1 resource"))
+	})
+
+	It("sendNotifications does not prefix the message when the Cleaner has no reason", func() {
+		ref, fake := createSlackSecret()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(HavePrefix("k8s-cleaner '" + cleaner.Name + "'"))
+	})
+
+	It("sendNotifications prefixes failure notifications with the Cleaner reason", func() {
+		ref, fake := createSlackSecret()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Reason = cleanerReason
+		cleaner.Spec.Notifications[0].NotifyOnFailure = true
+
+		Expect(executor.SendRunNotifications(context.TODO(), nil, cleaner, "", errors.New("list failed"),
+			logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(HavePrefix("[" + cleanerReason + "] Execution failed for k8s-cleaner instance"))
+	})
+
+	It("sendNotifications includes the Cleaner reason in the email subject and report", func() {
+		fake := &fakeMailer{}
+		useFakeMailer(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSMTP, nil)
+		cleaner.Spec.Reason = cleanerReason
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.subjects).To(HaveLen(1))
+		Expect(fake.subjects[0]).To(HavePrefix("[" + cleanerReason + "] k8s-cleaner '" + cleaner.Name + "'"))
+		Expect(fake.bodies[0]).To(ContainSubstring(`"reason": "` + cleanerReason + `"`))
+	})
+
+	It("renderHTMLReport shows the Cleaner reason", func() {
+		reportSpec := &appsv1alpha1.ReportSpec{Action: appsv1alpha1.ActionDelete, Reason: cleanerReason}
+
+		html, err := executor.RenderHTMLReport(randomString(), reportSpec, time.Now())
+		Expect(err).To(BeNil())
+		Expect(string(html)).To(ContainSubstring("Reason: " + cleanerReason))
+	})
+})
@@ -0,0 +1,111 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"fmt"
+	"regexp"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+const defaultRedactionReplacement = "[REDACTED]"
+
+// inClusterNotificationTypes are the notification types whose reports never
+// leave the cluster, so they are not redacted
+var inClusterNotificationTypes = map[appsv1alpha1.NotificationType]bool{
+	appsv1alpha1.NotificationTypeCleanerReport: true,
+	appsv1alpha1.NotificationTypeEvent:         true,
+}
+
+// reportRedactor masks substrings matching any of its patterns
+type reportRedactor struct {
+	patterns    []*regexp.Regexp
+	replacement string
+}
+
+// getReportRedactor returns the redactor for notifications of notificationType.
+// Returned redactor is nil when reports are not redacted.
+func getReportRedactor(redaction *appsv1alpha1.ReportRedaction,
+	notificationType appsv1alpha1.NotificationType) (*reportRedactor, error) {
+
+	if redaction == nil || len(redaction.Patterns) == 0 || inClusterNotificationTypes[notificationType] {
+		return nil, nil
+	}
+
+	r := &reportRedactor{replacement: redaction.Replacement}
+	if r.replacement == "" {
+		r.replacement = defaultRedactionReplacement
+	}
+	for _, pattern := range redaction.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid report redaction pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// redactString returns s with matching substrings replaced
+func (r *reportRedactor) redactString(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, re := range r.patterns {
+		s = re.ReplaceAllLiteralString(s, r.replacement)
+	}
+	return s
+}
+
+// redactReport returns a copy of reportSpec with matching substrings replaced.
+// reportSpec is returned as is when r is nil.
+func (r *reportRedactor) redactReport(reportSpec *appsv1alpha1.ReportSpec) *appsv1alpha1.ReportSpec {
+	if r == nil {
+		return reportSpec
+	}
+
+	redacted := reportSpec.DeepCopy()
+	redacted.Error = r.redactString(redacted.Error)
+	for i := range redacted.ResourceInfo {
+		info := &redacted.ResourceInfo[i]
+		info.Resource.Name = r.redactString(info.Resource.Name)
+		info.Message = r.redactString(info.Message)
+		info.Error = r.redactString(info.Error)
+		info.Diff = r.redactString(info.Diff)
+		for k := range info.Labels {
+			info.Labels[k] = r.redactString(info.Labels[k])
+		}
+		for k := range info.Annotations {
+			info.Annotations[k] = r.redactString(info.Annotations[k])
+		}
+	}
+	if spec := redacted.CleanerSpec; spec != nil {
+		spec.AggregatedSelection = r.redactString(spec.AggregatedSelection)
+		spec.Transform = r.redactString(spec.Transform)
+		for i := range spec.ResourceSelectors {
+			selector := &spec.ResourceSelectors[i]
+			selector.Namespace = r.redactString(selector.Namespace)
+			selector.NamespaceSelector = r.redactString(selector.NamespaceSelector)
+			selector.Evaluate = r.redactString(selector.Evaluate)
+			for j := range selector.LabelFilters {
+				selector.LabelFilters[j].Value = r.redactString(selector.LabelFilters[j].Value)
+			}
+		}
+	}
+	return redacted
+}
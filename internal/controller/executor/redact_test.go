@@ -0,0 +1,136 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+var _ = Describe("Report redaction", func() {
+	It("sendNotifications redacts reports sent outside the cluster only", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications = append(cleaner.Spec.Notifications, appsv1alpha1.Notification{
+			Name: randomString(),
+			Type: appsv1alpha1.NotificationTypeCleanerReport,
+		})
+		cleaner.Spec.ReportRedaction = &appsv1alpha1.ReportRedaction{
+			Patterns: []string{`db-password-[a-z0-9]+`},
+		}
+		secretName := "db-password-" + randomString()
+		resource := getResourceResult("Secret", randomString(), secretName)
+		resource.Message = "unused since " + secretName + " rotation"
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		for _, field := range []string{"text", "attachments"} {
+			Expect(fake.values[0].Get(field)).ToNot(ContainSubstring(secretName), field)
+		}
+		Expect(fake.values[0].Get("text")).To(ContainSubstring("[REDACTED]"))
+		Expect(fake.values[0].Get("attachments")).To(ContainSubstring("unused since [REDACTED] rotation"))
+
+		report := &appsv1alpha1.Report{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, report)).To(Succeed())
+		Expect(report.Spec.ResourceInfo).To(HaveLen(1))
+		Expect(report.Spec.ResourceInfo[0].Resource.Name).To(Equal(secretName))
+		Expect(report.Spec.ResourceInfo[0].Message).To(ContainSubstring(secretName))
+	})
+
+	It("sendNotifications uses the configured replacement", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.ReportRedaction = &appsv1alpha1.ReportRedaction{
+			Patterns:    []string{`^token-`},
+			Replacement: "***-",
+		}
+		suffix := randomString()
+		resource := getResourceResult("Secret", randomString(), "token-"+suffix)
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("attachments")).To(ContainSubstring("***-" + suffix))
+		Expect(fake.values[0].Get("attachments")).ToNot(ContainSubstring("token-" + suffix))
+	})
+
+	It("sendNotifications fails when a redaction pattern is invalid", func() {
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, nil)
+		cleaner.Spec.ReportRedaction = &appsv1alpha1.ReportRedaction{Patterns: []string{`(`}}
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("invalid report redaction pattern"))
+	})
+
+	It("sendNotifications redacts the Cleaner spec included in reports", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.WebexRoomID: []byte(randomWebexRoomID()),
+			libsveltosv1alpha1.WebexToken:  []byte(randomString()),
+		})
+		fake := &fakeWebexClient{}
+		useFakeWebexClient(fake)
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeWebex, ref)
+		cleaner.Spec.IncludeSpecInReport = true
+		cleaner.Spec.ReportRedaction = &appsv1alpha1.ReportRedaction{Patterns: []string{`s3cr3t-[a-z0-9]+`}}
+		secret := "s3cr3t-" + randomString()
+		cleaner.Spec.ResourcePolicySet.ResourceSelectors = []appsv1alpha1.ResourceSelector{
+			{Kind: "Secret", Version: "v1", Evaluate: `function evaluate() return obj.data.token == "` + secret + `" end`},
+		}
+		cleaner.Spec.Transform = `-- key ` + secret
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.files).To(HaveLen(1))
+		Expect(string(fake.files[0])).ToNot(ContainSubstring(secret))
+		reportSpec := &appsv1alpha1.ReportSpec{}
+		Expect(json.Unmarshal(fake.files[0], reportSpec)).To(Succeed())
+		Expect(reportSpec.CleanerSpec).ToNot(BeNil())
+		Expect(reportSpec.CleanerSpec.ResourceSelectors[0].Evaluate).To(ContainSubstring(`== "[REDACTED]"`))
+		Expect(reportSpec.CleanerSpec.Transform).To(Equal("-- key [REDACTED]"))
+	})
+})
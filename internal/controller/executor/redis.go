@@ -0,0 +1,268 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+const (
+	// redisTimeout bounds connecting and each command, so an unreachable
+	// Redis never blocks a Cleaner run
+	redisTimeout = 10 * time.Second
+
+	// maximum length of a Redis reply line
+	maxRedisReplyLine = 64 * 1024
+)
+
+type redisInfo struct {
+	address  string
+	username string
+	password string
+	key      string
+	// tlsConfig is nil when TLS is not used
+	tlsConfig *tls.Config
+}
+
+// redisError is an error reply of the Redis server
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis returned error: " + string(e)
+}
+
+func init() {
+	registerNotifier(appsv1alpha1.NotificationTypeRedis, notifierFunc(
+		func(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+			_ []ResourceResult, _ string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+			return sendRedisNotification(ctx, cleaner, reportSpec, notification, logger)
+		}))
+}
+
+func sendRedisNotification(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+	info, err := getRedisInfo(ctx, notification)
+	if err != nil {
+		return err
+	}
+
+	mode := appsv1alpha1.RedisModeStream
+	var maxLen int64
+	if notification.Redis != nil {
+		if notification.Redis.Mode != "" {
+			mode = notification.Redis.Mode
+		}
+		maxLen = notification.Redis.MaxLen
+	}
+
+	l := logger.WithValues(logKeyChannel, info.key, "mode", string(mode))
+	l.V(logs.LogInfo).Info("send report to redis")
+
+	data, err := marshalReport(reportSpec, getReportEncoding(notification.Type))
+	if err != nil {
+		l.Error(err, logMsgMarshalReportFailed)
+		return err
+	}
+
+	var args []string
+	switch mode {
+	case appsv1alpha1.RedisModeStream:
+		args = []string{"XADD", info.key}
+		if maxLen > 0 {
+			args = append(args, "MAXLEN", "~", strconv.FormatInt(maxLen, 10))
+		}
+		args = append(args, "*", "cleaner", cleaner.Name, "runID", reportSpec.RunID, "report", string(data))
+	case appsv1alpha1.RedisModePubSub:
+		args = []string{"PUBLISH", info.key, string(data)}
+	default:
+		return fmt.Errorf("unsupported redis mode %s", mode)
+	}
+
+	conn, err := dialRedis(ctx, info)
+	if err != nil {
+		l.Error(err, logMsgSendFailed)
+		return err
+	}
+	defer conn.Close()
+
+	reply, err := conn.do(args...)
+	if err != nil {
+		l.Error(err, logMsgSendFailed)
+		return err
+	}
+
+	if mode == appsv1alpha1.RedisModeStream {
+		l.V(logs.LogInfo).Info("report added to redis stream", "entryID", reply)
+	} else {
+		l.V(logs.LogInfo).Info("report published to redis channel", "receivers", reply)
+	}
+	return nil
+}
+
+// redisConn is a connection to a Redis server speaking RESP2
+// https://redis.io/docs/latest/develop/reference/protocol-spec/
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// dialRedis connects to the Redis server and authenticates, if credentials
+// are set
+func dialRedis(ctx context.Context, info *redisInfo) (*redisConn, error) {
+	dialer := &net.Dialer{Timeout: redisTimeout}
+	var conn net.Conn
+	var err error
+	if info.tlsConfig != nil {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: info.tlsConfig}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", info.address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", info.address)
+	}
+	if err != nil {
+		return nil, getTLSError(fmt.Errorf("failed to connect to redis %s: %w", info.address, err))
+	}
+
+	c := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+	if info.password != "" {
+		args := []string{"AUTH", info.password}
+		if info.username != "" {
+			args = []string{"AUTH", info.username, info.password}
+		}
+		if _, err := c.do(args...); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("failed to authenticate to redis: %w", err)
+		}
+	}
+	return c, nil
+}
+
+func (c *redisConn) Close() error {
+	return c.conn.Close()
+}
+
+// do sends a command and returns its reply. Integer and string replies are
+// returned as string.
+func (c *redisConn) do(args ...string) (string, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(redisTimeout)); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&sb, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, sb.String()); err != nil {
+		return "", err
+	}
+
+	return c.readReply()
+}
+
+func (c *redisConn) readReply() (string, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return "", err
+	}
+	if line == "" {
+		return "", fmt.Errorf("empty redis reply")
+	}
+
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", redisError(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return "", fmt.Errorf("invalid redis bulk string length %q", line[1:])
+		}
+		if n < 0 {
+			return "", nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.reader, buf); err != nil {
+			return "", err
+		}
+		return string(buf[:n]), nil
+	default:
+		return "", fmt.Errorf("unexpected redis reply %q", line)
+	}
+}
+
+func (c *redisConn) readLine() (string, error) {
+	line, err := c.reader.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) || len(line) > maxRedisReplyLine {
+		return "", fmt.Errorf("redis reply too long")
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(string(line), "\n"), "\r"), nil
+}
+
+func getRedisInfo(ctx context.Context, notification *appsv1alpha1.Notification) (*redisInfo, error) {
+	secret, err := getSecret(ctx, notification)
+	if err != nil {
+		return nil, err
+	}
+
+	address, ok := secret.Data[appsv1alpha1.RedisAddress]
+	if !ok || len(address) == 0 {
+		return nil, fmt.Errorf("secret does not contain redis address")
+	}
+
+	key, ok := secret.Data[appsv1alpha1.RedisKey]
+	if !ok || len(key) == 0 {
+		return nil, fmt.Errorf("secret does not contain redis key")
+	}
+
+	info := &redisInfo{
+		address:  string(address),
+		username: string(secret.Data[appsv1alpha1.RedisUsername]),
+		password: string(secret.Data[appsv1alpha1.RedisPassword]),
+		key:      string(key),
+	}
+
+	info.tlsConfig, err = getNotificationTLSConfig(secret)
+	if err != nil {
+		return nil, err
+	}
+	if info.tlsConfig == nil && strings.EqualFold(string(secret.Data[appsv1alpha1.RedisTLS]), "true") {
+		info.tlsConfig = newNotificationTLSConfig()
+	}
+
+	return info, nil
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+// fakeRedis is a Redis server recording received commands. reply returns the
+// RESP reply to a command.
+type fakeRedis struct {
+	mu       sync.Mutex
+	commands [][]string
+	reply    func(command []string) string
+}
+
+func (f *fakeRedis) getCommands() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.commands
+}
+
+// newFakeRedis starts a fake Redis server and returns it along with its address
+func newFakeRedis(reply func(command []string) string) (*fakeRedis, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).To(BeNil())
+	DeferCleanup(listener.Close)
+
+	f := &fakeRedis{reply: reply}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f, listener.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		command, err := readRedisCommand(reader)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.commands = append(f.commands, command)
+		f.mu.Unlock()
+		if _, err := io.WriteString(conn, f.reply(command)); err != nil {
+			return
+		}
+	}
+}
+
+// readRedisCommand reads a command sent as RESP array of bulk strings
+func readRedisCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	command := make([]string, n)
+	for i := range command {
+		if line, err = reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		command[i] = string(buf[:size])
+	}
+	return command, nil
+}
+
+// redisOK replies to AUTH with OK, to XADD with an entry ID and to PUBLISH with
+// the number of receivers
+func redisOK(command []string) string {
+	switch command[0] {
+	case "XADD":
+		return "$15\r\n1700000000000-0\r\n"
+	case "PUBLISH":
+		return ":2\r\n"
+	default:
+		return "+OK\r\n"
+	}
+}
+
+var _ = Describe("Redis", func() {
+	It("sendNotifications adds report to Redis stream", func() {
+		fake, address := newFakeRedis(redisOK)
+		stream := randomString()
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.RedisAddress:  []byte(address),
+			appsv1alpha1.RedisUsername: []byte("cleaner"),
+			appsv1alpha1.RedisPassword: []byte("secret"),
+			appsv1alpha1.RedisKey:      []byte(stream),
+		})
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeRedis, ref)
+		cleaner.Spec.Notifications[0].Redis = &appsv1alpha1.RedisOptions{MaxLen: 1000}
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		runID := randomString()
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, runID, logr.Discard())).To(Succeed())
+
+		commands := fake.getCommands()
+		Expect(commands).To(HaveLen(2))
+		Expect(commands[0]).To(Equal([]string{"AUTH", "cleaner", "secret"}))
+		Expect(commands[1][:10]).To(Equal([]string{"XADD", stream, "MAXLEN", "~", "1000", "*",
+			"cleaner", cleaner.Name, "runID", runID}))
+		Expect(commands[1][10]).To(Equal("report"))
+
+		reportSpec := &appsv1alpha1.ReportSpec{}
+		Expect(json.Unmarshal([]byte(commands[1][11]), reportSpec)).To(Succeed())
+		Expect(reportSpec.RunID).To(Equal(runID))
+		Expect(reportSpec.ResourceInfo).To(HaveLen(1))
+	})
+
+	It("sendNotifications publishes report to Redis channel", func() {
+		fake, address := newFakeRedis(redisOK)
+		channel := randomString()
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.RedisAddress: []byte(address),
+			appsv1alpha1.RedisKey:     []byte(channel),
+		})
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeRedis, ref)
+		cleaner.Spec.Notifications[0].Redis = &appsv1alpha1.RedisOptions{Mode: appsv1alpha1.RedisModePubSub}
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		// No credentials, so no AUTH
+		commands := fake.getCommands()
+		Expect(commands).To(HaveLen(1))
+		Expect(commands[0][:2]).To(Equal([]string{"PUBLISH", channel}))
+		Expect(commands[0][2]).To(ContainSubstring(resource.Resource.GetName()))
+	})
+
+	It("sendNotifications returns Redis error replies", func() {
+		_, address := newFakeRedis(func(command []string) string {
+			return "-WRONGPASS invalid username-password pair or user is disabled.\r\n"
+		})
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.RedisAddress:  []byte(address),
+			appsv1alpha1.RedisPassword: []byte(randomString()),
+			appsv1alpha1.RedisKey:      []byte(randomString()),
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeRedis, ref)
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("failed to authenticate to redis"))
+		Expect(err.Error()).To(ContainSubstring("WRONGPASS"))
+	})
+
+	It("sendNotifications returns connection errors", func() {
+		// Reserve an address nobody listens on
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).To(BeNil())
+		address := listener.Addr().String()
+		Expect(listener.Close()).To(Succeed())
+
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.RedisAddress: []byte(address),
+			appsv1alpha1.RedisKey:     []byte(randomString()),
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeRedis, ref)
+
+		err = executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring(fmt.Sprintf("failed to connect to redis %s", address)))
+	})
+})
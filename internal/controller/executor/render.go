@@ -0,0 +1,427 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"regexp"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+const (
+	// reportTimestampFormat is used when a timestamp is part of a file name
+	reportTimestampFormat = "20060102-150405"
+)
+
+// reportEncoding is how a report is serialized as JSON
+type reportEncoding int
+
+const (
+	// compactJSON is used for payloads consumed by machines
+	compactJSON reportEncoding = iota
+
+	// indentedJSON is used for reports read by humans
+	indentedJSON
+)
+
+// reportEncodings maps notification types to the JSON encoding of their report.
+// Reports read by humans, as a message attachment, a downloaded file or an email
+// body, are indented. Types not listed (webhooks and event collectors) keep
+// compact JSON. Teams embeds the report in a size-limited card, so it is compact
+// as well.
+var reportEncodings = map[appsv1alpha1.NotificationType]reportEncoding{
+	appsv1alpha1.NotificationTypeSlack:   indentedJSON,
+	appsv1alpha1.NotificationTypeDiscord: indentedJSON,
+	appsv1alpha1.NotificationTypeWebex:   indentedJSON,
+	appsv1alpha1.NotificationTypeSMTP:    indentedJSON,
+	appsv1alpha1.NotificationTypeFile:    indentedJSON,
+}
+
+func getReportEncoding(notificationType appsv1alpha1.NotificationType) reportEncoding {
+	return reportEncodings[notificationType]
+}
+
+// marshalReport serializes reportSpec as JSON using encoding
+func marshalReport(reportSpec *appsv1alpha1.ReportSpec, encoding reportEncoding) ([]byte, error) {
+	if encoding == indentedJSON {
+		return json.MarshalIndent(*reportSpec, "", "  ")
+	}
+	return json.Marshal(*reportSpec)
+}
+
+// rawReportOptionalTypes are the notification types which can omit the JSON
+// report, sending only the rendered summary
+var rawReportOptionalTypes = map[appsv1alpha1.NotificationType]bool{
+	appsv1alpha1.NotificationTypeSlack:   true,
+	appsv1alpha1.NotificationTypeDiscord: true,
+	appsv1alpha1.NotificationTypeWebex:   true,
+	appsv1alpha1.NotificationTypeSMTP:    true,
+}
+
+// isRawReportIncluded returns true unless notification opts out of the JSON
+// report, in which case only the rendered summary is sent
+func isRawReportIncluded(notification *appsv1alpha1.Notification) bool {
+	if !rawReportOptionalTypes[notification.Type] {
+		return true
+	}
+	return notification.IncludeRawReport == nil || *notification.IncludeRawReport
+}
+
+// isInResourceScope returns true if a resource in namespace belongs to scope.
+// Cluster-scoped resources have no namespace.
+func isInResourceScope(namespace string, scope appsv1alpha1.ResourceScope) bool {
+	switch scope {
+	case appsv1alpha1.ResourceScopeNamespaced:
+		return namespace != ""
+	case appsv1alpha1.ResourceScopeCluster:
+		return namespace == ""
+	default:
+		return true
+	}
+}
+
+// filterResourcesByScope returns the resources belonging to scope
+func filterResourcesByScope(resources []ResourceResult, scope appsv1alpha1.ResourceScope) []ResourceResult {
+	if scope == "" || scope == appsv1alpha1.ResourceScopeAll {
+		return resources
+	}
+
+	filtered := make([]ResourceResult, 0, len(resources))
+	for i := range resources {
+		if isInResourceScope(resources[i].Resource.GetNamespace(), scope) {
+			filtered = append(filtered, resources[i])
+		}
+	}
+	return filtered
+}
+
+// filterReportByScope only keeps in reportSpec the resources belonging to scope.
+// Summary is updated accordingly.
+func filterReportByScope(reportSpec *appsv1alpha1.ReportSpec, scope appsv1alpha1.ResourceScope) {
+	if scope == "" || scope == appsv1alpha1.ResourceScopeAll {
+		return
+	}
+
+	filtered := make([]appsv1alpha1.ResourceInfo, 0, len(reportSpec.ResourceInfo))
+	for i := range reportSpec.ResourceInfo {
+		if isInResourceScope(reportSpec.ResourceInfo[i].Resource.Namespace, scope) {
+			filtered = append(filtered, reportSpec.ResourceInfo[i])
+		}
+	}
+	reportSpec.ResourceInfo = filtered
+	reportSpec.Summary = getReportSummary(filtered)
+}
+
+// resourceFilter is the compiled ResourceFilter of a notification. A nil
+// resourceFilter keeps every resource.
+type resourceFilter struct {
+	include *resourceFilterMatch
+	exclude *resourceFilterMatch
+	// route, when set, only keeps the resources whose namespace is routed to it
+	route *namespaceRouteMatch
+}
+
+// resourceFilterMatch is a compiled ResourceFilterMatch. A nil expression
+// matches any value.
+type resourceFilterMatch struct {
+	kind      *regexp.Regexp
+	namespace *regexp.Regexp
+}
+
+// getResourceFilter compiles filter. Nil is returned when filter is nil.
+func getResourceFilter(filter *appsv1alpha1.ResourceFilter) (*resourceFilter, error) {
+	if filter == nil {
+		return nil, nil
+	}
+
+	include, err := getResourceFilterMatch(filter.Include)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resource filter include: %w", err)
+	}
+	exclude, err := getResourceFilterMatch(filter.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resource filter exclude: %w", err)
+	}
+	return &resourceFilter{include: include, exclude: exclude}, nil
+}
+
+func getResourceFilterMatch(match *appsv1alpha1.ResourceFilterMatch) (*resourceFilterMatch, error) {
+	if match == nil {
+		return nil, nil
+	}
+
+	kind, err := compileWholeValueRegexp(match.Kind)
+	if err != nil {
+		return nil, fmt.Errorf("kind: %w", err)
+	}
+	namespace, err := compileWholeValueRegexp(match.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("namespace: %w", err)
+	}
+	return &resourceFilterMatch{kind: kind, namespace: namespace}, nil
+}
+
+// compileWholeValueRegexp compiles expr so that it must match the whole value.
+// Nil is returned for an empty expression.
+func compileWholeValueRegexp(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	return regexp.Compile("^(?:" + expr + ")$")
+}
+
+func (m *resourceFilterMatch) matches(kind, namespace string) bool {
+	return (m.kind == nil || m.kind.MatchString(kind)) &&
+		(m.namespace == nil || m.namespace.MatchString(namespace))
+}
+
+// keeps returns true if a resource of kind in namespace matches Include, if
+// set, is routed to route, if set, and does not match Exclude, if set
+func (f *resourceFilter) keeps(kind, namespace string) bool {
+	if f == nil {
+		return true
+	}
+	if f.include != nil && !f.include.matches(kind, namespace) {
+		return false
+	}
+	if f.route != nil && !f.route.matches(namespace) {
+		return false
+	}
+	return f.exclude == nil || !f.exclude.matches(kind, namespace)
+}
+
+// filterResourcesByFilter returns the resources kept by filter
+func filterResourcesByFilter(resources []ResourceResult, filter *resourceFilter) []ResourceResult {
+	if filter == nil {
+		return resources
+	}
+
+	filtered := make([]ResourceResult, 0, len(resources))
+	for i := range resources {
+		if filter.keeps(resources[i].Resource.GetKind(), resources[i].Resource.GetNamespace()) {
+			filtered = append(filtered, resources[i])
+		}
+	}
+	return filtered
+}
+
+// filterReportByFilter only keeps in reportSpec the resources kept by filter.
+// Summary is updated accordingly.
+func filterReportByFilter(reportSpec *appsv1alpha1.ReportSpec, filter *resourceFilter) {
+	if filter == nil {
+		return
+	}
+
+	filtered := make([]appsv1alpha1.ResourceInfo, 0, len(reportSpec.ResourceInfo))
+	for i := range reportSpec.ResourceInfo {
+		resource := &reportSpec.ResourceInfo[i].Resource
+		if filter.keeps(resource.Kind, resource.Namespace) {
+			filtered = append(filtered, reportSpec.ResourceInfo[i])
+		}
+	}
+	reportSpec.ResourceInfo = filtered
+	reportSpec.Summary = getReportSummary(filtered)
+}
+
+// htmlReportTemplate renders a report as a self-contained HTML document.
+// All styling is inline so the document renders the same when opened
+// as a standalone file.
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="UTF-8">
+<title>k8s-cleaner report: {{ .CleanerName }}</title>
+</head>
+<body style="font-family: Arial, Helvetica, sans-serif; color: #24292f; margin: 24px;">
+<h2 style="margin-bottom: 4px;">k8s-cleaner report: {{ .CleanerName }}</h2>
+{{- if .Reason }}
+<p style="margin: 4px 0; font-weight: bold;">Reason: {{ .Reason }}</p>
+{{- end }}
+<p style="margin-top: 0; color: #57606a;">Action: {{ .Action }}
+{{- if .RunID }} &middot; Run ID: {{ .RunID }}{{ end }} &middot; Generated: {{ .GeneratedAt }}
+{{- " " }}&middot; Resources: {{ len .Resources }}
+{{- if .Failed }} &middot; <span style="color: #cf222e; font-weight: bold;">Failed: {{ .Failed }}</span>{{ end }}</p>
+<table style="border-collapse: collapse; width: 100%; font-size: 14px;">
+<thead>
+<tr style="background-color: #f6f8fa;">
+<th style="border: 1px solid #d0d7de; padding: 6px 10px; text-align: left;">Kind</th>
+<th style="border: 1px solid #d0d7de; padding: 6px 10px; text-align: left;">Namespace</th>
+<th style="border: 1px solid #d0d7de; padding: 6px 10px; text-align: left;">Name</th>
+<th style="border: 1px solid #d0d7de; padding: 6px 10px; text-align: left;">APIVersion</th>
+<th style="border: 1px solid #d0d7de; padding: 6px 10px; text-align: left;">Outcome</th>
+<th style="border: 1px solid #d0d7de; padding: 6px 10px; text-align: left;">Message</th>
+<th style="border: 1px solid #d0d7de; padding: 6px 10px; text-align: left;">Labels/Annotations</th>
+</tr>
+</thead>
+<tbody>
+{{- range .Resources }}
+<tr{{ if eq .Outcome "Failed" }} style="background-color: #ffebe9; color: #cf222e;"{{ end }}>
+<td style="border: 1px solid #d0d7de; padding: 6px 10px;">{{ .Resource.Kind }}</td>
+<td style="border: 1px solid #d0d7de; padding: 6px 10px;">{{ .Resource.Namespace }}</td>
+<td style="border: 1px solid #d0d7de; padding: 6px 10px;">{{ .Resource.Name }}</td>
+<td style="border: 1px solid #d0d7de; padding: 6px 10px;">{{ .Resource.APIVersion }}</td>
+<td style="border: 1px solid #d0d7de; padding: 6px 10px;">{{ .Outcome }}{{ if .Error }}: {{ .Error }}{{ end }}
+{{- with .Backup }}<br>Restore: <code>{{ .RestoreCommand }}</code>{{ end }}</td>
+<td style="border: 1px solid #d0d7de; padding: 6px 10px;">{{ .Message }}</td>
+<td style="border: 1px solid #d0d7de; padding: 6px 10px;">
+{{- range $key, $value := .Labels }}{{ $key }}={{ $value }}<br>{{ end }}
+{{- range $key, $value := .Annotations }}{{ $key }}={{ $value }}<br>{{ end -}}
+</td>
+</tr>
+{{- else }}
+<tr>
+<td colspan="7" style="border: 1px solid #d0d7de; padding: 6px 10px;">No resources matched</td>
+</tr>
+{{- end }}
+</tbody>
+</table>
+{{- if .Diffs }}
+<h3>Changes</h3>
+{{- range .Diffs }}
+<p style="margin-bottom: 4px; font-weight: bold;">{{ .Resource }}</p>
+<table style="border-collapse: collapse; width: 100%; font-family: monospace; font-size: 13px;">
+<tbody>
+{{- range .Lines }}
+<tr><td style="border: 1px solid #d0d7de; padding: 2px 10px; white-space: pre;{{ if .Color }} background-color: {{ .Color }};{{ end }}">{{ .Text }}</td></tr>
+{{- end }}
+</tbody>
+</table>
+{{- end }}
+{{- end }}
+</body>
+</html>
+`))
+
+type htmlReport struct {
+	CleanerName string
+	Action      appsv1alpha1.Action
+	RunID       string
+	Reason      string
+	GeneratedAt string
+	Resources   []appsv1alpha1.ResourceInfo
+	Failed      int
+	Diffs       []htmlResourceDiff
+}
+
+// htmlResourceDiff is the diff of a transformed resource, rendered as
+// a table with one row per line
+type htmlResourceDiff struct {
+	Resource string
+	Lines    []diffLine
+}
+
+// renderHTMLReport renders reportSpec as a self-contained HTML document.
+// Failed resources are highlighted in red. Transform diffs, if any, are rendered
+// in a table per resource.
+// generatedAt is rendered in its own time zone.
+func renderHTMLReport(cleanerName string, reportSpec *appsv1alpha1.ReportSpec,
+	generatedAt time.Time) ([]byte, error) {
+
+	report := htmlReport{
+		CleanerName: cleanerName,
+		Action:      reportSpec.Action,
+		RunID:       reportSpec.RunID,
+		Reason:      reportSpec.Reason,
+		GeneratedAt: generatedAt.Format(time.RFC3339),
+		Resources:   reportSpec.ResourceInfo,
+		Failed:      getFailedResourceCount(reportSpec),
+	}
+	for i := range reportSpec.ResourceInfo {
+		info := &reportSpec.ResourceInfo[i]
+		if info.Diff == "" {
+			continue
+		}
+		report.Diffs = append(report.Diffs, htmlResourceDiff{
+			Resource: getResourceDescription(&info.Resource),
+			Lines:    getDiffLines(info.Diff),
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := htmlReportTemplate.Execute(&buf, report); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderCSVReport renders reportSpec as CSV, with a header line followed by
+// one line per resource. Each line contains the run ID so lines can be
+// correlated once files are merged.
+func renderCSVReport(reportSpec *appsv1alpha1.ReportSpec) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	if err := writer.Write([]string{"runID", "action", "kind", "namespace", "name", "apiVersion", "message",
+		"labels", "annotations", "diff", "outcome", "error", "backup"}); err != nil {
+		return nil, err
+	}
+	for i := range reportSpec.ResourceInfo {
+		info := &reportSpec.ResourceInfo[i]
+		backup := ""
+		if info.Backup != nil {
+			backup = info.Backup.Location
+		}
+		if err := writer.Write([]string{reportSpec.RunID, string(reportSpec.Action), info.Resource.Kind,
+			info.Resource.Namespace, info.Resource.Name, info.Resource.APIVersion, info.Message,
+			formatCSVMetadata(info.Labels), formatCSVMetadata(info.Annotations), info.Diff,
+			string(info.Outcome), info.Error, backup}); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderYAMLReport renders reportSpec as YAML. Fields are named as in JSON, so
+// the file can be decoded back into a ReportSpec.
+func renderYAMLReport(reportSpec *appsv1alpha1.ReportSpec) ([]byte, error) {
+	return yaml.Marshal(*reportSpec)
+}
+
+// formatCSVMetadata returns labels (or annotations) as key=value pairs,
+// sorted by key and separated by semicolons
+func formatCSVMetadata(values map[string]string) string {
+	pairs := make([]string, 0, len(values))
+	for _, key := range getSortedMetadataKeys(values) {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, values[key]))
+	}
+	return strings.Join(pairs, ";")
+}
+
+// getReportFileName returns the name of a file containing a report for a
+// Cleaner instance generated at the given time. The run ID, when set, keeps
+// reports of runs generated within the same second apart.
+func getReportFileName(cleanerName, runID string, generatedAt time.Time, extension string) string {
+	timestamp := generatedAt.UTC().Format(reportTimestampFormat)
+	if runID == "" {
+		return fmt.Sprintf("%s-%s.%s", cleanerName, timestamp, extension)
+	}
+	return fmt.Sprintf("%s-%s-%s.%s", cleanerName, timestamp, runID, extension)
+}
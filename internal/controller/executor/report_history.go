@@ -0,0 +1,66 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+// maxReportHistoryLimit caps the number of runs kept in the Report history,
+// whatever the Cleaner ReportHistoryLimit
+const maxReportHistoryLimit = 100
+
+// getReportHistory returns history with the summary of reportSpec appended and
+// the oldest summaries dropped, so that at most limit are kept. A report whose
+// run is already the last one of history (for instance stored both by a
+// CleanerReport notification and because it overflowed a channel) replaces it.
+// Nil is returned when limit is not positive: history is not kept.
+func getReportHistory(history []appsv1alpha1.ReportRunSummary, reportSpec *appsv1alpha1.ReportSpec,
+	limit int32, now metav1.Time) []appsv1alpha1.ReportRunSummary {
+
+	if limit <= 0 {
+		return nil
+	}
+	if limit > maxReportHistoryLimit {
+		limit = maxReportHistoryLimit
+	}
+
+	summary := appsv1alpha1.ReportRunSummary{
+		Time:      now,
+		RunID:     reportSpec.RunID,
+		Action:    reportSpec.Action,
+		Total:     int32(len(reportSpec.ResourceInfo)),
+		RunFailed: reportSpec.Error != "",
+	}
+	if reportSpec.Summary != nil {
+		summary.Total = reportSpec.Summary.Total
+		summary.Failed = reportSpec.Summary.Failed
+	}
+
+	result := make([]appsv1alpha1.ReportRunSummary, 0, len(history)+1)
+	result = append(result, history...)
+	if last := len(result) - 1; last >= 0 && summary.RunID != "" && result[last].RunID == summary.RunID {
+		result = result[:last]
+	}
+	result = append(result, summary)
+	if len(result) > int(limit) {
+		result = result[len(result)-int(limit):]
+	}
+	return result
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+const (
+	labelCleanerName = "cleaner"
+)
+
+var (
+	reportResourceCount = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "k8s_cleaner_report_resource_count",
+		Help:    "Number of resources reported by a run, per Cleaner",
+		Buckets: prometheus.ExponentialBuckets(1, 4, 8),
+	}, []string{labelCleanerName})
+
+	reportBytes = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "k8s_cleaner_report_bytes",
+		Help:    "Size in bytes of the report sent to size limited channels, per Cleaner and notification type",
+		Buckets: prometheus.ExponentialBuckets(1024, 2, 10),
+	}, []string{labelCleanerName, labelNotificationType})
+
+	reportTruncated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "k8s_cleaner_report_truncated_total",
+		Help: "Number of reports exceeding the channel limit, per Cleaner and notification type",
+	}, []string{labelCleanerName, labelNotificationType})
+)
+
+func init() {
+	metrics.Registry.MustRegister(reportResourceCount, reportBytes, reportTruncated)
+}
+
+// recordReportResourceCount records the number of resources reported by a run
+// of cleaner
+func recordReportResourceCount(cleanerName string, count int) {
+	reportResourceCount.WithLabelValues(cleanerName).Observe(float64(count))
+}
+
+// recordReportSize records the size of the report sent by notification and
+// whether it exceeds limit, the report size limit of the channel
+func recordReportSize(cleanerName string, notificationType appsv1alpha1.NotificationType, size, limit int) {
+	reportBytes.WithLabelValues(cleanerName, string(notificationType)).Observe(float64(size))
+	if size > limit {
+		reportTruncated.WithLabelValues(cleanerName, string(notificationType)).Inc()
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+var _ = Describe("Report metrics", func() {
+	It("sendNotifications records report resource count and size", func() {
+		ref, _ := createSlackSecret()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		value, ok := executor.GetReportMetric("k8s_cleaner_report_resource_count", cleaner.Name)
+		Expect(ok).To(BeTrue())
+		Expect(value).To(Equal(float64(1)))
+		value, ok = executor.GetReportMetric("k8s_cleaner_report_bytes", cleaner.Name)
+		Expect(ok).To(BeTrue())
+		Expect(value).To(Equal(float64(1)))
+		_, ok = executor.GetReportMetric("k8s_cleaner_report_truncated_total", cleaner.Name)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("sendNotifications counts reports too large for the channel", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.TeamsWebhookURL: []byte("https://example.webhook.office.com/" + randomString()),
+		})
+		DeferCleanup(executor.SetTeamsClientFactory(func() executor.TeamsClient {
+			return &fakeTeamsClient{}
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeTeams, ref)
+		cleaner.Spec.DisableReport = true
+		resources := make([]executor.ResourceResult, 300)
+		for i := range resources {
+			resources[i] = getResourceResult("ConfigMap", randomString(), randomString())
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		value, ok := executor.GetReportMetric("k8s_cleaner_report_truncated_total", cleaner.Name)
+		Expect(ok).To(BeTrue())
+		Expect(value).To(Equal(float64(1)))
+	})
+})
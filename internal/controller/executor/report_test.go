@@ -0,0 +1,302 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+// racingReportClient simulates another writer modifying Reports concurrently.
+// Before the first Update of a Report, the Report is modified so the Update
+// conflicts. Before the first Create of a Report, the Report is created so
+// the Create fails as already existing.
+type racingReportClient struct {
+	client.Client
+	updates int
+	creates int
+}
+
+func (c *racingReportClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if _, ok := obj.(*appsv1alpha1.Report); ok {
+		c.updates++
+		if c.updates == 1 {
+			current := &appsv1alpha1.Report{}
+			Expect(c.Client.Get(ctx, types.NamespacedName{Name: obj.GetName()}, current)).To(Succeed())
+			current.Spec.RunID = randomString()
+			Expect(c.Client.Update(ctx, current)).To(Succeed())
+		}
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c *racingReportClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if _, ok := obj.(*appsv1alpha1.Report); ok {
+		c.creates++
+		if c.creates == 1 {
+			Expect(c.Client.Create(ctx, &appsv1alpha1.Report{
+				ObjectMeta: metav1.ObjectMeta{Name: obj.GetName()},
+			})).To(Succeed())
+		}
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+// forbiddenReportClient fails creating Reports
+type forbiddenReportClient struct {
+	client.Client
+	creates int
+}
+
+func (c *forbiddenReportClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if _, ok := obj.(*appsv1alpha1.Report); ok {
+		c.creates++
+		Expect(c.creates).To(Equal(1))
+		return apierrors.NewForbidden(appsv1alpha1.GroupVersion.WithResource("reports").GroupResource(),
+			obj.GetName(), fmt.Errorf("denied"))
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+// noReportKindClient fails getting Reports as if the Report CRD was not
+// established, the first failures times
+type noReportKindClient struct {
+	client.Client
+	failures int
+	gets     int
+}
+
+func (c *noReportKindClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object,
+	opts ...client.GetOption) error {
+
+	if _, ok := obj.(*appsv1alpha1.Report); ok {
+		c.gets++
+		if c.gets <= c.failures {
+			return &meta.NoKindMatchError{
+				GroupKind:        appsv1alpha1.GroupVersion.WithKind("Report").GroupKind(),
+				SearchedVersions: []string{appsv1alpha1.GroupVersion.Version},
+			}
+		}
+	}
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+var _ = Describe("Report", func() {
+	It("sendNotifications includes the Cleaner spec in the Report when IncludeSpecInReport is set", func() {
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCleanerReport, nil)
+		cleaner.Spec.Schedule = "0 * * * *"
+		cleaner.Spec.ResourcePolicySet = appsv1alpha1.ResourcePolicySet{
+			ResourceSelectors: []appsv1alpha1.ResourceSelector{
+				{Kind: "ConfigMap", Version: "v1", Namespace: "test"},
+			},
+			AggregatedSelection: "function evaluate() end",
+		}
+		cleaner.Spec.IncludeSpecInReport = true
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		report := &appsv1alpha1.Report{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, report)).To(Succeed())
+		Expect(report.Spec.CleanerSpec).To(Equal(&appsv1alpha1.ReportCleanerSpec{
+			Schedule:            cleaner.Spec.Schedule,
+			Action:              appsv1alpha1.ActionDelete,
+			ResourceSelectors:   cleaner.Spec.ResourcePolicySet.ResourceSelectors,
+			AggregatedSelection: cleaner.Spec.ResourcePolicySet.AggregatedSelection,
+		}))
+
+		cleaner.Spec.IncludeSpecInReport = false
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, report)).To(Succeed())
+		Expect(report.Spec.CleanerSpec).To(BeNil())
+	})
+
+	It("sendNotifications updates Report retrying on conflict", func() {
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCleanerReport, nil)
+		Expect(k8sClient.Create(context.TODO(), &appsv1alpha1.Report{
+			ObjectMeta: metav1.ObjectMeta{Name: cleaner.Name},
+		})).To(Succeed())
+
+		racing := &racingReportClient{Client: k8sClient}
+		DeferCleanup(executor.SetK8sClient(racing))
+
+		runID := randomString()
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, runID, logr.Discard())).To(Succeed())
+		Expect(racing.updates).To(Equal(2))
+
+		report := &appsv1alpha1.Report{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, report)).To(Succeed())
+		Expect(report.Spec.RunID).To(Equal(runID))
+		Expect(report.Spec.ResourceInfo).To(HaveLen(1))
+	})
+
+	It("sendNotifications updates Report created concurrently", func() {
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCleanerReport, nil)
+
+		racing := &racingReportClient{Client: k8sClient}
+		DeferCleanup(executor.SetK8sClient(racing))
+
+		runID := randomString()
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, runID, logr.Discard())).To(Succeed())
+		Expect(racing.creates).To(Equal(1))
+
+		report := &appsv1alpha1.Report{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, report)).To(Succeed())
+		Expect(report.Spec.RunID).To(Equal(runID))
+	})
+
+	It("sendNotifications retries creating Report till the Report CRD is established", func() {
+		DeferCleanup(executor.SetReportKindBackoff(wait.Backoff{Duration: time.Millisecond, Steps: 3}))
+		noKind := &noReportKindClient{Client: k8sClient, failures: 2}
+		DeferCleanup(executor.SetK8sClient(noKind))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCleanerReport, nil)
+		runID := randomString()
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, runID, logr.Discard())).To(Succeed())
+		Expect(noKind.gets).To(Equal(3))
+
+		report := &appsv1alpha1.Report{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, report)).To(Succeed())
+		Expect(report.Spec.RunID).To(Equal(runID))
+	})
+
+	It("sendNotifications skips Report and sends other notifications when the Report CRD is missing", func() {
+		DeferCleanup(executor.SetReportKindBackoff(wait.Backoff{Duration: time.Millisecond, Steps: 3}))
+		noKind := &noReportKindClient{Client: k8sClient, failures: 10}
+		DeferCleanup(executor.SetK8sClient(noKind))
+		recorder := record.NewFakeRecorder(10)
+		DeferCleanup(executor.SetEventRecorder(recorder))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCleanerReport, nil)
+		cleaner.Spec.Notifications = append(cleaner.Spec.Notifications, appsv1alpha1.Notification{
+			Name: randomString(),
+			Type: appsv1alpha1.NotificationTypeEvent,
+		})
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+		Expect(noKind.gets).To(Equal(3))
+		Expect(recorder.Events).To(HaveLen(1))
+	})
+
+	It("sendNotifications skips Report and sends other notifications when DisableReport is set", func() {
+		recorder := record.NewFakeRecorder(10)
+		DeferCleanup(executor.SetEventRecorder(recorder))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCleanerReport, nil)
+		cleaner.Spec.DisableReport = true
+		cleaner.Spec.Notifications = append(cleaner.Spec.Notifications, appsv1alpha1.Notification{
+			Name: randomString(),
+			Type: appsv1alpha1.NotificationTypeEvent,
+		})
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+		Expect(recorder.Events).To(HaveLen(1))
+
+		report := &appsv1alpha1.Report{}
+		err := k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, report)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("sendNotifications keeps the summaries of the last runs in the Report status", func() {
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCleanerReport, nil)
+		cleaner.Spec.ReportHistoryLimit = 2
+
+		runIDs := []string{randomString(), randomString(), randomString()}
+		for i := range runIDs {
+			resources := make([]executor.ResourceResult, i+1)
+			for j := range resources {
+				resources[j] = getResourceResult("ConfigMap", randomString(), randomString())
+			}
+			Expect(executor.SendNotifications(context.TODO(), resources, cleaner, runIDs[i], logr.Discard())).To(Succeed())
+		}
+
+		report := &appsv1alpha1.Report{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, report)).To(Succeed())
+		Expect(report.Spec.RunID).To(Equal(runIDs[2]))
+		Expect(report.Status.History).To(HaveLen(2))
+		Expect(report.Status.History[0].RunID).To(Equal(runIDs[1]))
+		Expect(report.Status.History[0].Total).To(Equal(int32(2)))
+		Expect(report.Status.History[1].RunID).To(Equal(runIDs[2]))
+		Expect(report.Status.History[1].Total).To(Equal(int32(3)))
+		Expect(report.Status.History[1].Action).To(Equal(appsv1alpha1.ActionDelete))
+	})
+
+	It("sendNotifications does not keep Report history unless ReportHistoryLimit is set", func() {
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCleanerReport, nil)
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, randomString(), logr.Discard())).To(Succeed())
+
+		report := &appsv1alpha1.Report{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, report)).To(Succeed())
+		Expect(report.Status.History).To(BeEmpty())
+	})
+
+	It("getReportHistory caps the history and replaces the summary of the same run", func() {
+		now := metav1.Now()
+		history := []appsv1alpha1.ReportRunSummary{}
+		for i := 0; i < 150; i++ {
+			history = executor.GetReportHistory(history, &appsv1alpha1.ReportSpec{RunID: fmt.Sprintf("run-%d", i)}, 500, now)
+		}
+		Expect(history).To(HaveLen(100))
+		Expect(history[0].RunID).To(Equal("run-50"))
+
+		reportSpec := &appsv1alpha1.ReportSpec{
+			RunID:   "run-149",
+			Error:   "list failed",
+			Summary: &appsv1alpha1.ReportSummary{Total: 4, Failed: 1},
+		}
+		history = executor.GetReportHistory(history, reportSpec, 500, now)
+		Expect(history).To(HaveLen(100))
+		Expect(history[99]).To(Equal(appsv1alpha1.ReportRunSummary{
+			Time: now, RunID: "run-149", Total: 4, Failed: 1, RunFailed: true,
+		}))
+
+		Expect(executor.GetReportHistory(history, reportSpec, 0, now)).To(BeNil())
+	})
+
+	It("sendNotifications does not retry on other errors", func() {
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCleanerReport, nil)
+		DeferCleanup(executor.SetK8sClient(&forbiddenReportClient{Client: k8sClient}))
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(apierrors.IsForbidden(err)).To(BeTrue())
+	})
+})
@@ -0,0 +1,81 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"fmt"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+// getLastMatchCount returns the number of resources processed by the last
+// successful run, as recorded in the Cleaner status
+func getLastMatchCount(cleaner *appsv1alpha1.Cleaner) int32 {
+	if cleaner.Status.LastMatchCount == nil {
+		return 0
+	}
+	return *cleaner.Status.LastMatchCount
+}
+
+// isResolvedRun returns true if the run succeeded matching no resource while
+// the previous successful run matched some
+func isResolvedRun(cleaner *appsv1alpha1.Cleaner, resources []ResourceResult, runErr error) bool {
+	return runErr == nil && len(resources) == 0 && getLastMatchCount(cleaner) > 0
+}
+
+// isThresholdExceededRun returns true if the run succeeded matching at least
+// threshold resources while the previous successful run matched fewer
+func isThresholdExceededRun(cleaner *appsv1alpha1.Cleaner, resources []ResourceResult, runErr error,
+	threshold *int32) bool {
+
+	return runErr == nil && threshold != nil && len(resources) >= int(*threshold) &&
+		getLastMatchCount(cleaner) < *threshold
+}
+
+// getThresholdExceededMessage returns the text sent along with the report of a
+// run reaching the warning threshold of a notification
+func getThresholdExceededMessage(cleanerName string, count int, threshold, previousCount int32, runID string) string {
+	message := fmt.Sprintf("Threshold exceeded: k8s-cleaner '%s' matched %d resource(s)", cleanerName, count)
+	if runID != "" {
+		message += fmt.Sprintf(" (run ID: %s)", runID)
+	}
+	return message + fmt.Sprintf(", reaching the warning threshold of %d. Previous run matched %d resource(s).",
+		threshold, previousCount)
+}
+
+// getResolvedMessage returns the text sent along with the report of a resolved run
+func getResolvedMessage(cleanerName string, previousCount int32, runID string) string {
+	message := fmt.Sprintf("Resolved: k8s-cleaner '%s' no longer matches any resource", cleanerName)
+	if runID != "" {
+		message += fmt.Sprintf(" (run ID: %s)", runID)
+	}
+	return message + fmt.Sprintf(". Previous run matched %d resource(s).", previousCount)
+}
+
+// recordMatchCount stores in the Cleaner status the number of resources processed
+// by a successful run. Status is only updated when the number changes.
+func recordMatchCount(ctx context.Context, cleaner *appsv1alpha1.Cleaner, count int) error {
+	if int32(count) == getLastMatchCount(cleaner) {
+		return nil
+	}
+
+	return updateCleanerStatus(ctx, cleaner.Name, func(current *appsv1alpha1.Cleaner) {
+		matchCount := int32(count)
+		current.Status.LastMatchCount = &matchCount
+	})
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+// getResolvedCleaner returns a Cleaner with two Slack notifications, only the
+// first one with NotifyOnResolved set, whose previous run matched lastMatchCount
+// resources
+func getResolvedCleaner(lastMatchCount *int32) (*appsv1alpha1.Cleaner, *fakeSlackClient) {
+	ref := createNotificationSecret(map[string][]byte{
+		libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+		libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+	})
+	fake := &fakeSlackClient{}
+	DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+		return fake
+	}))
+
+	cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+	cleaner.Spec.Notifications[0].NotifyOnResolved = true
+	cleaner.Spec.Notifications = append(cleaner.Spec.Notifications, appsv1alpha1.Notification{
+		Name:            randomString(),
+		Type:            appsv1alpha1.NotificationTypeSlack,
+		NotificationRef: ref,
+	})
+	cleaner.Status.LastMatchCount = lastMatchCount
+	return cleaner, fake
+}
+
+var _ = Describe("Resolved notifications", func() {
+	It("sendNotifications sends resolved notification when previous run matched resources", func() {
+		cleaner, fake := getResolvedCleaner(ptr.To(int32(3)))
+		runID := randomString()
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, runID, logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(2))
+		Expect(fake.values[0].Get("text")).To(HavePrefix("Resolved: k8s-cleaner '" + cleaner.Name +
+			"' no longer matches any resource (run ID: " + runID + "). Previous run matched 3 resource(s)."))
+		Expect(fake.values[1].Get("text")).To(HavePrefix("k8s-cleaner '" + cleaner.Name + "' performed Delete on 0 resources"))
+	})
+
+	It("sendNotifications does not send resolved notification when previous run matched nothing", func() {
+		for _, lastMatchCount := range []*int32{nil, ptr.To(int32(0))} {
+			cleaner, fake := getResolvedCleaner(lastMatchCount)
+
+			Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+
+			Expect(fake.values).To(HaveLen(2))
+			for i := range fake.values {
+				Expect(fake.values[i].Get("text")).To(HavePrefix("k8s-cleaner '" + cleaner.Name + "' performed"))
+			}
+		}
+	})
+
+	It("sendNotifications does not send resolved notification when resources are matched", func() {
+		cleaner, fake := getResolvedCleaner(ptr.To(int32(3)))
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(2))
+		Expect(fake.values[0].Get("text")).To(HavePrefix("k8s-cleaner '" + cleaner.Name + "' performed Delete on 1 resource"))
+	})
+
+	It("sendNotifications sends resolved notification right away for digest notifications", func() {
+		cleaner, fake := getResolvedCleaner(ptr.To(int32(1)))
+		cleaner.Spec.Notifications = cleaner.Spec.Notifications[:1]
+		cleaner.Spec.Notifications[0].Digest = &appsv1alpha1.DigestOptions{
+			Interval: metav1.Duration{Duration: time.Hour},
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(HavePrefix("Resolved: k8s-cleaner '" + cleaner.Name + "'"))
+	})
+
+	It("sendNotifications sends threshold exceeded notification when the run reaches the warning threshold", func() {
+		cleaner, fake := getResolvedCleaner(ptr.To(int32(1)))
+		cleaner.Spec.Notifications[0].NotifyOnResolved = false
+		cleaner.Spec.Notifications[0].WarningThreshold = ptr.To(int32(3))
+		cleaner.Spec.Action = appsv1alpha1.ActionScan
+		runID := randomString()
+		resources := []executor.ResourceResult{
+			getResourceResult("ConfigMap", randomString(), randomString()),
+			getResourceResult("ConfigMap", randomString(), randomString()),
+			getResourceResult("ConfigMap", randomString(), randomString()),
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, runID, logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(2))
+		Expect(fake.values[0].Get("text")).To(HavePrefix("Threshold exceeded: k8s-cleaner '" + cleaner.Name +
+			"' matched 3 resource(s) (run ID: " + runID + "), reaching the warning threshold of 3. " +
+			"Previous run matched 1 resource(s)."))
+		Expect(fake.values[0].Get("attachments")).To(ContainSubstring(resources[0].Resource.GetName()))
+		Expect(fake.values[1].Get("text")).To(HavePrefix("k8s-cleaner '" + cleaner.Name + "' performed Scan on 3 resources"))
+	})
+
+	It("sendNotifications does not send threshold exceeded notification when the run does not cross the threshold", func() {
+		// Previous run matched lastMatchCount resources, this run matches count
+		for _, run := range []struct{ lastMatchCount, count int32 }{
+			{lastMatchCount: 0, count: 1},
+			{lastMatchCount: 2, count: 4},
+			{lastMatchCount: 3, count: 2},
+		} {
+			cleaner, fake := getResolvedCleaner(ptr.To(run.lastMatchCount))
+			cleaner.Spec.Notifications = cleaner.Spec.Notifications[:1]
+			cleaner.Spec.Notifications[0].WarningThreshold = ptr.To(int32(2))
+			resources := make([]executor.ResourceResult, run.count)
+			for i := range resources {
+				resources[i] = getResourceResult("ConfigMap", randomString(), randomString())
+			}
+
+			Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+			Expect(fake.values).To(HaveLen(1))
+			Expect(fake.values[0].Get("text")).To(HavePrefix("k8s-cleaner '"+cleaner.Name+"' performed"),
+				"previous run matched %d resource(s), run matched %d", run.lastMatchCount, run.count)
+		}
+	})
+
+	It("sendNotifications sends threshold exceeded notification right away for digest notifications", func() {
+		cleaner, fake := getResolvedCleaner(nil)
+		cleaner.Spec.Notifications = cleaner.Spec.Notifications[:1]
+		cleaner.Spec.Notifications[0].WarningThreshold = ptr.To(int32(1))
+		cleaner.Spec.Notifications[0].Digest = &appsv1alpha1.DigestOptions{
+			Interval: metav1.Duration{Duration: time.Hour},
+		}
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(HavePrefix("Threshold exceeded: k8s-cleaner '" + cleaner.Name + "'"))
+	})
+
+	It("recordMatchCount stores the number of resources in the Cleaner status", func() {
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCleanerReport, nil)
+		cleaner.Spec.Schedule = "0 * * * *"
+		cleaner.Spec.ResourcePolicySet.ResourceSelectors = []appsv1alpha1.ResourceSelector{
+			{Kind: "ConfigMap", Version: "v1"},
+		}
+		Expect(k8sClient.Create(context.TODO(), cleaner)).To(Succeed())
+		Expect(waitForObject(context.TODO(), k8sClient, cleaner)).To(Succeed())
+
+		Expect(executor.RecordMatchCount(context.TODO(), cleaner, 4)).To(Succeed())
+
+		current := &appsv1alpha1.Cleaner{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, current)).To(Succeed())
+		Expect(current.Status.LastMatchCount).To(Equal(ptr.To(int32(4))))
+
+		Expect(executor.RecordMatchCount(context.TODO(), current, 0)).To(Succeed())
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, current)).To(Succeed())
+		Expect(current.Status.LastMatchCount).To(Equal(ptr.To(int32(0))))
+	})
+})
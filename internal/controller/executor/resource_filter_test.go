@@ -0,0 +1,142 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+var _ = Describe("Resource filter", func() {
+	var (
+		namespace   string
+		deployment  executor.ResourceResult
+		statefulSet executor.ResourceResult
+		configMap   executor.ResourceResult
+		other       executor.ResourceResult
+		cluster     executor.ResourceResult
+		resources   []executor.ResourceResult
+	)
+
+	BeforeEach(func() {
+		namespace = randomString()
+		deployment = getResourceResult("Deployment", namespace, randomString())
+		statefulSet = getResourceResult("StatefulSet", namespace, randomString())
+		configMap = getResourceResult("ConfigMap", namespace, randomString())
+		other = getResourceResult("Deployment", randomString(), randomString())
+		cluster = getResourceResult("ClusterRole", "", randomString())
+		resources = []executor.ResourceResult{deployment, statefulSet, configMap, other, cluster}
+	})
+
+	// sendFiltered sends resources to a File notification with filter, and
+	// returns the names of the resources in the written report
+	sendFiltered := func(filter *appsv1alpha1.ResourceFilter) []string {
+		dir := GinkgoT().TempDir()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeFile, nil)
+		cleaner.Spec.Notifications = []appsv1alpha1.Notification{
+			{
+				Name:           randomString(),
+				Type:           appsv1alpha1.NotificationTypeFile,
+				File:           &appsv1alpha1.FileOptions{Path: dir},
+				ResourceFilter: filter,
+			},
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		files := listFiles(dir)
+		Expect(files).To(HaveLen(1))
+		data, err := os.ReadFile(filepath.Join(dir, files[0]))
+		Expect(err).To(BeNil())
+		reportSpec := &appsv1alpha1.ReportSpec{}
+		Expect(json.Unmarshal(data, reportSpec)).To(Succeed())
+		Expect(reportSpec.Summary.Total).To(Equal(int32(len(reportSpec.ResourceInfo))))
+
+		names := []string{}
+		for i := range reportSpec.ResourceInfo {
+			names = append(names, reportSpec.ResourceInfo[i].Resource.Name)
+		}
+		return names
+	}
+
+	It("sendNotifications keeps every resource with an empty filter", func() {
+		Expect(sendFiltered(&appsv1alpha1.ResourceFilter{})).To(HaveLen(len(resources)))
+	})
+
+	It("sendNotifications only keeps resources matching include", func() {
+		names := sendFiltered(&appsv1alpha1.ResourceFilter{
+			Include: &appsv1alpha1.ResourceFilterMatch{Kind: "Deployment|StatefulSet"},
+		})
+		Expect(names).To(ConsistOf(deployment.Resource.GetName(), statefulSet.Resource.GetName(),
+			other.Resource.GetName()))
+
+		// Expressions match the whole value
+		names = sendFiltered(&appsv1alpha1.ResourceFilter{
+			Include: &appsv1alpha1.ResourceFilterMatch{Kind: "Deploy"},
+		})
+		Expect(names).To(BeEmpty())
+
+		names = sendFiltered(&appsv1alpha1.ResourceFilter{
+			Include: &appsv1alpha1.ResourceFilterMatch{Namespace: namespace},
+		})
+		Expect(names).To(ConsistOf(deployment.Resource.GetName(), statefulSet.Resource.GetName(),
+			configMap.Resource.GetName()))
+	})
+
+	It("sendNotifications removes resources matching exclude", func() {
+		names := sendFiltered(&appsv1alpha1.ResourceFilter{
+			Exclude: &appsv1alpha1.ResourceFilterMatch{Kind: "ConfigMap|ClusterRole"},
+		})
+		Expect(names).To(ConsistOf(deployment.Resource.GetName(), statefulSet.Resource.GetName(),
+			other.Resource.GetName()))
+
+		// Cluster-scoped resources have an empty namespace
+		names = sendFiltered(&appsv1alpha1.ResourceFilter{
+			Exclude: &appsv1alpha1.ResourceFilterMatch{Namespace: ".+"},
+		})
+		Expect(names).To(ConsistOf(cluster.Resource.GetName()))
+	})
+
+	It("sendNotifications applies exclude to the resources matching include", func() {
+		names := sendFiltered(&appsv1alpha1.ResourceFilter{
+			Include: &appsv1alpha1.ResourceFilterMatch{Kind: "Deployment|StatefulSet"},
+			Exclude: &appsv1alpha1.ResourceFilterMatch{Kind: "Deployment", Namespace: namespace},
+		})
+		Expect(names).To(ConsistOf(statefulSet.Resource.GetName(), other.Resource.GetName()))
+	})
+
+	It("sendNotifications fails the notification when an expression is invalid", func() {
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeFile, nil)
+		cleaner.Spec.Notifications[0].File = &appsv1alpha1.FileOptions{Path: GinkgoT().TempDir()}
+		cleaner.Spec.Notifications[0].ResourceFilter = &appsv1alpha1.ResourceFilter{
+			Exclude: &appsv1alpha1.ResourceFilterMatch{Namespace: "("},
+		}
+
+		err := executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("invalid resource filter exclude: namespace"))
+	})
+})
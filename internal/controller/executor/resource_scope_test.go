@@ -0,0 +1,134 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+var _ = Describe("Resource scope", func() {
+	var (
+		namespaced executor.ResourceResult
+		cluster    executor.ResourceResult
+		resources  []executor.ResourceResult
+	)
+
+	BeforeEach(func() {
+		namespaced = getResourceResult("ConfigMap", randomString(), randomString())
+		cluster = getResourceResult("ClusterRole", "", randomString())
+		resources = []executor.ResourceResult{namespaced, cluster}
+	})
+
+	// getFileReport returns the report written by the File notification in dir
+	getFileReport := func(dir string) *appsv1alpha1.ReportSpec {
+		files := listFiles(dir)
+		Expect(files).To(HaveLen(1))
+		data, err := os.ReadFile(filepath.Join(dir, files[0]))
+		Expect(err).To(BeNil())
+		reportSpec := &appsv1alpha1.ReportSpec{}
+		Expect(json.Unmarshal(data, reportSpec)).To(Succeed())
+		return reportSpec
+	}
+
+	getFileNotification := func(dir string, scope appsv1alpha1.ResourceScope) appsv1alpha1.Notification {
+		return appsv1alpha1.Notification{
+			Name:          randomString(),
+			Type:          appsv1alpha1.NotificationTypeFile,
+			File:          &appsv1alpha1.FileOptions{Path: dir},
+			ResourceScope: scope,
+		}
+	}
+
+	It("sendNotifications only includes namespaced resources with Namespaced scope", func() {
+		dir := GinkgoT().TempDir()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeFile, nil)
+		cleaner.Spec.Notifications = []appsv1alpha1.Notification{
+			getFileNotification(dir, appsv1alpha1.ResourceScopeNamespaced),
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		reportSpec := getFileReport(dir)
+		Expect(reportSpec.ResourceInfo).To(HaveLen(1))
+		Expect(reportSpec.ResourceInfo[0].Resource.Name).To(Equal(namespaced.Resource.GetName()))
+		Expect(reportSpec.Summary.Total).To(Equal(int32(1)))
+	})
+
+	It("sendNotifications only includes cluster-scoped resources with Cluster scope", func() {
+		dir := GinkgoT().TempDir()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeFile, nil)
+		cleaner.Spec.Notifications = []appsv1alpha1.Notification{
+			getFileNotification(dir, appsv1alpha1.ResourceScopeCluster),
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		reportSpec := getFileReport(dir)
+		Expect(reportSpec.ResourceInfo).To(HaveLen(1))
+		Expect(reportSpec.ResourceInfo[0].Resource.Name).To(Equal(cluster.Resource.GetName()))
+		Expect(reportSpec.Summary.ByNamespace).To(BeEmpty())
+	})
+
+	It("sendNotifications tailors the report of each notification", func() {
+		namespacedDir := GinkgoT().TempDir()
+		allDir := GinkgoT().TempDir()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeFile, nil)
+		cleaner.Spec.Notifications = []appsv1alpha1.Notification{
+			getFileNotification(namespacedDir, appsv1alpha1.ResourceScopeNamespaced),
+			getFileNotification(allDir, ""),
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(getFileReport(namespacedDir).ResourceInfo).To(HaveLen(1))
+		names := []string{}
+		for _, info := range getFileReport(allDir).ResourceInfo {
+			names = append(names, info.Resource.Name)
+		}
+		Expect(names).To(ConsistOf(namespaced.Resource.GetName(), cluster.Resource.GetName()))
+	})
+
+	It("sendNotifications counts resources in scope in the message", func() {
+		fake := &fakeTeamsClient{}
+		DeferCleanup(executor.SetTeamsClientFactory(func() executor.TeamsClient {
+			return fake
+		}))
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeTeams, createNotificationSecret(
+			map[string][]byte{libsveltosv1alpha1.TeamsWebhookURL: []byte("https://example.webhook.office.com/" + randomString())}))
+		cleaner.Spec.Notifications[0].ResourceScope = appsv1alpha1.ResourceScopeCluster
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.messages).To(HaveLen(1))
+		payload, err := json.Marshal(fake.messages[0])
+		Expect(err).To(BeNil())
+		Expect(string(payload)).To(ContainSubstring("performed Delete on 1 resource"))
+		Expect(string(payload)).ToNot(ContainSubstring(namespaced.Resource.GetName()))
+	})
+})
@@ -0,0 +1,147 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+// channelKey is the context key of the channel a notification is routed to
+type channelKey struct{}
+
+// withRoutedChannel returns a copy of ctx routing notifications to channel
+func withRoutedChannel(ctx context.Context, channel string) context.Context {
+	return context.WithValue(ctx, channelKey{}, channel)
+}
+
+// getRoutedChannel returns the channel set in ctx by withRoutedChannel, an empty
+// string if notification is sent to the channel of its credentials
+func getRoutedChannel(ctx context.Context) string {
+	channel, _ := ctx.Value(channelKey{}).(string)
+	return channel
+}
+
+// isRoutingSupported returns true if notifications of notificationType can be
+// routed to a templated channel
+func isRoutingSupported(notificationType appsv1alpha1.NotificationType) bool {
+	switch notificationType {
+	case appsv1alpha1.NotificationTypeSlack, appsv1alpha1.NotificationTypeDiscord,
+		appsv1alpha1.NotificationTypeWebex:
+		return true
+	default:
+		return false
+	}
+}
+
+// channelTemplateData is the data the channel template is evaluated against
+type channelTemplateData struct {
+	Kind        string
+	Namespace   string
+	Name        string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// channelGroup contains the resources routed to a channel. An empty channel is
+// the channel of the notification credentials.
+type channelGroup struct {
+	channel    string
+	resources  []ResourceResult
+	reportSpec *appsv1alpha1.ReportSpec
+}
+
+// groupByChannel evaluates channelTemplate against each resource and groups
+// resources, and the corresponding entries of reportSpec, by resulting channel.
+// Groups are sorted by channel, so the default channel comes first.
+func groupByChannel(channelTemplate string, reportSpec *appsv1alpha1.ReportSpec,
+	resources []ResourceResult) ([]*channelGroup, error) {
+
+	tmpl, err := template.New("channel").Option("missingkey=zero").Parse(channelTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid channel template: %w", err)
+	}
+
+	channels := make(map[string]string, len(resources))
+	groups := make(map[string]*channelGroup)
+	for i := range resources {
+		r := resources[i].Resource
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, channelTemplateData{
+			Kind:        r.GetKind(),
+			Namespace:   r.GetNamespace(),
+			Name:        r.GetName(),
+			Labels:      r.GetLabels(),
+			Annotations: r.GetAnnotations(),
+		}); err != nil {
+			return nil, fmt.Errorf("failed to evaluate channel template for %s %s/%s: %w",
+				r.GetKind(), r.GetNamespace(), r.GetName(), err)
+		}
+		channel := strings.TrimSpace(buf.String())
+		channels[getResourceKey(r.GetAPIVersion(), r.GetKind(), r.GetNamespace(), r.GetName())] = channel
+		if groups[channel] == nil {
+			groups[channel] = &channelGroup{
+				channel: channel,
+				reportSpec: &appsv1alpha1.ReportSpec{
+					SchemaVersion: reportSpec.SchemaVersion,
+					Action:        reportSpec.Action,
+					RunID:         reportSpec.RunID,
+					Reason:        reportSpec.Reason,
+					Error:         reportSpec.Error,
+					CleanerSpec:   reportSpec.CleanerSpec,
+				},
+			}
+		}
+		groups[channel].resources = append(groups[channel].resources, resources[i])
+	}
+
+	// Keep the order of the report, where failed resources come first
+	for i := range reportSpec.ResourceInfo {
+		channel, ok := channels[getObjectReferenceKey(&reportSpec.ResourceInfo[i].Resource)]
+		if !ok {
+			continue
+		}
+		group := groups[channel]
+		group.reportSpec.ResourceInfo = append(group.reportSpec.ResourceInfo, reportSpec.ResourceInfo[i])
+	}
+
+	result := make([]*channelGroup, 0, len(groups))
+	for _, group := range groups {
+		group.reportSpec.Summary = getReportSummary(group.reportSpec.ResourceInfo)
+		result = append(result, group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].channel < result[j].channel
+	})
+	return result, nil
+}
+
+func getResourceKey(apiVersion, kind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s/%s", apiVersion, kind, namespace, name)
+}
+
+func getObjectReferenceKey(ref *corev1.ObjectReference) string {
+	return getResourceKey(ref.APIVersion, ref.Kind, ref.Namespace, ref.Name)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+var _ = Describe("Channel routing", func() {
+	var fake *fakeSlackClient
+	var defaultChannelID string
+	var cleaner *appsv1alpha1.Cleaner
+
+	BeforeEach(func() {
+		fake = &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		defaultChannelID = randomSlackChannelID()
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(defaultChannelID),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+		cleaner = getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+	})
+
+	It("sendNotifications sends one message per channel resulting from the channel template", func() {
+		cleaner.Spec.Notifications[0].ChannelTemplate = "{{ with .Labels.team }}team-{{ . }}{{ end }}"
+
+		frontend := getResourceResult("ConfigMap", randomString(), randomString())
+		frontend.Resource.SetLabels(map[string]string{"team": "frontend"})
+		backend1 := getResourceResult("ConfigMap", randomString(), randomString())
+		backend1.Resource.SetLabels(map[string]string{"team": "backend"})
+		backend2 := getResourceResult("Secret", randomString(), randomString())
+		backend2.Resource.SetLabels(map[string]string{"team": "backend"})
+		unlabeled := getResourceResult("ConfigMap", randomString(), randomString())
+		backendID := randomSlackChannelID()
+		frontendID := randomSlackChannelID()
+		fake.channels = []slack.Channel{
+			getSlackChannel(backendID, "team-backend"),
+			getSlackChannel(frontendID, "team-frontend"),
+		}
+
+		Expect(executor.SendNotifications(context.TODO(),
+			[]executor.ResourceResult{frontend, backend1, unlabeled, backend2},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		// Template yielding an empty string routes to the default channel
+		Expect(fake.channelIDs).To(Equal([]string{defaultChannelID, backendID, frontendID}))
+		Expect(fake.values[0].Get("text")).To(ContainSubstring(unlabeled.Resource.GetName()))
+		Expect(fake.values[0].Get("text")).To(ContainSubstring("*Resources:* 1 (ConfigMap: 1)"))
+		Expect(fake.values[1].Get("text")).To(ContainSubstring(backend1.Resource.GetName()))
+		Expect(fake.values[1].Get("text")).To(ContainSubstring(backend2.Resource.GetName()))
+		Expect(fake.values[1].Get("text")).ToNot(ContainSubstring(frontend.Resource.GetName()))
+		Expect(fake.values[2].Get("text")).To(ContainSubstring(frontend.Resource.GetName()))
+	})
+
+	It("sendNotifications routes by namespace", func() {
+		cleaner.Spec.Notifications[0].ChannelTemplate = "ns-{{ .Namespace }}"
+		namespace := randomString()
+		channelID := randomSlackChannelID()
+		fake.channels = []slack.Channel{getSlackChannel(channelID, "ns-"+namespace)}
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{
+			getResourceResult("ConfigMap", namespace, randomString()),
+			getResourceResult("Secret", namespace, randomString()),
+		}, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.channelIDs).To(Equal([]string{channelID}))
+	})
+
+	It("sendNotifications fails on invalid channel template", func() {
+		cleaner.Spec.Notifications[0].ChannelTemplate = "team-{{ .Labels.team"
+
+		err := executor.SendNotifications(context.TODO(), []executor.ResourceResult{
+			getResourceResult("ConfigMap", randomString(), randomString()),
+		}, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("invalid channel template"))
+		Expect(fake.channelIDs).To(BeEmpty())
+	})
+})
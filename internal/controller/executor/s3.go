@@ -0,0 +1,221 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+const s3Service = "s3"
+
+var s3ContentTypes = map[appsv1alpha1.ReportFormat]string{
+	appsv1alpha1.ReportFormatJSON: "application/json",
+	appsv1alpha1.ReportFormatCSV:  "text/csv",
+	appsv1alpha1.ReportFormatYAML: "application/yaml",
+}
+
+type s3Info struct {
+	bucket   string
+	region   string
+	prefix   string
+	endpoint string
+	// credentials is nil when the secret does not contain AWS credentials
+	credentials *awsCredentials
+}
+
+func init() {
+	registerNotifier(appsv1alpha1.NotificationTypeS3, notifierFunc(
+		func(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+			_ []ResourceResult, _ string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+			return sendS3Notification(ctx, cleaner, reportSpec, notification, logger)
+		}))
+}
+
+func sendS3Notification(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+	info, err := getS3Info(ctx, notification)
+	if err != nil {
+		return err
+	}
+
+	options := notification.S3
+	if options == nil {
+		options = &appsv1alpha1.S3Options{}
+	}
+	format := options.Format
+	if format == "" {
+		format = appsv1alpha1.ReportFormatJSON
+	}
+
+	data, err := renderFileReport(reportSpec, format)
+	if err != nil {
+		logger.Error(err, "failed to render report")
+		return err
+	}
+
+	key := path.Join(info.prefix,
+		getReportFileName(cleaner.Name, reportSpec.RunID, time.Now(), getReportFileExtension(format)))
+	contentEncoding := ""
+	if options.Gzip {
+		if data, err = gzipReport(data); err != nil {
+			logger.Error(err, "failed to compress report")
+			return err
+		}
+		key += ".gz"
+		contentEncoding = "gzip"
+	}
+
+	l := logger.WithValues(logKeyBucket, info.bucket, logKeyKey, key)
+	l.V(logs.LogInfo).Info("upload report to s3")
+
+	headers := map[string]string{"Content-Type": s3ContentTypes[format]}
+	if contentEncoding != "" {
+		headers["Content-Encoding"] = contentEncoding
+	}
+	if options.ServerSideEncryption != "" {
+		headers["X-Amz-Server-Side-Encryption"] = string(options.ServerSideEncryption)
+		if options.ServerSideEncryption == appsv1alpha1.S3ServerSideEncryptionKMS && options.KMSKeyID != "" {
+			headers["X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"] = options.KMSKeyID
+		}
+	}
+	if err := putS3Object(ctx, info, key, data, headers); err != nil {
+		l.Error(err, logMsgSendFailed)
+		return err
+	}
+
+	l.V(logs.LogInfo).Info("report uploaded to s3")
+	return nil
+}
+
+// putS3Object uploads data to the object with key, setting headers on the
+// request. Requests are signed with the credentials of info or, when there
+// are none, with IRSA credentials.
+func putS3Object(ctx context.Context, info *s3Info, key string, data []byte, headers map[string]string) error {
+	credentials := info.credentials
+	if credentials == nil {
+		var err error
+		if credentials, err = getIRSACredentials(ctx, info.region); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, getS3ObjectURL(info, key), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	signAWSRequest(req, sha256Hex(data), credentials, info.region, s3Service, time.Now())
+
+	resp, err := newNotificationHTTPClient(awsRequestTimeout).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxAWSResponseBody))
+		return fmt.Errorf("s3 returned %s: %s", resp.Status, getAWSError(body))
+	}
+	return nil
+}
+
+// getS3ObjectURL returns the URL of the object with key. Buckets are addressed
+// virtual-hosted style on AWS and path-style on custom endpoints.
+func getS3ObjectURL(info *s3Info, key string) string {
+	escapedKey := (&url.URL{Path: key}).EscapedPath()
+	if info.endpoint != "" {
+		return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(info.endpoint, "/"), url.PathEscape(info.bucket), escapedKey)
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", info.bucket, info.region, escapedKey)
+}
+
+func gzipReport(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func getS3Info(ctx context.Context, notification *appsv1alpha1.Notification) (*s3Info, error) {
+	secret, err := getSecret(ctx, notification)
+	if err != nil {
+		return nil, err
+	}
+	return getS3InfoFromSecret(secret)
+}
+
+// getS3InfoFromSecret returns the bucket, region, prefix, endpoint and AWS
+// credentials contained in secret
+func getS3InfoFromSecret(secret *corev1.Secret) (*s3Info, error) {
+	bucket, ok := secret.Data[appsv1alpha1.S3Bucket]
+	if !ok || len(bucket) == 0 {
+		return nil, fmt.Errorf("secret does not contain s3 bucket")
+	}
+
+	region, ok := secret.Data[appsv1alpha1.S3Region]
+	if !ok || len(region) == 0 {
+		return nil, fmt.Errorf("secret does not contain s3 region")
+	}
+
+	info := &s3Info{
+		bucket:   string(bucket),
+		region:   string(region),
+		prefix:   strings.Trim(string(secret.Data[appsv1alpha1.S3Prefix]), "/"),
+		endpoint: string(secret.Data[appsv1alpha1.S3Endpoint]),
+	}
+
+	accessKeyID := secret.Data[appsv1alpha1.AWSAccessKeyID]
+	if len(accessKeyID) == 0 {
+		return info, nil
+	}
+	secretAccessKey, ok := secret.Data[appsv1alpha1.AWSSecretAccessKey]
+	if !ok || len(secretAccessKey) == 0 {
+		return nil, fmt.Errorf("secret contains AWS access key ID but not secret access key")
+	}
+	info.credentials = &awsCredentials{
+		accessKeyID:     string(accessKeyID),
+		secretAccessKey: string(secretAccessKey),
+		sessionToken:    string(secret.Data[appsv1alpha1.AWSSessionToken]),
+	}
+
+	return info, nil
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+const (
+	// slackConversationsPageSize is the number of channels requested per page
+	slackConversationsPageSize = 1000
+
+	// slackMaxConversationPages is the maximum number of pages of channels
+	// listed to resolve a channel name
+	slackMaxConversationPages = 20
+)
+
+// slackChannelIDRegexp matches Slack channel IDs: public (C), private (G) and
+// direct message (D) channels
+var slackChannelIDRegexp = regexp.MustCompile(`^[CGD][A-Z0-9]{8,}$`)
+
+// getSlackChannelID returns the ID of channel. Channel is used as is when it is
+// a Slack channel ID. Otherwise it is a channel name, with or without leading
+// '#', resolved using the public channels, and the private channels the bot is
+// a member of. Resolved names are cached for channelIDCacheTTL.
+func getSlackChannelID(ctx context.Context, api slackClient, token, channel string, now time.Time) (string, error) {
+	if slackChannelIDRegexp.MatchString(channel) {
+		return channel, nil
+	}
+
+	name := strings.TrimPrefix(channel, "#")
+	if channelID, ok := getCachedChannelID(appsv1alpha1.NotificationTypeSlack, token, name, now); ok {
+		return channelID, nil
+	}
+
+	found, err := findSlackChannel(ctx, api, name)
+	if err != nil {
+		return "", err
+	}
+	if found == nil {
+		return "", fmt.Errorf("slack channel #%s not found: private channels must have the bot as member", name)
+	}
+	if !found.IsMember {
+		return "", fmt.Errorf("slack bot is not a member of channel #%s: invite it with /invite", name)
+	}
+
+	cacheChannelID(appsv1alpha1.NotificationTypeSlack, token, name, found.ID, now)
+	return found.ID, nil
+}
+
+// findSlackChannel returns the channel named name, following pagination. Nil is
+// returned when there is none.
+func findSlackChannel(ctx context.Context, api slackClient, name string) (*slack.Channel, error) {
+	params := &slack.GetConversationsParameters{
+		ExcludeArchived: true,
+		Limit:           slackConversationsPageSize,
+		Types:           []string{"public_channel", "private_channel"},
+	}
+	for page := 0; page < slackMaxConversationPages; page++ {
+		channels, cursor, err := api.GetConversationsContext(ctx, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list slack channels: %w", err)
+		}
+		for i := range channels {
+			if channels[i].Name == name {
+				return &channels[i], nil
+			}
+		}
+		if cursor == "" {
+			return nil, nil
+		}
+		params.Cursor = cursor
+	}
+	return nil, fmt.Errorf("slack channels exceed %d pages", slackMaxConversationPages)
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+// sendToSlackChannel sends a notification of cleaner to channel and returns
+// the fake client messages are posted to, and the error
+func sendToSlackChannel(channel string, channels ...slack.Channel) (*fakeSlackClient, error) {
+	ref := createNotificationSecret(map[string][]byte{
+		libsveltosv1alpha1.SlackChannelID: []byte(channel),
+		libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+	})
+	fake := &fakeSlackClient{channels: channels}
+	DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+		return fake
+	}))
+
+	cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+	err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+	if err != nil {
+		return fake, err
+	}
+	// Following run uses the cached channel ID
+	return fake, executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+}
+
+var _ = Describe("Slack channel lookup", func() {
+	It("sendNotifications uses channel IDs as is", func() {
+		channelID := randomSlackChannelID()
+
+		fake, err := sendToSlackChannel(channelID)
+		Expect(err).To(BeNil())
+		Expect(fake.conversationCalls).To(BeZero())
+		Expect(fake.channelIDs).To(Equal([]string{channelID, channelID}))
+	})
+
+	It("sendNotifications resolves channel names once", func() {
+		channelID := randomSlackChannelID()
+		name := randomString()
+
+		for _, channel := range []string{name, "#" + name} {
+			fake, err := sendToSlackChannel(channel, getSlackChannel(randomSlackChannelID(), randomString()),
+				getSlackChannel(channelID, name))
+			Expect(err).To(BeNil())
+			Expect(fake.conversationCalls).To(Equal(1))
+			Expect(fake.channelIDs).To(Equal([]string{channelID, channelID}))
+		}
+	})
+
+	It("sendNotifications resolves private channels the bot is a member of", func() {
+		channel := getSlackChannel("G"+randomSlackChannelID()[1:], randomString())
+		channel.IsPrivate = true
+
+		fake, err := sendToSlackChannel(channel.Name, channel)
+		Expect(err).To(BeNil())
+		Expect(fake.channelIDs).To(Equal([]string{channel.ID, channel.ID}))
+	})
+
+	It("sendNotifications fails when the channel is not found", func() {
+		name := randomString()
+
+		fake, err := sendToSlackChannel("#"+name, getSlackChannel(randomSlackChannelID(), randomString()))
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("slack channel #" + name + " not found"))
+		Expect(fake.channelIDs).To(BeEmpty())
+	})
+
+	It("sendNotifications fails when the bot is not a member of the channel", func() {
+		channel := getSlackChannel(randomSlackChannelID(), randomString())
+		channel.IsMember = false
+
+		fake, err := sendToSlackChannel(channel.Name, channel)
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("slack bot is not a member of channel #" + channel.Name))
+		Expect(fake.channelIDs).To(BeEmpty())
+	})
+})
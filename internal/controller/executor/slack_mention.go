@@ -0,0 +1,63 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"strings"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+// getSlackMentions returns the mentions to prepend to the Slack message text of
+// reportSpec, or an empty string when notification does not mention anybody or
+// the report does not meet the mention conditions
+func getSlackMentions(reportSpec *appsv1alpha1.ReportSpec, notification *appsv1alpha1.Notification) string {
+	if notification.Slack == nil || notification.Slack.Mentions == nil {
+		return ""
+	}
+
+	mentions := notification.Slack.Mentions
+	minResources := int(mentions.MinResources)
+	if minResources < 1 {
+		minResources = 1
+	}
+	if len(reportSpec.ResourceInfo) < minResources {
+		return ""
+	}
+
+	if len(mentions.Actions) > 0 {
+		matched := false
+		for i := range mentions.Actions {
+			if mentions.Actions[i] == reportSpec.Action {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return ""
+		}
+	}
+
+	tags := make([]string, 0, len(mentions.GroupIDs)+len(mentions.UserIDs))
+	for i := range mentions.GroupIDs {
+		tags = append(tags, "<!subteam^"+mentions.GroupIDs[i]+">")
+	}
+	for i := range mentions.UserIDs {
+		tags = append(tags, "<@"+mentions.UserIDs[i]+">")
+	}
+	return strings.Join(tags, " ")
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+// getMentionCleaner returns a Cleaner with a Slack notification mentioning a
+// user group and a user
+func getMentionCleaner(mentions *appsv1alpha1.SlackMentions) (*appsv1alpha1.Cleaner, *fakeSlackClient) {
+	ref, fake := createSlackSecret()
+	cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+	mentions.GroupIDs = []string{"SAZ94GDB8"}
+	mentions.UserIDs = []string{"U024BE7LH"}
+	cleaner.Spec.Notifications[0].Slack = &appsv1alpha1.SlackOptions{Mentions: mentions}
+	return cleaner, fake
+}
+
+var _ = Describe("Slack mentions", func() {
+	It("sendNotifications mentions users and groups when report meets the conditions", func() {
+		cleaner, fake := getMentionCleaner(&appsv1alpha1.SlackMentions{
+			MinResources: 2,
+			Actions:      []appsv1alpha1.Action{appsv1alpha1.ActionDelete},
+		})
+		resources := []executor.ResourceResult{
+			getResourceResult("ConfigMap", randomString(), randomString()),
+			getResourceResult("ConfigMap", randomString(), randomString()),
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(HavePrefix("<!subteam^SAZ94GDB8> <@U024BE7LH> k8s-cleaner '" +
+			cleaner.Name + "' performed Delete on 2 resources"))
+	})
+
+	It("sendNotifications does not mention when report has fewer resources than MinResources", func() {
+		cleaner, fake := getMentionCleaner(&appsv1alpha1.SlackMentions{MinResources: 2})
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(HavePrefix("k8s-cleaner '" + cleaner.Name + "'"))
+	})
+
+	It("sendNotifications does not mention when no resource is matched", func() {
+		cleaner, fake := getMentionCleaner(&appsv1alpha1.SlackMentions{})
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).ToNot(ContainSubstring("<@U024BE7LH>"))
+	})
+
+	It("sendNotifications does not mention when action is not listed", func() {
+		cleaner, fake := getMentionCleaner(&appsv1alpha1.SlackMentions{
+			Actions: []appsv1alpha1.Action{appsv1alpha1.ActionDelete},
+		})
+		cleaner.Spec.Action = appsv1alpha1.ActionScan
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(HavePrefix("k8s-cleaner '" + cleaner.Name + "' performed Scan"))
+	})
+})
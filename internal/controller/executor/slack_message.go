@@ -0,0 +1,82 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/slack-go/slack"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+// isSlackMessageRecorded returns true if the messages posted by notification
+// are stored in the Cleaner status
+func isSlackMessageRecorded(notification *appsv1alpha1.Notification) bool {
+	return notification.Type == appsv1alpha1.NotificationTypeSlack &&
+		notification.Slack != nil && notification.Slack.RecordMessage
+}
+
+// newSlackMessage returns the Slack message posted at timestamp in channelID.
+// Its permalink is asked to Slack. Failing to get it is only logged, message
+// is returned without permalink.
+func newSlackMessage(ctx context.Context, api slackClient, notificationName, channelID, timestamp string,
+	now time.Time, logger logr.Logger) *appsv1alpha1.SlackMessage {
+
+	permalink, err := api.GetPermalinkContext(ctx, &slack.PermalinkParameters{Channel: channelID, Ts: timestamp})
+	if err != nil {
+		logger.Error(err, "failed to get slack message permalink")
+	}
+
+	return &appsv1alpha1.SlackMessage{
+		NotificationName: notificationName,
+		ChannelID:        channelID,
+		Timestamp:        timestamp,
+		Permalink:        permalink,
+		PostTime:         metav1.NewTime(now),
+	}
+}
+
+// updateSlackMessage stores message in the Cleaner status, replacing the
+// previous message of the same notification
+func updateSlackMessage(ctx context.Context, cleanerName string, message *appsv1alpha1.SlackMessage) error {
+	return updateCleanerStatus(ctx, cleanerName, func(cleaner *appsv1alpha1.Cleaner) {
+		// Messages of notifications removed, or not recording messages anymore, are dropped
+		recorded := make(map[string]bool)
+		for i := range cleaner.Spec.Notifications {
+			if isSlackMessageRecorded(&cleaner.Spec.Notifications[i]) {
+				recorded[cleaner.Spec.Notifications[i].Name] = true
+			}
+		}
+
+		messages := []appsv1alpha1.SlackMessage{*message}
+		for i := range cleaner.Status.SlackMessages {
+			name := cleaner.Status.SlackMessages[i].NotificationName
+			if name != message.NotificationName && recorded[name] {
+				messages = append(messages, cleaner.Status.SlackMessages[i])
+			}
+		}
+		sort.Slice(messages, func(i, j int) bool {
+			return messages[i].NotificationName < messages[j].NotificationName
+		})
+		cleaner.Status.SlackMessages = messages
+	})
+}
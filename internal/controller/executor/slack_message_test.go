@@ -0,0 +1,114 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+// createRecordedSlackCleaner creates a Cleaner with a Slack notification
+// recording its messages, posting to channelID
+func createRecordedSlackCleaner(channelID string) *appsv1alpha1.Cleaner {
+	ref := createNotificationSecret(map[string][]byte{
+		libsveltosv1alpha1.SlackChannelID: []byte(channelID),
+		libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+	})
+
+	cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+	cleaner.Spec.Schedule = "0 * * * *"
+	cleaner.Spec.ResourcePolicySet.ResourceSelectors = []appsv1alpha1.ResourceSelector{
+		{Kind: "ConfigMap", Version: "v1"},
+	}
+	cleaner.Spec.Notifications[0].Slack = &appsv1alpha1.SlackOptions{RecordMessage: true}
+	Expect(k8sClient.Create(context.TODO(), cleaner)).To(Succeed())
+	Expect(waitForObject(context.TODO(), k8sClient, cleaner)).To(Succeed())
+	return cleaner
+}
+
+var _ = Describe("Slack message", func() {
+	It("sendNotifications records the posted message and its permalink in the Cleaner status", func() {
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		channelID := randomSlackChannelID()
+		cleaner := createRecordedSlackCleaner(channelID)
+
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+		Expect(fake.values).To(HaveLen(1))
+
+		current := &appsv1alpha1.Cleaner{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, current)).To(Succeed())
+		Expect(current.Status.SlackMessages).To(HaveLen(1))
+		message := &current.Status.SlackMessages[0]
+		Expect(message.NotificationName).To(Equal(cleaner.Spec.Notifications[0].Name))
+		Expect(message.ChannelID).To(Equal(channelID))
+		Expect(message.Timestamp).To(Equal("1700000000.000100"))
+		Expect(message.Permalink).To(Equal("https://example.slack.com/archives/" + channelID + "/p1700000000000100"))
+		Expect(message.PostTime.Time).To(BeTemporally("~", time.Now(), time.Minute))
+		// Recording messages does not start a thread
+		Expect(current.Status.SlackThreads).To(BeEmpty())
+	})
+
+	It("sendNotifications records the message without permalink when Slack does not return one", func() {
+		fake := &fakeSlackClient{permalinkErr: errors.New("missing_scope")}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		cleaner := createRecordedSlackCleaner(randomSlackChannelID())
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+
+		current := &appsv1alpha1.Cleaner{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, current)).To(Succeed())
+		Expect(current.Status.SlackMessages).To(HaveLen(1))
+		Expect(current.Status.SlackMessages[0].Timestamp).To(Equal("1700000000.000100"))
+		Expect(current.Status.SlackMessages[0].Permalink).To(BeEmpty())
+	})
+
+	It("SendTestNotification does not record the test message", func() {
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		cleaner := createRecordedSlackCleaner(randomSlackChannelID())
+		Expect(executor.SendTestNotification(context.TODO(), cleaner, cleaner.Spec.Notifications[0].Name,
+			logr.Discard())).To(Succeed())
+		Expect(fake.values).To(HaveLen(1))
+
+		current := &appsv1alpha1.Cleaner{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, current)).To(Succeed())
+		Expect(current.Status.SlackMessages).To(BeEmpty())
+	})
+})
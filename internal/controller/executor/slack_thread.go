@@ -0,0 +1,98 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+// isSlackThreadNotification returns true if messages of notification are posted
+// in a Slack thread
+func isSlackThreadNotification(notification *appsv1alpha1.Notification) bool {
+	return notification.Type == appsv1alpha1.NotificationTypeSlack &&
+		notification.Slack != nil && notification.Slack.ThreadPeriod != nil
+}
+
+// getActiveSlackThread returns the Slack thread, stored in the Cleaner status, the
+// notification message must be posted to. Nil is returned if a new thread must be
+// started: notification does not use threads, no thread was started in channelID
+// yet or thread period has elapsed.
+func getActiveSlackThread(cleaner *appsv1alpha1.Cleaner, notification *appsv1alpha1.Notification,
+	channelID string, now time.Time) *appsv1alpha1.SlackThread {
+
+	if !isSlackThreadNotification(notification) {
+		return nil
+	}
+
+	for i := range cleaner.Status.SlackThreads {
+		thread := &cleaner.Status.SlackThreads[i]
+		if thread.NotificationName != notification.Name {
+			continue
+		}
+		// When failing over to different credentials, channel might change
+		if thread.ChannelID != channelID || thread.Timestamp == "" {
+			return nil
+		}
+		if now.Sub(thread.StartTime.Time) >= notification.Slack.ThreadPeriod.Duration {
+			return nil
+		}
+		return thread
+	}
+
+	return nil
+}
+
+// updateSlackThread stores thread in the Cleaner status
+func updateSlackThread(ctx context.Context, cleanerName string, thread *appsv1alpha1.SlackThread) error {
+	return updateCleanerStatus(ctx, cleanerName, func(cleaner *appsv1alpha1.Cleaner) {
+		// Threads of notifications removed, or not using threads anymore, are dropped
+		threadNames := make(map[string]bool)
+		for i := range cleaner.Spec.Notifications {
+			if isSlackThreadNotification(&cleaner.Spec.Notifications[i]) {
+				threadNames[cleaner.Spec.Notifications[i].Name] = true
+			}
+		}
+
+		threads := []appsv1alpha1.SlackThread{*thread}
+		for i := range cleaner.Status.SlackThreads {
+			name := cleaner.Status.SlackThreads[i].NotificationName
+			if name != thread.NotificationName && threadNames[name] {
+				threads = append(threads, cleaner.Status.SlackThreads[i])
+			}
+		}
+		sort.Slice(threads, func(i, j int) bool {
+			return threads[i].NotificationName < threads[j].NotificationName
+		})
+		cleaner.Status.SlackThreads = threads
+	})
+}
+
+// newSlackThread returns the Slack thread started by the message posted at timestamp
+func newSlackThread(notificationName, channelID, timestamp string, now time.Time) *appsv1alpha1.SlackThread {
+	return &appsv1alpha1.SlackThread{
+		NotificationName: notificationName,
+		ChannelID:        channelID,
+		Timestamp:        timestamp,
+		StartTime:        metav1.NewTime(now),
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+var _ = Describe("Slack thread", func() {
+	It("sendNotifications replies in thread and starts a new one once thread period elapsed", func() {
+		channelID := randomSlackChannelID()
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(channelID),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Schedule = "0 * * * *"
+		cleaner.Spec.ResourcePolicySet.ResourceSelectors = []appsv1alpha1.ResourceSelector{
+			{Kind: "ConfigMap", Version: "v1"},
+		}
+		cleaner.Spec.Notifications[0].Slack = &appsv1alpha1.SlackOptions{
+			ThreadPeriod: &metav1.Duration{Duration: 24 * time.Hour},
+		}
+		Expect(k8sClient.Create(context.TODO(), cleaner)).To(Succeed())
+		Expect(waitForObject(context.TODO(), k8sClient, cleaner)).To(Succeed())
+
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		// First message starts the thread
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("thread_ts")).To(BeEmpty())
+
+		currentCleaner := &appsv1alpha1.Cleaner{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, currentCleaner)).To(Succeed())
+		Expect(currentCleaner.Status.SlackThreads).To(HaveLen(1))
+		thread := &currentCleaner.Status.SlackThreads[0]
+		Expect(thread.NotificationName).To(Equal(cleaner.Spec.Notifications[0].Name))
+		Expect(thread.ChannelID).To(Equal(channelID))
+		Expect(thread.Timestamp).ToNot(BeEmpty())
+
+		// Subsequent messages are replies in the thread
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			currentCleaner, "", logr.Discard())).To(Succeed())
+		Expect(fake.values).To(HaveLen(2))
+		Expect(fake.values[1].Get("thread_ts")).To(Equal(thread.Timestamp))
+
+		// Once thread period elapsed, a new thread is started
+		thread.StartTime = metav1.NewTime(time.Now().Add(-25 * time.Hour))
+		Expect(k8sClient.Status().Update(context.TODO(), currentCleaner)).To(Succeed())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			currentCleaner, "", logr.Discard())).To(Succeed())
+		Expect(fake.values).To(HaveLen(3))
+		Expect(fake.values[2].Get("thread_ts")).To(BeEmpty())
+
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, currentCleaner)).To(Succeed())
+		Expect(currentCleaner.Status.SlackThreads).To(HaveLen(1))
+		Expect(currentCleaner.Status.SlackThreads[0].StartTime.Time).To(BeTemporally("~", time.Now(), time.Minute))
+	})
+
+	It("sendNotifications does not reply in a thread started in a different channel", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications[0].Slack = &appsv1alpha1.SlackOptions{
+			ThreadPeriod: &metav1.Duration{Duration: 24 * time.Hour},
+		}
+		cleaner.Status.SlackThreads = []appsv1alpha1.SlackThread{
+			{
+				NotificationName: cleaner.Spec.Notifications[0].Name,
+				ChannelID:        randomString(),
+				Timestamp:        "1700000000.000100",
+				StartTime:        metav1.Now(),
+			},
+		}
+
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("thread_ts")).To(BeEmpty())
+	})
+})
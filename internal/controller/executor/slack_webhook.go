@@ -0,0 +1,67 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/slack-go/slack"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+const slackWebhookTimeout = 30 * time.Second
+
+// postSlackWebhook posts msg to the Slack incoming webhook of info. The channel is
+// the one the webhook was created for, so channel routing and threads do not apply.
+func postSlackWebhook(ctx context.Context, info *slackInfo, msg *slackMessage,
+	notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+	logger.V(logs.LogInfo).Info("send slack webhook message")
+
+	webhookMessage := &slack.WebhookMessage{
+		Text:     msg.text,
+		Username: notification.Username,
+	}
+	webhookMessage.Attachments = msg.getAttachments()
+	// Slack uses icon_emoji when both are set
+	if notification.IconEmoji != "" {
+		webhookMessage.IconEmoji = notification.IconEmoji
+	} else {
+		webhookMessage.IconURL = notification.IconURL
+	}
+
+	if err := slack.PostWebhookCustomHTTPContext(ctx, info.webhookURL,
+		newNotificationTLSHTTPClient(slackWebhookTimeout, info.tlsConfig), webhookMessage); err != nil {
+		// URL contains the webhook token. Leave it out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("%s slack webhook: %w", urlErr.Op, getTLSError(urlErr.Err))
+		}
+		return err
+	}
+
+	logger.V(logs.LogInfo).Info("slack webhook message sent")
+	return nil
+}
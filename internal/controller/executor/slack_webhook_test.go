@@ -0,0 +1,136 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/slack-go/slack"
+	corev1 "k8s.io/api/core/v1"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+// newSlackWebhookServer returns a fake Slack incoming webhook recording posted messages
+func newSlackWebhookServer(messages *[]slack.WebhookMessage) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		Expect(err).To(BeNil())
+		msg := slack.WebhookMessage{}
+		Expect(json.Unmarshal(body, &msg)).To(Succeed())
+		*messages = append(*messages, msg)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	DeferCleanup(server.Close)
+	return server
+}
+
+var _ = Describe("Slack incoming webhook", func() {
+	It("sendNotifications posts Slack message to the incoming webhook", func() {
+		var messages []slack.WebhookMessage
+		server := newSlackWebhookServer(&messages)
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.SlackWebhookURL: []byte(server.URL + "/services/T000/B000/XXXX"),
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications[0].Username = "k8s-cleaner"
+		cleaner.Spec.Notifications[0].IconEmoji = ":broom:"
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(BeEmpty())
+		Expect(messages).To(HaveLen(1))
+		Expect(messages[0].Text).To(ContainSubstring("*Resources:* 1 (ConfigMap: 1)"))
+		Expect(messages[0].Text).To(ContainSubstring(resource.Resource.GetName()))
+		Expect(messages[0].Username).To(Equal("k8s-cleaner"))
+		Expect(messages[0].IconEmoji).To(Equal(":broom:"))
+		Expect(messages[0].Attachments).To(HaveLen(1))
+		Expect(messages[0].Attachments[0].Text).To(ContainSubstring(resource.Message))
+	})
+
+	It("sendNotifications attaches the report when upload is requested", func() {
+		var messages []slack.WebhookMessage
+		server := newSlackWebhookServer(&messages)
+
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.SlackWebhookURL: []byte(server.URL),
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications[0].Slack = &appsv1alpha1.SlackOptions{UploadReport: true}
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(messages).To(HaveLen(1))
+		Expect(messages[0].Text).ToNot(ContainSubstring("Full report attached"))
+		Expect(messages[0].Attachments).To(HaveLen(1))
+		Expect(messages[0].Attachments[0].Text).To(ContainSubstring(resource.Message))
+	})
+
+	It("sendNotifications fails over to next Slack secret when the webhook has been removed", func() {
+		webhook := newProviderServer(http.StatusNotFound, "no_service")
+		primary := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.SlackWebhookURL: []byte(webhook.URL),
+		})
+		backupToken := randomString()
+		backupChannelID := randomSlackChannelID()
+		backup := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(backupChannelID),
+			libsveltosv1alpha1.SlackToken:     []byte(backupToken),
+		})
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			Expect(token).To(Equal(backupToken))
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, primary)
+		cleaner.Spec.Notifications[0].FailoverNotificationRefs = []corev1.ObjectReference{*backup}
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+		Expect(fake.channelIDs).To(Equal([]string{backupChannelID}))
+	})
+
+	It("sendNotifications does not include the webhook URL in errors", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.SlackWebhookURL: []byte("http://127.0.0.1:1/services/T000/B000/secret-token"),
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).ToNot(ContainSubstring("secret-token"))
+	})
+})
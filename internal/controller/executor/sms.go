@@ -0,0 +1,178 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+const (
+	twilioRequestTimeout = 30 * time.Second
+
+	// maximum number of bytes of the Twilio response included in errors
+	maxTwilioResponseBody = 4096
+
+	// smsMaxLength is the maximum length of a Twilio message body
+	smsMaxLength = 1600
+)
+
+// twilioURL is the Twilio REST API base URL
+var twilioURL = "https://api.twilio.com/2010-04-01"
+
+type smsInfo struct {
+	accountSID string
+	authToken  string
+	from       string
+	to         []string
+}
+
+// twilioError is the body of Twilio error responses
+type twilioError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func init() {
+	registerNotifier(appsv1alpha1.NotificationTypeSMS, notifierFunc(
+		func(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+			_ []ResourceResult, _ string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+			return sendSMSNotification(ctx, cleaner, reportSpec, notification, logger)
+		}))
+}
+
+// sendSMSNotification sends the SMS summary of reportSpec to every recipient.
+// A failure sending to a recipient does not prevent sending to the others.
+func sendSMSNotification(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+	info, err := getSMSInfo(ctx, notification)
+	if err != nil {
+		return err
+	}
+
+	body := getSMSBody(cleaner, reportSpec)
+	for _, to := range info.to {
+		l := logger.WithValues(logKeyChannel, to)
+		l.V(logs.LogInfo).Info("send sms")
+		if sendErr := sendTwilioMessage(ctx, info, to, body); sendErr != nil {
+			l.Error(sendErr, logMsgSendFailed)
+			err = sendErr
+			continue
+		}
+		l.V(logs.LogDebug).Info("sms sent")
+	}
+	return err
+}
+
+func sendTwilioMessage(ctx context.Context, info *smsInfo, to, body string) error {
+	form := url.Values{
+		"From": {info.from},
+		"To":   {to},
+		"Body": {body},
+	}
+	endpoint := fmt.Sprintf("%s/Accounts/%s/Messages.json", twilioURL, url.PathEscape(info.accountSID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(info.accountSID, info.authToken)
+
+	resp, err := newNotificationHTTPClient(twilioRequestTimeout).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxTwilioResponseBody))
+		twilioErr := &twilioError{}
+		if json.Unmarshal(data, twilioErr) == nil && twilioErr.Message != "" {
+			return fmt.Errorf("twilio returned %s: %d %s", resp.Status, twilioErr.Code, twilioErr.Message)
+		}
+		return fmt.Errorf("twilio returned %s: %s", resp.Status, string(data))
+	}
+	return nil
+}
+
+// getSMSBody returns the short summary sent as SMS: Cleaner name, action and
+// resource counts, along with the run error, if any. Body is truncated to the
+// Twilio limit.
+func getSMSBody(cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec) string {
+	lines := []string{
+		fmt.Sprintf("k8s-cleaner %s: %s", cleaner.Name, reportSpec.Action),
+		getResourceSummary(reportSpec, true),
+	}
+	if failed := getFailedResourceCount(reportSpec); failed > 0 {
+		lines = append(lines, fmt.Sprintf("Failed: %d", failed))
+	}
+	if reportSpec.RunID != "" {
+		lines = append(lines, fmt.Sprintf("Run ID: %s", reportSpec.RunID))
+	}
+	if reportSpec.Error != "" {
+		lines = append(lines, fmt.Sprintf("Error: %s", reportSpec.Error))
+	}
+	return truncateString(strings.Join(lines, "\n"), smsMaxLength)
+}
+
+func getSMSInfo(ctx context.Context, notification *appsv1alpha1.Notification) (*smsInfo, error) {
+	secret, err := getSecret(ctx, notification)
+	if err != nil {
+		return nil, err
+	}
+
+	accountSID, ok := secret.Data[appsv1alpha1.TwilioAccountSID]
+	if !ok || len(accountSID) == 0 {
+		return nil, fmt.Errorf("secret does not contain twilio account SID")
+	}
+
+	authToken, ok := secret.Data[appsv1alpha1.TwilioAuthToken]
+	if !ok || len(authToken) == 0 {
+		return nil, fmt.Errorf("secret does not contain twilio auth token")
+	}
+
+	from, ok := secret.Data[appsv1alpha1.TwilioFromNumber]
+	if !ok || len(from) == 0 {
+		return nil, fmt.Errorf("secret does not contain twilio from number")
+	}
+
+	info := &smsInfo{accountSID: string(accountSID), authToken: string(authToken), from: string(from)}
+	for _, to := range strings.Split(string(secret.Data[appsv1alpha1.TwilioToNumbers]), ",") {
+		if to = strings.TrimSpace(to); to != "" {
+			info.to = append(info.to, to)
+		}
+	}
+	if len(info.to) == 0 {
+		return nil, fmt.Errorf("secret does not contain twilio to numbers")
+	}
+
+	return info, nil
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+// twilioMessage is a message received by the fake Twilio server
+type twilioMessage struct {
+	path     string
+	user     string
+	password string
+	from     string
+	to       string
+	body     string
+}
+
+// newTwilioServer returns a fake Twilio REST API recording sent messages
+func newTwilioServer(messages *[]twilioMessage) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Expect(r.ParseForm()).To(Succeed())
+		user, password, _ := r.BasicAuth()
+		*messages = append(*messages, twilioMessage{
+			path:     r.URL.Path,
+			user:     user,
+			password: password,
+			from:     r.PostForm.Get("From"),
+			to:       r.PostForm.Get("To"),
+			body:     r.PostForm.Get("Body"),
+		})
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"sid":"SM00000000000000000000000000000000","status":"queued"}`))
+	}))
+	DeferCleanup(server.Close)
+	DeferCleanup(executor.SetTwilioURL(server.URL))
+	return server
+}
+
+var _ = Describe("SMS", func() {
+	It("sendNotifications sends SMS summary to every recipient", func() {
+		var messages []twilioMessage
+		newTwilioServer(&messages)
+
+		accountSID := "AC" + randomString()
+		authToken := randomString()
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.TwilioAccountSID: []byte(accountSID),
+			appsv1alpha1.TwilioAuthToken:  []byte(authToken),
+			appsv1alpha1.TwilioFromNumber: []byte("+15005550006"),
+			appsv1alpha1.TwilioToNumbers:  []byte("+15005550001, +15005550002"),
+		})
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSMS, ref)
+		resource := getResourceResult("Secret", randomString(), randomString())
+		runID := randomString()
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, runID, logr.Discard())).To(Succeed())
+
+		Expect(messages).To(HaveLen(2))
+		Expect(messages[0].to).To(Equal("+15005550001"))
+		Expect(messages[1].to).To(Equal("+15005550002"))
+		for i := range messages {
+			Expect(messages[i].path).To(Equal("/Accounts/" + accountSID + "/Messages.json"))
+			Expect(messages[i].user).To(Equal(accountSID))
+			Expect(messages[i].password).To(Equal(authToken))
+			Expect(messages[i].from).To(Equal("+15005550006"))
+			Expect(messages[i].body).To(Equal("k8s-cleaner " + cleaner.Name + ": Delete\n" +
+				"Resources: 1 (Secret: 1)\nRun ID: " + runID))
+		}
+	})
+
+	It("getSMSBody truncates SMS to Twilio limit", func() {
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSMS, nil)
+		reportSpec := &appsv1alpha1.ReportSpec{
+			Action: appsv1alpha1.ActionDelete,
+			Error:  "failed: " + strings.Repeat("é", 2000),
+		}
+
+		body := executor.GetSMSBody(cleaner, reportSpec)
+		Expect(utf8.RuneCountInString(body)).To(Equal(1600))
+		Expect(body).To(HavePrefix("k8s-cleaner " + cleaner.Name + ": Delete\nResources: 0\nError: failed: é"))
+		Expect(body).To(HaveSuffix("…"))
+	})
+
+	It("sendNotifications returns Twilio error", func() {
+		server := newProviderServer(http.StatusBadRequest,
+			`{"code":21211,"message":"The 'To' number is not a valid phone number.","status":400}`)
+		DeferCleanup(executor.SetTwilioURL(server.URL))
+
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.TwilioAccountSID: []byte(randomString()),
+			appsv1alpha1.TwilioAuthToken:  []byte(randomString()),
+			appsv1alpha1.TwilioFromNumber: []byte("+15005550006"),
+			appsv1alpha1.TwilioToNumbers:  []byte("123"),
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSMS, ref)
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("21211 The 'To' number is not a valid phone number."))
+	})
+
+	It("sendNotifications requires recipients", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.TwilioAccountSID: []byte(randomString()),
+			appsv1alpha1.TwilioAuthToken:  []byte(randomString()),
+			appsv1alpha1.TwilioFromNumber: []byte("+15005550006"),
+			appsv1alpha1.TwilioToNumbers:  []byte(" , "),
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSMS, ref)
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("twilio to numbers"))
+	})
+})
@@ -0,0 +1,264 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+
+	libsveltosv1beta1 "github.com/projectsveltos/libsveltos/api/v1beta1"
+)
+
+const (
+	defaultSmtpPort = "587"
+
+	// base64 encoded content is split in lines of this length (RFC 2045)
+	mimeLineLength = 76
+
+	quotedPrintableEncoding = "quoted-printable"
+)
+
+type smtpInfo struct {
+	recipients string
+	bcc        string
+	identity   string
+	fromEmail  string
+	password   string
+	host       string
+	port       string
+}
+
+// mailAttachment is a file attached to an email
+type mailAttachment struct {
+	fileName    string
+	contentType string
+	data        []byte
+}
+
+// smtpMailer sends emails using the SMTP configuration contained in the
+// Secret referenced by a notification.
+// Secret keys are the same ones used by Sveltos.
+type smtpMailer struct {
+	info *smtpInfo
+}
+
+func newSmtpMailer(ctx context.Context, notification *appsv1alpha1.Notification) (*smtpMailer, error) {
+	info, err := getSmtpInfo(ctx, notification)
+	if err != nil {
+		return nil, fmt.Errorf("could not create mailer, %w", err)
+	}
+	return &smtpMailer{info: info}, nil
+}
+
+// SendMail sends an email. If sendAsHtml is set, message is sent as text/html.
+// Any attachment is added to the email as a separate MIME part.
+func (m *smtpMailer) SendMail(subject, message string, sendAsHtml bool, attachments ...mailAttachment) error {
+	msg, err := buildMailMessage(m.info, subject, message, sendAsHtml, attachments)
+	if err != nil {
+		return err
+	}
+
+	server := fmt.Sprintf("%s:%s", m.info.host, m.info.port)
+	// Bcc recipients are only added to the envelope so they are not visible
+	// to other recipients
+	to := strings.Split(m.info.recipients, ",")
+	if m.info.bcc != "" {
+		to = append(to, strings.Split(m.info.bcc, ",")...)
+	}
+	if m.info.password == "" {
+		return sendMailWithoutAuth(server, m.info.fromEmail, to, msg)
+	}
+
+	auth := smtp.PlainAuth(m.info.identity, m.info.fromEmail, m.info.password, m.info.host)
+	return smtp.SendMail(server, auth, m.info.fromEmail, to, msg)
+}
+
+// buildMailMessage returns the full email (headers and body). When there are no
+// attachments, the body is a single part. Otherwise a multipart/mixed message is
+// built, with message as first part followed by one part per attachment.
+// Body is declared as UTF-8 and sent quoted-printable encoded, so non-ASCII
+// characters survive relays which are not 8-bit clean.
+func buildMailMessage(info *smtpInfo, subject, message string, sendAsHtml bool,
+	attachments []mailAttachment) ([]byte, error) {
+
+	from := info.fromEmail
+	if info.identity != "" {
+		from = (&mail.Address{Name: info.identity, Address: info.fromEmail}).String()
+	}
+
+	bodyContentType := "text/plain; charset=\"UTF-8\""
+	if sendAsHtml {
+		bodyContentType = "text/html; charset=\"UTF-8\""
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", info.recipients)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	if len(attachments) == 0 {
+		fmt.Fprintf(&buf, "Content-Type: %s\r\n", bodyContentType)
+		fmt.Fprintf(&buf, "Content-Transfer-Encoding: %s\r\n\r\n", quotedPrintableEncoding)
+		if err := writeQuotedPrintable(&buf, message); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", writer.Boundary())
+
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {bodyContentType},
+		"Content-Transfer-Encoding": {quotedPrintableEncoding},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = writeQuotedPrintable(part, message); err != nil {
+		return nil, err
+	}
+
+	for i := range attachments {
+		header := textproto.MIMEHeader{
+			"Content-Type":              {attachments[i].contentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition": {mime.FormatMediaType("attachment",
+				map[string]string{"filename": attachments[i].fileName})},
+		}
+		part, err = writer.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = part.Write(encodeBase64Lines(attachments[i].data)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
+
+// writeQuotedPrintable writes message to w quoted-printable encoded
+func writeQuotedPrintable(w io.Writer, message string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(message)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// encodeBase64Lines base64 encodes data splitting output in lines of
+// mimeLineLength characters
+func encodeBase64Lines(data []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(data)
+
+	var buf bytes.Buffer
+	for len(encoded) > mimeLineLength {
+		buf.WriteString(encoded[:mimeLineLength])
+		buf.WriteString("\r\n")
+		encoded = encoded[mimeLineLength:]
+	}
+	buf.WriteString(encoded)
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+func sendMailWithoutAuth(server, from string, to []string, msg []byte) error {
+	c, err := smtp.Dial(server)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func getSmtpInfo(ctx context.Context, notification *appsv1alpha1.Notification) (*smtpInfo, error) {
+	secret, err := getSecret(ctx, notification)
+	if err != nil {
+		return nil, err
+	}
+
+	to, ok := secret.Data[libsveltosv1beta1.SmtpRecipients]
+	if !ok {
+		return nil, fmt.Errorf("secret does not contain email recipients")
+	}
+
+	from, ok := secret.Data[libsveltosv1beta1.SmtpSender]
+	if !ok {
+		return nil, fmt.Errorf("secret does not contain email sender")
+	}
+
+	host, ok := secret.Data[libsveltosv1beta1.SmtpHost]
+	if !ok {
+		return nil, fmt.Errorf("secret does not contain email host")
+	}
+
+	port, ok := secret.Data[libsveltosv1beta1.SmtpPort]
+	if !ok {
+		port = []byte(defaultSmtpPort)
+	}
+
+	// Bcc, identity and password are optional. Password is not set in environments
+	// that use e.g. IAM roles
+	return &smtpInfo{
+		recipients: string(to),
+		bcc:        string(secret.Data[libsveltosv1beta1.SmtpBcc]),
+		identity:   string(secret.Data[libsveltosv1beta1.SmtpIdentity]),
+		fromEmail:  string(from),
+		password:   string(secret.Data[libsveltosv1beta1.SmtpPassword]),
+		host:       string(host),
+		port:       string(port),
+	}, nil
+}
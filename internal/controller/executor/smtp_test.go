@@ -0,0 +1,129 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+var _ = Describe("SMTP", func() {
+	It("buildMailMessage sends single part email when there are no attachments", func() {
+		body := randomString()
+		msg, err := executor.BuildMailMessage("cleaner@example.com", "ops@example.com", "report",
+			body, false, nil)
+		Expect(err).To(BeNil())
+
+		email, err := mail.ReadMessage(bytes.NewReader(msg))
+		Expect(err).To(BeNil())
+		Expect(email.Header.Get("From")).To(Equal("cleaner@example.com"))
+		Expect(email.Header.Get("To")).To(Equal("ops@example.com"))
+		Expect(email.Header.Get("Subject")).To(Equal("report"))
+		Expect(email.Header.Get("Content-Type")).To(HavePrefix("text/plain"))
+		Expect(email.Header.Get("Content-Transfer-Encoding")).To(Equal("quoted-printable"))
+
+		content, err := io.ReadAll(quotedprintable.NewReader(email.Body))
+		Expect(err).To(BeNil())
+		Expect(string(content)).To(Equal(body))
+	})
+
+	It("buildMailMessage declares UTF-8 and encodes multibyte characters", func() {
+		body := `{"resourceInfo":[{"resource":{"kind":"ConfigMap","name":"café-設定-😀"}}]}`
+
+		for _, sendAsHtml := range []bool{false, true} {
+			msg, err := executor.BuildMailMessage("cleaner@example.com", "ops@example.com", "report für ops",
+				body, sendAsHtml, nil)
+			Expect(err).To(BeNil())
+
+			// Message must be 7-bit clean
+			for _, b := range msg {
+				Expect(b).To(BeNumerically("<", 128))
+			}
+
+			email, err := mail.ReadMessage(bytes.NewReader(msg))
+			Expect(err).To(BeNil())
+
+			subject, err := new(mime.WordDecoder).DecodeHeader(email.Header.Get("Subject"))
+			Expect(err).To(BeNil())
+			Expect(subject).To(Equal("report für ops"))
+
+			mediaType, params, err := mime.ParseMediaType(email.Header.Get("Content-Type"))
+			Expect(err).To(BeNil())
+			if sendAsHtml {
+				Expect(mediaType).To(Equal("text/html"))
+			} else {
+				Expect(mediaType).To(Equal("text/plain"))
+			}
+			Expect(params["charset"]).To(Equal("UTF-8"))
+			Expect(email.Header.Get("Content-Transfer-Encoding")).To(Equal("quoted-printable"))
+
+			content, err := io.ReadAll(quotedprintable.NewReader(email.Body))
+			Expect(err).To(BeNil())
+			Expect(string(content)).To(Equal(body))
+		}
+	})
+
+	It("buildMailMessage adds attachments as separate MIME parts", func() {
+		body := randomString()
+		// Long enough to verify base64 content is split in multiple lines
+		data := bytes.Repeat([]byte("<p>report</p>"), 20)
+		attachment := executor.NewMailAttachment("report.html", "text/html; charset=\"UTF-8\"", data)
+
+		msg, err := executor.BuildMailMessage("cleaner@example.com", "ops@example.com", "report",
+			body, false, []executor.MailAttachment{attachment})
+		Expect(err).To(BeNil())
+
+		email, err := mail.ReadMessage(bytes.NewReader(msg))
+		Expect(err).To(BeNil())
+
+		mediaType, params, err := mime.ParseMediaType(email.Header.Get("Content-Type"))
+		Expect(err).To(BeNil())
+		Expect(mediaType).To(Equal("multipart/mixed"))
+
+		reader := multipart.NewReader(email.Body, params["boundary"])
+
+		part, err := reader.NextPart()
+		Expect(err).To(BeNil())
+		Expect(part.Header.Get("Content-Type")).To(HavePrefix("text/plain"))
+		// quoted-printable parts are transparently decoded
+		content, err := io.ReadAll(part)
+		Expect(err).To(BeNil())
+		Expect(string(content)).To(Equal(body))
+
+		part, err = reader.NextPart()
+		Expect(err).To(BeNil())
+		Expect(part.FileName()).To(Equal("report.html"))
+		Expect(part.Header.Get("Content-Type")).To(HavePrefix("text/html"))
+		Expect(part.Header.Get("Content-Transfer-Encoding")).To(Equal("base64"))
+		content, err = io.ReadAll(base64.NewDecoder(base64.StdEncoding, part))
+		Expect(err).To(BeNil())
+		Expect(content).To(Equal(data))
+
+		_, err = reader.NextPart()
+		Expect(err).To(Equal(io.EOF))
+	})
+})
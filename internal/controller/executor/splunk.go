@@ -0,0 +1,208 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+const (
+	defaultSplunkSourceType = "k8s-cleaner"
+	splunkSource            = "k8s-cleaner"
+	splunkTruncatedField    = "truncated"
+
+	splunkRequestTimeout = 30 * time.Second
+
+	// maximum number of bytes of the HEC response included in errors
+	maxSplunkResponseBody = 4096
+)
+
+type splunkInfo struct {
+	url       string
+	token     string
+	tlsConfig *tls.Config
+}
+
+// splunkEvent is the HEC event format.
+// https://docs.splunk.com/Documentation/Splunk/latest/Data/FormateventsforHTTPEventCollector
+type splunkEvent struct {
+	Time       int64                    `json:"time"`
+	Source     string                   `json:"source"`
+	SourceType string                   `json:"sourcetype"`
+	Index      string                   `json:"index,omitempty"`
+	Event      *appsv1alpha1.ReportSpec `json:"event"`
+	Fields     map[string]string        `json:"fields,omitempty"`
+}
+
+func init() {
+	registerNotifier(appsv1alpha1.NotificationTypeSplunkHEC, notifierFunc(
+		func(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+			_ []ResourceResult, _ string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+			return sendSplunkNotification(ctx, cleaner, reportSpec, notification, logger)
+		}))
+}
+
+func sendSplunkNotification(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+	info, err := getSplunkInfo(ctx, notification)
+	if err != nil {
+		return err
+	}
+
+	l := logger.WithValues(logKeyURL, info.url)
+	l.V(logs.LogInfo).Info("send splunk event")
+
+	data, err := getSplunkEventData(cleaner, reportSpec, notification)
+	if err != nil {
+		l.Error(err, "failed to marshal splunk event")
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, info.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Splunk "+info.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	insecureSkipVerify := notification.Splunk != nil && notification.Splunk.InsecureSkipVerify
+	resp, err := getSplunkHTTPClient(info.tlsConfig, insecureSkipVerify).Do(req)
+	if err != nil {
+		err = getTLSError(err)
+		l.Error(err, logMsgSendFailed)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxSplunkResponseBody))
+		err = fmt.Errorf("splunk HEC returned %s: %s", resp.Status, string(body))
+		l.Error(err, logMsgSendFailed)
+		return err
+	}
+
+	l.V(logs.LogDebug).Info("splunk event sent")
+	return nil
+}
+
+func getSplunkEvent(cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	notification *appsv1alpha1.Notification) *splunkEvent {
+
+	event := &splunkEvent{
+		Time:       time.Now().Unix(),
+		Source:     splunkSource,
+		SourceType: defaultSplunkSourceType,
+		Event:      reportSpec,
+		Fields:     map[string]string{},
+	}
+
+	if notification.Splunk != nil {
+		if notification.Splunk.SourceType != "" {
+			event.SourceType = notification.Splunk.SourceType
+		}
+		event.Index = notification.Splunk.Index
+	}
+
+	for key, value := range notification.Metadata {
+		event.Fields[key] = value
+	}
+	event.Fields["cleaner"] = cleaner.Name
+	if reportSpec.RunID != "" {
+		event.Fields["runID"] = reportSpec.RunID
+	}
+
+	return event
+}
+
+// getSplunkEventData returns the serialized HEC event. If the event exceeds
+// splunkMaxEventSize, trailing resources are dropped and the truncation marker
+// is added to the event fields.
+func getSplunkEventData(cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	notification *appsv1alpha1.Notification) ([]byte, error) {
+
+	event := getSplunkEvent(cleaner, reportSpec, notification)
+	data, err := json.Marshal(event)
+	if err != nil || len(data) <= splunkMaxEventSize {
+		return data, err
+	}
+
+	size := func(spec *appsv1alpha1.ReportSpec, omitted int) (int, error) {
+		event.Event = spec
+		event.Fields[splunkTruncatedField] = getTruncationMarker(omitted)
+		data, err := json.Marshal(event)
+		return len(data), err
+	}
+
+	truncated, omitted, err := fitReportSpec(reportSpec, splunkMaxEventSize, size)
+	if err != nil {
+		return nil, err
+	}
+
+	event.Event = truncated
+	event.Fields[splunkTruncatedField] = getTruncationMarker(omitted)
+	return json.Marshal(event)
+}
+
+// getSplunkHTTPClient returns the HTTP client used to send HEC events.
+// tlsConfig, if not nil, contains the client certificate and CA defined in
+// the notification Secret.
+func getSplunkHTTPClient(tlsConfig *tls.Config, insecureSkipVerify bool) *http.Client {
+	// insecureSkipVerify is an explicit opt-in on the Notification
+	return &http.Client{
+		Timeout:   splunkRequestTimeout,
+		Transport: getNotificationTransport(tlsConfig, insecureSkipVerify),
+	}
+}
+
+func getSplunkInfo(ctx context.Context, notification *appsv1alpha1.Notification) (*splunkInfo, error) {
+	secret, err := getSecret(ctx, notification)
+	if err != nil {
+		return nil, err
+	}
+
+	url, ok := secret.Data[appsv1alpha1.SplunkHECURL]
+	if !ok {
+		return nil, fmt.Errorf("secret does not contain splunk HEC URL")
+	}
+
+	token, ok := secret.Data[appsv1alpha1.SplunkHECToken]
+	if !ok {
+		return nil, fmt.Errorf("secret does not contain splunk HEC token")
+	}
+
+	tlsConfig, err := getNotificationTLSConfig(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &splunkInfo{url: string(url), token: string(token), tlsConfig: tlsConfig}, nil
+}
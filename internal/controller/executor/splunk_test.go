@@ -0,0 +1,121 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+var _ = Describe("Splunk HEC", func() {
+	It("sendNotifications posts report as HEC event", func() {
+		token := randomString()
+
+		var authorization string
+		var payload map[string]interface{}
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization = r.Header.Get("Authorization")
+			body, err := io.ReadAll(r.Body)
+			Expect(err).To(BeNil())
+			Expect(json.Unmarshal(body, &payload)).To(Succeed())
+			_, _ = w.Write([]byte(`{"text":"Success","code":0}`))
+		}))
+		DeferCleanup(server.Close)
+
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.SplunkHECURL:   []byte(server.URL + "/services/collector/event"),
+			appsv1alpha1.SplunkHECToken: []byte(token),
+		})
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSplunkHEC, ref)
+		cleaner.Spec.Notifications[0].Metadata = map[string]string{"team": "platform"}
+		cleaner.Spec.Notifications[0].Splunk = &appsv1alpha1.SplunkOptions{
+			SourceType:         "cleaner:report",
+			Index:              "ops",
+			InsecureSkipVerify: true,
+		}
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		runID := randomString()
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, runID, logr.Discard())).To(Succeed())
+
+		Expect(authorization).To(Equal("Splunk " + token))
+		Expect(payload["sourcetype"]).To(Equal("cleaner:report"))
+		Expect(payload["index"]).To(Equal("ops"))
+		Expect(payload["fields"]).To(Equal(map[string]interface{}{
+			"team":    "platform",
+			"cleaner": cleaner.Name,
+			"runID":   runID,
+		}))
+
+		event, ok := payload["event"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(event["action"]).To(Equal(string(appsv1alpha1.ActionDelete)))
+		data, err := json.Marshal(event["resourceInfo"])
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(ContainSubstring(resource.Resource.GetName()))
+	})
+
+	It("sendNotifications verifies HEC certificate unless skip-verify is set", func() {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		DeferCleanup(server.Close)
+
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.SplunkHECURL:   []byte(server.URL),
+			appsv1alpha1.SplunkHECToken: []byte(randomString()),
+		})
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSplunkHEC, ref)
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("certificate"))
+	})
+
+	It("sendNotifications returns HEC response body on non-2xx status", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"text":"Invalid token","code":4}`))
+		}))
+		DeferCleanup(server.Close)
+
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.SplunkHECURL:   []byte(server.URL),
+			appsv1alpha1.SplunkHECToken: []byte(randomString()),
+		})
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSplunkHEC, ref)
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("403"))
+		Expect(err.Error()).To(ContainSubstring("Invalid token"))
+	})
+})
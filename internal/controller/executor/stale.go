@@ -0,0 +1,107 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+const (
+	// eventReasonStale is the reason of Events recorded on Cleaner instances
+	// which have not run for longer than allowed by their staleness watchdog
+	eventReasonStale = "CleanerStale"
+)
+
+// SendStaleNotification notifies that the Cleaner has not run since lastRun,
+// while a run was expected every interval. It is sent right away through all
+// Cleaner notifications, ignoring digests, except CleanerReport ones which would
+// overwrite the Report of the last run. Notifications suspended because of
+// repeated failures are skipped. A Warning Event is also recorded on the Cleaner
+// instance.
+func SendStaleNotification(ctx context.Context, cleaner *appsv1alpha1.Cleaner, lastRun time.Time,
+	interval time.Duration, logger logr.Logger) error {
+
+	staleErr := getStaleError(lastRun, interval)
+	if eventRecorder != nil {
+		eventRecorder.Event(cleaner, corev1.EventTypeWarning, eventReasonStale, truncateEventMessage(staleErr))
+	}
+
+	now := time.Now()
+	runID := string(uuid.NewUUID())
+	logger = logger.WithValues(logKeyRunID, runID)
+	logger.V(logs.LogInfo).Info("send stale notification", "reason", staleErr)
+
+	var err error
+	for i := range cleaner.Spec.Notifications {
+		notification := &cleaner.Spec.Notifications[i]
+		if notification.Type == appsv1alpha1.NotificationTypeCleanerReport {
+			continue
+		}
+		l := getNotificationLogger(logger, notification)
+		if !isNotificationEnabled(notification) {
+			l.V(logs.LogInfo).Info(logMsgNotificationDisabled)
+			continue
+		}
+		if isNotificationSuspended(cleaner, notification.Name, now) {
+			l.V(logs.LogInfo).Info(logMsgNotificationSuspended)
+			continue
+		}
+
+		location, locationErr := getNotificationLocation(notification)
+		if locationErr != nil {
+			l.Error(locationErr, logMsgSendFailed)
+			err = locationErr
+			continue
+		}
+		reportSpec := generateReportSpec(nil, cleaner, runID, now.In(location))
+		reportSpec.Error = staleErr
+		message := getStaleMessage(cleaner.Name, lastRun.In(location), interval)
+
+		deliveryErr := deliverNotification(ctx, cleaner, reportSpec, nil, message, notification, l)
+		if recordErr := recordNotificationResult(ctx, cleaner, notification.Name, deliveryErr, now); recordErr != nil {
+			l.Error(recordErr, logMsgRecordStatusFailed)
+		}
+		if deliveryErr != nil {
+			// Keep notifying through the other channels
+			l.Error(deliveryErr, logMsgSendFailed)
+			err = deliveryErr
+		}
+	}
+	return err
+}
+
+func getStaleError(lastRun time.Time, interval time.Duration) string {
+	return fmt.Sprintf("cleaner has not run since %s, while scheduled every %s",
+		lastRun.UTC().Format(time.RFC3339), interval)
+}
+
+// getStaleMessage returns the text sent along with a stale notification.
+// lastRun is formatted in its location.
+func getStaleMessage(cleanerName string, lastRun time.Time, interval time.Duration) string {
+	return fmt.Sprintf("k8s-cleaner instance %s is stale: it has not run since %s, while scheduled every %s. "+
+		"Cleanup is not happening", cleanerName, lastRun.Format(time.RFC3339), interval)
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+var _ = Describe("Stale notification", func() {
+	It("SendStaleNotification notifies through all notifications but CleanerReport", func() {
+		recorder := record.NewFakeRecorder(10)
+		DeferCleanup(executor.SetEventRecorder(recorder))
+
+		var messages []string
+		var reportSpecs []*appsv1alpha1.ReportSpec
+		failingType := appsv1alpha1.NotificationType(randomString())
+		workingType := appsv1alpha1.NotificationType(randomString())
+		DeferCleanup(executor.SetNotifier(failingType, executor.NotifierFunc(
+			func(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+				resources []executor.ResourceResult, message string, notification *appsv1alpha1.Notification,
+				logger logr.Logger) error {
+
+				return errors.New("channel unavailable")
+			})))
+		DeferCleanup(executor.SetNotifier(workingType, executor.NotifierFunc(
+			func(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+				resources []executor.ResourceResult, message string, notification *appsv1alpha1.Notification,
+				logger logr.Logger) error {
+
+				messages = append(messages, message)
+				reportSpecs = append(reportSpecs, reportSpec)
+				return nil
+			})))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCleanerReport, nil)
+		cleaner.Spec.Notifications = append(cleaner.Spec.Notifications,
+			appsv1alpha1.Notification{Name: randomString(), Type: failingType},
+			appsv1alpha1.Notification{
+				Name:   randomString(),
+				Type:   workingType,
+				Digest: &appsv1alpha1.DigestOptions{Interval: metav1.Duration{Duration: time.Hour}},
+			})
+		Expect(k8sClient.Create(context.TODO(), cleaner)).To(Succeed())
+		Expect(waitForObject(context.TODO(), k8sClient, cleaner)).To(Succeed())
+
+		lastRun := time.Now().Add(-3 * time.Hour)
+		err := executor.SendStaleNotification(context.TODO(), cleaner, lastRun, time.Hour, logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("channel unavailable"))
+
+		// Delivered right away, even though digest is set, and despite previous failure
+		Expect(messages).To(HaveLen(1))
+		Expect(messages[0]).To(ContainSubstring(cleaner.Name + " is stale"))
+		Expect(messages[0]).To(ContainSubstring("scheduled every 1h0m0s"))
+		Expect(reportSpecs[0].Error).To(ContainSubstring("has not run since"))
+		Expect(reportSpecs[0].ResourceInfo).To(BeEmpty())
+
+		Expect(<-recorder.Events).To(HavePrefix("Warning CleanerStale"))
+	})
+})
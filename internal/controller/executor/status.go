@@ -0,0 +1,48 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+// updateCleanerStatus fetches the current Cleaner instance, applies mutate to it and
+// updates its status. This is retried on conflict.
+// Status fields updated by the executor are never updated by the controller, which
+// only patches the fields it changes.
+func updateCleanerStatus(ctx context.Context, cleanerName string, mutate func(cleaner *appsv1alpha1.Cleaner)) error {
+	c, err := getK8sClient()
+	if err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cleaner := &appsv1alpha1.Cleaner{}
+		if err := c.Get(ctx, types.NamespacedName{Name: cleanerName}, cleaner); err != nil {
+			return err
+		}
+
+		mutate(cleaner)
+
+		return c.Status().Update(ctx, cleaner)
+	})
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+const (
+	// maxSummaryKinds is the maximum number of kinds listed in the resource
+	// summary of a chat message
+	maxSummaryKinds = 10
+
+	// maxSummaryResources is the maximum number of resources listed in the
+	// body of a chat message. All resources are part of the attached report.
+	maxSummaryResources = 5
+
+	// codeBlockFence opens and closes a markdown code block
+	codeBlockFence = "```"
+
+	// slackMaxCodeBlockSize is the maximum size of the summary code block of
+	// Slack messages. Slack collapses messages longer than 4000 characters.
+	slackMaxCodeBlockSize = 3000
+
+	// teamsMaxCodeBlockSize is the maximum size of the summary code block of
+	// Teams messages
+	teamsMaxCodeBlockSize = 3000
+)
+
+// getResourceSummary returns the number of resources in reportSpec, along with
+// the number of resources per kind, in a single line. Unless plainText is set,
+// the label is formatted as markdown bold.
+func getResourceSummary(reportSpec *appsv1alpha1.ReportSpec, plainText bool) string {
+	label := "**Resources:**"
+	if plainText {
+		label = "Resources:"
+	}
+	summary := fmt.Sprintf("%s %d", label, len(reportSpec.ResourceInfo))
+
+	kinds, counts := getResourceCountByKind(reportSpec)
+	if len(kinds) == 0 {
+		return summary
+	}
+	perKind := make([]string, 0, maxSummaryKinds+1)
+	for i := range kinds {
+		if i == maxSummaryKinds {
+			perKind = append(perKind, fmt.Sprintf("%d more kinds", len(kinds)-i))
+			break
+		}
+		perKind = append(perKind, fmt.Sprintf("%s: %d", kinds[i], counts[kinds[i]]))
+	}
+	return fmt.Sprintf("%s (%s)", summary, strings.Join(perKind, ", "))
+}
+
+// getTopResourcesMarkdown returns the first maxSummaryResources resources in
+// reportSpec as a markdown list. Failed resources are left out, as they are
+// listed along with their error. An empty string is returned when there is no
+// such resource.
+func getTopResourcesMarkdown(reportSpec *appsv1alpha1.ReportSpec) string {
+	lines := make([]string, 0, maxSummaryResources+1)
+	total := 0
+	for i := range reportSpec.ResourceInfo {
+		info := &reportSpec.ResourceInfo[i]
+		if info.Outcome == appsv1alpha1.ResourceOutcomeFailed {
+			continue
+		}
+		total++
+		if len(lines) < maxSummaryResources {
+			lines = append(lines, "- "+getResourceDescription(&info.Resource))
+		}
+	}
+
+	if total > maxSummaryResources {
+		lines = append(lines, fmt.Sprintf("…and %d more", total-maxSummaryResources))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// getChatSummary returns the summary shown in the body of Slack and Discord
+// messages: resource counts followed by the first resources
+func getChatSummary(reportSpec *appsv1alpha1.ReportSpec) string {
+	summary := getResourceSummary(reportSpec, false)
+	if resources := getTopResourcesMarkdown(reportSpec); resources != "" {
+		summary += "\n" + resources
+	}
+	return summary
+}
+
+// getPlainTextSummary returns the summary sent, in place of the report, by
+// notifications omitting the JSON report and not rendering markdown
+func getPlainTextSummary(reportSpec *appsv1alpha1.ReportSpec) string {
+	summary := getResourceSummary(reportSpec, true)
+	if resources := getTopResourcesMarkdown(reportSpec); resources != "" {
+		summary += "\n" + resources
+	}
+	return summary
+}
+
+// getCodeBlockSummary returns the plain text summary of reportSpec as a
+// markdown code block of at most maxLength characters. The summary is
+// truncated so that the code block is always closed.
+func getCodeBlockSummary(reportSpec *appsv1alpha1.ReportSpec, maxLength int) string {
+	// Fences are on their own lines
+	summary := truncateString(getPlainTextSummary(reportSpec), maxLength-2*len(codeBlockFence)-2)
+	return codeBlockFence + "\n" + summary + "\n" + codeBlockFence
+}
+
+// getMessageSummary returns the summary shown in the body of Slack and Discord
+// messages: the markdown summary or, when the notification has
+// SummaryAsCodeBlock set, the summary as code block of at most maxLength
+// characters
+func getMessageSummary(reportSpec *appsv1alpha1.ReportSpec, notification *appsv1alpha1.Notification,
+	maxLength int) string {
+
+	if notification.SummaryAsCodeBlock {
+		return getCodeBlockSummary(reportSpec, maxLength)
+	}
+	return getChatSummary(reportSpec)
+}
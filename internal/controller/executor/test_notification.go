@@ -0,0 +1,119 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/uuid"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+const (
+	// Reasons of Events recorded on Cleaner instances for test notifications
+	eventReasonTestNotificationSent   = "TestNotificationSent"
+	eventReasonTestNotificationFailed = "TestNotificationFailed"
+
+	testNotificationResourceName = "k8s-cleaner-test-notification"
+)
+
+// SendTestNotification sends a synthetic report, containing a single resource,
+// using only the Cleaner notification named notificationName. This gives immediate
+// feedback on whether the notification is properly configured.
+// Digest is ignored, so the test notification is sent right away, as is the
+// suspension of a notification failing repeatedly. Delivery result is recorded as
+// for any report, so a successful test resumes a suspended notification. The
+// outcome is also recorded as an Event on the Cleaner instance.
+func SendTestNotification(ctx context.Context, cleaner *appsv1alpha1.Cleaner, notificationName string,
+	logger logr.Logger) error {
+
+	err := sendTestNotification(ctx, cleaner, notificationName, logger)
+	if eventRecorder != nil {
+		if err != nil {
+			eventRecorder.Event(cleaner, corev1.EventTypeWarning, eventReasonTestNotificationFailed,
+				truncateEventMessage(fmt.Sprintf("test notification %s failed: %v", notificationName, err)))
+		} else {
+			eventRecorder.Event(cleaner, corev1.EventTypeNormal, eventReasonTestNotificationSent,
+				fmt.Sprintf("test notification %s sent", notificationName))
+		}
+	}
+	return err
+}
+
+func sendTestNotification(ctx context.Context, cleaner *appsv1alpha1.Cleaner, notificationName string,
+	logger logr.Logger) error {
+
+	var notification *appsv1alpha1.Notification
+	for i := range cleaner.Spec.Notifications {
+		if cleaner.Spec.Notifications[i].Name == notificationName {
+			notification = cleaner.Spec.Notifications[i].DeepCopy()
+			break
+		}
+	}
+	if notification == nil {
+		return fmt.Errorf("notification %s not found", notificationName)
+	}
+	if notification.Type == appsv1alpha1.NotificationTypeCleanerReport {
+		// Sending a test would overwrite the Report of the last run
+		return fmt.Errorf("notification %s of type %s cannot be tested", notificationName, notification.Type)
+	}
+	notification.Digest = nil
+	// Test notifications always report delivery errors, right away
+	notification.OnFailure = appsv1alpha1.NotificationFailurePolicyFail
+	// Test notification is posted as a new message, leaving the running thread
+	// and the recorded message untouched
+	if notification.Slack != nil {
+		notification.Slack.ThreadPeriod = nil
+		notification.Slack.RecordMessage = false
+	}
+	// The test resource does not exist, so no Event can be recorded on it
+	if notification.Event != nil {
+		notification.Event.RecordOnResources = false
+	}
+
+	testCleaner := cleaner.DeepCopy()
+	testCleaner.Spec.Notifications = []appsv1alpha1.Notification{*notification}
+	// The test resource must always be listed, and must not be tracked as notified
+	testCleaner.Spec.ResourceThrottling = nil
+
+	resource := &unstructured.Unstructured{}
+	resource.SetAPIVersion("v1")
+	resource.SetKind("ConfigMap")
+	resource.SetNamespace("default")
+	resource.SetName(testNotificationResourceName)
+	resources := []ResourceResult{
+		{
+			Resource: resource,
+			Message:  "This is a test notification. No resource was processed",
+		},
+	}
+
+	runID := string(uuid.NewUUID())
+	l := logger.WithValues(logKeyRunID, runID)
+	l.V(logs.LogInfo).Info("send test notification", logKeyNotification, notificationName)
+	// Test notifications are sent right away, never batched with reports of other Cleaners.
+	// They are sent even when the notification is suspended because of repeated
+	// failures, and a successful one resumes it.
+	return sendNotifications(withoutSuspension(withoutBatching(ctx)), resources, testCleaner, runID, l)
+}
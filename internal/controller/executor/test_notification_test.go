@@ -0,0 +1,135 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+var _ = Describe("Test notification", func() {
+	It("SendTestNotification sends a synthetic report only for the requested notification", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+		recorder := record.NewFakeRecorder(10)
+		DeferCleanup(executor.SetEventRecorder(recorder))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		// Digest is ignored for test notifications
+		cleaner.Spec.Notifications[0].Digest = &appsv1alpha1.DigestOptions{
+			Interval: metav1.Duration{Duration: time.Hour},
+		}
+		// Other notifications are not sent
+		cleaner.Spec.Notifications = append(cleaner.Spec.Notifications, appsv1alpha1.Notification{
+			Name: randomString(),
+			Type: appsv1alpha1.NotificationTypeEvent,
+		})
+
+		Expect(executor.SendTestNotification(context.TODO(), cleaner, cleaner.Spec.Notifications[0].Name,
+			logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("attachments")).To(ContainSubstring("k8s-cleaner-test-notification"))
+		Expect(recorder.Events).To(HaveLen(1))
+		Expect(<-recorder.Events).To(ContainSubstring("TestNotificationSent"))
+	})
+
+	It("SendTestNotification sends suspended notifications and resumes them on success", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+
+		fake := &fakeSlackClient{}
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return fake
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Schedule = "0 * * * *"
+		cleaner.Spec.ResourcePolicySet.ResourceSelectors = []appsv1alpha1.ResourceSelector{
+			{Kind: "ConfigMap", Version: "v1"},
+		}
+		Expect(k8sClient.Create(context.TODO(), cleaner)).To(Succeed())
+		Expect(waitForObject(context.TODO(), k8sClient, cleaner)).To(Succeed())
+
+		currentCleaner := &appsv1alpha1.Cleaner{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, currentCleaner)).To(Succeed())
+		currentCleaner.Status.NotificationStatuses = []appsv1alpha1.NotificationStatus{
+			{
+				NotificationName:    cleaner.Spec.Notifications[0].Name,
+				ConsecutiveFailures: executor.NotificationFailureThreshold,
+				SuspendedUntil:      &metav1.Time{Time: time.Now().Add(time.Hour)},
+			},
+		}
+		meta.SetStatusCondition(&currentCleaner.Status.Conditions, metav1.Condition{
+			Type:   appsv1alpha1.ConditionTypeNotificationDegraded,
+			Status: metav1.ConditionTrue,
+			Reason: "NotificationSuspended",
+		})
+		Expect(k8sClient.Status().Update(context.TODO(), currentCleaner)).To(Succeed())
+
+		Expect(executor.SendTestNotification(context.TODO(), currentCleaner, cleaner.Spec.Notifications[0].Name,
+			logr.Discard())).To(Succeed())
+		Expect(fake.values).To(HaveLen(1))
+
+		// Successful test resets the circuit breaker
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, currentCleaner)).To(Succeed())
+		Expect(currentCleaner.Status.NotificationStatuses).To(BeEmpty())
+		Expect(meta.IsStatusConditionFalse(currentCleaner.Status.Conditions,
+			appsv1alpha1.ConditionTypeNotificationDegraded)).To(BeTrue())
+	})
+
+	It("SendTestNotification fails for unknown notification", func() {
+		recorder := record.NewFakeRecorder(10)
+		DeferCleanup(executor.SetEventRecorder(recorder))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, nil)
+
+		err := executor.SendTestNotification(context.TODO(), cleaner, randomString(), logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("not found"))
+		Expect(<-recorder.Events).To(ContainSubstring("TestNotificationFailed"))
+	})
+
+	It("SendTestNotification does not overwrite Report instance", func() {
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCleanerReport, nil)
+
+		Expect(executor.SendTestNotification(context.TODO(), cleaner, cleaner.Spec.Notifications[0].Name,
+			logr.Discard())).ToNot(Succeed())
+	})
+})
@@ -0,0 +1,201 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+const (
+	// maxNotifiedResources is the maximum number of resources tracked in the
+	// Cleaner status. Resources which are not tracked are notified by every run.
+	maxNotifiedResources = 1000
+
+	// maxStillPresentResources is the maximum number of throttled resources
+	// listed in the still present note
+	maxStillPresentResources = 10
+)
+
+// stillPresentResource is a resource matched again within the cooldown
+type stillPresentResource struct {
+	resource *ResourceResult
+	since    metav1.Time
+}
+
+// resourceThrottle contains the resources of a run to notify when
+// ResourceThrottling is set
+type resourceThrottle struct {
+	// resources are the resources first matched by the run, or whose cooldown
+	// elapsed
+	resources []ResourceResult
+	// stillPresent are the resources notified within the cooldown
+	stillPresent []stillPresentResource
+	// notified is the state to store in the Cleaner status once the run is notified
+	notified []appsv1alpha1.NotifiedResource
+	// reportSpecs caches the reports of resources, by time zone
+	reportSpecs map[string]*appsv1alpha1.ReportSpec
+}
+
+// isThrottledNotification returns true if the regular report of a run is throttled
+// for notification. The Report instance (CleanerReport) always lists every resource.
+func isThrottledNotification(throttle *resourceThrottle, notification *appsv1alpha1.Notification) bool {
+	return throttle != nil && notification.Type != appsv1alpha1.NotificationTypeCleanerReport
+}
+
+// getResourceThrottle splits the resources matched by a run between the ones
+// to notify and the ones notified less than the cooldown ago. Resources notified
+// again after the cooldown have a still present message added.
+// Nil is returned when ResourceThrottling is not set.
+func getResourceThrottle(cleaner *appsv1alpha1.Cleaner, resources []ResourceResult, now time.Time) *resourceThrottle {
+	if cleaner.Spec.ResourceThrottling == nil || cleaner.Spec.ResourceThrottling.Cooldown.Duration <= 0 {
+		return nil
+	}
+	cooldown := cleaner.Spec.ResourceThrottling.Cooldown.Duration
+
+	previous := make(map[string]*appsv1alpha1.NotifiedResource, len(cleaner.Status.NotifiedResources))
+	for i := range cleaner.Status.NotifiedResources {
+		notified := &cleaner.Status.NotifiedResources[i]
+		previous[getNotifiedResourceKey(notified)] = notified
+	}
+
+	throttle := &resourceThrottle{
+		resources:   make([]ResourceResult, 0, len(resources)),
+		reportSpecs: make(map[string]*appsv1alpha1.ReportSpec),
+	}
+	// Resources already tracked come first, so they keep being tracked when
+	// new resources exceed maxNotifiedResources
+	var matched []appsv1alpha1.NotifiedResource
+	for i := range resources {
+		resource := resources[i]
+		entry := getNotifiedResource(&resource, now)
+		last, ok := previous[getNotifiedResourceKey(&entry)]
+		switch {
+		case !ok:
+			throttle.resources = append(throttle.resources, resource)
+			matched = append(matched, entry)
+			continue
+		case now.Sub(last.LastNotified.Time) < cooldown:
+			throttle.stillPresent = append(throttle.stillPresent,
+				stillPresentResource{resource: &resources[i], since: last.FirstMatched})
+			entry.LastNotified = last.LastNotified
+		default:
+			message := getStillPresentMessage(last.FirstMatched)
+			if resource.Message != "" {
+				message = resource.Message + resourceMessageSeparator + message
+			}
+			resource.Message = message
+			throttle.resources = append(throttle.resources, resource)
+		}
+		entry.FirstMatched = last.FirstMatched
+		throttle.notified = append(throttle.notified, entry)
+	}
+
+	for i := range matched {
+		if len(throttle.notified) == maxNotifiedResources {
+			break
+		}
+		throttle.notified = append(throttle.notified, matched[i])
+	}
+	return throttle
+}
+
+// filterStillPresent only keeps the throttled resources belonging to scope and
+// kept by filter
+func filterStillPresent(stillPresent []stillPresentResource, scope appsv1alpha1.ResourceScope,
+	filter *resourceFilter) []stillPresentResource {
+
+	filtered := make([]stillPresentResource, 0, len(stillPresent))
+	for i := range stillPresent {
+		resource := stillPresent[i].resource.Resource
+		if isInResourceScope(resource.GetNamespace(), scope) && filter.keeps(resource.GetKind(), resource.GetNamespace()) {
+			filtered = append(filtered, stillPresent[i])
+		}
+	}
+	return filtered
+}
+
+// getStillPresentNote returns the note appended to the message of a throttled
+// report. The first maxStillPresentResources throttled resources are listed.
+// An empty string is returned when no resource is throttled.
+func getStillPresentNote(stillPresent []stillPresentResource) string {
+	if len(stillPresent) == 0 {
+		return ""
+	}
+
+	listed := make([]string, 0, maxStillPresentResources)
+	for i := range stillPresent {
+		if i == maxStillPresentResources {
+			break
+		}
+		resource := stillPresent[i].resource.Resource
+		name := resource.GetName()
+		if resource.GetNamespace() != "" {
+			name = resource.GetNamespace() + "/" + name
+		}
+		listed = append(listed, fmt.Sprintf("%s %s (%s)", resource.GetKind(), name,
+			getStillPresentMessage(stillPresent[i].since)))
+	}
+	note := fmt.Sprintf("\n%d resource(s) already notified: %s", len(stillPresent), strings.Join(listed, ", "))
+	if len(stillPresent) > maxStillPresentResources {
+		note += fmt.Sprintf(" and %d more", len(stillPresent)-maxStillPresentResources)
+	}
+	return note
+}
+
+func getStillPresentMessage(since metav1.Time) string {
+	return "still present (since " + since.UTC().Format(time.RFC3339) + ")"
+}
+
+// getNotifiedResource returns the tracked state of a resource first matched,
+// and notified, at now
+func getNotifiedResource(resource *ResourceResult, now time.Time) appsv1alpha1.NotifiedResource {
+	return appsv1alpha1.NotifiedResource{
+		APIVersion:   resource.Resource.GetAPIVersion(),
+		Kind:         resource.Resource.GetKind(),
+		Namespace:    resource.Resource.GetNamespace(),
+		Name:         resource.Resource.GetName(),
+		FirstMatched: metav1.NewTime(now),
+		LastNotified: metav1.NewTime(now),
+	}
+}
+
+func getNotifiedResourceKey(resource *appsv1alpha1.NotifiedResource) string {
+	return fmt.Sprintf("%s/%s/%s/%s", resource.APIVersion, resource.Kind, resource.Namespace, resource.Name)
+}
+
+// recordNotifiedResources stores in the Cleaner status the resources tracked by
+// throttle. Status is only updated when they change.
+func recordNotifiedResources(ctx context.Context, cleaner *appsv1alpha1.Cleaner, throttle *resourceThrottle) error {
+	if len(throttle.notified) == 0 && len(cleaner.Status.NotifiedResources) == 0 {
+		return nil
+	}
+	if reflect.DeepEqual(throttle.notified, cleaner.Status.NotifiedResources) {
+		return nil
+	}
+
+	return updateCleanerStatus(ctx, cleaner.Name, func(current *appsv1alpha1.Cleaner) {
+		current.Status.NotifiedResources = throttle.notified
+	})
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+// getNotifiedResource returns the tracked state of resource
+func getNotifiedResource(resource *executor.ResourceResult, firstMatched, lastNotified time.Time) appsv1alpha1.NotifiedResource {
+	return appsv1alpha1.NotifiedResource{
+		APIVersion:   resource.Resource.GetAPIVersion(),
+		Kind:         resource.Resource.GetKind(),
+		Namespace:    resource.Resource.GetNamespace(),
+		Name:         resource.Resource.GetName(),
+		FirstMatched: metav1.NewTime(firstMatched),
+		LastNotified: metav1.NewTime(lastNotified),
+	}
+}
+
+var _ = Describe("Resource throttling", func() {
+	It("sendNotifications collapses resources notified within the cooldown into a note", func() {
+		ref, fake := createSlackSecret()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.ResourceThrottling = &appsv1alpha1.ResourceThrottling{
+			Cooldown: metav1.Duration{Duration: time.Hour},
+		}
+
+		namespace := randomString()
+		throttled := getResourceResult("ConfigMap", namespace, randomString())
+		expired := getResourceResult("ConfigMap", namespace, randomString())
+		added := getResourceResult("ConfigMap", namespace, randomString())
+		firstMatched := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		cleaner.Status.NotifiedResources = []appsv1alpha1.NotifiedResource{
+			getNotifiedResource(&throttled, firstMatched, time.Now().Add(-10*time.Minute)),
+			getNotifiedResource(&expired, firstMatched, time.Now().Add(-2*time.Hour)),
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{throttled, expired, added},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(HavePrefix("k8s-cleaner '" + cleaner.Name +
+			"' performed Delete on 2 resources"))
+		Expect(fake.values[0].Get("text")).To(ContainSubstring("\n1 resource(s) already notified: ConfigMap " +
+			namespace + "/" + throttled.Resource.GetName() + " (still present (since 2024-01-02T03:04:05Z))"))
+		attachments := fake.values[0].Get("attachments")
+		Expect(attachments).ToNot(ContainSubstring(throttled.Resource.GetName()))
+		Expect(attachments).To(ContainSubstring(added.Resource.GetName()))
+		Expect(attachments).To(ContainSubstring(expired.Resource.GetName()))
+		Expect(attachments).To(ContainSubstring("still present (since 2024-01-02T03:04:05Z)"))
+	})
+
+	It("sendNotifications does not throttle failure notifications", func() {
+		ref, fake := createSlackSecret()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications[0].NotifyOnFailure = true
+		cleaner.Spec.ResourceThrottling = &appsv1alpha1.ResourceThrottling{
+			Cooldown: metav1.Duration{Duration: time.Hour},
+		}
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		cleaner.Status.NotifiedResources = []appsv1alpha1.NotifiedResource{
+			getNotifiedResource(&resource, time.Now(), time.Now()),
+		}
+
+		Expect(executor.SendRunNotifications(context.TODO(), []executor.ResourceResult{resource}, cleaner, "",
+			errors.New("list failed"), logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).ToNot(ContainSubstring("already notified"))
+		Expect(fake.values[0].Get("attachments")).To(ContainSubstring(resource.Resource.GetName()))
+	})
+
+	It("sendNotifications records notified resources in the Cleaner status", func() {
+		ref, fake := createSlackSecret()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Schedule = "0 * * * *"
+		cleaner.Spec.ResourcePolicySet.ResourceSelectors = []appsv1alpha1.ResourceSelector{
+			{Kind: "ConfigMap", Version: "v1"},
+		}
+		cleaner.Spec.ResourceThrottling = &appsv1alpha1.ResourceThrottling{
+			Cooldown: metav1.Duration{Duration: time.Hour},
+		}
+		Expect(k8sClient.Create(context.TODO(), cleaner)).To(Succeed())
+		Expect(waitForObject(context.TODO(), k8sClient, cleaner)).To(Succeed())
+
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		current := &appsv1alpha1.Cleaner{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, current)).To(Succeed())
+		Expect(current.Status.NotifiedResources).To(HaveLen(1))
+		Expect(current.Status.NotifiedResources[0].Name).To(Equal(resource.Resource.GetName()))
+		Expect(current.Status.NotifiedResources[0].Namespace).To(Equal(resource.Resource.GetNamespace()))
+
+		// Resource is throttled by following run, and no longer tracked once
+		// it is not matched anymore
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			current, "", logr.Discard())).To(Succeed())
+		Expect(fake.values).To(HaveLen(2))
+		Expect(fake.values[1].Get("text")).To(ContainSubstring("1 resource(s) already notified"))
+
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, current)).To(Succeed())
+		Expect(executor.SendNotifications(context.TODO(), nil, current, "", logr.Discard())).To(Succeed())
+
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, current)).To(Succeed())
+		Expect(current.Status.NotifiedResources).To(BeEmpty())
+	})
+})
@@ -0,0 +1,154 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+// notificationTLSConfigs are the TLS configurations defined in notification
+// Secrets, keyed by getTLSConfigKey. Guarded by transportMux.
+var notificationTLSConfigs = map[string]*tls.Config{}
+
+// getNotificationTLSConfig returns the TLS configuration defined in the notification
+// Secret: the client certificate presented for mutual TLS and the CA bundle used to
+// verify the server certificate, on top of the notification TLS settings. Nil is
+// returned if the Secret defines neither.
+// Secrets with the same certificates get the same configuration, so that they
+// share a transport. Returned configuration must not be modified.
+func getNotificationTLSConfig(secret *corev1.Secret) (*tls.Config, error) {
+	cert, hasCert := secret.Data[appsv1alpha1.TLSClientCert]
+	key, hasKey := secret.Data[appsv1alpha1.TLSClientKey]
+	ca, hasCA := secret.Data[appsv1alpha1.TLSCACert]
+	if !hasCert && !hasKey && !hasCA {
+		return nil, nil
+	}
+
+	configKey := getTLSConfigKey(cert, key, ca)
+	transportMux.Lock()
+	defer transportMux.Unlock()
+	if tlsConfig, ok := notificationTLSConfigs[configKey]; ok {
+		return tlsConfig, nil
+	}
+
+	tlsConfig, err := newSecretTLSConfig(cert, key, ca, hasCert, hasKey, hasCA)
+	if err != nil {
+		return nil, err
+	}
+	if len(notificationTLSConfigs) >= maxNotificationTLSTransports {
+		notificationTLSConfigs = map[string]*tls.Config{}
+	}
+	notificationTLSConfigs[configKey] = tlsConfig
+	return tlsConfig, nil
+}
+
+// getTLSConfigKey returns the key of the TLS configuration of a Secret with
+// the given client certificate, key and CA bundle
+func getTLSConfigKey(cert, key, ca []byte) string {
+	h := sha256.New()
+	for _, data := range [][]byte{cert, key, ca} {
+		fmt.Fprintf(h, "%d:", len(data))
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// newSecretTLSConfig returns the TLS configuration using the client certificate
+// and CA bundle of a Secret
+func newSecretTLSConfig(cert, key, ca []byte, hasCert, hasKey, hasCA bool) (*tls.Config, error) {
+	tlsConfig := newNotificationTLSConfig()
+
+	if hasCert != hasKey {
+		return nil, fmt.Errorf("secret must contain both %s and %s for mutual TLS",
+			appsv1alpha1.TLSClientCert, appsv1alpha1.TLSClientKey)
+	}
+	if hasCert {
+		certificate, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TLS client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	if hasCA {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("secret %s does not contain any valid PEM certificate",
+				appsv1alpha1.TLSCACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// newNotificationTLSHTTPClient returns an HTTP client using the notification
+// transport of tlsConfig, the shared one if nil. A zero timeout means no timeout.
+func newNotificationTLSHTTPClient(timeout time.Duration, tlsConfig *tls.Config) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: getNotificationTransport(tlsConfig, false),
+	}
+}
+
+// getTLSError returns an error describing the TLS handshake failure if err is
+// one. Other errors are returned unchanged.
+func getTLSError(err error) error {
+	var (
+		opErr           *net.OpError
+		verificationErr *tls.CertificateVerificationError
+		recordErr       tls.RecordHeaderError
+		urlErr          *url.Error
+	)
+
+	// TLS alerts sent by the server are reported as "remote error"
+	isAlert := errors.As(err, &opErr) && opErr.Op == "remote error"
+	if !isAlert && !errors.As(err, &verificationErr) && !errors.As(err, &recordErr) {
+		return err
+	}
+
+	host := "server"
+	if errors.As(err, &urlErr) {
+		if u, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+			host = u.Host
+		}
+	}
+
+	switch {
+	case errors.As(err, &verificationErr):
+		return fmt.Errorf("TLS handshake with %s failed: server certificate cannot be verified "+
+			"(set %s in the secret to trust its CA): %w", host, appsv1alpha1.TLSCACert, err)
+	case isAlert:
+		return fmt.Errorf("TLS handshake with %s failed: server rejected the connection, "+
+			"verify %s and %s in the secret: %w", host, appsv1alpha1.TLSClientCert, appsv1alpha1.TLSClientKey, err)
+	default:
+		return fmt.Errorf("TLS handshake with %s failed: %w", host, err)
+	}
+}
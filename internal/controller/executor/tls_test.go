@@ -0,0 +1,268 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	crand "crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+const tlsClientCommonName = "k8s-cleaner"
+
+// newMTLSServer starts an HTTPS server requiring a client certificate signed by
+// clientCA. Common names of the client certificates received are sent on
+// commonNames.
+func newMTLSServer(clientCA *x509.Certificate, commonNames chan<- string) *httptest.Server {
+	pool := x509.NewCertPool()
+	pool.AddCert(clientCA)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		commonNames <- r.TLS.PeerCertificates[0].Subject.CommonName
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+	}
+	server.StartTLS()
+	DeferCleanup(server.Close)
+	return server
+}
+
+// newTestCA returns a self signed CA certificate and its key
+func newTestCA() (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	Expect(err).To(BeNil())
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "k8s-cleaner-test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(crand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).To(BeNil())
+	cert, err := x509.ParseCertificate(der)
+	Expect(err).To(BeNil())
+	return cert, key
+}
+
+// newTestClientCert returns a PEM encoded client certificate, signed by ca, and
+// its PEM encoded key
+func newTestClientCert(ca *x509.Certificate, caKey *ecdsa.PrivateKey) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	Expect(err).To(BeNil())
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: tlsClientCommonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(crand.Reader, template, ca, &key.PublicKey, caKey)
+	Expect(err).To(BeNil())
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Expect(err).To(BeNil())
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func getServerCAPEM(server *httptest.Server) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+}
+
+var _ = Describe("Notification mutual TLS", func() {
+	var (
+		server      *httptest.Server
+		commonNames chan string
+		certPEM     []byte
+		keyPEM      []byte
+	)
+
+	BeforeEach(func() {
+		ca, caKey := newTestCA()
+		commonNames = make(chan string, 1)
+		server = newMTLSServer(ca, commonNames)
+		certPEM, keyPEM = newTestClientCert(ca, caKey)
+	})
+
+	It("sendNotifications presents client certificate to CloudEvents sink", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.CloudEventsSinkURL: []byte(server.URL),
+			appsv1alpha1.TLSClientCert:      certPEM,
+			appsv1alpha1.TLSClientKey:       keyPEM,
+			appsv1alpha1.TLSCACert:          getServerCAPEM(server),
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCloudEvents, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, randomString(), logr.Discard())).To(Succeed())
+		Expect(commonNames).To(Receive(Equal(tlsClientCommonName)))
+	})
+
+	It("sendNotifications presents client certificate to Splunk HEC", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.SplunkHECURL:   []byte(server.URL + "/services/collector/event"),
+			appsv1alpha1.SplunkHECToken: []byte(randomString()),
+			appsv1alpha1.TLSClientCert:  certPEM,
+			appsv1alpha1.TLSClientKey:   keyPEM,
+			appsv1alpha1.TLSCACert:      getServerCAPEM(server),
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSplunkHEC, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, randomString(), logr.Discard())).To(Succeed())
+		Expect(commonNames).To(Receive(Equal(tlsClientCommonName)))
+	})
+
+	It("sendNotifications reports TLS handshake failure when server rejects the client", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.CloudEventsSinkURL: []byte(server.URL),
+			appsv1alpha1.TLSCACert:          getServerCAPEM(server),
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCloudEvents, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		err := executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, randomString(), logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("TLS handshake with " + server.Listener.Addr().String() + " failed"))
+		Expect(err.Error()).To(ContainSubstring(appsv1alpha1.TLSClientCert))
+	})
+
+	It("sendNotifications reports TLS handshake failure when server certificate is not trusted", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.SplunkHECURL:   []byte(server.URL + "/services/collector/event"),
+			appsv1alpha1.SplunkHECToken: []byte(randomString()),
+			appsv1alpha1.TLSClientCert:  certPEM,
+			appsv1alpha1.TLSClientKey:   keyPEM,
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSplunkHEC, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		err := executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, randomString(), logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("server certificate cannot be verified"))
+		Expect(err.Error()).To(ContainSubstring(appsv1alpha1.TLSCACert))
+	})
+
+	It("sendNotifications fails when client key is missing", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.CloudEventsSinkURL: []byte(server.URL),
+			appsv1alpha1.TLSClientCert:      certPEM,
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCloudEvents, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		err := executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, randomString(), logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("secret must contain both"))
+		Expect(commonNames).ToNot(Receive())
+	})
+
+	It("sendNotifications fails when CA bundle is invalid", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.CloudEventsSinkURL: []byte(server.URL),
+			appsv1alpha1.TLSCACert:          []byte(randomString()),
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeCloudEvents, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		err := executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, randomString(), logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("does not contain any valid PEM certificate"))
+	})
+})
+
+var _ = Describe("Notification CA bundle", func() {
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	It("sendNotifications verifies Slack incoming webhook certificate with the CA bundle", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.SlackWebhookURL: []byte(server.URL + "/services/" + randomString()),
+			appsv1alpha1.TLSCACert:       getServerCAPEM(server),
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, randomString(), logr.Discard())).To(Succeed())
+	})
+
+	It("sendNotifications verifies Loki certificate with the CA bundle", func() {
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.LokiURL:   []byte(server.URL + "/loki/api/v1/push"),
+			appsv1alpha1.TLSCACert: getServerCAPEM(server),
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeLoki, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, randomString(), logr.Discard())).To(Succeed())
+	})
+
+	It("sendNotifications reports server certificate not trusted without the CA bundle", func() {
+		for notificationType, data := range map[appsv1alpha1.NotificationType]map[string][]byte{
+			appsv1alpha1.NotificationTypeSlack: {appsv1alpha1.SlackWebhookURL: []byte(server.URL + "/services/" + randomString())},
+			appsv1alpha1.NotificationTypeLoki:  {appsv1alpha1.LokiURL: []byte(server.URL + "/loki/api/v1/push")},
+		} {
+			cleaner := getCleanerWithNotification(notificationType, createNotificationSecret(data))
+			resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+			err := executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+				cleaner, randomString(), logr.Discard())
+			Expect(err).ToNot(BeNil(), "notification type %s", notificationType)
+			Expect(err.Error()).To(ContainSubstring("server certificate cannot be verified"))
+			Expect(err.Error()).To(ContainSubstring(appsv1alpha1.TLSCACert))
+		}
+	})
+})
@@ -0,0 +1,60 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+const (
+	tracerName = "gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	notifySpanName = "cleaner.notify"
+
+	attributeCleanerName      = "cleaner.name"
+	attributeNotificationName = "cleaner.notification.name"
+	attributeNotificationType = "cleaner.notification.type"
+	attributeResourceCount    = "cleaner.resource.count"
+	attributeRunID            = "cleaner.run.id"
+)
+
+// tracer uses the global TracerProvider. Spans are dropped unless the
+// process registers one (otel.SetTracerProvider).
+var tracer trace.Tracer = otel.Tracer(tracerName)
+
+// getNotificationSpanName returns the name of the span wrapping delivery of a
+// notification of the given type, for instance cleaner.notify.slack
+func getNotificationSpanName(notificationType appsv1alpha1.NotificationType) string {
+	return notifySpanName + "." + strings.ToLower(string(notificationType))
+}
+
+// endSpan sets span status based on err, records err if any, and ends span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetStatus(codes.Ok, "")
+	}
+	span.End()
+}
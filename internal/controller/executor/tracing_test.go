@@ -0,0 +1,101 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+var _ = Describe("Tracing", func() {
+	It("sendNotifications creates a span per notification", func() {
+		recorder := tracetest.NewSpanRecorder()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+		DeferCleanup(executor.SetTracer(provider.Tracer("test")))
+
+		slackRef := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.SlackChannelID: []byte(randomSlackChannelID()),
+			libsveltosv1alpha1.SlackToken:     []byte(randomString()),
+		})
+		DeferCleanup(executor.SetSlackClientFactory(func(token string) executor.SlackClient {
+			return &fakeSlackClient{}
+		}))
+
+		teamsRef := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.TeamsWebhookURL: []byte("https://example.webhook.office.com/" + randomString()),
+		})
+		teamsErr := fmt.Errorf("teams is unavailable")
+		DeferCleanup(executor.SetTeamsClientFactory(func() executor.TeamsClient {
+			return &fakeTeamsClient{err: teamsErr}
+		}))
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, slackRef)
+		cleaner.Spec.Notifications = append(cleaner.Spec.Notifications, appsv1alpha1.Notification{
+			Name:            randomString(),
+			Type:            appsv1alpha1.NotificationTypeTeams,
+			NotificationRef: teamsRef,
+		})
+		resources := []executor.ResourceResult{
+			getResourceResult("ConfigMap", randomString(), randomString()),
+			getResourceResult("Secret", randomString(), randomString()),
+		}
+
+		err := executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())
+		Expect(err).To(Equal(teamsErr))
+
+		spans := recorder.Ended()
+		Expect(len(spans)).To(Equal(3))
+
+		slackSpan, teamsSpan, notifySpan := spans[0], spans[1], spans[2]
+
+		Expect(notifySpan.Name()).To(Equal("cleaner.notify"))
+		Expect(notifySpan.Status().Code).To(Equal(codes.Error))
+		Expect(notifySpan.Attributes()).To(ContainElements(
+			attribute.String("cleaner.name", cleaner.Name),
+			attribute.Int("cleaner.resource.count", len(resources)),
+		))
+
+		Expect(slackSpan.Name()).To(Equal("cleaner.notify.slack"))
+		Expect(slackSpan.Parent().SpanID()).To(Equal(notifySpan.SpanContext().SpanID()))
+		Expect(slackSpan.Status().Code).To(Equal(codes.Ok))
+		Expect(slackSpan.Attributes()).To(ContainElements(
+			attribute.String("cleaner.name", cleaner.Name),
+			attribute.String("cleaner.notification.type", string(appsv1alpha1.NotificationTypeSlack)),
+			attribute.Int("cleaner.resource.count", len(resources)),
+		))
+
+		Expect(teamsSpan.Name()).To(Equal("cleaner.notify.teams"))
+		Expect(teamsSpan.Parent().SpanID()).To(Equal(notifySpan.SpanContext().SpanID()))
+		Expect(teamsSpan.Status().Code).To(Equal(codes.Error))
+		Expect(teamsSpan.Status().Description).To(Equal(teamsErr.Error()))
+		Expect(len(teamsSpan.Events())).To(Equal(1))
+		Expect(teamsSpan.Events()[0].Name).To(Equal("exception"))
+	})
+})
@@ -0,0 +1,107 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+)
+
+// notificationTLSVersions are the minimum TLS versions notification clients can
+// be configured with
+var notificationTLSVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var (
+	// notificationTLSMinVersion is the minimum TLS version of connections opened
+	// by notification clients
+	notificationTLSMinVersion uint16 = tls.VersionTLS12
+
+	// notificationCipherSuites, when not empty, restricts the cipher suites
+	// negotiated by notification clients for TLS 1.2 connections
+	notificationCipherSuites []uint16
+
+	// notificationDisableKeepAlives, when set, makes notification HTTP clients
+	// open a new connection for every request
+	notificationDisableKeepAlives bool
+)
+
+// SetNotificationTLSMinVersion sets the minimum TLS version, "1.2" or "1.3", of
+// connections opened by notification clients. An empty version restores the
+// default, TLS 1.2.
+func SetNotificationTLSMinVersion(version string) error {
+	if version == "" {
+		notificationTLSMinVersion = tls.VersionTLS12
+		resetNotificationTransports()
+		return nil
+	}
+
+	v, ok := notificationTLSVersions[version]
+	if !ok {
+		return fmt.Errorf("invalid notification TLS minimum version %q: must be 1.2 or 1.3", version)
+	}
+	notificationTLSMinVersion = v
+	resetNotificationTransports()
+	return nil
+}
+
+// SetNotificationCipherSuites restricts the cipher suites notification clients
+// negotiate for TLS 1.2 connections. names are IANA names (for instance
+// TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384) of cipher suites considered secure by
+// Go. TLS 1.3 cipher suites are not configurable. No names restores the default
+// cipher suites.
+func SetNotificationCipherSuites(names []string) error {
+	secure := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		secure[suite.Name] = suite.ID
+	}
+
+	suites := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := secure[strings.TrimSpace(name)]
+		if !ok {
+			return fmt.Errorf("invalid notification cipher suite %q: unknown or insecure cipher suite", name)
+		}
+		suites = append(suites, id)
+	}
+	if len(suites) == 0 {
+		suites = nil
+	}
+	notificationCipherSuites = suites
+	resetNotificationTransports()
+	return nil
+}
+
+// SetNotificationDisableKeepAlives sets whether notification HTTP clients open a
+// new connection for every request instead of reusing idle ones
+func SetNotificationDisableKeepAlives(disable bool) {
+	notificationDisableKeepAlives = disable
+	resetNotificationTransports()
+}
+
+// newNotificationTLSConfig returns the TLS configuration of notification clients,
+// which TLS settings defined in notification Secrets are added to
+func newNotificationTLSConfig() *tls.Config {
+	config := &tls.Config{MinVersion: notificationTLSMinVersion}
+	if len(notificationCipherSuites) > 0 {
+		config.CipherSuites = append([]uint16(nil), notificationCipherSuites...)
+	}
+	return config
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+var _ = Describe("Notification transport", func() {
+	BeforeEach(func() {
+		DeferCleanup(executor.ResetNotificationTransportSettings)
+	})
+
+	It("newNotificationTransport requires TLS 1.2 by default", func() {
+		transport := executor.NewNotificationTransport()
+		Expect(transport.TLSClientConfig).ToNot(BeNil())
+		Expect(transport.TLSClientConfig.MinVersion).To(Equal(uint16(tls.VersionTLS12)))
+		Expect(transport.TLSClientConfig.CipherSuites).To(BeEmpty())
+		Expect(transport.DisableKeepAlives).To(BeFalse())
+	})
+
+	It("newNotificationTransport uses the configured TLS and keep-alive settings", func() {
+		Expect(executor.SetNotificationTLSMinVersion("1.3")).To(Succeed())
+		Expect(executor.SetNotificationCipherSuites([]string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"})).To(Succeed())
+		executor.SetNotificationDisableKeepAlives(true)
+
+		transport := executor.NewNotificationTransport()
+		Expect(transport.TLSClientConfig.MinVersion).To(Equal(uint16(tls.VersionTLS13)))
+		Expect(transport.TLSClientConfig.CipherSuites).To(Equal([]uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}))
+		Expect(transport.DisableKeepAlives).To(BeTrue())
+	})
+
+	It("newNotificationTransport refuses servers below the minimum TLS version", func() {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+		server.StartTLS()
+		defer server.Close()
+
+		Expect(executor.SetNotificationTLSMinVersion("1.3")).To(Succeed())
+		transport := executor.NewNotificationTransport()
+		transport.TLSClientConfig.RootCAs = server.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+
+		_, err := (&http.Client{Transport: transport}).Get(server.URL)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("protocol version"))
+	})
+
+	It("SetNotificationTLSMinVersion rejects unsupported versions", func() {
+		for _, version := range []string{"1.0", "1.1", "TLS1.2", "2"} {
+			Expect(executor.SetNotificationTLSMinVersion(version)).ToNot(Succeed(), version)
+		}
+	})
+
+	It("SetNotificationCipherSuites rejects unknown and insecure cipher suites", func() {
+		Expect(executor.SetNotificationCipherSuites([]string{"TLS_UNKNOWN"})).ToNot(Succeed())
+		Expect(executor.SetNotificationCipherSuites([]string{"TLS_RSA_WITH_RC4_128_SHA"})).ToNot(Succeed())
+	})
+
+	It("notification HTTP clients share a transport, built again when settings change", func() {
+		transport := executor.GetNotificationTransport(nil)
+		Expect(executor.GetNotificationTransport(nil)).To(BeIdenticalTo(transport))
+
+		executor.SetNotificationDisableKeepAlives(true)
+		updated := executor.GetNotificationTransport(nil)
+		Expect(updated).ToNot(BeIdenticalTo(transport))
+		Expect(updated.DisableKeepAlives).To(BeTrue())
+	})
+
+	It("getNotificationTransport creates one transport per TLS configuration", func() {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		getTransport := func() *http.Transport {
+			secret := &corev1.Secret{Data: map[string][]byte{appsv1alpha1.TLSCACert: getServerCAPEM(server)}}
+			tlsConfig, err := executor.GetNotificationTLSConfig(secret)
+			Expect(err).To(BeNil())
+			return executor.GetNotificationTransport(tlsConfig)
+		}
+
+		transport := getTransport()
+		Expect(transport).ToNot(BeIdenticalTo(executor.GetNotificationTransport(nil)))
+		Expect(transport.TLSClientConfig.RootCAs).ToNot(BeNil())
+		Expect(getTransport()).To(BeIdenticalTo(transport))
+
+		_, err := (&http.Client{Transport: transport}).Get(server.URL)
+		Expect(err).To(BeNil())
+	})
+})
@@ -0,0 +1,149 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+// Maximum size, in bytes, of the report sent to each channel. Limits are
+// expressed in bytes, which is conservative for channels counting characters.
+const (
+	// Slack truncates message and attachment text longer than 40,000 characters
+	slackMaxReportSize = 40000
+
+	// Report is uploaded as a file. Slack limits files to 1GB
+	slackMaxFileSize = 1024 * 1024 * 1024
+
+	// Teams rejects webhook payloads bigger than ~28KB. The report is embedded
+	// (and escaped) in an adaptive card, so leave room for the card envelope.
+	teamsMaxReportSize = 20000
+
+	// Report is uploaded as a file. Default upload limit for a Discord server
+	discordMaxReportSize = 8 * 1024 * 1024
+
+	// Report is uploaded as a file. Webex limits files to 100MB
+	webexMaxReportSize = 100 * 1024 * 1024
+
+	// Most SMTP servers reject emails bigger than 10MB
+	smtpMaxReportSize = 10 * 1024 * 1024
+
+	// HEC default max_content_length is 1MB in older Splunk releases
+	splunkMaxEventSize = 1024 * 1024
+)
+
+// reportSizeLimits maps each notification type sending a serialized report
+// to the maximum size of that report
+var reportSizeLimits = map[appsv1alpha1.NotificationType]int{
+	appsv1alpha1.NotificationTypeSlack:     slackMaxReportSize,
+	appsv1alpha1.NotificationTypeTeams:     teamsMaxReportSize,
+	appsv1alpha1.NotificationTypeDiscord:   discordMaxReportSize,
+	appsv1alpha1.NotificationTypeWebex:     webexMaxReportSize,
+	appsv1alpha1.NotificationTypeSMTP:      smtpMaxReportSize,
+	appsv1alpha1.NotificationTypeSplunkHEC: splunkMaxEventSize,
+}
+
+// getTruncationMarker returns the marker appended to a report when omitted
+// resources were dropped
+func getTruncationMarker(omitted int) string {
+	return fmt.Sprintf("…(truncated, %d resources omitted)", omitted)
+}
+
+// truncateReport serializes reportSpec as JSON using encoding. If the result exceeds limit bytes,
+// trailing resources are dropped and a marker with the number of omitted resources
+// is appended, so that the returned value is never longer than limit.
+func truncateReport(reportSpec *appsv1alpha1.ReportSpec, limit int, encoding reportEncoding) (string, error) {
+	data, err := marshalReport(reportSpec, encoding)
+	if err != nil {
+		return "", err
+	}
+	if len(data) <= limit {
+		return string(data), nil
+	}
+
+	size := func(spec *appsv1alpha1.ReportSpec, omitted int) (int, error) {
+		data, err := marshalReport(spec, encoding)
+		if err != nil {
+			return 0, err
+		}
+		return len(data) + len(getTruncationMarker(omitted)), nil
+	}
+
+	truncated, omitted, err := fitReportSpec(reportSpec, limit, size)
+	if err != nil {
+		return "", err
+	}
+
+	data, err = marshalReport(truncated, encoding)
+	if err != nil {
+		return "", err
+	}
+
+	result := string(data) + getTruncationMarker(omitted)
+	if len(result) > limit {
+		// Not even an empty report fits. Cut it keeping valid UTF-8
+		result = result[:limit]
+		for !utf8.ValidString(result) {
+			result = result[:len(result)-1]
+		}
+	}
+	return result, nil
+}
+
+// fitReportSpec returns a copy of reportSpec containing the largest number of
+// leading resources so that size is at most limit, along with the number of
+// resources omitted. size is given the candidate ReportSpec and the number of
+// resources omitted from it and returns the size of the resulting payload.
+func fitReportSpec(reportSpec *appsv1alpha1.ReportSpec, limit int,
+	size func(spec *appsv1alpha1.ReportSpec, omitted int) (int, error)) (*appsv1alpha1.ReportSpec, int, error) {
+
+	total := len(reportSpec.ResourceInfo)
+	candidate := func(kept int) *appsv1alpha1.ReportSpec {
+		return &appsv1alpha1.ReportSpec{
+			SchemaVersion: reportSpec.SchemaVersion,
+			Action:        reportSpec.Action,
+			ResourceInfo:  reportSpec.ResourceInfo[:kept],
+			RunID:         reportSpec.RunID,
+			Reason:        reportSpec.Reason,
+			Summary:       reportSpec.Summary,
+			CleanerSpec:   reportSpec.CleanerSpec,
+		}
+	}
+
+	var sizeErr error
+	// Find the first number of kept resources for which payload does not fit.
+	kept := sort.Search(total+1, func(kept int) bool {
+		if sizeErr != nil {
+			return true
+		}
+		var s int
+		s, sizeErr = size(candidate(kept), total-kept)
+		return s > limit
+	})
+	if sizeErr != nil {
+		return nil, 0, sizeErr
+	}
+
+	if kept > 0 {
+		kept--
+	}
+	return candidate(kept), total - kept, nil
+}
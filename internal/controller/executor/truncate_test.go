@@ -0,0 +1,213 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+const (
+	truncationTestResources = 5
+)
+
+var _ = Describe("Truncation", func() {
+	It("truncateReport does not truncate reports at exactly the channel limit", func() {
+		limit := getSmallestReportSizeLimit()
+		reportSpec := getReportSpecOfSize(limit)
+
+		result, err := executor.TruncateReport(reportSpec, limit, executor.CompactJSON)
+		Expect(err).To(BeNil())
+		Expect(len(result)).To(Equal(limit))
+		Expect(result).ToNot(ContainSubstring("truncated"))
+
+		currentReportSpec := &appsv1alpha1.ReportSpec{}
+		Expect(json.Unmarshal([]byte(result), currentReportSpec)).To(Succeed())
+		Expect(len(currentReportSpec.ResourceInfo)).To(Equal(truncationTestResources))
+	})
+
+	It("truncateReport truncates reports one byte over the channel limit", func() {
+		limit := getSmallestReportSizeLimit()
+		reportSpec := getReportSpecOfSize(limit + 1)
+
+		result, err := executor.TruncateReport(reportSpec, limit, executor.CompactJSON)
+		Expect(err).To(BeNil())
+		Expect(len(result)).To(BeNumerically("<=", limit))
+
+		marker := executor.GetTruncationMarker(1)
+		Expect(result).To(HaveSuffix(marker))
+		Expect(marker).To(Equal("…(truncated, 1 resources omitted)"))
+
+		currentReportSpec := &appsv1alpha1.ReportSpec{}
+		Expect(json.Unmarshal([]byte(strings.TrimSuffix(result, marker)), currentReportSpec)).To(Succeed())
+		Expect(len(currentReportSpec.ResourceInfo)).To(Equal(truncationTestResources - 1))
+	})
+
+	It("truncateReport keeps summary counting all resources", func() {
+		reportSpec := getReportSpecOfSize(1000)
+		reportSpec.Summary = &appsv1alpha1.ReportSummary{
+			Total:  truncationTestResources,
+			ByKind: map[string]int32{"ConfigMap": truncationTestResources},
+		}
+		const limit = 600
+
+		result, err := executor.TruncateReport(reportSpec, limit, executor.CompactJSON)
+		Expect(err).To(BeNil())
+
+		currentReportSpec := &appsv1alpha1.ReportSpec{}
+		Expect(json.Unmarshal([]byte(result[:strings.LastIndex(result, "}")+1]), currentReportSpec)).To(Succeed())
+		Expect(len(currentReportSpec.ResourceInfo)).To(BeNumerically("<", truncationTestResources))
+		Expect(currentReportSpec.Summary).To(Equal(reportSpec.Summary))
+	})
+
+	It("truncateReport keeps schema version", func() {
+		reportSpec := getReportSpecOfSize(1000)
+		reportSpec.SchemaVersion = appsv1alpha1.ReportSchemaVersion
+		const limit = 600
+
+		result, err := executor.TruncateReport(reportSpec, limit, executor.CompactJSON)
+		Expect(err).To(BeNil())
+		Expect(result).To(ContainSubstring("truncated"))
+		Expect(result).To(ContainSubstring(`"schemaVersion":1`))
+	})
+
+	It("truncateReport never exceeds limit even when no resource fits", func() {
+		reportSpec := getReportSpecOfSize(1000)
+		const limit = 20
+
+		result, err := executor.TruncateReport(reportSpec, limit, executor.CompactJSON)
+		Expect(err).To(BeNil())
+		Expect(len(result)).To(BeNumerically("<=", limit))
+		Expect(utf8.ValidString(result)).To(BeTrue())
+	})
+
+	It("truncateReport indents reports read by humans and never exceeds limit", func() {
+		reportSpec := getReportSpecOfSize(1000)
+
+		result, err := executor.TruncateReport(reportSpec, 2000, executor.IndentedJSON)
+		Expect(err).To(BeNil())
+		Expect(result).To(HavePrefix("{\n  \"resourceInfo\": [\n"))
+		currentReportSpec := &appsv1alpha1.ReportSpec{}
+		Expect(json.Unmarshal([]byte(result), currentReportSpec)).To(Succeed())
+		Expect(currentReportSpec.ResourceInfo).To(HaveLen(truncationTestResources))
+
+		// Indentation is accounted for when truncating
+		const limit = 1000
+		result, err = executor.TruncateReport(reportSpec, limit, executor.IndentedJSON)
+		Expect(err).To(BeNil())
+		Expect(len(result)).To(BeNumerically("<=", limit))
+		Expect(result).To(ContainSubstring("truncated"))
+	})
+
+	It("getReportEncoding indents reports read by humans only", func() {
+		for _, notificationType := range []appsv1alpha1.NotificationType{
+			appsv1alpha1.NotificationTypeSlack,
+			appsv1alpha1.NotificationTypeDiscord,
+			appsv1alpha1.NotificationTypeWebex,
+			appsv1alpha1.NotificationTypeSMTP,
+			appsv1alpha1.NotificationTypeFile,
+		} {
+			Expect(executor.GetReportEncoding(notificationType)).To(Equal(executor.IndentedJSON), string(notificationType))
+		}
+		for _, notificationType := range []appsv1alpha1.NotificationType{
+			appsv1alpha1.NotificationTypeTeams,
+			appsv1alpha1.NotificationTypeSplunkHEC,
+			appsv1alpha1.NotificationTypeCloudEvents,
+		} {
+			Expect(executor.GetReportEncoding(notificationType)).To(Equal(executor.CompactJSON), string(notificationType))
+		}
+	})
+
+	It("getSplunkEventData drops resources when event exceeds HEC limit", func() {
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSplunkHEC, nil)
+		notification := &cleaner.Spec.Notifications[0]
+
+		// Event contains the report plus the event envelope
+		const smallReportSize = 1000
+		data, err := executor.GetSplunkEventData(cleaner, getReportSpecOfSize(smallReportSize), notification)
+		Expect(err).To(BeNil())
+		envelopeSize := len(data) - smallReportSize
+
+		// Resize report so that the event is exactly at the limit
+		reportSpec := getReportSpecOfSize(executor.SplunkMaxEventSize - envelopeSize)
+		data, err = executor.GetSplunkEventData(cleaner, reportSpec, notification)
+		Expect(err).To(BeNil())
+		Expect(len(data)).To(Equal(executor.SplunkMaxEventSize))
+		Expect(string(data)).ToNot(ContainSubstring("truncated"))
+
+		reportSpec = getReportSpecOfSize(executor.SplunkMaxEventSize - envelopeSize + 1)
+		data, err = executor.GetSplunkEventData(cleaner, reportSpec, notification)
+		Expect(err).To(BeNil())
+		Expect(len(data)).To(BeNumerically("<=", executor.SplunkMaxEventSize))
+
+		event := map[string]interface{}{}
+		Expect(json.Unmarshal(data, &event)).To(Succeed())
+		fields, ok := event["fields"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(fields["truncated"]).To(Equal(executor.GetTruncationMarker(1)))
+	})
+})
+
+// getSmallestReportSizeLimit returns the smallest channel limit. Truncation does
+// not depend on the channel, so boundaries are verified against this limit only
+// instead of building reports as big as the largest (100MB) limit.
+func getSmallestReportSizeLimit() int {
+	smallest := 0
+	for _, limit := range executor.GetReportSizeLimits() {
+		if smallest == 0 || limit < smallest {
+			smallest = limit
+		}
+	}
+	return smallest
+}
+
+// getReportSpecOfSize returns a ReportSpec with truncationTestResources resources
+// whose JSON representation is exactly size bytes. Padding is added to the last
+// resource.
+func getReportSpecOfSize(size int) *appsv1alpha1.ReportSpec {
+	reportSpec := &appsv1alpha1.ReportSpec{Action: appsv1alpha1.ActionDelete}
+	for i := 0; i < truncationTestResources; i++ {
+		reportSpec.ResourceInfo = append(reportSpec.ResourceInfo, appsv1alpha1.ResourceInfo{
+			Resource: corev1.ObjectReference{
+				Kind:       "ConfigMap",
+				APIVersion: "v1",
+				Namespace:  randomString(),
+				Name:       randomString(),
+			},
+		})
+	}
+
+	data, err := json.Marshal(*reportSpec)
+	Expect(err).To(BeNil())
+	// An empty message is omitted, so account for the "message" key also
+	padding := size - len(data) - len(`,"message":""`)
+	Expect(padding).To(BeNumerically(">", 0))
+	reportSpec.ResourceInfo[truncationTestResources-1].Message = strings.Repeat("a", padding)
+
+	data, err = json.Marshal(*reportSpec)
+	Expect(err).To(BeNil())
+	Expect(len(data)).To(Equal(size))
+	return reportSpec
+}
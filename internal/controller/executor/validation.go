@@ -0,0 +1,130 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/robfig/cron/v3"
+	lua "github.com/yuin/gopher-lua"
+	"k8s.io/apimachinery/pkg/labels"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+// ValidateCleaner verifies the parts of the cleaner configuration which can be
+// checked without running it: schedule, namespace selectors and Lua scripts of
+// the resource selectors, aggregated selection and transform, and the settings
+// of each notification. The returned error lists every problem found, in spec
+// order. Nil is returned if cleaner is valid.
+func ValidateCleaner(cleaner *appsv1alpha1.Cleaner) error {
+	var problems []string
+	if _, err := cron.ParseStandard(cleaner.Spec.Schedule); err != nil {
+		problems = append(problems, fmt.Sprintf("unparseable schedule %q: %v", cleaner.Spec.Schedule, err))
+	}
+
+	policySet := &cleaner.Spec.ResourcePolicySet
+	for i := range policySet.ResourceSelectors {
+		selector := &policySet.ResourceSelectors[i]
+		if selector.NamespaceSelector != "" {
+			if _, err := labels.Parse(selector.NamespaceSelector); err != nil {
+				problems = append(problems, fmt.Sprintf("resourceSelectors[%d]: invalid namespaceSelector: %v", i, err))
+			}
+		}
+		if err := validateLuaScript(selector.Evaluate); err != nil {
+			problems = append(problems, fmt.Sprintf("resourceSelectors[%d]: invalid evaluate script: %v", i, err))
+		}
+	}
+	if err := validateLuaScript(policySet.AggregatedSelection); err != nil {
+		problems = append(problems, fmt.Sprintf("invalid aggregatedSelection script: %v", err))
+	}
+	if err := validateLuaScript(cleaner.Spec.Transform); err != nil {
+		problems = append(problems, fmt.Sprintf("invalid transform script: %v", err))
+	}
+
+	for i := range cleaner.Spec.Notifications {
+		notification := &cleaner.Spec.Notifications[i]
+		if err := validateNotification(notification); err != nil {
+			problems = append(problems, err.Error())
+		}
+		// Previous run is read from the Report instance
+		if notification.IncludeDelta && !hasReportNotification(cleaner) {
+			problems = append(problems, fmt.Sprintf("notification %s: includeDelta requires an enabled %s notification",
+				notification.Name, appsv1alpha1.NotificationTypeCleanerReport))
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(problems, "; "))
+}
+
+// validateLuaScript verifies script compiles. Script is not run.
+func validateLuaScript(script string) error {
+	if script == "" {
+		return nil
+	}
+
+	l := lua.NewState()
+	defer l.Close()
+	if _, err := l.LoadString(script); err != nil {
+		// Syntax errors end with a new line
+		return errors.New(strings.TrimSpace(err.Error()))
+	}
+	return nil
+}
+
+// validateNotification verifies notification type, time zone, resource filter,
+// namespace routes and templates. Credentials are only verified when the
+// notification is sent. The returned error names the notification.
+func validateNotification(notification *appsv1alpha1.Notification) error {
+	if _, err := getNotificationLocation(notification); err != nil {
+		return err
+	}
+	if _, err := getNotifier(notification.Type); err != nil {
+		return fmt.Errorf("notification %s: %w", notification.Name, err)
+	}
+	if _, err := getResourceFilter(notification.ResourceFilter); err != nil {
+		return fmt.Errorf("notification %s: %w", notification.Name, err)
+	}
+	if _, err := getNamespaceRoutes(notification); err != nil {
+		return fmt.Errorf("notification %s: %w", notification.Name, err)
+	}
+	if notification.MessageTemplate != "" {
+		if _, err := parseMessageTemplate(notification.MessageTemplate); err != nil {
+			return fmt.Errorf("notification %s: %w", notification.Name, err)
+		}
+	}
+	templates := []struct{ name, text string }{
+		{name: "channelTemplate", text: notification.ChannelTemplate},
+		{name: "attachmentNameTemplate", text: notification.AttachmentNameTemplate},
+		{name: "linkTemplate", text: notification.LinkTemplate},
+	}
+	for _, t := range templates {
+		if t.text == "" {
+			continue
+		}
+		if _, err := template.New(t.name).Parse(t.text); err != nil {
+			return fmt.Errorf("notification %s: invalid %s: %w", notification.Name, t.name, err)
+		}
+	}
+	return nil
+}
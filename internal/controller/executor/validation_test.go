@@ -0,0 +1,113 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/tools/record"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+// getValidCleaner returns a Cleaner with a valid schedule and selector
+func getValidCleaner() *appsv1alpha1.Cleaner {
+	cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, nil)
+	cleaner.Spec.Schedule = "0 * * * *"
+	cleaner.Spec.ResourcePolicySet.ResourceSelectors = []appsv1alpha1.ResourceSelector{
+		{
+			Kind:              "ConfigMap",
+			Version:           "v1",
+			NamespaceSelector: "env=prod",
+			Evaluate:          "function evaluate() return {matching = true} end",
+		},
+	}
+	return cleaner
+}
+
+var _ = Describe("Cleaner validation", func() {
+	It("ValidateCleaner accepts a valid Cleaner", func() {
+		Expect(executor.ValidateCleaner(getValidCleaner())).To(Succeed())
+	})
+
+	It("ValidateCleaner lists every configuration error in spec order", func() {
+		cleaner := getValidCleaner()
+		cleaner.Spec.Schedule = "every hour"
+		cleaner.Spec.ResourcePolicySet.ResourceSelectors[0].NamespaceSelector = "env in (prod"
+		cleaner.Spec.ResourcePolicySet.ResourceSelectors[0].Evaluate = "function evaluate("
+		cleaner.Spec.Notifications[0].Timezone = "Mars/Olympus"
+		cleaner.Spec.Notifications = append(cleaner.Spec.Notifications, appsv1alpha1.Notification{
+			Name:            randomString(),
+			Type:            appsv1alpha1.NotificationTypeSlack,
+			MessageTemplate: "{{ .Cleaner",
+		})
+
+		err := executor.ValidateCleaner(cleaner)
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(MatchRegexp(`^unparseable schedule "every hour": .*; ` +
+			`resourceSelectors\[0\]: invalid namespaceSelector: .*; ` +
+			`resourceSelectors\[0\]: invalid evaluate script: .*; ` +
+			`notification ` + cleaner.Spec.Notifications[0].Name + `: invalid timezone "Mars/Olympus".*; ` +
+			`notification ` + cleaner.Spec.Notifications[1].Name + `: invalid message template: .*`))
+	})
+
+	It("SetAdminNotification rejects invalid settings", func() {
+		DeferCleanup(executor.SetAdminNotification, "", "")
+
+		Expect(executor.SetAdminNotification(string(appsv1alpha1.NotificationTypeCleanerReport),
+			"ns/name")).ToNot(Succeed())
+		Expect(executor.SetAdminNotification("Pigeon", "ns/name")).ToNot(Succeed())
+		Expect(executor.SetAdminNotification(string(appsv1alpha1.NotificationTypeSlack), "name")).ToNot(Succeed())
+		Expect(executor.SetAdminNotification(string(appsv1alpha1.NotificationTypeSlack), "ns/name")).To(Succeed())
+	})
+
+	It("SendValidationErrorNotification sends the error to the admin notification", func() {
+		ref, fake := createSlackSecret()
+		Expect(executor.SetAdminNotification(string(appsv1alpha1.NotificationTypeSlack),
+			ref.Namespace+"/"+ref.Name)).To(Succeed())
+		DeferCleanup(executor.SetAdminNotification, "", "")
+		recorder := record.NewFakeRecorder(1)
+		DeferCleanup(executor.SetEventRecorder(recorder))
+
+		cleaner := getValidCleaner()
+		validationErr := errors.New("invalid transform script: " + randomString())
+		Expect(executor.SendValidationErrorNotification(context.TODO(), cleaner, validationErr,
+			logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(HavePrefix("k8s-cleaner instance " + cleaner.Name +
+			" is misconfigured and may not run as expected: " + validationErr.Error()))
+		Expect(recorder.Events).To(Receive(Equal("Warning CleanerInvalid " + validationErr.Error())))
+	})
+
+	It("SendValidationErrorNotification only records an Event when no admin notification is set", func() {
+		_, fake := createSlackSecret()
+		recorder := record.NewFakeRecorder(1)
+		DeferCleanup(executor.SetEventRecorder(recorder))
+
+		Expect(executor.SendValidationErrorNotification(context.TODO(), getValidCleaner(), errors.New(randomString()),
+			logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(BeEmpty())
+		Expect(recorder.Events).To(Receive(HavePrefix("Warning CleanerInvalid ")))
+	})
+})
@@ -0,0 +1,198 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+const (
+	victorOpsRequestTimeout = 30 * time.Second
+
+	// maximum number of bytes of the VictorOps response included in errors
+	maxVictorOpsResponseBody = 4096
+
+	// victorOpsMaxStateMessage is the maximum length of the alert state message
+	victorOpsMaxStateMessage = 10000
+
+	victorOpsMonitoringTool = "k8s-cleaner"
+)
+
+// VictorOps message types
+// https://help.victorops.com/knowledge-base/rest-endpoint-integration-guide/
+const (
+	victorOpsMessageCritical = "CRITICAL"
+	victorOpsMessageWarning  = "WARNING"
+	victorOpsMessageInfo     = "INFO"
+	victorOpsMessageRecovery = "RECOVERY"
+)
+
+// victorOpsURL is the VictorOps REST endpoint. API key and routing key are
+// appended to it.
+var victorOpsURL = "https://alert.victorops.com/integrations/generic/20131114/alert"
+
+type victorOpsInfo struct {
+	apiKey     string
+	routingKey string
+}
+
+// victorOpsAlert is the body of a VictorOps REST endpoint alert
+type victorOpsAlert struct {
+	MessageType       string `json:"message_type"`
+	EntityID          string `json:"entity_id"`
+	EntityDisplayName string `json:"entity_display_name"`
+	StateMessage      string `json:"state_message"`
+	MonitoringTool    string `json:"monitoring_tool"`
+	RunID             string `json:"run_id,omitempty"`
+}
+
+func init() {
+	registerNotifier(appsv1alpha1.NotificationTypeVictorOps, notifierFunc(
+		func(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+			_ []ResourceResult, message string, notification *appsv1alpha1.Notification, logger logr.Logger) error {
+
+			return sendVictorOpsNotification(ctx, cleaner, reportSpec, message, notification, logger)
+		}))
+}
+
+func sendVictorOpsNotification(ctx context.Context, cleaner *appsv1alpha1.Cleaner,
+	reportSpec *appsv1alpha1.ReportSpec, message string, notification *appsv1alpha1.Notification,
+	logger logr.Logger) error {
+
+	info, err := getVictorOpsInfo(ctx, notification)
+	if err != nil {
+		return err
+	}
+
+	l := logger.WithValues(logKeyChannel, info.routingKey)
+	l.V(logs.LogInfo).Info("send victorops alert")
+
+	data, err := json.Marshal(getVictorOpsAlert(cleaner, reportSpec, message))
+	if err != nil {
+		l.Error(err, "failed to marshal victorops alert")
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/%s/%s", victorOpsURL, url.PathEscape(info.apiKey), url.PathEscape(info.routingKey))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := newNotificationHTTPClient(victorOpsRequestTimeout).Do(req)
+	if err != nil {
+		// URL contains the API key. Leave it out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = fmt.Errorf("%s victorops REST endpoint: %w", urlErr.Op, urlErr.Err)
+		}
+		l.Error(err, logMsgSendFailed)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxVictorOpsResponseBody))
+		err = fmt.Errorf("victorops returned %s: %s", resp.Status, string(body))
+		l.Error(err, logMsgSendFailed)
+		return err
+	}
+
+	l.V(logs.LogDebug).Info("victorops alert sent")
+	return nil
+}
+
+// getVictorOpsAlert returns the alert for reportSpec. Entity ID is derived from
+// the Cleaner name, so alerts of subsequent runs of the same Cleaner are
+// deduplicated in a single incident. State message contains message followed by
+// the resource summary and the failed resources, if any.
+func getVictorOpsAlert(cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	message string) *victorOpsAlert {
+
+	sections := []string{message, getResourceSummary(reportSpec, true)}
+	if resources := getTopResourcesMarkdown(reportSpec); resources != "" {
+		sections = append(sections, resources)
+	}
+	if failures := getFailedResourcesMarkdown(reportSpec, victorOpsMaxStateMessage); failures != "" {
+		sections = append(sections, strings.Replace(failures, "**", "", 2))
+	}
+
+	return &victorOpsAlert{
+		MessageType:       getVictorOpsMessageType(reportSpec),
+		EntityID:          fmt.Sprintf("k8s-cleaner/%s", cleaner.Name),
+		EntityDisplayName: fmt.Sprintf("%s: %s", cleaner.Name, reportSpec.Action),
+		StateMessage:      truncateString(strings.Join(sections, "\n\n"), victorOpsMaxStateMessage),
+		MonitoringTool:    victorOpsMonitoringTool,
+		RunID:             reportSpec.RunID,
+	}
+}
+
+// getVictorOpsMessageType returns the message type keyed to the action severity.
+// Failed runs are critical. Runs with no resources resolve the incident opened by
+// previous runs.
+func getVictorOpsMessageType(reportSpec *appsv1alpha1.ReportSpec) string {
+	if reportSpec.Error != "" || getFailedResourceCount(reportSpec) > 0 {
+		return victorOpsMessageCritical
+	}
+	if len(reportSpec.ResourceInfo) == 0 {
+		return victorOpsMessageRecovery
+	}
+
+	switch reportSpec.Action {
+	case appsv1alpha1.ActionDelete:
+		return victorOpsMessageCritical
+	case appsv1alpha1.ActionTransform:
+		return victorOpsMessageWarning
+	default:
+		return victorOpsMessageInfo
+	}
+}
+
+func getVictorOpsInfo(ctx context.Context, notification *appsv1alpha1.Notification) (*victorOpsInfo, error) {
+	secret, err := getSecret(ctx, notification)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey, ok := secret.Data[appsv1alpha1.VictorOpsAPIKey]
+	if !ok || len(apiKey) == 0 {
+		return nil, fmt.Errorf("secret does not contain victorops API key")
+	}
+
+	routingKey, ok := secret.Data[appsv1alpha1.VictorOpsRoutingKey]
+	if !ok || len(routingKey) == 0 {
+		return nil, fmt.Errorf("secret does not contain victorops routing key")
+	}
+
+	return &victorOpsInfo{apiKey: string(apiKey), routingKey: string(routingKey)}, nil
+}
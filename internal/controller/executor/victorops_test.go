@@ -0,0 +1,125 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+var _ = Describe("VictorOps", func() {
+	It("sendNotifications sends alert to the VictorOps REST endpoint", func() {
+		var path string
+		var payload map[string]interface{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			body, err := io.ReadAll(r.Body)
+			Expect(err).To(BeNil())
+			Expect(json.Unmarshal(body, &payload)).To(Succeed())
+			_, _ = w.Write([]byte(`{"result":"success"}`))
+		}))
+		DeferCleanup(server.Close)
+		DeferCleanup(executor.SetVictorOpsURL(server.URL + "/alert"))
+
+		apiKey := randomString()
+		routingKey := randomString()
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.VictorOpsAPIKey:     []byte(apiKey),
+			appsv1alpha1.VictorOpsRoutingKey: []byte(routingKey),
+		})
+
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeVictorOps, ref)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+		runID := randomString()
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, runID, logr.Discard())).To(Succeed())
+
+		Expect(path).To(Equal("/alert/" + apiKey + "/" + routingKey))
+		Expect(payload["message_type"]).To(Equal("CRITICAL"))
+		Expect(payload["entity_id"]).To(Equal("k8s-cleaner/" + cleaner.Name))
+		Expect(payload["entity_display_name"]).To(Equal(cleaner.Name + ": Delete"))
+		Expect(payload["monitoring_tool"]).To(Equal("k8s-cleaner"))
+		Expect(payload["run_id"]).To(Equal(runID))
+		Expect(payload["state_message"]).To(ContainSubstring("Resources: 1 (ConfigMap: 1)"))
+		Expect(payload["state_message"]).To(ContainSubstring(resource.Resource.GetName()))
+	})
+
+	It("sendNotifications returns VictorOps error", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"result":"failure","message":"Missing fields"}`))
+		}))
+		DeferCleanup(server.Close)
+		DeferCleanup(executor.SetVictorOpsURL(server.URL))
+
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.VictorOpsAPIKey:     []byte(randomString()),
+			appsv1alpha1.VictorOpsRoutingKey: []byte(randomString()),
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeVictorOps, ref)
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("400 Bad Request"))
+		Expect(err.Error()).To(ContainSubstring("Missing fields"))
+	})
+
+	It("sendNotifications does not leak VictorOps API key in errors", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		server.Close()
+		DeferCleanup(executor.SetVictorOpsURL(server.URL))
+
+		apiKey := randomString()
+		ref := createNotificationSecret(map[string][]byte{
+			appsv1alpha1.VictorOpsAPIKey:     []byte(apiKey),
+			appsv1alpha1.VictorOpsRoutingKey: []byte(randomString()),
+		})
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeVictorOps, ref)
+
+		err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).ToNot(ContainSubstring(apiKey))
+	})
+
+	It("getVictorOpsMessageType is keyed to action severity", func() {
+		reportSpec := &appsv1alpha1.ReportSpec{Action: appsv1alpha1.ActionScan}
+		Expect(executor.GetVictorOpsMessageType(reportSpec)).To(Equal("RECOVERY"))
+
+		reportSpec.ResourceInfo = []appsv1alpha1.ResourceInfo{{}}
+		Expect(executor.GetVictorOpsMessageType(reportSpec)).To(Equal("INFO"))
+
+		reportSpec.Action = appsv1alpha1.ActionTransform
+		Expect(executor.GetVictorOpsMessageType(reportSpec)).To(Equal("WARNING"))
+
+		reportSpec.Action = appsv1alpha1.ActionDelete
+		Expect(executor.GetVictorOpsMessageType(reportSpec)).To(Equal("CRITICAL"))
+
+		reportSpec = &appsv1alpha1.ReportSpec{Action: appsv1alpha1.ActionScan, Error: randomString()}
+		Expect(executor.GetVictorOpsMessageType(reportSpec)).To(Equal("CRITICAL"))
+	})
+})
@@ -0,0 +1,264 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/atc0005/go-teams-notify/v2/adaptivecard"
+	"github.com/go-resty/resty/v2"
+	webexteams "github.com/jbogarin/go-cisco-webex-teams/sdk"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+const (
+	webexAPIURL = "https://webexapis.com/v1"
+
+	// webexCardVersion is the latest adaptive card version rendered by Webex
+	webexCardVersion = "1.3"
+
+	// webexMaxCardResources is the maximum number of resources listed in a card
+	webexMaxCardResources = 20
+)
+
+// getWebexMessage returns the Webex message for reportSpec: message followed by
+// a summary of the resources and the failed resources, if any. As markdown,
+// Transform diffs are included as well. As plain text, markdown formatting is
+// left out and diffs are only part of the attached report.
+func getWebexMessage(reportSpec *appsv1alpha1.ReportSpec, message, roomID string,
+	notification *appsv1alpha1.Notification) *webexteams.MessageCreateRequest {
+
+	plainText := notification.Webex != nil && notification.Webex.Format == appsv1alpha1.WebexFormatText
+
+	sections := []string{message, getResourceSummary(reportSpec, plainText)}
+	if failures := getFailedResourcesMarkdown(reportSpec, webexMaxFailuresSize); failures != "" {
+		if plainText {
+			failures = strings.Replace(failures, "**", "", 2)
+		}
+		sections = append(sections, failures)
+	}
+	if plainText {
+		return &webexteams.MessageCreateRequest{
+			Text:   strings.Join(sections, "\n\n"),
+			RoomID: roomID,
+		}
+	}
+
+	if diff := getDiffMarkdown(reportSpec, webexMaxDiffSize); diff != "" {
+		sections = append(sections, diff)
+	}
+	return &webexteams.MessageCreateRequest{
+		Markdown: strings.Join(sections, "\n\n"),
+		RoomID:   roomID,
+	}
+}
+
+// addWebexInlineReport adds reportSpec to webexMessage, truncated to the room
+// left by the rest of the message. In plain text the report is not wrapped in a
+// code block.
+func addWebexInlineReport(webexMessage *webexteams.MessageCreateRequest, reportSpec *appsv1alpha1.ReportSpec,
+	notification *appsv1alpha1.Notification) error {
+
+	const separator = "\n\n"
+	if webexMessage.Markdown == "" {
+		limit := webexMaxMessageSize - len(webexMessage.Text) - len(separator)
+		if limit <= 0 {
+			return nil
+		}
+		report, err := truncateReport(reportSpec, limit, getReportEncoding(notification.Type))
+		if err != nil {
+			return err
+		}
+		webexMessage.Text += separator + report
+		return nil
+	}
+
+	report, err := getInlineReport(reportSpec, webexMaxMessageSize-len(webexMessage.Markdown)-len(separator),
+		getReportEncoding(notification.Type))
+	if err != nil || report == "" {
+		return err
+	}
+	webexMessage.Markdown += separator + report
+	return nil
+}
+
+// isWebexCardNotification returns true if the report must be sent as an adaptive card
+func isWebexCardNotification(notification *appsv1alpha1.Notification) bool {
+	return notification.Webex != nil && notification.Webex.Format == appsv1alpha1.WebexFormatCard
+}
+
+// getWebexCard returns the adaptive card for reportSpec: message as title, the
+// action and number of resources, failed resources, if any, followed by the
+// resources, up to webexMaxCardResources. Metadata and links are rendered as
+// for Teams.
+func getWebexCard(cleanerName string, reportSpec *appsv1alpha1.ReportSpec, message string,
+	notification *appsv1alpha1.Notification) (*webexteams.Attachment, error) {
+
+	links, err := getNotificationLinks(cleanerName, reportSpec, notification)
+	if err != nil {
+		return nil, err
+	}
+
+	card := adaptivecard.NewCard()
+	card.Version = webexCardVersion
+	if err := card.AddElement(false, adaptivecard.NewTitleTextBlock(message, true)); err != nil {
+		return nil, err
+	}
+
+	summary := adaptivecard.NewFactSet()
+	if reportSpec.Action != "" {
+		if err := summary.AddFact(adaptivecard.Fact{Title: "Action", Value: string(reportSpec.Action)}); err != nil {
+			return nil, err
+		}
+	}
+	if err := summary.AddFact(adaptivecard.Fact{Title: "Resources",
+		Value: fmt.Sprintf("%d", len(reportSpec.ResourceInfo))}); err != nil {
+		return nil, err
+	}
+	if err := card.AddFactSet(false, summary); err != nil {
+		return nil, err
+	}
+
+	if failures := getFailedResourcesMarkdown(reportSpec, webexMaxFailuresSize); failures != "" {
+		failuresBlock := adaptivecard.NewTextBlock(failures, true)
+		failuresBlock.Color = adaptivecard.ColorAttention
+		if err := card.AddElement(false, failuresBlock); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(reportSpec.ResourceInfo) > 0 {
+		resources := adaptivecard.NewFactSet()
+		for i := range reportSpec.ResourceInfo {
+			if i == webexMaxCardResources {
+				break
+			}
+			resource := &reportSpec.ResourceInfo[i].Resource
+			name := resource.Name
+			if resource.Namespace != "" {
+				name = resource.Namespace + "/" + name
+			}
+			if err := resources.AddFact(adaptivecard.Fact{Title: resource.Kind, Value: name}); err != nil {
+				return nil, err
+			}
+		}
+		if err := card.AddFactSet(false, resources); err != nil {
+			return nil, err
+		}
+		if omitted := len(reportSpec.ResourceInfo) - webexMaxCardResources; omitted > 0 {
+			if err := card.AddElement(false, adaptivecard.NewTextBlock(
+				fmt.Sprintf("... and %d more resource(s)", omitted), true)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := addCardMetadata(&card, notification.Metadata); err != nil {
+		return nil, err
+	}
+	if err := addCardLinks(&card, links); err != nil {
+		return nil, err
+	}
+	if err := card.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Webex expects the card as a JSON object
+	data, err := json.Marshal(card)
+	if err != nil {
+		return nil, err
+	}
+	content := map[string]interface{}{}
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, err
+	}
+	return &webexteams.Attachment{ContentType: adaptivecard.AttachmentContentType, Content: content}, nil
+}
+
+// webexMessagesClient creates Webex messages. It mirrors the Webex SDK
+// MessagesService, which does not allow setting the HTTP transport, so that
+// requests go through the notification proxy.
+type webexMessagesClient struct {
+	client *resty.Client
+}
+
+func newWebexMessagesClient(token string) *webexMessagesClient {
+	client := resty.New().
+		SetTransport(getNotificationTransport(nil, false)).
+		SetHostURL(webexAPIURL).
+		SetAuthToken(token)
+	return &webexMessagesClient{client: client}
+}
+
+// CreateMessage posts a message, and its file if any, to a room or person
+func (c *webexMessagesClient) CreateMessage(messageCreateRequest *webexteams.MessageCreateRequest,
+) (*webexteams.Message, *resty.Response, error) {
+
+	request := c.client.R()
+
+	if len(messageCreateRequest.Attachments) == 0 {
+		formData := map[string]string{}
+		if messageCreateRequest.RoomID != "" {
+			formData["roomId"] = messageCreateRequest.RoomID
+		}
+		if messageCreateRequest.ParentID != "" {
+			formData["parentId"] = messageCreateRequest.ParentID
+		}
+		if messageCreateRequest.Markdown != "" {
+			formData["markdown"] = messageCreateRequest.Markdown
+		}
+		if messageCreateRequest.Text != "" {
+			formData["text"] = messageCreateRequest.Text
+		}
+		if messageCreateRequest.ToPersonEmail != "" {
+			formData["toPersonEmail"] = messageCreateRequest.ToPersonEmail
+		}
+		if messageCreateRequest.ToPersonID != "" {
+			formData["toPersonId"] = messageCreateRequest.ToPersonID
+		}
+		if len(messageCreateRequest.Files) > 1 {
+			return nil, nil, errors.New("multi file attachment is not supported")
+		}
+		for _, file := range messageCreateRequest.Files {
+			if file.RemoteFileURI != "" {
+				formData["files"] = file.RemoteFileURI
+			} else if file.Reader != nil {
+				request.SetMultipartField("files", file.Name, file.ContentType, file.Reader)
+			}
+		}
+		request.SetMultipartFormData(formData)
+	} else {
+		if len(messageCreateRequest.Files) > 0 {
+			return nil, nil, errors.New("sending files with attachment is not supported")
+		}
+		request.SetBody(messageCreateRequest)
+	}
+
+	response, err := request.
+		SetResult(&webexteams.Message{}).
+		SetError(&webexteams.Error{}).
+		Post("/messages/")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return response.Result().(*webexteams.Message), response, nil
+}
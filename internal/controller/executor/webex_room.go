@@ -0,0 +1,145 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	webexteams "github.com/jbogarin/go-cisco-webex-teams/sdk"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+)
+
+const (
+	// webexIDPrefix is the prefix of decoded Webex IDs
+	webexIDPrefix = "ciscospark://"
+
+	// webexRoomsPageSize is the number of rooms requested per page
+	webexRoomsPageSize = 1000
+
+	// webexMaxRoomPages is the maximum number of pages of rooms listed to
+	// resolve a room title
+	webexMaxRoomPages = 10
+
+	// maximum number of bytes of the Webex response included in errors
+	maxWebexResponseBody = 4096
+)
+
+// webexNextPageRegexp extracts the URL of the next page from a Link header
+var webexNextPageRegexp = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// getWebexRoomID returns the ID of the room. Room is used as is when it is a
+// Webex room ID. Otherwise it is a room title, resolved using the rooms the
+// token has access to. Resolved titles are cached for channelIDCacheTTL.
+func getWebexRoomID(api webexClient, token, room string, now time.Time) (string, error) {
+	if isWebexRoomID(room) {
+		return room, nil
+	}
+
+	if roomID, ok := getCachedChannelID(appsv1alpha1.NotificationTypeWebex, token, room, now); ok {
+		return roomID, nil
+	}
+
+	rooms, err := api.ListRooms()
+	if err != nil {
+		return "", fmt.Errorf("failed to list webex rooms: %w", err)
+	}
+
+	var matching []string
+	for i := range rooms {
+		if rooms[i].Title == room {
+			matching = append(matching, rooms[i].ID)
+		}
+	}
+	switch len(matching) {
+	case 0:
+		return "", fmt.Errorf("no webex room with title %q found", room)
+	case 1:
+	default:
+		return "", fmt.Errorf("%d webex rooms with title %q found: use the room ID instead", len(matching), room)
+	}
+
+	cacheChannelID(appsv1alpha1.NotificationTypeWebex, token, room, matching[0], now)
+	return matching[0], nil
+}
+
+// resolveWebexRoomID returns a client for the Webex token and the ID of the
+// room messages are sent to, resolving the room title when needed
+func resolveWebexRoomID(info *webexInfo) (webexClient, string, error) {
+	client := newWebexClient(info.token)
+	if client == nil {
+		return nil, "", fmt.Errorf("failed to get webexClient client")
+	}
+
+	roomID, err := getWebexRoomID(client, info.token, info.room, time.Now())
+	if err != nil {
+		return nil, "", err
+	}
+	return client, roomID, nil
+}
+
+// isWebexRoomID returns true if value is a Webex room ID, the base64 encoding
+// of a ciscospark URI
+func isWebexRoomID(value string) bool {
+	value = strings.TrimRight(value, "=")
+	for _, encoding := range []*base64.Encoding{base64.RawStdEncoding, base64.RawURLEncoding} {
+		if decoded, err := encoding.DecodeString(value); err == nil {
+			return strings.HasPrefix(string(decoded), webexIDPrefix)
+		}
+	}
+	return false
+}
+
+// ListRooms returns the rooms the token has access to, following pagination
+func (c *webexMessagesClient) ListRooms() ([]webexteams.Room, error) {
+	var rooms []webexteams.Room
+	url := fmt.Sprintf("/rooms?max=%d", webexRoomsPageSize)
+	for page := 0; url != ""; page++ {
+		if page == webexMaxRoomPages {
+			return nil, fmt.Errorf("webex rooms exceed %d pages", webexMaxRoomPages)
+		}
+
+		response, err := c.client.R().
+			SetResult(&webexteams.Rooms{}).
+			Get(url)
+		if err != nil {
+			return nil, err
+		}
+		if response.IsError() {
+			return nil, fmt.Errorf("webex returned %s: %s", response.Status(),
+				truncateString(string(response.Body()), maxWebexResponseBody))
+		}
+
+		rooms = append(rooms, response.Result().(*webexteams.Rooms).Items...)
+		url = getWebexNextPage(response.Header().Get("Link"))
+	}
+	return rooms, nil
+}
+
+// getWebexNextPage returns the URL of the next page in a Link header, or an
+// empty string when there is none
+func getWebexNextPage(link string) string {
+	match := webexNextPageRegexp.FindStringSubmatch(link)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
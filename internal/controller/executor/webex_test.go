@@ -0,0 +1,175 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-logr/logr"
+	webexteams "github.com/jbogarin/go-cisco-webex-teams/sdk"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+
+	libsveltosv1alpha1 "github.com/projectsveltos/libsveltos/api/v1alpha1"
+)
+
+// getWebexCardCleaner returns a Cleaner with a Webex notification sending cards
+func getWebexCardCleaner() (*appsv1alpha1.Cleaner, *fakeWebexClient) {
+	ref := createNotificationSecret(map[string][]byte{
+		libsveltosv1alpha1.WebexRoomID: []byte(randomWebexRoomID()),
+		libsveltosv1alpha1.WebexToken:  []byte(randomString()),
+	})
+	fake := &fakeWebexClient{}
+	useFakeWebexClient(fake)
+
+	cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeWebex, ref)
+	cleaner.Spec.Notifications[0].Webex = &appsv1alpha1.WebexOptions{Format: appsv1alpha1.WebexFormatCard}
+	return cleaner, fake
+}
+
+var _ = Describe("Webex", func() {
+	It("sendNotifications sends Webex report as adaptive card with markdown fallback", func() {
+		cleaner, fake := getWebexCardCleaner()
+		cleaner.Spec.Notifications[0].Metadata = map[string]string{"team": "platform"}
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.requests).To(HaveLen(1))
+		Expect(fake.requests[0].Markdown).To(ContainSubstring(cleaner.Name))
+		Expect(fake.files).To(BeEmpty())
+		Expect(fake.requests[0].Attachments).To(HaveLen(1))
+		attachment := fake.requests[0].Attachments[0]
+		Expect(attachment.ContentType).To(Equal("application/vnd.microsoft.card.adaptive"))
+		Expect(attachment.Content["version"]).To(Equal("1.3"))
+
+		data, err := json.Marshal(attachment.Content)
+		Expect(err).To(BeNil())
+		card := string(data)
+		Expect(card).To(ContainSubstring("k8s-cleaner '" + cleaner.Name + "' performed Delete on 1 resource"))
+		Expect(card).To(ContainSubstring(`{"title":"Action","value":"Delete"}`))
+		Expect(card).To(ContainSubstring(`{"title":"ConfigMap","value":"` + resource.Resource.GetNamespace() + "/" +
+			resource.Resource.GetName() + `"}`))
+		Expect(card).To(ContainSubstring(`{"title":"team","value":"platform"}`))
+	})
+
+	It("sendNotifications lists a limited number of resources in Webex card", func() {
+		cleaner, fake := getWebexCardCleaner()
+		resources := make([]executor.ResourceResult, 0)
+		for i := 0; i < 25; i++ {
+			resources = append(resources, getResourceResult("ConfigMap", randomString(), randomString()))
+		}
+
+		Expect(executor.SendNotifications(context.TODO(), resources, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.requests).To(HaveLen(1))
+		data, err := json.Marshal(fake.requests[0].Attachments[0].Content)
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(ContainSubstring("... and 5 more resource(s)"))
+	})
+
+	It("sendNotifications resolves a Webex room title to its ID once", func() {
+		title := randomString()
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.WebexRoomID: []byte(title),
+			libsveltosv1alpha1.WebexToken:  []byte(randomString()),
+		})
+		roomID := randomWebexRoomID()
+		fake := &fakeWebexClient{rooms: []webexteams.Room{
+			{ID: randomWebexRoomID(), Title: randomString()},
+			{ID: roomID, Title: title},
+		}}
+		useFakeWebexClient(fake)
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeWebex, ref)
+
+		for i := 0; i < 2; i++ {
+			Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+		}
+
+		Expect(fake.requests).To(HaveLen(2))
+		for i := range fake.requests {
+			Expect(fake.requests[i].RoomID).To(Equal(roomID))
+		}
+		Expect(fake.listCalls).To(Equal(1))
+	})
+
+	It("sendNotifications uses a Webex room ID as is", func() {
+		roomID := randomWebexRoomID()
+		ref := createNotificationSecret(map[string][]byte{
+			libsveltosv1alpha1.WebexRoomID: []byte(roomID),
+			libsveltosv1alpha1.WebexToken:  []byte(randomString()),
+		})
+		fake := &fakeWebexClient{}
+		useFakeWebexClient(fake)
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeWebex, ref)
+
+		Expect(executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.requests).To(HaveLen(1))
+		Expect(fake.requests[0].RoomID).To(Equal(roomID))
+		Expect(fake.listCalls).To(BeZero())
+	})
+
+	It("sendNotifications fails when no Webex room, or more than one, has the title", func() {
+		title := randomString()
+		for _, rooms := range [][]webexteams.Room{
+			{{ID: randomWebexRoomID(), Title: randomString()}},
+			{{ID: randomWebexRoomID(), Title: title}, {ID: randomWebexRoomID(), Title: title}},
+		} {
+			ref := createNotificationSecret(map[string][]byte{
+				libsveltosv1alpha1.WebexRoomID: []byte(title),
+				libsveltosv1alpha1.WebexToken:  []byte(randomString()),
+			})
+			fake := &fakeWebexClient{rooms: rooms}
+			useFakeWebexClient(fake)
+			cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeWebex, ref)
+
+			err := executor.SendNotifications(context.TODO(), nil, cleaner, "", logr.Discard())
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("webex room"))
+			Expect(err.Error()).To(ContainSubstring(title))
+			Expect(fake.requests).To(BeEmpty())
+		}
+	})
+
+	It("getWebexNextPage returns the next page of a Link header", func() {
+		next := "https://webexapis.com/v1/rooms?max=1000&cursor=" + randomString()
+		Expect(executor.GetWebexNextPage(`<` + next + `>; rel="next"`)).To(Equal(next))
+		Expect(executor.GetWebexNextPage("")).To(BeEmpty())
+		Expect(executor.GetWebexNextPage(`<https://webexapis.com/v1/rooms>; rel="prev"`)).To(BeEmpty())
+	})
+
+	It("sendNotifications falls back to Webex markdown message when card cannot be rendered", func() {
+		cleaner, fake := getWebexCardCleaner()
+		// Adaptive card facts cannot have empty values
+		cleaner.Spec.Notifications[0].Metadata = map[string]string{"team": ""}
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.requests).To(HaveLen(1))
+		Expect(fake.requests[0].Attachments).To(BeEmpty())
+		Expect(fake.requests[0].Markdown).To(ContainSubstring(cleaner.Name))
+		Expect(fake.files).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,121 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+
+	logs "github.com/projectsveltos/libsveltos/lib/logsettings"
+)
+
+const (
+	// defaultWindowEndHour is the hour the active window closes when EndHour is not set
+	defaultWindowEndHour = 24
+)
+
+// isQueueWindowNotification returns true if reports of runs outside the active
+// window of notification are queued
+func isQueueWindowNotification(notification *appsv1alpha1.Notification) bool {
+	return notification.ActiveWindow != nil &&
+		notification.ActiveWindow.Policy == appsv1alpha1.NotificationWindowPolicyQueue
+}
+
+// isInNotificationWindow returns true if notification can be sent at now, that
+// is when it has no active window or now, in the notification time zone, is
+// within it
+func isInNotificationWindow(notification *appsv1alpha1.Notification, now time.Time) (bool, error) {
+	window := notification.ActiveWindow
+	if window == nil {
+		return true, nil
+	}
+
+	location, err := getNotificationLocation(notification)
+	if err != nil {
+		return false, err
+	}
+	now = now.In(location)
+
+	endHour := int(window.EndHour)
+	if endHour == 0 {
+		endHour = defaultWindowEndHour
+	}
+	startHour := int(window.StartHour)
+	hour := now.Hour()
+
+	if startHour < endHour {
+		return hour >= startHour && hour < endHour && isWindowDay(window, now.Weekday()), nil
+	}
+	// Window spans midnight. Hours after midnight belong to the window opened
+	// the day before.
+	if hour >= startHour {
+		return isWindowDay(window, now.Weekday()), nil
+	}
+	if hour < endHour {
+		return isWindowDay(window, now.AddDate(0, 0, -1).Weekday()), nil
+	}
+	return false, nil
+}
+
+// isWindowDay returns true if window opens on day
+func isWindowDay(window *appsv1alpha1.NotificationWindow, day time.Weekday) bool {
+	if len(window.Days) == 0 {
+		return true
+	}
+	for i := range window.Days {
+		if string(window.Days[i]) == day.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// hasQueuedReports returns true if reports of runs outside the active window of
+// the notification are waiting to be sent
+func hasQueuedReports(cleaner *appsv1alpha1.Cleaner, notificationName string) bool {
+	for i := range cleaner.Status.NotificationDigests {
+		if cleaner.Status.NotificationDigests[i].NotificationName == notificationName {
+			return cleaner.Status.NotificationDigests[i].Runs > 0
+		}
+	}
+	return false
+}
+
+// queueReport accumulates reportSpec, of a run outside the active window of
+// notification, in the Cleaner status. Queued reports are stored as a digest.
+func queueReport(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	notification *appsv1alpha1.Notification, now time.Time, logger logr.Logger) error {
+
+	digest := getNotificationDigest(cleaner, notification.Name, now)
+	addToDigest(digest, reportSpec)
+	logger.V(logs.LogDebug).Info("report queued outside active window", logKeyRuns, digest.Runs)
+	return updateNotificationDigest(ctx, cleaner.Name, digest)
+}
+
+// sendQueuedReports delivers a single notification combining reportSpec with
+// the reports queued while outside the active window of notification
+func sendQueuedReports(ctx context.Context, cleaner *appsv1alpha1.Cleaner, reportSpec *appsv1alpha1.ReportSpec,
+	notification *appsv1alpha1.Notification, now time.Time, logger logr.Logger) error {
+
+	digest := getNotificationDigest(cleaner, notification.Name, now)
+	addToDigest(digest, reportSpec)
+	return sendDigest(ctx, cleaner, reportSpec, digest, notification, now, logger)
+}
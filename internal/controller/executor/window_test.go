@@ -0,0 +1,162 @@
+/*
+Copyright 2023. projectsveltos.io. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+
+	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
+	"gianlucam76/k8s-cleaner/internal/controller/executor"
+)
+
+// getClosedWindow returns an active window which is closed for the whole day
+func getClosedWindow(policy appsv1alpha1.NotificationWindowPolicy) *appsv1alpha1.NotificationWindow {
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Weekday()
+	return &appsv1alpha1.NotificationWindow{
+		Days:   []appsv1alpha1.Weekday{appsv1alpha1.Weekday(tomorrow.String())},
+		Policy: policy,
+	}
+}
+
+var _ = Describe("Active window", func() {
+	It("isInNotificationWindow evaluates days and hours in the notification time zone", func() {
+		notification := &appsv1alpha1.Notification{
+			Timezone: "Europe/Rome",
+			ActiveWindow: &appsv1alpha1.NotificationWindow{
+				Days:      []appsv1alpha1.Weekday{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"},
+				StartHour: 9,
+				EndHour:   17,
+			},
+		}
+
+		// Wednesday 2024-05-15
+		for utcHour, expected := range map[int]bool{6: false, 7: true, 14: true, 15: false} {
+			inWindow, err := executor.IsInNotificationWindow(notification,
+				time.Date(2024, time.May, 15, utcHour, 30, 0, 0, time.UTC))
+			Expect(err).To(BeNil())
+			Expect(inWindow).To(Equal(expected), "UTC hour %d", utcHour)
+		}
+
+		// Saturday 2024-05-18
+		inWindow, err := executor.IsInNotificationWindow(notification,
+			time.Date(2024, time.May, 18, 10, 0, 0, 0, time.UTC))
+		Expect(err).To(BeNil())
+		Expect(inWindow).To(BeFalse())
+	})
+
+	It("isInNotificationWindow supports windows spanning midnight", func() {
+		notification := &appsv1alpha1.Notification{
+			ActiveWindow: &appsv1alpha1.NotificationWindow{
+				Days:      []appsv1alpha1.Weekday{"Friday"},
+				StartHour: 22,
+				EndHour:   6,
+			},
+		}
+
+		for date, expected := range map[time.Time]bool{
+			time.Date(2024, time.May, 17, 23, 0, 0, 0, time.UTC): true,  // Friday night
+			time.Date(2024, time.May, 18, 3, 0, 0, 0, time.UTC):  true,  // Saturday morning
+			time.Date(2024, time.May, 17, 3, 0, 0, 0, time.UTC):  false, // Friday morning
+			time.Date(2024, time.May, 18, 12, 0, 0, 0, time.UTC): false,
+		} {
+			inWindow, err := executor.IsInNotificationWindow(notification, date)
+			Expect(err).To(BeNil())
+			Expect(inWindow).To(Equal(expected), "time %s", date)
+		}
+	})
+
+	It("isInNotificationWindow defaults to a window open the whole day", func() {
+		notification := &appsv1alpha1.Notification{ActiveWindow: &appsv1alpha1.NotificationWindow{}}
+
+		inWindow, err := executor.IsInNotificationWindow(notification, time.Date(2024, time.May, 18, 23, 59, 0, 0, time.UTC))
+		Expect(err).To(BeNil())
+		Expect(inWindow).To(BeTrue())
+	})
+
+	It("sendNotifications suppresses notifications outside the active window", func() {
+		ref, fake := createSlackSecret()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications[0].ActiveWindow = getClosedWindow(appsv1alpha1.NotificationWindowPolicySuppress)
+		resource := getResourceResult("ConfigMap", randomString(), randomString())
+
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{resource},
+			cleaner, "", logr.Discard())).To(Succeed())
+		Expect(fake.values).To(BeEmpty())
+	})
+
+	It("sendRunNotifications sends failure notifications outside the active window", func() {
+		ref, fake := createSlackSecret()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Notifications[0].NotifyOnFailure = true
+		cleaner.Spec.Notifications[0].ActiveWindow = getClosedWindow(appsv1alpha1.NotificationWindowPolicySuppress)
+
+		Expect(executor.SendRunNotifications(context.TODO(), nil, cleaner, "", errors.New("list failed"),
+			logr.Discard())).To(Succeed())
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(HavePrefix("Execution failed for k8s-cleaner instance"))
+	})
+
+	It("sendNotifications queues reports outside the active window and sends them once it opens", func() {
+		ref, fake := createSlackSecret()
+		cleaner := getCleanerWithNotification(appsv1alpha1.NotificationTypeSlack, ref)
+		cleaner.Spec.Schedule = "0 * * * *"
+		cleaner.Spec.ResourcePolicySet.ResourceSelectors = []appsv1alpha1.ResourceSelector{
+			{Kind: "ConfigMap", Version: "v1"},
+		}
+		cleaner.Spec.Notifications[0].ActiveWindow = getClosedWindow(appsv1alpha1.NotificationWindowPolicyQueue)
+		Expect(k8sClient.Create(context.TODO(), cleaner)).To(Succeed())
+		Expect(waitForObject(context.TODO(), k8sClient, cleaner)).To(Succeed())
+
+		first := getResourceResult("ConfigMap", randomString(), randomString())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{first},
+			cleaner, "", logr.Discard())).To(Succeed())
+		Expect(fake.values).To(BeEmpty())
+
+		current := &appsv1alpha1.Cleaner{}
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, current)).To(Succeed())
+		Expect(current.Status.NotificationDigests).To(HaveLen(1))
+		Expect(current.Status.NotificationDigests[0].Runs).To(Equal(int32(1)))
+		Expect(current.Status.NotificationOutcomes).To(HaveLen(1))
+		Expect(current.Status.NotificationOutcomes[0].Outcome).To(Equal(appsv1alpha1.NotificationOutcomeQueued))
+
+		// Window opens
+		current.Spec.Notifications[0].ActiveWindow.Days = nil
+		Expect(k8sClient.Update(context.TODO(), current)).To(Succeed())
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, current)).To(Succeed())
+
+		second := getResourceResult("ConfigMap", randomString(), randomString())
+		Expect(executor.SendNotifications(context.TODO(), []executor.ResourceResult{second},
+			current, "", logr.Discard())).To(Succeed())
+
+		Expect(fake.values).To(HaveLen(1))
+		Expect(fake.values[0].Get("text")).To(ContainSubstring("2 run(s) since"))
+		attachments := fake.values[0].Get("attachments")
+		Expect(attachments).To(ContainSubstring(first.Resource.GetName()))
+		Expect(attachments).To(ContainSubstring(second.Resource.GetName()))
+
+		Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Name: cleaner.Name}, current)).To(Succeed())
+		Expect(current.Status.NotificationDigests).To(HaveLen(1))
+		Expect(current.Status.NotificationDigests[0].Runs).To(BeZero())
+	})
+})
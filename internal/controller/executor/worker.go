@@ -33,10 +33,12 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	appsv1alpha1 "gianlucam76/k8s-cleaner/api/v1alpha1"
@@ -71,17 +73,35 @@ type ResourceResult struct {
 	// Message is an optional field.
 	// +optional
 	Message string `json:"message,omitempty"`
+
+	// Diff is the unified diff of the resource before and after a
+	// transformation
+	// +optional
+	Diff string `json:"diff,omitempty"`
+
+	// Outcome is the outcome of the action taken on the resource
+	// +optional
+	Outcome appsv1alpha1.ResourceOutcome `json:"outcome,omitempty"`
+
+	// Error is the error returned when the action failed on the resource
+	// +optional
+	Error string `json:"error,omitempty"`
+
+	// Backup is the backup of the resource taken before deleting it
+	// +optional
+	Backup *appsv1alpha1.BackupReference `json:"backup,omitempty"`
 }
 
 type responseParams struct {
 	cleanerName string
+	runID       string
 	err         error
 }
 
 var (
-	k8sClient client.Client
-	config    *rest.Config
-	scheme    *runtime.Scheme
+	config        *rest.Config
+	scheme        *runtime.Scheme
+	eventRecorder record.EventRecorder
 )
 
 const (
@@ -111,11 +131,13 @@ func processRequests(ctx context.Context, i int, logger logr.Logger) {
 
 	for {
 		if cleanerName != nil {
-			l := logger.WithValues("cleaner", cleanerName)
+			// runID correlates logs, notifications and status for this run
+			runID := string(uuid.NewUUID())
+			l := logger.WithValues("cleaner", cleanerName, "runID", runID)
 			// Get error only from getIsCleanupFromKey as same key is always used
 			l.Info(fmt.Sprintf("worker: %d processing request", id))
-			err := processCleanerInstance(ctx, *cleanerName, l)
-			storeResult(*cleanerName, err, l)
+			err := processCleanerInstance(ctx, *cleanerName, runID, l)
+			storeResult(*cleanerName, runID, err, l)
 			l.Info(fmt.Sprintf("worker: %d request processed", id))
 		}
 		cleanerName = nil
@@ -149,7 +171,7 @@ func processRequests(ctx context.Context, i int, logger logr.Logger) {
 	}
 }
 
-func processCleanerInstance(ctx context.Context, cleanerName string, logger logr.Logger) error {
+func processCleanerInstance(ctx context.Context, cleanerName, runID string, logger logr.Logger) error {
 	cleaner, err := getCleanerInstance(ctx, cleanerName)
 	if err != nil {
 		logger.Info(fmt.Sprintf("failed to get cleaner instance: %v", err))
@@ -168,6 +190,7 @@ func processCleanerInstance(ctx context.Context, cleanerName string, logger logr
 		if err != nil {
 			logger.Info(fmt.Sprintf("failed to fetch resource (gvk: %s): %v",
 				fmt.Sprintf("%s:%s:%s", selector.Group, selector.Version, selector.Kind), err))
+			notifyRunFailure(ctx, cleaner, runID, err, logger)
 			return err
 		}
 		resources = append(resources, tmpResources...)
@@ -178,6 +201,7 @@ func processCleanerInstance(ctx context.Context, cleanerName string, logger logr
 			logger)
 		if err != nil {
 			logger.Info(fmt.Sprintf("failed to filter aggregated resources: %v", err))
+			notifyRunFailure(ctx, cleaner, runID, err, logger)
 			return err
 		}
 	}
@@ -185,7 +209,7 @@ func processCleanerInstance(ctx context.Context, cleanerName string, logger logr
 	var processedResources []ResourceResult
 	switch cleaner.Spec.Action {
 	case appsv1alpha1.ActionDelete:
-		processedResources, err = deleteMatchingResources(ctx, resources, cleaner.Spec.DeleteOptions, logger)
+		processedResources, err = deleteMatchingResources(ctx, resources, cleaner, logger)
 	case appsv1alpha1.ActionTransform:
 		processedResources, err = updateMatchingResources(ctx, resources, cleaner.Spec.Transform, logger)
 	case appsv1alpha1.ActionScan:
@@ -194,11 +218,19 @@ func processCleanerInstance(ctx context.Context, cleanerName string, logger logr
 	}
 
 	// Send notification irrespective of err
-	sendErr := sendNotifications(ctx, processedResources, cleaner, logger)
+	sendErr := sendRunNotifications(ctx, processedResources, cleaner, runID, err, logger)
 	if sendErr != nil {
 		return sendErr
 	}
 
+	// Recorded once notifications are delivered, so a resolved notification
+	// which failed is sent again by next run
+	if err == nil {
+		if recordErr := recordMatchCount(ctx, cleaner, len(processedResources)); recordErr != nil {
+			logger.Error(recordErr, logMsgRecordStatusFailed)
+		}
+	}
+
 	// Store resources before any action was taken irrespective of err
 	storeErr := storeResources(processedResources, scheme, cleaner, logger)
 	if storeErr != nil {
@@ -208,6 +240,17 @@ func processCleanerInstance(ctx context.Context, cleanerName string, logger logr
 	return err
 }
 
+// notifyRunFailure sends a failure report to notifications requesting it, when
+// the run failed before any resource was processed. The run error is returned
+// to the caller, so failing to notify is only logged.
+func notifyRunFailure(ctx context.Context, cleaner *appsv1alpha1.Cleaner, runID string, runErr error,
+	logger logr.Logger) {
+
+	if err := sendRunNotifications(ctx, nil, cleaner, runID, runErr, logger); err != nil {
+		logger.Info(fmt.Sprintf("failed to send failure notifications: %v", err))
+	}
+}
+
 func getMatchingResources(ctx context.Context, sr *appsv1alpha1.ResourceSelector, logger logr.Logger,
 ) ([]ResourceResult, error) {
 
@@ -248,10 +291,19 @@ func getMatchingResources(ctx context.Context, sr *appsv1alpha1.ResourceSelector
 	return results, nil
 }
 
+// deleteMatchingResources deletes resources using the DeleteOptions of cleaner.
+// When cleaner has Backup set, each resource is backed up first, and resources
+// which cannot be backed up are not deleted.
 func deleteMatchingResources(ctx context.Context, resources []ResourceResult,
-	deleteOptions *appsv1alpha1.DeleteOptions, logger logr.Logger) ([]ResourceResult, error) {
+	cleaner *appsv1alpha1.Cleaner, logger logr.Logger) ([]ResourceResult, error) {
 
-	processedResources := make([]ResourceResult, 0)
+	c, err := getK8sClient()
+	if err != nil {
+		return nil, err
+	}
+
+	processedResources := make([]ResourceResult, 0, len(resources))
+	var failures []error
 
 	for i := range resources {
 		resource := resources[i]
@@ -259,28 +311,56 @@ func deleteMatchingResources(ctx context.Context, resources []ResourceResult,
 			resource.Resource.GetKind(),
 			resource.Resource.GetNamespace(),
 			resource.Resource.GetName()))
+		if cleaner.Spec.Backup != nil {
+			backup, err := backupResource(ctx, cleaner.Name, cleaner.Spec.Backup, resource.Resource, time.Now())
+			if err != nil {
+				l.Info(fmt.Sprintf("failed to back up resource. Do not delete it: %v", err))
+				resource.Outcome = appsv1alpha1.ResourceOutcomeFailed
+				resource.Error = fmt.Sprintf("backup failed, resource not deleted: %v", err)
+				failures = append(failures, err)
+				processedResources = append(processedResources, resource)
+				continue
+			}
+			resource.Backup = backup
+		}
+
 		l.Info("deleting resource")
 
 		options := &client.DeleteOptions{}
-		if deleteOptions != nil {
+		if deleteOptions := cleaner.Spec.DeleteOptions; deleteOptions != nil {
 			options.GracePeriodSeconds = deleteOptions.GracePeriodSeconds
 			options.PropagationPolicy = deleteOptions.PropagationPolicy
 		}
 
-		if err := k8sClient.Delete(ctx, resource.Resource, options); err != nil {
+		err := c.Delete(ctx, resource.Resource, options)
+		switch {
+		case err == nil:
+			resource.Outcome = appsv1alpha1.ResourceOutcomeSucceeded
+		case apierrors.IsNotFound(err):
+			l.V(logs.LogDebug).Info("resource already deleted")
+			resource.Outcome = appsv1alpha1.ResourceOutcomeSkipped
+		default:
 			l.Info(fmt.Sprintf("failed to delete resource: %v", err))
-			return processedResources, err
+			resource.Outcome = appsv1alpha1.ResourceOutcomeFailed
+			resource.Error = err.Error()
+			failures = append(failures, err)
 		}
 		processedResources = append(processedResources, resource)
 	}
 
-	return processedResources, nil
+	return processedResources, getActionError("delete", failures, len(resources))
 }
 
 func updateMatchingResources(ctx context.Context, resources []ResourceResult,
 	transformFunction string, logger logr.Logger) ([]ResourceResult, error) {
 
-	processedResources := make([]ResourceResult, 0)
+	c, err := getK8sClient()
+	if err != nil {
+		return nil, err
+	}
+
+	processedResources := make([]ResourceResult, 0, len(resources))
+	var failures []error
 
 	for i := range resources {
 		resource := resources[i]
@@ -292,16 +372,45 @@ func updateMatchingResources(ctx context.Context, resources []ResourceResult,
 		newResource, err := transform(resource.Resource, transformFunction, l)
 		if err != nil {
 			l.Info(fmt.Sprintf("failed to transform resource: %v", err))
-			return processedResources, err
+			resource.Outcome = appsv1alpha1.ResourceOutcomeFailed
+			resource.Error = err.Error()
+			failures = append(failures, err)
+			processedResources = append(processedResources, resource)
+			continue
+		}
+		// Diff is computed before updating, as update changes resourceVersion
+		diff, err := getResourceDiff(resource.Resource, newResource)
+		if err != nil {
+			// Error is ignored as diff is only used in reports
+			l.V(logs.LogInfo).Info(fmt.Sprintf("failed to compute diff: %v", err))
 		}
-		if err := k8sClient.Update(ctx, newResource); err != nil {
+		err = c.Update(ctx, newResource)
+		switch {
+		case err == nil:
+			resource.Diff = diff
+			resource.Outcome = appsv1alpha1.ResourceOutcomeSucceeded
+		case apierrors.IsNotFound(err):
+			l.V(logs.LogDebug).Info("resource already deleted")
+			resource.Outcome = appsv1alpha1.ResourceOutcomeSkipped
+		default:
 			l.Info(fmt.Sprintf("failed to update resource: %v", err))
-			return processedResources, err
+			resource.Outcome = appsv1alpha1.ResourceOutcomeFailed
+			resource.Error = err.Error()
+			failures = append(failures, err)
 		}
 		processedResources = append(processedResources, resource)
 	}
 
-	return processedResources, nil
+	return processedResources, getActionError("update", failures, len(resources))
+}
+
+// getActionError returns an error summarizing the failures of action on total
+// resources, nil if there is none. The first failure is wrapped.
+func getActionError(action string, failures []error, total int) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	return fmt.Errorf("failed to %s %d of %d resources: %w", action, len(failures), total, failures[0])
 }
 
 func fetchResources(ctx context.Context, resourceSelector *appsv1alpha1.ResourceSelector,
@@ -419,8 +528,12 @@ func getNamespaces(ctx context.Context, resourceSelector *appsv1alpha1.ResourceS
 			return nil, err
 		}
 
+		c, err := getK8sClient()
+		if err != nil {
+			return nil, err
+		}
 		namespaces := &corev1.NamespaceList{}
-		err = k8sClient.List(ctx, namespaces)
+		err = c.List(ctx, namespaces)
 		if err != nil {
 			logger.Error(err, "failed to list all namespaces")
 			return nil, err
@@ -641,8 +754,13 @@ func aggregatedSelection(luaScript string, resources []ResourceResult, logger lo
 }
 
 func getCleanerInstance(ctx context.Context, cleanerName string) (*appsv1alpha1.Cleaner, error) {
+	c, err := getK8sClient()
+	if err != nil {
+		return nil, err
+	}
+
 	cleaner := &appsv1alpha1.Cleaner{}
-	err := k8sClient.Get(ctx, types.NamespacedName{Name: cleanerName}, cleaner)
+	err = c.Get(ctx, types.NamespacedName{Name: cleanerName}, cleaner)
 
 	if apierrors.IsNotFound(err) {
 		err = nil
@@ -655,7 +773,7 @@ func getCleanerInstance(ctx context.Context, cleanerName string) (*appsv1alpha1.
 // - set results for further in time lookup
 // - remove request from inProgress
 // - if request is in dirty, remove it from there and add it to the back of the jobQueue
-func storeResult(cleanerName string, err error, logger logr.Logger) {
+func storeResult(cleanerName, runID string, err error, logger logr.Logger) {
 	managerInstance.mu.Lock()
 	defer managerInstance.mu.Unlock()
 
@@ -676,7 +794,7 @@ func storeResult(cleanerName string, err error, logger logr.Logger) {
 	} else {
 		logger.V(logs.LogDebug).Info("added to result")
 	}
-	managerInstance.results[key] = err
+	managerInstance.results[key] = responseParams{cleanerName: key, runID: runID, err: err}
 
 	// if key is in dirty, remove from there and push to jobQueue
 	for i := range managerInstance.dirty {
@@ -705,15 +823,11 @@ func getRequestStatus(cleanerName string) (*responseParams, error) {
 	key := cleanerName
 
 	logger.V(logs.LogDebug).Info("searching result")
-	if _, ok := managerInstance.results[key]; ok {
+	if resp, ok := managerInstance.results[key]; ok {
 		logger.V(logs.LogDebug).Info("request already processed, result present. returning result.")
-		if managerInstance.results[key] != nil {
+		if resp.err != nil {
 			logger.V(logs.LogDebug).Info("returning a response with an error")
 		}
-		resp := responseParams{
-			cleanerName: key,
-			err:         managerInstance.results[key],
-		}
 		logger.V(logs.LogDebug).Info("removing result")
 		delete(managerInstance.results, key)
 		return &resp, nil
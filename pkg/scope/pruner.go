@@ -86,11 +86,26 @@ func (s *CleanerScope) SetLastRunTime(lastRunTime *metav1.Time) {
 	s.Cleaner.Status.LastRunTime = lastRunTime
 }
 
+// SetLastRunID sets LastRunID field
+func (s *CleanerScope) SetLastRunID(lastRunID string) {
+	s.Cleaner.Status.LastRunID = lastRunID
+}
+
+// SetLastStaleNotificationTime sets LastStaleNotificationTime field
+func (s *CleanerScope) SetLastStaleNotificationTime(lastStaleNotificationTime *metav1.Time) {
+	s.Cleaner.Status.LastStaleNotificationTime = lastStaleNotificationTime
+}
+
 // SetNextScheduleTime sets NextScheduleTime field
 func (s *CleanerScope) SetNextScheduleTime(lastRunTime *metav1.Time) {
 	s.Cleaner.Status.NextScheduleTime = lastRunTime
 }
 
+// SetNotifiedValidationError sets NotifiedValidationError field
+func (s *CleanerScope) SetNotifiedValidationError(notifiedValidationError string) {
+	s.Cleaner.Status.NotifiedValidationError = notifiedValidationError
+}
+
 // SetFailureMessage sets FasilureMessage field
 func (s *CleanerScope) SetFailureMessage(failureMessage *string) {
 	s.Cleaner.Status.FailureMessage = failureMessage